@@ -15,39 +15,39 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/auth/device": {
-            "post": {
-                "description": "Привязывает новое устройство к пользователю и возвращает UUID устройства",
+        "/admin/lockouts": {
+            "get": {
+                "description": "Возвращает последние блокировки входа по имени пользователя и IP-адресу, от новых к старым.\nДоступно только с токеном администратора сервера (флаг --admin-token) в заголовке Authorization: Bearer.",
                 "consumes": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Auth"
+                    "Admin"
                 ],
-                "summary": "Добавление нового устройства",
+                "summary": "Блокировки входа",
                 "parameters": [
                     {
-                        "description": "Данные устройства",
-                        "name": "request",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/handlers.DeviceRequest"
-                        }
+                        "type": "integer",
+                        "description": "Число записей (по умолчанию 100, не более 1000)",
+                        "name": "limit",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "UUID устройства",
+                        "description": "Блокировки входа",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.LockoutsResponse"
                         }
                     },
                     "400": {
-                        "description": "Неверные учетные данные или некорректные данные запроса"
+                        "description": "Некорректный limit"
+                    },
+                    "401": {
+                        "description": "Неверный токен администратора"
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера"
@@ -55,9 +55,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/auth/login": {
+        "/auth/device": {
             "post": {
-                "description": "Проверяет username, password и deviceUUID, возвращает JWT в заголовке Authorization",
+                "description": "Привязывает новое устройство к пользователю и возвращает UUID устройства.\nЕсли на сервере включено подтверждение устройств и у пользователя уже есть доверенное устройство,\nновое устройство ожидает подтверждения и сервер отвечает 202.",
                 "consumes": [
                     "application/json"
                 ],
@@ -67,34 +67,46 @@ const docTemplate = `{
                 "tags": [
                     "Auth"
                 ],
-                "summary": "Вход пользователя",
+                "summary": "Добавление нового устройства",
                 "parameters": [
                     {
-                        "description": "Данные для входа",
+                        "description": "Данные устройства",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.LoginRequest"
+                            "$ref": "#/definitions/handlers.DeviceRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "JWT токен успешно сгенерирован и возвращен в заголовке Authorization"
+                        "description": "UUID устройства",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "202": {
+                        "description": "UUID устройства, ожидающего подтверждения",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
                     "400": {
                         "description": "Неверные учетные данные или некорректные данные запроса"
                     },
+                    "429": {
+                        "description": "Слишком много неудачных попыток входа; время ожидания в секундах — в заголовке Retry-After"
+                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера"
                     }
                 }
             }
         },
-        "/auth/register": {
-            "post": {
-                "description": "Создаёт нового пользователя с заданными username и password",
+        "/auth/device/signing-key": {
+            "put": {
+                "description": "Сохраняет ключ подписи активного устройства, добавленного до появления ключей подписи.\nПока у пользователя есть активное устройство, новые устройства ожидают подтверждения,\nа подтверждать их может только устройство с ключом подписи. Тело содержит ключ и подпись\nэтим ключом над UUID и ключами текущего устройства, подтверждающую владение ключом.",
                 "consumes": [
                     "application/json"
                 ],
@@ -104,27 +116,36 @@ const docTemplate = `{
                 "tags": [
                     "Auth"
                 ],
-                "summary": "Регистрация нового пользователя",
+                "summary": "Регистрация ключа подписи устройства",
                 "parameters": [
                     {
-                        "description": "Данные пользователя",
+                        "description": "Ключ подписи и подпись",
                         "name": "request",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/handlers.RegisterRequest"
+                            "$ref": "#/definitions/handlers.SetSigningKeyRequest"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Пользователь успешно зарегистрирован"
+                        "description": "Ключ подписи сохранён"
                     },
                     "400": {
-                        "description": "Некорректные данные запроса"
+                        "description": "Некорректные данные запроса, ключ или подпись"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Текущее устройство не активно"
+                    },
+                    "404": {
+                        "description": "Устройство не найдено"
                     },
                     "409": {
-                        "description": "Пользователь с таким именем уже существует"
+                        "description": "Ключ подписи уже задан"
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера"
@@ -132,29 +153,26 @@ const docTemplate = `{
                 }
             }
         },
-        "/chat": {
-            "post": {
-                "description": "Создаёт новую комнату для текущего пользователя",
+        "/auth/devices": {
+            "get": {
+                "description": "Возвращает неотозванные устройства текущего пользователя в порядке добавления,\nвключая устройства, ожидающие подтверждения (status = pending).\nУстройство, с которого выполнен запрос, отмечено полем current.",
                 "consumes": [
                     "text/plain"
                 ],
                 "produces": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "tags": [
-                    "Chat"
+                    "Auth"
                 ],
-                "summary": "Создание новой комнаты",
+                "summary": "Список устройств",
                 "responses": {
                     "200": {
-                        "description": "UUID комнаты",
+                        "description": "Устройства пользователя",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/handlers.DevicesResponse"
                         }
                     },
-                    "400": {
-                        "description": "Некорректные данные запроса"
-                    },
                     "401": {
                         "description": "Неавторизован"
                     },
@@ -164,66 +182,79 @@ const docTemplate = `{
                 }
             }
         },
-        "/chat/ws/{room-uuid}": {
-            "get": {
-                "description": "Создает WebSocket соединение для конкретной комнаты. Сообщения рассылаются всем участникам, кроме отправителя.",
+        "/auth/devices/{device-uuid}": {
+            "delete": {
+                "description": "Отзывает устройство текущего пользователя: вход с него становится невозможен,\nвсе его access- и refresh-токены отзываются, WebSocket-соединения закрываются,\nа зашифрованные для него ключи комнат удаляются. Во всех комнатах пользователя начинается новая эпоха ключа.\nОтозвать можно и текущее устройство — это равносильно выходу с него без возможности повторного входа.",
                 "consumes": [
                     "text/plain"
                 ],
                 "produces": [
-                    "application/json"
+                    "text/plain"
                 ],
                 "tags": [
-                    "Chat"
+                    "Auth"
                 ],
-                "summary": "WebSocket соединение для чата",
+                "summary": "Отзыв устройства",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "UUID комнаты",
-                        "name": "room-uuid",
+                        "description": "UUID устройства",
+                        "name": "device-uuid",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "101": {
-                        "description": "WebSocket соединение установлено"
+                    "200": {
+                        "description": "Устройство отозвано"
                     },
                     "400": {
-                        "description": "Некорректный UUID комнаты"
+                        "description": "Некорректный UUID устройства"
                     },
                     "401": {
                         "description": "Неавторизован"
+                    },
+                    "404": {
+                        "description": "Устройство не найдено"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
                     }
                 }
-            }
-        },
-        "/chat/{room-uuid}": {
-            "delete": {
-                "description": "Удаляет комнату по UUID",
+            },
+            "patch": {
+                "description": "Задаёт человекочитаемое название устройства текущего пользователя (не длиннее 64 символов).",
                 "consumes": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "Chat"
+                    "Auth"
                 ],
-                "summary": "Удаление комнаты",
+                "summary": "Переименование устройства",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "UUID комнаты",
-                        "name": "room-uuid",
+                        "description": "UUID устройства",
+                        "name": "device-uuid",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Новое название",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RenameDeviceRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Комната успешно удалена"
+                        "description": "Устройство переименовано"
                     },
                     "400": {
                         "description": "Некорректные данные запроса"
@@ -232,7 +263,7 @@ const docTemplate = `{
                         "description": "Неавторизован"
                     },
                     "404": {
-                        "description": "Комната не найдена"
+                        "description": "Устройство не найдено"
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера"
@@ -240,141 +271,1686 @@ const docTemplate = `{
                 }
             }
         },
-        "/chat/{room-uuid}/{member-uuid}": {
+        "/auth/devices/{device-uuid}/approve": {
             "post": {
-                "description": "Добавляет указанного пользователя (member-uuid) в комнату",
+                "description": "Делает ожидающее устройство пользователя активным. Запрос выполняется с уже активного устройства\nтого же пользователя, у которого есть ключ подписи; тело содержит подпись этим ключом над UUID\nи ключами подтверждаемого устройства. Подпись проверяется и сохраняется вместе с устройством.",
                 "consumes": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "Chat"
+                    "Auth"
                 ],
-                "summary": "Добавление пользователя в комнату",
+                "summary": "Подтверждение устройства",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "UUID комнаты",
-                        "name": "room-uuid",
+                        "description": "UUID подтверждаемого устройства",
+                        "name": "device-uuid",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "UUID пользователя",
-                        "name": "member-uuid",
-                        "in": "path",
-                        "required": true
+                        "description": "Подпись подтверждения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ApproveDeviceRequest"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Пользователь успешно добавлен"
+                        "description": "Устройство подтверждено"
                     },
                     "400": {
-                        "description": "Некорректные данные запроса"
+                        "description": "Некорректные данные запроса или неверная подпись"
                     },
                     "401": {
                         "description": "Неавторизован"
                     },
+                    "403": {
+                        "description": "Текущее устройство не может подтверждать устройства"
+                    },
                     "404": {
-                        "description": "Комната не найдена"
+                        "description": "Устройство не найдено"
+                    },
+                    "409": {
+                        "description": "Устройство не ожидает подтверждения"
                     },
                     "500": {
                         "description": "Внутренняя ошибка сервера"
                     }
                 }
-            },
-            "delete": {
-                "description": "Удаляет указанного пользователя (member-uuid) из комнаты",
+            }
+        },
+        "/auth/login": {
+            "post": {
+                "description": "Проверяет username, password и deviceUUID, возвращает короткоживущий JWT в заголовке Authorization\nи одноразовый refresh-токен в заголовке X-Refresh-Token",
                 "consumes": [
-                    "text/plain"
+                    "application/json"
                 ],
                 "produces": [
                     "text/plain"
                 ],
                 "tags": [
-                    "Chat"
+                    "Auth"
                 ],
-                "summary": "Удаление пользователя из комнаты",
+                "summary": "Вход пользователя",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "UUID комнаты",
-                        "name": "room-uuid",
-                        "in": "path",
-                        "required": true
+                        "description": "Данные для входа",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "JWT токен возвращен в заголовке Authorization, refresh-токен — в заголовке X-Refresh-Token"
+                    },
+                    "400": {
+                        "description": "Неверные учетные данные или некорректные данные запроса"
+                    },
+                    "403": {
+                        "description": "Устройство ожидает подтверждения"
                     },
+                    "429": {
+                        "description": "Слишком много неудачных попыток входа; время ожидания в секундах — в заголовке Retry-After"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/auth/logout": {
+            "post": {
+                "description": "Отзывает текущий JWT и refresh-токены его сессии. С параметром scope=device отзываются все сессии текущего устройства,\nс scope=user — все сессии пользователя на всех устройствах.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Выход из аккаунта",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "UUID пользователя",
-                        "name": "member-uuid",
-                        "in": "path",
-                        "required": true
+                        "description": "Область выхода: token (по умолчанию), device или user",
+                        "name": "scope",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Пользователь успешно удалён"
+                        "description": "Выход выполнен, токен отозван"
                     },
                     "400": {
-                        "description": "Некорректные данные запроса"
+                        "description": "Некорректная область выхода"
                     },
                     "401": {
                         "description": "Неавторизован"
                     },
-                    "404": {
-                        "description": "Комната или пользователь не найдены"
-                    },
                     "500": {
                         "description": "Внутренняя ошибка сервера"
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "handlers.DeviceRequest": {
-            "type": "object",
-            "properties": {
-                "password": {
-                    "description": "Пароль пользователя\nrequired: true\nexample: mySecret123",
-                    "type": "string"
-                },
-                "public_key": {
-                    "description": "Публичный ключ устройства\nrequired: true\nexample: MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAr...",
-                    "type": "string"
-                },
-                "username": {
-                    "description": "Username пользователя\nrequired: true\nexample: johndoe",
-                    "type": "string"
-                }
-            }
         },
-        "handlers.LoginRequest": {
-            "type": "object",
-            "properties": {
-                "device_uuid": {
-                    "description": "UUID устройства\nrequired: true\nexample: 4c2b87cd-8c44-4546-89df-7a751cbac96e",
-                    "type": "string"
-                },
-                "password": {
-                    "description": "Пароль пользователя\nrequired: true\nexample: mySecret123",
-                    "type": "string"
-                },
-                "username": {
-                    "description": "Username пользователя\nrequired: true\nexample: johndoe",
-                    "type": "string"
+        "/auth/password": {
+            "post": {
+                "description": "Проверяет текущий пароль и политику паролей, сохраняет bcrypt-хэш нового пароля и отзывает\nвсе сессии пользователя на всех устройствах. Для текущего устройства начинается новая сессия:\nJWT возвращается в заголовке Authorization, refresh-токен — в заголовке X-Refresh-Token.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Смена пароля",
+                "parameters": [
+                    {
+                        "description": "Текущий и новый пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пароль изменён, новые токены возвращены в заголовках Authorization и X-Refresh-Token"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса, неверный текущий пароль или новый пароль не соответствует политике"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "429": {
+                        "description": "Слишком много неудачных попыток; время ожидания в секундах — в заголовке Retry-After"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
                 }
             }
         },
-        "handlers.RegisterRequest": {
-            "type": "object",
-            "properties": {
-                "password": {
-                    "description": "Пароль пользователя\nrequired: true\nexample: mySecret123",
+        "/auth/refresh": {
+            "post": {
+                "description": "Обменивает одноразовый refresh-токен на новый JWT (заголовок Authorization)\nи новый refresh-токен (заголовок X-Refresh-Token). Повторное использование\nуже обменянного refresh-токена отзывает все сессии устройства.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Обновление токенов",
+                "parameters": [
+                    {
+                        "description": "Refresh-токен",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RefreshRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Новые токены возвращены в заголовках Authorization и X-Refresh-Token"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Refresh-токен недействителен, истёк, отозван или использован повторно"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/auth/register": {
+            "post": {
+                "description": "Создаёт нового пользователя с заданными username и password.\nИмя пользователя: 3–32 символа, латинские буквы, цифры, '_', '.' и '-', начинается с буквы или цифры.\nПароль проверяется политикой паролей сервера; при нарушении тело ответа содержит описание правила.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Auth"
+                ],
+                "summary": "Регистрация нового пользователя",
+                "parameters": [
+                    {
+                        "description": "Данные пользователя",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пользователь успешно зарегистрирован"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса, имя пользователя или пароль не соответствуют правилам"
+                    },
+                    "409": {
+                        "description": "Пользователь с таким именем уже существует"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat": {
+            "get": {
+                "description": "Возвращает комнаты, в которых состоит пользователь, с его ролью, количеством участников\nи временем последней активности; комнаты с недавней активностью идут первыми.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Список комнат пользователя",
+                "responses": {
+                    "200": {
+                        "description": "Комнаты пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RoomsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            },
+            "post": {
+                "description": "Создаёт групповую комнату с необязательными названием и темой; пустое тело создаёт комнату без названия.\nДля kind=direct возвращает личную комнату с пользователем member_uuid: для каждой пары пользователей\nсуществует одна личная комната, и повторный запрос любого из собеседников возвращает её же.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Создание новой комнаты",
+                "parameters": [
+                    {
+                        "description": "Параметры комнаты",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.CreateChatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "UUID комнаты",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "404": {
+                        "description": "Собеседник не найден"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}": {
+            "put": {
+                "description": "Изменяет название и тему комнаты. В групповой комнате доступно владельцу и администраторам,\nв личной — обоим собеседникам. Подключённые участники получают событие room_updated.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Изменение названия и темы комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новые название и тема",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UpdateChatRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Комната успешно изменена"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Недостаточно прав"
+                    },
+                    "404": {
+                        "description": "Комната не найдена"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет комнату по UUID. Доступно только владельцу комнаты.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Удаление комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Комната успешно удалена"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Недостаточно прав"
+                    },
+                    "404": {
+                        "description": "Комната не найдена"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/devices": {
+            "get": {
+                "description": "Возвращает текущую эпоху ключа и все устройства всех участников комнаты с их публичными ключами\nи ключами подписи, чтобы клиент мог зашифровать ключ этой эпохи для каждого из них\nи проверить подписи полученных ключей.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Keys"
+                ],
+                "summary": "Устройства участников комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Эпоха ключа и устройства участников",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RoomDevicesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Пользователь не состоит в комнате"
+                    },
+                    "404": {
+                        "description": "Комната не найдена"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/invite-links": {
+            "post": {
+                "description": "Создаёт ссылку-приглашение в групповую комнату со сроком действия и ограничением числа входов.\nДоступно владельцу и администраторам. Пустое тело создаёт ссылку на сутки без ограничения числа входов.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invites"
+                ],
+                "summary": "Создание ссылки-приглашения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Параметры ссылки",
+                        "name": "request",
+                        "in": "body",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InviteLinkRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылка-приглашение",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InviteLinkResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Недостаточно прав или комната личная"
+                    },
+                    "404": {
+                        "description": "Комната не найдена"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/invites": {
+            "post": {
+                "description": "Создаёт приглашение пользователя member_uuid в групповую комнату. Доступно владельцу и администраторам.\nПользователь становится участником, только приняв приглашение. Повторное приглашение,\nпока на прежнее не ответили, возвращает прежнее приглашение.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invites"
+                ],
+                "summary": "Приглашение пользователя в комнату",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Приглашаемый пользователь",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InviteRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Приглашение",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InviteResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Недостаточно прав или комната личная"
+                    },
+                    "404": {
+                        "description": "Комната или пользователь не найдены"
+                    },
+                    "409": {
+                        "description": "Пользователь уже состоит в комнате"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/keys": {
+            "get": {
+                "description": "Возвращает ключ комнаты, зашифрованный публичным ключом текущего устройства (device_uuid из JWT).\nБез параметра epoch возвращается ключ текущей эпохи. Вместе с ключом возвращаются устройство,\nзагрузившее его, и подпись: клиент проверяет её ключом подписи устройства участника комнаты.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Keys"
+                ],
+                "summary": "Получение ключа комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Эпоха ключа",
+                        "name": "epoch",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Зашифрованный ключ комнаты",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RoomKeyResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Пользователь не состоит в комнате"
+                    },
+                    "404": {
+                        "description": "Комната или ключ для устройства не найдены"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            },
+            "put": {
+                "description": "Сохраняет ключ комнаты текущей эпохи, зашифрованный публичным ключом каждого из указанных устройств.\nДоступно любому участнику комнаты; устройства должны принадлежать участникам комнаты.\nКаждый ключ подписывается ключом подписи загружающего устройства (device_uuid из JWT).\nКлючи всех устройств сохраняются атомарно. У эпохи один ключ: его выпускает первая загрузка,\nпоследующие только пересылают его устройствам без ключа и принимаются, если ключ эпохи уже есть\nу текущего устройства. Ключ, выпущенный другим устройством той же эпохи, отклоняется с кодом 409:\nклиент должен получить сохранённый ключ эпохи и использовать его.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Keys"
+                ],
+                "summary": "Загрузка ключей комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Ключи для устройств",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UploadRoomKeysRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ключи сохранены"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса, устройство не принадлежит участнику комнаты или подпись ключа неверна"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Пользователь не состоит в комнате"
+                    },
+                    "404": {
+                        "description": "Комната не найдена"
+                    },
+                    "409": {
+                        "description": "Эпоха ключа устарела или её ключ уже выпущен другим устройством"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/leave": {
+            "post": {
+                "description": "Удаляет текущего пользователя из комнаты. Владелец должен сначала передать владение.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Выход из комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пользователь успешно покинул комнату"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "404": {
+                        "description": "Комната не найдена или пользователь не состоит в ней"
+                    },
+                    "409": {
+                        "description": "Владелец не может покинуть комнату без передачи владения"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/members": {
+            "get": {
+                "description": "Возвращает участников комнаты с именами пользователей и ролями. Доступно только участникам комнаты.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Список участников комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Участники комнаты",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.RoomMembersResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Пользователь не состоит в комнате"
+                    },
+                    "404": {
+                        "description": "Комната не найдена"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/messages": {
+            "get": {
+                "description": "Возвращает зашифрованные сообщения комнаты в порядке отправки. Доступно только участникам комнаты.\nБез параметров возвращаются последние сообщения. С before — сообщения, отправленные до указанного,\nс after — после указанного; before и after можно задать одновременно.\nЧтобы листать историю назад, передайте в before UUID первого сообщения предыдущей страницы.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "История сообщений комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID сообщения: вернуть сообщения, отправленные до него",
+                        "name": "before",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID сообщения: вернуть сообщения, отправленные после него",
+                        "name": "after",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Размер страницы (по умолчанию 50, не более 200)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Страница истории",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.MessagesResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные параметры запроса или курсор не является сообщением комнаты"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Пользователь не состоит в комнате"
+                    },
+                    "404": {
+                        "description": "Комната не найдена"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/ws": {
+            "get": {
+                "description": "Создает WebSocket соединение для конкретной комнаты. Сообщения рассылаются на все подключённые устройства участников, включая другие устройства отправителя.\nКлиент отправляет конверты {\"v\":1,\"type\":\"message\",\"ciphertext\":\"...\",\"key_epoch\":N};\nсервер дополняет их полями message_uuid, room_uuid, sender_uuid, sender_device и sent_at.\nКадры неизвестного типа не рассылаются: отправитель получает конверт {\"type\":\"error\",\"code\":\"unknown_type\"}.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "WebSocket соединение для чата",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "101": {
+                        "description": "WebSocket соединение установлено"
+                    },
+                    "400": {
+                        "description": "Некорректный UUID комнаты"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Пользователь не является участником комнаты"
+                    },
+                    "404": {
+                        "description": "Комната не найдена"
+                    },
+                    "500": {
+                        "description": "Ошибка сервера при апгрейде соединения"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/{member-uuid}": {
+            "delete": {
+                "description": "Удаляет указанного пользователя (member-uuid) из комнаты.\nВладелец может удалить любого участника, администратор — только обычных участников.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Удаление пользователя из комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID пользователя",
+                        "name": "member-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пользователь успешно удалён"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Недостаточно прав"
+                    },
+                    "404": {
+                        "description": "Комната или пользователь не найдены"
+                    },
+                    "409": {
+                        "description": "Владелец не может покинуть комнату без передачи владения"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/{member-uuid}/owner": {
+            "post": {
+                "description": "Передаёт владение комнатой участнику member-uuid. Прежний владелец становится администратором.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Передача владения комнатой",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID нового владельца",
+                        "name": "member-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Владение успешно передано"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Недостаточно прав"
+                    },
+                    "404": {
+                        "description": "Комната или пользователь не найдены"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/chat/{room-uuid}/{member-uuid}/role": {
+            "put": {
+                "description": "Назначает участнику member-uuid роль admin или member. Доступно только владельцу комнаты.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Chat"
+                ],
+                "summary": "Изменение роли участника комнаты",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID комнаты",
+                        "name": "room-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "UUID пользователя",
+                        "name": "member-uuid",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Новая роль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handlers.SetRoleRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Роль успешно изменена"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Недостаточно прав"
+                    },
+                    "404": {
+                        "description": "Комната или пользователь не найдены"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/invites": {
+            "get": {
+                "description": "Возвращает приглашения в комнаты, на которые пользователь ещё не ответил.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Invites"
+                ],
+                "summary": "Список приглашений пользователя",
+                "responses": {
+                    "200": {
+                        "description": "Приглашения пользователя",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.InvitesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/invites/links/{link-uuid}": {
+            "post": {
+                "description": "Добавляет текущего пользователя в комнату по ссылке-приглашению и возвращает UUID комнаты.\nУчастнику комнаты возвращается UUID комнаты без учёта входа по ссылке.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Invites"
+                ],
+                "summary": "Вход в комнату по ссылке-приглашению",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID ссылки-приглашения",
+                        "name": "link-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "UUID комнаты",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена"
+                    },
+                    "410": {
+                        "description": "Срок действия ссылки истёк или входы по ней закончились"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            },
+            "delete": {
+                "description": "Удаляет ссылку-приглашение: входить по ней больше нельзя. Доступно владельцу и администраторам комнаты.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Invites"
+                ],
+                "summary": "Отзыв ссылки-приглашения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID ссылки-приглашения",
+                        "name": "link-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ссылка отозвана"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "403": {
+                        "description": "Недостаточно прав"
+                    },
+                    "404": {
+                        "description": "Ссылка не найдена"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/invites/{invite-uuid}/accept": {
+            "post": {
+                "description": "Принимает приглашение текущего пользователя и добавляет его в комнату; начинается новая эпоха ключа комнаты.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Invites"
+                ],
+                "summary": "Принятие приглашения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID приглашения",
+                        "name": "invite-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "UUID комнаты",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "404": {
+                        "description": "Приглашение не найдено или на него уже ответили"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/invites/{invite-uuid}/decline": {
+            "post": {
+                "description": "Отклоняет приглашение текущего пользователя.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "text/plain"
+                ],
+                "tags": [
+                    "Invites"
+                ],
+                "summary": "Отклонение приглашения",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "UUID приглашения",
+                        "name": "invite-uuid",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Приглашение отклонено"
+                    },
+                    "400": {
+                        "description": "Некорректные данные запроса"
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "404": {
+                        "description": "Приглашение не найдено или на него уже ответили"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/users/{username}": {
+            "get": {
+                "description": "Возвращает UUID пользователя с указанным именем, например чтобы добавить его в комнату.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Поиск пользователя по имени",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя пользователя",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пользователь",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "404": {
+                        "description": "Пользователь не найден"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        },
+        "/users/{username}/devices": {
+            "get": {
+                "description": "Возвращает UUID пользователя и публичные ключи его активных устройств с отпечатками,\nчтобы клиент мог вычислить номер безопасности и сверить его с собеседником.",
+                "consumes": [
+                    "text/plain"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Устройства пользователя",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Имя пользователя",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Пользователь и его устройства",
+                        "schema": {
+                            "$ref": "#/definitions/handlers.UserDevicesResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Неавторизован"
+                    },
+                    "404": {
+                        "description": "Пользователь не найден"
+                    },
+                    "500": {
+                        "description": "Внутренняя ошибка сервера"
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "handlers.ApproveDeviceRequest": {
+            "type": "object",
+            "properties": {
+                "signature": {
+                    "description": "Подпись Ed25519 (base64) ключом подписи текущего устройства над UUID и ключами подтверждаемого устройства\nrequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "current_password": {
+                    "description": "Текущий пароль пользователя\nrequired: true",
+                    "type": "string"
+                },
+                "new_password": {
+                    "description": "Новый пароль пользователя\nrequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.CreateChatRequest": {
+            "type": "object",
+            "properties": {
+                "kind": {
+                    "description": "Тип комнаты: group (по умолчанию) или direct\nexample: group",
+                    "type": "string"
+                },
+                "member_uuid": {
+                    "description": "UUID собеседника; обязателен для личной комнаты",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Название групповой комнаты\nexample: Команда",
+                    "type": "string"
+                },
+                "topic": {
+                    "description": "Тема групповой комнаты\nexample: Планы на релиз",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DeviceRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "description": "Пароль пользователя\nrequired: true\nexample: mySecret123",
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "Публичный ключ устройства\nrequired: true\nexample: MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAr...",
+                    "type": "string"
+                },
+                "signing_key": {
+                    "description": "Публичный ключ подписи устройства (Ed25519, base64); нужен, чтобы подтверждать новые устройства\nexample: 9c8Yk0m3qYH2b1sV2uQ6fC5a1nS7R4o0pZ3kL8eW2xE=",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Username пользователя\nrequired: true\nexample: johndoe",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DeviceResponse": {
+            "type": "object",
+            "properties": {
+                "approval_signature": {
+                    "description": "Подпись подтверждения ключом подписи устройства approved_by",
+                    "type": "string"
+                },
+                "approved_by": {
+                    "description": "UUID устройства, подтвердившего это устройство",
+                    "type": "string"
+                },
+                "created_at": {
+                    "description": "Время добавления устройства",
+                    "type": "string"
+                },
+                "current": {
+                    "description": "Признак устройства, с которого выполнен запрос",
+                    "type": "boolean"
+                },
+                "device_uuid": {
+                    "description": "UUID устройства",
+                    "type": "string"
+                },
+                "fingerprint": {
+                    "description": "Отпечаток публичного ключа устройства",
+                    "type": "string"
+                },
+                "name": {
+                    "description": "Название устройства",
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "Публичный ключ устройства",
+                    "type": "string"
+                },
+                "signing_key": {
+                    "description": "Публичный ключ подписи устройства",
+                    "type": "string"
+                },
+                "status": {
+                    "description": "Статус устройства: active или pending (ожидает подтверждения)",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.DevicesResponse": {
+            "type": "object",
+            "properties": {
+                "devices": {
+                    "description": "Неотозванные устройства",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.DeviceResponse"
+                    }
+                },
+                "user_uuid": {
+                    "description": "UUID текущего пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InviteLinkRequest": {
+            "type": "object",
+            "properties": {
+                "expires_in": {
+                    "description": "Срок действия ссылки в секундах (по умолчанию сутки, не более 30 дней)\nexample: 3600",
+                    "type": "integer"
+                },
+                "max_uses": {
+                    "description": "Максимальное число входов по ссылке (0 — без ограничения)\nexample: 10",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.InviteLinkResponse": {
+            "type": "object",
+            "properties": {
+                "expires_at": {
+                    "description": "Время, после которого ссылка недействительна",
+                    "type": "string"
+                },
+                "link_uuid": {
+                    "description": "UUID ссылки, по которому входят в комнату",
+                    "type": "string"
+                },
+                "max_uses": {
+                    "description": "Максимальное число входов по ссылке (0 — без ограничения)",
+                    "type": "integer"
+                },
+                "room_uuid": {
+                    "description": "UUID комнаты",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InviteRequest": {
+            "type": "object",
+            "properties": {
+                "member_uuid": {
+                    "description": "UUID приглашаемого пользователя\nrequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InviteResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Время создания приглашения",
+                    "type": "string"
+                },
+                "invite_uuid": {
+                    "description": "UUID приглашения",
+                    "type": "string"
+                },
+                "invitee_uuid": {
+                    "description": "UUID приглашённого пользователя",
+                    "type": "string"
+                },
+                "inviter_username": {
+                    "description": "Имя пригласившего пользователя",
+                    "type": "string"
+                },
+                "inviter_uuid": {
+                    "description": "UUID пригласившего пользователя",
+                    "type": "string"
+                },
+                "room_name": {
+                    "description": "Название комнаты",
+                    "type": "string"
+                },
+                "room_uuid": {
+                    "description": "UUID комнаты",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.InvitesResponse": {
+            "type": "object",
+            "properties": {
+                "invites": {
+                    "description": "Приглашения, начиная с самых новых",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.InviteResponse"
+                    }
+                }
+            }
+        },
+        "handlers.LockoutResponse": {
+            "type": "object",
+            "properties": {
+                "failures": {
+                    "description": "Число неудачных попыток к моменту блокировки",
+                    "type": "integer"
+                },
+                "key": {
+                    "description": "Имя пользователя (в нижнем регистре) или IP-адрес клиента",
+                    "type": "string"
+                },
+                "locked_at": {
+                    "description": "Момент блокировки",
+                    "type": "string"
+                },
+                "locked_until": {
+                    "description": "Момент окончания блокировки",
+                    "type": "string"
+                },
+                "lockout_uuid": {
+                    "description": "UUID записи",
+                    "type": "string"
+                },
+                "scope": {
+                    "description": "Область блокировки: username или ip",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.LockoutsResponse": {
+            "type": "object",
+            "properties": {
+                "lockouts": {
+                    "description": "Блокировки от новых к старым",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.LockoutResponse"
+                    }
+                }
+            }
+        },
+        "handlers.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "device_uuid": {
+                    "description": "UUID устройства\nrequired: true\nexample: 4c2b87cd-8c44-4546-89df-7a751cbac96e",
+                    "type": "string"
+                },
+                "password": {
+                    "description": "Пароль пользователя\nrequired: true\nexample: mySecret123",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Username пользователя\nrequired: true\nexample: johndoe",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MessageResponse": {
+            "type": "object",
+            "properties": {
+                "ciphertext": {
+                    "description": "Шифртекст сообщения",
+                    "type": "string"
+                },
+                "key_epoch": {
+                    "description": "Эпоха ключа комнаты, которым зашифровано сообщение",
+                    "type": "integer"
+                },
+                "message_uuid": {
+                    "description": "UUID сообщения",
+                    "type": "string"
+                },
+                "room_uuid": {
+                    "description": "UUID комнаты",
+                    "type": "string"
+                },
+                "sender_device": {
+                    "description": "UUID устройства отправителя",
+                    "type": "string"
+                },
+                "sender_uuid": {
+                    "description": "UUID отправителя",
+                    "type": "string"
+                },
+                "sent_at": {
+                    "description": "Время приёма сообщения сервером",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.MessagesResponse": {
+            "type": "object",
+            "properties": {
+                "has_more": {
+                    "description": "Есть ли ещё сообщения в направлении чтения: более новые при заданном after, иначе более старые",
+                    "type": "boolean"
+                },
+                "messages": {
+                    "description": "Сообщения в порядке отправки (от старых к новым)",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.MessageResponse"
+                    }
+                }
+            }
+        },
+        "handlers.RefreshRequest": {
+            "type": "object",
+            "properties": {
+                "refresh_token": {
+                    "description": "Refresh-токен, полученный при входе или предыдущем обновлении\nrequired: true\nexample: 3q2-7wEjRk3x0y9JbYlQm3bTkpZL4m2Zl8c1Y0n8d5A",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RegisterRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "description": "Пароль пользователя\nrequired: true\nexample: mySecret123",
                     "type": "string"
                 },
                 "username": {
@@ -382,6 +1958,289 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "handlers.RenameDeviceRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "Новое название устройства; пустая строка сбрасывает название\nexample: Рабочий ноутбук",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RoomDeviceResponse": {
+            "type": "object",
+            "properties": {
+                "device_uuid": {
+                    "description": "UUID устройства",
+                    "type": "string"
+                },
+                "fingerprint": {
+                    "description": "Отпечаток публичного ключа устройства",
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "Публичный ключ устройства",
+                    "type": "string"
+                },
+                "signing_key": {
+                    "description": "Ключ подписи устройства, которым проверяются выпущенные им ключи комнаты",
+                    "type": "string"
+                },
+                "user_uuid": {
+                    "description": "UUID пользователя-владельца устройства",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RoomDevicesResponse": {
+            "type": "object",
+            "properties": {
+                "devices": {
+                    "description": "Устройства участников комнаты",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.RoomDeviceResponse"
+                    }
+                },
+                "key_epoch": {
+                    "description": "Текущая эпоха ключа комнаты, для которой нужно зашифровать ключ",
+                    "type": "integer"
+                }
+            }
+        },
+        "handlers.RoomKeyItem": {
+            "type": "object",
+            "properties": {
+                "device_uuid": {
+                    "description": "UUID устройства получателя\nrequired: true\nexample: 4c2b87cd-8c44-4546-89df-7a751cbac96e",
+                    "type": "string"
+                },
+                "encrypted_key": {
+                    "description": "Ключ комнаты, зашифрованный публичным ключом устройства (base64)\nrequired: true\nexample: kq3Vn0mYp2Jt...",
+                    "type": "string"
+                },
+                "signature": {
+                    "description": "Подпись зашифрованного ключа ключом подписи загружающего устройства (Ed25519, base64)\nrequired: true\nexample: 3q2+7wHf0QhX...",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RoomKeyResponse": {
+            "type": "object",
+            "properties": {
+                "encrypted_key": {
+                    "description": "Ключ комнаты, зашифрованный публичным ключом устройства (base64)",
+                    "type": "string"
+                },
+                "issuer_device_uuid": {
+                    "description": "UUID устройства, загрузившего ключ; пусто для ключей, загруженных до появления подписей",
+                    "type": "string"
+                },
+                "issuer_signing_key": {
+                    "description": "Ключ подписи устройства, загрузившего ключ",
+                    "type": "string"
+                },
+                "issuer_user_uuid": {
+                    "description": "UUID владельца устройства, загрузившего ключ; пусто для ключей, загруженных до появления подписей",
+                    "type": "string"
+                },
+                "key_epoch": {
+                    "description": "Эпоха ключа комнаты",
+                    "type": "integer"
+                },
+                "signature": {
+                    "description": "Подпись ключа ключом подписи загрузившего устройства (base64)",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RoomMemberResponse": {
+            "type": "object",
+            "properties": {
+                "joined_at": {
+                    "description": "Время присоединения к комнате",
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Роль участника (owner, admin или member)",
+                    "type": "string"
+                },
+                "user_uuid": {
+                    "description": "UUID пользователя",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Имя пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RoomMembersResponse": {
+            "type": "object",
+            "properties": {
+                "members": {
+                    "description": "Участники в порядке присоединения к комнате",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.RoomMemberResponse"
+                    }
+                }
+            }
+        },
+        "handlers.RoomResponse": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "description": "Время создания комнаты",
+                    "type": "string"
+                },
+                "kind": {
+                    "description": "Тип комнаты (group или direct)",
+                    "type": "string"
+                },
+                "last_activity_at": {
+                    "description": "Время последнего сообщения или создания комнаты, если сообщений нет",
+                    "type": "string"
+                },
+                "member_count": {
+                    "description": "Количество участников комнаты",
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Название комнаты",
+                    "type": "string"
+                },
+                "role": {
+                    "description": "Роль пользователя в комнате (owner, admin или member)",
+                    "type": "string"
+                },
+                "room_uuid": {
+                    "description": "UUID комнаты",
+                    "type": "string"
+                },
+                "topic": {
+                    "description": "Тема комнаты",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.RoomsResponse": {
+            "type": "object",
+            "properties": {
+                "rooms": {
+                    "description": "Комнаты, начиная с комнат с недавней активностью",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.RoomResponse"
+                    }
+                }
+            }
+        },
+        "handlers.SetRoleRequest": {
+            "type": "object",
+            "properties": {
+                "role": {
+                    "description": "Роль участника (admin или member)\nrequired: true\nexample: admin",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.SetSigningKeyRequest": {
+            "type": "object",
+            "properties": {
+                "signature": {
+                    "description": "Подпись Ed25519 (base64) этим ключом над UUID и ключами текущего устройства\nrequired: true",
+                    "type": "string"
+                },
+                "signing_key": {
+                    "description": "Публичный ключ подписи Ed25519 текущего устройства (base64)\nrequired: true",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UpdateChatRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "description": "Новое название комнаты; если поле не передано, название не меняется\nexample: Команда",
+                    "type": "string"
+                },
+                "topic": {
+                    "description": "Новая тема комнаты; если поле не передано, тема не меняется\nexample: Планы на релиз",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UploadRoomKeysRequest": {
+            "type": "object",
+            "properties": {
+                "key_epoch": {
+                    "description": "Эпоха ключа комнаты; должна совпадать с текущей эпохой\nrequired: true\nexample: 2",
+                    "type": "integer"
+                },
+                "keys": {
+                    "description": "Ключи комнаты для устройств участников\nrequired: true",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.RoomKeyItem"
+                    }
+                }
+            }
+        },
+        "handlers.UserDeviceResponse": {
+            "type": "object",
+            "properties": {
+                "device_uuid": {
+                    "description": "UUID устройства",
+                    "type": "string"
+                },
+                "fingerprint": {
+                    "description": "Отпечаток публичного ключа устройства",
+                    "type": "string"
+                },
+                "public_key": {
+                    "description": "Публичный ключ устройства",
+                    "type": "string"
+                },
+                "signing_key": {
+                    "description": "Публичный ключ подписи устройства",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UserDevicesResponse": {
+            "type": "object",
+            "properties": {
+                "devices": {
+                    "description": "Активные устройства пользователя",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/handlers.UserDeviceResponse"
+                    }
+                },
+                "user_uuid": {
+                    "description": "UUID пользователя",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Имя пользователя",
+                    "type": "string"
+                }
+            }
+        },
+        "handlers.UserResponse": {
+            "type": "object",
+            "properties": {
+                "user_uuid": {
+                    "description": "UUID пользователя",
+                    "type": "string"
+                },
+                "username": {
+                    "description": "Имя пользователя",
+                    "type": "string"
+                }
+            }
         }
     }
 }`
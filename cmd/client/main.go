@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/go-resty/resty/v2"
 	"github.com/google/uuid"
 	"github.com/sbilibin2017/bil-message/internal/client"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
 	"github.com/sbilibin2017/bil-message/internal/transport/http"
 	"github.com/spf13/cobra"
 )
@@ -33,16 +39,62 @@ func run() error {
 		newRegisterCommand(),
 		newDeviceCommand(),
 		newLoginCommand(),
+		newLogoutCommand(),
+		newChangePasswordCommand(),
+		newListDevicesCommand(),
+		newRenameDeviceCommand(),
+		newRevokeDeviceCommand(),
+		newApproveDeviceCommand(),
+		newVerifyCommand(),
+		newListLockoutsCommand(),
 		newVersionCommand(),
 		newCreateChatCommand(),
+		newEditChatCommand(),
 		newRemoveChatCommand(),
-		newAddChatMemberCommand(),
+		newInviteChatMemberCommand(),
+		newListInvitesCommand(),
+		newAcceptInviteCommand(),
+		newDeclineInviteCommand(),
+		newCreateInviteLinkCommand(),
+		newJoinByInviteLinkCommand(),
+		newRevokeInviteLinkCommand(),
+		newFindUserCommand(),
 		newRemoveChatMemberCommand(),
+		newLeaveChatCommand(),
+		newTransferChatOwnershipCommand(),
+		newSetChatMemberRoleCommand(),
+		newListChatsCommand(),
+		newListChatMembersCommand(),
 		newWebSocketCommand(),
+		newHistoryCommand(),
 	)
 	return cmd.Execute()
 }
 
+// newTokenStore возвращает хранилище токенов, сохранённых командой login
+func newTokenStore() *client.FileTokenStore {
+	configDir := os.ExpandEnv("$HOME/.config")
+	return client.NewFileTokenStore(fmt.Sprintf("%s/bil_message_client_tokens", configDir))
+}
+
+// newKeyStore возвращает хранилище приватного ключа устройства, созданного командой device
+func newKeyStore() *client.FileKeyStore {
+	configDir := os.ExpandEnv("$HOME/.config")
+	return client.NewFileKeyStore(fmt.Sprintf("%s/bil_message_client_device_key", configDir))
+}
+
+// newVerifiedKeyStore возвращает хранилище ключей собеседников, проверенных командой verify
+func newVerifiedKeyStore() *client.FileVerifiedKeyStore {
+	configDir := os.ExpandEnv("$HOME/.config")
+	return client.NewFileVerifiedKeyStore(fmt.Sprintf("%s/bil_message_client_verified_keys", configDir))
+}
+
+// newAuthorizedHTTPClient создаёт HTTP-клиент, который подставляет сохранённый JWT
+// в запросы без явного токена и прозрачно обновляет его по refresh-токену при ответе 401
+func newAuthorizedHTTPClient(address string) (*resty.Client, error) {
+	return http.New(address, http.WithTokenRefresh(newTokenStore(), client.Refresh))
+}
+
 // newRootCommand создаёт корневую команду CLI
 func newRootCommand() *cobra.Command {
 	return &cobra.Command{
@@ -58,9 +110,11 @@ func newRegisterCommand() *cobra.Command {
 	var address, username, password string
 
 	cmd := &cobra.Command{
-		Use:     "register",
-		Short:   "Регистрация нового пользователя",
-		Example: "bil-message-client register -a http://localhost:8080 -u testuser -p secret",
+		Use:   "register",
+		Short: "Регистрация нового пользователя",
+		Long: "Регистрирует пользователя. Имя: 3–32 символа, латинские буквы, цифры, '_', '.' и '-'. " +
+			"Пароль должен соответствовать политике паролей сервера; при отказе выводится нарушенное правило.",
+		Example: "bil-message-client register -a http://localhost:8080 -u testuser -p 'Secret-42'",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 			httpClient, err := http.New(address, http.WithRetryPolicy(http.RetryPolicy{
@@ -91,12 +145,15 @@ func newRegisterCommand() *cobra.Command {
 
 // newDeviceCommand создаёт команду 'device' для добавления нового устройства
 func newDeviceCommand() *cobra.Command {
-	var address, username, password, publicKey string
+	var address, username, password string
 
 	cmd := &cobra.Command{
-		Use:     "device",
-		Short:   "Добавление нового устройства для пользователя",
-		Example: "bil-message-client device -a http://localhost:8080 -u testuser -p secret -k myPublicKey",
+		Use:   "device",
+		Short: "Добавление нового устройства для пользователя",
+		Long: "Генерирует ключевую пару X25519 устройства, сохраняет приватный ключ локально и регистрирует на сервере публичный ключ " +
+			"и производный от него ключ подписи Ed25519. Если сервер требует подтверждения устройств, новое устройство " +
+			"ожидает подтверждения командой device-approve с уже доверенного устройства.",
+		Example: "bil-message-client device -a http://localhost:8080 -u testuser -p secret",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
 			httpClient, err := http.New(address, http.WithRetryPolicy(http.RetryPolicy{
@@ -108,11 +165,29 @@ func newDeviceCommand() *cobra.Command {
 				return err
 			}
 
-			deviceUUID, err := client.AddDevice(ctx, httpClient, username, password, publicKey)
+			priv, err := e2ee.GenerateDeviceKey()
+			if err != nil {
+				return fmt.Errorf("не удалось сгенерировать ключ устройства: %w", err)
+			}
+
+			signingKey, err := e2ee.DeriveSigningKey(priv)
+			if err != nil {
+				return fmt.Errorf("не удалось получить ключ подписи устройства: %w", err)
+			}
+
+			deviceUUID, pending, err := client.AddDevice(
+				ctx, httpClient, username, password,
+				e2ee.EncodePublicKey(priv.PublicKey()),
+				e2ee.EncodeSigningKey(signingKey.Public().(ed25519.PublicKey)),
+			)
 			if err != nil {
 				return fmt.Errorf("не удалось добавить устройство: %w", err)
 			}
 
+			if err := newKeyStore().Save(priv); err != nil {
+				return fmt.Errorf("не удалось сохранить ключ устройства: %w", err)
+			}
+
 			configDir := os.ExpandEnv("$HOME/.config")
 			if err := os.MkdirAll(configDir, 0o755); err != nil {
 				return fmt.Errorf("не удалось создать директорию конфигурации: %w", err)
@@ -124,6 +199,9 @@ func newDeviceCommand() *cobra.Command {
 			}
 
 			cmd.Println(deviceUUID.String())
+			if pending {
+				cmd.Println("Устройство ожидает подтверждения: выполните device-approve -d " + deviceUUID.String() + " на доверенном устройстве")
+			}
 			return nil
 		},
 	}
@@ -131,7 +209,6 @@ func newDeviceCommand() *cobra.Command {
 	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
 	cmd.Flags().StringVarP(&username, "username", "u", "user", "Имя пользователя")
 	cmd.Flags().StringVarP(&password, "password", "p", "password", "Пароль пользователя")
-	cmd.Flags().StringVarP(&publicKey, "public-key", "k", "key", "Публичный ключ устройства")
 	return cmd
 }
 
@@ -167,11 +244,21 @@ func newLoginCommand() *cobra.Command {
 				return fmt.Errorf("некорректный uuid устройства в файле: %w", err)
 			}
 
-			token, err := client.Login(ctx, httpClient, username, password, deviceUUID)
+			token, refreshToken, err := client.Login(ctx, httpClient, username, password, deviceUUID)
+			if errors.Is(err, client.ErrDevicePendingApproval) {
+				return fmt.Errorf("устройство %s ещё не подтверждено: выполните device-approve на доверенном устройстве", deviceUUID)
+			}
+			if errors.Is(err, client.ErrTooManyAttempts) {
+				return fmt.Errorf("слишком много неудачных попыток входа, повторите позже: %w", err)
+			}
 			if err != nil {
 				return fmt.Errorf("не удалось выполнить вход: %w", err)
 			}
 
+			if err := newTokenStore().Save(token, refreshToken); err != nil {
+				return fmt.Errorf("не удалось сохранить токены: %w", err)
+			}
+
 			cmd.Println(token)
 			return nil
 		},
@@ -183,199 +270,1407 @@ func newLoginCommand() *cobra.Command {
 	return cmd
 }
 
-// newVersionCommand создаёт команду 'version' для вывода информации о версии клиента
-func newVersionCommand() *cobra.Command {
-	return &cobra.Command{
-		Use:     "version",
-		Short:   "Показать информацию о версии клиента",
-		Example: "bil-message-client version",
-		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Println("bil-message-client")
-			cmd.Printf("Версия: %s\n", buildVersion)
-			cmd.Printf("Коммит: %s\n", buildCommit)
-			cmd.Printf("Дата сборки: %s\n", buildDate)
+// newLogoutCommand создаёт команду 'logout' для выхода из аккаунта и отзыва токена
+func newLogoutCommand() *cobra.Command {
+	var address, token, scope string
+
+	cmd := &cobra.Command{
+		Use:     "logout",
+		Short:   "Выход из аккаунта",
+		Long:    "Отзывает текущий токен. С --scope device отзываются все сессии устройства, с --scope user — все сессии пользователя.",
+		Example: "bil-message-client logout -a http://localhost:8080 -t <jwt-token> --scope device",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			if err := client.Logout(ctx, httpClient, token, scope); err != nil {
+				return fmt.Errorf("не удалось выйти из аккаунта: %w", err)
+			}
+
+			// Сохранённые токены относятся к завершённой сессии
+			if token == "" {
+				if err := newTokenStore().Clear(); err != nil {
+					return err
+				}
+			}
+
+			cmd.Println("Выход выполнен")
+			return nil
 		},
 	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&scope, "scope", "s", "token", "Область выхода: token, device или user")
+
+	return cmd
 }
 
-// Создание новой комнаты
-func newCreateChatCommand() *cobra.Command {
+// newChangePasswordCommand создаёт команду 'password' для смены пароля
+func newChangePasswordCommand() *cobra.Command {
+	var address, token, currentPassword, newPassword string
+
+	cmd := &cobra.Command{
+		Use:   "password",
+		Short: "Сменить пароль",
+		Long: "Меняет пароль текущего пользователя. Все сессии пользователя на всех устройствах завершаются, " +
+			"а для текущего устройства сохраняются токены новой сессии.",
+		Example: "bil-message-client password -a http://localhost:8080 --current-password 'Secret-42' --new-password 'N3w-secret'",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			newToken, refreshToken, err := client.ChangePassword(ctx, httpClient, token, currentPassword, newPassword)
+			if err != nil {
+				return fmt.Errorf("не удалось сменить пароль: %w", err)
+			}
+
+			if err := newTokenStore().Save(newToken, refreshToken); err != nil {
+				return fmt.Errorf("не удалось сохранить токены: %w", err)
+			}
+
+			cmd.Println("Пароль изменён, сессии на остальных устройствах завершены")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVar(&currentPassword, "current-password", "", "Текущий пароль")
+	cmd.Flags().StringVar(&newPassword, "new-password", "", "Новый пароль")
+	cmd.MarkFlagRequired("current-password")
+	cmd.MarkFlagRequired("new-password")
+
+	return cmd
+}
+
+// newListDevicesCommand создаёт команду 'devices' для просмотра активных устройств пользователя
+func newListDevicesCommand() *cobra.Command {
 	var address, token string
 
 	cmd := &cobra.Command{
-		Use:     "create",
-		Short:   "Создать новую комнату",
-		Example: "bil-message-client create -a http://localhost:8080 -t <jwt-token>",
+		Use:     "devices",
+		Short:   "Показать устройства пользователя",
+		Example: "bil-message-client devices -a http://localhost:8080 -t <jwt-token>",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
-			httpClient, err := http.New(address)
+			httpClient, err := newAuthorizedHTTPClient(address)
 			if err != nil {
 				return err
 			}
 
-			roomUUID, err := client.CreateChat(ctx, httpClient, token)
+			_, devices, err := client.ListDevices(ctx, httpClient, token)
 			if err != nil {
-				return fmt.Errorf("не удалось создать чат: %w", err)
+				return fmt.Errorf("не удалось получить список устройств: %w", err)
 			}
 
-			cmd.Println(roomUUID.String())
+			for _, device := range devices {
+				name := device.Name
+				if name == "" {
+					name = "(без названия)"
+				}
+				current := ""
+				if device.Current {
+					current = "\t(текущее)"
+				}
+				status := "активно"
+				if device.Status == client.DeviceStatusPending {
+					status = "ожидает подтверждения"
+				}
+				cmd.Printf("%s\t%s\t%s\tотпечаток: %s\tдобавлено: %s%s\n",
+					device.DeviceUUID, name, status, fingerprint(device.PublicKey), device.CreatedAt.Local().Format(time.DateTime), current)
+			}
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
-	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации")
-	cmd.MarkFlagRequired("token") // обязательно
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
 
 	return cmd
 }
 
-// Удаление комнаты
-func newRemoveChatCommand() *cobra.Command {
-	var address, token, roomUUID string
+// newRenameDeviceCommand создаёт команду 'device-rename' для изменения названия устройства
+func newRenameDeviceCommand() *cobra.Command {
+	var address, token, deviceUUID, name string
 
 	cmd := &cobra.Command{
-		Use:     "remove",
-		Short:   "Удалить комнату",
-		Example: "bil-message-client remove -a http://localhost:8080 -t <jwt-token> -c <room-uuid>",
+		Use:     "device-rename",
+		Short:   "Изменить название устройства",
+		Example: "bil-message-client device-rename -a http://localhost:8080 -t <jwt-token> -d <device-uuid> --name \"Рабочий ноутбук\"",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
-			httpClient, err := http.New(address)
+			httpClient, err := newAuthorizedHTTPClient(address)
 			if err != nil {
 				return err
 			}
 
-			uuidRoom, err := uuid.Parse(roomUUID)
+			uuidDevice, err := uuid.Parse(deviceUUID)
 			if err != nil {
-				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+				return fmt.Errorf("некорректный UUID устройства: %w", err)
 			}
 
-			if err := client.RemoveChat(ctx, httpClient, token, uuidRoom); err != nil {
-				return fmt.Errorf("не удалось удалить чат: %w", err)
+			if err := client.RenameDevice(ctx, httpClient, token, uuidDevice, name); err != nil {
+				return fmt.Errorf("не удалось переименовать устройство: %w", err)
 			}
 
-			cmd.Println("Комната успешно удалена")
+			cmd.Println("Устройство переименовано")
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
-	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации")
-	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
-	cmd.MarkFlagRequired("token")
-	cmd.MarkFlagRequired("room-uuid")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&deviceUUID, "device-uuid", "d", "", "UUID устройства")
+	cmd.Flags().StringVar(&name, "name", "", "Новое название устройства")
+	cmd.MarkFlagRequired("device-uuid")
+	cmd.MarkFlagRequired("name")
 
 	return cmd
 }
 
-// Добавление пользователя в комнату
-func newAddChatMemberCommand() *cobra.Command {
-	var address, token, roomUUID, memberUUID string
+// newRevokeDeviceCommand создаёт команду 'device-revoke' для отзыва устройства
+func newRevokeDeviceCommand() *cobra.Command {
+	var address, token, deviceUUID string
 
 	cmd := &cobra.Command{
-		Use:     "add-member",
-		Short:   "Добавить пользователя в комнату",
-		Example: "bil-message-client add-member -a http://localhost:8080 -t <jwt-token> -c <room-uuid> -m <user-uuid>",
+		Use:     "device-revoke",
+		Short:   "Отозвать устройство",
+		Long:    "Отзывает устройство: вход с него становится невозможен, его сессии завершаются, а ключи комнат для него удаляются.",
+		Example: "bil-message-client device-revoke -a http://localhost:8080 -t <jwt-token> -d <device-uuid>",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
-			httpClient, err := http.New(address)
+			httpClient, err := newAuthorizedHTTPClient(address)
 			if err != nil {
 				return err
 			}
 
-			uuidRoom, err := uuid.Parse(roomUUID)
+			uuidDevice, err := uuid.Parse(deviceUUID)
 			if err != nil {
-				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+				return fmt.Errorf("некорректный UUID устройства: %w", err)
 			}
 
-			uuidMember, err := uuid.Parse(memberUUID)
+			if err := client.RevokeDevice(ctx, httpClient, token, uuidDevice); err != nil {
+				return fmt.Errorf("не удалось отозвать устройство: %w", err)
+			}
+
+			cmd.Println("Устройство отозвано")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&deviceUUID, "device-uuid", "d", "", "UUID устройства")
+	cmd.MarkFlagRequired("device-uuid")
+
+	return cmd
+}
+
+// newApproveDeviceCommand создаёт команду 'device-approve' для подтверждения нового устройства
+func newApproveDeviceCommand() *cobra.Command {
+	var address, token, deviceUUID string
+
+	cmd := &cobra.Command{
+		Use:   "device-approve",
+		Short: "Подтвердить новое устройство",
+		Long: "Подписывает UUID и ключи ожидающего устройства ключом подписи текущего устройства и отправляет подпись на сервер. " +
+			"Перед подтверждением сверьте UUID и отпечаток ключа устройства с выводом команд device и devices на новом устройстве. " +
+			"Если текущее устройство добавлено до появления ключей подписи, команда сначала регистрирует его ключ подписи.",
+		Example: "bil-message-client device-approve -a http://localhost:8080 -t <jwt-token> -d <device-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
 			if err != nil {
-				return fmt.Errorf("некорректный UUID пользователя: %w", err)
+				return err
+			}
+
+			uuidDevice, err := uuid.Parse(deviceUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID устройства: %w", err)
+			}
+
+			priv, err := newKeyStore().Load()
+			if err != nil {
+				return fmt.Errorf("не удалось загрузить ключ устройства: %w", err)
+			}
+			signingKey, err := e2ee.DeriveSigningKey(priv)
+			if err != nil {
+				return fmt.Errorf("не удалось получить ключ подписи устройства: %w", err)
+			}
+
+			_, devices, err := client.ListDevices(ctx, httpClient, token)
+			if err != nil {
+				return fmt.Errorf("не удалось получить список устройств: %w", err)
+			}
+
+			var target, current *client.Device
+			for i := range devices {
+				if devices[i].DeviceUUID == uuidDevice {
+					target = &devices[i]
+				}
+				if devices[i].Current {
+					current = &devices[i]
+				}
+			}
+			if target == nil {
+				return fmt.Errorf("устройство %s не найдено", uuidDevice)
+			}
+			if target.Status != client.DeviceStatusPending {
+				return fmt.Errorf("устройство %s не ожидает подтверждения", uuidDevice)
 			}
 
-			if err := client.AddChatMember(ctx, httpClient, token, uuidRoom, uuidMember); err != nil {
-				return fmt.Errorf("не удалось добавить пользователя в чат: %w", err)
+			if err := ensureSigningKey(ctx, httpClient, token, signingKey, current); err != nil {
+				return err
+			}
+
+			signature := e2ee.SignDeviceApproval(signingKey, target.DeviceUUID, target.PublicKey, target.SigningKey)
+			if err := client.ApproveDevice(ctx, httpClient, token, uuidDevice, signature); err != nil {
+				return fmt.Errorf("не удалось подтвердить устройство: %w", err)
 			}
 
-			cmd.Println("Пользователь успешно добавлен в комнату")
+			cmd.Printf("Устройство подтверждено, отпечаток ключа: %s\n", fingerprint(target.PublicKey))
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
-	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации")
-	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
-	cmd.Flags().StringVarP(&memberUUID, "member-uuid", "m", "", "UUID пользователя для добавления")
-	cmd.MarkFlagRequired("token")
-	cmd.MarkFlagRequired("room-uuid")
-	cmd.MarkFlagRequired("member-uuid")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&deviceUUID, "device-uuid", "d", "", "UUID подтверждаемого устройства")
+	cmd.MarkFlagRequired("device-uuid")
 
 	return cmd
 }
 
-// Удаление пользователя из комнаты
-func newRemoveChatMemberCommand() *cobra.Command {
-	var address, token, roomUUID, memberUUID string
+// newVerifyCommand создаёт команду 'verify' для сверки номера безопасности с собеседником
+func newVerifyCommand() *cobra.Command {
+	var address, token, username string
+	var confirm bool
 
 	cmd := &cobra.Command{
-		Use:     "remove-member",
-		Short:   "Удалить пользователя из комнаты",
-		Example: "bil-message-client remove-member -a http://localhost:8080 -t <jwt-token> -c <room-uuid> -m <user-uuid>",
+		Use:   "verify",
+		Short: "Сверить номер безопасности с собеседником",
+		Long: `Вычисляет номер безопасности по публичным ключам активных устройств текущего пользователя и собеседника
+и выводит отпечатки ключей устройств собеседника. Сверьте номер с собеседником по независимому каналу
+(лично или по телефону): совпадение означает, что сервер не подменил ключи. С флагом --confirm
+текущий набор ключей собеседника записывается как проверенный; команда ws предупредит, если он изменится.`,
+		Example: "bil-message-client verify -a http://localhost:8080 -t <jwt-token> -u <username> --confirm",
 		RunE: func(cmd *cobra.Command, args []string) error {
 			ctx := context.Background()
-			httpClient, err := http.New(address)
+			httpClient, err := newAuthorizedHTTPClient(address)
 			if err != nil {
 				return err
 			}
 
-			uuidRoom, err := uuid.Parse(roomUUID)
+			selfUUID, selfDevices, err := client.ListDevices(ctx, httpClient, token)
 			if err != nil {
-				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+				return fmt.Errorf("не удалось получить список устройств: %w", err)
+			}
+			var selfKeys []string
+			for _, device := range selfDevices {
+				if device.Status != client.DeviceStatusPending {
+					selfKeys = append(selfKeys, device.PublicKey)
+				}
 			}
 
-			uuidMember, err := uuid.Parse(memberUUID)
+			// ключ этого устройства должен входить в набор, иначе сервер подменил ключи текущего пользователя
+			if priv, err := newKeyStore().Load(); err == nil {
+				if !slices.Contains(selfKeys, e2ee.EncodePublicKey(priv.PublicKey())) {
+					return errors.New("сервер не вернул публичный ключ этого устройства среди активных: номер безопасности недостоверен")
+				}
+			}
+
+			contact, err := client.ListUserDevices(ctx, httpClient, token, username)
 			if err != nil {
-				return fmt.Errorf("некорректный UUID пользователя: %w", err)
+				return fmt.Errorf("не удалось получить устройства пользователя: %w", err)
+			}
+			if contact.UserUUID == selfUUID {
+				return errors.New("нельзя сверить номер безопасности с самим собой")
+			}
+			if len(contact.Devices) == 0 {
+				return fmt.Errorf("у пользователя %s нет активных устройств", contact.Username)
 			}
 
-			if err := client.RemoveChatMember(ctx, httpClient, token, uuidRoom, uuidMember); err != nil {
-				return fmt.Errorf("не удалось удалить пользователя из чата: %w", err)
+			number, err := e2ee.SafetyNumber(selfUUID, selfKeys, contact.UserUUID, contact.PublicKeys())
+			if err != nil {
+				return fmt.Errorf("не удалось вычислить номер безопасности: %w", err)
 			}
 
-			cmd.Println("Пользователь успешно удалён из комнаты")
+			groups := strings.Fields(number)
+			cmd.Printf("Номер безопасности с %s:\n", contact.Username)
+			for i := 0; i < len(groups); i += 4 {
+				cmd.Printf("  %s\n", strings.Join(groups[i:min(i+4, len(groups))], " "))
+			}
+			cmd.Printf("Устройства %s:\n", contact.Username)
+			for _, device := range contact.Devices {
+				cmd.Printf("  %s\tотпечаток: %s\n", device.DeviceUUID, fingerprint(device.PublicKey))
+			}
+
+			store := newVerifiedKeyStore()
+			previous, err := store.Get(contact.UserUUID)
+			if err != nil {
+				return fmt.Errorf("не удалось прочитать проверенные ключи: %w", err)
+			}
+			if previous != nil {
+				verifiedAt := previous.VerifiedAt.Local().Format(time.DateTime)
+				if client.KeysEqual(previous.PublicKeys, contact.PublicKeys()) {
+					cmd.Printf("Ключи %s проверены %s и с тех пор не изменились\n", contact.Username, verifiedAt)
+				} else {
+					cmd.Printf("ВНИМАНИЕ: ключи %s изменились после проверки %s\n", contact.Username, verifiedAt)
+				}
+			}
+
+			if !confirm {
+				cmd.Printf("Сверьте номер с собеседником и выполните verify -u %s --confirm\n", contact.Username)
+				return nil
+			}
+
+			if err := store.Save(client.VerifiedContact{
+				UserUUID:    contact.UserUUID,
+				Username:    contact.Username,
+				PublicKeys:  contact.PublicKeys(),
+				SigningKeys: contact.SigningKeys(),
+				VerifiedAt:  time.Now().UTC(),
+			}); err != nil {
+				return fmt.Errorf("не удалось сохранить проверенные ключи: %w", err)
+			}
+
+			cmd.Printf("Ключи %s отмечены как проверенные\n", contact.Username)
 			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
-	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации")
-	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
-	cmd.Flags().StringVarP(&memberUUID, "member-uuid", "m", "", "UUID пользователя для удаления")
-	cmd.MarkFlagRequired("token")
-	cmd.MarkFlagRequired("room-uuid")
-	cmd.MarkFlagRequired("member-uuid")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&username, "username", "u", "", "Имя собеседника")
+	cmd.Flags().BoolVar(&confirm, "confirm", false, "Записать текущие ключи собеседника как проверенные")
+	cmd.MarkFlagRequired("username")
 
 	return cmd
 }
 
-// newWebSocketCommand создаёт команду для подключения к WebSocket чата
-func newWebSocketCommand() *cobra.Command {
-	var address, token, roomUUID string
+// ensureSigningKey регистрирует ключ подписи текущего устройства current, если оно добавлено до появления ключей подписи
+func ensureSigningKey(ctx context.Context, httpClient *resty.Client, token string, signingKey ed25519.PrivateKey, current *client.Device) error {
+	if current == nil || current.SigningKey != "" {
+		return nil
+	}
+
+	signingPub := e2ee.EncodeSigningKey(signingKey.Public().(ed25519.PublicKey))
+	selfSignature := e2ee.SignDeviceApproval(signingKey, current.DeviceUUID, current.PublicKey, signingPub)
+	if err := client.SetSigningKey(ctx, httpClient, token, signingPub, selfSignature); err != nil {
+		return fmt.Errorf("не удалось зарегистрировать ключ подписи устройства: %w", err)
+	}
+	return nil
+}
+
+// newIssuerWarning возвращает наблюдателя ключей комнаты, который громко предупреждает о ключе,
+// выпущенном не устройством текущего пользователя и не устройством собеседника, проверенного командой verify,
+// а также о ключе прошлой эпохи без подписи. ownKeys — ключи подписи устройств текущего пользователя.
+func newIssuerWarning(cmd *cobra.Command, verified *client.FileVerifiedKeyStore, ownKeys []string) func(int64, client.RoomDevice) {
+	return func(keyEpoch int64, issuer client.RoomDevice) {
+		if issuer.DeviceUUID == uuid.Nil {
+			cmd.PrintErrf("\n!!! ВНИМАНИЕ: ключ комнаты эпохи %d загружен без подписи, его выпуск участником комнаты не подтверждён !!!\n\n", keyEpoch)
+			return
+		}
+		if slices.Contains(ownKeys, issuer.SigningKey) {
+			return
+		}
+
+		trusted, err := verified.Trusted(issuer)
+		if err != nil {
+			cmd.PrintErrln("Не удалось сверить выпустившее ключ комнаты устройство с проверенными ключами:", err)
+			return
+		}
+		if !trusted {
+			cmd.PrintErrf("\n!!! ВНИМАНИЕ: ключ комнаты эпохи %d выпущен устройством %s пользователя %s, ключи которого не проверены !!!\n"+
+				"!!! Сообщения этой эпохи может читать тот, кто выпустил ключ. Сверьте номер безопасности с участниками: verify -u <username> !!!\n\n",
+				keyEpoch, issuer.DeviceUUID, issuer.UserUUID)
+		}
+	}
+}
+
+// ownSigningKeys возвращает ключи подписи подтверждённых устройств текущего пользователя и ключ подписи этого устройства
+func ownSigningKeys(devices []client.Device, signingKey ed25519.PrivateKey) []string {
+	keys := []string{e2ee.EncodeSigningKey(signingKey.Public().(ed25519.PublicKey))}
+	for _, d := range devices {
+		if d.Status != client.DeviceStatusPending && d.SigningKey != "" {
+			keys = append(keys, d.SigningKey)
+		}
+	}
+	return keys
+}
+
+// fingerprint возвращает отпечаток публичного ключа, вычисленный на клиенте, или прочерк для ключа неизвестного формата
+func fingerprint(publicKey string) string {
+	fp, err := e2ee.Fingerprint(publicKey)
+	if err != nil {
+		return "—"
+	}
+	return fp
+}
+
+// newListLockoutsCommand создаёт команду 'lockouts' для просмотра блокировок входа администратором сервера
+func newListLockoutsCommand() *cobra.Command {
+	var address, adminToken string
+	var limit int
 
 	cmd := &cobra.Command{
-		Use:     "ws",
-		Short:   "Подключиться к WebSocket чата",
-		Example: "bil-message-client ws -a http://localhost:8080 -t <jwt-token> -c <room-uuid>",
+		Use:     "lockouts",
+		Short:   "Показать последние блокировки входа (для администратора сервера)",
+		Long:    "Показывает блокировки входа по имени пользователя и IP-адресу после серии неудачных попыток. Требует токен, заданный серверу флагом --admin-token.",
+		Example: "bil-message-client lockouts -a http://localhost:8080 --admin-token <admin-token> -n 20",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := http.New(address)
+			if err != nil {
+				return err
+			}
+
+			lockouts, err := client.ListLockouts(ctx, httpClient, adminToken, limit)
+			if err != nil {
+				return fmt.Errorf("не удалось получить блокировки входа: %w", err)
+			}
+
+			for _, l := range lockouts {
+				scope := "пользователь"
+				if l.Scope == "ip" {
+					scope = "IP-адрес"
+				}
+				cmd.Printf("%s\t%s %s\tнеудачных попыток: %d\tдо %s\n",
+					l.LockedAt.Local().Format(time.DateTime), scope, l.Key, l.Failures, l.LockedUntil.Local().Format(time.DateTime))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&adminToken, "admin-token", "", "", "Токен администратора сервера")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 0, "Число записей (по умолчанию — значение сервера)")
+	cmd.MarkFlagRequired("admin-token")
+
+	return cmd
+}
+
+// newVersionCommand создаёт команду 'version' для вывода информации о версии клиента
+func newVersionCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:     "version",
+		Short:   "Показать информацию о версии клиента",
+		Example: "bil-message-client version",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.Println("bil-message-client")
+			cmd.Printf("Версия: %s\n", buildVersion)
+			cmd.Printf("Коммит: %s\n", buildCommit)
+			cmd.Printf("Дата сборки: %s\n", buildDate)
+		},
+	}
+}
+
+// Создание новой комнаты
+func newCreateChatCommand() *cobra.Command {
+	var address, token, name, topic, peerUUID string
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Создать новую комнату или открыть личную переписку",
+		Example: "bil-message-client create -a http://localhost:8080 -t <jwt-token> --name Команда --topic \"Планы на релиз\"\n" +
+			"bil-message-client create -a http://localhost:8080 -t <jwt-token> --direct <user-uuid>",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return client.ConnectWebSocket(fmt.Sprintf("%s/chat/%s/ws", address, roomUUID), token)
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			var roomUUID uuid.UUID
+			if peerUUID != "" {
+				if name != "" || topic != "" {
+					return fmt.Errorf("название и тема задаются только для групповой комнаты")
+				}
+				uuidPeer, err := uuid.Parse(peerUUID)
+				if err != nil {
+					return fmt.Errorf("некорректный UUID собеседника: %w", err)
+				}
+				roomUUID, err = client.CreateDirectChat(ctx, httpClient, token, uuidPeer)
+				if err != nil {
+					return fmt.Errorf("не удалось открыть личную переписку: %w", err)
+				}
+			} else {
+				roomUUID, err = client.CreateChat(ctx, httpClient, token, name, topic)
+				if err != nil {
+					return fmt.Errorf("не удалось создать чат: %w", err)
+				}
+			}
+
+			cmd.Println(roomUUID.String())
+			return nil
 		},
 	}
 
 	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
-	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации")
-	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
-	cmd.MarkFlagRequired("token")
-	cmd.MarkFlagRequired("room-uuid")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&name, "name", "", "", "Название комнаты")
+	cmd.Flags().StringVarP(&topic, "topic", "", "", "Тема комнаты")
+	cmd.Flags().StringVarP(&peerUUID, "direct", "", "", "UUID пользователя для личной переписки")
 
 	return cmd
 }
+
+// Изменение названия и темы комнаты
+func newEditChatCommand() *cobra.Command {
+	var address, token, roomUUID, name, topic string
+
+	cmd := &cobra.Command{
+		Use:     "edit",
+		Short:   "Изменить название и тему комнаты",
+		Example: "bil-message-client edit -a http://localhost:8080 -t <jwt-token> -c <room-uuid> --name Команда --topic \"Планы на релиз\"",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			var newName, newTopic *string
+			if cmd.Flags().Changed("name") {
+				newName = &name
+			}
+			if cmd.Flags().Changed("topic") {
+				newTopic = &topic
+			}
+			if newName == nil && newTopic == nil {
+				return fmt.Errorf("укажите --name или --topic")
+			}
+
+			if err := client.UpdateChat(ctx, httpClient, token, uuidRoom, newName, newTopic); err != nil {
+				return fmt.Errorf("не удалось изменить комнату: %w", err)
+			}
+
+			cmd.Println("Комната успешно изменена")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.Flags().StringVarP(&name, "name", "", "", "Новое название комнаты")
+	cmd.Flags().StringVarP(&topic, "topic", "", "", "Новая тема комнаты")
+	cmd.MarkFlagRequired("room-uuid")
+
+	return cmd
+}
+
+// Удаление комнаты
+func newRemoveChatCommand() *cobra.Command {
+	var address, token, roomUUID string
+
+	cmd := &cobra.Command{
+		Use:     "remove",
+		Short:   "Удалить комнату",
+		Example: "bil-message-client remove -a http://localhost:8080 -t <jwt-token> -c <room-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			if err := client.RemoveChat(ctx, httpClient, token, uuidRoom); err != nil {
+				return fmt.Errorf("не удалось удалить чат: %w", err)
+			}
+
+			cmd.Println("Комната успешно удалена")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.MarkFlagRequired("room-uuid")
+
+	return cmd
+}
+
+// Приглашение пользователя в комнату
+func newInviteChatMemberCommand() *cobra.Command {
+	var address, token, roomUUID, memberUUID, username string
+
+	cmd := &cobra.Command{
+		Use:   "invite",
+		Short: "Пригласить пользователя в комнату",
+		Example: "bil-message-client invite -a http://localhost:8080 -t <jwt-token> -c <room-uuid> -m <user-uuid>\n" +
+			"bil-message-client invite -a http://localhost:8080 -t <jwt-token> -c <room-uuid> -u <username>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			var uuidMember uuid.UUID
+			if username != "" {
+				user, err := client.FindUser(ctx, httpClient, token, username)
+				if err != nil {
+					return fmt.Errorf("не удалось найти пользователя: %w", err)
+				}
+				uuidMember = user.UserUUID
+			} else {
+				uuidMember, err = uuid.Parse(memberUUID)
+				if err != nil {
+					return fmt.Errorf("некорректный UUID пользователя: %w", err)
+				}
+			}
+
+			invite, err := client.InviteChatMember(ctx, httpClient, token, uuidRoom, uuidMember)
+			if err != nil {
+				return fmt.Errorf("не удалось пригласить пользователя: %w", err)
+			}
+
+			cmd.Println(invite.InviteUUID.String())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.Flags().StringVarP(&memberUUID, "member-uuid", "m", "", "UUID приглашаемого пользователя")
+	cmd.Flags().StringVarP(&username, "username", "u", "", "Имя приглашаемого пользователя")
+	cmd.MarkFlagRequired("room-uuid")
+	cmd.MarkFlagsOneRequired("member-uuid", "username")
+	cmd.MarkFlagsMutuallyExclusive("member-uuid", "username")
+
+	return cmd
+}
+
+// Список входящих приглашений
+func newListInvitesCommand() *cobra.Command {
+	var address, token string
+
+	cmd := &cobra.Command{
+		Use:     "invites",
+		Short:   "Показать входящие приглашения в комнаты",
+		Example: "bil-message-client invites -a http://localhost:8080 -t <jwt-token>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			invites, err := client.ListInvites(ctx, httpClient, token)
+			if err != nil {
+				return fmt.Errorf("не удалось получить список приглашений: %w", err)
+			}
+
+			if len(invites) == 0 {
+				cmd.Println("Нет ожидающих приглашений")
+				return nil
+			}
+			for _, invite := range invites {
+				name := invite.RoomName
+				if name == "" {
+					name = "(без названия)"
+				}
+				cmd.Printf("%s\tкомната: %s (%s)\tот: %s\t%s\n",
+					invite.InviteUUID, name, invite.RoomUUID, invite.InviterUsername, invite.CreatedAt.Local().Format(time.DateTime))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+
+	return cmd
+}
+
+// Принятие приглашения
+func newAcceptInviteCommand() *cobra.Command {
+	var address, token, inviteUUID string
+
+	cmd := &cobra.Command{
+		Use:     "accept",
+		Short:   "Принять приглашение в комнату",
+		Example: "bil-message-client accept -a http://localhost:8080 -t <jwt-token> -i <invite-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidInvite, err := uuid.Parse(inviteUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID приглашения: %w", err)
+			}
+
+			roomUUID, err := client.AcceptInvite(ctx, httpClient, token, uuidInvite)
+			if err != nil {
+				return fmt.Errorf("не удалось принять приглашение: %w", err)
+			}
+
+			cmd.Println(roomUUID.String())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&inviteUUID, "invite-uuid", "i", "", "UUID приглашения")
+	cmd.MarkFlagRequired("invite-uuid")
+
+	return cmd
+}
+
+// Отклонение приглашения
+func newDeclineInviteCommand() *cobra.Command {
+	var address, token, inviteUUID string
+
+	cmd := &cobra.Command{
+		Use:     "decline",
+		Short:   "Отклонить приглашение в комнату",
+		Example: "bil-message-client decline -a http://localhost:8080 -t <jwt-token> -i <invite-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidInvite, err := uuid.Parse(inviteUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID приглашения: %w", err)
+			}
+
+			if err := client.DeclineInvite(ctx, httpClient, token, uuidInvite); err != nil {
+				return fmt.Errorf("не удалось отклонить приглашение: %w", err)
+			}
+
+			cmd.Println("Приглашение отклонено")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&inviteUUID, "invite-uuid", "i", "", "UUID приглашения")
+	cmd.MarkFlagRequired("invite-uuid")
+
+	return cmd
+}
+
+// Создание ссылки-приглашения
+func newCreateInviteLinkCommand() *cobra.Command {
+	var address, token, roomUUID string
+	var expiresIn time.Duration
+	var maxUses int
+
+	cmd := &cobra.Command{
+		Use:     "invite-link",
+		Short:   "Создать ссылку-приглашение в комнату",
+		Example: "bil-message-client invite-link -a http://localhost:8080 -t <jwt-token> -c <room-uuid> --expires-in 24h --max-uses 10",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			link, err := client.CreateInviteLink(ctx, httpClient, token, uuidRoom, expiresIn, maxUses)
+			if err != nil {
+				return fmt.Errorf("не удалось создать ссылку-приглашение: %w", err)
+			}
+
+			cmd.Println(link.LinkUUID.String())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.Flags().DurationVar(&expiresIn, "expires-in", 0, "Срок действия ссылки (по умолчанию 24h, не более 720h)")
+	cmd.Flags().IntVar(&maxUses, "max-uses", 0, "Максимальное число использований (0 — без ограничения)")
+	cmd.MarkFlagRequired("room-uuid")
+
+	return cmd
+}
+
+// Вступление в комнату по ссылке-приглашению
+func newJoinByInviteLinkCommand() *cobra.Command {
+	var address, token, linkUUID string
+
+	cmd := &cobra.Command{
+		Use:     "join",
+		Short:   "Вступить в комнату по ссылке-приглашению",
+		Example: "bil-message-client join -a http://localhost:8080 -t <jwt-token> -l <link-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidLink, err := uuid.Parse(linkUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID ссылки: %w", err)
+			}
+
+			roomUUID, err := client.JoinByInviteLink(ctx, httpClient, token, uuidLink)
+			if err != nil {
+				return fmt.Errorf("не удалось вступить в комнату: %w", err)
+			}
+
+			cmd.Println(roomUUID.String())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&linkUUID, "link-uuid", "l", "", "UUID ссылки-приглашения")
+	cmd.MarkFlagRequired("link-uuid")
+
+	return cmd
+}
+
+// newRevokeInviteLinkCommand создаёт команду для отзыва ссылки-приглашения
+func newRevokeInviteLinkCommand() *cobra.Command {
+	var address, token, linkUUID string
+
+	cmd := &cobra.Command{
+		Use:     "invite-link-revoke",
+		Short:   "Отозвать ссылку-приглашение",
+		Example: "bil-message-client invite-link-revoke -a http://localhost:8080 -t <jwt-token> -l <link-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidLink, err := uuid.Parse(linkUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID ссылки: %w", err)
+			}
+
+			if err := client.RevokeInviteLink(ctx, httpClient, token, uuidLink); err != nil {
+				return fmt.Errorf("не удалось отозвать ссылку-приглашение: %w", err)
+			}
+
+			cmd.Println("Ссылка-приглашение отозвана")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&linkUUID, "link-uuid", "l", "", "UUID ссылки-приглашения")
+	cmd.MarkFlagRequired("link-uuid")
+
+	return cmd
+}
+
+// Поиск пользователя по имени
+func newFindUserCommand() *cobra.Command {
+	var address, token, username string
+
+	cmd := &cobra.Command{
+		Use:     "user",
+		Short:   "Найти пользователя по имени и показать его UUID",
+		Example: "bil-message-client user -a http://localhost:8080 -t <jwt-token> -u <username>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			user, err := client.FindUser(ctx, httpClient, token, username)
+			if err != nil {
+				return fmt.Errorf("не удалось найти пользователя: %w", err)
+			}
+
+			cmd.Println(user.UserUUID.String())
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&username, "username", "u", "", "Имя пользователя")
+	cmd.MarkFlagRequired("username")
+
+	return cmd
+}
+
+// Удаление пользователя из комнаты
+func newRemoveChatMemberCommand() *cobra.Command {
+	var address, token, roomUUID, memberUUID string
+
+	cmd := &cobra.Command{
+		Use:     "remove-member",
+		Short:   "Удалить пользователя из комнаты",
+		Example: "bil-message-client remove-member -a http://localhost:8080 -t <jwt-token> -c <room-uuid> -m <user-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			uuidMember, err := uuid.Parse(memberUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID пользователя: %w", err)
+			}
+
+			if err := client.RemoveChatMember(ctx, httpClient, token, uuidRoom, uuidMember); err != nil {
+				return fmt.Errorf("не удалось удалить пользователя из чата: %w", err)
+			}
+
+			cmd.Println("Пользователь успешно удалён из комнаты")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.Flags().StringVarP(&memberUUID, "member-uuid", "m", "", "UUID пользователя для удаления")
+	cmd.MarkFlagRequired("room-uuid")
+	cmd.MarkFlagRequired("member-uuid")
+
+	return cmd
+}
+
+// Выход из комнаты
+func newLeaveChatCommand() *cobra.Command {
+	var address, token, roomUUID string
+
+	cmd := &cobra.Command{
+		Use:     "leave",
+		Short:   "Покинуть комнату",
+		Example: "bil-message-client leave -a http://localhost:8080 -t <jwt-token> -c <room-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			if err := client.LeaveChat(ctx, httpClient, token, uuidRoom); err != nil {
+				return fmt.Errorf("не удалось покинуть чат: %w", err)
+			}
+
+			cmd.Println("Вы покинули комнату")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.MarkFlagRequired("room-uuid")
+
+	return cmd
+}
+
+// Передача владения комнатой
+func newTransferChatOwnershipCommand() *cobra.Command {
+	var address, token, roomUUID, memberUUID string
+
+	cmd := &cobra.Command{
+		Use:     "transfer-owner",
+		Short:   "Передать владение комнатой другому участнику",
+		Example: "bil-message-client transfer-owner -a http://localhost:8080 -t <jwt-token> -c <room-uuid> -m <user-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			uuidMember, err := uuid.Parse(memberUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID пользователя: %w", err)
+			}
+
+			if err := client.TransferChatOwnership(ctx, httpClient, token, uuidRoom, uuidMember); err != nil {
+				return fmt.Errorf("не удалось передать владение комнатой: %w", err)
+			}
+
+			cmd.Println("Владение комнатой успешно передано")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.Flags().StringVarP(&memberUUID, "member-uuid", "m", "", "UUID нового владельца")
+	cmd.MarkFlagRequired("room-uuid")
+	cmd.MarkFlagRequired("member-uuid")
+
+	return cmd
+}
+
+// Изменение роли участника комнаты
+func newSetChatMemberRoleCommand() *cobra.Command {
+	var address, token, roomUUID, memberUUID, role string
+
+	cmd := &cobra.Command{
+		Use:     "set-role",
+		Short:   "Назначить участнику комнаты роль (admin или member)",
+		Example: "bil-message-client set-role -a http://localhost:8080 -t <jwt-token> -c <room-uuid> -m <user-uuid> -r admin",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			uuidMember, err := uuid.Parse(memberUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID пользователя: %w", err)
+			}
+
+			if err := client.SetChatMemberRole(ctx, httpClient, token, uuidRoom, uuidMember, role); err != nil {
+				return fmt.Errorf("не удалось изменить роль участника: %w", err)
+			}
+
+			cmd.Println("Роль участника успешно изменена")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.Flags().StringVarP(&memberUUID, "member-uuid", "m", "", "UUID пользователя")
+	cmd.Flags().StringVarP(&role, "role", "r", "member", "Роль участника (admin или member)")
+	cmd.MarkFlagRequired("room-uuid")
+	cmd.MarkFlagRequired("member-uuid")
+
+	return cmd
+}
+
+// Список комнат пользователя
+func newListChatsCommand() *cobra.Command {
+	var address, token string
+
+	cmd := &cobra.Command{
+		Use:     "rooms",
+		Short:   "Показать комнаты, в которых вы состоите",
+		Example: "bil-message-client rooms -a http://localhost:8080 -t <jwt-token>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			rooms, err := client.ListChats(ctx, httpClient, token)
+			if err != nil {
+				return fmt.Errorf("не удалось получить список комнат: %w", err)
+			}
+
+			if len(rooms) == 0 {
+				cmd.Println("Вы не состоите ни в одной комнате")
+				return nil
+			}
+			for _, room := range rooms {
+				name := room.Name
+				if name == "" {
+					name = "(без названия)"
+				}
+				cmd.Printf("%s\t%s\tтип: %s\tроль: %s\tучастников: %d\tактивность: %s\n",
+					room.RoomUUID, name, room.Kind, room.Role, room.MemberCount, room.LastActivityAt.Local().Format(time.DateTime))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+
+	return cmd
+}
+
+// Список участников комнаты
+func newListChatMembersCommand() *cobra.Command {
+	var address, token, roomUUID string
+
+	cmd := &cobra.Command{
+		Use:     "members",
+		Short:   "Показать участников комнаты",
+		Example: "bil-message-client members -a http://localhost:8080 -t <jwt-token> -c <room-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			members, err := client.ListChatMembers(ctx, httpClient, token, uuidRoom)
+			if err != nil {
+				return fmt.Errorf("не удалось получить участников комнаты: %w", err)
+			}
+
+			for _, m := range members {
+				cmd.Printf("%s\t%s\tроль: %s\tв комнате с %s\n",
+					m.UserUUID, m.Username, m.Role, m.JoinedAt.Local().Format(time.DateTime))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.MarkFlagRequired("room-uuid")
+
+	return cmd
+}
+
+// newWebSocketCommand создаёт команду для подключения к WebSocket чата
+func newWebSocketCommand() *cobra.Command {
+	var address, token, roomUUID string
+
+	cmd := &cobra.Command{
+		Use:   "ws",
+		Short: "Подключиться к WebSocket чата",
+		Long: "Получает ключ комнаты (или создаёт его), проверяет подпись выпустившего его устройства участника комнаты, " +
+			"расшифровывает ключ ключом устройства и обменивается сообщениями со сквозным шифрованием. " +
+			"Если набор ключей собеседника, проверенного командой verify, изменился или ключ комнаты выпущен непроверенным устройством, выводит предупреждение.",
+		Example: "bil-message-client ws -a http://localhost:8080 -t <jwt-token> -c <room-uuid>",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// JWT короткоживущий, а сеанс длится дольше него: запросы ключей комнаты читают текущий JWT
+			// из хранилища и обновляют его по ответу 401, а перед каждым подключением WebSocket JWT
+			// обменивается на свежий по refresh-токену
+			apiTokens, dialTokens := client.StaticToken(token), client.StaticToken(token)
+			if token == "" {
+				store := newTokenStore()
+				if _, _, err := store.Load(); err != nil {
+					return fmt.Errorf("не найден сохранённый токен, выполните login: %w", err)
+				}
+
+				refreshClient, err := http.New(address)
+				if err != nil {
+					return err
+				}
+				apiTokens, dialTokens = store.StoredToken(), store.RefreshedToken(refreshClient)
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			priv, err := newKeyStore().Load()
+			if err != nil {
+				return fmt.Errorf("не найден ключ устройства, выполните device: %w", err)
+			}
+
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			// Ключи комнаты подписываются ключом подписи устройства: устройство, добавленное до его появления,
+			// сначала регистрирует ключ подписи, иначе сервер не примет выпущенный им ключ
+			signingKey, err := e2ee.DeriveSigningKey(priv)
+			if err != nil {
+				return fmt.Errorf("не удалось получить ключ подписи устройства: %w", err)
+			}
+			ctx := context.Background()
+			_, devices, err := client.ListDevices(ctx, httpClient, token)
+			if err != nil {
+				return fmt.Errorf("не удалось получить список устройств: %w", err)
+			}
+			for i := range devices {
+				if devices[i].Current {
+					if err := ensureSigningKey(ctx, httpClient, token, signingKey, &devices[i]); err != nil {
+						return err
+					}
+				}
+			}
+
+			verified := newVerifiedKeyStore()
+			warnKeyChanges := func(devices []client.RoomDevice) {
+				changed, err := verified.Changed(devices)
+				if err != nil {
+					cmd.PrintErrln("Не удалось сверить ключи участников с проверенными:", err)
+					return
+				}
+				for _, contact := range changed {
+					cmd.PrintErrf("\n!!! ВНИМАНИЕ: набор ключей устройств %s изменился после проверки %s !!!\n"+
+						"!!! Сообщения комнаты могут получать непроверенные устройства. Сверьте номер безопасности заново: verify -u %s !!!\n\n",
+						contact.Username, contact.VerifiedAt.Local().Format(time.DateTime), contact.Username)
+				}
+			}
+
+			keyRing, err := client.NewRoomKeyRing(ctx, httpClient, apiTokens, uuidRoom, priv,
+				client.WithDeviceObserver(warnKeyChanges),
+				client.WithIssuerObserver(newIssuerWarning(cmd, verified, ownSigningKeys(devices, signingKey))))
+			if err != nil {
+				return fmt.Errorf("не удалось получить ключ комнаты: %w", err)
+			}
+
+			return client.ConnectWebSocket(websocketURL(address, uuidRoom), dialTokens, keyRing)
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.MarkFlagRequired("room-uuid")
+
+	return cmd
+}
+
+// newHistoryCommand создаёт команду для просмотра истории сообщений комнаты
+func newHistoryCommand() *cobra.Command {
+	var address, token, roomUUID, before, after string
+	var limit int
+
+	cmd := &cobra.Command{
+		Use:   "history",
+		Short: "Показать историю сообщений комнаты",
+		Long: `Выводит страницу истории комнаты от старых сообщений к новым без подключения к WebSocket.
+Если на устройстве есть ключ, созданный командой device, сообщения расшифровываются ключами комнаты
+соответствующих эпох; иначе выводятся только сведения о зашифрованных сообщениях.
+Без --before и --after выводятся последние сообщения.`,
+		Example: "bil-message-client history -a http://localhost:8080 -t <jwt-token> -c <room-uuid> --before <message-uuid> -n 20",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ctx := context.Background()
+			httpClient, err := newAuthorizedHTTPClient(address)
+			if err != nil {
+				return err
+			}
+
+			uuidRoom, err := uuid.Parse(roomUUID)
+			if err != nil {
+				return fmt.Errorf("некорректный UUID комнаты: %w", err)
+			}
+
+			var uuidBefore, uuidAfter uuid.UUID
+			if before != "" {
+				if uuidBefore, err = uuid.Parse(before); err != nil {
+					return fmt.Errorf("некорректный UUID сообщения в --before: %w", err)
+				}
+			}
+			if after != "" {
+				if uuidAfter, err = uuid.Parse(after); err != nil {
+					return fmt.Errorf("некорректный UUID сообщения в --after: %w", err)
+				}
+			}
+
+			page, err := client.ListMessages(ctx, httpClient, token, uuidRoom, uuidBefore, uuidAfter, limit)
+			if err != nil {
+				return fmt.Errorf("не удалось получить историю сообщений: %w", err)
+			}
+
+			var decrypter client.MessageDecrypter
+			if priv, err := newKeyStore().Load(); err == nil {
+				signingKey, err := e2ee.DeriveSigningKey(priv)
+				if err != nil {
+					return fmt.Errorf("не удалось получить ключ подписи устройства: %w", err)
+				}
+				_, devices, err := client.ListDevices(ctx, httpClient, token)
+				if err != nil {
+					return fmt.Errorf("не удалось получить список устройств: %w", err)
+				}
+				decrypter = client.NewHistoryKeyRing(httpClient, client.StaticToken(token), uuidRoom, priv,
+					client.WithIssuerObserver(newIssuerWarning(cmd, newVerifiedKeyStore(), ownSigningKeys(devices, signingKey))))
+			}
+
+			for _, m := range page.Messages {
+				cmd.Println(client.FormatHistoryMessage(decrypter, m))
+			}
+
+			if page.HasMore && len(page.Messages) > 0 {
+				if uuidAfter != uuid.Nil {
+					cmd.Printf("Есть более новые сообщения: --after %s\n", page.Messages[len(page.Messages)-1].MessageUUID)
+				} else {
+					cmd.Printf("Есть более ранние сообщения: --before %s\n", page.Messages[0].MessageUUID)
+				}
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&address, "address", "a", "http://localhost:8080", "Адрес сервера")
+	cmd.Flags().StringVarP(&token, "token", "t", "", "JWT токен авторизации (по умолчанию используется сохранённый командой login)")
+	cmd.Flags().StringVarP(&roomUUID, "room-uuid", "c", "", "UUID комнаты")
+	cmd.Flags().StringVar(&before, "before", "", "UUID сообщения: показать сообщения, отправленные до него")
+	cmd.Flags().StringVar(&after, "after", "", "UUID сообщения: показать сообщения, отправленные после него")
+	cmd.Flags().IntVarP(&limit, "limit", "n", 0, "Количество сообщений (по умолчанию 50, не более 200)")
+	cmd.MarkFlagRequired("room-uuid")
+
+	return cmd
+}
+
+// websocketURL возвращает адрес WebSocket комнаты, заменяя схему http(s) адреса сервера на ws(s)
+func websocketURL(address string, roomUUID uuid.UUID) string {
+	switch {
+	case strings.HasPrefix(address, "https://"):
+		address = "wss://" + strings.TrimPrefix(address, "https://")
+	case strings.HasPrefix(address, "http://"):
+		address = "ws://" + strings.TrimPrefix(address, "http://")
+	}
+	return fmt.Sprintf("%s/chat/%s/ws", address, roomUUID)
+}
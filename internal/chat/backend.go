@@ -0,0 +1,68 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Event — событие комнаты, которое нужно доставить клиентам, подключённым к любому экземпляру сервера
+type Event struct {
+	RoomUUID      uuid.UUID       `json:"room_uuid"`               // комната события; при отключении uuid.Nil — все комнаты
+	UserUUID      uuid.UUID       `json:"user_uuid,omitzero"`      // если задан, кадр получают только устройства этого пользователя
+	ExcludeDevice uuid.UUID       `json:"exclude_device,omitzero"` // устройство, которому кадр не отправляется (отправитель)
+	Frame         json.RawMessage `json:"frame,omitempty"`         // кадр протокола WebSocket для клиентов
+	Disconnect    string          `json:"disconnect,omitempty"`    // если задана, клиенты пользователя UserUUID отключаются с этой причиной
+	DeviceUUID    uuid.UUID       `json:"device_uuid,omitzero"`    // при отключении — только это устройство пользователя
+}
+
+// Backend передаёт события комнат между экземплярами сервера.
+// Событие, опубликованное на любом экземпляре, получают подписчики всех экземпляров, включая текущий.
+type Backend interface {
+	// Publish публикует событие комнаты
+	Publish(ctx context.Context, event Event) error
+	// Subscribe регистрирует обработчик событий, опубликованных на любом экземпляре
+	Subscribe(deliver func(event Event))
+}
+
+// subscribers хранит обработчики событий бэкенда
+type subscribers struct {
+	mu       sync.RWMutex
+	handlers []func(event Event)
+}
+
+// Subscribe регистрирует обработчик событий
+func (s *subscribers) Subscribe(deliver func(event Event)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = append(s.handlers, deliver)
+}
+
+// deliver передаёт событие всем обработчикам
+func (s *subscribers) deliver(event Event) {
+	s.mu.RLock()
+	handlers := s.handlers
+	s.mu.RUnlock()
+	for _, h := range handlers {
+		h(event)
+	}
+}
+
+// LocalBackend — бэкенд для одного экземпляра сервера: события доставляются подписчикам в том же процессе.
+// Несколько хабов с общим LocalBackend ведут себя как экземпляры сервера с общим бэкендом.
+type LocalBackend struct {
+	subscribers
+}
+
+// NewLocalBackend создаёт бэкенд, доставляющий события внутри процесса
+func NewLocalBackend() *LocalBackend {
+	return &LocalBackend{}
+}
+
+// Publish синхронно доставляет событие всем подписчикам
+func (b *LocalBackend) Publish(ctx context.Context, event Event) error {
+	b.deliver(event)
+	return nil
+}
@@ -0,0 +1,128 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// failingBackend — бэкенд, публикация в который всегда завершается ошибкой
+type failingBackend struct {
+	subscribers
+}
+
+func (b *failingBackend) Publish(ctx context.Context, event Event) error {
+	return errors.New("backend unavailable")
+}
+
+// newHubServer запускает экземпляр сервера с хабом и возвращает адрес WebSocket.
+// UUID пользователя задаётся параметром запроса user.
+func newHubServer(t *testing.T, hub *Hub, roomUUID uuid.UUID) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(nil, err)
+
+		userUUID, err := uuid.Parse(r.URL.Query().Get("user"))
+		require.NoError(nil, err)
+		client := NewChatClient(conn, userUUID, uuid.New(), roomUUID)
+		room, err := hub.Register(r.Context(), client, nil)
+		require.NoError(nil, err)
+		client.ReadPump(room)
+		client.WritePump()
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + server.URL[len("http"):]
+}
+
+func TestLocalBackend(t *testing.T) {
+	backend := NewLocalBackend()
+	var first, second []Event
+	backend.Subscribe(func(event Event) { first = append(first, event) })
+	backend.Subscribe(func(event Event) { second = append(second, event) })
+
+	event := Event{RoomUUID: uuid.New(), Frame: json.RawMessage(`{"v":1}`)}
+	require.NoError(t, backend.Publish(context.Background(), event))
+	require.Equal(t, []Event{event}, first)
+	require.Equal(t, []Event{event}, second)
+}
+
+func TestHubsShareBackend(t *testing.T) {
+	backend := NewLocalBackend()
+	newRoom := func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) }
+	nodeA := NewHub(newRoom, WithBackend(backend))
+	nodeB := NewHub(newRoom, WithBackend(backend))
+	roomUUID := uuid.New()
+	aliceUUID, bobUUID := uuid.New(), uuid.New()
+
+	alice, _, err := websocket.DefaultDialer.Dial(newHubServer(t, nodeA, roomUUID)+"?user="+aliceUUID.String(), nil)
+	require.NoError(t, err)
+	defer alice.Close()
+	bob, _, err := websocket.DefaultDialer.Dial(newHubServer(t, nodeB, roomUUID)+"?user="+bobUUID.String(), nil)
+	require.NoError(t, err)
+	defer bob.Close()
+
+	require.Eventually(t, func() bool {
+		return nodeA.ConnectionCount() == 1 && nodeB.ConnectionCount() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Сообщение, принятое одним экземпляром, получает клиент другого
+	require.NoError(t, alice.WriteMessage(websocket.TextMessage, messageFrame(1, "hello")))
+	ack := readEnvelope(t, alice)
+	require.Equal(t, TypeAck, ack.Type)
+
+	message := readEnvelope(t, bob)
+	require.Equal(t, TypeMessage, message.Type)
+	require.Equal(t, ack.MessageUUID, message.MessageUUID)
+	require.Equal(t, aliceUUID, message.SenderUUID)
+
+	// Квитанция возвращается отправителю через другой экземпляр
+	receipt, err := json.Marshal(Envelope{
+		Version:     ProtocolVersion,
+		Type:        TypeReceipt,
+		MessageUUID: message.MessageUUID,
+		SenderUUID:  aliceUUID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, bob.WriteMessage(websocket.TextMessage, receipt))
+
+	delivered := readEnvelope(t, alice)
+	require.Equal(t, TypeReceipt, delivered.Type)
+	require.Equal(t, bobUUID, delivered.RecipientUUID)
+
+	// Ротацию ключа, инициированную на одном экземпляре, получают клиенты всех экземпляров
+	nodeA.NotifyKeyRotation(roomUUID, 2)
+	for _, conn := range []*websocket.Conn{alice, bob} {
+		env := readEnvelope(t, conn)
+		require.Equal(t, TypeKeyRotation, env.Type)
+		require.Equal(t, int64(2), env.KeyEpoch)
+	}
+}
+
+func TestHubDeliversLocallyWhenBackendFails(t *testing.T) {
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) }, WithBackend(&failingBackend{}))
+	roomUUID := uuid.New()
+	wsURL := newHubServer(t, hub, roomUUID)
+
+	sender, _, err := websocket.DefaultDialer.Dial(wsURL+"?user="+uuid.NewString(), nil)
+	require.NoError(t, err)
+	defer sender.Close()
+	receiver, _, err := websocket.DefaultDialer.Dial(wsURL+"?user="+uuid.NewString(), nil)
+	require.NoError(t, err)
+	defer receiver.Close()
+
+	require.Eventually(t, func() bool { return hub.ConnectionCount() == 2 }, time.Second, 10*time.Millisecond)
+
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage, messageFrame(1, "hello")))
+	require.Equal(t, TypeAck, readEnvelope(t, sender).Type)
+	require.Equal(t, "hello", readEnvelope(t, receiver).Ciphertext)
+}
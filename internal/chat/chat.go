@@ -1,59 +1,326 @@
 package chat
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// MessageStore описывает хранилище истории сообщений комнаты
+type MessageStore interface {
+	// SaveMessage сохраняет сообщение, отправленное с устройства senderDeviceUUID и зашифрованное ключом эпохи keyEpoch,
+	// и возвращает его с присвоенными UUID и временем отправки. Если сообщение с clientMessageUUID уже сохранено,
+	// возвращается ранее сохранённое сообщение и duplicate = true.
+	SaveMessage(
+		ctx context.Context,
+		roomUUID, senderUUID, senderDeviceUUID, clientMessageUUID uuid.UUID,
+		keyEpoch int64,
+		ciphertext string,
+	) (message *models.RoomMessageDB, duplicate bool, err error)
+	// ListLastMessages возвращает последние limit сообщений комнаты от старых к новым
+	ListLastMessages(ctx context.Context, roomUUID uuid.UUID, limit int) ([]models.RoomMessageDB, error)
+	// RoomKeyEpoch возвращает текущую эпоху ключа комнаты
+	RoomKeyEpoch(ctx context.Context, roomUUID uuid.UUID) (int64, error)
+}
+
+// Параметры соединения WebSocket по умолчанию
+const (
+	DefaultPingInterval   = 30 * time.Second // интервал отправки ping клиенту
+	DefaultPongTimeout    = 60 * time.Second // время ожидания pong (или любого кадра) от клиента
+	DefaultWriteTimeout   = 10 * time.Second // время на запись одного кадра клиенту
+	DefaultMaxMessageSize = 64 << 10         // максимальный размер кадра клиента в байтах
+)
+
+// Время на обработку событий комнаты по умолчанию
+const (
+	DefaultFrameTimeout   = 10 * time.Second // время на обработку кадра клиента: сохранение сообщения и рассылку
+	DefaultPublishTimeout = 10 * time.Second // время на публикацию события комнаты через бэкенд
 )
 
 // ChatClient представляет подключение пользователя через WebSocket
 type ChatClient struct {
-	Conn      *websocket.Conn
-	UserUUID  uuid.UUID
-	RoomUUID  uuid.UUID
-	Send      chan []byte
-	closeOnce sync.Once
-	closed    chan struct{}
+	Conn           *websocket.Conn
+	UserUUID       uuid.UUID
+	DeviceUUID     uuid.UUID
+	RoomUUID       uuid.UUID
+	Send           chan []byte
+	pingInterval   time.Duration
+	pongTimeout    time.Duration
+	writeTimeout   time.Duration
+	maxMessageSize int64
+	closeOnce      sync.Once
+	closed         chan struct{}
+	done           chan struct{} // закрывается при завершении WritePump
+	sendMu         sync.Mutex    // защищает отправку в Send от гонки с его закрытием
+	isClosed       bool
+	holding        bool                   // живые кадры откладываются до окончания отправки истории; защищено sendMu
+	held           [][]byte               // живые кадры, отложенные во время отправки истории; защищено sendMu
+	replayed       map[uuid.UUID]struct{} // сообщения, отправленные из истории, пока откладывались живые кадры
+	drainCode      int                    // код кадра закрытия после отправки очереди при плавном закрытии
+	drainReason    string                 // причина закрытия при плавном закрытии
+}
+
+// ClientOpt — функциональная опция для настройки клиента.
+type ClientOpt func(*ChatClient)
+
+// WithPingInterval задаёт интервал отправки ping клиенту.
+// Используется первое положительное значение.
+func WithPingInterval(intervals ...time.Duration) ClientOpt {
+	return func(c *ChatClient) {
+		for _, i := range intervals {
+			if i > 0 {
+				c.pingInterval = i
+				return
+			}
+		}
+	}
+}
+
+// WithPongTimeout задаёт время ожидания pong от клиента, после которого соединение считается разорванным.
+// Должно превышать интервал ping. Используется первое положительное значение.
+func WithPongTimeout(timeouts ...time.Duration) ClientOpt {
+	return func(c *ChatClient) {
+		for _, t := range timeouts {
+			if t > 0 {
+				c.pongTimeout = t
+				return
+			}
+		}
+	}
+}
+
+// WithWriteTimeout задаёт время на запись одного кадра клиенту.
+// Используется первое положительное значение.
+func WithWriteTimeout(timeouts ...time.Duration) ClientOpt {
+	return func(c *ChatClient) {
+		for _, t := range timeouts {
+			if t > 0 {
+				c.writeTimeout = t
+				return
+			}
+		}
+	}
+}
+
+// WithMaxMessageSize задаёт максимальный размер кадра клиента в байтах.
+// Используется первое положительное значение.
+func WithMaxMessageSize(sizes ...int64) ClientOpt {
+	return func(c *ChatClient) {
+		for _, s := range sizes {
+			if s > 0 {
+				c.maxMessageSize = s
+				return
+			}
+		}
+	}
 }
 
 // NewChatClient создаёт нового клиента
-func NewChatClient(conn *websocket.Conn, userUUID, roomUUID uuid.UUID) *ChatClient {
-	return &ChatClient{
-		Conn:     conn,
-		UserUUID: userUUID,
-		RoomUUID: roomUUID,
-		Send:     make(chan []byte, 1024),
-		closed:   make(chan struct{}),
+func NewChatClient(conn *websocket.Conn, userUUID, deviceUUID, roomUUID uuid.UUID, opts ...ClientOpt) *ChatClient {
+	c := &ChatClient{
+		Conn:           conn,
+		UserUUID:       userUUID,
+		DeviceUUID:     deviceUUID,
+		RoomUUID:       roomUUID,
+		Send:           make(chan []byte, 1024),
+		pingInterval:   DefaultPingInterval,
+		pongTimeout:    DefaultPongTimeout,
+		writeTimeout:   DefaultWriteTimeout,
+		maxMessageSize: DefaultMaxMessageSize,
+		closed:         make(chan struct{}),
+		done:           make(chan struct{}),
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
-// ReadPump запускает чтение сообщений от клиента в отдельной горутине
+// ReadPump запускает чтение сообщений от клиента в отдельной горутине.
+//
+// Сообщение сохраняется в истории, отправитель получает подтверждение ack, остальные участники — само сообщение.
+// Повторно отправленное после переподключения сообщение (тот же client_message_uuid) подтверждается
+// снова, но не рассылается повторно. Квитанции получателей пересылаются отправителю сообщения.
+// Кадры, не являющиеся корректным конвертом, не рассылаются: отправителю возвращается конверт ошибки.
+//
+// Кадр больше максимального размера закрывает соединение с кодом 1009 (message too big),
+// бинарный кадр — с кодом 1003 (unsupported data). Если от клиента дольше времени ожидания pong
+// не приходит ни одного кадра, соединение считается разорванным.
+// После разрыва соединения клиент удаляется из комнаты.
 func (c *ChatClient) ReadPump(room *ChatRoom) {
+	c.Conn.SetReadLimit(c.maxMessageSize)
+	c.Conn.SetReadDeadline(time.Now().Add(c.pongTimeout))
+	c.Conn.SetPongHandler(func(string) error {
+		return c.Conn.SetReadDeadline(time.Now().Add(c.pongTimeout))
+	})
+
 	go func() {
-		defer c.Close()
+		defer func() {
+			if room != nil {
+				room.RemoveClient(c)
+			} else {
+				c.Close()
+			}
+		}()
 		for {
-			_, msg, err := c.Conn.ReadMessage()
+			// при превышении размера кадра соединение закрывается с кодом 1009 внутри ReadMessage
+			messageType, msg, err := c.Conn.ReadMessage()
 			if err != nil {
 				break
 			}
-			if room != nil {
-				room.Broadcast(msg, c.UserUUID)
+			c.Conn.SetReadDeadline(time.Now().Add(c.pongTimeout))
+			if messageType != websocket.TextMessage {
+				c.CloseWithCode(websocket.CloseUnsupportedData, "only text frames are supported")
+				break
+			}
+			if room == nil || room.isClosing() {
+				// сервер останавливается: неподтверждённые сообщения клиент отправит повторно после переподключения
+				continue
+			}
+
+			env, err := ParseEnvelope(msg)
+			if err != nil {
+				c.sendEnvelope(ErrorEnvelope(err))
+				continue
+			}
+
+			switch env.Type {
+			case TypeMessage:
+				ctx, cancel := room.frameContext()
+				room.handleMessage(ctx, c, env)
+				cancel()
+			case TypeReceipt:
+				room.ForwardReceipt(c, env)
 			}
 		}
 	}()
 }
 
-// WritePump запускает запись сообщений клиенту в отдельной горутине
+// sendEnvelope ставит конверт в очередь отправки клиенту
+func (c *ChatClient) sendEnvelope(env Envelope) bool {
+	data, err := json.Marshal(env)
+	if err != nil {
+		return false
+	}
+	return c.trySend(data)
+}
+
+// trySend ставит кадр в очередь отправки клиенту без блокировки; пока клиенту отправляется история,
+// кадр откладывается (см. holdLive). Возвращает false, если клиент закрыт или его очередь переполнена.
+func (c *ChatClient) trySend(data []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.isClosed {
+		return false
+	}
+	if c.holding {
+		if len(c.held) >= cap(c.Send) {
+			return false
+		}
+		c.held = append(c.held, data)
+		return true
+	}
+	return c.enqueue(data)
+}
+
+// closing сообщает, что клиент закрыт или отправляет оставшуюся очередь перед закрытием
+func (c *ChatClient) closing() bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	return c.isClosed
+}
+
+// sendHistory ставит в очередь кадр сообщения messageUUID из истории комнаты, не дожидаясь отложенных живых кадров.
+// Возвращает false, если клиент закрыт или его очередь переполнена.
+func (c *ChatClient) sendHistory(messageUUID uuid.UUID, data []byte) bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.isClosed {
+		return false
+	}
+	if c.holding {
+		c.replayed[messageUUID] = struct{}{}
+	}
+	return c.enqueue(data)
+}
+
+// holdLive начинает откладывать живые кадры: клиент добавляется в комнату до загрузки истории,
+// и разосланные в это время сообщения не должны обогнать историю
+func (c *ChatClient) holdLive() {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	c.holding = true
+	c.replayed = make(map[uuid.UUID]struct{})
+}
+
+// releaseLive ставит в очередь отложенные живые кадры, кроме сообщений, уже отправленных из истории,
+// и возобновляет прямую отправку. Возвращает false, если очередь клиента переполнена.
+func (c *ChatClient) releaseLive() bool {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	held, replayed := c.held, c.replayed
+	c.holding, c.held, c.replayed = false, nil, nil
+	if c.isClosed {
+		return true
+	}
+	for _, data := range held {
+		var env Envelope
+		if json.Unmarshal(data, &env) == nil && env.Type == TypeMessage {
+			if _, ok := replayed[env.MessageUUID]; ok {
+				continue
+			}
+		}
+		if !c.enqueue(data) {
+			return false
+		}
+	}
+	return true
+}
+
+// enqueue ставит кадр в канал Send без блокировки; вызывается под sendMu
+func (c *ChatClient) enqueue(data []byte) bool {
+	select {
+	case c.Send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+// WritePump запускает запись сообщений клиенту в отдельной горутине.
+// Каждый кадр должен быть записан за время записи, иначе соединение закрывается;
+// с интервалом ping клиенту отправляется ping для проверки соединения.
+// После плавного закрытия (Shutdown) горутина отправляет оставшиеся в очереди кадры и кадр закрытия.
 func (c *ChatClient) WritePump() {
 	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(c.pingInterval)
+		defer ticker.Stop()
 		for {
 			select {
 			case msg, ok := <-c.Send:
 				if !ok {
+					// очередь закрыта и отправлена полностью; после Close кадр закрытия уже отправлен
+					c.CloseWithCode(c.drainCode, c.drainReason)
+					return
+				}
+				c.Conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+				if err := c.Conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+					c.Close()
+					return
+				}
+			case <-ticker.C:
+				if err := c.Conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(c.writeTimeout)); err != nil {
+					c.Close()
 					return
 				}
-				_ = c.Conn.WriteMessage(websocket.TextMessage, msg)
 			case <-c.closed:
 				return
 			}
@@ -61,59 +328,704 @@ func (c *ChatClient) WritePump() {
 	}()
 }
 
-// Close безопасно закрывает WebSocket соединение и канал Send
+// Close безопасно закрывает WebSocket соединение с кодом 1000 (normal closure) и канал Send
 func (c *ChatClient) Close() {
+	c.CloseWithCode(websocket.CloseNormalClosure, "")
+}
+
+// CloseWithCode отправляет клиенту кадр закрытия с кодом code и причиной reason,
+// затем закрывает WebSocket соединение и канал Send. Повторные вызовы ничего не делают.
+func (c *ChatClient) CloseWithCode(code int, reason string) {
 	c.closeOnce.Do(func() {
 		if c.Conn != nil {
+			// ошибка не важна: соединение могло быть уже разорвано клиентом
+			_ = c.Conn.WriteControl(
+				websocket.CloseMessage,
+				websocket.FormatCloseMessage(code, reason),
+				time.Now().Add(c.writeTimeout),
+			)
 			c.Conn.Close()
 		}
 		close(c.closed)
-		close(c.Send)
+
+		c.sendMu.Lock()
+		if !c.isClosed {
+			c.isClosed = true
+			close(c.Send)
+		}
+		c.sendMu.Unlock()
 	})
 }
 
-// ChatRoom представляет комнату с участниками WebSocket
+// Shutdown плавно закрывает соединение: новые кадры в очередь больше не принимаются, уже поставленные
+// отправляются клиенту WritePump, после чего отправляется кадр закрытия с кодом code и причиной reason.
+// Завершение отправки можно дождаться через Done.
+func (c *ChatClient) Shutdown(code int, reason string) {
+	c.sendMu.Lock()
+	defer c.sendMu.Unlock()
+	if c.isClosed {
+		return
+	}
+	c.drainCode, c.drainReason = code, reason
+	c.isClosed = true
+	close(c.Send)
+}
+
+// Done возвращает канал, который закрывается, когда WritePump завершил отправку кадров клиенту
+func (c *ChatClient) Done() <-chan struct{} {
+	return c.done
+}
+
+// ChatRoom представляет комнату с участниками WebSocket.
+// Подключения хранятся по UUID устройства: один пользователь может быть подключён с нескольких устройств.
 type ChatRoom struct {
-	RoomUUID uuid.UUID
-	Members  map[uuid.UUID]*ChatClient
-	mu       sync.Mutex
+	RoomUUID     uuid.UUID
+	Members      map[uuid.UUID]*ChatClient // подключения по UUID устройства
+	store        MessageStore
+	historyLimit int
+	mu           sync.Mutex
+	onEmpty      func(room *ChatRoom)                   // вызывается, когда из комнаты удалён последний клиент
+	publish      func(ctx context.Context, event Event) // публикация события через бэкенд хаба
+	closing      bool                                   // хаб останавливается, кадры клиентов не обрабатываются; защищено mu
+	frameTimeout time.Duration                          // время на обработку кадра клиента
+	ctx          context.Context                        // родительский контекст обработки кадров; отменяется при остановке хаба
+}
+
+// RoomOpt — функциональная опция для настройки комнаты.
+type RoomOpt func(*ChatRoom)
+
+// WithMessageStore задаёт хранилище истории сообщений.
+// Используется первое непустое значение.
+func WithMessageStore(stores ...MessageStore) RoomOpt {
+	return func(r *ChatRoom) {
+		for _, s := range stores {
+			if s != nil {
+				r.store = s
+				return
+			}
+		}
+	}
+}
+
+// WithHistoryLimit задаёт количество последних сообщений, отправляемых клиенту при подключении.
+// Используется первое положительное значение.
+func WithHistoryLimit(limits ...int) RoomOpt {
+	return func(r *ChatRoom) {
+		for _, l := range limits {
+			if l > 0 {
+				r.historyLimit = l
+				return
+			}
+		}
+	}
+}
+
+// WithFrameTimeout задаёт время на обработку кадра клиента: сохранение сообщения в истории и его рассылку.
+// Пока кадр обрабатывается, следующие кадры клиента не читаются, поэтому зависшее хранилище
+// не должно блокировать соединение бессрочно. Используется первое положительное значение.
+func WithFrameTimeout(timeouts ...time.Duration) RoomOpt {
+	return func(r *ChatRoom) {
+		for _, t := range timeouts {
+			if t > 0 {
+				r.frameTimeout = t
+				return
+			}
+		}
+	}
 }
 
 // NewChatRoom создаёт новую комнату
-func NewChatRoom(roomUUID uuid.UUID) *ChatRoom {
-	return &ChatRoom{
-		RoomUUID: roomUUID,
-		Members:  make(map[uuid.UUID]*ChatClient),
+func NewChatRoom(roomUUID uuid.UUID, opts ...RoomOpt) *ChatRoom {
+	r := &ChatRoom{
+		RoomUUID:     roomUUID,
+		Members:      make(map[uuid.UUID]*ChatClient),
+		frameTimeout: DefaultFrameTimeout,
+		ctx:          context.Background(),
+	}
+	for _, opt := range opts {
+		opt(r)
 	}
+	return r
 }
 
-// AddClient добавляет клиента в комнату
+// AddClient добавляет клиента в комнату.
+// Предыдущее подключение с того же устройства закрывается.
 func (r *ChatRoom) AddClient(client *ChatClient) {
 	r.mu.Lock()
-	defer r.mu.Unlock()
-	r.Members[client.UserUUID] = client
+	previous, ok := r.Members[client.DeviceUUID]
+	r.Members[client.DeviceUUID] = client
+	r.mu.Unlock()
+	if ok && previous != client {
+		// закрытие в отдельной горутине: AddClient вызывается под блокировкой хаба,
+		// а запись кадра закрытия может ждать до таймаута
+		go previous.CloseWithCode(websocket.CloseNormalClosure, "replaced by a new connection")
+	}
+}
+
+// Join добавляет клиента в комнату и отправляет ему последние сообщения из истории.
+// Клиент добавляется до загрузки истории, поэтому сообщения, разосланные в это время, не теряются:
+// они отправляются после истории, а уже попавшие в неё пропускаются.
+func (r *ChatRoom) Join(ctx context.Context, client *ChatClient) error {
+	client.holdLive()
+	r.AddClient(client)
+	return r.replayJoined(ctx, client)
 }
 
-// RemoveClient удаляет клиента из комнаты
-// RemoveClient удаляет клиента из комнаты
+// replayJoined отправляет клиенту, добавленному в комнату с отложенными живыми кадрами, историю комнаты,
+// затем отложенные кадры. Клиент, очередь которого переполнилась, отключается, как в Broadcast.
+func (r *ChatRoom) replayJoined(ctx context.Context, client *ChatClient) error {
+	err := r.ReplayHistory(ctx, client)
+	if client.releaseLive() {
+		return err
+	}
+
+	r.detach(client)
+	client.CloseWithCode(websocket.CloseTryAgainLater, "too slow to receive messages")
+	return err
+}
+
+// RemoveClient удаляет клиента из комнаты и закрывает его соединение.
+// Если с того же устройства уже подключился другой клиент, он остаётся в комнате.
 func (r *ChatRoom) RemoveClient(client *ChatClient) {
+	r.detach(client)
+	client.Close()
+}
+
+// detach удаляет клиента из комнаты, не закрывая его соединение.
+// Комната, в которой не осталось клиентов, передаётся onEmpty.
+func (r *ChatRoom) detach(client *ChatClient) {
 	r.mu.Lock()
-	delete(r.Members, client.UserUUID)
+	if r.Members[client.DeviceUUID] == client {
+		delete(r.Members, client.DeviceUUID)
+	}
+	empty := len(r.Members) == 0
 	r.mu.Unlock()
-	client.Close()
+
+	if empty && r.onEmpty != nil {
+		r.onEmpty(r)
+	}
 }
 
-// Broadcast рассылает сообщение всем участникам, кроме отправителя
-func (r *ChatRoom) Broadcast(message []byte, senderUUID uuid.UUID) {
+// frameContext возвращает контекст обработки кадра клиента, ограниченный временем обработки кадра
+// и отменяемый при остановке хаба
+func (r *ChatRoom) frameContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(r.ctx, r.frameTimeout)
+}
+
+// isClosing сообщает, что хаб комнаты останавливается и новые кадры клиентов не обрабатываются
+func (r *ChatRoom) isClosing() bool {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	for uid, client := range r.Members {
-		if uid != senderUUID {
-			select {
-			case client.Send <- message:
-			default:
-				// канал переполнен — сообщение можно пропустить
+	return r.closing
+}
+
+// ClientCount возвращает количество подключённых к комнате клиентов
+func (r *ChatRoom) ClientCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.Members)
+}
+
+// Broadcast рассылает сообщение на все подключённые устройства, кроме устройства отправителя:
+// другие устройства отправителя тоже получают сообщение, чтобы переписка на них оставалась полной.
+//
+// Сообщение не теряется молча: клиент, очередь которого переполнена, отключается
+// и получает пропущенные сообщения из истории при переподключении. Уже закрытые или закрывающиеся
+// клиенты (например, отправляющие очередь при остановке хаба) пропускаются.
+func (r *ChatRoom) Broadcast(message []byte, senderDeviceUUID uuid.UUID) {
+	var slow []*ChatClient
+	r.mu.Lock()
+	for deviceUUID, client := range r.Members {
+		if deviceUUID == senderDeviceUUID {
+			continue
+		}
+		if !client.trySend(message) {
+			if client.closing() {
+				continue
 			}
+			log.Printf("chat: устройство %s пользователя %s не успевает получать сообщения комнаты %s и отключено",
+				deviceUUID, client.UserUUID, r.RoomUUID)
+			delete(r.Members, deviceUUID)
+			slow = append(slow, client)
 		}
 	}
+	r.mu.Unlock()
+
+	// кадр закрытия отправляется вне блокировки: запись медленному клиенту может ждать до таймаута
+	for _, client := range slow {
+		client.CloseWithCode(websocket.CloseTryAgainLater, "too slow to receive messages")
+	}
+}
+
+// ForwardReceipt пересылает квитанцию о доставке сообщения участнику reader на все подключённые устройства
+// отправителя сообщения. Квитанции на собственные сообщения (в том числе с других устройств) отбрасываются.
+func (r *ChatRoom) ForwardReceipt(reader *ChatClient, receipt Envelope) {
+	if receipt.SenderUUID == reader.UserUUID {
+		return
+	}
+	receipt.RoomUUID = r.RoomUUID
+	receipt.RecipientUUID = reader.UserUUID
+
+	data, err := json.Marshal(receipt)
+	if err != nil {
+		return
+	}
+	ctx, cancel := r.frameContext()
+	defer cancel()
+	r.dispatch(ctx, Event{
+		RoomUUID: r.RoomUUID,
+		UserUUID: receipt.SenderUUID,
+		Frame:    data,
+	})
+}
+
+// SendToUser отправляет кадр на все подключённые к комнате устройства пользователя userUUID
+func (r *ChatRoom) SendToUser(message []byte, userUUID uuid.UUID) {
+	r.mu.Lock()
+	var devices []*ChatClient
+	for _, client := range r.Members {
+		if client.UserUUID == userUUID {
+			devices = append(devices, client)
+		}
+	}
+	r.mu.Unlock()
+	for _, client := range devices {
+		client.trySend(message)
+	}
+}
+
+// dispatch доставляет событие комнаты: через бэкенд хаба на все экземпляры сервера
+// или, если комната создана вне хаба, только подключённым к ней клиентам
+func (r *ChatRoom) dispatch(ctx context.Context, event Event) {
+	if r.publish != nil {
+		r.publish(ctx, event)
+		return
+	}
+	r.deliver(event)
+}
+
+// deliver доставляет событие клиентам, подключённым к комнате на этом экземпляре сервера
+func (r *ChatRoom) deliver(event Event) {
+	if event.UserUUID != uuid.Nil {
+		r.SendToUser(event.Frame, event.UserUUID)
+		return
+	}
+	r.Broadcast(event.Frame, event.ExcludeDevice)
+}
+
+// disconnect закрывает соединения пользователя event.UserUUID (только устройства event.DeviceUUID, если оно задано;
+// всех клиентов комнаты, если пользователь не задан) с кодом 1008 (policy violation) и причиной event.Disconnect
+// и удаляет их из комнаты
+func (r *ChatRoom) disconnect(event Event) {
+	var clients []*ChatClient
+	r.mu.Lock()
+	for deviceUUID, client := range r.Members {
+		if (event.UserUUID != uuid.Nil && client.UserUUID != event.UserUUID) || (event.DeviceUUID != uuid.Nil && deviceUUID != event.DeviceUUID) {
+			continue
+		}
+		delete(r.Members, deviceUUID)
+		clients = append(clients, client)
+	}
+	empty := len(r.Members) == 0
+	r.mu.Unlock()
+
+	for _, client := range clients {
+		client.CloseWithCode(websocket.ClosePolicyViolation, event.Disconnect)
+	}
+	if len(clients) > 0 && empty && r.onEmpty != nil {
+		r.onEmpty(r)
+	}
+}
+
+// SaveMessage сохраняет конверт сообщения клиента в истории комнаты и возвращает конверт,
+// заполненный сервером: UUID сообщения, комната, отправитель, его устройство и время отправки.
+// duplicate = true, если сообщение с тем же client_message_uuid уже было сохранено.
+// Сообщение, зашифрованное ключом не текущей эпохи комнаты, отклоняется с ErrStaleKeyEpoch:
+// участник, исключённый из комнаты, не может писать ключом эпохи, которую он ещё знает.
+// Если хранилище не задано, сообщение не сохраняется, а UUID и время присваиваются на месте.
+func (r *ChatRoom) SaveMessage(ctx context.Context, sender *ChatClient, env Envelope) (saved Envelope, duplicate bool, err error) {
+	if r.store == nil {
+		env.MessageUUID = uuid.New()
+		env.RoomUUID = r.RoomUUID
+		env.SenderUUID = sender.UserUUID
+		env.SenderDevice = sender.DeviceUUID
+		env.SentAt = time.Now().UTC()
+		return env, false, nil
+	}
+
+	keyEpoch, err := r.store.RoomKeyEpoch(ctx, r.RoomUUID)
+	if err != nil {
+		return Envelope{}, false, err
+	}
+	if env.KeyEpoch != keyEpoch {
+		return Envelope{}, false, ErrStaleKeyEpoch
+	}
+
+	m, duplicate, err := r.store.SaveMessage(
+		ctx,
+		r.RoomUUID,
+		sender.UserUUID,
+		sender.DeviceUUID,
+		env.ClientMessageUUID,
+		env.KeyEpoch,
+		env.Ciphertext,
+	)
+	if err != nil {
+		return Envelope{}, false, err
+	}
+	return MessageEnvelope(*m), duplicate, nil
+}
+
+// handleMessage сохраняет сообщение клиента, подтверждает его отправителю и рассылает на остальные устройства
+// всех экземпляров сервера
+func (r *ChatRoom) handleMessage(ctx context.Context, sender *ChatClient, env Envelope) {
+	saved, duplicate, err := r.SaveMessage(ctx, sender, env)
+	if err != nil {
+		log.Printf("chat: не удалось сохранить сообщение комнаты %s: %v", r.RoomUUID, err)
+		errEnv := ErrorEnvelope(err)
+		errEnv.ClientMessageUUID = env.ClientMessageUUID
+		sender.sendEnvelope(errEnv)
+		return
+	}
+
+	sender.sendEnvelope(AckEnvelope(saved))
+	if duplicate {
+		return
+	}
+
+	data, err := json.Marshal(saved)
+	if err != nil {
+		return
+	}
+	r.dispatch(ctx, Event{
+		RoomUUID:      r.RoomUUID,
+		ExcludeDevice: sender.DeviceUUID,
+		Frame:         data,
+	})
+}
+
+// ReplayHistory отправляет клиенту последние сообщения комнаты в порядке отправки.
+// Если хранилище не задано или лимит истории не положительный, ничего не отправляет.
+func (r *ChatRoom) ReplayHistory(ctx context.Context, client *ChatClient) error {
+	if r.store == nil || r.historyLimit <= 0 {
+		return nil
+	}
+
+	messages, err := r.store.ListLastMessages(ctx, r.RoomUUID, r.historyLimit)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range messages {
+		data, err := json.Marshal(MessageEnvelope(m))
+		if err != nil {
+			return err
+		}
+		if !client.sendHistory(m.MessageUUID, data) {
+			// канал переполнен — остаток истории пропускается
+			return nil
+		}
+	}
+	return nil
+}
+
+// ErrHubClosed возвращается при регистрации клиента в остановленном хабе
+var ErrHubClosed = errors.New("chat hub is closed")
+
+// Hub — реестр комнат, открытых в памяти сервера.
+//
+// Клиенты регистрируются в хабе при подключении и удаляются из комнаты при отключении;
+// комната без подключённых клиентов удаляется из реестра и при следующем подключении создаётся заново.
+//
+// События комнат (сообщения, квитанции, ротация ключа) публикуются через бэкенд, поэтому
+// их получают клиенты, подключённые к любому экземпляру сервера с общим бэкендом.
+type Hub struct {
+	newRoom        func(roomUUID uuid.UUID) *ChatRoom
+	backend        Backend
+	rooms          map[uuid.UUID]*ChatRoom
+	closed         bool
+	mu             sync.Mutex
+	publishTimeout time.Duration      // время на публикацию события через бэкенд
+	ctx            context.Context    // отменяется при остановке хаба
+	cancel         context.CancelFunc // отменяет ctx
+}
+
+// HubOpt — функциональная опция для настройки хаба.
+type HubOpt func(*Hub)
+
+// WithBackend задаёт бэкенд для обмена событиями комнат между экземплярами сервера.
+// Используется первое непустое значение; по умолчанию события доставляются только внутри процесса.
+func WithBackend(backends ...Backend) HubOpt {
+	return func(h *Hub) {
+		for _, b := range backends {
+			if b != nil {
+				h.backend = b
+				return
+			}
+		}
+	}
+}
+
+// WithPublishTimeout задаёт время на публикацию события комнаты через бэкенд.
+// Используется первое положительное значение.
+func WithPublishTimeout(timeouts ...time.Duration) HubOpt {
+	return func(h *Hub) {
+		for _, t := range timeouts {
+			if t > 0 {
+				h.publishTimeout = t
+				return
+			}
+		}
+	}
+}
+
+// NewHub создаёт хаб; новые комнаты создаются функцией newRoom
+func NewHub(newRoom func(roomUUID uuid.UUID) *ChatRoom, opts ...HubOpt) *Hub {
+	h := &Hub{
+		newRoom:        newRoom,
+		rooms:          make(map[uuid.UUID]*ChatRoom),
+		publishTimeout: DefaultPublishTimeout,
+	}
+	h.ctx, h.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.backend == nil {
+		h.backend = NewLocalBackend()
+	}
+	h.backend.Subscribe(h.deliver)
+	return h
+}
+
+// Register добавляет клиента в его комнату, создавая её при необходимости, и возвращает комнату.
+// Клиент добавляется до загрузки истории и получает сначала последние сообщения из неё, затем сообщения,
+// разосланные во время загрузки (кроме уже попавших в историю), как ChatRoom.Join.
+//
+// Если задан admit, он вызывается после добавления клиента, но до загрузки истории: так проверка доступа
+// учитывает изменения, сделанные до начала живой доставки, а клиент без доступа не получает историю.
+// При ошибке admit клиент удаляется из комнаты без закрытия соединения и возвращается ошибка admit.
+// После остановки хаба возвращает ErrHubClosed.
+func (h *Hub) Register(ctx context.Context, client *ChatClient, admit func(ctx context.Context) error) (*ChatRoom, error) {
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		return nil, ErrHubClosed
+	}
+	room, ok := h.rooms[client.RoomUUID]
+	if !ok {
+		room = h.newRoom(client.RoomUUID)
+		room.onEmpty = h.evict
+		room.publish = h.publish
+		room.ctx = h.ctx
+		h.rooms[client.RoomUUID] = room
+	}
+	// клиент добавляется под блокировкой хаба, чтобы комната не была удалена из реестра до его добавления
+	client.holdLive()
+	room.AddClient(client)
+	h.mu.Unlock()
+
+	if admit != nil {
+		if err := admit(ctx); err != nil {
+			room.detach(client)
+			return nil, err
+		}
+	}
+
+	if err := room.replayJoined(ctx, client); err != nil {
+		log.Printf("chat: не удалось загрузить историю комнаты %s: %v", client.RoomUUID, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrHubClosed
+	}
+	return room, nil
+}
+
+// Unregister удаляет клиента из его комнаты и закрывает соединение.
+// Комната, в которой не осталось клиентов, удаляется из хаба.
+func (h *Hub) Unregister(client *ChatClient) {
+	h.mu.Lock()
+	room, ok := h.rooms[client.RoomUUID]
+	h.mu.Unlock()
+	if !ok {
+		client.Close()
+		return
+	}
+	room.RemoveClient(client)
+}
+
+// Shutdown останавливает хаб: новые клиенты больше не регистрируются, новые кадры клиентов и события бэкенда
+// не обрабатываются, каждому подключённому клиенту отправляются сообщения, уже поставленные в его очередь,
+// и кадр закрытия 1001 (going away). Неподтверждённые сообщения клиенты отправят повторно после переподключения.
+// Все сообщения сохраняются в истории до рассылки, поэтому клиенты, не успевшие их получить,
+// получат их из истории при переподключении. Обработка кадров и публикация событий, ещё не завершённые
+// к моменту остановки, отменяются. Если ctx завершается раньше, чем очереди отправлены,
+// оставшиеся соединения закрываются сразу и возвращается ошибка ctx.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.cancel()
+
+	h.mu.Lock()
+	h.closed = true
+	var clients []*ChatClient
+	for _, room := range h.rooms {
+		room.mu.Lock()
+		room.closing = true
+		for _, client := range room.Members {
+			clients = append(clients, client)
+		}
+		room.mu.Unlock()
+	}
+	h.mu.Unlock()
+
+	for _, client := range clients {
+		client.Shutdown(websocket.CloseGoingAway, "server shutting down")
+	}
+	for _, client := range clients {
+		select {
+		case <-client.Done():
+		case <-ctx.Done():
+			for _, client := range clients {
+				client.CloseWithCode(websocket.CloseGoingAway, "server shutting down")
+			}
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// evict удаляет комнату из хаба, если в ней нет клиентов
+func (h *Hub) evict(room *ChatRoom) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[room.RoomUUID] != room || room.ClientCount() > 0 {
+		return
+	}
+	delete(h.rooms, room.RoomUUID)
+}
+
+// RoomCount возвращает количество открытых комнат
+func (h *Hub) RoomCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.rooms)
+}
+
+// ConnectionCount возвращает количество подключённых клиентов во всех комнатах
+func (h *Hub) ConnectionCount() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	count := 0
+	for _, room := range h.rooms {
+		count += room.ClientCount()
+	}
+	return count
+}
+
+// NotifyKeyRotation уведомляет подключённых к любому экземпляру сервера клиентов комнаты
+// о начале эпохи ключа keyEpoch. Ключ эпохи выпускает первый загрузивший его клиент, остальные получают
+// его ключ. Клиенты, не подключённые к комнате, узнают о новой эпохе при подключении.
+func (h *Hub) NotifyKeyRotation(roomUUID uuid.UUID, keyEpoch int64) {
+	data, err := json.Marshal(Envelope{
+		Version:  ProtocolVersion,
+		Type:     TypeKeyRotation,
+		RoomUUID: roomUUID,
+		KeyEpoch: keyEpoch,
+	})
+	if err != nil {
+		return
+	}
+	ctx, cancel := h.publishContext()
+	defer cancel()
+	h.publish(ctx, Event{RoomUUID: roomUUID, Frame: data})
+}
+
+// NotifyRoomUpdated уведомляет подключённых к любому экземпляру сервера клиентов комнаты
+// об изменении её названия или темы. Событие содержит текущие название и тему целиком;
+// пустые значения в конверт не попадают.
+func (h *Hub) NotifyRoomUpdated(roomUUID uuid.UUID, name string, topic string) {
+	data, err := json.Marshal(Envelope{
+		Version:  ProtocolVersion,
+		Type:     TypeRoomUpdated,
+		RoomUUID: roomUUID,
+		Name:     name,
+		Topic:    topic,
+	})
+	if err != nil {
+		return
+	}
+	ctx, cancel := h.publishContext()
+	defer cancel()
+	h.publish(ctx, Event{RoomUUID: roomUUID, Frame: data})
+}
+
+// Disconnect отключает клиентов пользователя userUUID, подключённых к любому экземпляру сервера:
+// только устройство deviceUUID или все устройства, если deviceUUID = uuid.Nil; только в комнате roomUUID
+// или во всех комнатах, если roomUUID = uuid.Nil. Если userUUID = uuid.Nil, отключаются все клиенты
+// комнаты roomUUID (например, при её удалении); без комнаты и пользователя вызов ничего не делает.
+// Соединения закрываются с кодом 1008 (policy violation) и причиной reason,
+// поэтому клиент не переподключается с прежними правами.
+func (h *Hub) Disconnect(roomUUID, userUUID, deviceUUID uuid.UUID, reason string) {
+	if roomUUID == uuid.Nil && userUUID == uuid.Nil {
+		return
+	}
+	ctx, cancel := h.publishContext()
+	defer cancel()
+	h.publish(ctx, Event{
+		RoomUUID:   roomUUID,
+		UserUUID:   userUUID,
+		DeviceUUID: deviceUUID,
+		Disconnect: reason,
+	})
+}
+
+// publishContext возвращает контекст публикации события, ограниченный временем публикации
+// и отменяемый при остановке хаба
+func (h *Hub) publishContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(h.ctx, h.publishTimeout)
+}
+
+// publish публикует событие через бэкенд. Если бэкенд недоступен,
+// событие доставляется хотя бы клиентам этого экземпляра сервера.
+func (h *Hub) publish(ctx context.Context, event Event) {
+	if err := h.backend.Publish(ctx, event); err != nil {
+		log.Printf("chat: не удалось опубликовать событие комнаты %s: %v", event.RoomUUID, err)
+		h.deliver(event)
+	}
+}
+
+// deliver доставляет событие, полученное от бэкенда, клиентам комнаты на этом экземпляре сервера.
+// После начала остановки хаба события, кроме отключений, не доставляются.
+func (h *Hub) deliver(event Event) {
+	if event.Disconnect != "" {
+		h.disconnect(event)
+		return
+	}
+
+	h.mu.Lock()
+	room, ok := h.rooms[event.RoomUUID]
+	closed := h.closed
+	h.mu.Unlock()
+	if ok && !closed {
+		room.deliver(event)
+	}
+}
+
+// disconnect отключает клиентов по событию отключения в комнате события или, если она не задана, во всех комнатах
+func (h *Hub) disconnect(event Event) {
+	var rooms []*ChatRoom
+	h.mu.Lock()
+	if event.RoomUUID == uuid.Nil {
+		for _, room := range h.rooms {
+			rooms = append(rooms, room)
+		}
+	} else if room, ok := h.rooms[event.RoomUUID]; ok {
+		rooms = append(rooms, room)
+	}
+	h.mu.Unlock()
+
+	for _, room := range rooms {
+		room.disconnect(event)
+	}
 }
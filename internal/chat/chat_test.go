@@ -1,17 +1,25 @@
 package chat
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/sbilibin2017/bil-message/internal/models"
 	"github.com/stretchr/testify/require"
 )
 
-// newTestWSHandler создаёт HTTP-хендлер, который апгрейдит соединение и добавляет клиента в комнату
-func newTestWSHandler(room *ChatRoom) http.HandlerFunc {
+// newTestWSHandler создаёт HTTP-хендлер, который апгрейдит соединение и добавляет клиента в комнату.
+// UUID пользователя можно задать параметром запроса user, иначе он генерируется; устройство всегда новое.
+func newTestWSHandler(room *ChatRoom, opts ...ClientOpt) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		upgrader := websocket.Upgrader{
 			CheckOrigin: func(r *http.Request) bool { return true },
@@ -19,7 +27,11 @@ func newTestWSHandler(room *ChatRoom) http.HandlerFunc {
 		conn, err := upgrader.Upgrade(w, r, nil)
 		require.NoError(nil, err) // nil потому что это внутри handler, можно panic
 
-		client := NewChatClient(conn, uuid.New(), room.RoomUUID)
+		userUUID, err := uuid.Parse(r.URL.Query().Get("user"))
+		if err != nil {
+			userUUID = uuid.New()
+		}
+		client := NewChatClient(conn, userUUID, uuid.New(), room.RoomUUID, opts...)
 		room.AddClient(client)
 
 		// запускаем горутины внутри методов
@@ -59,13 +71,12 @@ func TestChatRoomMultipleClients(t *testing.T) {
 	defer c2.Close()
 
 	// Отправляем сообщение от первого клиента
-	testMsg := []byte("hello")
-	require.NoError(t, c1.WriteMessage(websocket.TextMessage, testMsg))
+	require.NoError(t, c1.WriteMessage(websocket.TextMessage, messageFrame(1, "hello")))
 
 	// Проверяем, что второй клиент получил сообщение
-	_, msg, err := c2.ReadMessage()
-	require.NoError(t, err)
-	require.Equal(t, testMsg, msg)
+	env := readEnvelope(t, c2)
+	require.Equal(t, TypeMessage, env.Type)
+	require.Equal(t, "hello", env.Ciphertext)
 
 	room.mu.Lock()
 	require.Len(t, room.Members, 2)
@@ -80,7 +91,7 @@ func TestChatClientClose(t *testing.T) {
 		conn, err := upgrader.Upgrade(w, r, nil)
 		require.NoError(nil, err)
 
-		client := NewChatClient(conn, uuid.New(), room.RoomUUID)
+		client := NewChatClient(conn, uuid.New(), uuid.New(), room.RoomUUID)
 		room.AddClient(client)
 
 		client.ReadPump(room)
@@ -105,7 +116,7 @@ func TestChatClientClose(t *testing.T) {
 	room.RemoveClient(client)
 
 	room.mu.Lock()
-	_, exists := room.Members[client.UserUUID]
+	_, exists := room.Members[client.DeviceUUID]
 	room.mu.Unlock()
 	require.False(t, exists)
 
@@ -129,12 +140,986 @@ func TestChatRoomBroadcastMultipleClients(t *testing.T) {
 		clients[i] = c
 	}
 
-	testMsg := []byte("broadcast")
-	require.NoError(t, clients[0].WriteMessage(websocket.TextMessage, testMsg))
+	require.NoError(t, clients[0].WriteMessage(websocket.TextMessage, messageFrame(1, "broadcast")))
 
 	for i := 1; i < 3; i++ {
-		_, msg, err := clients[i].ReadMessage()
+		env := readEnvelope(t, clients[i])
+		require.Equal(t, "broadcast", env.Ciphertext)
+	}
+}
+
+// memoryStore — простое in-memory хранилище сообщений для тестов
+type memoryStore struct {
+	mu       sync.Mutex
+	messages []models.RoomMessageDB
+	keyEpoch int64
+}
+
+func (s *memoryStore) SaveMessage(
+	ctx context.Context,
+	roomUUID, senderUUID, senderDeviceUUID, clientMessageUUID uuid.UUID,
+	keyEpoch int64,
+	ciphertext string,
+) (*models.RoomMessageDB, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if clientMessageUUID != uuid.Nil {
+		for _, m := range s.messages {
+			if m.SenderUUID == senderUUID && m.ClientMessageUUID == clientMessageUUID {
+				return &m, true, nil
+			}
+		}
+	}
+	m := models.RoomMessageDB{
+		MessageUUID:       uuid.New(),
+		RoomUUID:          roomUUID,
+		SenderUUID:        senderUUID,
+		SenderDeviceUUID:  senderDeviceUUID,
+		ClientMessageUUID: clientMessageUUID,
+		KeyEpoch:          keyEpoch,
+		Ciphertext:        ciphertext,
+		SentAt:            time.Now().UTC(),
+	}
+	s.messages = append(s.messages, m)
+	return &m, false, nil
+}
+
+func (s *memoryStore) ListLastMessages(ctx context.Context, roomUUID uuid.UUID, limit int) ([]models.RoomMessageDB, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var result []models.RoomMessageDB
+	for _, m := range s.messages {
+		if m.RoomUUID == roomUUID {
+			result = append(result, m)
+		}
+	}
+	if len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
+}
+
+func (s *memoryStore) RoomKeyEpoch(ctx context.Context, roomUUID uuid.UUID) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.keyEpoch, nil
+}
+
+func (s *memoryStore) list() []models.RoomMessageDB {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]models.RoomMessageDB(nil), s.messages...)
+}
+
+func (s *memoryStore) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.messages)
+}
+
+// newTestWSHandlerWithHistory создаёт HTTP-хендлер, который добавляет клиента в комнату и отправляет ему историю
+func newTestWSHandlerWithHistory(room *ChatRoom) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(nil, err)
+
+		client := NewChatClient(conn, uuid.New(), uuid.New(), room.RoomUUID)
+		require.NoError(nil, room.Join(r.Context(), client))
+
+		client.ReadPump(room)
+		client.WritePump()
+	}
+}
+
+func TestChatRoomPersistAndReplayHistory(t *testing.T) {
+	store := &memoryStore{keyEpoch: 1}
+	room := NewChatRoom(uuid.New(), WithMessageStore(store), WithHistoryLimit(2))
+	server := httptest.NewServer(newTestWSHandlerWithHistory(room))
+	defer server.Close()
+
+	wsURL := "ws" + server.URL[len("http"):]
+
+	sender, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer sender.Close()
+
+	for _, text := range []string{"one", "two", "three"} {
+		require.NoError(t, sender.WriteMessage(websocket.TextMessage, messageFrame(1, text)))
+	}
+	require.Eventually(t, func() bool { return store.count() == 3 }, time.Second, 10*time.Millisecond)
+
+	// Новый клиент получает последние сообщения в порядке отправки
+	receiver, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer receiver.Close()
+
+	for _, expected := range store.list()[1:] {
+		env := readEnvelope(t, receiver)
+		require.Equal(t, expected.MessageUUID, env.MessageUUID)
+		require.Equal(t, expected.Ciphertext, env.Ciphertext)
+		require.Equal(t, expected.KeyEpoch, env.KeyEpoch)
+		require.Equal(t, expected.SenderDeviceUUID, env.SenderDevice)
+	}
+}
+
+// blockingStore отдаёт историю только после release, чтобы во время её загрузки можно было разослать сообщения
+type blockingStore struct {
+	*memoryStore
+	listing chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) ListLastMessages(ctx context.Context, roomUUID uuid.UUID, limit int) ([]models.RoomMessageDB, error) {
+	close(s.listing)
+	<-s.release
+	return s.memoryStore.ListLastMessages(ctx, roomUUID, limit)
+}
+
+func TestChatRoomJoinKeepsMessagesSentDuringReplay(t *testing.T) {
+	roomUUID := uuid.New()
+	store := &blockingStore{memoryStore: &memoryStore{keyEpoch: 1}, listing: make(chan struct{}), release: make(chan struct{})}
+	room := NewChatRoom(roomUUID, WithMessageStore(store), WithHistoryLimit(10))
+	sender := NewChatClient(nil, uuid.New(), uuid.New(), roomUUID)
+
+	first, _, err := store.SaveMessage(context.Background(), roomUUID, sender.UserUUID, sender.DeviceUUID, uuid.Nil, 1, "first")
+	require.NoError(t, err)
+
+	client := NewChatClient(nil, uuid.New(), uuid.New(), roomUUID)
+	joined := make(chan error, 1)
+	go func() { joined <- room.Join(context.Background(), client) }()
+	<-store.listing
+
+	// Пока загружается история, одно сообщение успевает попасть в неё, другое — нет
+	second, _, err := store.SaveMessage(context.Background(), roomUUID, sender.UserUUID, sender.DeviceUUID, uuid.Nil, 1, "second")
+	require.NoError(t, err)
+	third := models.RoomMessageDB{MessageUUID: uuid.New(), RoomUUID: roomUUID, SenderUUID: sender.UserUUID, KeyEpoch: 1, Ciphertext: "third"}
+	for _, m := range []models.RoomMessageDB{*second, third} {
+		data, err := json.Marshal(MessageEnvelope(m))
+		require.NoError(t, err)
+		room.Broadcast(data, sender.DeviceUUID)
+	}
+	close(store.release)
+	require.NoError(t, <-joined)
+
+	// Клиент получает историю, затем только не попавшее в неё сообщение
+	for _, expected := range []uuid.UUID{first.MessageUUID, second.MessageUUID, third.MessageUUID} {
+		var env Envelope
+		require.NoError(t, json.Unmarshal(<-client.Send, &env))
+		require.Equal(t, expected, env.MessageUUID)
+	}
+	require.Empty(t, client.Send)
+
+	// После загрузки истории сообщения отправляются сразу
+	room.Broadcast([]byte("live"), sender.DeviceUUID)
+	require.Equal(t, []byte("live"), <-client.Send)
+}
+
+func TestChatRoomReplayHistoryWithoutStore(t *testing.T) {
+	room := NewChatRoom(uuid.New(), WithHistoryLimit(10))
+	client := NewChatClient(nil, uuid.New(), uuid.New(), room.RoomUUID)
+
+	require.NoError(t, room.ReplayHistory(context.Background(), client))
+	env, duplicate, err := room.SaveMessage(context.Background(), client, Envelope{Version: ProtocolVersion, Type: TypeMessage, Ciphertext: "msg", KeyEpoch: 1})
+	require.NoError(t, err)
+	require.False(t, duplicate)
+	require.NotEqual(t, uuid.Nil, env.MessageUUID)
+	require.Equal(t, client.DeviceUUID, env.SenderDevice)
+	require.Len(t, client.Send, 0)
+}
+
+func TestChatRoomRejectsInvalidFrames(t *testing.T) {
+	store := &memoryStore{keyEpoch: 1}
+	room := NewChatRoom(uuid.New(), WithMessageStore(store))
+	server := httptest.NewServer(newTestWSHandler(room))
+	defer server.Close()
+
+	wsURL := "ws" + server.URL[len("http"):]
+
+	sender, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer sender.Close()
+
+	receiver, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer receiver.Close()
+
+	require.Eventually(t, func() bool {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return len(room.Members) == 2
+	}, time.Second, 10*time.Millisecond)
+
+	rejected := []struct {
+		frame string
+		code  string
+	}{
+		{"plaintext", ErrorCodeInvalidFrame},
+		{`{"v":2,"type":"message","ciphertext":"c","key_epoch":1}`, ErrorCodeUnsupportedVersion},
+		{`{"v":1,"type":"typing"}`, ErrorCodeUnknownType},
+		{`{"v":1,"type":"key_rotation","key_epoch":5}`, ErrorCodeUnknownType},
+		{`{"v":1,"type":"message","ciphertext":"c"}`, ErrorCodeInvalidFrame},
+	}
+	for _, r := range rejected {
+		require.NoError(t, sender.WriteMessage(websocket.TextMessage, []byte(r.frame)))
+
+		// Отправитель получает типизированную ошибку
+		env := readEnvelope(t, sender)
+		require.Equal(t, TypeError, env.Type, r.frame)
+		require.Equal(t, r.code, env.Code, r.frame)
+	}
+
+	// Поля, проставляемые сервером, нельзя подделать
+	forged := `{"v":1,"type":"message","ciphertext":"c","key_epoch":1,"sender_uuid":"` + uuid.NewString() + `"}`
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage, []byte(forged)))
+
+	// Получатель видит только корректное сообщение
+	env := readEnvelope(t, receiver)
+	require.Equal(t, TypeMessage, env.Type)
+	require.Equal(t, store.list()[0].SenderUUID, env.SenderUUID)
+	require.Equal(t, 1, store.count())
+}
+
+func TestHubNotifyKeyRotation(t *testing.T) {
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) })
+	roomUUID := uuid.New()
+
+	// Комната не открыта — уведомление никому не отправляется
+	hub.NotifyKeyRotation(roomUUID, 2)
+	require.Equal(t, 0, hub.RoomCount())
+
+	client := NewChatClient(nil, uuid.New(), uuid.New(), roomUUID)
+	room, err := hub.Register(context.Background(), client, nil)
+	require.NoError(t, err)
+	require.Equal(t, roomUUID, room.RoomUUID)
+
+	hub.NotifyKeyRotation(roomUUID, 3)
+	require.Len(t, client.Send, 1)
+
+	var env Envelope
+	require.NoError(t, json.Unmarshal(<-client.Send, &env))
+	require.Equal(t, Envelope{Version: ProtocolVersion, Type: TypeKeyRotation, RoomUUID: roomUUID, KeyEpoch: 3}, env)
+}
+
+func TestHubNotifyRoomUpdated(t *testing.T) {
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) })
+	roomUUID := uuid.New()
+
+	client := NewChatClient(nil, uuid.New(), uuid.New(), roomUUID)
+	_, err := hub.Register(context.Background(), client, nil)
+	require.NoError(t, err)
+
+	hub.NotifyRoomUpdated(roomUUID, "Команда", "")
+	require.Len(t, client.Send, 1)
+
+	frame := <-client.Send
+	require.NotContains(t, string(frame), "topic")
+
+	var env Envelope
+	require.NoError(t, json.Unmarshal(frame, &env))
+	require.Equal(t, Envelope{Version: ProtocolVersion, Type: TypeRoomUpdated, RoomUUID: roomUUID, Name: "Команда"}, env)
+}
+
+func TestHubDisconnect(t *testing.T) {
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) })
+	firstRoom, secondRoom := uuid.New(), uuid.New()
+	aliceUUID, bobUUID := uuid.New(), uuid.New()
+	alicePhone, aliceLaptop := uuid.New(), uuid.New()
+
+	phoneFirst := NewChatClient(nil, aliceUUID, alicePhone, firstRoom)
+	phoneSecond := NewChatClient(nil, aliceUUID, alicePhone, secondRoom)
+	laptopFirst := NewChatClient(nil, aliceUUID, aliceLaptop, firstRoom)
+	bob := NewChatClient(nil, bobUUID, uuid.New(), firstRoom)
+	for _, client := range []*ChatClient{phoneFirst, phoneSecond, laptopFirst, bob} {
+		_, err := hub.Register(context.Background(), client, nil)
+		require.NoError(t, err)
+	}
+
+	// Устройство отключается во всех комнатах, остальные устройства пользователя остаются
+	hub.Disconnect(uuid.Nil, aliceUUID, alicePhone, "device revoked")
+	require.False(t, phoneFirst.trySend([]byte("after revoke")))
+	require.False(t, phoneSecond.trySend([]byte("after revoke")))
+	require.Equal(t, 2, hub.ConnectionCount())
+	require.Equal(t, 1, hub.RoomCount())
+
+	// Пользователь отключается только в указанной комнате
+	hub.Disconnect(firstRoom, aliceUUID, uuid.Nil, "removed from room")
+	require.False(t, laptopFirst.trySend([]byte("after remove")))
+	require.True(t, bob.trySend([]byte("still connected")))
+	require.Equal(t, 1, hub.ConnectionCount())
+
+	// Отключение в неоткрытой комнате ничего не делает
+	hub.Disconnect(uuid.New(), bobUUID, uuid.Nil, "removed from room")
+	require.Equal(t, 1, hub.ConnectionCount())
+
+	// Без комнаты и пользователя никто не отключается, а без пользователя отключается вся комната
+	hub.Disconnect(uuid.Nil, uuid.Nil, uuid.Nil, "room removed")
+	require.Equal(t, 1, hub.ConnectionCount())
+	hub.Disconnect(firstRoom, uuid.Nil, uuid.Nil, "room removed")
+	require.False(t, bob.trySend([]byte("after room removed")))
+	require.Equal(t, 0, hub.ConnectionCount())
+}
+
+func TestHubRegisterAdmit(t *testing.T) {
+	store := &memoryStore{keyEpoch: 1}
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom {
+		return NewChatRoom(roomUUID, WithMessageStore(store), WithHistoryLimit(10))
+	})
+	roomUUID := uuid.New()
+	_, _, err := store.SaveMessage(context.Background(), roomUUID, uuid.New(), uuid.New(), uuid.New(), 1, "history")
+	require.NoError(t, err)
+
+	// Клиент без доступа не получает историю и удаляется из комнаты; соединение закрывает вызывающий
+	errDenied := errors.New("denied")
+	denied := NewChatClient(nil, uuid.New(), uuid.New(), roomUUID)
+	_, err = hub.Register(context.Background(), denied, func(ctx context.Context) error {
+		require.Equal(t, 1, hub.ConnectionCount())
+		return errDenied
+	})
+	require.ErrorIs(t, err, errDenied)
+	require.Empty(t, denied.Send)
+	require.Equal(t, 0, hub.RoomCount())
+	select {
+	case <-denied.closed:
+		t.Fatal("denied client must be closed by the caller")
+	default:
+	}
+
+	// Клиент с доступом получает историю
+	allowed := NewChatClient(nil, uuid.New(), uuid.New(), roomUUID)
+	_, err = hub.Register(context.Background(), allowed, func(ctx context.Context) error { return nil })
+	require.NoError(t, err)
+	require.Len(t, allowed.Send, 1)
+	var env Envelope
+	require.NoError(t, json.Unmarshal(<-allowed.Send, &env))
+	require.Equal(t, "history", env.Ciphertext)
+}
+
+func TestHubLifecycle(t *testing.T) {
+	store := &memoryStore{keyEpoch: 1}
+	created := 0
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom {
+		created++
+		return NewChatRoom(roomUUID, WithMessageStore(store), WithHistoryLimit(10))
+	})
+	firstRoom, secondRoom := uuid.New(), uuid.New()
+
+	alice := NewChatClient(nil, uuid.New(), uuid.New(), firstRoom)
+	bob := NewChatClient(nil, uuid.New(), uuid.New(), firstRoom)
+	carol := NewChatClient(nil, uuid.New(), uuid.New(), secondRoom)
+
+	room, err := hub.Register(context.Background(), alice, nil)
+	require.NoError(t, err)
+	bobRoom, err := hub.Register(context.Background(), bob, nil)
+	require.NoError(t, err)
+	require.Same(t, room, bobRoom)
+	_, err = hub.Register(context.Background(), carol, nil)
+	require.NoError(t, err)
+	require.Equal(t, 2, hub.RoomCount())
+	require.Equal(t, 3, hub.ConnectionCount())
+	require.Equal(t, 2, room.ClientCount())
+
+	// Комната остаётся открытой, пока в ней есть клиенты
+	room.RemoveClient(alice)
+	require.Equal(t, 2, hub.RoomCount())
+	require.Equal(t, 2, hub.ConnectionCount())
+
+	// Отключение последнего клиента удаляет комнату из хаба
+	hub.Unregister(bob)
+	require.Equal(t, 1, hub.RoomCount())
+	require.Equal(t, 1, hub.ConnectionCount())
+	require.False(t, bob.trySend([]byte("after close")))
+
+	// Повторное отключение безопасно
+	hub.Unregister(bob)
+	room.RemoveClient(bob)
+	require.Equal(t, 1, hub.RoomCount())
+
+	// Повторное подключение открывает комнату заново
+	dave := NewChatClient(nil, uuid.New(), uuid.New(), firstRoom)
+	daveRoom, err := hub.Register(context.Background(), dave, nil)
+	require.NoError(t, err)
+	require.NotSame(t, room, daveRoom)
+	require.Equal(t, 3, created)
+	require.Equal(t, 2, hub.RoomCount())
+}
+
+func TestHubEvictsRoomsOnDisconnect(t *testing.T) {
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) })
+	roomUUID := uuid.New()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(nil, err)
+
+		client := NewChatClient(conn, uuid.New(), uuid.New(), roomUUID)
+		room, err := hub.Register(r.Context(), client, nil)
+		require.NoError(nil, err)
+		client.ReadPump(room)
+		client.WritePump()
+	}))
+	defer server.Close()
+
+	wsURL := "ws" + server.URL[len("http"):]
+	conns := make([]*websocket.Conn, 3)
+	for i := range conns {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		require.NoError(t, err)
+		conns[i] = conn
+	}
+	require.Eventually(t, func() bool { return hub.ConnectionCount() == 3 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, hub.RoomCount())
+
+	for _, conn := range conns {
+		require.NoError(t, conn.Close())
+	}
+	require.Eventually(t, func() bool { return hub.RoomCount() == 0 }, time.Second, 10*time.Millisecond)
+	require.Equal(t, 0, hub.ConnectionCount())
+}
+
+// messageFrame формирует кадр клиента с конвертом сообщения
+func messageFrame(keyEpoch int64, ciphertext string) []byte {
+	data, _ := json.Marshal(Envelope{Version: ProtocolVersion, Type: TypeMessage, Ciphertext: ciphertext, KeyEpoch: keyEpoch})
+	return data
+}
+
+// readEnvelope читает и разбирает следующий конверт из соединения
+func readEnvelope(t *testing.T, conn *websocket.Conn) Envelope {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, msg, err := conn.ReadMessage()
+	require.NoError(t, err)
+
+	var env Envelope
+	require.NoError(t, json.Unmarshal(msg, &env))
+	return env
+}
+
+// dialPair подключает к комнате двух клиентов и ждёт, пока оба будут добавлены
+func dialPair(t *testing.T, room *ChatRoom, wsURL string) (*websocket.Conn, *websocket.Conn) {
+	t.Helper()
+	first, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { first.Close() })
+
+	second, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { second.Close() })
+
+	require.Eventually(t, func() bool {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return len(room.Members) == 2
+	}, time.Second, 10*time.Millisecond)
+	return first, second
+}
+
+func TestChatRoomAcksAndDeduplicatesResends(t *testing.T) {
+	store := &memoryStore{keyEpoch: 1}
+	room := NewChatRoom(uuid.New(), WithMessageStore(store))
+	server := httptest.NewServer(newTestWSHandler(room))
+	defer server.Close()
+
+	sender, receiver := dialPair(t, room, "ws"+server.URL[len("http"):])
+
+	clientUUID := uuid.New()
+	frame, err := json.Marshal(Envelope{
+		Version:           ProtocolVersion,
+		Type:              TypeMessage,
+		ClientMessageUUID: clientUUID,
+		Ciphertext:        "hello",
+		KeyEpoch:          1,
+	})
+	require.NoError(t, err)
+
+	// Отправитель получает подтверждение сохранения с UUID, присвоенным сервером
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage, frame))
+	ack := readEnvelope(t, sender)
+	require.Equal(t, TypeAck, ack.Type)
+	require.Equal(t, clientUUID, ack.ClientMessageUUID)
+	require.NotEqual(t, uuid.Nil, ack.MessageUUID)
+
+	received := readEnvelope(t, receiver)
+	require.Equal(t, ack.MessageUUID, received.MessageUUID)
+	require.Equal(t, clientUUID, received.ClientMessageUUID)
+
+	// Повтор после переподключения подтверждается тем же UUID, но не рассылается
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage, frame))
+	again := readEnvelope(t, sender)
+	require.Equal(t, ack.MessageUUID, again.MessageUUID)
+	require.Equal(t, 1, store.count())
+
+	receiver.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err = receiver.ReadMessage()
+	require.Error(t, err)
+}
+
+func TestChatRoomRejectsStaleKeyEpoch(t *testing.T) {
+	store := &memoryStore{keyEpoch: 2}
+	room := NewChatRoom(uuid.New(), WithMessageStore(store))
+	server := httptest.NewServer(newTestWSHandler(room))
+	defer server.Close()
+
+	sender, receiver := dialPair(t, room, "ws"+server.URL[len("http"):])
+
+	clientUUID := uuid.New()
+	frame, err := json.Marshal(Envelope{
+		Version:           ProtocolVersion,
+		Type:              TypeMessage,
+		ClientMessageUUID: clientUUID,
+		Ciphertext:        "old key",
+		KeyEpoch:          1,
+	})
+	require.NoError(t, err)
+
+	// Сообщение ключом прошлой эпохи не сохраняется и не рассылается, отправитель получает ошибку
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage, frame))
+	env := readEnvelope(t, sender)
+	require.Equal(t, TypeError, env.Type)
+	require.Equal(t, ErrorCodeStaleKeyEpoch, env.Code)
+	require.Equal(t, clientUUID, env.ClientMessageUUID)
+	require.Equal(t, 0, store.count())
+
+	receiver.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err = receiver.ReadMessage()
+	require.Error(t, err)
+}
+
+// stalledStore не отвечает на сохранение сообщения до отмены контекста, как зависшая база данных
+type stalledStore struct {
+	*memoryStore
+	saving   chan struct{}
+	canceled chan error
+}
+
+func (s *stalledStore) SaveMessage(
+	ctx context.Context,
+	roomUUID, senderUUID, senderDeviceUUID, clientMessageUUID uuid.UUID,
+	keyEpoch int64,
+	ciphertext string,
+) (*models.RoomMessageDB, bool, error) {
+	close(s.saving)
+	<-ctx.Done()
+	s.canceled <- ctx.Err()
+	return nil, false, ctx.Err()
+}
+
+func TestChatRoomFrameTimeout(t *testing.T) {
+	store := &stalledStore{memoryStore: &memoryStore{keyEpoch: 1}, saving: make(chan struct{}), canceled: make(chan error, 1)}
+	room := NewChatRoom(uuid.New(), WithMessageStore(store), WithFrameTimeout(0, 50*time.Millisecond))
+	server := httptest.NewServer(newTestWSHandler(room))
+	defer server.Close()
+
+	sender, _ := dialPair(t, room, "ws"+server.URL[len("http"):])
+
+	// Зависшее хранилище не блокирует соединение: по истечении времени отправитель получает ошибку
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage, messageFrame(1, "hello")))
+	env := readEnvelope(t, sender)
+	require.Equal(t, TypeError, env.Type)
+	require.ErrorIs(t, <-store.canceled, context.DeadlineExceeded)
+	require.Equal(t, 2, room.ClientCount())
+}
+
+func TestHubShutdownCancelsFrameProcessing(t *testing.T) {
+	store := &stalledStore{memoryStore: &memoryStore{keyEpoch: 1}, saving: make(chan struct{}), canceled: make(chan error, 1)}
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID, WithMessageStore(store)) })
+	roomUUID := uuid.New()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(nil, err)
+
+		client := NewChatClient(conn, uuid.New(), uuid.New(), roomUUID)
+		room, err := hub.Register(r.Context(), client, nil)
+		require.NoError(nil, err)
+		client.ReadPump(room)
+		client.WritePump()
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[len("http"):], nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, messageFrame(1, "hello")))
+	<-store.saving
+
+	// Остановка хаба отменяет сохранение, не дожидаясь времени обработки кадра
+	require.NoError(t, hub.Shutdown(context.Background()))
+	select {
+	case err := <-store.canceled:
+		require.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("frame processing was not canceled on shutdown")
+	}
+}
+
+func TestChatRoomForwardsReceipts(t *testing.T) {
+	room := NewChatRoom(uuid.New())
+	server := httptest.NewServer(newTestWSHandler(room))
+	defer server.Close()
+
+	sender, receiver := dialPair(t, room, "ws"+server.URL[len("http"):])
+
+	require.NoError(t, sender.WriteMessage(websocket.TextMessage, messageFrame(1, "hello")))
+	ack := readEnvelope(t, sender)
+	message := readEnvelope(t, receiver)
+
+	receipt, err := json.Marshal(Envelope{
+		Version:     ProtocolVersion,
+		Type:        TypeReceipt,
+		MessageUUID: message.MessageUUID,
+		SenderUUID:  message.SenderUUID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, receiver.WriteMessage(websocket.TextMessage, receipt))
+
+	delivered := readEnvelope(t, sender)
+	require.Equal(t, TypeReceipt, delivered.Type)
+	require.Equal(t, ack.MessageUUID, delivered.MessageUUID)
+	require.Equal(t, room.RoomUUID, delivered.RoomUUID)
+	require.NotEqual(t, uuid.Nil, delivered.RecipientUUID)
+	require.NotEqual(t, message.SenderUUID, delivered.RecipientUUID)
+}
+
+func TestChatRoomDisconnectsSlowClient(t *testing.T) {
+	room := NewChatRoom(uuid.New())
+	slow := NewChatClient(nil, uuid.New(), uuid.New(), room.RoomUUID)
+	room.AddClient(slow)
+
+	for i := 0; i < cap(slow.Send); i++ {
+		room.Broadcast([]byte("msg"), uuid.New())
+	}
+	room.mu.Lock()
+	require.Len(t, room.Members, 1)
+	room.mu.Unlock()
+
+	// Очередь переполнена — клиент отключается, а не теряет сообщение молча
+	room.Broadcast([]byte("overflow"), uuid.New())
+	room.mu.Lock()
+	require.Empty(t, room.Members)
+	room.mu.Unlock()
+	require.False(t, slow.trySend([]byte("after close")))
+}
+
+func TestChatRoomRemovesClientOnDisconnect(t *testing.T) {
+	room := NewChatRoom(uuid.New())
+	server := httptest.NewServer(newTestWSHandler(room))
+	defer server.Close()
+
+	first, second := dialPair(t, room, "ws"+server.URL[len("http"):])
+	require.NoError(t, first.Close())
+
+	require.Eventually(t, func() bool {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return len(room.Members) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Рассылка после отключения участника не паникует и доходит до оставшихся
+	require.NoError(t, second.WriteMessage(websocket.TextMessage, messageFrame(1, "still here")))
+	require.Equal(t, TypeAck, readEnvelope(t, second).Type)
+}
+
+func TestChatRoomFansOutToAllDevices(t *testing.T) {
+	room := NewChatRoom(uuid.New())
+	server := httptest.NewServer(newTestWSHandler(room))
+	defer server.Close()
+
+	wsURL := "ws" + server.URL[len("http"):]
+	aliceUUID := uuid.New()
+
+	dial := func(query string) *websocket.Conn {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+query, nil)
 		require.NoError(t, err)
-		require.Equal(t, testMsg, msg)
+		t.Cleanup(func() { conn.Close() })
+		return conn
+	}
+	alicePhone := dial("?user=" + aliceUUID.String())
+	aliceLaptop := dial("?user=" + aliceUUID.String())
+	bob := dial("")
+
+	require.Eventually(t, func() bool {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return len(room.Members) == 3
+	}, time.Second, 10*time.Millisecond)
+
+	// Сообщение доходит до всех устройств, включая другие устройства отправителя
+	require.NoError(t, alicePhone.WriteMessage(websocket.TextMessage, messageFrame(1, "hello")))
+	ack := readEnvelope(t, alicePhone)
+	require.Equal(t, TypeAck, ack.Type)
+
+	for _, conn := range []*websocket.Conn{aliceLaptop, bob} {
+		env := readEnvelope(t, conn)
+		require.Equal(t, TypeMessage, env.Type)
+		require.Equal(t, ack.MessageUUID, env.MessageUUID)
+		require.Equal(t, aliceUUID, env.SenderUUID)
+	}
+
+	// Квитанция с другого устройства отправителя не пересылается,
+	// квитанция другого участника доходит до всех устройств отправителя
+	receipt, err := json.Marshal(Envelope{
+		Version:     ProtocolVersion,
+		Type:        TypeReceipt,
+		MessageUUID: ack.MessageUUID,
+		SenderUUID:  aliceUUID,
+	})
+	require.NoError(t, err)
+	require.NoError(t, aliceLaptop.WriteMessage(websocket.TextMessage, receipt))
+	require.NoError(t, bob.WriteMessage(websocket.TextMessage, receipt))
+
+	for _, conn := range []*websocket.Conn{alicePhone, aliceLaptop} {
+		env := readEnvelope(t, conn)
+		require.Equal(t, TypeReceipt, env.Type)
+		require.Equal(t, ack.MessageUUID, env.MessageUUID)
+		require.NotEqual(t, aliceUUID, env.RecipientUUID)
+	}
+}
+
+func TestChatRoomReplacesConnectionOfSameDevice(t *testing.T) {
+	room := NewChatRoom(uuid.New())
+	userUUID, deviceUUID := uuid.New(), uuid.New()
+
+	previous := NewChatClient(nil, userUUID, deviceUUID, room.RoomUUID)
+	room.AddClient(previous)
+	current := NewChatClient(nil, userUUID, deviceUUID, room.RoomUUID)
+	room.AddClient(current)
+
+	// Прежнее подключение устройства закрыто, его отключение не удаляет новое
+	require.Eventually(t, func() bool { return !previous.trySend([]byte("msg")) }, time.Second, 5*time.Millisecond)
+	room.RemoveClient(previous)
+
+	room.mu.Lock()
+	require.Len(t, room.Members, 1)
+	require.Same(t, current, room.Members[deviceUUID])
+	room.mu.Unlock()
+}
+
+func TestChatClientClosesOversizedFrames(t *testing.T) {
+	room := NewChatRoom(uuid.New())
+	server := httptest.NewServer(newTestWSHandler(room, WithMaxMessageSize(128)))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[len("http"):], nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Кадр в пределах лимита обрабатывается
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, messageFrame(1, "short")))
+	require.Equal(t, TypeAck, readEnvelope(t, conn).Type)
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, messageFrame(1, strings.Repeat("x", 256))))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), "unexpected error: %v", err)
+
+	require.Eventually(t, func() bool {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return len(room.Members) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestChatClientClosesBinaryFrames(t *testing.T) {
+	room := NewChatRoom(uuid.New())
+	server := httptest.NewServer(newTestWSHandler(room))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[len("http"):], nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, conn.WriteMessage(websocket.BinaryMessage, messageFrame(1, "binary")))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseUnsupportedData), "unexpected error: %v", err)
+}
+
+func TestChatClientKeepalive(t *testing.T) {
+	room := NewChatRoom(uuid.New())
+	server := httptest.NewServer(newTestWSHandler(room,
+		WithPingInterval(20*time.Millisecond),
+		WithPongTimeout(100*time.Millisecond),
+	))
+	defer server.Close()
+
+	wsURL := "ws" + server.URL[len("http"):]
+
+	// Клиент, отвечающий на ping, остаётся подключённым дольше времени ожидания pong
+	alive, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer alive.Close()
+
+	var pings sync.WaitGroup
+	pings.Add(1)
+	var once sync.Once
+	alive.SetPingHandler(func(data string) error {
+		once.Do(pings.Done)
+		return alive.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+	})
+	go func() {
+		for {
+			if _, _, err := alive.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Клиент, который не читает кадры и не отвечает на ping, отключается по таймауту
+	dead, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer dead.Close()
+
+	require.Eventually(t, func() bool {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return len(room.Members) == 2
+	}, time.Second, 5*time.Millisecond)
+
+	pings.Wait()
+	require.Eventually(t, func() bool {
+		room.mu.Lock()
+		defer room.mu.Unlock()
+		return len(room.Members) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	time.Sleep(200 * time.Millisecond)
+	room.mu.Lock()
+	require.Len(t, room.Members, 1)
+	room.mu.Unlock()
+}
+
+func TestChatClientOptions(t *testing.T) {
+	c := NewChatClient(nil, uuid.New(), uuid.New(), uuid.New())
+	require.Equal(t, DefaultPingInterval, c.pingInterval)
+	require.Equal(t, DefaultPongTimeout, c.pongTimeout)
+	require.Equal(t, DefaultWriteTimeout, c.writeTimeout)
+	require.Equal(t, int64(DefaultMaxMessageSize), c.maxMessageSize)
+
+	c = NewChatClient(nil, uuid.New(), uuid.New(), uuid.New(),
+		WithPingInterval(0, time.Second),
+		WithPongTimeout(-1, 2*time.Second),
+		WithWriteTimeout(3*time.Second),
+		WithMaxMessageSize(0, 1024),
+	)
+	require.Equal(t, time.Second, c.pingInterval)
+	require.Equal(t, 2*time.Second, c.pongTimeout)
+	require.Equal(t, 3*time.Second, c.writeTimeout)
+	require.Equal(t, int64(1024), c.maxMessageSize)
+}
+
+func TestTimeoutOptions(t *testing.T) {
+	room := NewChatRoom(uuid.New())
+	require.Equal(t, DefaultFrameTimeout, room.frameTimeout)
+	room = NewChatRoom(uuid.New(), WithFrameTimeout(-1, time.Second))
+	require.Equal(t, time.Second, room.frameTimeout)
+
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) })
+	require.Equal(t, DefaultPublishTimeout, hub.publishTimeout)
+	hub = NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) }, WithPublishTimeout(0, 2*time.Second))
+	require.Equal(t, 2*time.Second, hub.publishTimeout)
+}
+
+func TestHubShutdownFlushesQueuesAndSendsGoingAway(t *testing.T) {
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) })
+	roomUUID := uuid.New()
+
+	registered := make(chan *ChatClient, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		require.NoError(nil, err)
+
+		client := NewChatClient(conn, uuid.New(), uuid.New(), roomUUID)
+		room, err := hub.Register(r.Context(), client, nil)
+		require.NoError(nil, err)
+		client.ReadPump(room)
+		registered <- client
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[len("http"):], nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := <-registered
+
+	// Кадры стоят в очереди, но ещё не отправлены
+	for i := int64(1); i <= 3; i++ {
+		hub.NotifyKeyRotation(roomUUID, i)
+	}
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- hub.Shutdown(context.Background()) }()
+	require.Eventually(t, func() bool { return !client.trySend([]byte("late")) }, time.Second, 5*time.Millisecond)
+	client.WritePump()
+
+	for i := int64(1); i <= 3; i++ {
+		env := readEnvelope(t, conn)
+		require.Equal(t, TypeKeyRotation, env.Type)
+		require.Equal(t, i, env.KeyEpoch)
+	}
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), "unexpected error: %v", err)
+	require.NoError(t, <-shutdownErr)
+
+	// После остановки новые клиенты не регистрируются
+	_, err = hub.Register(context.Background(), NewChatClient(nil, uuid.New(), uuid.New(), roomUUID), nil)
+	require.ErrorIs(t, err, ErrHubClosed)
+}
+
+func TestHubShutdownSkipsClosingClients(t *testing.T) {
+	store := &memoryStore{keyEpoch: 1}
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID, WithMessageStore(store)) })
+	roomUUID := uuid.New()
+
+	sender := NewChatClient(nil, uuid.New(), uuid.New(), roomUUID)
+	receiver := NewChatClient(nil, uuid.New(), uuid.New(), roomUUID)
+	room, err := hub.Register(context.Background(), sender, nil)
+	require.NoError(t, err)
+	_, err = hub.Register(context.Background(), receiver, nil)
+	require.NoError(t, err)
+
+	shutdownErr := make(chan error, 1)
+	go func() { shutdownErr <- hub.Shutdown(context.Background()) }()
+	require.Eventually(t, func() bool { return receiver.closing() && room.isClosing() }, time.Second, 5*time.Millisecond)
+
+	// Рассылка во время остановки не считает отправляющих очередь клиентов медленными
+	room.Broadcast(messageFrame(1, "late"), sender.DeviceUUID)
+	hub.NotifyKeyRotation(roomUUID, 2)
+	select {
+	case <-receiver.closed:
+		t.Fatal("closing client was disconnected as slow")
+	default:
+	}
+	require.Equal(t, 2, room.ClientCount())
+
+	sender.WritePump()
+	receiver.WritePump()
+	require.NoError(t, <-shutdownErr)
+	require.Equal(t, websocket.CloseGoingAway, receiver.drainCode)
+	require.Empty(t, receiver.Send)
+}
+
+func TestHubShutdownRespectsContext(t *testing.T) {
+	hub := NewHub(func(roomUUID uuid.UUID) *ChatRoom { return NewChatRoom(roomUUID) })
+	client := NewChatClient(nil, uuid.New(), uuid.New(), uuid.New())
+	_, err := hub.Register(context.Background(), client, nil)
+	require.NoError(t, err)
+
+	// WritePump не запущен — очередь не будет отправлена до истечения контекста
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, hub.Shutdown(ctx), context.DeadlineExceeded)
+
+	select {
+	case <-client.closed:
+	default:
+		t.Fatal("client must be closed after shutdown deadline")
 	}
 }
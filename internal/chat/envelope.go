@@ -0,0 +1,163 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// ProtocolVersion — текущая версия конверта протокола WebSocket
+const ProtocolVersion = 1
+
+// Типы конвертов протокола WebSocket
+const (
+	TypeMessage     = "message"      // зашифрованное сообщение участника комнаты
+	TypeAck         = "ack"          // подтверждение отправителю, что сообщение сохранено сервером
+	TypeReceipt     = "receipt"      // квитанция о доставке сообщения получателю
+	TypeKeyRotation = "key_rotation" // начало новой эпохи ключа комнаты
+	TypeRoomUpdated = "room_updated" // изменение названия или темы комнаты
+	TypeError       = "error"        // ошибка обработки кадра клиента
+)
+
+// Коды ошибок в конвертах типа error
+const (
+	ErrorCodeUnsupportedVersion = "unsupported_version" // неподдерживаемая версия протокола
+	ErrorCodeUnknownType        = "unknown_type"        // неизвестный тип конверта
+	ErrorCodeInvalidFrame       = "invalid_frame"       // кадр не является корректным конвертом сообщения
+	ErrorCodeStaleKeyEpoch      = "stale_key_epoch"     // сообщение зашифровано ключом не текущей эпохи комнаты
+	ErrorCodeInternal           = "internal"            // внутренняя ошибка сервера
+)
+
+var (
+	// ErrInvalidFrame возвращается, если кадр не является корректным конвертом сообщения
+	ErrInvalidFrame = errors.New("invalid message frame")
+	// ErrUnsupportedVersion возвращается, если версия конверта не поддерживается сервером
+	ErrUnsupportedVersion = errors.New("unsupported protocol version")
+	// ErrUnknownType возвращается, если тип конверта неизвестен или не может быть отправлен клиентом
+	ErrUnknownType = errors.New("unknown envelope type")
+	// ErrStaleKeyEpoch возвращается, если сообщение зашифровано ключом не текущей эпохи комнаты
+	ErrStaleKeyEpoch = errors.New("stale room key epoch")
+)
+
+// Envelope — версионированный JSON-конверт кадра протокола WebSocket.
+//
+// Клиент отправляет конверты двух типов:
+//   - message с полями ciphertext, key_epoch и необязательным client_message_uuid для дедупликации повторов;
+//   - receipt с полями message_uuid и sender_uuid полученного сообщения.
+//
+// message_uuid, room_uuid, sender_uuid, sender_device, sent_at сообщения и recipient_uuid квитанции
+// проставляет сервер, игнорируя присланные клиентом значения.
+type Envelope struct {
+	Version           int       `json:"v"`                            // версия протокола
+	Type              string    `json:"type"`                         // тип конверта
+	MessageUUID       uuid.UUID `json:"message_uuid,omitzero"`        // UUID сообщения
+	ClientMessageUUID uuid.UUID `json:"client_message_uuid,omitzero"` // UUID сообщения, присвоенный клиентом; для type=error — отклонённого сообщения
+	RoomUUID          uuid.UUID `json:"room_uuid,omitzero"`           // UUID комнаты
+	SenderUUID        uuid.UUID `json:"sender_uuid,omitzero"`         // UUID отправителя
+	SenderDevice      uuid.UUID `json:"sender_device,omitzero"`       // UUID устройства отправителя
+	RecipientUUID     uuid.UUID `json:"recipient_uuid,omitzero"`      // UUID получателя (для type=receipt)
+	SentAt            time.Time `json:"sent_at,omitzero"`             // время приёма сообщения сервером
+	Ciphertext        string    `json:"ciphertext,omitempty"`         // шифртекст сообщения
+	KeyEpoch          int64     `json:"key_epoch,omitempty"`          // эпоха ключа комнаты
+	Name              string    `json:"name,omitempty"`               // название комнаты (для type=room_updated)
+	Topic             string    `json:"topic,omitempty"`              // тема комнаты (для type=room_updated)
+	Code              string    `json:"code,omitempty"`               // код ошибки (для type=error)
+	Message           string    `json:"message,omitempty"`            // описание ошибки (для type=error)
+}
+
+// ParseEnvelope разбирает кадр клиента и проверяет, что это конверт поддерживаемой версии
+// одного из типов, которые может отправлять клиент. Поля, которые проставляет сервер, сбрасываются.
+func ParseEnvelope(frame []byte) (Envelope, error) {
+	var env Envelope
+	if err := json.Unmarshal(frame, &env); err != nil {
+		return Envelope{}, ErrInvalidFrame
+	}
+	if env.Version != ProtocolVersion {
+		return Envelope{}, ErrUnsupportedVersion
+	}
+
+	switch env.Type {
+	case TypeMessage:
+		if env.Ciphertext == "" || env.KeyEpoch <= 0 {
+			return Envelope{}, ErrInvalidFrame
+		}
+		return Envelope{
+			Version:           ProtocolVersion,
+			Type:              TypeMessage,
+			ClientMessageUUID: env.ClientMessageUUID,
+			Ciphertext:        env.Ciphertext,
+			KeyEpoch:          env.KeyEpoch,
+		}, nil
+	case TypeReceipt:
+		if env.MessageUUID == uuid.Nil || env.SenderUUID == uuid.Nil {
+			return Envelope{}, ErrInvalidFrame
+		}
+		return Envelope{
+			Version:     ProtocolVersion,
+			Type:        TypeReceipt,
+			MessageUUID: env.MessageUUID,
+			SenderUUID:  env.SenderUUID,
+		}, nil
+	default:
+		return Envelope{}, ErrUnknownType
+	}
+}
+
+// MessageEnvelope формирует конверт сообщения из записи истории комнаты
+func MessageEnvelope(m models.RoomMessageDB) Envelope {
+	return Envelope{
+		Version:           ProtocolVersion,
+		Type:              TypeMessage,
+		MessageUUID:       m.MessageUUID,
+		ClientMessageUUID: m.ClientMessageUUID,
+		RoomUUID:          m.RoomUUID,
+		SenderUUID:        m.SenderUUID,
+		SenderDevice:      m.SenderDeviceUUID,
+		SentAt:            m.SentAt,
+		Ciphertext:        m.Ciphertext,
+		KeyEpoch:          m.KeyEpoch,
+	}
+}
+
+// AckEnvelope формирует подтверждение отправителю, что сообщение сохранено сервером
+func AckEnvelope(message Envelope) Envelope {
+	return Envelope{
+		Version:           ProtocolVersion,
+		Type:              TypeAck,
+		MessageUUID:       message.MessageUUID,
+		ClientMessageUUID: message.ClientMessageUUID,
+		RoomUUID:          message.RoomUUID,
+		SentAt:            message.SentAt,
+	}
+}
+
+// ErrorEnvelope формирует конверт ошибки обработки кадра клиента
+func ErrorEnvelope(err error) Envelope {
+	code := ErrorCodeInternal
+	switch {
+	case errors.Is(err, ErrUnsupportedVersion):
+		code = ErrorCodeUnsupportedVersion
+	case errors.Is(err, ErrUnknownType):
+		code = ErrorCodeUnknownType
+	case errors.Is(err, ErrInvalidFrame):
+		code = ErrorCodeInvalidFrame
+	case errors.Is(err, ErrStaleKeyEpoch):
+		code = ErrorCodeStaleKeyEpoch
+	}
+
+	message := err.Error()
+	if code == ErrorCodeInternal {
+		// подробности внутренних ошибок клиенту не раскрываются
+		message = "internal server error"
+	}
+
+	return Envelope{
+		Version: ProtocolVersion,
+		Type:    TypeError,
+		Code:    code,
+		Message: message,
+	}
+}
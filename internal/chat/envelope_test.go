@@ -0,0 +1,125 @@
+package chat
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnvelope(t *testing.T) {
+	clientUUID, messageUUID, senderUUID := uuid.New(), uuid.New(), uuid.New()
+
+	tests := []struct {
+		name        string
+		frame       string
+		expected    Envelope
+		expectedErr error
+	}{
+		{
+			name:     "message",
+			frame:    `{"v":1,"type":"message","ciphertext":"c2VjcmV0","key_epoch":3}`,
+			expected: Envelope{Version: ProtocolVersion, Type: TypeMessage, Ciphertext: "c2VjcmV0", KeyEpoch: 3},
+		},
+		{
+			name:     "message with client UUID",
+			frame:    `{"v":1,"type":"message","client_message_uuid":"` + clientUUID.String() + `","ciphertext":"c","key_epoch":1}`,
+			expected: Envelope{Version: ProtocolVersion, Type: TypeMessage, ClientMessageUUID: clientUUID, Ciphertext: "c", KeyEpoch: 1},
+		},
+		{
+			name:     "receipt",
+			frame:    `{"v":1,"type":"receipt","message_uuid":"` + messageUUID.String() + `","sender_uuid":"` + senderUUID.String() + `","recipient_uuid":"` + uuid.NewString() + `"}`,
+			expected: Envelope{Version: ProtocolVersion, Type: TypeReceipt, MessageUUID: messageUUID, SenderUUID: senderUUID},
+		},
+		{name: "receipt without message", frame: `{"v":1,"type":"receipt","sender_uuid":"` + senderUUID.String() + `"}`, expectedErr: ErrInvalidFrame},
+		{name: "ack from client", frame: `{"v":1,"type":"ack","message_uuid":"` + messageUUID.String() + `"}`, expectedErr: ErrUnknownType},
+		{
+			name:     "server fields are dropped",
+			frame:    `{"v":1,"type":"message","ciphertext":"c","key_epoch":1,"message_uuid":"` + uuid.NewString() + `","sent_at":"2020-01-01T00:00:00Z"}`,
+			expected: Envelope{Version: ProtocolVersion, Type: TypeMessage, Ciphertext: "c", KeyEpoch: 1},
+		},
+		{name: "not json", frame: "1.c2VjcmV0", expectedErr: ErrInvalidFrame},
+		{name: "missing version", frame: `{"type":"message","ciphertext":"c","key_epoch":1}`, expectedErr: ErrUnsupportedVersion},
+		{name: "unknown type", frame: `{"v":1,"type":"typing"}`, expectedErr: ErrUnknownType},
+		{name: "server-only type", frame: `{"v":1,"type":"error","code":"internal"}`, expectedErr: ErrUnknownType},
+		{name: "missing ciphertext", frame: `{"v":1,"type":"message","key_epoch":1}`, expectedErr: ErrInvalidFrame},
+		{name: "missing key epoch", frame: `{"v":1,"type":"message","ciphertext":"c"}`, expectedErr: ErrInvalidFrame},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env, err := ParseEnvelope([]byte(tt.frame))
+			if tt.expectedErr != nil {
+				require.ErrorIs(t, err, tt.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, env)
+		})
+	}
+}
+
+func TestMessageEnvelope(t *testing.T) {
+	m := models.RoomMessageDB{
+		MessageUUID:      uuid.New(),
+		RoomUUID:         uuid.New(),
+		SenderUUID:       uuid.New(),
+		SenderDeviceUUID: uuid.New(),
+		KeyEpoch:         2,
+		Ciphertext:       "c2VjcmV0",
+		SentAt:           time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+
+	data, err := json.Marshal(MessageEnvelope(m))
+	require.NoError(t, err)
+	require.JSONEq(t, `{
+		"v": 1,
+		"type": "message",
+		"message_uuid": "`+m.MessageUUID.String()+`",
+		"room_uuid": "`+m.RoomUUID.String()+`",
+		"sender_uuid": "`+m.SenderUUID.String()+`",
+		"sender_device": "`+m.SenderDeviceUUID.String()+`",
+		"sent_at": "2025-01-02T03:04:05Z",
+		"ciphertext": "c2VjcmV0",
+		"key_epoch": 2
+	}`, string(data))
+}
+
+func TestAckEnvelope(t *testing.T) {
+	message := MessageEnvelope(models.RoomMessageDB{
+		MessageUUID:       uuid.New(),
+		ClientMessageUUID: uuid.New(),
+		RoomUUID:          uuid.New(),
+		SenderUUID:        uuid.New(),
+		Ciphertext:        "c2VjcmV0",
+		KeyEpoch:          1,
+		SentAt:            time.Now().UTC(),
+	})
+
+	ack := AckEnvelope(message)
+	require.Equal(t, TypeAck, ack.Type)
+	require.Equal(t, message.MessageUUID, ack.MessageUUID)
+	require.Equal(t, message.ClientMessageUUID, ack.ClientMessageUUID)
+	require.Equal(t, message.SentAt, ack.SentAt)
+	require.Empty(t, ack.Ciphertext)
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	require.Equal(t, ErrorCodeUnknownType, ErrorEnvelope(ErrUnknownType).Code)
+	require.Equal(t, ErrorCodeUnsupportedVersion, ErrorEnvelope(ErrUnsupportedVersion).Code)
+	require.Equal(t, ErrorCodeInvalidFrame, ErrorEnvelope(ErrInvalidFrame).Code)
+	require.Equal(t, ErrorCodeStaleKeyEpoch, ErrorEnvelope(ErrStaleKeyEpoch).Code)
+
+	internal := ErrorEnvelope(errors.New("pq: connection refused"))
+	require.Equal(t, TypeError, internal.Type)
+	require.Equal(t, ErrorCodeInternal, internal.Code)
+	require.NotContains(t, internal.Message, "pq")
+
+	data, err := json.Marshal(ErrorEnvelope(ErrUnknownType))
+	require.NoError(t, err)
+	require.JSONEq(t, `{"v":1,"type":"error","code":"unknown_type","message":"unknown envelope type"}`, string(data))
+}
@@ -0,0 +1,178 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// DefaultNotifyChannel — канал PostgreSQL LISTEN/NOTIFY для событий комнат по умолчанию
+	DefaultNotifyChannel = "chat_events"
+	// maxNotifyPayload — максимальный размер события, передаваемого в NOTIFY целиком
+	// (предел PostgreSQL — 8000 байт); события больше сохраняются в таблице chat_events
+	maxNotifyPayload = 7000
+	// chatEventRetention — время хранения больших событий в таблице chat_events
+	chatEventRetention = 5 * time.Minute
+	// listenRetryDelay — задержка перед повторным подключением к каналу после ошибки
+	listenRetryDelay = time.Second
+)
+
+// notification — содержимое NOTIFY: событие целиком или ссылка на событие в таблице chat_events
+type notification struct {
+	Event   *Event `json:"event,omitempty"`
+	EventID int64  `json:"event_id,omitempty"`
+}
+
+// PostgresBackend передаёт события комнат между экземплярами сервера через PostgreSQL LISTEN/NOTIFY.
+// Для прослушивания канала нужен драйвер pgx; Run должен быть запущен на каждом экземпляре.
+type PostgresBackend struct {
+	subscribers
+	db      *sqlx.DB
+	channel string
+}
+
+// PostgresBackendOpt — функциональная опция для настройки PostgresBackend.
+type PostgresBackendOpt func(*PostgresBackend)
+
+// WithNotifyChannel задаёт канал LISTEN/NOTIFY.
+// Используется первое непустое значение.
+func WithNotifyChannel(channels ...string) PostgresBackendOpt {
+	return func(b *PostgresBackend) {
+		for _, c := range channels {
+			if c != "" {
+				b.channel = c
+				return
+			}
+		}
+	}
+}
+
+// NewPostgresBackend создаёт бэкенд LISTEN/NOTIFY поверх подключения к базе данных
+func NewPostgresBackend(db *sqlx.DB, opts ...PostgresBackendOpt) *PostgresBackend {
+	b := &PostgresBackend{
+		db:      db,
+		channel: DefaultNotifyChannel,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish отправляет событие в канал NOTIFY. Событие, не помещающееся в NOTIFY,
+// сохраняется в таблице chat_events, а в канал отправляется ссылка на него.
+func (b *PostgresBackend) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(notification{Event: &event})
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxNotifyPayload {
+		eventPayload, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		var eventID int64
+		if err := b.db.GetContext(ctx, &eventID,
+			`INSERT INTO chat_events (payload) VALUES ($1) RETURNING event_id`, string(eventPayload),
+		); err != nil {
+			return err
+		}
+		if _, err := b.db.ExecContext(ctx,
+			`DELETE FROM chat_events WHERE created_at < $1`, time.Now().UTC().Add(-chatEventRetention),
+		); err != nil {
+			log.Printf("chat: не удалось удалить устаревшие события: %v", err)
+		}
+		if payload, err = json.Marshal(notification{EventID: eventID}); err != nil {
+			return err
+		}
+	}
+
+	_, err = b.db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, b.channel, string(payload))
+	return err
+}
+
+// Run слушает канал и передаёт полученные события подписчикам, пока не завершится ctx.
+// При разрыве соединения с базой подписка восстанавливается; события, опубликованные
+// в это время, теряются, и клиенты получают пропущенные сообщения из истории при переподключении.
+func (b *PostgresBackend) Run(ctx context.Context) {
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("chat: прослушивание канала %s прервано: %v", b.channel, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+// listen подписывается на канал на выделенном соединении и обрабатывает уведомления до ошибки
+func (b *PostgresBackend) listen(ctx context.Context) error {
+	conn, err := b.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("postgres backend requires the pgx driver")
+		}
+		pgConn := stdConn.Conn()
+
+		if _, err := pgConn.Exec(ctx, "LISTEN "+pgx.Identifier{b.channel}.Sanitize()); err != nil {
+			return err
+		}
+		// соединение возвращается в пул, поэтому подписка снимается при выходе
+		defer pgConn.Exec(context.Background(), "UNLISTEN *")
+
+		for {
+			n, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			event, err := b.decode(ctx, []byte(n.Payload))
+			if err != nil {
+				log.Printf("chat: не удалось разобрать событие канала %s: %v", b.channel, err)
+				continue
+			}
+			b.deliver(event)
+		}
+	})
+}
+
+// decode разбирает уведомление и при необходимости загружает событие из таблицы chat_events
+func (b *PostgresBackend) decode(ctx context.Context, payload []byte) (Event, error) {
+	var n notification
+	if err := json.Unmarshal(payload, &n); err != nil {
+		return Event{}, err
+	}
+	if n.Event != nil {
+		return *n.Event, nil
+	}
+
+	var eventPayload string
+	if err := b.db.GetContext(ctx, &eventPayload,
+		`SELECT payload FROM chat_events WHERE event_id = $1`, n.EventID,
+	); err != nil {
+		return Event{}, err
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(eventPayload), &event); err != nil {
+		return Event{}, err
+	}
+	return event, nil
+}
@@ -0,0 +1,59 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestPostgresBackendDecode(t *testing.T) {
+	db, err := sqlx.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+	defer db.Close()
+	_, err = db.Exec(`
+		CREATE TABLE chat_events (
+			event_id   INTEGER PRIMARY KEY AUTOINCREMENT,
+			payload    TEXT NOT NULL,
+			created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`)
+	require.NoError(t, err)
+
+	backend := NewPostgresBackend(db, WithNotifyChannel("", "room_events"))
+	require.Equal(t, "room_events", backend.channel)
+
+	// Небольшое событие передаётся в уведомлении целиком
+	event := Event{RoomUUID: uuid.New(), ExcludeDevice: uuid.New(), Frame: json.RawMessage(`{"v":1,"type":"message"}`)}
+	payload, err := json.Marshal(notification{Event: &event})
+	require.NoError(t, err)
+	decoded, err := backend.decode(context.Background(), payload)
+	require.NoError(t, err)
+	require.Equal(t, event.RoomUUID, decoded.RoomUUID)
+	require.Equal(t, event.ExcludeDevice, decoded.ExcludeDevice)
+	require.JSONEq(t, string(event.Frame), string(decoded.Frame))
+
+	// Большое событие загружается из таблицы по ссылке
+	large := Event{RoomUUID: uuid.New(), UserUUID: uuid.New(), Frame: json.RawMessage(`"` + strings.Repeat("x", 2*maxNotifyPayload) + `"`)}
+	largePayload, err := json.Marshal(large)
+	require.NoError(t, err)
+	var eventID int64
+	require.NoError(t, db.Get(&eventID, `INSERT INTO chat_events (payload) VALUES ($1) RETURNING event_id`, string(largePayload)))
+
+	payload, err = json.Marshal(notification{EventID: eventID})
+	require.NoError(t, err)
+	decoded, err = backend.decode(context.Background(), payload)
+	require.NoError(t, err)
+	require.Equal(t, large.RoomUUID, decoded.RoomUUID)
+	require.Equal(t, large.UserUUID, decoded.UserUUID)
+	require.Equal(t, string(large.Frame), string(decoded.Frame))
+
+	_, err = backend.decode(context.Background(), []byte(`{"event_id":100500}`))
+	require.Error(t, err)
+	_, err = backend.decode(context.Background(), []byte(`not json`))
+	require.Error(t, err)
+}
@@ -0,0 +1,52 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+)
+
+// Lockout — запись о блокировке входа
+type Lockout struct {
+	LockoutUUID uuid.UUID `json:"lockout_uuid"`
+	Scope       string    `json:"scope"`
+	Key         string    `json:"key"`
+	Failures    int       `json:"failures"`
+	LockedAt    time.Time `json:"locked_at"`
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// ListLockouts возвращает последние блокировки входа, от новых к старым.
+// adminToken — токен администратора сервера; limit <= 0 означает значение сервера по умолчанию.
+func ListLockouts(ctx context.Context, client *resty.Client, adminToken string, limit int) ([]Lockout, error) {
+	req := client.R().
+		SetContext(ctx).
+		SetAuthToken(strings.TrimSpace(adminToken))
+	if limit > 0 {
+		req.SetQueryParam("limit", strconv.Itoa(limit))
+	}
+
+	resp, err := req.Get("/admin/lockouts")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var body struct {
+		Lockouts []Lockout `json:"lockouts"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return nil, fmt.Errorf("invalid lockouts response: %w", err)
+	}
+
+	return body.Lockouts, nil
+}
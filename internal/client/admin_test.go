@@ -0,0 +1,42 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListLockouts(t *testing.T) {
+	lockedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	lockouts := []Lockout{{LockoutUUID: uuid.New(), Scope: "ip", Key: "203.0.113.7", Failures: 50, LockedAt: lockedAt, LockedUntil: lockedAt.Add(15 * time.Minute)}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/admin/lockouts" || r.Method != http.MethodGet {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer admin-secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Equal(t, "20", r.URL.Query().Get("limit"))
+		json.NewEncoder(w).Encode(map[string]any{"lockouts": lockouts})
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	got, err := ListLockouts(context.Background(), client, "admin-secret", 20)
+	require.NoError(t, err)
+	assert.Equal(t, lockouts, got)
+
+	_, err = ListLockouts(context.Background(), client, "guess", 20)
+	assert.Error(t, err)
+}
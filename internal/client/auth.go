@@ -2,13 +2,23 @@ package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/google/uuid"
 )
 
+// ErrDevicePendingApproval возвращается при входе с устройства, которое ещё не подтверждено с доверенного устройства
+var ErrDevicePendingApproval = errors.New("device pending approval")
+
+// ErrTooManyAttempts возвращается, если сервер временно запретил попытки входа после неудачных попыток
+var ErrTooManyAttempts = errors.New("too many login attempts")
+
 // Register отправляет запрос на регистрацию пользователя и возвращает user_uuid.
 func Register(
 	ctx context.Context,
@@ -30,6 +40,9 @@ func Register(
 		return uuid.Nil, fmt.Errorf("failed to send request: %w", err)
 	}
 
+	if resp.StatusCode() == http.StatusBadRequest {
+		return uuid.Nil, rejectedError(resp)
+	}
 	if resp.IsError() {
 		return uuid.Nil, fmt.Errorf("server returned error: %s", resp.Status())
 	}
@@ -44,15 +57,17 @@ func Register(
 }
 
 // AddDevice отправляет запрос на регистрацию нового устройства для пользователя и возвращает UUID устройства.
+// pending равен true, если устройство ожидает подтверждения с уже доверенного устройства пользователя.
 func AddDevice(
 	ctx context.Context,
 	client *resty.Client,
-	username, password, publicKey string,
-) (deviceUUID uuid.UUID, err error) {
+	username, password, publicKey, signingKey string,
+) (deviceUUID uuid.UUID, pending bool, err error) {
 	body := map[string]string{
-		"username":   username,
-		"password":   password,
-		"public_key": publicKey,
+		"username":    username,
+		"password":    password,
+		"public_key":  publicKey,
+		"signing_key": signingKey,
 	}
 
 	resp, err := client.R().
@@ -61,28 +76,35 @@ func AddDevice(
 		SetBody(body).
 		Post("/auth/device")
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("failed to send request: %w", err)
+		return uuid.Nil, false, fmt.Errorf("failed to send request: %w", err)
 	}
 
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		return uuid.Nil, false, tooManyAttemptsError(resp)
+	}
 	if resp.IsError() {
-		return uuid.Nil, fmt.Errorf("server returned error: %s", resp.Status())
+		return uuid.Nil, false, fmt.Errorf("server returned error: %s", resp.Status())
 	}
 
 	deviceUUID, err = uuid.Parse(strings.TrimSpace(resp.String()))
 	if err != nil {
-		return uuid.Nil, fmt.Errorf("invalid device UUID returned: %w", err)
+		return uuid.Nil, false, fmt.Errorf("invalid device UUID returned: %w", err)
 	}
 
-	return deviceUUID, nil
+	return deviceUUID, resp.StatusCode() == http.StatusAccepted, nil
 }
 
-// Login отправляет запрос на логин пользователя с указанным deviceUUID и возвращает JWT токен.
+// RefreshTokenHeader — заголовок ответа сервера с refresh-токеном.
+const RefreshTokenHeader = "X-Refresh-Token"
+
+// Login отправляет запрос на логин пользователя с указанным deviceUUID
+// и возвращает JWT токен и refresh-токен.
 func Login(
 	ctx context.Context,
 	client *resty.Client,
 	username, password string,
 	deviceUUID uuid.UUID,
-) (token string, err error) {
+) (token string, refreshToken string, err error) {
 	body := map[string]string{
 		"username":    username,
 		"password":    password,
@@ -95,22 +117,143 @@ func Login(
 		SetBody(body).
 		Post("/auth/login")
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return "", "", fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode() == http.StatusForbidden {
+		return "", "", ErrDevicePendingApproval
+	}
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		return "", "", tooManyAttemptsError(resp)
+	}
+
+	if resp.IsError() {
+		return "", "", fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return tokensFromResponse(resp)
+}
+
+// Refresh обменивает refresh-токен на новую пару JWT и refresh-токена.
+func Refresh(
+	ctx context.Context,
+	client *resty.Client,
+	refreshToken string,
+) (token string, newRefreshToken string, err error) {
+	body := map[string]string{
+		"refresh_token": refreshToken,
+	}
+
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetBody(body).
+		Post("/auth/refresh")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.IsError() {
+		return "", "", fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return tokensFromResponse(resp)
+}
+
+// ChangePassword меняет пароль текущего пользователя и возвращает JWT и refresh-токен новой сессии устройства:
+// все прежние сессии пользователя, включая текущую, отзываются.
+func ChangePassword(
+	ctx context.Context,
+	client *resty.Client,
+	token string,
+	currentPassword, newPassword string,
+) (newToken string, refreshToken string, err error) {
+	body := map[string]string{
+		"current_password": currentPassword,
+		"new_password":     newPassword,
+	}
+
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(strings.TrimSpace(token)).
+		SetBody(body).
+		Post("/auth/password")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to send request: %w", err)
 	}
 
+	if resp.StatusCode() == http.StatusBadRequest {
+		return "", "", rejectedError(resp)
+	}
+	if resp.StatusCode() == http.StatusTooManyRequests {
+		return "", "", tooManyAttemptsError(resp)
+	}
 	if resp.IsError() {
-		return "", fmt.Errorf("server returned error: %s", resp.Status())
+		return "", "", fmt.Errorf("server returned error: %s", resp.Status())
 	}
 
+	return tokensFromResponse(resp)
+}
+
+// rejectedError возвращает ошибку отклонённого запроса вместе с причиной из тела ответа, если сервер её указал
+func rejectedError(resp *resty.Response) error {
+	if reason := strings.TrimSpace(resp.String()); reason != "" {
+		return fmt.Errorf("server returned error: %s: %s", resp.Status(), reason)
+	}
+	return fmt.Errorf("server returned error: %s", resp.Status())
+}
+
+// tooManyAttemptsError возвращает ErrTooManyAttempts со временем ожидания из заголовка Retry-After
+func tooManyAttemptsError(resp *resty.Response) error {
+	seconds, err := strconv.Atoi(resp.Header().Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return ErrTooManyAttempts
+	}
+	return fmt.Errorf("%w: retry after %s", ErrTooManyAttempts, time.Duration(seconds)*time.Second)
+}
+
+// tokensFromResponse извлекает JWT и refresh-токен из заголовков ответа.
+func tokensFromResponse(resp *resty.Response) (token string, refreshToken string, err error) {
 	// JWT возвращается в заголовке Authorization: "Bearer <token>"
 	token = resp.Header().Get("Authorization")
 	if token == "" {
-		return "", fmt.Errorf("no Authorization header returned")
+		return "", "", fmt.Errorf("no Authorization header returned")
 	}
 
 	// Убираем префикс "Bearer "
 	token = strings.TrimPrefix(token, "Bearer ")
 	token = strings.TrimSpace(token)
 
-	return token, nil
+	refreshToken = strings.TrimSpace(resp.Header().Get(RefreshTokenHeader))
+
+	return token, refreshToken, nil
+}
+
+// Logout отправляет запрос на выход из аккаунта и отзыв токена.
+// scope задаёт область выхода: "token" (только текущий токен), "device" (все сессии устройства)
+// или "user" (все сессии пользователя). Пустой scope означает "token".
+func Logout(
+	ctx context.Context,
+	client *resty.Client,
+	token string,
+	scope string,
+) error {
+	req := client.R().
+		SetContext(ctx).
+		SetAuthToken(strings.TrimSpace(token))
+	if scope != "" {
+		req.SetQueryParam("scope", scope)
+	}
+
+	resp, err := req.Post("/auth/logout")
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
 }
@@ -59,6 +59,20 @@ func TestRegister_ServerError(t *testing.T) {
 	assert.Contains(t, err.Error(), "server returned error")
 }
 
+func TestRegister_PolicyRejected(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "password does not meet the password policy: password must be at least 8 characters long", http.StatusBadRequest)
+	}))
+	defer ts.Close()
+
+	restClient := resty.New().SetBaseURL(ts.URL)
+
+	_, err := Register(context.Background(), restClient, "user", "pass")
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "password must be at least 8 characters long")
+}
+
 func TestAddDevice(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.URL.Path != "/auth/device" {
@@ -72,25 +86,78 @@ func TestAddDevice(t *testing.T) {
 
 	client := resty.New().SetBaseURL(ts.URL)
 
-	deviceUUID, err := AddDevice(context.Background(), client, "user", "pass", "pubkey")
+	deviceUUID, pending, err := AddDevice(context.Background(), client, "user", "pass", "pubkey", "signkey")
+
+	assert.NoError(t, err)
+	assert.False(t, pending)
+	assert.Equal(t, uuid.MustParse("123e4567-e89b-12d3-a456-426614174001"), deviceUUID)
+}
+
+func TestAddDevice_Pending(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, "signkey", body["signing_key"])
+		w.WriteHeader(http.StatusAccepted)
+		fmt.Fprint(w, "123e4567-e89b-12d3-a456-426614174001")
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	deviceUUID, pending, err := AddDevice(context.Background(), client, "user", "pass", "pubkey", "signkey")
 
 	assert.NoError(t, err)
+	assert.True(t, pending)
 	assert.Equal(t, uuid.MustParse("123e4567-e89b-12d3-a456-426614174001"), deviceUUID)
 }
 
 func TestLogin(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Authorization", "Bearer faketoken123")
+		w.Header().Set(RefreshTokenHeader, "refresh123")
 		w.WriteHeader(http.StatusOK)
 	}))
 	defer ts.Close()
 
 	client := resty.New().SetBaseURL(ts.URL)
 
-	token, err := Login(context.Background(), client, "user", "pass", uuid.New())
+	token, refreshToken, err := Login(context.Background(), client, "user", "pass", uuid.New())
 
 	assert.NoError(t, err)
 	assert.Equal(t, "faketoken123", token) // теперь без "Bearer"
+	assert.Equal(t, "refresh123", refreshToken)
+}
+
+func TestLogin_DevicePendingApproval(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	_, _, err := Login(context.Background(), client, "user", "pass", uuid.New())
+
+	assert.ErrorIs(t, err, ErrDevicePendingApproval)
+}
+
+func TestLogin_TooManyAttempts(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "90")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	_, _, err := Login(context.Background(), client, "user", "pass", uuid.New())
+
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	assert.ErrorContains(t, err, "retry after 1m30s")
+
+	_, _, err = AddDevice(context.Background(), client, "user", "pass", "pubkey", "signkey")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
 }
 
 func TestLogin_NoAuthHeader(t *testing.T) {
@@ -101,9 +168,123 @@ func TestLogin_NoAuthHeader(t *testing.T) {
 
 	client := resty.New().SetBaseURL(ts.URL)
 
-	token, err := Login(context.Background(), client, "user", "pass", uuid.New())
+	token, _, err := Login(context.Background(), client, "user", "pass", uuid.New())
 
 	assert.Error(t, err)
 	assert.Empty(t, token)
 	assert.Contains(t, err.Error(), "no Authorization header returned")
 }
+
+func TestRefresh(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/refresh" || r.Method != http.MethodPost {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["refresh_token"] != "refresh-old" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Set("Authorization", "Bearer token-new")
+		w.Header().Set(RefreshTokenHeader, "refresh-new")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	token, refreshToken, err := Refresh(context.Background(), client, "refresh-old")
+	assert.NoError(t, err)
+	assert.Equal(t, "token-new", token)
+	assert.Equal(t, "refresh-new", refreshToken)
+
+	_, _, err = Refresh(context.Background(), client, "refresh-unknown")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "server returned error")
+}
+
+func TestLogout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/logout" || r.Method != http.MethodPost {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("scope") != "device" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	err := Logout(context.Background(), client, "token123", "device")
+	assert.NoError(t, err)
+}
+
+func TestLogout_ServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	err := Logout(context.Background(), client, "token123", "")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "server returned error")
+}
+
+func TestChangePassword(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/password" || r.Method != http.MethodPost {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "Bearer token123", r.Header.Get("Authorization"))
+
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["current_password"] == "locked" {
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		if body["current_password"] != "old-secret-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if body["new_password"] == "short" {
+			http.Error(w, "password does not meet the password policy", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Authorization", "Bearer new-token")
+		w.Header().Set(RefreshTokenHeader, "new-refresh")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	token, refreshToken, err := ChangePassword(context.Background(), client, "token123", "old-secret-1", "new-secret-2")
+	assert.NoError(t, err)
+	assert.Equal(t, "new-token", token)
+	assert.Equal(t, "new-refresh", refreshToken)
+
+	_, _, err = ChangePassword(context.Background(), client, "token123", "wrong", "new-secret-2")
+	assert.Error(t, err)
+
+	_, _, err = ChangePassword(context.Background(), client, "token123", "old-secret-1", "short")
+	assert.ErrorContains(t, err, "password does not meet the password policy")
+
+	_, _, err = ChangePassword(context.Background(), client, "token123", "locked", "new-secret-2")
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+}
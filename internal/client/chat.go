@@ -3,23 +3,38 @@ package client
 import (
 	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/sbilibin2017/bil-message/internal/chat"
 )
 
-// CreateChat отправляет запрос на создание новой комнаты и возвращает UUID комнаты
-func CreateChat(ctx context.Context, client *resty.Client, token string) (uuid.UUID, error) {
+// CreateChat отправляет запрос на создание новой групповой комнаты с названием и темой и возвращает UUID комнаты
+func CreateChat(ctx context.Context, client *resty.Client, token string, name string, topic string) (uuid.UUID, error) {
+	return createChat(ctx, client, token, map[string]string{"name": name, "topic": topic})
+}
+
+// CreateDirectChat возвращает UUID личной комнаты с пользователем peerUUID, создавая её при первом обращении
+func CreateDirectChat(ctx context.Context, client *resty.Client, token string, peerUUID uuid.UUID) (uuid.UUID, error) {
+	return createChat(ctx, client, token, map[string]string{"kind": "direct", "member_uuid": peerUUID.String()})
+}
+
+// createChat отправляет запрос на создание комнаты с указанными параметрами и возвращает UUID комнаты
+func createChat(ctx context.Context, client *resty.Client, token string, body map[string]string) (uuid.UUID, error) {
 	token = strings.TrimSpace(token)
 	resp, err := client.R().
 		SetContext(ctx).
-		SetHeader("Content-Type", "text/plain").
+		SetHeader("Content-Type", "application/json").
 		SetAuthToken(token).
+		SetBody(body).
 		Post("/chat")
 	if err != nil {
 		return uuid.Nil, err
@@ -37,6 +52,34 @@ func CreateChat(ctx context.Context, client *resty.Client, token string) (uuid.U
 	return roomUUID, nil
 }
 
+// UpdateChat изменяет название и тему комнаты; nil оставляет значение без изменений
+func UpdateChat(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, name *string, topic *string) error {
+	body := make(map[string]string)
+	if name != nil {
+		body["name"] = *name
+	}
+	if topic != nil {
+		body["topic"] = *topic
+	}
+
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(token).
+		SetBody(body).
+		Put("/chat/" + chatUUID.String())
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
+
 // RemoveChat удаляет комнату по UUID
 func RemoveChat(ctx context.Context, client *resty.Client, token string, roomUUID uuid.UUID) error {
 	token = strings.TrimSpace(token)
@@ -56,14 +99,14 @@ func RemoveChat(ctx context.Context, client *resty.Client, token string, roomUUI
 	return nil
 }
 
-// AddChatMember добавляет пользователя в указанную комнату
-func AddChatMember(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, memberUUID uuid.UUID) error {
+// RemoveChatMember удаляет пользователя из указанной комнаты
+func RemoveChatMember(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, memberUUID uuid.UUID) error {
 	token = strings.TrimSpace(token)
 	resp, err := client.R().
 		SetContext(ctx).
 		SetHeader("Content-Type", "text/plain").
 		SetAuthToken(token).
-		Post("/chat/" + chatUUID.String() + "/" + memberUUID.String())
+		Delete("/chat/" + chatUUID.String() + "/" + memberUUID.String())
 	if err != nil {
 		return err
 	}
@@ -75,14 +118,14 @@ func AddChatMember(ctx context.Context, client *resty.Client, token string, chat
 	return nil
 }
 
-// RemoveChatMember удаляет пользователя из указанной комнаты
-func RemoveChatMember(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, memberUUID uuid.UUID) error {
+// LeaveChat выходит из указанной комнаты от имени текущего пользователя
+func LeaveChat(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID) error {
 	token = strings.TrimSpace(token)
 	resp, err := client.R().
 		SetContext(ctx).
 		SetHeader("Content-Type", "text/plain").
 		SetAuthToken(token).
-		Delete("/chat/" + chatUUID.String() + "/" + memberUUID.String())
+		Post("/chat/" + chatUUID.String() + "/leave")
 	if err != nil {
 		return err
 	}
@@ -94,49 +137,457 @@ func RemoveChatMember(ctx context.Context, client *resty.Client, token string, c
 	return nil
 }
 
-// ConnectWebSocket подключается к указанному wsURL с JWT токеном и запускает чтение/запись сообщений
-func ConnectWebSocket(wsURL, token string) error {
-	header := http.Header{}
-	header.Set("Authorization", "Bearer "+token)
+// TransferChatOwnership передаёт владение комнатой указанному участнику
+func TransferChatOwnership(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, memberUUID uuid.UUID) error {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "text/plain").
+		SetAuthToken(token).
+		Post("/chat/" + chatUUID.String() + "/" + memberUUID.String() + "/owner")
+	if err != nil {
+		return err
+	}
 
-	// подключаемся к WebSocket
-	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
+
+// SetChatMemberRole назначает участнику комнаты роль (admin или member)
+func SetChatMemberRole(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, memberUUID uuid.UUID, role string) error {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(token).
+		SetBody(map[string]string{"role": role}).
+		Put("/chat/" + chatUUID.String() + "/" + memberUUID.String() + "/role")
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
+
+// Room — комната пользователя в списке комнат
+type Room struct {
+	RoomUUID       uuid.UUID `json:"room_uuid"`
+	Name           string    `json:"name"`
+	Topic          string    `json:"topic"`
+	Kind           string    `json:"kind"`
+	Role           string    `json:"role"`
+	MemberCount    int64     `json:"member_count"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// RoomMember — участник комнаты
+type RoomMember struct {
+	UserUUID uuid.UUID `json:"user_uuid"`
+	Username string    `json:"username"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// ListChats возвращает комнаты пользователя, начиная с комнат с недавней активностью
+func ListChats(ctx context.Context, client *resty.Client, token string) ([]Room, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		Get("/chat")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var body struct {
+		Rooms []Room `json:"rooms"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return nil, fmt.Errorf("invalid rooms response: %w", err)
+	}
+
+	return body.Rooms, nil
+}
+
+// ListChatMembers возвращает участников комнаты с именами пользователей
+func ListChatMembers(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID) ([]RoomMember, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		Get("/chat/" + chatUUID.String() + "/members")
 	if err != nil {
-		return fmt.Errorf("не удалось подключиться к WebSocket: %w", err)
+		return nil, err
 	}
-	defer conn.Close()
 
-	fmt.Println("WebSocket соединение установлено. Введите сообщения:")
+	if resp.IsError() {
+		return nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
 
-	done := make(chan struct{})
+	var body struct {
+		Members []RoomMember `json:"members"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return nil, fmt.Errorf("invalid members response: %w", err)
+	}
+
+	return body.Members, nil
+}
+
+// MessageCipher шифрует исходящие и расшифровывает входящие сообщения комнаты
+type MessageCipher interface {
+	// Encrypt шифрует сообщение ключом текущей эпохи
+	Encrypt(plaintext []byte) (keyEpoch int64, ciphertext string, err error)
+	// Decrypt расшифровывает сообщение ключом эпохи keyEpoch
+	Decrypt(keyEpoch int64, ciphertext string) ([]byte, error)
+	// Rotate переходит на ключ новой текущей эпохи и возвращает её номер
+	Rotate(ctx context.Context) (int64, error)
+}
+
+// Параметры переподключения клиента WebSocket
+const (
+	maxReconnectAttempts = 5                      // попыток переподключения подряд до завершения с ошибкой
+	reconnectDelay       = 500 * time.Millisecond // задержка перед первой попыткой, удваивается с каждой следующей
+	ackWaitTimeout       = 5 * time.Second        // ожидание подтверждений отправленных сообщений при завершении ввода
+)
+
+// ConnectWebSocket подключается к указанному wsURL и запускает чтение/запись сообщений.
+// JWT запрашивается у tokens перед каждым подключением, поэтому переподключение работает и после истечения
+// токена, с которым сеанс начался.
+// Исходящие сообщения шифруются cipher и отправляются в конверте chat.Envelope,
+// входящие расшифровываются и выводятся с отправителем и временем; кадры, не прошедшие
+// проверку подлинности, не выводятся как сообщения, а помечаются отдельно.
+// По событию ротации ключа от сервера cipher переходит на ключ новой эпохи.
+//
+// Доставка выполняется «хотя бы один раз»: каждое сообщение получает client_message_uuid и хранится
+// в очереди до подтверждения ack от сервера. При разрыве соединения клиент переподключается
+// и повторно отправляет неподтверждённые сообщения; сервер не рассылает повторы второй раз.
+// Соединение, закрытое сервером с кодом 1008 (сессия отозвана или доступ к комнате закрыт), не восстанавливается.
+// Сообщение, отклонённое сервером как зашифрованное ключом устаревшей эпохи, шифруется ключом новой эпохи
+// и отправляется повторно с тем же client_message_uuid; сообщение, отклонённое по другой причине, кроме
+// внутренней ошибки сервера, удаляется из очереди, и пользователь видит, что оно не отправлено.
+// Полученные сообщения, уже показанные ранее, пропускаются, на новые отправляется квитанция о доставке.
+func ConnectWebSocket(wsURL string, tokens TokenSource, cipher MessageCipher) error {
+	outbox := NewOutbox()
+	seen := newSeenMessages()
+
+	// Чтение сообщений с консоли; канал закрывается по окончании ввода
+	lines := make(chan []byte)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- append([]byte(nil), scanner.Bytes()...)
+		}
+		if err := scanner.Err(); err != nil {
+			fmt.Println("Ошибка ввода:", err)
+		}
+	}()
+
+	connected := false
+	for attempt := 0; ; attempt++ {
+		conn, err := dialWebSocket(wsURL, tokens)
+		if err != nil {
+			if !connected {
+				return fmt.Errorf("не удалось подключиться к WebSocket: %w", err)
+			}
+			if attempt >= maxReconnectAttempts {
+				return fmt.Errorf("не удалось переподключиться к WebSocket: %w", err)
+			}
+			time.Sleep(reconnectDelay << attempt)
+			continue
+		}
+
+		if !connected {
+			fmt.Println("WebSocket соединение установлено. Сообщения шифруются сквозным шифрованием. Введите сообщения:")
+		} else {
+			fmt.Printf("Соединение восстановлено, повторно отправляется неподтверждённых сообщений: %d\n", outbox.Len())
+		}
+		connected = true
+		attempt = -1
+
+		inputDone, err := runChatSession(conn, cipher, outbox, seen, lines)
+		conn.Close()
+		if inputDone {
+			return nil
+		}
+		if websocket.IsCloseError(err, websocket.ClosePolicyViolation) {
+			// сессия отозвана или доступ к комнате закрыт: переподключение с тем же токеном бессмысленно
+			return fmt.Errorf("сервер закрыл соединение: %w", err)
+		}
+		fmt.Println("Соединение потеряно:", err)
+	}
+}
+
+// dialWebSocket подключается к wsURL с JWT, полученным от tokens непосредственно перед подключением
+func dialWebSocket(wsURL string, tokens TokenSource) (*websocket.Conn, error) {
+	token, err := tokens(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	return conn, err
+}
+
+// runChatSession обслуживает одно соединение: повторно отправляет неподтверждённые сообщения,
+// затем пересылает ввод на сервер и выводит входящие кадры. Возвращает inputDone = true,
+// если ввод завершён, иначе — ошибку разрыва соединения.
+//
+// Если сервер закрыл соединение с кодом 1009 (message too big), последнее отправленное сообщение
+// удаляется из очереди: его повторная отправка снова привела бы к разрыву.
+func runChatSession(
+	conn *websocket.Conn,
+	cipher MessageCipher,
+	outbox *Outbox,
+	seen *seenMessages,
+	lines <-chan []byte,
+) (inputDone bool, err error) {
+	var writeMu sync.Mutex
+	write := func(frame []byte) error {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+		return conn.WriteMessage(websocket.TextMessage, frame)
+	}
+
+	var lastSent uuid.UUID
+	defer func() {
+		if websocket.IsCloseError(err, websocket.CloseMessageTooBig) && outbox.Ack(lastSent) {
+			fmt.Println("Сообщение превышает допустимый сервером размер и не отправлено")
+		}
+	}()
 
 	// Чтение сообщений от сервера
+	readErr := make(chan error, 1)
+	// writeFailed дожидается причины разрыва от сервера (например, кадра закрытия), если она успевает прийти
+	writeFailed := func(err error) error {
+		select {
+		case closeErr := <-readErr:
+			return closeErr
+		case <-time.After(time.Second):
+			return err
+		}
+	}
 	go func() {
-		defer close(done)
 		for {
 			_, msg, err := conn.ReadMessage()
 			if err != nil {
-				fmt.Println("Ошибка при чтении:", err)
+				readErr <- err
 				return
 			}
-			fmt.Printf("[Получено] %s\n", string(msg))
+			line, reply := handleIncoming(cipher, outbox, seen, msg)
+			if line != "" {
+				fmt.Println(line)
+			}
+			if reply != nil {
+				// ответ не критичен: при ошибке соединение будет восстановлено, а сообщения из очереди отправлены повторно
+				_ = write(reply)
+			}
 		}
 	}()
 
-	// Чтение сообщений с консоли и отправка на сервер
-	scanner := bufio.NewScanner(os.Stdin)
-	for scanner.Scan() {
-		input := scanner.Text()
-		if err := conn.WriteMessage(websocket.TextMessage, []byte(input)); err != nil {
-			fmt.Println("Ошибка отправки:", err)
-			break
+	for _, message := range outbox.Pending() {
+		lastSent = message.ClientMessageUUID
+		if err := write(message.Frame); err != nil {
+			return false, writeFailed(err)
+		}
+	}
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				waitForAcks(outbox, readErr)
+				return true, nil
+			}
+			clientMessageUUID, frame, err := newMessageFrame(cipher, outbox, line)
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			lastSent = clientMessageUUID
+			if err := write(frame); err != nil {
+				// сообщение остаётся в очереди и будет отправлено после переподключения
+				return false, writeFailed(err)
+			}
+		case err := <-readErr:
+			return false, err
+		}
+	}
+}
+
+// newMessageFrame шифрует строку ввода, присваивает сообщению client_message_uuid
+// и ставит кадр в очередь неподтверждённых сообщений
+func newMessageFrame(cipher MessageCipher, outbox *Outbox, plaintext []byte) (uuid.UUID, []byte, error) {
+	keyEpoch, ciphertext, err := cipher.Encrypt(plaintext)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("Ошибка шифрования: %w", err)
+	}
+	clientMessageUUID := uuid.New()
+	frame, err := json.Marshal(chat.Envelope{
+		Version:           chat.ProtocolVersion,
+		Type:              chat.TypeMessage,
+		ClientMessageUUID: clientMessageUUID,
+		Ciphertext:        ciphertext,
+		KeyEpoch:          keyEpoch,
+	})
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("Ошибка кодирования: %w", err)
+	}
+	if err := outbox.Add(clientMessageUUID, frame); err != nil {
+		return uuid.Nil, nil, fmt.Errorf("Сообщение не отправлено: %w", err)
+	}
+	return clientMessageUUID, frame, nil
+}
+
+// waitForAcks ждёт подтверждения отправленных сообщений, разрыва соединения или истечения ackWaitTimeout
+func waitForAcks(outbox *Outbox, readErr <-chan error) {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	timeout := time.After(ackWaitTimeout)
+	for outbox.Len() > 0 {
+		select {
+		case <-ticker.C:
+		case <-readErr:
+			return
+		case <-timeout:
+			fmt.Printf("Не подтверждено сервером сообщений: %d\n", outbox.Len())
+			return
+		}
+	}
+}
+
+// handleIncoming обрабатывает входящий кадр: подтверждённые сообщения удаляются из outbox,
+// повторно полученные сообщения пропускаются, на новые формируется квитанция о доставке,
+// сообщения, отклонённые из-за устаревшей эпохи ключа, шифруются заново, а окончательно отклонённые
+// сервером или не зашифрованные заново удаляются из outbox.
+// Возвращает строку для вывода (пустую, если выводить нечего) и кадр, который нужно отправить в ответ.
+func handleIncoming(cipher MessageCipher, outbox *Outbox, seen *seenMessages, msg []byte) (line string, reply []byte) {
+	var env chat.Envelope
+	if err := json.Unmarshal(msg, &env); err == nil {
+		switch env.Type {
+		case chat.TypeAck:
+			outbox.Ack(env.ClientMessageUUID)
+			// собственное сообщение может прийти из истории после переподключения
+			seen.Add(env.MessageUUID)
+		case chat.TypeMessage:
+			if !seen.Add(env.MessageUUID) {
+				return "", nil
+			}
+			reply, _ = json.Marshal(chat.Envelope{
+				Version:     chat.ProtocolVersion,
+				Type:        chat.TypeReceipt,
+				MessageUUID: env.MessageUUID,
+				SenderUUID:  env.SenderUUID,
+			})
+		case chat.TypeError:
+			// внутренняя ошибка сервера временная: сообщение остаётся в очереди и отправится после переподключения
+			if env.ClientMessageUUID == uuid.Nil || env.Code == chat.ErrorCodeInternal {
+				break
+			}
+			if env.Code != chat.ErrorCodeStaleKeyEpoch {
+				if outbox.Ack(env.ClientMessageUUID) {
+					return fmt.Sprintf("[Сообщение не отправлено] %s: %s: %s", env.ClientMessageUUID, env.Code, env.Message), nil
+				}
+				break
+			}
+			frame, err := reencryptMessage(cipher, outbox, env.ClientMessageUUID)
+			if err != nil {
+				// повторная отправка того же кадра снова была бы отклонена
+				outbox.Ack(env.ClientMessageUUID)
+				return fmt.Sprintf("[Сообщение не отправлено] %s: %v", env.ClientMessageUUID, err), nil
+			}
+			if frame != nil {
+				return fmt.Sprintf("[Ключ комнаты обновлён] сообщение %s отправлено повторно", env.ClientMessageUUID), frame
+			}
+		}
+	}
+	return formatIncoming(cipher, msg), reply
+}
+
+// reencryptMessage шифрует неподтверждённое сообщение ключом текущей эпохи, заменяет его кадр в outbox
+// и возвращает новый кадр (nil, если сообщение уже подтверждено). Если cipher ещё не перешёл на эпоху новее
+// той, которой зашифровано сообщение (событие ротации не получено), ключ текущей эпохи запрашивается сразу.
+func reencryptMessage(cipher MessageCipher, outbox *Outbox, clientMessageUUID uuid.UUID) ([]byte, error) {
+	frame, ok := outbox.Frame(clientMessageUUID)
+	if !ok {
+		return nil, nil
+	}
+	var env chat.Envelope
+	if err := json.Unmarshal(frame, &env); err != nil {
+		return nil, err
+	}
+	plaintext, err := cipher.Decrypt(env.KeyEpoch, env.Ciphertext)
+	if err != nil {
+		return nil, err
+	}
+
+	keyEpoch, ciphertext, err := cipher.Encrypt(plaintext)
+	if err == nil && keyEpoch <= env.KeyEpoch {
+		if _, err = cipher.Rotate(context.Background()); err == nil {
+			keyEpoch, ciphertext, err = cipher.Encrypt(plaintext)
 		}
 	}
+	if err != nil {
+		return nil, err
+	}
+	if keyEpoch <= env.KeyEpoch {
+		return nil, ErrStaleKeyEpoch
+	}
 
-	if err := scanner.Err(); err != nil {
-		fmt.Println("Ошибка ввода:", err)
+	env.KeyEpoch, env.Ciphertext = keyEpoch, ciphertext
+	frame, err = json.Marshal(env)
+	if err != nil {
+		return nil, err
 	}
+	outbox.Replace(clientMessageUUID, frame)
+	return frame, nil
+}
 
-	<-done
-	return nil
+// formatIncoming разбирает входящий конверт и форматирует его для вывода: сообщения расшифровываются
+// и выводятся с отправителем и временем отправки, по событию ротации ключа cipher переходит на новую эпоху.
+func formatIncoming(cipher MessageCipher, msg []byte) string {
+	var env chat.Envelope
+	if err := json.Unmarshal(msg, &env); err != nil {
+		return fmt.Sprintf("[НЕ ПРОШЛО ПРОВЕРКУ ПОДЛИННОСТИ] кадр длиной %d байт отброшен: %v", len(msg), err)
+	}
+
+	switch env.Type {
+	case chat.TypeMessage:
+		plaintext, err := cipher.Decrypt(env.KeyEpoch, env.Ciphertext)
+		if err != nil {
+			return fmt.Sprintf("[НЕ ПРОШЛО ПРОВЕРКУ ПОДЛИННОСТИ] сообщение %s от %s отброшено: %v", env.MessageUUID, env.SenderUUID, err)
+		}
+		return fmt.Sprintf("[%s] %s: %s", env.SentAt.Local().Format(time.DateTime), env.SenderUUID, plaintext)
+	case chat.TypeAck:
+		return fmt.Sprintf("[Сохранено сервером] сообщение %s", env.MessageUUID)
+	case chat.TypeReceipt:
+		return fmt.Sprintf("[Доставлено] сообщение %s получено %s", env.MessageUUID, env.RecipientUUID)
+	case chat.TypeKeyRotation:
+		keyEpoch, err := cipher.Rotate(context.Background())
+		if err != nil {
+			return fmt.Sprintf("[Ошибка ротации ключа] %v", err)
+		}
+		return fmt.Sprintf("[Ключ комнаты обновлён] эпоха %d", keyEpoch)
+	case chat.TypeRoomUpdated:
+		return fmt.Sprintf("[Комната обновлена] название «%s», тема «%s»", env.Name, env.Topic)
+	case chat.TypeError:
+		return fmt.Sprintf("[Ошибка сервера] %s: %s", env.Code, env.Message)
+	default:
+		return fmt.Sprintf("[Неизвестный кадр] тип %q", env.Type)
+	}
 }
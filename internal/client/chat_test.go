@@ -2,14 +2,23 @@ package client
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/go-resty/resty/v2"
 	"github.com/google/uuid"
+	"github.com/gorilla/websocket"
+	"github.com/sbilibin2017/bil-message/internal/chat"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCreateChat(t *testing.T) {
@@ -22,6 +31,11 @@ func TestCreateChat(t *testing.T) {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["name"] != "Команда" || body["topic"] != "Планы" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
 
 		w.WriteHeader(http.StatusOK)
 		fmt.Fprint(w, "123e4567-e89b-12d3-a456-426614174000")
@@ -29,7 +43,7 @@ func TestCreateChat(t *testing.T) {
 	defer ts.Close()
 
 	client := resty.New().SetBaseURL(ts.URL)
-	roomUUID, err := CreateChat(context.Background(), client, "token123")
+	roomUUID, err := CreateChat(context.Background(), client, "token123", "Команда", "Планы")
 
 	assert.NoError(t, err)
 	assert.Equal(t, uuid.MustParse("123e4567-e89b-12d3-a456-426614174000"), roomUUID)
@@ -37,7 +51,7 @@ func TestCreateChat(t *testing.T) {
 
 func TestCreateChat_NetworkError(t *testing.T) {
 	client := resty.New().SetBaseURL("http://127.0.0.1:0")
-	roomUUID, err := CreateChat(context.Background(), client, "token123")
+	roomUUID, err := CreateChat(context.Background(), client, "token123", "", "")
 
 	assert.Error(t, err)
 	assert.Equal(t, uuid.Nil, roomUUID)
@@ -50,12 +64,64 @@ func TestCreateChat_ServerError(t *testing.T) {
 	defer ts.Close()
 
 	client := resty.New().SetBaseURL(ts.URL)
-	roomUUID, err := CreateChat(context.Background(), client, "token123")
+	roomUUID, err := CreateChat(context.Background(), client, "token123", "", "")
 
 	assert.Error(t, err)
 	assert.Equal(t, uuid.Nil, roomUUID)
 }
 
+func TestCreateDirectChat(t *testing.T) {
+	peerUUID := uuid.New()
+	roomUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["kind"] != "direct" || body["member_uuid"] != peerUUID.String() {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, roomUUID.String())
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	got, err := CreateDirectChat(context.Background(), client, "token123", peerUUID)
+
+	require.NoError(t, err)
+	assert.Equal(t, roomUUID, got)
+}
+
+func TestUpdateChat(t *testing.T) {
+	roomUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/"+roomUUID.String() || r.Method != http.MethodPut {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		if _, ok := body["name"]; ok {
+			http.Error(w, "name must not be sent", http.StatusBadRequest)
+			return
+		}
+		if body["topic"] != "" {
+			http.Error(w, "unexpected topic", http.StatusBadRequest)
+			return
+		}
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	topic := ""
+	err := UpdateChat(context.Background(), client, "token123", roomUUID, nil, &topic)
+	assert.NoError(t, err)
+
+	err = UpdateChat(context.Background(), client, "token123", uuid.New(), nil, &topic)
+	assert.Error(t, err)
+}
+
 func TestRemoveChat(t *testing.T) {
 	roomUUID := uuid.New()
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -98,7 +164,7 @@ func TestRemoveChat_ServerError(t *testing.T) {
 	assert.Error(t, err)
 }
 
-func TestAddChatMember(t *testing.T) {
+func TestRemoveChatMember(t *testing.T) {
 	roomUUID := uuid.New()
 	memberUUID := uuid.New()
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -107,7 +173,7 @@ func TestAddChatMember(t *testing.T) {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
 		}
-		if r.Method != http.MethodPost {
+		if r.Method != http.MethodDelete {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
@@ -116,21 +182,21 @@ func TestAddChatMember(t *testing.T) {
 	defer ts.Close()
 
 	client := resty.New().SetBaseURL(ts.URL)
-	err := AddChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
+	err := RemoveChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
 
 	assert.NoError(t, err)
 }
 
-func TestAddChatMember_NetworkError(t *testing.T) {
+func TestRemoveChatMember_NetworkError(t *testing.T) {
 	roomUUID := uuid.New()
 	memberUUID := uuid.New()
 	client := resty.New().SetBaseURL("http://127.0.0.1:0")
-	err := AddChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
+	err := RemoveChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
 
 	assert.Error(t, err)
 }
 
-func TestAddChatMember_ServerError(t *testing.T) {
+func TestRemoveChatMember_ServerError(t *testing.T) {
 	roomUUID := uuid.New()
 	memberUUID := uuid.New()
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -139,21 +205,54 @@ func TestAddChatMember_ServerError(t *testing.T) {
 	defer ts.Close()
 
 	client := resty.New().SetBaseURL(ts.URL)
-	err := AddChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
+	err := RemoveChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
 
 	assert.Error(t, err)
 }
 
-func TestRemoveChatMember(t *testing.T) {
+func TestLeaveChat(t *testing.T) {
+	roomUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/"+roomUUID.String()+"/leave" {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	err := LeaveChat(context.Background(), client, "token123", roomUUID)
+
+	assert.NoError(t, err)
+}
+
+func TestLeaveChat_ServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "conflict", http.StatusConflict)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	err := LeaveChat(context.Background(), client, "token123", uuid.New())
+
+	assert.Error(t, err)
+}
+
+func TestTransferChatOwnership(t *testing.T) {
 	roomUUID := uuid.New()
 	memberUUID := uuid.New()
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		expectedPath := "/chat/" + roomUUID.String() + "/" + memberUUID.String()
+		expectedPath := "/chat/" + roomUUID.String() + "/" + memberUUID.String() + "/owner"
 		if r.URL.Path != expectedPath {
 			http.Error(w, "not found", http.StatusNotFound)
 			return
 		}
-		if r.Method != http.MethodDelete {
+		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
@@ -162,30 +261,538 @@ func TestRemoveChatMember(t *testing.T) {
 	defer ts.Close()
 
 	client := resty.New().SetBaseURL(ts.URL)
-	err := RemoveChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
+	err := TransferChatOwnership(context.Background(), client, "token123", roomUUID, memberUUID)
 
 	assert.NoError(t, err)
 }
 
-func TestRemoveChatMember_NetworkError(t *testing.T) {
+func TestTransferChatOwnership_ServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	err := TransferChatOwnership(context.Background(), client, "token123", uuid.New(), uuid.New())
+
+	assert.Error(t, err)
+}
+
+func TestSetChatMemberRole(t *testing.T) {
 	roomUUID := uuid.New()
 	memberUUID := uuid.New()
-	client := resty.New().SetBaseURL("http://127.0.0.1:0")
-	err := RemoveChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expectedPath := "/chat/" + roomUUID.String() + "/" + memberUUID.String() + "/role"
+		if r.URL.Path != expectedPath {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Method != http.MethodPut {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body["role"] != "admin" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	err := SetChatMemberRole(context.Background(), client, "token123", roomUUID, memberUUID, "admin")
+
+	assert.NoError(t, err)
+}
+
+func TestSetChatMemberRole_ServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	err := SetChatMemberRole(context.Background(), client, "token123", uuid.New(), uuid.New(), "admin")
 
 	assert.Error(t, err)
 }
 
-func TestRemoveChatMember_ServerError(t *testing.T) {
+func TestListChats(t *testing.T) {
+	room := Room{
+		RoomUUID:       uuid.New(),
+		Name:           "Команда",
+		Topic:          "Планы",
+		Kind:           "group",
+		Role:           "owner",
+		MemberCount:    2,
+		CreatedAt:      time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		LastActivityAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat" || r.Method != http.MethodGet {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"rooms": []Room{room}})
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	rooms, err := ListChats(context.Background(), client, "token123")
+
+	require.NoError(t, err)
+	assert.Equal(t, []Room{room}, rooms)
+}
+
+func TestListChats_ServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	_, err := ListChats(context.Background(), client, "token123")
+
+	assert.Error(t, err)
+}
+
+func TestListChatMembers(t *testing.T) {
 	roomUUID := uuid.New()
-	memberUUID := uuid.New()
+	member := RoomMember{UserUUID: uuid.New(), Username: "alice", Role: "owner", JoinedAt: time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)}
 	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		if r.URL.Path != "/chat/"+roomUUID.String()+"/members" || r.Method != http.MethodGet {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"members": []RoomMember{member}})
 	}))
 	defer ts.Close()
 
 	client := resty.New().SetBaseURL(ts.URL)
-	err := RemoveChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
+	members, err := ListChatMembers(context.Background(), client, "token123", roomUUID)
+	require.NoError(t, err)
+	assert.Equal(t, []RoomMember{member}, members)
 
+	_, err = ListChatMembers(context.Background(), client, "token123", uuid.New())
 	assert.Error(t, err)
 }
+
+// rotatingCipher — шифр комнаты с одной эпохой ключа, считающий вызовы ротации
+type rotatingCipher struct {
+	cipher    *e2ee.RoomCipher
+	keyEpoch  int64
+	rotations int
+}
+
+func (c *rotatingCipher) Encrypt(plaintext []byte) (int64, string, error) {
+	ciphertext, err := c.cipher.Encrypt(plaintext)
+	return c.keyEpoch, ciphertext, err
+}
+
+func (c *rotatingCipher) Decrypt(keyEpoch int64, ciphertext string) ([]byte, error) {
+	if keyEpoch != c.keyEpoch {
+		return nil, ErrRoomKeyNotFound
+	}
+	return c.cipher.Decrypt(ciphertext)
+}
+
+func (c *rotatingCipher) Rotate(ctx context.Context) (int64, error) {
+	c.rotations++
+	return c.keyEpoch + int64(c.rotations), nil
+}
+
+func TestFormatIncoming(t *testing.T) {
+	roomUUID := uuid.New()
+	roomKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	roomCipher, err := e2ee.NewRoomCipher(roomKey, roomUUID, 1)
+	require.NoError(t, err)
+	cipher := &rotatingCipher{cipher: roomCipher, keyEpoch: 1}
+
+	keyEpoch, ciphertext, err := cipher.Encrypt([]byte("привет"))
+	require.NoError(t, err)
+
+	sentAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	message := chat.Envelope{
+		Version:      chat.ProtocolVersion,
+		Type:         chat.TypeMessage,
+		MessageUUID:  uuid.New(),
+		RoomUUID:     roomUUID,
+		SenderUUID:   uuid.New(),
+		SenderDevice: uuid.New(),
+		SentAt:       sentAt,
+		Ciphertext:   ciphertext,
+		KeyEpoch:     keyEpoch,
+	}
+	frame, err := json.Marshal(message)
+	require.NoError(t, err)
+	assert.Equal(t,
+		fmt.Sprintf("[%s] %s: привет", sentAt.Local().Format(time.DateTime), message.SenderUUID),
+		formatIncoming(cipher, frame),
+	)
+
+	// Шифртекст, подменённый сервером, не выводится
+	message.Ciphertext = "plaintext from server"
+	frame, err = json.Marshal(message)
+	require.NoError(t, err)
+	flagged := formatIncoming(cipher, frame)
+	assert.Contains(t, flagged, "НЕ ПРОШЛО ПРОВЕРКУ ПОДЛИННОСТИ")
+	assert.NotContains(t, flagged, "plaintext from server")
+
+	flagged = formatIncoming(cipher, []byte("plaintext from server"))
+	assert.Contains(t, flagged, "НЕ ПРОШЛО ПРОВЕРКУ ПОДЛИННОСТИ")
+	assert.NotContains(t, flagged, "plaintext from server")
+
+	rotation, err := json.Marshal(chat.Envelope{Version: chat.ProtocolVersion, Type: chat.TypeKeyRotation, RoomUUID: roomUUID, KeyEpoch: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "[Ключ комнаты обновлён] эпоха 2", formatIncoming(cipher, rotation))
+	assert.Equal(t, 1, cipher.rotations)
+
+	updated, err := json.Marshal(chat.Envelope{Version: chat.ProtocolVersion, Type: chat.TypeRoomUpdated, RoomUUID: roomUUID, Name: "Команда", Topic: "Планы"})
+	require.NoError(t, err)
+	assert.Equal(t, "[Комната обновлена] название «Команда», тема «Планы»", formatIncoming(cipher, updated))
+
+	serverErr, err := json.Marshal(chat.ErrorEnvelope(chat.ErrUnknownType))
+	require.NoError(t, err)
+	assert.Equal(t, "[Ошибка сервера] unknown_type: unknown envelope type", formatIncoming(cipher, serverErr))
+
+	assert.Equal(t, `[Неизвестный кадр] тип "typing"`, formatIncoming(cipher, []byte(`{"v":1,"type":"typing"}`)))
+}
+
+func TestHandleIncoming(t *testing.T) {
+	roomKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	roomCipher, err := e2ee.NewRoomCipher(roomKey, uuid.New(), 1)
+	require.NoError(t, err)
+	cipher := &rotatingCipher{cipher: roomCipher, keyEpoch: 1}
+	outbox := NewOutbox()
+	seen := newSeenMessages()
+
+	// Подтверждение удаляет сообщение из очереди
+	_, frame, err := newMessageFrame(cipher, outbox, []byte("привет"))
+	require.NoError(t, err)
+	var sent chat.Envelope
+	require.NoError(t, json.Unmarshal(frame, &sent))
+	require.NotEqual(t, uuid.Nil, sent.ClientMessageUUID)
+	require.Equal(t, 1, outbox.Len())
+
+	ownUUID := uuid.New()
+	ack, err := json.Marshal(chat.AckEnvelope(chat.Envelope{MessageUUID: ownUUID, ClientMessageUUID: sent.ClientMessageUUID}))
+	require.NoError(t, err)
+	line, receipt := handleIncoming(cipher, outbox, seen, ack)
+	assert.Equal(t, "[Сохранено сервером] сообщение "+ownUUID.String(), line)
+	assert.Nil(t, receipt)
+	assert.Equal(t, 0, outbox.Len())
+
+	// Новое сообщение выводится, на него отправляется квитанция; повтор пропускается
+	keyEpoch, ciphertext, err := cipher.Encrypt([]byte("ответ"))
+	require.NoError(t, err)
+	message := chat.Envelope{
+		Version:     chat.ProtocolVersion,
+		Type:        chat.TypeMessage,
+		MessageUUID: uuid.New(),
+		SenderUUID:  uuid.New(),
+		SentAt:      time.Now(),
+		Ciphertext:  ciphertext,
+		KeyEpoch:    keyEpoch,
+	}
+	frame, err = json.Marshal(message)
+	require.NoError(t, err)
+
+	line, receipt = handleIncoming(cipher, outbox, seen, frame)
+	assert.Contains(t, line, "ответ")
+	require.NotNil(t, receipt)
+	parsed, err := chat.ParseEnvelope(receipt)
+	require.NoError(t, err)
+	assert.Equal(t, chat.TypeReceipt, parsed.Type)
+	assert.Equal(t, message.MessageUUID, parsed.MessageUUID)
+	assert.Equal(t, message.SenderUUID, parsed.SenderUUID)
+
+	line, receipt = handleIncoming(cipher, outbox, seen, frame)
+	assert.Empty(t, line)
+	assert.Nil(t, receipt)
+
+	// Собственное сообщение из истории после подтверждения не выводится повторно
+	own, err := json.Marshal(chat.Envelope{Version: chat.ProtocolVersion, Type: chat.TypeMessage, MessageUUID: ownUUID})
+	require.NoError(t, err)
+	line, receipt = handleIncoming(cipher, outbox, seen, own)
+	assert.Empty(t, line)
+	assert.Nil(t, receipt)
+
+	recipientUUID := uuid.New()
+	delivered, err := json.Marshal(chat.Envelope{
+		Version:       chat.ProtocolVersion,
+		Type:          chat.TypeReceipt,
+		MessageUUID:   ownUUID,
+		RecipientUUID: recipientUUID,
+	})
+	require.NoError(t, err)
+	line, receipt = handleIncoming(cipher, outbox, seen, delivered)
+	assert.Equal(t, fmt.Sprintf("[Доставлено] сообщение %s получено %s", ownUUID, recipientUUID), line)
+	assert.Nil(t, receipt)
+}
+
+// epochCipher — шифр комнаты, переходящий на следующую эпоху при каждой ротации
+type epochCipher struct {
+	cipher  *e2ee.RoomCipher
+	current int64
+}
+
+func (c *epochCipher) Encrypt(plaintext []byte) (int64, string, error) {
+	ciphertext, err := c.cipher.Encrypt(plaintext)
+	return c.current, ciphertext, err
+}
+
+func (c *epochCipher) Decrypt(keyEpoch int64, ciphertext string) ([]byte, error) {
+	if keyEpoch > c.current {
+		return nil, ErrRoomKeyNotFound
+	}
+	return c.cipher.Decrypt(ciphertext)
+}
+
+func (c *epochCipher) Rotate(ctx context.Context) (int64, error) {
+	c.current++
+	return c.current, nil
+}
+
+func TestHandleIncomingReencryptsStaleMessage(t *testing.T) {
+	roomKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	roomCipher, err := e2ee.NewRoomCipher(roomKey, uuid.New(), 1)
+	require.NoError(t, err)
+	cipher := &epochCipher{cipher: roomCipher, current: 1}
+	outbox := NewOutbox()
+	seen := newSeenMessages()
+
+	clientMessageUUID, _, err := newMessageFrame(cipher, outbox, []byte("привет"))
+	require.NoError(t, err)
+
+	stale := chat.ErrorEnvelope(chat.ErrStaleKeyEpoch)
+	stale.ClientMessageUUID = clientMessageUUID
+	frame, err := json.Marshal(stale)
+	require.NoError(t, err)
+
+	// Событие ротации ещё не получено: ключ новой эпохи запрашивается сразу, сообщение шифруется заново
+	line, reply := handleIncoming(cipher, outbox, seen, frame)
+	assert.Equal(t, fmt.Sprintf("[Ключ комнаты обновлён] сообщение %s отправлено повторно", clientMessageUUID), line)
+	require.NotNil(t, reply)
+	resent, err := chat.ParseEnvelope(reply)
+	require.NoError(t, err)
+	assert.Equal(t, clientMessageUUID, resent.ClientMessageUUID)
+	assert.Equal(t, int64(2), resent.KeyEpoch)
+	plaintext, err := cipher.Decrypt(resent.KeyEpoch, resent.Ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "привет", string(plaintext))
+
+	// В очереди остаётся новый кадр, который будет отправлен после переподключения
+	pending := outbox.Pending()
+	require.Len(t, pending, 1)
+	assert.Equal(t, reply, pending[0].Frame)
+
+	// Ошибка по уже подтверждённому сообщению только выводится
+	outbox.Ack(clientMessageUUID)
+	line, reply = handleIncoming(cipher, outbox, seen, frame)
+	assert.Equal(t, "[Ошибка сервера] stale_key_epoch: stale room key epoch", line)
+	assert.Nil(t, reply)
+}
+
+func TestHandleIncomingDropsRejectedMessage(t *testing.T) {
+	roomKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	roomCipher, err := e2ee.NewRoomCipher(roomKey, uuid.New(), 1)
+	require.NoError(t, err)
+	cipher := &epochCipher{cipher: roomCipher, current: 1}
+	outbox := NewOutbox()
+	seen := newSeenMessages()
+
+	clientMessageUUID, _, err := newMessageFrame(cipher, outbox, []byte("привет"))
+	require.NoError(t, err)
+
+	// Внутренняя ошибка сервера временная: сообщение остаётся в очереди
+	internal := chat.ErrorEnvelope(errors.New("db down"))
+	internal.ClientMessageUUID = clientMessageUUID
+	frame, err := json.Marshal(internal)
+	require.NoError(t, err)
+	line, reply := handleIncoming(cipher, outbox, seen, frame)
+	assert.Equal(t, "[Ошибка сервера] internal: internal server error", line)
+	assert.Nil(t, reply)
+	assert.Equal(t, 1, outbox.Len())
+
+	// Окончательно отклонённое сообщение удаляется из очереди
+	invalid := chat.ErrorEnvelope(chat.ErrInvalidFrame)
+	invalid.ClientMessageUUID = clientMessageUUID
+	frame, err = json.Marshal(invalid)
+	require.NoError(t, err)
+	line, reply = handleIncoming(cipher, outbox, seen, frame)
+	assert.Equal(t, fmt.Sprintf("[Сообщение не отправлено] %s: invalid_frame: %s", clientMessageUUID, chat.ErrInvalidFrame), line)
+	assert.Nil(t, reply)
+	assert.Equal(t, 0, outbox.Len())
+
+	// Сообщение, которое не удалось зашифровать заново, тоже удаляется из очереди
+	clientMessageUUID, _, err = newMessageFrame(cipher, outbox, []byte("привет"))
+	require.NoError(t, err)
+	pending := outbox.Pending()
+	require.Len(t, pending, 1)
+	var env chat.Envelope
+	require.NoError(t, json.Unmarshal(pending[0].Frame, &env))
+	env.Ciphertext = "corrupted"
+	corrupted, err := json.Marshal(env)
+	require.NoError(t, err)
+	require.True(t, outbox.Replace(clientMessageUUID, corrupted))
+
+	stale := chat.ErrorEnvelope(chat.ErrStaleKeyEpoch)
+	stale.ClientMessageUUID = clientMessageUUID
+	frame, err = json.Marshal(stale)
+	require.NoError(t, err)
+	line, reply = handleIncoming(cipher, outbox, seen, frame)
+	assert.Contains(t, line, "[Сообщение не отправлено] "+clientMessageUUID.String())
+	assert.Nil(t, reply)
+	assert.Equal(t, 0, outbox.Len())
+}
+
+func TestRunChatSessionResendsAfterReconnect(t *testing.T) {
+	roomKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	roomCipher, err := e2ee.NewRoomCipher(roomKey, uuid.New(), 1)
+	require.NoError(t, err)
+	cipher := &rotatingCipher{cipher: roomCipher, keyEpoch: 1}
+
+	// Первое соединение сервер разрывает, не подтвердив сообщение; второе — подтверждает всё полученное
+	received := make(chan chat.Envelope, 4)
+	connections := 0
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		connections++
+		first := connections == 1
+		for {
+			_, frame, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			env, err := chat.ParseEnvelope(frame)
+			if err != nil {
+				return
+			}
+			received <- env
+			if first {
+				return
+			}
+			ack, _ := json.Marshal(chat.AckEnvelope(chat.Envelope{MessageUUID: uuid.New(), ClientMessageUUID: env.ClientMessageUUID}))
+			if err := conn.WriteMessage(websocket.TextMessage, ack); err != nil {
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	outbox := NewOutbox()
+	seen := newSeenMessages()
+	lines := make(chan []byte, 1)
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	lines <- []byte("привет")
+	inputDone, err := runChatSession(conn, cipher, outbox, seen, lines)
+	conn.Close()
+	assert.False(t, inputDone)
+	assert.Error(t, err)
+	assert.Equal(t, 1, outbox.Len())
+	first := <-received
+
+	conn, _, err = websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	close(lines)
+	inputDone, err = runChatSession(conn, cipher, outbox, seen, lines)
+	conn.Close()
+	assert.True(t, inputDone)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, outbox.Len())
+
+	resent := <-received
+	assert.Equal(t, first.ClientMessageUUID, resent.ClientMessageUUID)
+	assert.Equal(t, first.Ciphertext, resent.Ciphertext)
+}
+
+func TestRunChatSessionDropsOversizedMessage(t *testing.T) {
+	roomKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	roomCipher, err := e2ee.NewRoomCipher(roomKey, uuid.New(), 1)
+	require.NoError(t, err)
+	cipher := &rotatingCipher{cipher: roomCipher, keyEpoch: 1}
+
+	upgrader := websocket.Upgrader{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadLimit(256)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer ts.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	outbox := NewOutbox()
+	lines := make(chan []byte, 1)
+	lines <- []byte(strings.Repeat("x", 1024))
+
+	inputDone, err := runChatSession(conn, cipher, outbox, newSeenMessages(), lines)
+	assert.False(t, inputDone)
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), "unexpected error: %v", err)
+	assert.Equal(t, 0, outbox.Len())
+}
+
+func TestDialWebSocketRequestsFreshToken(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	var (
+		mu      sync.Mutex
+		headers []string
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		headers = append(headers, r.Header.Get("Authorization"))
+		mu.Unlock()
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer ts.Close()
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http")
+
+	// Каждое подключение, в том числе переподключение, получает JWT заново
+	calls := 0
+	tokens := func(context.Context) (string, error) {
+		calls++
+		return fmt.Sprintf("token-%d", calls), nil
+	}
+	for range 2 {
+		conn, err := dialWebSocket(wsURL, tokens)
+		require.NoError(t, err)
+		conn.Close()
+	}
+
+	_, err := dialWebSocket(wsURL, func(context.Context) (string, error) { return "", errors.New("no token") })
+	assert.EqualError(t, err, "no token")
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-2"}, headers)
+}
@@ -0,0 +1,136 @@
+package client
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// seenLimit — сколько последних UUID полученных сообщений помнит клиент для дедупликации
+const seenLimit = 10000
+
+// outboxLimit — сколько неподтверждённых сообщений может ждать в очереди
+const outboxLimit = 1000
+
+// ErrOutboxFull возвращается, если в очереди уже outboxLimit неподтверждённых сообщений
+var ErrOutboxFull = errors.New("too many unacknowledged messages")
+
+// Outbox хранит исходящие кадры до подтверждения сервером, чтобы повторить их после переподключения.
+// Очередь ограничена outboxLimit сообщениями: пока сервер их не подтвердит или не отклонит, новые не принимаются.
+type Outbox struct {
+	mu     sync.Mutex
+	order  []uuid.UUID
+	frames map[uuid.UUID][]byte
+}
+
+// NewOutbox создаёт пустую очередь неподтверждённых сообщений
+func NewOutbox() *Outbox {
+	return &Outbox{frames: make(map[uuid.UUID][]byte)}
+}
+
+// Add добавляет кадр сообщения с UUID, присвоенным клиентом.
+// Если очередь заполнена, возвращает ErrOutboxFull.
+func (o *Outbox) Add(clientMessageUUID uuid.UUID, frame []byte) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.frames[clientMessageUUID]; ok {
+		return nil
+	}
+	if len(o.order) >= outboxLimit {
+		return ErrOutboxFull
+	}
+	o.order = append(o.order, clientMessageUUID)
+	o.frames[clientMessageUUID] = frame
+	return nil
+}
+
+// Frame возвращает кадр неподтверждённого сообщения; ok = false, если сообщения нет в очереди
+func (o *Outbox) Frame(clientMessageUUID uuid.UUID) (frame []byte, ok bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	frame, ok = o.frames[clientMessageUUID]
+	return frame, ok
+}
+
+// Replace заменяет кадр неподтверждённого сообщения, сохраняя его место в очереди;
+// возвращает false, если сообщения нет в очереди
+func (o *Outbox) Replace(clientMessageUUID uuid.UUID, frame []byte) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.frames[clientMessageUUID]; !ok {
+		return false
+	}
+	o.frames[clientMessageUUID] = frame
+	return true
+}
+
+// Ack удаляет подтверждённое или окончательно отклонённое сервером сообщение;
+// возвращает false, если сообщения нет в очереди
+func (o *Outbox) Ack(clientMessageUUID uuid.UUID) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.frames[clientMessageUUID]; !ok {
+		return false
+	}
+	delete(o.frames, clientMessageUUID)
+	for i, id := range o.order {
+		if id == clientMessageUUID {
+			o.order = append(o.order[:i], o.order[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+// PendingMessage — неподтверждённое сообщение из очереди
+type PendingMessage struct {
+	ClientMessageUUID uuid.UUID
+	Frame             []byte
+}
+
+// Pending возвращает неподтверждённые сообщения в порядке отправки
+func (o *Outbox) Pending() []PendingMessage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	messages := make([]PendingMessage, 0, len(o.order))
+	for _, id := range o.order {
+		messages = append(messages, PendingMessage{ClientMessageUUID: id, Frame: o.frames[id]})
+	}
+	return messages
+}
+
+// Len возвращает количество неподтверждённых сообщений
+func (o *Outbox) Len() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.order)
+}
+
+// seenMessages помнит UUID последних полученных сообщений, чтобы не показывать повторы:
+// при переподключении сервер заново отправляет историю, а доставка выполняется «хотя бы один раз».
+type seenMessages struct {
+	mu    sync.Mutex
+	order []uuid.UUID
+	set   map[uuid.UUID]struct{}
+}
+
+func newSeenMessages() *seenMessages {
+	return &seenMessages{set: make(map[uuid.UUID]struct{})}
+}
+
+// Add запоминает UUID сообщения и возвращает false, если сообщение уже было получено
+func (s *seenMessages) Add(messageUUID uuid.UUID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.set[messageUUID]; ok {
+		return false
+	}
+	if len(s.order) >= seenLimit {
+		delete(s.set, s.order[0])
+		s.order = s.order[1:]
+	}
+	s.order = append(s.order, messageUUID)
+	s.set[messageUUID] = struct{}{}
+	return true
+}
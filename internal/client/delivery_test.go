@@ -0,0 +1,64 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutbox(t *testing.T) {
+	outbox := NewOutbox()
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+
+	assert.NoError(t, outbox.Add(first, []byte("1")))
+	assert.NoError(t, outbox.Add(second, []byte("2")))
+	assert.NoError(t, outbox.Add(third, []byte("3")))
+	assert.NoError(t, outbox.Add(first, []byte("повтор")))
+	assert.Equal(t, 3, outbox.Len())
+
+	assert.True(t, outbox.Ack(second))
+	assert.False(t, outbox.Ack(second))
+	assert.False(t, outbox.Ack(uuid.New()))
+	assert.Equal(t, []PendingMessage{
+		{ClientMessageUUID: first, Frame: []byte("1")},
+		{ClientMessageUUID: third, Frame: []byte("3")},
+	}, outbox.Pending())
+
+	// Замена кадра сохраняет место сообщения в очереди
+	assert.True(t, outbox.Replace(first, []byte("1'")))
+	assert.False(t, outbox.Replace(second, []byte("2'")))
+	frame, ok := outbox.Frame(first)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1'"), frame)
+	_, ok = outbox.Frame(second)
+	assert.False(t, ok)
+	assert.Equal(t, first, outbox.Pending()[0].ClientMessageUUID)
+
+	assert.True(t, outbox.Ack(first))
+	assert.True(t, outbox.Ack(third))
+	assert.Empty(t, outbox.Pending())
+
+	// Заполненная очередь не принимает новые сообщения, пока сервер не подтвердит отправленные
+	for range outboxLimit {
+		assert.NoError(t, outbox.Add(uuid.New(), []byte("m")))
+	}
+	assert.ErrorIs(t, outbox.Add(uuid.New(), []byte("m")), ErrOutboxFull)
+	assert.True(t, outbox.Ack(outbox.Pending()[0].ClientMessageUUID))
+	assert.NoError(t, outbox.Add(uuid.New(), []byte("m")))
+	assert.Equal(t, outboxLimit, outbox.Len())
+}
+
+func TestSeenMessages(t *testing.T) {
+	seen := newSeenMessages()
+	first := uuid.New()
+
+	assert.True(t, seen.Add(first))
+	assert.False(t, seen.Add(first))
+
+	// При переполнении забываются самые старые сообщения
+	for range seenLimit {
+		seen.Add(uuid.New())
+	}
+	assert.True(t, seen.Add(first))
+}
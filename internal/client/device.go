@@ -0,0 +1,134 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+)
+
+// Device — устройство пользователя
+type Device struct {
+	DeviceUUID        uuid.UUID `json:"device_uuid"`
+	Name              string    `json:"name"`
+	PublicKey         string    `json:"public_key"`
+	SigningKey        string    `json:"signing_key"`
+	Status            string    `json:"status"`
+	ApprovedBy        string    `json:"approved_by"`
+	ApprovalSignature string    `json:"approval_signature"`
+	Current           bool      `json:"current"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+// DeviceStatusPending — статус устройства, ожидающего подтверждения
+const DeviceStatusPending = "pending"
+
+// ListDevices возвращает UUID текущего пользователя и его неотозванные устройства
+func ListDevices(ctx context.Context, client *resty.Client, token string) (uuid.UUID, []Device, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		Get("/auth/devices")
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	if resp.IsError() {
+		return uuid.Nil, nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var body struct {
+		UserUUID uuid.UUID `json:"user_uuid"`
+		Devices  []Device  `json:"devices"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return uuid.Nil, nil, fmt.Errorf("invalid devices response: %w", err)
+	}
+
+	return body.UserUUID, body.Devices, nil
+}
+
+// RenameDevice задаёт название устройства текущего пользователя
+func RenameDevice(ctx context.Context, client *resty.Client, token string, deviceUUID uuid.UUID, name string) error {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(token).
+		SetBody(map[string]string{"name": name}).
+		Patch("/auth/devices/" + deviceUUID.String())
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
+
+// RevokeDevice отзывает устройство текущего пользователя
+func RevokeDevice(ctx context.Context, client *resty.Client, token string, deviceUUID uuid.UUID) error {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "text/plain").
+		SetAuthToken(token).
+		Delete("/auth/devices/" + deviceUUID.String())
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
+
+// ApproveDevice подтверждает ожидающее устройство подписью ключа подписи текущего устройства
+func ApproveDevice(ctx context.Context, client *resty.Client, token string, deviceUUID uuid.UUID, signature string) error {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(token).
+		SetBody(map[string]string{"signature": signature}).
+		Post("/auth/devices/" + deviceUUID.String() + "/approve")
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
+
+// SetSigningKey регистрирует ключ подписи signingKey текущего устройства, добавленного без него;
+// signature — подпись этим ключом над UUID и ключами устройства (см. e2ee.SignDeviceApproval).
+func SetSigningKey(ctx context.Context, client *resty.Client, token string, signingKey string, signature string) error {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(token).
+		SetBody(map[string]string{"signing_key": signingKey, "signature": signature}).
+		Put("/auth/device/signing-key")
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDevices(t *testing.T) {
+	userUUID := uuid.New()
+	devices := []Device{{DeviceUUID: uuid.New(), Name: "Ноутбук", PublicKey: "key", SigningKey: "sign", Status: DeviceStatusPending, Current: true}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/devices" || r.Method != http.MethodGet {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		assert.Equal(t, "Bearer token123", r.Header.Get("Authorization"))
+		json.NewEncoder(w).Encode(map[string]any{"user_uuid": userUUID, "devices": devices})
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	gotUser, got, err := ListDevices(context.Background(), client, "token123")
+
+	require.NoError(t, err)
+	assert.Equal(t, userUUID, gotUser)
+	require.Len(t, got, 1)
+	assert.Equal(t, devices[0].DeviceUUID, got[0].DeviceUUID)
+	assert.Equal(t, "Ноутбук", got[0].Name)
+	assert.Equal(t, "sign", got[0].SigningKey)
+	assert.Equal(t, DeviceStatusPending, got[0].Status)
+	assert.True(t, got[0].Current)
+}
+
+func TestListDevices_ServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	_, _, err := ListDevices(context.Background(), client, "token123")
+
+	assert.Error(t, err)
+}
+
+func TestRenameDevice(t *testing.T) {
+	deviceUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/devices/"+deviceUUID.String() || r.Method != http.MethodPatch {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "Ноутбук", body["name"])
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	assert.NoError(t, RenameDevice(context.Background(), client, "token123", deviceUUID, "Ноутбук"))
+	assert.Error(t, RenameDevice(context.Background(), client, "token123", uuid.New(), "Ноутбук"))
+}
+
+func TestRevokeDevice(t *testing.T) {
+	deviceUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/devices/"+deviceUUID.String() || r.Method != http.MethodDelete {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	assert.NoError(t, RevokeDevice(context.Background(), client, "token123", deviceUUID))
+	assert.Error(t, RevokeDevice(context.Background(), client, "token123", uuid.New()))
+}
+
+func TestApproveDevice(t *testing.T) {
+	deviceUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/devices/"+deviceUUID.String()+"/approve" || r.Method != http.MethodPost {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, "sig", body["signature"])
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	assert.NoError(t, ApproveDevice(context.Background(), client, "token123", deviceUUID, "sig"))
+	assert.Error(t, ApproveDevice(context.Background(), client, "token123", uuid.New(), "sig"))
+}
+
+func TestSetSigningKey(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/auth/device/signing-key" || r.Method != http.MethodPut {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		if body["signing_key"] != "key" {
+			http.Error(w, "conflict", http.StatusConflict)
+			return
+		}
+		assert.Equal(t, "sig", body["signature"])
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	assert.NoError(t, SetSigningKey(context.Background(), client, "token123", "key", "sig"))
+	assert.Error(t, SetSigningKey(context.Background(), client, "token123", "other-key", "sig"))
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+)
+
+// HistoryMessage — зашифрованное сообщение из истории комнаты
+type HistoryMessage struct {
+	MessageUUID  uuid.UUID `json:"message_uuid"`
+	RoomUUID     uuid.UUID `json:"room_uuid"`
+	SenderUUID   uuid.UUID `json:"sender_uuid"`
+	SenderDevice uuid.UUID `json:"sender_device"`
+	SentAt       time.Time `json:"sent_at"`
+	KeyEpoch     int64     `json:"key_epoch"`
+	Ciphertext   string    `json:"ciphertext"`
+}
+
+// HistoryPage — страница истории комнаты в порядке отправки
+type HistoryPage struct {
+	Messages []HistoryMessage `json:"messages"`
+	// HasMore сообщает, есть ли ещё сообщения: более новые при заданном after, иначе более старые
+	HasMore bool `json:"has_more"`
+}
+
+// MessageDecrypter расшифровывает сообщения комнаты
+type MessageDecrypter interface {
+	// Decrypt расшифровывает сообщение ключом эпохи keyEpoch
+	Decrypt(keyEpoch int64, ciphertext string) ([]byte, error)
+}
+
+// ListMessages возвращает страницу истории комнаты. before и after — UUID сообщений, ограничивающих
+// страницу (uuid.Nil — без ограничения); limit не больше нуля означает размер страницы по умолчанию.
+func ListMessages(
+	ctx context.Context,
+	client *resty.Client,
+	token string,
+	chatUUID uuid.UUID,
+	before uuid.UUID,
+	after uuid.UUID,
+	limit int,
+) (HistoryPage, error) {
+	token = strings.TrimSpace(token)
+	req := client.R().
+		SetContext(ctx).
+		SetAuthToken(token)
+	if before != uuid.Nil {
+		req.SetQueryParam("before", before.String())
+	}
+	if after != uuid.Nil {
+		req.SetQueryParam("after", after.String())
+	}
+	if limit > 0 {
+		req.SetQueryParam("limit", strconv.Itoa(limit))
+	}
+
+	resp, err := req.Get("/chat/" + chatUUID.String() + "/messages")
+	if err != nil {
+		return HistoryPage{}, err
+	}
+
+	if resp.IsError() {
+		return HistoryPage{}, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var page HistoryPage
+	if err := json.Unmarshal(resp.Body(), &page); err != nil {
+		return HistoryPage{}, fmt.Errorf("invalid messages response: %w", err)
+	}
+
+	return page, nil
+}
+
+// FormatHistoryMessage форматирует сообщение истории для вывода. Если decrypter не задан,
+// выводится только отметка о зашифрованном сообщении; сообщения, не прошедшие проверку
+// подлинности или зашифрованные ключом эпохи, недоступным устройству, помечаются отдельно.
+func FormatHistoryMessage(decrypter MessageDecrypter, m HistoryMessage) string {
+	sentAt := m.SentAt.Local().Format(time.DateTime)
+	if decrypter == nil {
+		return fmt.Sprintf("[%s] %s: [Зашифровано] сообщение %s, эпоха ключа %d", sentAt, m.SenderUUID, m.MessageUUID, m.KeyEpoch)
+	}
+
+	plaintext, err := decrypter.Decrypt(m.KeyEpoch, m.Ciphertext)
+	if errors.Is(err, ErrRoomKeyNotFound) {
+		return fmt.Sprintf("[%s] %s: [Нет ключа эпохи %d] сообщение %s", sentAt, m.SenderUUID, m.KeyEpoch, m.MessageUUID)
+	}
+	if err != nil {
+		return fmt.Sprintf("[НЕ ПРОШЛО ПРОВЕРКУ ПОДЛИННОСТИ] сообщение %s от %s отброшено: %v", m.MessageUUID, m.SenderUUID, err)
+	}
+	return fmt.Sprintf("[%s] %s: %s", sentAt, m.SenderUUID, plaintext)
+}
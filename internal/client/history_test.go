@@ -0,0 +1,104 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListMessages(t *testing.T) {
+	roomUUID := uuid.New()
+	before := uuid.New()
+	message := HistoryMessage{
+		MessageUUID:  uuid.New(),
+		RoomUUID:     roomUUID,
+		SenderUUID:   uuid.New(),
+		SenderDevice: uuid.New(),
+		SentAt:       time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC),
+		KeyEpoch:     2,
+		Ciphertext:   "ciphertext",
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/"+roomUUID.String()+"/messages" || r.Method != http.MethodGet {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer token123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("before") != before.String() || query.Get("after") != "" || query.Get("limit") != "10" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(HistoryPage{Messages: []HistoryMessage{message}, HasMore: true})
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	page, err := ListMessages(context.Background(), client, "token123", roomUUID, before, uuid.Nil, 10)
+	require.NoError(t, err)
+	assert.True(t, page.HasMore)
+	assert.Equal(t, []HistoryMessage{message}, page.Messages)
+
+	_, err = ListMessages(context.Background(), client, "token123", roomUUID, uuid.Nil, uuid.Nil, 0)
+	assert.Error(t, err)
+
+	_, err = ListMessages(context.Background(), client, "token123", uuid.New(), before, uuid.Nil, 10)
+	assert.Error(t, err)
+}
+
+func TestFormatHistoryMessage(t *testing.T) {
+	roomUUID := uuid.New()
+	roomKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	roomCipher, err := e2ee.NewRoomCipher(roomKey, roomUUID, 1)
+	require.NoError(t, err)
+	cipher := &rotatingCipher{cipher: roomCipher, keyEpoch: 1}
+
+	keyEpoch, ciphertext, err := cipher.Encrypt([]byte("привет"))
+	require.NoError(t, err)
+
+	sentAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	message := HistoryMessage{
+		MessageUUID: uuid.New(),
+		RoomUUID:    roomUUID,
+		SenderUUID:  uuid.New(),
+		SentAt:      sentAt,
+		KeyEpoch:    keyEpoch,
+		Ciphertext:  ciphertext,
+	}
+	prefix := fmt.Sprintf("[%s] %s: ", sentAt.Local().Format(time.DateTime), message.SenderUUID)
+
+	assert.Equal(t, prefix+"привет", FormatHistoryMessage(cipher, message))
+	assert.Equal(t,
+		prefix+fmt.Sprintf("[Зашифровано] сообщение %s, эпоха ключа 1", message.MessageUUID),
+		FormatHistoryMessage(nil, message),
+	)
+
+	message.KeyEpoch = 7
+	assert.Equal(t,
+		prefix+fmt.Sprintf("[Нет ключа эпохи 7] сообщение %s", message.MessageUUID),
+		FormatHistoryMessage(cipher, message),
+	)
+
+	// Шифртекст, подменённый сервером, не выводится
+	message.KeyEpoch = keyEpoch
+	message.Ciphertext = "plaintext from server"
+	flagged := FormatHistoryMessage(cipher, message)
+	assert.Contains(t, flagged, "НЕ ПРОШЛО ПРОВЕРКУ ПОДЛИННОСТИ")
+	assert.NotContains(t, flagged, "plaintext from server")
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+)
+
+// ErrInviteLinkExpired возвращается, если ссылка-приглашение истекла или исчерпала лимит использований
+var ErrInviteLinkExpired = errors.New("invite link expired")
+
+// Invite — приглашение в комнату
+type Invite struct {
+	InviteUUID      uuid.UUID `json:"invite_uuid"`
+	RoomUUID        uuid.UUID `json:"room_uuid"`
+	RoomName        string    `json:"room_name,omitempty"`
+	InviterUUID     uuid.UUID `json:"inviter_uuid"`
+	InviterUsername string    `json:"inviter_username,omitempty"`
+	InviteeUUID     uuid.UUID `json:"invitee_uuid"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// InviteLink — ссылка-приглашение в комнату
+type InviteLink struct {
+	LinkUUID  uuid.UUID `json:"link_uuid"`
+	RoomUUID  uuid.UUID `json:"room_uuid"`
+	ExpiresAt time.Time `json:"expires_at"`
+	MaxUses   int       `json:"max_uses"`
+}
+
+// InviteChatMember приглашает пользователя в комнату и возвращает созданное приглашение
+func InviteChatMember(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, memberUUID uuid.UUID) (*Invite, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(token).
+		SetBody(map[string]string{"member_uuid": memberUUID.String()}).
+		Post("/chat/" + chatUUID.String() + "/invites")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var invite Invite
+	if err := json.Unmarshal(resp.Body(), &invite); err != nil {
+		return nil, fmt.Errorf("invalid invite response: %w", err)
+	}
+
+	return &invite, nil
+}
+
+// ListInvites возвращает ожидающие ответа приглашения текущего пользователя
+func ListInvites(ctx context.Context, client *resty.Client, token string) ([]Invite, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		Get("/invites")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var body struct {
+		Invites []Invite `json:"invites"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return nil, fmt.Errorf("invalid invites response: %w", err)
+	}
+
+	return body.Invites, nil
+}
+
+// AcceptInvite принимает приглашение и возвращает UUID комнаты, в которую вступил пользователь
+func AcceptInvite(ctx context.Context, client *resty.Client, token string, inviteUUID uuid.UUID) (uuid.UUID, error) {
+	return postForRoom(ctx, client, token, "/invites/"+inviteUUID.String()+"/accept")
+}
+
+// DeclineInvite отклоняет приглашение
+func DeclineInvite(ctx context.Context, client *resty.Client, token string, inviteUUID uuid.UUID) error {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "text/plain").
+		SetAuthToken(token).
+		Post("/invites/" + inviteUUID.String() + "/decline")
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
+
+// CreateInviteLink создаёт ссылку-приглашение в комнату; нулевой ttl означает срок по умолчанию, нулевой maxUses — без ограничения
+func CreateInviteLink(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, ttl time.Duration, maxUses int) (*InviteLink, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(token).
+		SetBody(map[string]int64{"expires_in": int64(ttl / time.Second), "max_uses": int64(maxUses)}).
+		Post("/chat/" + chatUUID.String() + "/invite-links")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.IsError() {
+		return nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var link InviteLink
+	if err := json.Unmarshal(resp.Body(), &link); err != nil {
+		return nil, fmt.Errorf("invalid invite link response: %w", err)
+	}
+
+	return &link, nil
+}
+
+// JoinByInviteLink вступает в комнату по ссылке-приглашению и возвращает UUID комнаты
+func JoinByInviteLink(ctx context.Context, client *resty.Client, token string, linkUUID uuid.UUID) (uuid.UUID, error) {
+	return postForRoom(ctx, client, token, "/invites/links/"+linkUUID.String())
+}
+
+// RevokeInviteLink отзывает ссылку-приглашение: входить по ней больше нельзя
+func RevokeInviteLink(ctx context.Context, client *resty.Client, token string, linkUUID uuid.UUID) error {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "text/plain").
+		SetAuthToken(token).
+		Delete("/invites/links/" + linkUUID.String())
+	if err != nil {
+		return err
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
+
+// postForRoom отправляет POST-запрос без тела и разбирает UUID комнаты из ответа
+func postForRoom(ctx context.Context, client *resty.Client, token string, path string) (uuid.UUID, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "text/plain").
+		SetAuthToken(token).
+		Post(path)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if resp.StatusCode() == http.StatusGone {
+		return uuid.Nil, ErrInviteLinkExpired
+	}
+	if resp.IsError() {
+		return uuid.Nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return uuid.Parse(resp.String())
+}
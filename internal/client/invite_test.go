@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteChatMember(t *testing.T) {
+	roomUUID := uuid.New()
+	memberUUID := uuid.New()
+	invite := Invite{InviteUUID: uuid.New(), RoomUUID: roomUUID, InviterUUID: uuid.New(), InviteeUUID: memberUUID, CreatedAt: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/"+roomUUID.String()+"/invites" || r.Method != http.MethodPost {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, memberUUID.String(), body["member_uuid"])
+		json.NewEncoder(w).Encode(invite)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	got, err := InviteChatMember(context.Background(), client, "token123", roomUUID, memberUUID)
+
+	require.NoError(t, err)
+	assert.Equal(t, &invite, got)
+}
+
+func TestInviteChatMember_ServerError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	_, err := InviteChatMember(context.Background(), client, "token123", uuid.New(), uuid.New())
+
+	assert.Error(t, err)
+}
+
+func TestListInvites(t *testing.T) {
+	invites := []Invite{{InviteUUID: uuid.New(), RoomUUID: uuid.New(), RoomName: "Команда", InviterUsername: "alice"}}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/invites" || r.Method != http.MethodGet {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]Invite{"invites": invites})
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	got, err := ListInvites(context.Background(), client, "token123")
+
+	require.NoError(t, err)
+	assert.Equal(t, invites[0].InviteUUID, got[0].InviteUUID)
+	assert.Equal(t, "Команда", got[0].RoomName)
+	assert.Equal(t, "alice", got[0].InviterUsername)
+}
+
+func TestAcceptInvite(t *testing.T) {
+	inviteUUID := uuid.New()
+	roomUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/invites/"+inviteUUID.String()+"/accept" || r.Method != http.MethodPost {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(roomUUID.String()))
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	got, err := AcceptInvite(context.Background(), client, "token123", inviteUUID)
+	require.NoError(t, err)
+	assert.Equal(t, roomUUID, got)
+
+	_, err = AcceptInvite(context.Background(), client, "token123", uuid.New())
+	assert.Error(t, err)
+}
+
+func TestDeclineInvite(t *testing.T) {
+	inviteUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/invites/"+inviteUUID.String()+"/decline" || r.Method != http.MethodPost {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	assert.NoError(t, DeclineInvite(context.Background(), client, "token123", inviteUUID))
+	assert.Error(t, DeclineInvite(context.Background(), client, "token123", uuid.New()))
+}
+
+func TestCreateInviteLink(t *testing.T) {
+	roomUUID := uuid.New()
+	link := InviteLink{LinkUUID: uuid.New(), RoomUUID: roomUUID, ExpiresAt: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), MaxUses: 5}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/"+roomUUID.String()+"/invite-links" || r.Method != http.MethodPost {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		var body map[string]int64
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		assert.Equal(t, int64(3600), body["expires_in"])
+		assert.Equal(t, int64(5), body["max_uses"])
+		json.NewEncoder(w).Encode(link)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+	got, err := CreateInviteLink(context.Background(), client, "token123", roomUUID, time.Hour, 5)
+
+	require.NoError(t, err)
+	assert.Equal(t, &link, got)
+}
+
+func TestJoinByInviteLink(t *testing.T) {
+	linkUUID := uuid.New()
+	expiredUUID := uuid.New()
+	roomUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/invites/links/" + linkUUID.String():
+			w.Write([]byte(roomUUID.String()))
+		case "/invites/links/" + expiredUUID.String():
+			http.Error(w, "gone", http.StatusGone)
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	got, err := JoinByInviteLink(context.Background(), client, "token123", linkUUID)
+	require.NoError(t, err)
+	assert.Equal(t, roomUUID, got)
+
+	_, err = JoinByInviteLink(context.Background(), client, "token123", expiredUUID)
+	assert.ErrorIs(t, err, ErrInviteLinkExpired)
+
+	_, err = JoinByInviteLink(context.Background(), client, "token123", uuid.New())
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInviteLinkExpired)
+}
+
+func TestRevokeInviteLink(t *testing.T) {
+	linkUUID := uuid.New()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/invites/links/"+linkUUID.String() || r.Method != http.MethodDelete {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	assert.NoError(t, RevokeInviteLink(context.Background(), client, "token123", linkUUID))
+	assert.Error(t, RevokeInviteLink(context.Background(), client, "token123", uuid.New()))
+}
@@ -0,0 +1,48 @@
+package client
+
+import (
+	"crypto/ecdh"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+)
+
+// FileKeyStore хранит приватный ключ устройства в файле, доступном только владельцу.
+// Приватный ключ никогда не покидает устройство.
+type FileKeyStore struct {
+	path string
+}
+
+// NewFileKeyStore создаёт хранилище приватного ключа в файле path.
+func NewFileKeyStore(path string) *FileKeyStore {
+	return &FileKeyStore{path: path}
+}
+
+// Load читает приватный ключ устройства из файла.
+func (s *FileKeyStore) Load() (*ecdh.PrivateKey, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read device key: %w", err)
+	}
+
+	priv, err := e2ee.ParsePrivateKey(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid device key file: %w", err)
+	}
+	return priv, nil
+}
+
+// Save записывает приватный ключ устройства в файл, создавая каталог при необходимости.
+func (s *FileKeyStore) Save(priv *ecdh.PrivateKey) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create device key directory: %w", err)
+	}
+
+	if err := os.WriteFile(s.path, []byte(e2ee.EncodePrivateKey(priv)), 0o600); err != nil {
+		return fmt.Errorf("failed to write device key: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "device_key")
+	store := NewFileKeyStore(path)
+
+	_, err := store.Load()
+	assert.Error(t, err)
+
+	priv, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+	require.NoError(t, store.Save(priv))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	loaded, err := store.Load()
+	require.NoError(t, err)
+	assert.True(t, priv.Equal(loaded))
+
+	require.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+	_, err = store.Load()
+	assert.Error(t, err)
+}
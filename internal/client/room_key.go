@@ -0,0 +1,483 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+)
+
+// ErrRoomKeyNotFound возвращается, если для устройства ещё не загружен ключ комнаты
+var ErrRoomKeyNotFound = errors.New("room key not found")
+
+// ErrStaleKeyEpoch возвращается, если ключ загружается для эпохи, которая уже не является текущей,
+// или ключ эпохи уже выпущен другим устройством: нужно получить сохранённый ключ эпохи и использовать его
+var ErrStaleKeyEpoch = errors.New("stale room key epoch")
+
+// ErrUntrustedRoomKey возвращается, если ключ комнаты не подписан устройством участника комнаты
+// или подпись не прошла проверку: такой ключ мог подменить сервер
+var ErrUntrustedRoomKey = errors.New("room key is not signed by a room member device")
+
+// maxRotationAttempts — число попыток получить ключ текущей эпохи, если эпоха сменилась
+// или её ключ выпущен другим устройством во время обмена
+const maxRotationAttempts = 3
+
+// RoomDevice — устройство участника комнаты с публичным ключом и ключом подписи
+type RoomDevice struct {
+	UserUUID   uuid.UUID `json:"user_uuid"`
+	DeviceUUID uuid.UUID `json:"device_uuid"`
+	PublicKey  string    `json:"public_key"`
+	SigningKey string    `json:"signing_key,omitempty"`
+}
+
+// RoomKey — ключ комнаты эпохи KeyEpoch, зашифрованный для устройства и подписанный загрузившим его устройством.
+// У ключей, загруженных до появления подписей, загрузившее устройство и подпись не указаны.
+type RoomKey struct {
+	KeyEpoch         int64     `json:"key_epoch"`
+	EncryptedKey     string    `json:"encrypted_key"`
+	IssuerUserUUID   uuid.UUID `json:"issuer_user_uuid"`
+	IssuerDeviceUUID uuid.UUID `json:"issuer_device_uuid"`
+	IssuerSigningKey string    `json:"issuer_signing_key,omitempty"`
+	Signature        string    `json:"signature,omitempty"`
+}
+
+// SignedRoomKey — ключ комнаты, зашифрованный для устройства, с подписью загружающего устройства
+type SignedRoomKey struct {
+	EncryptedKey string
+	Signature    string
+}
+
+// ListRoomDevices возвращает текущую эпоху ключа и устройства всех участников комнаты с их публичными ключами
+func ListRoomDevices(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID) (int64, []RoomDevice, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		Get("/chat/" + chatUUID.String() + "/devices")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if resp.IsError() {
+		return 0, nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var body struct {
+		KeyEpoch int64        `json:"key_epoch"`
+		Devices  []RoomDevice `json:"devices"`
+	}
+	if err := json.Unmarshal(resp.Body(), &body); err != nil {
+		return 0, nil, fmt.Errorf("invalid devices response: %w", err)
+	}
+
+	return body.KeyEpoch, body.Devices, nil
+}
+
+// UploadRoomKeys загружает ключ комнаты эпохи keyEpoch, зашифрованный и подписанный для каждого устройства (deviceUUID -> ключ)
+func UploadRoomKeys(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, keyEpoch int64, keys map[uuid.UUID]SignedRoomKey) error {
+	type keyItem struct {
+		DeviceUUID   string `json:"device_uuid"`
+		EncryptedKey string `json:"encrypted_key"`
+		Signature    string `json:"signature"`
+	}
+
+	items := make([]keyItem, 0, len(keys))
+	for deviceUUID, key := range keys {
+		items = append(items, keyItem{DeviceUUID: deviceUUID.String(), EncryptedKey: key.EncryptedKey, Signature: key.Signature})
+	}
+
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetHeader("Content-Type", "application/json").
+		SetAuthToken(token).
+		SetBody(map[string]any{"key_epoch": keyEpoch, "keys": items}).
+		Put("/chat/" + chatUUID.String() + "/keys")
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode() == http.StatusConflict {
+		return ErrStaleKeyEpoch
+	}
+
+	if resp.IsError() {
+		return fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	return nil
+}
+
+// GetRoomKey возвращает ключ комнаты эпохи keyEpoch, зашифрованный для текущего устройства.
+// Если keyEpoch не больше нуля, возвращается ключ текущей эпохи.
+func GetRoomKey(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, keyEpoch int64) (RoomKey, error) {
+	token = strings.TrimSpace(token)
+	req := client.R().
+		SetContext(ctx).
+		SetAuthToken(token)
+	if keyEpoch > 0 {
+		req.SetQueryParam("epoch", strconv.FormatInt(keyEpoch, 10))
+	}
+
+	resp, err := req.Get("/chat/" + chatUUID.String() + "/keys")
+	if err != nil {
+		return RoomKey{}, err
+	}
+
+	if resp.StatusCode() == http.StatusNotFound {
+		return RoomKey{}, ErrRoomKeyNotFound
+	}
+
+	if resp.IsError() {
+		return RoomKey{}, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var key RoomKey
+	if err := json.Unmarshal(resp.Body(), &key); err != nil {
+		return RoomKey{}, fmt.Errorf("invalid room key response: %w", err)
+	}
+
+	return key, nil
+}
+
+// EnsureRoomKey возвращает текущую эпоху, ключ комнаты этой эпохи, расшифрованный приватным ключом устройства,
+// и устройство участника, выпустившее ключ. Если ключа текущей эпохи для устройства ещё нет, генерирует новый ключ комнаты.
+// Затем ключ шифруется для всех устройств участников комнаты, подписывается ключом подписи устройства
+// и загружается на сервер, чтобы его получили и устройства, добавленные после создания ключа.
+// Ключ, не подписанный устройством участника комнаты, отклоняется с ErrUntrustedRoomKey.
+//
+// Сервер атомарно сохраняет только первый выпущенный ключ эпохи и отклоняет ключ, выпущенный после него,
+// с кодом 409: тогда ключ запрашивается повторно, и используется ключ, выпущенный другим участником.
+func EnsureRoomKey(ctx context.Context, client *resty.Client, token string, chatUUID uuid.UUID, priv *ecdh.PrivateKey) (int64, []byte, RoomDevice, error) {
+	signingKey, err := e2ee.DeriveSigningKey(priv)
+	if err != nil {
+		return 0, nil, RoomDevice{}, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		keyEpoch, roomKey, issuer, err := ensureEpochKey(ctx, client, token, chatUUID, priv, signingKey)
+		if errors.Is(err, ErrStaleKeyEpoch) && attempt+1 < maxRotationAttempts {
+			continue
+		}
+		return keyEpoch, roomKey, issuer, err
+	}
+}
+
+// ensureEpochKey выполняет одну попытку EnsureRoomKey для эпохи, текущей на момент запроса устройств
+func ensureEpochKey(
+	ctx context.Context,
+	client *resty.Client,
+	token string,
+	chatUUID uuid.UUID,
+	priv *ecdh.PrivateKey,
+	signingKey ed25519.PrivateKey,
+) (int64, []byte, RoomDevice, error) {
+	keyEpoch, devices, err := ListRoomDevices(ctx, client, token, chatUUID)
+	if err != nil {
+		return 0, nil, RoomDevice{}, err
+	}
+
+	key, err := GetRoomKey(ctx, client, token, chatUUID, keyEpoch)
+	switch {
+	case err == nil:
+		roomKey, issuer, err := openRoomKey(chatUUID, keyEpoch, key, priv, devices)
+		if err != nil {
+			return 0, nil, RoomDevice{}, err
+		}
+		if err := ShareRoomKey(ctx, client, token, chatUUID, keyEpoch, roomKey, devices, signingKey); err != nil {
+			return 0, nil, RoomDevice{}, err
+		}
+		return keyEpoch, roomKey, issuer, nil
+	case errors.Is(err, ErrRoomKeyNotFound):
+		roomKey, err := e2ee.NewRoomKey()
+		if err != nil {
+			return 0, nil, RoomDevice{}, err
+		}
+		if err := ShareRoomKey(ctx, client, token, chatUUID, keyEpoch, roomKey, devices, signingKey); err != nil {
+			return 0, nil, RoomDevice{}, err
+		}
+	default:
+		return 0, nil, RoomDevice{}, err
+	}
+
+	key, err = GetRoomKey(ctx, client, token, chatUUID, keyEpoch)
+	if err != nil {
+		return 0, nil, RoomDevice{}, err
+	}
+	roomKey, issuer, err := openRoomKey(chatUUID, keyEpoch, key, priv, devices)
+	if err != nil {
+		return 0, nil, RoomDevice{}, err
+	}
+	return keyEpoch, roomKey, issuer, nil
+}
+
+// openRoomKey проверяет подпись ключа комнаты эпохи keyEpoch ключом подписи выпустившего его устройства из devices
+// и расшифровывает ключ приватным ключом устройства. Возвращает ключ и выпустившее его устройство.
+// Ключ, выпущенный устройством не из devices, или ключ с неверной подписью отклоняется с ErrUntrustedRoomKey.
+func openRoomKey(chatUUID uuid.UUID, keyEpoch int64, key RoomKey, priv *ecdh.PrivateKey, devices []RoomDevice) ([]byte, RoomDevice, error) {
+	recipient := e2ee.EncodePublicKey(priv.PublicKey())
+
+	var issuer *RoomDevice
+	for i := range devices {
+		if devices[i].DeviceUUID == key.IssuerDeviceUUID {
+			issuer = &devices[i]
+			break
+		}
+	}
+	if key.Signature == "" || issuer == nil {
+		return nil, RoomDevice{}, fmt.Errorf("room key for epoch %d: %w", keyEpoch, ErrUntrustedRoomKey)
+	}
+	if err := e2ee.VerifyRoomKey(issuer.SigningKey, chatUUID, keyEpoch, recipient, key.EncryptedKey, key.Signature); err != nil {
+		return nil, RoomDevice{}, fmt.Errorf("room key for epoch %d: %w: %v", keyEpoch, ErrUntrustedRoomKey, err)
+	}
+
+	roomKey, err := e2ee.UnwrapRoomKey(key.EncryptedKey, priv, chatUUID, keyEpoch)
+	if err != nil {
+		return nil, RoomDevice{}, fmt.Errorf("failed to unwrap room key: %w", err)
+	}
+	return roomKey, *issuer, nil
+}
+
+// ShareRoomKey шифрует ключ комнаты эпохи keyEpoch публичным ключом каждого из устройств, подписывает каждый
+// зашифрованный ключ ключом подписи signingKey текущего устройства и загружает на сервер.
+// Устройства с публичным ключом не в формате X25519 пропускаются: они не смогут расшифровать сообщения.
+func ShareRoomKey(
+	ctx context.Context,
+	client *resty.Client,
+	token string,
+	chatUUID uuid.UUID,
+	keyEpoch int64,
+	roomKey []byte,
+	devices []RoomDevice,
+	signingKey ed25519.PrivateKey,
+) error {
+	keys := make(map[uuid.UUID]SignedRoomKey, len(devices))
+	for _, d := range devices {
+		pub, err := e2ee.ParsePublicKey(d.PublicKey)
+		if err != nil {
+			continue
+		}
+
+		wrapped, err := e2ee.WrapRoomKey(roomKey, pub, chatUUID, keyEpoch)
+		if err != nil {
+			return err
+		}
+		keys[d.DeviceUUID] = SignedRoomKey{
+			EncryptedKey: wrapped,
+			Signature:    e2ee.SignRoomKey(signingKey, chatUUID, keyEpoch, e2ee.EncodePublicKey(pub), wrapped),
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return UploadRoomKeys(ctx, client, token, chatUUID, keyEpoch, keys)
+}
+
+// RoomKeyRing хранит ключи комнаты по эпохам: исходящие сообщения шифруются ключом текущей эпохи,
+// входящие расшифровываются ключом эпохи, указанной в конверте сообщения. Ключи прошлых эпох запрашиваются
+// у сервера при первой необходимости, чтобы читать историю, отправленную до ротации.
+type RoomKeyRing struct {
+	client   *resty.Client
+	tokens   TokenSource // JWT запрашивается перед каждым обращением к серверу, а не сохраняется при создании
+	roomUUID uuid.UUID
+	priv     *ecdh.PrivateKey
+
+	observer func(devices []RoomDevice)              // вызывается с устройствами комнаты при каждой ротации
+	issuers  func(keyEpoch int64, issuer RoomDevice) // вызывается с устройством, выпустившим каждый полученный ключ
+
+	mu      sync.Mutex
+	current int64
+	ciphers map[int64]*e2ee.RoomCipher
+}
+
+// RoomKeyRingOpt — функциональная опция для настройки связки ключей комнаты.
+type RoomKeyRingOpt func(*RoomKeyRing)
+
+// WithDeviceObserver задаёт функцию, которой после получения ключа текущей эпохи передаются
+// устройства участников комнаты, например чтобы сверить их с проверенными ключами собеседников.
+// Используется первая функция, отличная от nil.
+func WithDeviceObserver(observers ...func(devices []RoomDevice)) RoomKeyRingOpt {
+	return func(k *RoomKeyRing) {
+		for _, o := range observers {
+			if o != nil {
+				k.observer = o
+				return
+			}
+		}
+	}
+}
+
+// WithIssuerObserver задаёт функцию, которой после проверки подписи каждого полученного ключа комнаты
+// передаются его эпоха и выпустившее его устройство, например чтобы предупредить о ключе от непроверенного собеседника.
+// Для ключа прошлой эпохи, загруженного до появления подписей, передаётся устройство с нулевым UUID.
+// Используется первая функция, отличная от nil.
+func WithIssuerObserver(observers ...func(keyEpoch int64, issuer RoomDevice)) RoomKeyRingOpt {
+	return func(k *RoomKeyRing) {
+		for _, o := range observers {
+			if o != nil {
+				k.issuers = o
+				return
+			}
+		}
+	}
+}
+
+// NewRoomKeyRing создаёт связку ключей комнаты и получает ключ текущей эпохи
+func NewRoomKeyRing(ctx context.Context, client *resty.Client, tokens TokenSource, roomUUID uuid.UUID, priv *ecdh.PrivateKey, opts ...RoomKeyRingOpt) (*RoomKeyRing, error) {
+	ring := NewHistoryKeyRing(client, tokens, roomUUID, priv, opts...)
+	if _, err := ring.Rotate(ctx); err != nil {
+		return nil, err
+	}
+	return ring, nil
+}
+
+// NewHistoryKeyRing создаёт связку ключей комнаты только для чтения истории: ключи эпох запрашиваются
+// у сервера при расшифровке, а ключ текущей эпохи не создаётся, поэтому Encrypt до вызова Rotate
+// возвращает ErrRoomKeyNotFound.
+func NewHistoryKeyRing(client *resty.Client, tokens TokenSource, roomUUID uuid.UUID, priv *ecdh.PrivateKey, opts ...RoomKeyRingOpt) *RoomKeyRing {
+	ring := &RoomKeyRing{
+		client:   client,
+		tokens:   tokens,
+		roomUUID: roomUUID,
+		priv:     priv,
+		ciphers:  make(map[int64]*e2ee.RoomCipher),
+	}
+	for _, opt := range opts {
+		opt(ring)
+	}
+	return ring
+}
+
+// Rotate получает ключ новой текущей эпохи (если его ещё никто не выпустил, генерирует его) и возвращает эпоху.
+// Ключ выпускает любой участник, первым получивший уведомление о ротации: сервер сохраняет только первый
+// загруженный ключ эпохи, и остальные устройства используют его.
+func (k *RoomKeyRing) Rotate(ctx context.Context) (int64, error) {
+	token, err := k.tokens(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	keyEpoch, roomKey, issuer, err := EnsureRoomKey(ctx, k.client, token, k.roomUUID, k.priv)
+	if err != nil {
+		return 0, err
+	}
+	if k.issuers != nil {
+		k.issuers(keyEpoch, issuer)
+	}
+
+	cipher, err := e2ee.NewRoomCipher(roomKey, k.roomUUID, keyEpoch)
+	if err != nil {
+		return 0, err
+	}
+
+	if k.observer != nil {
+		_, devices, err := ListRoomDevices(ctx, k.client, token, k.roomUUID)
+		if err != nil {
+			return 0, err
+		}
+		k.observer(devices)
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.ciphers[keyEpoch] = cipher
+	if keyEpoch > k.current {
+		k.current = keyEpoch
+	}
+	return k.current, nil
+}
+
+// Encrypt шифрует сообщение ключом текущей эпохи и возвращает эпоху вместе с шифртекстом
+func (k *RoomKeyRing) Encrypt(plaintext []byte) (int64, string, error) {
+	k.mu.Lock()
+	keyEpoch, cipher := k.current, k.ciphers[k.current]
+	k.mu.Unlock()
+	if cipher == nil {
+		return 0, "", ErrRoomKeyNotFound
+	}
+
+	ciphertext, err := cipher.Encrypt(plaintext)
+	if err != nil {
+		return 0, "", err
+	}
+	return keyEpoch, ciphertext, nil
+}
+
+// Decrypt расшифровывает шифртекст ключом эпохи keyEpoch
+func (k *RoomKeyRing) Decrypt(keyEpoch int64, ciphertext string) ([]byte, error) {
+	cipher, err := k.cipher(context.Background(), keyEpoch)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.Decrypt(ciphertext)
+}
+
+// cipher возвращает шифр эпохи keyEpoch, запрашивая ключ у сервера, если его ещё нет в связке
+func (k *RoomKeyRing) cipher(ctx context.Context, keyEpoch int64) (*e2ee.RoomCipher, error) {
+	k.mu.Lock()
+	cipher, ok := k.ciphers[keyEpoch]
+	k.mu.Unlock()
+	if ok {
+		return cipher, nil
+	}
+
+	token, err := k.tokens(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, err := GetRoomKey(ctx, k.client, token, k.roomUUID, keyEpoch)
+	if err != nil {
+		return nil, fmt.Errorf("room key for epoch %d: %w", keyEpoch, err)
+	}
+
+	var roomKey []byte
+	var issuer RoomDevice
+	if key.Signature == "" && key.IssuerDeviceUUID == uuid.Nil {
+		// ключ прошлой эпохи, загруженный до появления подписей: выпустившее его устройство неизвестно
+		roomKey, err = e2ee.UnwrapRoomKey(key.EncryptedKey, k.priv, k.roomUUID, keyEpoch)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unwrap room key: %w", err)
+		}
+	} else {
+		_, devices, err := ListRoomDevices(ctx, k.client, token, k.roomUUID)
+		if err != nil {
+			return nil, err
+		}
+		// ключ прошлой эпохи мог выпустить участник, уже покинувший комнату: тогда подпись проверяется
+		// ключом подписи, который сообщает сервер, а доверие к устройству оценивает наблюдатель
+		devices = append(devices, RoomDevice{
+			UserUUID:   key.IssuerUserUUID,
+			DeviceUUID: key.IssuerDeviceUUID,
+			SigningKey: key.IssuerSigningKey,
+		})
+		roomKey, issuer, err = openRoomKey(k.roomUUID, keyEpoch, key, k.priv, devices)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if k.issuers != nil {
+		k.issuers(keyEpoch, issuer)
+	}
+
+	cipher, err = e2ee.NewRoomCipher(roomKey, k.roomUUID, keyEpoch)
+	if err != nil {
+		return nil, err
+	}
+
+	k.mu.Lock()
+	k.ciphers[keyEpoch] = cipher
+	k.mu.Unlock()
+	return cipher, nil
+}
@@ -0,0 +1,667 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListRoomDevices(t *testing.T) {
+	roomUUID := uuid.New()
+	device := RoomDevice{UserUUID: uuid.New(), DeviceUUID: uuid.New(), PublicKey: "pk"}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/"+roomUUID.String()+"/devices" || r.Method != http.MethodGet {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{"key_epoch": 2, "devices": []RoomDevice{device}})
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	keyEpoch, devices, err := ListRoomDevices(context.Background(), client, "token123", roomUUID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), keyEpoch)
+	assert.Equal(t, []RoomDevice{device}, devices)
+
+	_, _, err = ListRoomDevices(context.Background(), client, "token123", uuid.New())
+	assert.Error(t, err)
+}
+
+func TestUploadRoomKeys(t *testing.T) {
+	roomUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/"+roomUUID.String()+"/keys" || r.Method != http.MethodPut {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		var body struct {
+			KeyEpoch int64 `json:"key_epoch"`
+			Keys     []struct {
+				DeviceUUID   string `json:"device_uuid"`
+				EncryptedKey string `json:"encrypted_key"`
+				Signature    string `json:"signature"`
+			} `json:"keys"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil ||
+			len(body.Keys) != 1 ||
+			body.Keys[0].DeviceUUID != deviceUUID.String() ||
+			body.Keys[0].EncryptedKey != "wrapped" ||
+			body.Keys[0].Signature != "signature" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if body.KeyEpoch != 2 {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	key := SignedRoomKey{EncryptedKey: "wrapped", Signature: "signature"}
+
+	err := UploadRoomKeys(context.Background(), client, "token123", roomUUID, 2, map[uuid.UUID]SignedRoomKey{deviceUUID: key})
+	assert.NoError(t, err)
+
+	err = UploadRoomKeys(context.Background(), client, "token123", roomUUID, 1, map[uuid.UUID]SignedRoomKey{deviceUUID: key})
+	assert.ErrorIs(t, err, ErrStaleKeyEpoch)
+
+	err = UploadRoomKeys(context.Background(), client, "token123", roomUUID, 2, map[uuid.UUID]SignedRoomKey{uuid.New(): key})
+	assert.Error(t, err)
+}
+
+func TestGetRoomKey(t *testing.T) {
+	roomUUID := uuid.New()
+	issued := RoomKey{
+		KeyEpoch:         3,
+		EncryptedKey:     "wrapped",
+		IssuerUserUUID:   uuid.New(),
+		IssuerDeviceUUID: uuid.New(),
+		IssuerSigningKey: "signing-key",
+		Signature:        "signature",
+	}
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/chat/"+roomUUID.String()+"/keys" || r.Method != http.MethodGet {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if r.URL.Query().Get("epoch") == "1" {
+			// ключ, загруженный до появления подписей
+			w.Write([]byte(`{"key_epoch":1,"encrypted_key":"legacy"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(issued)
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	key, err := GetRoomKey(context.Background(), client, "token123", roomUUID, 0)
+	require.NoError(t, err)
+	assert.Equal(t, issued, key)
+
+	key, err = GetRoomKey(context.Background(), client, "token123", roomUUID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, RoomKey{KeyEpoch: 1, EncryptedKey: "legacy"}, key)
+
+	_, err = GetRoomKey(context.Background(), client, "token123", uuid.New(), 0)
+	assert.ErrorIs(t, err, ErrRoomKeyNotFound)
+}
+
+// roomDevice возвращает устройство deviceUUID пользователя userUUID с ключами, выведенными из priv
+func roomDevice(t *testing.T, userUUID uuid.UUID, deviceUUID uuid.UUID, priv *ecdh.PrivateKey) RoomDevice {
+	signingKey, err := e2ee.DeriveSigningKey(priv)
+	require.NoError(t, err)
+	return RoomDevice{
+		UserUUID:   userUUID,
+		DeviceUUID: deviceUUID,
+		PublicKey:  e2ee.EncodePublicKey(priv.PublicKey()),
+		SigningKey: e2ee.EncodeSigningKey(signingKey.Public().(ed25519.PublicKey)),
+	}
+}
+
+// signedKey шифрует ключ комнаты для recipient и подписывает его от имени устройства issuerUUID с ключом issuer
+func signedKey(
+	t *testing.T,
+	issuer *ecdh.PrivateKey,
+	issuerUUID uuid.UUID,
+	roomUUID uuid.UUID,
+	keyEpoch int64,
+	roomKey []byte,
+	recipient *ecdh.PublicKey,
+) RoomKey {
+	signingKey, err := e2ee.DeriveSigningKey(issuer)
+	require.NoError(t, err)
+	wrapped, err := e2ee.WrapRoomKey(roomKey, recipient, roomUUID, keyEpoch)
+	require.NoError(t, err)
+	return RoomKey{
+		KeyEpoch:         keyEpoch,
+		EncryptedKey:     wrapped,
+		IssuerDeviceUUID: issuerUUID,
+		Signature:        e2ee.SignRoomKey(signingKey, roomUUID, keyEpoch, e2ee.EncodePublicKey(recipient), wrapped),
+	}
+}
+
+// fakeKeyServer — сервер ключей комнаты, хранящий только зашифрованные и подписанные ключи по эпохам.
+// Запросы ключа выполняются от имени устройства selfUUID; ключ эпохи выпускает только первая загрузка,
+// последующие принимаются, только если ключ эпохи уже есть у selfUUID.
+type fakeKeyServer struct {
+	mu       sync.Mutex
+	roomUUID uuid.UUID
+	selfUUID uuid.UUID
+	devices  []RoomDevice
+	former   []RoomDevice // устройства участников, покинувших комнату
+	keyEpoch int64
+	stored   map[int64]map[string]RoomKey
+	uploads  int
+	onUpload func(s *fakeKeyServer) // вызывается один раз перед обработкой следующей загрузки
+}
+
+// issue сохраняет ключи эпохи keyEpoch, выпущенные другим устройством
+func (s *fakeKeyServer) issue(keyEpoch int64, keys map[string]RoomKey) {
+	if s.stored[keyEpoch] == nil {
+		s.stored[keyEpoch] = make(map[string]RoomKey)
+	}
+	for deviceUUID, key := range keys {
+		s.stored[keyEpoch][deviceUUID] = key
+	}
+}
+
+func (s *fakeKeyServer) rotate() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyEpoch++
+}
+
+func (s *fakeKeyServer) keys(keyEpoch int64) map[string]string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := make(map[string]string, len(s.stored[keyEpoch]))
+	for deviceUUID, key := range s.stored[keyEpoch] {
+		keys[deviceUUID] = key.EncryptedKey
+	}
+	return keys
+}
+
+func (s *fakeKeyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/chat/"+s.roomUUID.String()+"/devices":
+		json.NewEncoder(w).Encode(map[string]any{"key_epoch": s.keyEpoch, "devices": s.devices})
+	case r.Method == http.MethodGet && r.URL.Path == "/chat/"+s.roomUUID.String()+"/keys":
+		keyEpoch := s.keyEpoch
+		if v := r.URL.Query().Get("epoch"); v != "" {
+			keyEpoch, _ = strconv.ParseInt(v, 10, 64)
+		}
+		key, ok := s.stored[keyEpoch][s.selfUUID.String()]
+		if !ok {
+			http.Error(w, "room key not found", http.StatusNotFound)
+			return
+		}
+		key.KeyEpoch = keyEpoch
+		for _, d := range append(s.devices, s.former...) {
+			if d.DeviceUUID == key.IssuerDeviceUUID {
+				key.IssuerUserUUID, key.IssuerSigningKey = d.UserUUID, d.SigningKey
+			}
+		}
+		json.NewEncoder(w).Encode(key)
+	case r.Method == http.MethodPut && r.URL.Path == "/chat/"+s.roomUUID.String()+"/keys":
+		var body struct {
+			KeyEpoch int64 `json:"key_epoch"`
+			Keys     []struct {
+				DeviceUUID   string `json:"device_uuid"`
+				EncryptedKey string `json:"encrypted_key"`
+				Signature    string `json:"signature"`
+			} `json:"keys"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if s.onUpload != nil {
+			s.onUpload(s)
+			s.onUpload = nil
+		}
+		if body.KeyEpoch != s.keyEpoch {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if _, ok := s.stored[body.KeyEpoch][s.selfUUID.String()]; len(s.stored[body.KeyEpoch]) > 0 && !ok {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		s.uploads++
+		if s.stored[body.KeyEpoch] == nil {
+			s.stored[body.KeyEpoch] = make(map[string]RoomKey)
+		}
+		for _, k := range body.Keys {
+			if _, ok := s.stored[body.KeyEpoch][k.DeviceUUID]; !ok {
+				s.stored[body.KeyEpoch][k.DeviceUUID] = RoomKey{
+					EncryptedKey:     k.EncryptedKey,
+					IssuerDeviceUUID: s.selfUUID,
+					Signature:        k.Signature,
+				}
+			}
+		}
+	default:
+		http.Error(w, "not found", http.StatusNotFound)
+	}
+}
+
+func TestEnsureRoomKey(t *testing.T) {
+	self, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+	other, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+
+	selfUUID, otherUUID, legacyUUID := uuid.New(), uuid.New(), uuid.New()
+	srv := &fakeKeyServer{
+		roomUUID: uuid.New(),
+		selfUUID: selfUUID,
+		devices: []RoomDevice{
+			roomDevice(t, uuid.New(), selfUUID, self),
+			roomDevice(t, uuid.New(), otherUUID, other),
+			{UserUUID: uuid.New(), DeviceUUID: legacyUUID, PublicKey: "publickey123"},
+		},
+		keyEpoch: 1,
+		stored:   make(map[int64]map[string]RoomKey),
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	// Ключа ещё нет — генерируется новый и рассылается всем устройствам с ключом X25519
+	keyEpoch, roomKey, issuer, err := EnsureRoomKey(context.Background(), client, "token123", srv.roomUUID, self)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), keyEpoch)
+	require.Len(t, roomKey, e2ee.RoomKeySize)
+	assert.Equal(t, srv.devices[0], issuer)
+
+	stored := srv.keys(1)
+	assert.Len(t, stored, 2)
+	assert.NotContains(t, stored, legacyUUID.String())
+
+	otherKey, err := e2ee.UnwrapRoomKey(stored[otherUUID.String()], other, srv.roomUUID, 1)
+	require.NoError(t, err)
+	assert.Equal(t, roomKey, otherKey)
+
+	// Ключ для другого устройства подписан ключом подписи текущего
+	otherSigned := srv.stored[1][otherUUID.String()]
+	assert.NoError(t, e2ee.VerifyRoomKey(srv.devices[0].SigningKey, srv.roomUUID, 1,
+		srv.devices[1].PublicKey, otherSigned.EncryptedKey, otherSigned.Signature))
+
+	// Повторный вызов возвращает уже существующий ключ
+	_, again, _, err := EnsureRoomKey(context.Background(), client, "token123", srv.roomUUID, self)
+	require.NoError(t, err)
+	assert.Equal(t, roomKey, again)
+
+	// После ротации генерируется новый ключ новой эпохи
+	srv.rotate()
+	keyEpoch, rotated, _, err := EnsureRoomKey(context.Background(), client, "token123", srv.roomUUID, self)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), keyEpoch)
+	assert.NotEqual(t, roomKey, rotated)
+
+	// Ключ, зашифрованный для другого устройства, не расшифровывается
+	_, _, _, err = EnsureRoomKey(context.Background(), client, "token123", srv.roomUUID, other)
+	assert.Error(t, err)
+}
+
+func TestEnsureRoomKey_RejectsUntrustedKey(t *testing.T) {
+	self, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+	other, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+	outsider, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+
+	roomKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+
+	selfUUID, otherUUID := uuid.New(), uuid.New()
+	roomUUID := uuid.New()
+
+	forged := signedKey(t, other, otherUUID, roomUUID, 1, roomKey, self.PublicKey())
+	forged.EncryptedKey = signedKey(t, other, otherUUID, roomUUID, 1, roomKey, self.PublicKey()).EncryptedKey
+
+	tests := []struct {
+		name string
+		key  RoomKey
+	}{
+		{name: "unsigned", key: RoomKey{EncryptedKey: signedKey(t, other, otherUUID, roomUUID, 1, roomKey, self.PublicKey()).EncryptedKey}},
+		{name: "issuer outside the room", key: signedKey(t, outsider, uuid.New(), roomUUID, 1, roomKey, self.PublicKey())},
+		{name: "signed by another key", key: signedKey(t, outsider, otherUUID, roomUUID, 1, roomKey, self.PublicKey())},
+		{name: "substituted ciphertext", key: forged},
+		{name: "signed for another epoch", key: signedKey(t, other, otherUUID, roomUUID, 2, roomKey, self.PublicKey())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &fakeKeyServer{
+				roomUUID: roomUUID,
+				selfUUID: selfUUID,
+				devices: []RoomDevice{
+					roomDevice(t, uuid.New(), selfUUID, self),
+					roomDevice(t, uuid.New(), otherUUID, other),
+				},
+				keyEpoch: 1,
+				stored:   map[int64]map[string]RoomKey{1: {selfUUID.String(): tt.key}},
+			}
+			ts := httptest.NewServer(srv)
+			defer ts.Close()
+			client := resty.New().SetBaseURL(ts.URL)
+
+			_, _, _, err := EnsureRoomKey(context.Background(), client, "token123", roomUUID, self)
+			assert.ErrorIs(t, err, ErrUntrustedRoomKey)
+			assert.Zero(t, srv.uploads)
+		})
+	}
+}
+
+func TestRoomKeyRing(t *testing.T) {
+	self, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+
+	selfUUID := uuid.New()
+	srv := &fakeKeyServer{
+		roomUUID: uuid.New(),
+		selfUUID: selfUUID,
+		devices: []RoomDevice{
+			roomDevice(t, uuid.New(), selfUUID, self),
+		},
+		keyEpoch: 1,
+		stored:   make(map[int64]map[string]RoomKey),
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	ring, err := NewRoomKeyRing(context.Background(), client, StaticToken("token123"), srv.roomUUID, self)
+	require.NoError(t, err)
+
+	epochBefore, before, err := ring.Encrypt([]byte("до ротации"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), epochBefore)
+
+	srv.rotate()
+	keyEpoch, err := ring.Rotate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), keyEpoch)
+
+	epochAfter, after, err := ring.Encrypt([]byte("после ротации"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), epochAfter)
+
+	// Новая связка без ключа первой эпохи запрашивает его у сервера, чтобы прочитать историю
+	fresh, err := NewRoomKeyRing(context.Background(), client, StaticToken("token123"), srv.roomUUID, self)
+	require.NoError(t, err)
+
+	plaintext, err := fresh.Decrypt(epochBefore, before)
+	require.NoError(t, err)
+	assert.Equal(t, "до ротации", string(plaintext))
+
+	plaintext, err = fresh.Decrypt(epochAfter, after)
+	require.NoError(t, err)
+	assert.Equal(t, "после ротации", string(plaintext))
+
+	_, err = fresh.Decrypt(7, after)
+	assert.ErrorIs(t, err, ErrRoomKeyNotFound)
+
+	// Связка для чтения истории получает ключи эпох по запросу, но не шифрует без ключа текущей эпохи
+	history := NewHistoryKeyRing(client, StaticToken("token123"), srv.roomUUID, self)
+	plaintext, err = history.Decrypt(epochBefore, before)
+	require.NoError(t, err)
+	assert.Equal(t, "до ротации", string(plaintext))
+
+	_, _, err = history.Encrypt([]byte("без ключа"))
+	assert.ErrorIs(t, err, ErrRoomKeyNotFound)
+}
+
+func TestRoomKeyRing_RequestsTokenForEachCall(t *testing.T) {
+	self, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+
+	selfUUID := uuid.New()
+	srv := &fakeKeyServer{
+		roomUUID: uuid.New(),
+		selfUUID: selfUUID,
+		devices: []RoomDevice{
+			roomDevice(t, uuid.New(), selfUUID, self),
+		},
+		keyEpoch: 1,
+		stored:   make(map[int64]map[string]RoomKey),
+	}
+
+	var mu sync.Mutex
+	current, seen := "token-1", map[string]bool{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Header.Get("Authorization")] = true
+		mu.Unlock()
+		srv.ServeHTTP(w, r)
+	}))
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	tokens := func(context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		return current, nil
+	}
+	ring, err := NewRoomKeyRing(context.Background(), client, tokens, srv.roomUUID, self)
+	require.NoError(t, err)
+
+	// JWT истёк и обновлён во время сеанса: следующая ротация использует новый JWT
+	mu.Lock()
+	current = "token-2"
+	mu.Unlock()
+	srv.rotate()
+	_, err = ring.Rotate(context.Background())
+	require.NoError(t, err)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(t, seen["Bearer token-1"])
+	assert.True(t, seen["Bearer token-2"])
+}
+
+func TestRoomKeyRing_DeviceObserver(t *testing.T) {
+	self, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+
+	selfUUID := uuid.New()
+	srv := &fakeKeyServer{
+		roomUUID: uuid.New(),
+		selfUUID: selfUUID,
+		devices: []RoomDevice{
+			roomDevice(t, uuid.New(), selfUUID, self),
+		},
+		keyEpoch: 1,
+		stored:   make(map[int64]map[string]RoomKey),
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	var observed [][]RoomDevice
+	ring, err := NewRoomKeyRing(context.Background(), client, StaticToken("token123"), srv.roomUUID, self,
+		WithDeviceObserver(nil, func(devices []RoomDevice) { observed = append(observed, devices) }))
+	require.NoError(t, err)
+	require.Len(t, observed, 1)
+	assert.Equal(t, srv.devices, observed[0])
+
+	srv.rotate()
+	_, err = ring.Rotate(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, observed, 2)
+}
+
+func TestEnsureRoomKey_LosesIssueRace(t *testing.T) {
+	self, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+	other, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+
+	selfUUID, otherUUID := uuid.New(), uuid.New()
+	srv := &fakeKeyServer{
+		roomUUID: uuid.New(),
+		selfUUID: selfUUID,
+		devices: []RoomDevice{
+			roomDevice(t, uuid.New(), selfUUID, self),
+			roomDevice(t, uuid.New(), otherUUID, other),
+		},
+		keyEpoch: 1,
+		stored:   make(map[int64]map[string]RoomKey),
+	}
+
+	// Другое устройство выпускает ключ эпохи, пока текущее генерирует свой
+	winnerKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	wrappedSelf := signedKey(t, other, otherUUID, srv.roomUUID, 1, winnerKey, self.PublicKey())
+	wrappedOther := signedKey(t, other, otherUUID, srv.roomUUID, 1, winnerKey, other.PublicKey())
+	srv.onUpload = func(s *fakeKeyServer) {
+		s.issue(1, map[string]RoomKey{selfUUID.String(): wrappedSelf, otherUUID.String(): wrappedOther})
+	}
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	// Загрузка проигравшего ключа отклоняется, и используется сохранённый ключ эпохи
+	keyEpoch, roomKey, issuer, err := EnsureRoomKey(context.Background(), client, "token123", srv.roomUUID, self)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), keyEpoch)
+	assert.Equal(t, winnerKey, roomKey)
+	assert.Equal(t, otherUUID, issuer.DeviceUUID)
+	assert.Equal(t, wrappedOther.EncryptedKey, srv.keys(1)[otherUUID.String()])
+}
+
+func TestRoomKeyRing_RotateByAnyMember(t *testing.T) {
+	self, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+	other, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+
+	selfUUID, otherUUID := uuid.New(), uuid.New()
+	srv := &fakeKeyServer{
+		roomUUID: uuid.New(),
+		selfUUID: selfUUID,
+		devices: []RoomDevice{
+			roomDevice(t, uuid.New(), selfUUID, self),
+			roomDevice(t, uuid.New(), otherUUID, other),
+		},
+		keyEpoch: 1,
+		stored:   make(map[int64]map[string]RoomKey),
+	}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	ring, err := NewRoomKeyRing(context.Background(), client, StaticToken("token123"), srv.roomUUID, self)
+	require.NoError(t, err)
+	require.Equal(t, 1, srv.uploads)
+
+	// Другой участник успел выпустить ключ новой эпохи: связка использует его
+	rotatedKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	wrapped := signedKey(t, other, otherUUID, srv.roomUUID, 2, rotatedKey, self.PublicKey())
+	srv.rotate()
+	srv.mu.Lock()
+	srv.issue(2, map[string]RoomKey{selfUUID.String(): wrapped})
+	srv.mu.Unlock()
+
+	keyEpoch, err := ring.Rotate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), keyEpoch)
+	assert.Equal(t, wrapped.EncryptedKey, srv.keys(2)[selfUUID.String()])
+
+	// Ключ новой эпохи ещё никто не выпустил: связка выпускает его сразу, не дожидаясь других участников
+	srv.rotate()
+	keyEpoch, err = ring.Rotate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), keyEpoch)
+	assert.Len(t, srv.keys(3), 2)
+}
+
+func TestRoomKeyRing_IssuerObserver(t *testing.T) {
+	self, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+	former, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+
+	selfUUID, formerUUID := uuid.New(), uuid.New()
+	srv := &fakeKeyServer{
+		roomUUID: uuid.New(),
+		selfUUID: selfUUID,
+		devices: []RoomDevice{
+			roomDevice(t, uuid.New(), selfUUID, self),
+		},
+		keyEpoch: 3,
+		stored:   make(map[int64]map[string]RoomKey),
+	}
+	formerDevice := roomDevice(t, uuid.New(), formerUUID, former)
+	srv.former = []RoomDevice{formerDevice}
+
+	// Ключ первой эпохи загружен до появления подписей, второй выпустил покинувший комнату участник
+	legacyKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	legacy := signedKey(t, former, formerUUID, srv.roomUUID, 1, legacyKey, self.PublicKey())
+	srv.issue(1, map[string]RoomKey{selfUUID.String(): {EncryptedKey: legacy.EncryptedKey}})
+
+	formerKey, err := e2ee.NewRoomKey()
+	require.NoError(t, err)
+	srv.issue(2, map[string]RoomKey{selfUUID.String(): signedKey(t, former, formerUUID, srv.roomUUID, 2, formerKey, self.PublicKey())})
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+	client := resty.New().SetBaseURL(ts.URL)
+
+	issuers := make(map[int64]RoomDevice)
+	ring, err := NewRoomKeyRing(context.Background(), client, StaticToken("token123"), srv.roomUUID, self,
+		WithIssuerObserver(nil, func(keyEpoch int64, issuer RoomDevice) { issuers[keyEpoch] = issuer }))
+	require.NoError(t, err)
+	assert.Equal(t, srv.devices[0], issuers[3])
+
+	for keyEpoch, roomKey := range map[int64][]byte{1: legacyKey, 2: formerKey} {
+		cipher, err := e2ee.NewRoomCipher(roomKey, srv.roomUUID, keyEpoch)
+		require.NoError(t, err)
+		ciphertext, err := cipher.Encrypt([]byte("история"))
+		require.NoError(t, err)
+
+		plaintext, err := ring.Decrypt(keyEpoch, ciphertext)
+		require.NoError(t, err)
+		assert.Equal(t, "история", string(plaintext))
+	}
+
+	assert.Equal(t, uuid.Nil, issuers[1].DeviceUUID)
+	assert.Equal(t, RoomDevice{
+		UserUUID:   formerDevice.UserUUID,
+		DeviceUUID: formerUUID,
+		SigningKey: formerDevice.SigningKey,
+	}, issuers[2])
+}
@@ -0,0 +1,110 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// TokenSource возвращает JWT для очередного запроса или подключения.
+// Короткоживущий JWT истекает во время длинного сеанса, поэтому его нельзя получить один раз при запуске.
+type TokenSource func(ctx context.Context) (string, error)
+
+// StaticToken возвращает источник, который всегда отдаёт token, например переданный флагом --token.
+func StaticToken(token string) TokenSource {
+	return func(context.Context) (string, error) {
+		return token, nil
+	}
+}
+
+// StoredToken возвращает источник, который читает текущий JWT из store. Токены в store обновляет
+// HTTP-клиент с опцией WithTokenRefresh, поэтому каждый запрос использует последний выданный JWT.
+func (s *FileTokenStore) StoredToken() TokenSource {
+	return func(context.Context) (string, error) {
+		accessToken, _, err := s.Load()
+		return accessToken, err
+	}
+}
+
+// RefreshedToken возвращает источник, который при каждом вызове обменивает refresh-токен из store
+// на новую пару токенов через client и сохраняет её. Подходит для подключений, которые не обновляют
+// токен по ответу 401, например для WebSocket.
+func (s *FileTokenStore) RefreshedToken(client *resty.Client) TokenSource {
+	return func(ctx context.Context) (string, error) {
+		_, refreshToken, err := s.Load()
+		if err != nil {
+			return "", err
+		}
+		accessToken, newRefreshToken, err := Refresh(ctx, client, refreshToken)
+		if err != nil {
+			return "", fmt.Errorf("failed to refresh token: %w", err)
+		}
+		if err := s.Save(accessToken, newRefreshToken); err != nil {
+			return "", err
+		}
+		return accessToken, nil
+	}
+}
+
+// FileTokenStore хранит JWT и refresh-токен клиента в JSON-файле, доступном только владельцу.
+type FileTokenStore struct {
+	path string
+}
+
+// NewFileTokenStore создаёт хранилище токенов в файле path.
+func NewFileTokenStore(path string) *FileTokenStore {
+	return &FileTokenStore{path: path}
+}
+
+// storedTokens — содержимое файла с токенами.
+type storedTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Load читает сохранённые токены из файла.
+func (s *FileTokenStore) Load() (accessToken string, refreshToken string, err error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read tokens: %w", err)
+	}
+
+	var tokens storedTokens
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return "", "", fmt.Errorf("invalid tokens file: %w", err)
+	}
+
+	return tokens.AccessToken, tokens.RefreshToken, nil
+}
+
+// Save записывает токены в файл, создавая каталог при необходимости.
+func (s *FileTokenStore) Save(accessToken string, refreshToken string) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create tokens directory: %w", err)
+	}
+
+	data, err := json.Marshal(storedTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write tokens: %w", err)
+	}
+	return nil
+}
+
+// Clear удаляет файл с токенами.
+func (s *FileTokenStore) Clear() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove tokens: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "tokens")
+	store := NewFileTokenStore(path)
+
+	_, _, err := store.Load()
+	assert.Error(t, err)
+
+	require.NoError(t, store.Save("access", "refresh"))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	access, refresh, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, "access", access)
+	assert.Equal(t, "refresh", refresh)
+
+	require.NoError(t, store.Clear())
+	require.NoError(t, store.Clear())
+
+	_, _, err = store.Load()
+	assert.Error(t, err)
+}
+
+func TestTokenSources(t *testing.T) {
+	token, err := StaticToken("static")(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "static", token)
+
+	refreshes := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body["refresh_token"] != "refresh-"+strconv.Itoa(refreshes) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		refreshes++
+		w.Header().Set("Authorization", "Bearer access-"+strconv.Itoa(refreshes))
+		w.Header().Set(RefreshTokenHeader, "refresh-"+strconv.Itoa(refreshes))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	store := NewFileTokenStore(filepath.Join(t.TempDir(), "tokens"))
+	_, err = store.StoredToken()(context.Background())
+	assert.Error(t, err)
+	_, err = store.RefreshedToken(resty.New().SetBaseURL(ts.URL))(context.Background())
+	assert.Error(t, err)
+
+	require.NoError(t, store.Save("access-0", "refresh-0"))
+	stored, refreshed := store.StoredToken(), store.RefreshedToken(resty.New().SetBaseURL(ts.URL))
+
+	token, err = stored(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "access-0", token)
+
+	// Каждый вызов обменивает refresh-токен на новую пару и сохраняет её
+	for i := 1; i <= 2; i++ {
+		token, err = refreshed(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "access-"+strconv.Itoa(i), token)
+
+		token, err = stored(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "access-"+strconv.Itoa(i), token)
+	}
+
+	require.NoError(t, store.Save("access-2", "revoked"))
+	_, err = refreshed(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+)
+
+// ErrUserNotFound возвращается, если пользователя с указанным именем нет
+var ErrUserNotFound = errors.New("user not found")
+
+// User — пользователь из справочника
+type User struct {
+	UserUUID uuid.UUID `json:"user_uuid"`
+	Username string    `json:"username"`
+}
+
+// UserDevice — активное устройство пользователя с его ключами
+type UserDevice struct {
+	DeviceUUID uuid.UUID `json:"device_uuid"`
+	PublicKey  string    `json:"public_key"`
+	SigningKey string    `json:"signing_key"`
+}
+
+// UserDevices — пользователь и его активные устройства
+type UserDevices struct {
+	User
+	Devices []UserDevice `json:"devices"`
+}
+
+// PublicKeys возвращает публичные ключи устройств пользователя
+func (u *UserDevices) PublicKeys() []string {
+	keys := make([]string, 0, len(u.Devices))
+	for _, d := range u.Devices {
+		keys = append(keys, d.PublicKey)
+	}
+	return keys
+}
+
+// SigningKeys возвращает ключи подписи устройств пользователя, у которых они есть
+func (u *UserDevices) SigningKeys() []string {
+	keys := make([]string, 0, len(u.Devices))
+	for _, d := range u.Devices {
+		if d.SigningKey != "" {
+			keys = append(keys, d.SigningKey)
+		}
+	}
+	return keys
+}
+
+// FindUser возвращает пользователя по имени
+func FindUser(ctx context.Context, client *resty.Client, token string, username string) (*User, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		Get("/users/" + url.PathEscape(username))
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var user User
+	if err := json.Unmarshal(resp.Body(), &user); err != nil {
+		return nil, fmt.Errorf("invalid user response: %w", err)
+	}
+
+	return &user, nil
+}
+
+// ListUserDevices возвращает пользователя по имени и публичные ключи его активных устройств
+func ListUserDevices(ctx context.Context, client *resty.Client, token string, username string) (*UserDevices, error) {
+	token = strings.TrimSpace(token)
+	resp, err := client.R().
+		SetContext(ctx).
+		SetAuthToken(token).
+		Get("/users/" + url.PathEscape(username) + "/devices")
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode() == http.StatusNotFound {
+		return nil, fmt.Errorf("%w: %s", ErrUserNotFound, username)
+	}
+	if resp.IsError() {
+		return nil, fmt.Errorf("server returned error: %s", resp.Status())
+	}
+
+	var devices UserDevices
+	if err := json.Unmarshal(resp.Body(), &devices); err != nil {
+		return nil, fmt.Errorf("invalid user devices response: %w", err)
+	}
+
+	return &devices, nil
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindUser(t *testing.T) {
+	user := User{UserUUID: uuid.New(), Username: "alice"}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/alice":
+			json.NewEncoder(w).Encode(user)
+		case "/users/bob":
+			http.Error(w, "not found", http.StatusNotFound)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	got, err := FindUser(context.Background(), client, "token123", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, &user, got)
+
+	_, err = FindUser(context.Background(), client, "token123", "bob")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = FindUser(context.Background(), client, "token123", "carol")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestListUserDevices(t *testing.T) {
+	devices := UserDevices{
+		User:    User{UserUUID: uuid.New(), Username: "alice"},
+		Devices: []UserDevice{{DeviceUUID: uuid.New(), PublicKey: "key-1", SigningKey: "sign-1"}},
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/alice/devices":
+			assert.Equal(t, "Bearer token123", r.Header.Get("Authorization"))
+			json.NewEncoder(w).Encode(devices)
+		case "/users/bob/devices":
+			http.Error(w, "not found", http.StatusNotFound)
+		default:
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+	}))
+	defer ts.Close()
+
+	client := resty.New().SetBaseURL(ts.URL)
+
+	got, err := ListUserDevices(context.Background(), client, "token123", "alice")
+	require.NoError(t, err)
+	assert.Equal(t, &devices, got)
+	assert.Equal(t, []string{"key-1"}, got.PublicKeys())
+	assert.Equal(t, []string{"sign-1"}, got.SigningKeys())
+
+	_, err = ListUserDevices(context.Background(), client, "token123", "bob")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	_, err = ListUserDevices(context.Background(), client, "token123", "carol")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrUserNotFound)
+}
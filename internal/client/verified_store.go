@@ -0,0 +1,151 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// VerifiedContact — собеседник, номер безопасности с которым сверен, и набор ключей его устройств на момент сверки
+type VerifiedContact struct {
+	UserUUID    uuid.UUID `json:"user_uuid"`
+	Username    string    `json:"username"`
+	PublicKeys  []string  `json:"public_keys"`
+	SigningKeys []string  `json:"signing_keys,omitempty"`
+	VerifiedAt  time.Time `json:"verified_at"`
+}
+
+// FileVerifiedKeyStore хранит проверенные ключи собеседников в JSON-файле, доступном только владельцу.
+// Файл не покидает устройство, поэтому сервер не может подменить записанные в нём ключи.
+type FileVerifiedKeyStore struct {
+	path string
+}
+
+// NewFileVerifiedKeyStore создаёт хранилище проверенных ключей в файле path.
+func NewFileVerifiedKeyStore(path string) *FileVerifiedKeyStore {
+	return &FileVerifiedKeyStore{path: path}
+}
+
+// Load читает проверенных собеседников из файла; если файла нет, возвращает пустой набор.
+func (s *FileVerifiedKeyStore) Load() (map[uuid.UUID]VerifiedContact, error) {
+	contacts := make(map[uuid.UUID]VerifiedContact)
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return contacts, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read verified keys: %w", err)
+	}
+
+	var list []VerifiedContact
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("invalid verified keys file: %w", err)
+	}
+	for _, contact := range list {
+		contacts[contact.UserUUID] = contact
+	}
+	return contacts, nil
+}
+
+// Get возвращает проверенного собеседника или nil, если он ещё не проверялся.
+func (s *FileVerifiedKeyStore) Get(userUUID uuid.UUID) (*VerifiedContact, error) {
+	contacts, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	contact, ok := contacts[userUUID]
+	if !ok {
+		return nil, nil
+	}
+	return &contact, nil
+}
+
+// Save записывает собеседника с текущим набором ключей, заменяя прежнюю запись о нём.
+func (s *FileVerifiedKeyStore) Save(contact VerifiedContact) error {
+	contacts, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	contact.PublicKeys = sortedKeys(contact.PublicKeys)
+	contacts[contact.UserUUID] = contact
+
+	list := make([]VerifiedContact, 0, len(contacts))
+	for _, c := range contacts {
+		list = append(list, c)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Username < list[j].Username })
+
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create verified keys directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write verified keys: %w", err)
+	}
+	return nil
+}
+
+// Changed возвращает проверенных собеседников, чей набор ключей среди устройств комнаты
+// отличается от записанного при сверке. Собеседники без устройств в комнате не проверяются.
+func (s *FileVerifiedKeyStore) Changed(devices []RoomDevice) ([]VerifiedContact, error) {
+	contacts, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[uuid.UUID][]string)
+	for _, d := range devices {
+		if _, ok := contacts[d.UserUUID]; ok {
+			keys[d.UserUUID] = append(keys[d.UserUUID], d.PublicKey)
+		}
+	}
+
+	var changed []VerifiedContact
+	for userUUID, current := range keys {
+		contact := contacts[userUUID]
+		if !KeysEqual(contact.PublicKeys, current) {
+			changed = append(changed, contact)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Username < changed[j].Username })
+	return changed, nil
+}
+
+// Trusted сообщает, принадлежит ли устройство issuer, выпустившее ключ комнаты, проверенному собеседнику:
+// ключ подписи устройства должен входить в набор, записанный при сверке номера безопасности.
+func (s *FileVerifiedKeyStore) Trusted(issuer RoomDevice) (bool, error) {
+	if issuer.SigningKey == "" {
+		return false, nil
+	}
+
+	contact, err := s.Get(issuer.UserUUID)
+	if err != nil || contact == nil {
+		return false, err
+	}
+	return slices.Contains(contact.SigningKeys, issuer.SigningKey), nil
+}
+
+// KeysEqual сообщает, совпадают ли наборы публичных ключей без учёта порядка.
+func KeysEqual(a, b []string) bool {
+	return slices.Equal(sortedKeys(a), sortedKeys(b))
+}
+
+// sortedKeys возвращает отсортированную копию набора ключей
+func sortedKeys(keys []string) []string {
+	sorted := slices.Clone(keys)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,104 @@
+package client
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileVerifiedKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "verified_keys")
+	store := NewFileVerifiedKeyStore(path)
+
+	contacts, err := store.Load()
+	require.NoError(t, err)
+	assert.Empty(t, contacts)
+
+	alice := VerifiedContact{UserUUID: uuid.New(), Username: "alice", PublicKeys: []string{"key-2", "key-1"}, VerifiedAt: time.Now().UTC()}
+	require.NoError(t, store.Save(alice))
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	got, err := store.Get(alice.UserUUID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, "alice", got.Username)
+	assert.Equal(t, []string{"key-1", "key-2"}, got.PublicKeys)
+
+	missing, err := store.Get(uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	// повторная сверка заменяет набор ключей
+	alice.PublicKeys = []string{"key-3"}
+	require.NoError(t, store.Save(alice))
+	got, err = store.Get(alice.UserUUID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"key-3"}, got.PublicKeys)
+
+	require.NoError(t, os.WriteFile(path, []byte("garbage"), 0o600))
+	_, err = store.Load()
+	assert.Error(t, err)
+}
+
+func TestFileVerifiedKeyStore_Changed(t *testing.T) {
+	store := NewFileVerifiedKeyStore(filepath.Join(t.TempDir(), "verified_keys"))
+
+	alice := VerifiedContact{UserUUID: uuid.New(), Username: "alice", PublicKeys: []string{"a-1", "a-2"}}
+	bob := VerifiedContact{UserUUID: uuid.New(), Username: "bob", PublicKeys: []string{"b-1"}}
+	carol := VerifiedContact{UserUUID: uuid.New(), Username: "carol", PublicKeys: []string{"c-1"}}
+	require.NoError(t, store.Save(alice))
+	require.NoError(t, store.Save(bob))
+	require.NoError(t, store.Save(carol))
+
+	devices := []RoomDevice{
+		{UserUUID: alice.UserUUID, DeviceUUID: uuid.New(), PublicKey: "a-2"},
+		{UserUUID: alice.UserUUID, DeviceUUID: uuid.New(), PublicKey: "a-1"},
+		{UserUUID: bob.UserUUID, DeviceUUID: uuid.New(), PublicKey: "b-1"},
+		{UserUUID: bob.UserUUID, DeviceUUID: uuid.New(), PublicKey: "b-2"},
+		{UserUUID: uuid.New(), DeviceUUID: uuid.New(), PublicKey: "x-1"},
+	}
+
+	changed, err := store.Changed(devices)
+	require.NoError(t, err)
+	require.Len(t, changed, 1)
+	assert.Equal(t, "bob", changed[0].Username)
+}
+
+func TestFileVerifiedKeyStore_Trusted(t *testing.T) {
+	store := NewFileVerifiedKeyStore(filepath.Join(t.TempDir(), "verified_keys"))
+
+	alice := VerifiedContact{UserUUID: uuid.New(), Username: "alice", PublicKeys: []string{"a-1"}, SigningKeys: []string{"a-sign"}}
+	require.NoError(t, store.Save(alice))
+
+	tests := []struct {
+		name    string
+		issuer  RoomDevice
+		trusted bool
+	}{
+		{name: "verified signing key", issuer: RoomDevice{UserUUID: alice.UserUUID, SigningKey: "a-sign"}, trusted: true},
+		{name: "unknown signing key", issuer: RoomDevice{UserUUID: alice.UserUUID, SigningKey: "x-sign"}},
+		{name: "no signing key", issuer: RoomDevice{UserUUID: alice.UserUUID}},
+		{name: "unverified user", issuer: RoomDevice{UserUUID: uuid.New(), SigningKey: "a-sign"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trusted, err := store.Trusted(tt.issuer)
+			require.NoError(t, err)
+			assert.Equal(t, tt.trusted, trusted)
+		})
+	}
+}
+
+func TestKeysEqual(t *testing.T) {
+	assert.True(t, KeysEqual([]string{"a", "b"}, []string{"b", "a"}))
+	assert.False(t, KeysEqual([]string{"a"}, []string{"a", "b"}))
+	assert.True(t, KeysEqual(nil, []string{}))
+}
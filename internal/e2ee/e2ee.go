@@ -0,0 +1,211 @@
+// Package e2ee реализует клиентское сквозное шифрование сообщений комнат.
+//
+// Каждое устройство владеет ключевой парой X25519. Ключ комнаты — случайный
+// 256-битный симметричный ключ, который передаётся через сервер только в
+// зашифрованном для конкретного устройства виде (ECDH с эфемерным ключом,
+// HKDF-SHA256 и ChaCha20-Poly1305). Сообщения шифруются ключом комнаты
+// алгоритмом XChaCha20-Poly1305, UUID комнаты и эпоха ключа используются как
+// дополнительные аутентифицируемые данные. Зашифрованный ключ комнаты так же
+// привязан к комнате, эпохе и публичному ключу устройства-получателя, поэтому
+// сервер не может выдать его за ключ другой комнаты или эпохи.
+package e2ee
+
+import (
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+// RoomKeySize — размер ключа комнаты в байтах
+const RoomKeySize = chacha20poly1305.KeySize
+
+// wrapInfo — контекст HKDF при выводе ключа для шифрования ключа комнаты
+var wrapInfo = []byte("bil-message room key wrap")
+
+var (
+	// ErrInvalidKey возвращается при некорректном формате ключа
+	ErrInvalidKey = errors.New("invalid key")
+	// ErrDecrypt возвращается, если шифртекст повреждён или не прошёл проверку подлинности
+	ErrDecrypt = errors.New("message authentication failed")
+)
+
+// GenerateDeviceKey генерирует ключевую пару X25519 устройства
+func GenerateDeviceKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// EncodePublicKey кодирует публичный ключ устройства в base64
+func EncodePublicKey(pub *ecdh.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub.Bytes())
+}
+
+// ParsePublicKey декодирует публичный ключ устройства из base64
+func ParsePublicKey(s string) (*ecdh.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	pub, err := ecdh.X25519().NewPublicKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return pub, nil
+}
+
+// EncodePrivateKey кодирует приватный ключ устройства в base64
+func EncodePrivateKey(priv *ecdh.PrivateKey) string {
+	return base64.StdEncoding.EncodeToString(priv.Bytes())
+}
+
+// ParsePrivateKey декодирует приватный ключ устройства из base64
+func ParsePrivateKey(s string) (*ecdh.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	return priv, nil
+}
+
+// NewRoomKey генерирует случайный ключ комнаты
+func NewRoomKey() ([]byte, error) {
+	key := make([]byte, RoomKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// WrapRoomKey шифрует ключ комнаты roomUUID эпохи keyEpoch для устройства с публичным ключом pub.
+// Результат: base64(эфемерный публичный ключ || nonce || шифртекст).
+func WrapRoomKey(roomKey []byte, pub *ecdh.PublicKey, roomUUID uuid.UUID, keyEpoch int64) (string, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	aead, err := wrapAEAD(ephemeral, pub, ephemeral.PublicKey())
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	out := append(ephemeral.PublicKey().Bytes(), nonce...)
+	out = aead.Seal(out, nonce, roomKey, wrapAD(roomUUID, keyEpoch, pub))
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// UnwrapRoomKey расшифровывает ключ комнаты roomUUID эпохи keyEpoch приватным ключом устройства.
+// Ключ, зашифрованный для другой комнаты, эпохи или устройства, отклоняется с ErrDecrypt.
+func UnwrapRoomKey(wrapped string, priv *ecdh.PrivateKey, roomUUID uuid.UUID, keyEpoch int64) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	const pubSize = 32
+	if len(raw) < pubSize+chacha20poly1305.NonceSize {
+		return nil, ErrDecrypt
+	}
+
+	ephemeral, err := ecdh.X25519().NewPublicKey(raw[:pubSize])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	aead, err := wrapAEAD(priv, ephemeral, ephemeral)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	nonce := raw[pubSize : pubSize+aead.NonceSize()]
+	roomKey, err := aead.Open(nil, nonce, raw[pubSize+aead.NonceSize():], wrapAD(roomUUID, keyEpoch, priv.PublicKey()))
+	if err != nil || len(roomKey) != RoomKeySize {
+		return nil, ErrDecrypt
+	}
+	return roomKey, nil
+}
+
+// wrapAEAD выводит ключ шифрования ключа комнаты из общего секрета ECDH.
+// Соль HKDF — эфемерный публичный ключ отправителя.
+func wrapAEAD(priv *ecdh.PrivateKey, peer, ephemeral *ecdh.PublicKey) (cipher.AEAD, error) {
+	shared, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+
+	key := make([]byte, chacha20poly1305.KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, ephemeral.Bytes(), wrapInfo), key); err != nil {
+		return nil, err
+	}
+	return chacha20poly1305.New(key)
+}
+
+// epochAD возвращает дополнительные аутентифицируемые данные UUID комнаты || эпоха ключа (big-endian)
+func epochAD(roomUUID uuid.UUID, keyEpoch int64) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte(nil), roomUUID[:]...), uint64(keyEpoch))
+}
+
+// wrapAD возвращает дополнительные аутентифицируемые данные зашифрованного ключа комнаты:
+// UUID комнаты || эпоха ключа || публичный ключ устройства-получателя
+func wrapAD(roomUUID uuid.UUID, keyEpoch int64, pub *ecdh.PublicKey) []byte {
+	return append(epochAD(roomUUID, keyEpoch), pub.Bytes()...)
+}
+
+// RoomCipher шифрует и расшифровывает сообщения одной комнаты
+type RoomCipher struct {
+	aead cipher.AEAD
+	ad   []byte
+}
+
+// NewRoomCipher создаёт шифр сообщений комнаты roomUUID с ключом roomKey эпохи keyEpoch
+func NewRoomCipher(roomKey []byte, roomUUID uuid.UUID, keyEpoch int64) (*RoomCipher, error) {
+	if len(roomKey) != RoomKeySize {
+		return nil, ErrInvalidKey
+	}
+	aead, err := chacha20poly1305.NewX(roomKey)
+	if err != nil {
+		return nil, err
+	}
+	return &RoomCipher{aead: aead, ad: epochAD(roomUUID, keyEpoch)}, nil
+}
+
+// Encrypt шифрует сообщение и возвращает base64(nonce || шифртекст)
+func (c *RoomCipher) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	out := c.aead.Seal(nonce, nonce, plaintext, c.ad)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Decrypt расшифровывает сообщение; при повреждении или подмене возвращает ErrDecrypt
+func (c *RoomCipher) Decrypt(ciphertext string) ([]byte, error) {
+	raw, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil || len(raw) < c.aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	plaintext, err := c.aead.Open(nil, raw[:c.aead.NonceSize()], raw[c.aead.NonceSize():], c.ad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
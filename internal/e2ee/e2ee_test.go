@@ -0,0 +1,103 @@
+package e2ee
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceKeyEncoding(t *testing.T) {
+	priv, err := GenerateDeviceKey()
+	require.NoError(t, err)
+
+	parsedPriv, err := ParsePrivateKey(EncodePrivateKey(priv))
+	require.NoError(t, err)
+	assert.True(t, priv.Equal(parsedPriv))
+
+	parsedPub, err := ParsePublicKey(EncodePublicKey(priv.PublicKey()))
+	require.NoError(t, err)
+	assert.True(t, priv.PublicKey().Equal(parsedPub))
+
+	_, err = ParsePublicKey("publickey123")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = ParsePrivateKey("not base64!")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestWrapRoomKey(t *testing.T) {
+	roomUUID := uuid.New()
+	priv, err := GenerateDeviceKey()
+	require.NoError(t, err)
+	other, err := GenerateDeviceKey()
+	require.NoError(t, err)
+
+	roomKey, err := NewRoomKey()
+	require.NoError(t, err)
+
+	wrapped, err := WrapRoomKey(roomKey, priv.PublicKey(), roomUUID, 2)
+	require.NoError(t, err)
+
+	unwrapped, err := UnwrapRoomKey(wrapped, priv, roomUUID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, roomKey, unwrapped)
+
+	// Чужое устройство не может расшифровать ключ
+	_, err = UnwrapRoomKey(wrapped, other, roomUUID, 2)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// Сервер не может выдать ключ за ключ другой комнаты или эпохи
+	_, err = UnwrapRoomKey(wrapped, priv, uuid.New(), 2)
+	assert.ErrorIs(t, err, ErrDecrypt)
+	_, err = UnwrapRoomKey(wrapped, priv, roomUUID, 3)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = UnwrapRoomKey("AAAA", priv, roomUUID, 2)
+	assert.ErrorIs(t, err, ErrDecrypt)
+}
+
+func TestRoomCipher(t *testing.T) {
+	roomUUID := uuid.New()
+	roomKey, err := NewRoomKey()
+	require.NoError(t, err)
+
+	c, err := NewRoomCipher(roomKey, roomUUID, 1)
+	require.NoError(t, err)
+
+	ciphertext, err := c.Encrypt([]byte("hello"))
+	require.NoError(t, err)
+	assert.NotContains(t, ciphertext, "hello")
+
+	plaintext, err := c.Decrypt(ciphertext)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(plaintext))
+
+	// Тот же ключ, но другая комната
+	otherRoom, err := NewRoomCipher(roomKey, uuid.New(), 1)
+	require.NoError(t, err)
+	_, err = otherRoom.Decrypt(ciphertext)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// Тот же ключ и комната, но другая эпоха
+	otherEpoch, err := NewRoomCipher(roomKey, roomUUID, 2)
+	require.NoError(t, err)
+	_, err = otherEpoch.Decrypt(ciphertext)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// Другой ключ
+	otherKey, err := NewRoomKey()
+	require.NoError(t, err)
+	otherCipher, err := NewRoomCipher(otherKey, roomUUID, 1)
+	require.NoError(t, err)
+	_, err = otherCipher.Decrypt(ciphertext)
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	// Открытый текст от сервера или повреждённый кадр
+	_, err = c.Decrypt("hello")
+	assert.ErrorIs(t, err, ErrDecrypt)
+
+	_, err = NewRoomCipher([]byte("short"), roomUUID, 1)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
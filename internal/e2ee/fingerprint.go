@@ -0,0 +1,99 @@
+package e2ee
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// safetyNumberContext отделяет хэш номера безопасности от других применений SHA-512
+var safetyNumberContext = []byte("bil-message safety number v1")
+
+// safetyNumberIterations — число итераций хэширования половины номера безопасности
+const safetyNumberIterations = 5200
+
+// Fingerprint возвращает отпечаток публичного ключа X25519 устройства:
+// первые 16 байт SHA-512 от ключа в шестнадцатеричном виде группами по 4 символа.
+func Fingerprint(publicKey string) (string, error) {
+	pub, err := ParsePublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha512.Sum512(pub.Bytes())
+	digits := hex.EncodeToString(sum[:16])
+
+	groups := make([]string, 0, len(digits)/4)
+	for i := 0; i < len(digits); i += 4 {
+		groups = append(groups, digits[i:i+4])
+	}
+	return strings.Join(groups, " "), nil
+}
+
+// SafetyNumber вычисляет номер безопасности пары пользователей по наборам публичных ключей их устройств.
+// Номер из 60 цифр одинаков у обоих собеседников независимо от порядка аргументов и порядка ключей;
+// его сверяют вне сервера, чтобы убедиться, что сервер не подменил ключи.
+func SafetyNumber(userA uuid.UUID, keysA []string, userB uuid.UUID, keysB []string) (string, error) {
+	a, err := safetyNumberHalf(userA, keysA)
+	if err != nil {
+		return "", err
+	}
+	b, err := safetyNumberHalf(userB, keysB)
+	if err != nil {
+		return "", err
+	}
+
+	if userB.String() < userA.String() {
+		a, b = b, a
+	}
+
+	digits := a + b
+	groups := make([]string, 0, len(digits)/5)
+	for i := 0; i < len(digits); i += 5 {
+		groups = append(groups, digits[i:i+5])
+	}
+	return strings.Join(groups, " "), nil
+}
+
+// safetyNumberHalf возвращает 30 цифр номера безопасности, относящихся к одному пользователю
+func safetyNumberHalf(userUUID uuid.UUID, keys []string) (string, error) {
+	if len(keys) == 0 {
+		return "", fmt.Errorf("%w: no device keys for user %s", ErrInvalidKey, userUUID)
+	}
+
+	raw := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pub, err := ParsePublicKey(key)
+		if err != nil {
+			return "", err
+		}
+		raw = append(raw, string(pub.Bytes()))
+	}
+	sort.Strings(raw)
+
+	input := append([]byte{}, safetyNumberContext...)
+	input = append(input, userUUID[:]...)
+	for _, key := range raw {
+		input = append(input, key...)
+	}
+
+	sum := sha512.Sum512(input)
+	for i := 1; i < safetyNumberIterations; i++ {
+		sum = sha512.Sum512(append(sum[:], input...))
+	}
+
+	var b strings.Builder
+	for i := 0; i < 6; i++ {
+		chunk := sum[i*5 : i*5+5]
+		var n uint64
+		for _, c := range chunk {
+			n = n<<8 | uint64(c)
+		}
+		fmt.Fprintf(&b, "%05d", n%100000)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,64 @@
+package e2ee
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFingerprint(t *testing.T) {
+	priv, err := GenerateDeviceKey()
+	require.NoError(t, err)
+	publicKey := EncodePublicKey(priv.PublicKey())
+
+	first, err := Fingerprint(publicKey)
+	require.NoError(t, err)
+	second, err := Fingerprint(publicKey)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Regexp(t, regexp.MustCompile(`^([0-9a-f]{4} ){7}[0-9a-f]{4}$`), first)
+
+	other, err := GenerateDeviceKey()
+	require.NoError(t, err)
+	otherFingerprint, err := Fingerprint(EncodePublicKey(other.PublicKey()))
+	require.NoError(t, err)
+	assert.NotEqual(t, first, otherFingerprint)
+
+	_, err = Fingerprint("not a key")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestSafetyNumber(t *testing.T) {
+	newKey := func() string {
+		priv, err := GenerateDeviceKey()
+		require.NoError(t, err)
+		return EncodePublicKey(priv.PublicKey())
+	}
+
+	alice, bob := uuid.New(), uuid.New()
+	aliceKeys := []string{newKey(), newKey()}
+	bobKeys := []string{newKey()}
+
+	number, err := SafetyNumber(alice, aliceKeys, bob, bobKeys)
+	require.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^([0-9]{5} ){11}[0-9]{5}$`), number)
+
+	// номер одинаков у обоих собеседников и не зависит от порядка ключей
+	reversed, err := SafetyNumber(bob, bobKeys, alice, []string{aliceKeys[1], aliceKeys[0]})
+	require.NoError(t, err)
+	assert.Equal(t, number, reversed)
+
+	// новое устройство собеседника меняет номер
+	changed, err := SafetyNumber(alice, aliceKeys, bob, append(bobKeys, newKey()))
+	require.NoError(t, err)
+	assert.NotEqual(t, number, changed)
+
+	_, err = SafetyNumber(alice, aliceKeys, bob, nil)
+	assert.ErrorIs(t, err, ErrInvalidKey)
+
+	_, err = SafetyNumber(alice, aliceKeys, bob, []string{"not a key"})
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
@@ -0,0 +1,128 @@
+package e2ee
+
+import (
+	"crypto/ecdh"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/hkdf"
+)
+
+// signingInfo — контекст HKDF при выводе ключа подписи из ключа устройства
+var signingInfo = []byte("bil-message device signing key")
+
+// approvalContext — префикс подписываемого сообщения при подтверждении устройства
+var approvalContext = []byte("bil-message device approval v1")
+
+// roomKeyContext — префикс подписываемого сообщения при выпуске ключа комнаты
+var roomKeyContext = []byte("bil-message room key v1")
+
+// ErrInvalidSignature возвращается, если подпись не прошла проверку
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// DeriveSigningKey выводит ключ подписи Ed25519 из приватного ключа X25519 устройства.
+// Ключ подписи детерминирован, поэтому его не нужно хранить отдельно от ключа устройства.
+func DeriveSigningKey(priv *ecdh.PrivateKey) (ed25519.PrivateKey, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, priv.Bytes(), nil, signingInfo), seed); err != nil {
+		return nil, err
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// EncodeSigningKey кодирует публичный ключ подписи устройства в base64
+func EncodeSigningKey(pub ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(pub)
+}
+
+// ParseSigningKey декодирует публичный ключ подписи устройства из base64
+func ParseSigningKey(s string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidKey, err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: signing key must be %d bytes", ErrInvalidKey, ed25519.PublicKeySize)
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// SignDeviceApproval подписывает подтверждение нового устройства deviceUUID с ключами
+// publicKey и signingKey (в base64) ключом подписи уже доверенного устройства.
+// Возвращает подпись в base64.
+func SignDeviceApproval(priv ed25519.PrivateKey, deviceUUID uuid.UUID, publicKey string, signingKey string) string {
+	sig := ed25519.Sign(priv, deviceApprovalMessage(deviceUUID, publicKey, signingKey))
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// VerifyDeviceApproval проверяет подпись подтверждения устройства ключом подписи approverKey (в base64).
+// Возвращает ErrInvalidSignature, если подпись не соответствует устройству и его ключам.
+func VerifyDeviceApproval(approverKey string, deviceUUID uuid.UUID, publicKey string, signingKey string, signature string) error {
+	pub, err := ParseSigningKey(approverKey)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(pub, deviceApprovalMessage(deviceUUID, publicKey, signingKey), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// deviceApprovalMessage собирает подписываемое сообщение:
+// контекст || UUID устройства || публичный ключ || 0 || ключ подписи
+func deviceApprovalMessage(deviceUUID uuid.UUID, publicKey string, signingKey string) []byte {
+	msg := make([]byte, 0, len(approvalContext)+16+len(publicKey)+1+len(signingKey))
+	msg = append(msg, approvalContext...)
+	msg = append(msg, deviceUUID[:]...)
+	msg = append(msg, publicKey...)
+	msg = append(msg, 0)
+	msg = append(msg, signingKey...)
+	return msg
+}
+
+// SignRoomKey подписывает ключ комнаты roomUUID эпохи keyEpoch, зашифрованный для устройства с публичным ключом
+// publicKey (в base64), ключом подписи загружающего устройства. Возвращает подпись в base64.
+func SignRoomKey(priv ed25519.PrivateKey, roomUUID uuid.UUID, keyEpoch int64, publicKey string, encryptedKey string) string {
+	sig := ed25519.Sign(priv, roomKeyMessage(roomUUID, keyEpoch, publicKey, encryptedKey))
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// VerifyRoomKey проверяет подпись зашифрованного ключа комнаты ключом подписи issuerKey (в base64).
+// Возвращает ErrInvalidSignature, если подпись не соответствует комнате, эпохе, получателю или ключу.
+func VerifyRoomKey(issuerKey string, roomUUID uuid.UUID, keyEpoch int64, publicKey string, encryptedKey string, signature string) error {
+	pub, err := ParseSigningKey(issuerKey)
+	if err != nil {
+		return err
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if !ed25519.Verify(pub, roomKeyMessage(roomUUID, keyEpoch, publicKey, encryptedKey), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// roomKeyMessage собирает подписываемое сообщение:
+// контекст || UUID комнаты || эпоха (big-endian) || публичный ключ получателя || 0 || зашифрованный ключ
+func roomKeyMessage(roomUUID uuid.UUID, keyEpoch int64, publicKey string, encryptedKey string) []byte {
+	msg := make([]byte, 0, len(roomKeyContext)+16+8+len(publicKey)+1+len(encryptedKey))
+	msg = append(msg, roomKeyContext...)
+	msg = append(msg, roomUUID[:]...)
+	msg = binary.BigEndian.AppendUint64(msg, uint64(keyEpoch))
+	msg = append(msg, publicKey...)
+	msg = append(msg, 0)
+	msg = append(msg, encryptedKey...)
+	return msg
+}
@@ -0,0 +1,94 @@
+package e2ee
+
+import (
+	"crypto/ed25519"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeriveSigningKey(t *testing.T) {
+	priv, err := GenerateDeviceKey()
+	require.NoError(t, err)
+
+	first, err := DeriveSigningKey(priv)
+	require.NoError(t, err)
+	second, err := DeriveSigningKey(priv)
+	require.NoError(t, err)
+	assert.True(t, first.Equal(second), "ключ подписи должен выводиться детерминированно")
+
+	other, err := GenerateDeviceKey()
+	require.NoError(t, err)
+	otherSigning, err := DeriveSigningKey(other)
+	require.NoError(t, err)
+	assert.False(t, first.Equal(otherSigning))
+
+	parsed, err := ParseSigningKey(EncodeSigningKey(first.Public().(ed25519.PublicKey)))
+	require.NoError(t, err)
+	assert.True(t, parsed.Equal(first.Public()))
+
+	_, err = ParseSigningKey("c2hvcnQ=")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestDeviceApprovalSignature(t *testing.T) {
+	approver, err := GenerateDeviceKey()
+	require.NoError(t, err)
+	approverSigning, err := DeriveSigningKey(approver)
+	require.NoError(t, err)
+	approverKey := EncodeSigningKey(approverSigning.Public().(ed25519.PublicKey))
+
+	device, err := GenerateDeviceKey()
+	require.NoError(t, err)
+	deviceSigning, err := DeriveSigningKey(device)
+	require.NoError(t, err)
+
+	deviceUUID := uuid.New()
+	publicKey := EncodePublicKey(device.PublicKey())
+	signingKey := EncodeSigningKey(deviceSigning.Public().(ed25519.PublicKey))
+
+	sig := SignDeviceApproval(approverSigning, deviceUUID, publicKey, signingKey)
+	assert.NoError(t, VerifyDeviceApproval(approverKey, deviceUUID, publicKey, signingKey, sig))
+
+	// подпись привязана к устройству и его ключам
+	assert.ErrorIs(t, VerifyDeviceApproval(approverKey, uuid.New(), publicKey, signingKey, sig), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyDeviceApproval(approverKey, deviceUUID, EncodePublicKey(approver.PublicKey()), signingKey, sig), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyDeviceApproval(approverKey, deviceUUID, publicKey, approverKey, sig), ErrInvalidSignature)
+
+	// подпись другим ключом не принимается
+	assert.ErrorIs(t, VerifyDeviceApproval(signingKey, deviceUUID, publicKey, signingKey, sig), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyDeviceApproval(approverKey, deviceUUID, publicKey, signingKey, "not base64!"), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyDeviceApproval("bad", deviceUUID, publicKey, signingKey, sig), ErrInvalidKey)
+}
+
+func TestRoomKeySignature(t *testing.T) {
+	issuer, err := GenerateDeviceKey()
+	require.NoError(t, err)
+	issuerSigning, err := DeriveSigningKey(issuer)
+	require.NoError(t, err)
+	issuerKey := EncodeSigningKey(issuerSigning.Public().(ed25519.PublicKey))
+
+	recipient, err := GenerateDeviceKey()
+	require.NoError(t, err)
+	publicKey := EncodePublicKey(recipient.PublicKey())
+	roomUUID := uuid.New()
+
+	sig := SignRoomKey(issuerSigning, roomUUID, 2, publicKey, "wrapped")
+	assert.NoError(t, VerifyRoomKey(issuerKey, roomUUID, 2, publicKey, "wrapped", sig))
+
+	// подпись привязана к комнате, эпохе, получателю и зашифрованному ключу
+	assert.ErrorIs(t, VerifyRoomKey(issuerKey, uuid.New(), 2, publicKey, "wrapped", sig), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyRoomKey(issuerKey, roomUUID, 3, publicKey, "wrapped", sig), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyRoomKey(issuerKey, roomUUID, 2, EncodePublicKey(issuer.PublicKey()), "wrapped", sig), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyRoomKey(issuerKey, roomUUID, 2, publicKey, "substituted", sig), ErrInvalidSignature)
+
+	// подпись другим ключом не принимается
+	recipientSigning, err := DeriveSigningKey(recipient)
+	require.NoError(t, err)
+	otherKey := EncodeSigningKey(recipientSigning.Public().(ed25519.PublicKey))
+	assert.ErrorIs(t, VerifyRoomKey(otherKey, roomUUID, 2, publicKey, "wrapped", sig), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyRoomKey(issuerKey, roomUUID, 2, publicKey, "wrapped", "not base64!"), ErrInvalidSignature)
+	assert.ErrorIs(t, VerifyRoomKey("", roomUUID, 2, publicKey, "wrapped", sig), ErrInvalidKey)
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// Интерфейс для получения записей о блокировках входа
+type LockoutLister interface {
+	// ListLockouts возвращает не более limit последних блокировок входа, от новых к старым
+	ListLockouts(ctx context.Context, limit int) ([]models.LoginLockoutDB, error)
+}
+
+// LockoutResponse — запись о блокировке входа.
+// swagger:model LockoutResponse
+type LockoutResponse struct {
+	// UUID записи
+	LockoutUUID string `json:"lockout_uuid"`
+	// Область блокировки: username или ip
+	Scope string `json:"scope"`
+	// Имя пользователя (в нижнем регистре) или IP-адрес клиента
+	Key string `json:"key"`
+	// Число неудачных попыток к моменту блокировки
+	Failures int `json:"failures"`
+	// Момент блокировки
+	LockedAt time.Time `json:"locked_at"`
+	// Момент окончания блокировки
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+// LockoutsResponse — список блокировок входа.
+// swagger:model LockoutsResponse
+type LockoutsResponse struct {
+	// Блокировки от новых к старым
+	Lockouts []LockoutResponse `json:"lockouts"`
+}
+
+// ListLockoutsHandler возвращает последние блокировки входа
+// @Summary Блокировки входа
+// @Description Возвращает последние блокировки входа по имени пользователя и IP-адресу, от новых к старым.
+// @Description Доступно только с токеном администратора сервера (флаг --admin-token) в заголовке Authorization: Bearer.
+// @Tags Admin
+// @Accept plain
+// @Produce json
+// @Param limit query int false "Число записей (по умолчанию 100, не более 1000)"
+// @Success 200 {object} LockoutsResponse "Блокировки входа"
+// @Failure 400 "Некорректный limit"
+// @Failure 401 "Неверный токен администратора"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /admin/lockouts [get]
+func ListLockoutsHandler(svc LockoutLister, adminToken string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdmin(r, adminToken) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var limit int
+		if v := r.URL.Query().Get("limit"); v != "" {
+			var err error
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		lockouts, err := svc.ListLockouts(r.Context(), limit)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp := LockoutsResponse{Lockouts: make([]LockoutResponse, 0, len(lockouts))}
+		for _, l := range lockouts {
+			resp.Lockouts = append(resp.Lockouts, LockoutResponse{
+				LockoutUUID: l.LockoutUUID.String(),
+				Scope:       l.Scope,
+				Key:         l.Key,
+				Failures:    l.Failures,
+				LockedAt:    l.LockedAt,
+				LockedUntil: l.LockedUntil,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// isAdmin сравнивает токен из заголовка Authorization: Bearer с токеном администратора за постоянное время.
+// Пустой токен администратора не принимается никогда.
+func isAdmin(r *http.Request, adminToken string) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || adminToken == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(strings.TrimSpace(token)), []byte(adminToken)) == 1
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListLockoutsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockLockoutLister(ctrl)
+
+	lockedAt := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	lockout := models.LoginLockoutDB{
+		LockoutUUID: uuid.New(),
+		Scope:       models.LoginLockoutScopeUsername,
+		Key:         "johndoe",
+		Failures:    10,
+		LockedAt:    lockedAt,
+		LockedUntil: lockedAt.Add(15 * time.Minute),
+	}
+
+	tests := []struct {
+		name           string
+		adminToken     string
+		authHeader     string
+		query          string
+		setup          func()
+		expectedStatus int
+		expectedBody   *LockoutsResponse
+	}{
+		{
+			name:       "success",
+			adminToken: "admin-secret",
+			authHeader: "Bearer admin-secret",
+			query:      "?limit=10",
+			setup: func() {
+				mockSvc.EXPECT().ListLockouts(gomock.Any(), 10).Return([]models.LoginLockoutDB{lockout}, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody: &LockoutsResponse{Lockouts: []LockoutResponse{{
+				LockoutUUID: lockout.LockoutUUID.String(),
+				Scope:       models.LoginLockoutScopeUsername,
+				Key:         "johndoe",
+				Failures:    10,
+				LockedAt:    lockout.LockedAt,
+				LockedUntil: lockout.LockedUntil,
+			}}},
+		},
+		{
+			name:       "default limit",
+			adminToken: "admin-secret",
+			authHeader: "Bearer admin-secret",
+			setup: func() {
+				mockSvc.EXPECT().ListLockouts(gomock.Any(), 0).Return(nil, nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   &LockoutsResponse{Lockouts: []LockoutResponse{}},
+		},
+		{
+			name:           "wrong token",
+			adminToken:     "admin-secret",
+			authHeader:     "Bearer guess",
+			setup:          func() {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "missing token",
+			adminToken:     "admin-secret",
+			setup:          func() {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "admin token not configured",
+			authHeader:     "Bearer ",
+			setup:          func() {},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid limit",
+			adminToken:     "admin-secret",
+			authHeader:     "Bearer admin-secret",
+			query:          "?limit=-1",
+			setup:          func() {},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "service error",
+			adminToken: "admin-secret",
+			authHeader: "Bearer admin-secret",
+			setup: func() {
+				mockSvc.EXPECT().ListLockouts(gomock.Any(), 0).Return(nil, errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/lockouts"+tt.query, nil)
+			if tt.authHeader != "" {
+				req.Header.Set("Authorization", tt.authHeader)
+			}
+			w := httptest.NewRecorder()
+
+			ListLockoutsHandler(mockSvc, tt.adminToken).ServeHTTP(w, req)
+
+			require.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != nil {
+				var body LockoutsResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+				assert.Equal(t, *tt.expectedBody, body)
+			}
+		})
+	}
+}
@@ -4,9 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"net"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/jwt"
+	"github.com/sbilibin2017/bil-message/internal/models"
 	"github.com/sbilibin2017/bil-message/internal/services"
 )
 
@@ -30,13 +35,15 @@ type RegisterRequest struct {
 
 // RegisterHandler
 // @Summary Регистрация нового пользователя
-// @Description Создаёт нового пользователя с заданными username и password
+// @Description Создаёт нового пользователя с заданными username и password.
+// @Description Имя пользователя: 3–32 символа, латинские буквы, цифры, '_', '.' и '-', начинается с буквы или цифры.
+// @Description Пароль проверяется политикой паролей сервера; при нарушении тело ответа содержит описание правила.
 // @Tags Auth
 // @Accept json
 // @Produce plain
 // @Param request body RegisterRequest true "Данные пользователя"
 // @Success 200 "Пользователь успешно зарегистрирован"
-// @Failure 400 "Некорректные данные запроса"
+// @Failure 400 "Некорректные данные запроса, имя пользователя или пароль не соответствуют правилам"
 // @Failure 409 "Пользователь с таким именем уже существует"
 // @Failure 500 "Внутренняя ошибка сервера"
 // @Router /auth/register [post]
@@ -59,6 +66,9 @@ func RegisterHandler(svc Registerer) http.HandlerFunc {
 				w.WriteHeader(http.StatusConflict)
 				return
 			}
+			if writePolicyError(w, err) {
+				return
+			}
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
@@ -69,7 +79,7 @@ func RegisterHandler(svc Registerer) http.HandlerFunc {
 }
 
 type DeviceAdder interface {
-	AddDevice(ctx context.Context, username, password, publicKey string) (deviceUUID uuid.UUID, err error)
+	AddDevice(ctx context.Context, username, password, publicKey, signingKey, ip string) (deviceUUID uuid.UUID, status string, err error)
 }
 
 // DeviceRequest представляет JSON тело запроса на добавление устройства.
@@ -89,17 +99,25 @@ type DeviceRequest struct {
 	// required: true
 	// example: MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEAr...
 	PublicKey string `json:"public_key"`
+
+	// Публичный ключ подписи устройства (Ed25519, base64); нужен, чтобы подтверждать новые устройства
+	// example: 9c8Yk0m3qYH2b1sV2uQ6fC5a1nS7R4o0pZ3kL8eW2xE=
+	SigningKey string `json:"signing_key,omitempty"`
 }
 
 // AddDeviceHandler
 // @Summary Добавление нового устройства
-// @Description Привязывает новое устройство к пользователю и возвращает UUID устройства
+// @Description Привязывает новое устройство к пользователю и возвращает UUID устройства.
+// @Description Если на сервере включено подтверждение устройств и у пользователя уже есть доверенное устройство,
+// @Description новое устройство ожидает подтверждения и сервер отвечает 202.
 // @Tags Auth
 // @Accept json
 // @Produce plain
 // @Param request body DeviceRequest true "Данные устройства"
 // @Success 200 {string} string "UUID устройства"
+// @Success 202 {string} string "UUID устройства, ожидающего подтверждения"
 // @Failure 400 "Неверные учетные данные или некорректные данные запроса"
+// @Failure 429 "Слишком много неудачных попыток входа; время ожидания в секундах — в заголовке Retry-After"
 // @Failure 500 "Внутренняя ошибка сервера"
 // @Router /auth/device [post]
 func AddDeviceHandler(svc DeviceAdder) http.HandlerFunc {
@@ -115,24 +133,33 @@ func AddDeviceHandler(svc DeviceAdder) http.HandlerFunc {
 			return
 		}
 
-		deviceUUID, err := svc.AddDevice(r.Context(), req.Username, req.Password, req.PublicKey)
+		deviceUUID, status, err := svc.AddDevice(r.Context(), req.Username, req.Password, req.PublicKey, req.SigningKey, clientIP(r))
 		if err != nil {
-			if errors.Is(err, services.ErrInvalidCredentials) {
+			if errors.Is(err, services.ErrInvalidCredentials) || errors.Is(err, services.ErrInvalidDeviceKey) {
 				w.WriteHeader(http.StatusBadRequest)
 				return
 			}
+			if writeTooManyAttempts(w, err) {
+				return
+			}
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
+		if status == models.DeviceStatusPending {
+			w.WriteHeader(http.StatusAccepted)
+		}
 		w.Write([]byte(deviceUUID.String()))
 	}
 }
 
 type Loginer interface {
-	Login(ctx context.Context, username, password string, deviceUUID uuid.UUID) (token string, err error)
+	Login(ctx context.Context, username, password string, deviceUUID uuid.UUID, ip string) (token string, refreshToken string, err error)
 }
 
+// RefreshTokenHeader — заголовок ответа, в котором возвращается refresh-токен.
+const RefreshTokenHeader = "X-Refresh-Token"
+
 // LoginRequest представляет JSON тело запроса на логин.
 // swagger:model LoginRequest
 type LoginRequest struct {
@@ -154,13 +181,16 @@ type LoginRequest struct {
 
 // LoginHandler
 // @Summary Вход пользователя
-// @Description Проверяет username, password и deviceUUID, возвращает JWT в заголовке Authorization
+// @Description Проверяет username, password и deviceUUID, возвращает короткоживущий JWT в заголовке Authorization
+// @Description и одноразовый refresh-токен в заголовке X-Refresh-Token
 // @Tags Auth
 // @Accept json
 // @Produce plain
 // @Param request body LoginRequest true "Данные для входа"
-// @Success 200 "JWT токен успешно сгенерирован и возвращен в заголовке Authorization"
+// @Success 200 "JWT токен возвращен в заголовке Authorization, refresh-токен — в заголовке X-Refresh-Token"
 // @Failure 400 "Неверные учетные данные или некорректные данные запроса"
+// @Failure 403 "Устройство ожидает подтверждения"
+// @Failure 429 "Слишком много неудачных попыток входа; время ожидания в секундах — в заголовке Retry-After"
 // @Failure 500 "Внутренняя ошибка сервера"
 // @Router /auth/login [post]
 func LoginHandler(svc Loginer) http.HandlerFunc {
@@ -182,17 +212,175 @@ func LoginHandler(svc Loginer) http.HandlerFunc {
 			return
 		}
 
-		token, err := svc.Login(r.Context(), req.Username, req.Password, deviceUUID)
+		token, refreshToken, err := svc.Login(r.Context(), req.Username, req.Password, deviceUUID, clientIP(r))
 		if err != nil {
 			if errors.Is(err, services.ErrInvalidCredentials) {
 				w.WriteHeader(http.StatusBadRequest)
 				return
 			}
+			if errors.Is(err, services.ErrDevicePendingApproval) {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			if writeTooManyAttempts(w, err) {
+				return
+			}
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Authorization", "Bearer "+token)
+		w.Header().Set(RefreshTokenHeader, refreshToken)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// clientIP возвращает IP-адрес клиента из r.RemoteAddr. За обратным прокси адрес из заголовков
+// X-Forwarded-For / X-Real-IP подставляется в RemoteAddr middleware, если сервер запущен с доверием к ним.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// writeTooManyAttempts отвечает 429 с заголовком Retry-After (в целых секундах, с округлением вверх),
+// если err — ограничение попыток входа. Возвращает true, если ответ записан.
+func writeTooManyAttempts(w http.ResponseWriter, err error) bool {
+	var tooMany *services.TooManyAttemptsError
+	if !errors.As(err, &tooMany) {
+		return false
+	}
+
+	seconds := int64((tooMany.RetryAfter + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	w.Header().Set("Retry-After", strconv.FormatInt(seconds, 10))
+	w.WriteHeader(http.StatusTooManyRequests)
+	return true
+}
+
+type Refresher interface {
+	// Refresh обменивает refresh-токен на новую пару токенов
+	Refresh(ctx context.Context, refreshToken string) (token string, newRefreshToken string, err error)
+}
+
+// RefreshRequest представляет JSON тело запроса на обновление токенов.
+// swagger:model RefreshRequest
+type RefreshRequest struct {
+	// Refresh-токен, полученный при входе или предыдущем обновлении
+	// required: true
+	// example: 3q2-7wEjRk3x0y9JbYlQm3bTkpZL4m2Zl8c1Y0n8d5A
+	RefreshToken string `json:"refresh_token"`
+}
+
+// RefreshHandler
+// @Summary Обновление токенов
+// @Description Обменивает одноразовый refresh-токен на новый JWT (заголовок Authorization)
+// @Description и новый refresh-токен (заголовок X-Refresh-Token). Повторное использование
+// @Description уже обменянного refresh-токена отзывает все сессии устройства.
+// @Tags Auth
+// @Accept json
+// @Produce plain
+// @Param request body RefreshRequest true "Refresh-токен"
+// @Success 200 "Новые токены возвращены в заголовках Authorization и X-Refresh-Token"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Refresh-токен недействителен, истёк, отозван или использован повторно"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /auth/refresh [post]
+func RefreshHandler(svc Refresher) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		if req.RefreshToken == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, refreshToken, err := svc.Refresh(r.Context(), req.RefreshToken)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Authorization", "Bearer "+token)
+		w.Header().Set(RefreshTokenHeader, refreshToken)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+type SessionRevoker interface {
+	// Logout отзывает текущий токен, его сессию и, в зависимости от scope, все сессии устройства или пользователя
+	Logout(
+		ctx context.Context,
+		tokenID string,
+		sessionUUID uuid.UUID,
+		userUUID uuid.UUID,
+		deviceUUID uuid.UUID,
+		expiresAt time.Time,
+		scope string,
+	) error
+}
+
+type ClaimsParser interface {
+	// GetFromRequest получает токен из HTTP-запроса
+	GetFromRequest(r *http.Request) (tokenString string, err error)
+	// ParseClaims парсит токен и возвращает его данные
+	ParseClaims(tokenString string) (*jwt.Claims, error)
+}
+
+// LogoutHandler
+// @Summary Выход из аккаунта
+// @Description Отзывает текущий JWT и refresh-токены его сессии. С параметром scope=device отзываются все сессии текущего устройства,
+// @Description с scope=user — все сессии пользователя на всех устройствах.
+// @Tags Auth
+// @Accept plain
+// @Produce plain
+// @Param scope query string false "Область выхода: token (по умолчанию), device или user"
+// @Success 200 "Выход выполнен, токен отозван"
+// @Failure 400 "Некорректная область выхода"
+// @Failure 401 "Неавторизован"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /auth/logout [post]
+func LogoutHandler(svc SessionRevoker, parser ClaimsParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		claims, err := parser.ParseClaims(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		scope := r.URL.Query().Get("scope")
+		if scope == "" {
+			scope = models.LogoutScopeToken
+		}
+
+		err = svc.Logout(r.Context(), claims.TokenID, claims.SessionUUID, claims.UserUUID, claims.DeviceUUID, claims.ExpiresAt, scope)
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidLogoutScope) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
 	}
 }
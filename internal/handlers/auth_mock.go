@@ -6,10 +6,13 @@ package handlers
 
 import (
 	context "context"
+	http "net/http"
 	reflect "reflect"
+	time "time"
 
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
+	jwt "github.com/sbilibin2017/bil-message/internal/jwt"
 )
 
 // MockRegisterer is a mock of Registerer interface.
@@ -74,18 +77,19 @@ func (m *MockDeviceAdder) EXPECT() *MockDeviceAdderMockRecorder {
 }
 
 // AddDevice mocks base method.
-func (m *MockDeviceAdder) AddDevice(ctx context.Context, username, password, publicKey string) (uuid.UUID, error) {
+func (m *MockDeviceAdder) AddDevice(ctx context.Context, username, password, publicKey, signingKey, ip string) (uuid.UUID, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddDevice", ctx, username, password, publicKey)
+	ret := m.ctrl.Call(m, "AddDevice", ctx, username, password, publicKey, signingKey, ip)
 	ret0, _ := ret[0].(uuid.UUID)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
 }
 
 // AddDevice indicates an expected call of AddDevice.
-func (mr *MockDeviceAdderMockRecorder) AddDevice(ctx, username, password, publicKey interface{}) *gomock.Call {
+func (mr *MockDeviceAdderMockRecorder) AddDevice(ctx, username, password, publicKey, signingKey, ip interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDevice", reflect.TypeOf((*MockDeviceAdder)(nil).AddDevice), ctx, username, password, publicKey)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddDevice", reflect.TypeOf((*MockDeviceAdder)(nil).AddDevice), ctx, username, password, publicKey, signingKey, ip)
 }
 
 // MockLoginer is a mock of Loginer interface.
@@ -112,16 +116,146 @@ func (m *MockLoginer) EXPECT() *MockLoginerMockRecorder {
 }
 
 // Login mocks base method.
-func (m *MockLoginer) Login(ctx context.Context, username, password string, deviceUUID uuid.UUID) (string, error) {
+func (m *MockLoginer) Login(ctx context.Context, username, password string, deviceUUID uuid.UUID, ip string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Login", ctx, username, password, deviceUUID, ip)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Login indicates an expected call of Login.
+func (mr *MockLoginerMockRecorder) Login(ctx, username, password, deviceUUID, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockLoginer)(nil).Login), ctx, username, password, deviceUUID, ip)
+}
+
+// MockRefresher is a mock of Refresher interface.
+type MockRefresher struct {
+	ctrl     *gomock.Controller
+	recorder *MockRefresherMockRecorder
+}
+
+// MockRefresherMockRecorder is the mock recorder for MockRefresher.
+type MockRefresherMockRecorder struct {
+	mock *MockRefresher
+}
+
+// NewMockRefresher creates a new mock instance.
+func NewMockRefresher(ctrl *gomock.Controller) *MockRefresher {
+	mock := &MockRefresher{ctrl: ctrl}
+	mock.recorder = &MockRefresherMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRefresher) EXPECT() *MockRefresherMockRecorder {
+	return m.recorder
+}
+
+// Refresh mocks base method.
+func (m *MockRefresher) Refresh(ctx context.Context, refreshToken string) (string, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Refresh", ctx, refreshToken)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Refresh indicates an expected call of Refresh.
+func (mr *MockRefresherMockRecorder) Refresh(ctx, refreshToken interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Refresh", reflect.TypeOf((*MockRefresher)(nil).Refresh), ctx, refreshToken)
+}
+
+// MockSessionRevoker is a mock of SessionRevoker interface.
+type MockSessionRevoker struct {
+	ctrl     *gomock.Controller
+	recorder *MockSessionRevokerMockRecorder
+}
+
+// MockSessionRevokerMockRecorder is the mock recorder for MockSessionRevoker.
+type MockSessionRevokerMockRecorder struct {
+	mock *MockSessionRevoker
+}
+
+// NewMockSessionRevoker creates a new mock instance.
+func NewMockSessionRevoker(ctrl *gomock.Controller) *MockSessionRevoker {
+	mock := &MockSessionRevoker{ctrl: ctrl}
+	mock.recorder = &MockSessionRevokerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockSessionRevoker) EXPECT() *MockSessionRevokerMockRecorder {
+	return m.recorder
+}
+
+// Logout mocks base method.
+func (m *MockSessionRevoker) Logout(ctx context.Context, tokenID string, sessionUUID, userUUID, deviceUUID uuid.UUID, expiresAt time.Time, scope string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Logout", ctx, tokenID, sessionUUID, userUUID, deviceUUID, expiresAt, scope)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Logout indicates an expected call of Logout.
+func (mr *MockSessionRevokerMockRecorder) Logout(ctx, tokenID, sessionUUID, userUUID, deviceUUID, expiresAt, scope interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Logout", reflect.TypeOf((*MockSessionRevoker)(nil).Logout), ctx, tokenID, sessionUUID, userUUID, deviceUUID, expiresAt, scope)
+}
+
+// MockClaimsParser is a mock of ClaimsParser interface.
+type MockClaimsParser struct {
+	ctrl     *gomock.Controller
+	recorder *MockClaimsParserMockRecorder
+}
+
+// MockClaimsParserMockRecorder is the mock recorder for MockClaimsParser.
+type MockClaimsParserMockRecorder struct {
+	mock *MockClaimsParser
+}
+
+// NewMockClaimsParser creates a new mock instance.
+func NewMockClaimsParser(ctrl *gomock.Controller) *MockClaimsParser {
+	mock := &MockClaimsParser{ctrl: ctrl}
+	mock.recorder = &MockClaimsParserMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockClaimsParser) EXPECT() *MockClaimsParserMockRecorder {
+	return m.recorder
+}
+
+// GetFromRequest mocks base method.
+func (m *MockClaimsParser) GetFromRequest(r *http.Request) (string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Login", ctx, username, password, deviceUUID)
+	ret := m.ctrl.Call(m, "GetFromRequest", r)
 	ret0, _ := ret[0].(string)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// Login indicates an expected call of Login.
-func (mr *MockLoginerMockRecorder) Login(ctx, username, password, deviceUUID interface{}) *gomock.Call {
+// GetFromRequest indicates an expected call of GetFromRequest.
+func (mr *MockClaimsParserMockRecorder) GetFromRequest(r interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFromRequest", reflect.TypeOf((*MockClaimsParser)(nil).GetFromRequest), r)
+}
+
+// ParseClaims mocks base method.
+func (m *MockClaimsParser) ParseClaims(tokenString string) (*jwt.Claims, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ParseClaims", tokenString)
+	ret0, _ := ret[0].(*jwt.Claims)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ParseClaims indicates an expected call of ParseClaims.
+func (mr *MockClaimsParserMockRecorder) ParseClaims(tokenString interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Login", reflect.TypeOf((*MockLoginer)(nil).Login), ctx, username, password, deviceUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ParseClaims", reflect.TypeOf((*MockClaimsParser)(nil).ParseClaims), tokenString)
 }
@@ -4,12 +4,16 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/jwt"
+	"github.com/sbilibin2017/bil-message/internal/models"
 	"github.com/sbilibin2017/bil-message/internal/services"
 	"github.com/stretchr/testify/require"
 )
@@ -72,6 +76,34 @@ func TestRegisterHandler(t *testing.T) {
 			wantStatus: http.StatusConflict,
 			wantBody:   "",
 		},
+		{
+			name: "weak password",
+			reqBody: RegisterRequest{
+				Username: "johndoe",
+				Password: "secret",
+			},
+			mockSetup: func() {
+				mockSvc.EXPECT().
+					Register(gomock.Any(), "johndoe", "secret").
+					Return(uuid.Nil, fmt.Errorf("%w: password must be at least 8 characters long", services.ErrWeakPassword))
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "password does not meet the password policy: password must be at least 8 characters long\n",
+		},
+		{
+			name: "invalid username",
+			reqBody: RegisterRequest{
+				Username: "johndoe",
+				Password: "secret",
+			},
+			mockSetup: func() {
+				mockSvc.EXPECT().
+					Register(gomock.Any(), "johndoe", "secret").
+					Return(uuid.Nil, services.ErrInvalidUsername)
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   "invalid username\n",
+		},
 		{
 			name: "service error",
 			reqBody: RegisterRequest{
@@ -134,12 +166,43 @@ func TestAddDeviceHandler(t *testing.T) {
 			},
 			mockSetup: func() {
 				mockSvc.EXPECT().
-					AddDevice(gomock.Any(), "johndoe", "secret", "pubkey").
-					Return(deviceUUIDExample, nil)
+					AddDevice(gomock.Any(), "johndoe", "secret", "pubkey", "", "192.0.2.1").
+					Return(deviceUUIDExample, models.DeviceStatusActive, nil)
 			},
 			wantStatus: http.StatusOK,
 			wantBody:   deviceUUIDExample.String(),
 		},
+		{
+			name: "device pending approval",
+			reqBody: DeviceRequest{
+				Username:   "johndoe",
+				Password:   "secret",
+				PublicKey:  "pubkey",
+				SigningKey: "signingkey",
+			},
+			mockSetup: func() {
+				mockSvc.EXPECT().
+					AddDevice(gomock.Any(), "johndoe", "secret", "pubkey", "signingkey", "192.0.2.1").
+					Return(deviceUUIDExample, models.DeviceStatusPending, nil)
+			},
+			wantStatus: http.StatusAccepted,
+			wantBody:   deviceUUIDExample.String(),
+		},
+		{
+			name: "invalid signing key",
+			reqBody: DeviceRequest{
+				Username:   "johndoe",
+				Password:   "secret",
+				PublicKey:  "pubkey",
+				SigningKey: "bad",
+			},
+			mockSetup: func() {
+				mockSvc.EXPECT().
+					AddDevice(gomock.Any(), "johndoe", "secret", "pubkey", "bad", "192.0.2.1").
+					Return(uuid.Nil, "", services.ErrInvalidDeviceKey)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
 		{
 			name:       "invalid JSON",
 			reqBody:    "{invalid-json",
@@ -164,11 +227,25 @@ func TestAddDeviceHandler(t *testing.T) {
 			},
 			mockSetup: func() {
 				mockSvc.EXPECT().
-					AddDevice(gomock.Any(), "johndoe", "secret", "pubkey").
-					Return(uuid.Nil, services.ErrInvalidCredentials)
+					AddDevice(gomock.Any(), "johndoe", "secret", "pubkey", "", "192.0.2.1").
+					Return(uuid.Nil, "", services.ErrInvalidCredentials)
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "too many attempts",
+			reqBody: DeviceRequest{
+				Username:  "johndoe",
+				Password:  "secret",
+				PublicKey: "pubkey",
+			},
+			mockSetup: func() {
+				mockSvc.EXPECT().
+					AddDevice(gomock.Any(), "johndoe", "secret", "pubkey", "", "192.0.2.1").
+					Return(uuid.Nil, "", &services.TooManyAttemptsError{RetryAfter: time.Minute})
+			},
+			wantStatus: http.StatusTooManyRequests,
+		},
 		{
 			name: "service error",
 			reqBody: DeviceRequest{
@@ -178,8 +255,8 @@ func TestAddDeviceHandler(t *testing.T) {
 			},
 			mockSetup: func() {
 				mockSvc.EXPECT().
-					AddDevice(gomock.Any(), "johndoe", "secret", "pubkey").
-					Return(uuid.Nil, errors.New("db error"))
+					AddDevice(gomock.Any(), "johndoe", "secret", "pubkey", "", "192.0.2.1").
+					Return(uuid.Nil, "", errors.New("db error"))
 			},
 			wantStatus: http.StatusInternalServerError,
 		},
@@ -205,7 +282,7 @@ func TestAddDeviceHandler(t *testing.T) {
 
 			resp := w.Result()
 			require.Equal(t, tt.wantStatus, resp.StatusCode)
-			if tt.wantStatus == http.StatusOK {
+			if tt.wantBody != "" {
 				require.Equal(t, tt.wantBody, w.Body.String())
 			}
 		})
@@ -225,6 +302,7 @@ func TestLoginHandler(t *testing.T) {
 		mockSetup  func()
 		wantStatus int
 		wantAuth   string
+		wantRetry  string
 	}{
 		{
 			name: "successful login",
@@ -235,8 +313,8 @@ func TestLoginHandler(t *testing.T) {
 			},
 			mockSetup: func() {
 				mockSvc.EXPECT().
-					Login(gomock.Any(), "johndoe", "secret", validUUID).
-					Return("jwt-token-123", nil)
+					Login(gomock.Any(), "johndoe", "secret", validUUID, "192.0.2.1").
+					Return("jwt-token-123", "refresh-123", nil)
 			},
 			wantStatus: http.StatusOK,
 			wantAuth:   "Bearer jwt-token-123",
@@ -276,11 +354,40 @@ func TestLoginHandler(t *testing.T) {
 			},
 			mockSetup: func() {
 				mockSvc.EXPECT().
-					Login(gomock.Any(), "johndoe", "secret", validUUID).
-					Return("", services.ErrInvalidCredentials)
+					Login(gomock.Any(), "johndoe", "secret", validUUID, "192.0.2.1").
+					Return("", "", services.ErrInvalidCredentials)
 			},
 			wantStatus: http.StatusBadRequest,
 		},
+		{
+			name: "device pending approval",
+			reqBody: LoginRequest{
+				Username:   "johndoe",
+				Password:   "secret",
+				DeviceUUID: validUUID.String(),
+			},
+			mockSetup: func() {
+				mockSvc.EXPECT().
+					Login(gomock.Any(), "johndoe", "secret", validUUID, "192.0.2.1").
+					Return("", "", services.ErrDevicePendingApproval)
+			},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name: "too many attempts",
+			reqBody: LoginRequest{
+				Username:   "johndoe",
+				Password:   "secret",
+				DeviceUUID: validUUID.String(),
+			},
+			mockSetup: func() {
+				mockSvc.EXPECT().
+					Login(gomock.Any(), "johndoe", "secret", validUUID, "192.0.2.1").
+					Return("", "", &services.TooManyAttemptsError{RetryAfter: 1500 * time.Millisecond})
+			},
+			wantStatus: http.StatusTooManyRequests,
+			wantRetry:  "2",
+		},
 		{
 			name: "service error",
 			reqBody: LoginRequest{
@@ -290,8 +397,8 @@ func TestLoginHandler(t *testing.T) {
 			},
 			mockSetup: func() {
 				mockSvc.EXPECT().
-					Login(gomock.Any(), "johndoe", "secret", validUUID).
-					Return("", errors.New("service failure"))
+					Login(gomock.Any(), "johndoe", "secret", validUUID, "192.0.2.1").
+					Return("", "", errors.New("service failure"))
 			},
 			wantStatus: http.StatusInternalServerError,
 		},
@@ -318,7 +425,195 @@ func TestLoginHandler(t *testing.T) {
 			require.Equal(t, tt.wantStatus, resp.StatusCode)
 			if tt.wantStatus == http.StatusOK {
 				require.Equal(t, tt.wantAuth, w.Header().Get("Authorization"))
+				require.Equal(t, "refresh-123", w.Header().Get(RefreshTokenHeader))
+			}
+			require.Equal(t, tt.wantRetry, w.Header().Get("Retry-After"))
+		})
+	}
+}
+
+func TestRefreshHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockRefresher(ctrl)
+
+	tests := []struct {
+		name       string
+		reqBody    interface{}
+		mockSetup  func()
+		wantStatus int
+	}{
+		{
+			name:    "successful refresh",
+			reqBody: RefreshRequest{RefreshToken: "refresh-old"},
+			mockSetup: func() {
+				mockSvc.EXPECT().Refresh(gomock.Any(), "refresh-old").Return("jwt-new", "refresh-new", nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "invalid JSON",
+			reqBody:    "{invalid-json",
+			mockSetup:  func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty refresh token",
+			reqBody:    RefreshRequest{},
+			mockSetup:  func() {},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:    "invalid refresh token",
+			reqBody: RefreshRequest{RefreshToken: "refresh-old"},
+			mockSetup: func() {
+				mockSvc.EXPECT().Refresh(gomock.Any(), "refresh-old").Return("", "", services.ErrInvalidRefreshToken)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "reused refresh token",
+			reqBody: RefreshRequest{RefreshToken: "refresh-old"},
+			mockSetup: func() {
+				mockSvc.EXPECT().Refresh(gomock.Any(), "refresh-old").Return("", "", services.ErrRefreshTokenReused)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:    "service error",
+			reqBody: RefreshRequest{RefreshToken: "refresh-old"},
+			mockSetup: func() {
+				mockSvc.EXPECT().Refresh(gomock.Any(), "refresh-old").Return("", "", errors.New("db error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			var bodyBytes []byte
+			switch v := tt.reqBody.(type) {
+			case string:
+				bodyBytes = []byte(v)
+			default:
+				bodyBytes, _ = json.Marshal(v)
+			}
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/refresh", bytes.NewReader(bodyBytes))
+			w := httptest.NewRecorder()
+
+			RefreshHandler(mockSvc).ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Result().StatusCode)
+			if tt.wantStatus == http.StatusOK {
+				require.Equal(t, "Bearer jwt-new", w.Header().Get("Authorization"))
+				require.Equal(t, "refresh-new", w.Header().Get(RefreshTokenHeader))
 			}
 		})
 	}
 }
+
+func TestLogoutHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockSessionRevoker(ctrl)
+	mockParser := NewMockClaimsParser(ctrl)
+
+	claims := &jwt.Claims{
+		TokenID:     "jti",
+		SessionUUID: uuid.New(),
+		UserUUID:    uuid.New(),
+		DeviceUUID:  uuid.New(),
+		ExpiresAt:   time.Now().Add(time.Hour),
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		mockSetup  func()
+		wantStatus int
+	}{
+		{
+			name:  "default scope",
+			query: "",
+			mockSetup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().ParseClaims("token").Return(claims, nil)
+				mockSvc.EXPECT().
+					Logout(gomock.Any(), "jti", claims.SessionUUID, claims.UserUUID, claims.DeviceUUID, claims.ExpiresAt, models.LogoutScopeToken).
+					Return(nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "user scope",
+			query: "?scope=user",
+			mockSetup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().ParseClaims("token").Return(claims, nil)
+				mockSvc.EXPECT().
+					Logout(gomock.Any(), "jti", claims.SessionUUID, claims.UserUUID, claims.DeviceUUID, claims.ExpiresAt, models.LogoutScopeUser).
+					Return(nil)
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:  "missing token",
+			query: "",
+			mockSetup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "revoked token",
+			query: "",
+			mockSetup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().ParseClaims("token").Return(nil, jwt.ErrTokenRevoked)
+			},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:  "invalid scope",
+			query: "?scope=everything",
+			mockSetup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().ParseClaims("token").Return(claims, nil)
+				mockSvc.EXPECT().
+					Logout(gomock.Any(), "jti", claims.SessionUUID, claims.UserUUID, claims.DeviceUUID, claims.ExpiresAt, "everything").
+					Return(services.ErrInvalidLogoutScope)
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:  "service error",
+			query: "",
+			mockSetup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().ParseClaims("token").Return(claims, nil)
+				mockSvc.EXPECT().
+					Logout(gomock.Any(), "jti", claims.SessionUUID, claims.UserUUID, claims.DeviceUUID, claims.ExpiresAt, models.LogoutScopeToken).
+					Return(errors.New("db error"))
+			},
+			wantStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/logout"+tt.query, nil)
+			w := httptest.NewRecorder()
+
+			LogoutHandler(mockSvc, mockParser).ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Result().StatusCode)
+		})
+	}
+}
@@ -2,37 +2,72 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
-	"sync"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/sbilibin2017/bil-message/internal/chat"
 	"github.com/sbilibin2017/bil-message/internal/jwt"
+	"github.com/sbilibin2017/bil-message/internal/models"
 	"github.com/sbilibin2017/bil-message/internal/services"
 )
 
 // Интерфейсы для работы с комнатами и участниками
 type RoomCreator interface {
-	// CreateRoom создаёт новую комнату для пользователя и возвращает UUID комнаты
-	CreateRoom(ctx context.Context, userUUID uuid.UUID) (roomUUID uuid.UUID, err error)
+	// CreateRoom создаёт новую групповую комнату для пользователя и возвращает UUID комнаты
+	CreateRoom(ctx context.Context, userUUID uuid.UUID, name string, topic string) (roomUUID uuid.UUID, err error)
+	// CreateDirectRoom возвращает личную комнату пользователя и собеседника, создавая её при первом обращении
+	CreateDirectRoom(ctx context.Context, userUUID uuid.UUID, peerUUID uuid.UUID) (uuid.UUID, error)
 }
 
-type RoomRemover interface {
-	// RemoveRoom удаляет комнату по UUID
-	RemoveRoom(ctx context.Context, roomUUID uuid.UUID) error
+type RoomUpdater interface {
+	// UpdateRoom изменяет название и тему комнаты от имени пользователя actorUUID; nil оставляет значение без изменений
+	UpdateRoom(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, name *string, topic *string) error
 }
 
-type RoomMemberAdder interface {
-	// AddRoomMember добавляет пользователя в комнату
-	AddRoomMember(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error
+type RoomRemover interface {
+	// RemoveRoom удаляет комнату по UUID от имени пользователя actorUUID
+	RemoveRoom(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID) error
 }
 
 type RoomMemberRemover interface {
-	// RemoveRoomMember удаляет пользователя из комнаты
-	RemoveRoomMember(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error
+	// RemoveRoomMember удаляет пользователя из комнаты от имени пользователя actorUUID
+	RemoveRoomMember(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, userUUID uuid.UUID) error
+}
+
+type RoomLeaver interface {
+	// LeaveRoom удаляет пользователя из комнаты по его собственному запросу
+	LeaveRoom(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error
+}
+
+type RoomOwnershipTransferrer interface {
+	// TransferOwnership передаёт владение комнатой другому участнику
+	TransferOwnership(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, newOwnerUUID uuid.UUID) error
+}
+
+type RoomMemberRoleSetter interface {
+	// SetRoomMemberRole назначает участнику комнаты роль
+	SetRoomMemberRole(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, userUUID uuid.UUID, role string) error
+}
+
+type RoomLister interface {
+	// ListRooms возвращает комнаты, в которых состоит пользователь
+	ListRooms(ctx context.Context, userUUID uuid.UUID) ([]models.RoomSummaryDB, error)
+}
+
+type RoomMemberLister interface {
+	// ListRoomMembers возвращает участников комнаты с именами пользователей
+	ListRoomMembers(ctx context.Context, userUUID uuid.UUID, roomUUID uuid.UUID) ([]models.RoomMemberUserDB, error)
+}
+
+type RoomMemberChecker interface {
+	// CheckRoomMember проверяет, что комната существует и пользователь является её участником
+	CheckRoomMember(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error
 }
 
 type TokenParser interface {
@@ -42,19 +77,59 @@ type TokenParser interface {
 	Parse(tokenString string) (userUUID uuid.UUID, deviceUUID uuid.UUID, err error)
 }
 
+// CreateChatRequest представляет JSON тело запроса на создание комнаты.
+// swagger:model CreateChatRequest
+type CreateChatRequest struct {
+	// Тип комнаты: group (по умолчанию) или direct
+	// example: group
+	Kind string `json:"kind,omitempty"`
+	// Название групповой комнаты
+	// example: Команда
+	Name string `json:"name,omitempty"`
+	// Тема групповой комнаты
+	// example: Планы на релиз
+	Topic string `json:"topic,omitempty"`
+	// UUID собеседника; обязателен для личной комнаты
+	MemberUUID string `json:"member_uuid,omitempty"`
+}
+
 // CreateChatHandler создаёт новую комнату для текущего пользователя
 // @Summary Создание новой комнаты
-// @Description Создаёт новую комнату для текущего пользователя
+// @Description Создаёт групповую комнату с необязательными названием и темой; пустое тело создаёт комнату без названия.
+// @Description Для kind=direct возвращает личную комнату с пользователем member_uuid: для каждой пары пользователей
+// @Description существует одна личная комната, и повторный запрос любого из собеседников возвращает её же.
 // @Tags Chat
-// @Accept plain
+// @Accept json
 // @Produce plain
+// @Param request body CreateChatRequest false "Параметры комнаты"
 // @Success 200 {string} string "UUID комнаты"
 // @Failure 400 "Некорректные данные запроса"
 // @Failure 401 "Неавторизован"
+// @Failure 404 "Собеседник не найден"
 // @Failure 500 "Внутренняя ошибка сервера"
 // @Router /chat [post]
 func CreateChatHandler(svc RoomCreator, parser TokenParser) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		var req CreateChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var peerUUID uuid.UUID
+		switch req.Kind {
+		case "", models.RoomKindGroup:
+		case models.RoomKindDirect:
+			var err error
+			if peerUUID, err = uuid.Parse(req.MemberUUID); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
 		token, err := parser.GetFromRequest(r)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
@@ -67,9 +142,21 @@ func CreateChatHandler(svc RoomCreator, parser TokenParser) http.HandlerFunc {
 			return
 		}
 
-		roomUUID, err := svc.CreateRoom(r.Context(), userUUID)
+		var roomUUID uuid.UUID
+		if req.Kind == models.RoomKindDirect {
+			roomUUID, err = svc.CreateDirectRoom(r.Context(), userUUID, peerUUID)
+		} else {
+			roomUUID, err = svc.CreateRoom(r.Context(), userUUID, req.Name, req.Topic)
+		}
 		if err != nil {
-			w.WriteHeader(http.StatusInternalServerError)
+			switch {
+			case errors.Is(err, services.ErrInvalidRoomMetadata), errors.Is(err, services.ErrInvalidDirectRoom):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, services.ErrUserNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
 			return
 		}
 
@@ -77,9 +164,79 @@ func CreateChatHandler(svc RoomCreator, parser TokenParser) http.HandlerFunc {
 	}
 }
 
+// UpdateChatRequest представляет JSON тело запроса на изменение комнаты.
+// swagger:model UpdateChatRequest
+type UpdateChatRequest struct {
+	// Новое название комнаты; если поле не передано, название не меняется
+	// example: Команда
+	Name *string `json:"name,omitempty"`
+	// Новая тема комнаты; если поле не передано, тема не меняется
+	// example: Планы на релиз
+	Topic *string `json:"topic,omitempty"`
+}
+
+// UpdateChatHandler изменяет название и тему комнаты
+// @Summary Изменение названия и темы комнаты
+// @Description Изменяет название и тему комнаты. В групповой комнате доступно владельцу и администраторам,
+// @Description в личной — обоим собеседникам. Подключённые участники получают событие room_updated.
+// @Tags Chat
+// @Accept json
+// @Produce plain
+// @Param room-uuid path string true "UUID комнаты"
+// @Param request body UpdateChatRequest true "Новые название и тема"
+// @Success 200 "Комната успешно изменена"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Недостаточно прав"
+// @Failure 404 "Комната не найдена"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid} [put]
+func UpdateChatHandler(svc RoomUpdater, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomUUID, err := uuid.Parse(chi.URLParam(r, "room-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req UpdateChatRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.UpdateRoom(r.Context(), userUUID, roomUUID, req.Name, req.Topic); err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidRoomMetadata):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, services.ErrRoomNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
 // RemoveChatHandler удаляет комнату по UUID
 // @Summary Удаление комнаты
-// @Description Удаляет комнату по UUID
+// @Description Удаляет комнату по UUID. Доступно только владельцу комнаты.
 // @Tags Chat
 // @Accept plain
 // @Produce plain
@@ -87,6 +244,7 @@ func CreateChatHandler(svc RoomCreator, parser TokenParser) http.HandlerFunc {
 // @Success 200 "Комната успешно удалена"
 // @Failure 400 "Некорректные данные запроса"
 // @Failure 401 "Неавторизован"
+// @Failure 403 "Недостаточно прав"
 // @Failure 404 "Комната не найдена"
 // @Failure 500 "Внутренняя ошибка сервера"
 // @Router /chat/{room-uuid} [delete]
@@ -105,18 +263,21 @@ func RemoveChatHandler(svc RoomRemover, parser TokenParser) http.HandlerFunc {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
-		_, _, err = parser.Parse(token)
+		userUUID, _, err := parser.Parse(token)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		if err := svc.RemoveRoom(r.Context(), roomUUID); err != nil {
-			if errors.Is(err, services.ErrRoomNotFound) {
+		if err := svc.RemoveRoom(r.Context(), userUUID, roomUUID); err != nil {
+			switch {
+			case errors.Is(err, services.ErrRoomNotFound):
 				w.WriteHeader(http.StatusNotFound)
-				return
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
 			}
-			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
@@ -124,21 +285,24 @@ func RemoveChatHandler(svc RoomRemover, parser TokenParser) http.HandlerFunc {
 	}
 }
 
-// AddChatMemberHandler добавляет пользователя в комнату
-// @Summary Добавление пользователя в комнату
-// @Description Добавляет указанного пользователя (member-uuid) в комнату
+// RemoveChatMemberHandler удаляет пользователя из комнаты
+// @Summary Удаление пользователя из комнаты
+// @Description Удаляет указанного пользователя (member-uuid) из комнаты.
+// @Description Владелец может удалить любого участника, администратор — только обычных участников.
 // @Tags Chat
 // @Accept plain
 // @Produce plain
 // @Param room-uuid path string true "UUID комнаты"
 // @Param member-uuid path string true "UUID пользователя"
-// @Success 200 "Пользователь успешно добавлен"
+// @Success 200 "Пользователь успешно удалён"
 // @Failure 400 "Некорректные данные запроса"
 // @Failure 401 "Неавторизован"
-// @Failure 404 "Комната не найдена"
+// @Failure 403 "Недостаточно прав"
+// @Failure 404 "Комната или пользователь не найдены"
+// @Failure 409 "Владелец не может покинуть комнату без передачи владения"
 // @Failure 500 "Внутренняя ошибка сервера"
-// @Router /chat/{room-uuid}/{member-uuid} [post]
-func AddChatMemberHandler(svc RoomMemberAdder, parser TokenParser) http.HandlerFunc {
+// @Router /chat/{room-uuid}/{member-uuid} [delete]
+func RemoveChatMemberHandler(svc RoomMemberRemover, parser TokenParser) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		roomID := chi.URLParam(r, "room-uuid")
 		memberID := chi.URLParam(r, "member-uuid")
@@ -160,18 +324,23 @@ func AddChatMemberHandler(svc RoomMemberAdder, parser TokenParser) http.HandlerF
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
-		_, _, err = parser.Parse(token)
+		userUUID, _, err := parser.Parse(token)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		if err := svc.AddRoomMember(r.Context(), roomUUID, memberUUID); err != nil {
-			if errors.Is(err, services.ErrRoomNotFound) {
+		if err := svc.RemoveRoomMember(r.Context(), userUUID, roomUUID, memberUUID); err != nil {
+			switch {
+			case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrUserNotInRoom):
 				w.WriteHeader(http.StatusNotFound)
-				return
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			case errors.Is(err, services.ErrOwnerCannotLeave):
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
 			}
-			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
@@ -179,21 +348,142 @@ func AddChatMemberHandler(svc RoomMemberAdder, parser TokenParser) http.HandlerF
 	}
 }
 
-// RemoveChatMemberHandler удаляет пользователя из комнаты
-// @Summary Удаление пользователя из комнаты
-// @Description Удаляет указанного пользователя (member-uuid) из комнаты
+// LeaveChatHandler удаляет текущего пользователя из комнаты
+// @Summary Выход из комнаты
+// @Description Удаляет текущего пользователя из комнаты. Владелец должен сначала передать владение.
 // @Tags Chat
 // @Accept plain
 // @Produce plain
 // @Param room-uuid path string true "UUID комнаты"
+// @Success 200 "Пользователь успешно покинул комнату"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 404 "Комната не найдена или пользователь не состоит в ней"
+// @Failure 409 "Владелец не может покинуть комнату без передачи владения"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid}/leave [post]
+func LeaveChatHandler(svc RoomLeaver, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID := chi.URLParam(r, "room-uuid")
+
+		roomUUID, err := uuid.Parse(roomID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.LeaveRoom(r.Context(), roomUUID, userUUID); err != nil {
+			switch {
+			case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrUserNotInRoom):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, services.ErrOwnerCannotLeave):
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// TransferChatOwnershipHandler передаёт владение комнатой другому участнику
+// @Summary Передача владения комнатой
+// @Description Передаёт владение комнатой участнику member-uuid. Прежний владелец становится администратором.
+// @Tags Chat
+// @Accept plain
+// @Produce plain
+// @Param room-uuid path string true "UUID комнаты"
+// @Param member-uuid path string true "UUID нового владельца"
+// @Success 200 "Владение успешно передано"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Недостаточно прав"
+// @Failure 404 "Комната или пользователь не найдены"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid}/{member-uuid}/owner [post]
+func TransferChatOwnershipHandler(svc RoomOwnershipTransferrer, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomID := chi.URLParam(r, "room-uuid")
+		memberID := chi.URLParam(r, "member-uuid")
+
+		roomUUID, err := uuid.Parse(roomID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		memberUUID, err := uuid.Parse(memberID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.TransferOwnership(r.Context(), userUUID, roomUUID, memberUUID); err != nil {
+			switch {
+			case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrUserNotInRoom):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// SetRoleRequest представляет JSON тело запроса на изменение роли участника.
+// swagger:model SetRoleRequest
+type SetRoleRequest struct {
+	// Роль участника (admin или member)
+	// required: true
+	// example: admin
+	Role string `json:"role"`
+}
+
+// SetChatMemberRoleHandler изменяет роль участника комнаты
+// @Summary Изменение роли участника комнаты
+// @Description Назначает участнику member-uuid роль admin или member. Доступно только владельцу комнаты.
+// @Tags Chat
+// @Accept json
+// @Produce plain
+// @Param room-uuid path string true "UUID комнаты"
 // @Param member-uuid path string true "UUID пользователя"
-// @Success 200 "Пользователь успешно удалён"
+// @Param request body SetRoleRequest true "Новая роль"
+// @Success 200 "Роль успешно изменена"
 // @Failure 400 "Некорректные данные запроса"
 // @Failure 401 "Неавторизован"
+// @Failure 403 "Недостаточно прав"
 // @Failure 404 "Комната или пользователь не найдены"
 // @Failure 500 "Внутренняя ошибка сервера"
-// @Router /chat/{room-uuid}/{member-uuid} [delete]
-func RemoveChatMemberHandler(svc RoomMemberRemover, parser TokenParser) http.HandlerFunc {
+// @Router /chat/{room-uuid}/{member-uuid}/role [put]
+func SetChatMemberRoleHandler(svc RoomMemberRoleSetter, parser TokenParser) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		roomID := chi.URLParam(r, "room-uuid")
 		memberID := chi.URLParam(r, "member-uuid")
@@ -210,23 +500,34 @@ func RemoveChatMemberHandler(svc RoomMemberRemover, parser TokenParser) http.Han
 			return
 		}
 
+		var req SetRoleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Role == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
 		token, err := parser.GetFromRequest(r)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
-		_, _, err = parser.Parse(token)
+		userUUID, _, err := parser.Parse(token)
 		if err != nil {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
 
-		if err := svc.RemoveRoomMember(r.Context(), roomUUID, memberUUID); err != nil {
-			if errors.Is(err, services.ErrRoomNotFound) {
+		if err := svc.SetRoomMemberRole(r.Context(), userUUID, roomUUID, memberUUID, req.Role); err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidRole):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrUserNotInRoom):
 				w.WriteHeader(http.StatusNotFound)
-				return
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
 			}
-			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 
@@ -234,18 +535,190 @@ func RemoveChatMemberHandler(svc RoomMemberRemover, parser TokenParser) http.Han
 	}
 }
 
+// RoomResponse — комната пользователя в списке комнат.
+// swagger:model RoomResponse
+type RoomResponse struct {
+	// UUID комнаты
+	RoomUUID string `json:"room_uuid"`
+	// Название комнаты
+	Name string `json:"name"`
+	// Тема комнаты
+	Topic string `json:"topic"`
+	// Тип комнаты (group или direct)
+	Kind string `json:"kind"`
+	// Роль пользователя в комнате (owner, admin или member)
+	Role string `json:"role"`
+	// Количество участников комнаты
+	MemberCount int64 `json:"member_count"`
+	// Время создания комнаты
+	CreatedAt time.Time `json:"created_at"`
+	// Время последнего сообщения или создания комнаты, если сообщений нет
+	LastActivityAt time.Time `json:"last_activity_at"`
+}
+
+// RoomsResponse — список комнат пользователя.
+// swagger:model RoomsResponse
+type RoomsResponse struct {
+	// Комнаты, начиная с комнат с недавней активностью
+	Rooms []RoomResponse `json:"rooms"`
+}
+
+// RoomMemberResponse — участник комнаты.
+// swagger:model RoomMemberResponse
+type RoomMemberResponse struct {
+	// UUID пользователя
+	UserUUID string `json:"user_uuid"`
+	// Имя пользователя
+	Username string `json:"username"`
+	// Роль участника (owner, admin или member)
+	Role string `json:"role"`
+	// Время присоединения к комнате
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// RoomMembersResponse — список участников комнаты.
+// swagger:model RoomMembersResponse
+type RoomMembersResponse struct {
+	// Участники в порядке присоединения к комнате
+	Members []RoomMemberResponse `json:"members"`
+}
+
+// ListChatsHandler возвращает комнаты текущего пользователя
+// @Summary Список комнат пользователя
+// @Description Возвращает комнаты, в которых состоит пользователь, с его ролью, количеством участников
+// @Description и временем последней активности; комнаты с недавней активностью идут первыми.
+// @Tags Chat
+// @Accept plain
+// @Produce json
+// @Success 200 {object} RoomsResponse "Комнаты пользователя"
+// @Failure 401 "Неавторизован"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat [get]
+func ListChatsHandler(svc RoomLister, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		rooms, err := svc.ListRooms(r.Context(), userUUID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp := RoomsResponse{Rooms: make([]RoomResponse, 0, len(rooms))}
+		for _, room := range rooms {
+			resp.Rooms = append(resp.Rooms, RoomResponse{
+				RoomUUID:       room.RoomUUID.String(),
+				Name:           room.Name,
+				Topic:          room.Topic,
+				Kind:           room.Kind,
+				Role:           room.Role,
+				MemberCount:    room.MemberCount,
+				CreatedAt:      room.CreatedAt,
+				LastActivityAt: room.LastActivityAt(),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// ListChatMembersHandler возвращает участников комнаты
+// @Summary Список участников комнаты
+// @Description Возвращает участников комнаты с именами пользователей и ролями. Доступно только участникам комнаты.
+// @Tags Chat
+// @Accept plain
+// @Produce json
+// @Param room-uuid path string true "UUID комнаты"
+// @Success 200 {object} RoomMembersResponse "Участники комнаты"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Пользователь не состоит в комнате"
+// @Failure 404 "Комната не найдена"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid}/members [get]
+func ListChatMembersHandler(svc RoomMemberLister, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomUUID, err := uuid.Parse(chi.URLParam(r, "room-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		members, err := svc.ListRoomMembers(r.Context(), userUUID, roomUUID)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrUserNotInRoom):
+				w.WriteHeader(http.StatusForbidden)
+			case errors.Is(err, services.ErrRoomNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		resp := RoomMembersResponse{Members: make([]RoomMemberResponse, 0, len(members))}
+		for _, m := range members {
+			resp.Members = append(resp.Members, RoomMemberResponse{
+				UserUUID: m.UserUUID.String(),
+				Username: m.Username,
+				Role:     m.Role,
+				JoinedAt: m.JoinedAt,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
 // ChatWebSocketHandler возвращает http.HandlerFunc для WebSocket соединений.
 //
 // Подключение по WebSocket осуществляется по пути /chat/ws/{room-uuid}.
 // Пользователь должен быть авторизован и передать валидный токен в заголовке Authorization.
 //
-// После подключения клиент создается и добавляется в комнату.
-// Если комната с заданным UUID не существует, она создается автоматически.
+// Перед апгрейдом проверяется, что комната существует и пользователь является её участником.
+// После регистрации в хабе членство проверяется снова: если участника удалили, пока клиент регистрировался,
+// событие отключения могло не застать его в комнате, поэтому соединение закрывается с кодом 1008 (policy violation).
+//
+// После подключения клиент создается и добавляется в комнату под UUID устройства из токена:
+// пользователь может быть подключён с нескольких устройств, каждое получает все сообщения комнаты.
+// Клиент регистрируется в хабе hub: если комната с заданным UUID ещё не открыта, она создается автоматически,
+// а после отключения последнего клиента удаляется из хаба.
+// Перед добавлением в комнату клиент получает последние сообщения из истории.
+//
+// Кадры передаются в виде JSON-конвертов chat.Envelope: сервер проверяет конверты клиента,
+// проставляет UUID сообщения, отправителя, его устройство и время, а на некорректные кадры
+// отвечает конвертом типа error.
 //
 // Чтение и запись сообщений происходят асинхронно через ReadPump и WritePump.
 //
 // @Summary WebSocket соединение для чата
-// @Description Создает WebSocket соединение для конкретной комнаты. Сообщения рассылаются всем участникам, кроме отправителя.
+// @Description Создает WebSocket соединение для конкретной комнаты. Сообщения рассылаются на все подключённые устройства участников, включая другие устройства отправителя.
+// @Description Клиент отправляет конверты {"v":1,"type":"message","ciphertext":"...","key_epoch":N};
+// @Description сервер дополняет их полями message_uuid, room_uuid, sender_uuid, sender_device и sent_at.
+// @Description Кадры неизвестного типа не рассылаются: отправитель получает конверт {"type":"error","code":"unknown_type"}.
 // @Tags Chat
 // @Accept plain
 // @Produce json
@@ -253,17 +726,17 @@ func RemoveChatMemberHandler(svc RoomMemberRemover, parser TokenParser) http.Han
 // @Success 101 "WebSocket соединение установлено"
 // @Failure 400 "Некорректный UUID комнаты"
 // @Failure 401 "Неавторизован"
+// @Failure 403 "Пользователь не является участником комнаты"
+// @Failure 404 "Комната не найдена"
 // @Failure 500 "Ошибка сервера при апгрейде соединения"
 // @Router /chat/{room-uuid}/ws [get]
 func ChatWebSocketHandler(
-	newClient func(conn *websocket.Conn, userUUID, roomUUID uuid.UUID) *chat.ChatClient,
-	newRoom func(roomUUID uuid.UUID) *chat.ChatRoom,
+	newClient func(conn *websocket.Conn, userUUID, deviceUUID, roomUUID uuid.UUID) *chat.ChatClient,
+	hub *chat.Hub,
+	checker RoomMemberChecker,
 	parser *jwt.JWT,
 ) http.HandlerFunc {
 
-	rooms := make(map[uuid.UUID]*chat.ChatRoom)
-	var mu sync.Mutex
-
 	upgrader := websocket.Upgrader{
 		CheckOrigin: func(r *http.Request) bool { return true },
 	}
@@ -285,12 +758,25 @@ func ChatWebSocketHandler(
 		}
 
 		// Парсим токен
-		userUUID, _, err := parser.Parse(token)
+		userUUID, deviceUUID, err := parser.Parse(token)
 		if err != nil {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 
+		// Проверяем, что пользователь является участником комнаты
+		if err := checker.CheckRoomMember(r.Context(), roomUUID, userUUID); err != nil {
+			switch {
+			case errors.Is(err, services.ErrRoomNotFound):
+				http.Error(w, "room not found", http.StatusNotFound)
+			case errors.Is(err, services.ErrUserNotInRoom):
+				http.Error(w, "forbidden", http.StatusForbidden)
+			default:
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+			}
+			return
+		}
+
 		// Апгрейдим соединение в WebSocket
 		conn, err := upgrader.Upgrade(w, r, nil)
 		if err != nil {
@@ -298,18 +784,26 @@ func ChatWebSocketHandler(
 			return
 		}
 
-		client := newClient(conn, userUUID, roomUUID)
+		client := newClient(conn, userUUID, deviceUUID, roomUUID)
 
-		// Получаем или создаём комнату
-		mu.Lock()
-		room, ok := rooms[roomUUID]
-		if !ok {
-			room = newRoom(roomUUID)
-			rooms[roomUUID] = room
+		// Регистрируем клиента в комнате; членство перепроверяется после добавления клиента, но до загрузки истории,
+		// чтобы удалённый между проверками пользователь не получил ни историю, ни новые сообщения
+		admit := func(ctx context.Context) error {
+			return checker.CheckRoomMember(ctx, roomUUID, userUUID)
+		}
+		room, err := hub.Register(r.Context(), client, admit)
+		if err != nil {
+			switch {
+			case errors.Is(err, chat.ErrHubClosed):
+				// сервер останавливается — клиенту следует переподключиться позже
+				client.CloseWithCode(websocket.CloseGoingAway, "server shutting down")
+			case errors.Is(err, services.ErrRoomNotFound) || errors.Is(err, services.ErrUserNotInRoom):
+				client.CloseWithCode(websocket.ClosePolicyViolation, "not a room member")
+			default:
+				client.CloseWithCode(websocket.CloseInternalServerErr, "internal server error")
+			}
+			return
 		}
-		mu.Unlock()
-
-		room.AddClient(client)
 
 		// Запускаем неблокирующие горутины для чтения и записи
 		client.ReadPump(room)
@@ -11,6 +11,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	uuid "github.com/google/uuid"
+	models "github.com/sbilibin2017/bil-message/internal/models"
 )
 
 // MockRoomCreator is a mock of RoomCreator interface.
@@ -36,93 +37,108 @@ func (m *MockRoomCreator) EXPECT() *MockRoomCreatorMockRecorder {
 	return m.recorder
 }
 
+// CreateDirectRoom mocks base method.
+func (m *MockRoomCreator) CreateDirectRoom(ctx context.Context, userUUID, peerUUID uuid.UUID) (uuid.UUID, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateDirectRoom", ctx, userUUID, peerUUID)
+	ret0, _ := ret[0].(uuid.UUID)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateDirectRoom indicates an expected call of CreateDirectRoom.
+func (mr *MockRoomCreatorMockRecorder) CreateDirectRoom(ctx, userUUID, peerUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateDirectRoom", reflect.TypeOf((*MockRoomCreator)(nil).CreateDirectRoom), ctx, userUUID, peerUUID)
+}
+
 // CreateRoom mocks base method.
-func (m *MockRoomCreator) CreateRoom(ctx context.Context, userUUID uuid.UUID) (uuid.UUID, error) {
+func (m *MockRoomCreator) CreateRoom(ctx context.Context, userUUID uuid.UUID, name, topic string) (uuid.UUID, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "CreateRoom", ctx, userUUID)
+	ret := m.ctrl.Call(m, "CreateRoom", ctx, userUUID, name, topic)
 	ret0, _ := ret[0].(uuid.UUID)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // CreateRoom indicates an expected call of CreateRoom.
-func (mr *MockRoomCreatorMockRecorder) CreateRoom(ctx, userUUID interface{}) *gomock.Call {
+func (mr *MockRoomCreatorMockRecorder) CreateRoom(ctx, userUUID, name, topic interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRoom", reflect.TypeOf((*MockRoomCreator)(nil).CreateRoom), ctx, userUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRoom", reflect.TypeOf((*MockRoomCreator)(nil).CreateRoom), ctx, userUUID, name, topic)
 }
 
-// MockRoomRemover is a mock of RoomRemover interface.
-type MockRoomRemover struct {
+// MockRoomUpdater is a mock of RoomUpdater interface.
+type MockRoomUpdater struct {
 	ctrl     *gomock.Controller
-	recorder *MockRoomRemoverMockRecorder
+	recorder *MockRoomUpdaterMockRecorder
 }
 
-// MockRoomRemoverMockRecorder is the mock recorder for MockRoomRemover.
-type MockRoomRemoverMockRecorder struct {
-	mock *MockRoomRemover
+// MockRoomUpdaterMockRecorder is the mock recorder for MockRoomUpdater.
+type MockRoomUpdaterMockRecorder struct {
+	mock *MockRoomUpdater
 }
 
-// NewMockRoomRemover creates a new mock instance.
-func NewMockRoomRemover(ctrl *gomock.Controller) *MockRoomRemover {
-	mock := &MockRoomRemover{ctrl: ctrl}
-	mock.recorder = &MockRoomRemoverMockRecorder{mock}
+// NewMockRoomUpdater creates a new mock instance.
+func NewMockRoomUpdater(ctrl *gomock.Controller) *MockRoomUpdater {
+	mock := &MockRoomUpdater{ctrl: ctrl}
+	mock.recorder = &MockRoomUpdaterMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockRoomRemover) EXPECT() *MockRoomRemoverMockRecorder {
+func (m *MockRoomUpdater) EXPECT() *MockRoomUpdaterMockRecorder {
 	return m.recorder
 }
 
-// RemoveRoom mocks base method.
-func (m *MockRoomRemover) RemoveRoom(ctx context.Context, roomUUID uuid.UUID) error {
+// UpdateRoom mocks base method.
+func (m *MockRoomUpdater) UpdateRoom(ctx context.Context, actorUUID, roomUUID uuid.UUID, name, topic *string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RemoveRoom", ctx, roomUUID)
+	ret := m.ctrl.Call(m, "UpdateRoom", ctx, actorUUID, roomUUID, name, topic)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// RemoveRoom indicates an expected call of RemoveRoom.
-func (mr *MockRoomRemoverMockRecorder) RemoveRoom(ctx, roomUUID interface{}) *gomock.Call {
+// UpdateRoom indicates an expected call of UpdateRoom.
+func (mr *MockRoomUpdaterMockRecorder) UpdateRoom(ctx, actorUUID, roomUUID, name, topic interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRoom", reflect.TypeOf((*MockRoomRemover)(nil).RemoveRoom), ctx, roomUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRoom", reflect.TypeOf((*MockRoomUpdater)(nil).UpdateRoom), ctx, actorUUID, roomUUID, name, topic)
 }
 
-// MockRoomMemberAdder is a mock of RoomMemberAdder interface.
-type MockRoomMemberAdder struct {
+// MockRoomRemover is a mock of RoomRemover interface.
+type MockRoomRemover struct {
 	ctrl     *gomock.Controller
-	recorder *MockRoomMemberAdderMockRecorder
+	recorder *MockRoomRemoverMockRecorder
 }
 
-// MockRoomMemberAdderMockRecorder is the mock recorder for MockRoomMemberAdder.
-type MockRoomMemberAdderMockRecorder struct {
-	mock *MockRoomMemberAdder
+// MockRoomRemoverMockRecorder is the mock recorder for MockRoomRemover.
+type MockRoomRemoverMockRecorder struct {
+	mock *MockRoomRemover
 }
 
-// NewMockRoomMemberAdder creates a new mock instance.
-func NewMockRoomMemberAdder(ctrl *gomock.Controller) *MockRoomMemberAdder {
-	mock := &MockRoomMemberAdder{ctrl: ctrl}
-	mock.recorder = &MockRoomMemberAdderMockRecorder{mock}
+// NewMockRoomRemover creates a new mock instance.
+func NewMockRoomRemover(ctrl *gomock.Controller) *MockRoomRemover {
+	mock := &MockRoomRemover{ctrl: ctrl}
+	mock.recorder = &MockRoomRemoverMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockRoomMemberAdder) EXPECT() *MockRoomMemberAdderMockRecorder {
+func (m *MockRoomRemover) EXPECT() *MockRoomRemoverMockRecorder {
 	return m.recorder
 }
 
-// AddRoomMember mocks base method.
-func (m *MockRoomMemberAdder) AddRoomMember(ctx context.Context, roomUUID, userUUID uuid.UUID) error {
+// RemoveRoom mocks base method.
+func (m *MockRoomRemover) RemoveRoom(ctx context.Context, actorUUID, roomUUID uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "AddRoomMember", ctx, roomUUID, userUUID)
+	ret := m.ctrl.Call(m, "RemoveRoom", ctx, actorUUID, roomUUID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// AddRoomMember indicates an expected call of AddRoomMember.
-func (mr *MockRoomMemberAdderMockRecorder) AddRoomMember(ctx, roomUUID, userUUID interface{}) *gomock.Call {
+// RemoveRoom indicates an expected call of RemoveRoom.
+func (mr *MockRoomRemoverMockRecorder) RemoveRoom(ctx, actorUUID, roomUUID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddRoomMember", reflect.TypeOf((*MockRoomMemberAdder)(nil).AddRoomMember), ctx, roomUUID, userUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRoom", reflect.TypeOf((*MockRoomRemover)(nil).RemoveRoom), ctx, actorUUID, roomUUID)
 }
 
 // MockRoomMemberRemover is a mock of RoomMemberRemover interface.
@@ -149,17 +165,241 @@ func (m *MockRoomMemberRemover) EXPECT() *MockRoomMemberRemoverMockRecorder {
 }
 
 // RemoveRoomMember mocks base method.
-func (m *MockRoomMemberRemover) RemoveRoomMember(ctx context.Context, roomUUID, userUUID uuid.UUID) error {
+func (m *MockRoomMemberRemover) RemoveRoomMember(ctx context.Context, actorUUID, roomUUID, userUUID uuid.UUID) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RemoveRoomMember", ctx, roomUUID, userUUID)
+	ret := m.ctrl.Call(m, "RemoveRoomMember", ctx, actorUUID, roomUUID, userUUID)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // RemoveRoomMember indicates an expected call of RemoveRoomMember.
-func (mr *MockRoomMemberRemoverMockRecorder) RemoveRoomMember(ctx, roomUUID, userUUID interface{}) *gomock.Call {
+func (mr *MockRoomMemberRemoverMockRecorder) RemoveRoomMember(ctx, actorUUID, roomUUID, userUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRoomMember", reflect.TypeOf((*MockRoomMemberRemover)(nil).RemoveRoomMember), ctx, actorUUID, roomUUID, userUUID)
+}
+
+// MockRoomLeaver is a mock of RoomLeaver interface.
+type MockRoomLeaver struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoomLeaverMockRecorder
+}
+
+// MockRoomLeaverMockRecorder is the mock recorder for MockRoomLeaver.
+type MockRoomLeaverMockRecorder struct {
+	mock *MockRoomLeaver
+}
+
+// NewMockRoomLeaver creates a new mock instance.
+func NewMockRoomLeaver(ctrl *gomock.Controller) *MockRoomLeaver {
+	mock := &MockRoomLeaver{ctrl: ctrl}
+	mock.recorder = &MockRoomLeaverMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoomLeaver) EXPECT() *MockRoomLeaverMockRecorder {
+	return m.recorder
+}
+
+// LeaveRoom mocks base method.
+func (m *MockRoomLeaver) LeaveRoom(ctx context.Context, roomUUID, userUUID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LeaveRoom", ctx, roomUUID, userUUID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LeaveRoom indicates an expected call of LeaveRoom.
+func (mr *MockRoomLeaverMockRecorder) LeaveRoom(ctx, roomUUID, userUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LeaveRoom", reflect.TypeOf((*MockRoomLeaver)(nil).LeaveRoom), ctx, roomUUID, userUUID)
+}
+
+// MockRoomOwnershipTransferrer is a mock of RoomOwnershipTransferrer interface.
+type MockRoomOwnershipTransferrer struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoomOwnershipTransferrerMockRecorder
+}
+
+// MockRoomOwnershipTransferrerMockRecorder is the mock recorder for MockRoomOwnershipTransferrer.
+type MockRoomOwnershipTransferrerMockRecorder struct {
+	mock *MockRoomOwnershipTransferrer
+}
+
+// NewMockRoomOwnershipTransferrer creates a new mock instance.
+func NewMockRoomOwnershipTransferrer(ctrl *gomock.Controller) *MockRoomOwnershipTransferrer {
+	mock := &MockRoomOwnershipTransferrer{ctrl: ctrl}
+	mock.recorder = &MockRoomOwnershipTransferrerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoomOwnershipTransferrer) EXPECT() *MockRoomOwnershipTransferrerMockRecorder {
+	return m.recorder
+}
+
+// TransferOwnership mocks base method.
+func (m *MockRoomOwnershipTransferrer) TransferOwnership(ctx context.Context, actorUUID, roomUUID, newOwnerUUID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferOwnership", ctx, actorUUID, roomUUID, newOwnerUUID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// TransferOwnership indicates an expected call of TransferOwnership.
+func (mr *MockRoomOwnershipTransferrerMockRecorder) TransferOwnership(ctx, actorUUID, roomUUID, newOwnerUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferOwnership", reflect.TypeOf((*MockRoomOwnershipTransferrer)(nil).TransferOwnership), ctx, actorUUID, roomUUID, newOwnerUUID)
+}
+
+// MockRoomMemberRoleSetter is a mock of RoomMemberRoleSetter interface.
+type MockRoomMemberRoleSetter struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoomMemberRoleSetterMockRecorder
+}
+
+// MockRoomMemberRoleSetterMockRecorder is the mock recorder for MockRoomMemberRoleSetter.
+type MockRoomMemberRoleSetterMockRecorder struct {
+	mock *MockRoomMemberRoleSetter
+}
+
+// NewMockRoomMemberRoleSetter creates a new mock instance.
+func NewMockRoomMemberRoleSetter(ctrl *gomock.Controller) *MockRoomMemberRoleSetter {
+	mock := &MockRoomMemberRoleSetter{ctrl: ctrl}
+	mock.recorder = &MockRoomMemberRoleSetterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoomMemberRoleSetter) EXPECT() *MockRoomMemberRoleSetterMockRecorder {
+	return m.recorder
+}
+
+// SetRoomMemberRole mocks base method.
+func (m *MockRoomMemberRoleSetter) SetRoomMemberRole(ctx context.Context, actorUUID, roomUUID, userUUID uuid.UUID, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRoomMemberRole", ctx, actorUUID, roomUUID, userUUID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetRoomMemberRole indicates an expected call of SetRoomMemberRole.
+func (mr *MockRoomMemberRoleSetterMockRecorder) SetRoomMemberRole(ctx, actorUUID, roomUUID, userUUID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRoomMemberRole", reflect.TypeOf((*MockRoomMemberRoleSetter)(nil).SetRoomMemberRole), ctx, actorUUID, roomUUID, userUUID, role)
+}
+
+// MockRoomLister is a mock of RoomLister interface.
+type MockRoomLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoomListerMockRecorder
+}
+
+// MockRoomListerMockRecorder is the mock recorder for MockRoomLister.
+type MockRoomListerMockRecorder struct {
+	mock *MockRoomLister
+}
+
+// NewMockRoomLister creates a new mock instance.
+func NewMockRoomLister(ctrl *gomock.Controller) *MockRoomLister {
+	mock := &MockRoomLister{ctrl: ctrl}
+	mock.recorder = &MockRoomListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoomLister) EXPECT() *MockRoomListerMockRecorder {
+	return m.recorder
+}
+
+// ListRooms mocks base method.
+func (m *MockRoomLister) ListRooms(ctx context.Context, userUUID uuid.UUID) ([]models.RoomSummaryDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRooms", ctx, userUUID)
+	ret0, _ := ret[0].([]models.RoomSummaryDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRooms indicates an expected call of ListRooms.
+func (mr *MockRoomListerMockRecorder) ListRooms(ctx, userUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRooms", reflect.TypeOf((*MockRoomLister)(nil).ListRooms), ctx, userUUID)
+}
+
+// MockRoomMemberLister is a mock of RoomMemberLister interface.
+type MockRoomMemberLister struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoomMemberListerMockRecorder
+}
+
+// MockRoomMemberListerMockRecorder is the mock recorder for MockRoomMemberLister.
+type MockRoomMemberListerMockRecorder struct {
+	mock *MockRoomMemberLister
+}
+
+// NewMockRoomMemberLister creates a new mock instance.
+func NewMockRoomMemberLister(ctrl *gomock.Controller) *MockRoomMemberLister {
+	mock := &MockRoomMemberLister{ctrl: ctrl}
+	mock.recorder = &MockRoomMemberListerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoomMemberLister) EXPECT() *MockRoomMemberListerMockRecorder {
+	return m.recorder
+}
+
+// ListRoomMembers mocks base method.
+func (m *MockRoomMemberLister) ListRoomMembers(ctx context.Context, userUUID, roomUUID uuid.UUID) ([]models.RoomMemberUserDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRoomMembers", ctx, userUUID, roomUUID)
+	ret0, _ := ret[0].([]models.RoomMemberUserDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRoomMembers indicates an expected call of ListRoomMembers.
+func (mr *MockRoomMemberListerMockRecorder) ListRoomMembers(ctx, userUUID, roomUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRoomMembers", reflect.TypeOf((*MockRoomMemberLister)(nil).ListRoomMembers), ctx, userUUID, roomUUID)
+}
+
+// MockRoomMemberChecker is a mock of RoomMemberChecker interface.
+type MockRoomMemberChecker struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoomMemberCheckerMockRecorder
+}
+
+// MockRoomMemberCheckerMockRecorder is the mock recorder for MockRoomMemberChecker.
+type MockRoomMemberCheckerMockRecorder struct {
+	mock *MockRoomMemberChecker
+}
+
+// NewMockRoomMemberChecker creates a new mock instance.
+func NewMockRoomMemberChecker(ctrl *gomock.Controller) *MockRoomMemberChecker {
+	mock := &MockRoomMemberChecker{ctrl: ctrl}
+	mock.recorder = &MockRoomMemberCheckerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoomMemberChecker) EXPECT() *MockRoomMemberCheckerMockRecorder {
+	return m.recorder
+}
+
+// CheckRoomMember mocks base method.
+func (m *MockRoomMemberChecker) CheckRoomMember(ctx context.Context, roomUUID, userUUID uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckRoomMember", ctx, roomUUID, userUUID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckRoomMember indicates an expected call of CheckRoomMember.
+func (mr *MockRoomMemberCheckerMockRecorder) CheckRoomMember(ctx, roomUUID, userUUID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveRoomMember", reflect.TypeOf((*MockRoomMemberRemover)(nil).RemoveRoomMember), ctx, roomUUID, userUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckRoomMember", reflect.TypeOf((*MockRoomMemberChecker)(nil).CheckRoomMember), ctx, roomUUID, userUUID)
 }
 
 // MockTokenParser is a mock of TokenParser interface.
@@ -1,9 +1,11 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +15,7 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/sbilibin2017/bil-message/internal/chat"
 	"github.com/sbilibin2017/bil-message/internal/jwt"
+	"github.com/sbilibin2017/bil-message/internal/models"
 	"github.com/sbilibin2017/bil-message/internal/services"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -26,20 +29,90 @@ func TestCreateChatHandler(t *testing.T) {
 	mockParser := NewMockTokenParser(ctrl)
 
 	userUUID := uuid.New()
+	peerUUID := uuid.New()
 	roomUUID := uuid.New()
 
 	tests := []struct {
 		name           string
+		body           string
 		expectedStatus int
 		setup          func()
 	}{
 		{
-			name:           "success",
+			name:           "success without body",
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.Nil, nil)
+				mockSvc.EXPECT().CreateRoom(gomock.Any(), userUUID, "", "").Return(roomUUID, nil)
+			},
+		},
+		{
+			name:           "success with name and topic",
+			body:           `{"name":"Команда","topic":"Планы"}`,
 			expectedStatus: http.StatusOK,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
 				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.Nil, nil)
-				mockSvc.EXPECT().CreateRoom(gomock.Any(), userUUID).Return(roomUUID, nil)
+				mockSvc.EXPECT().CreateRoom(gomock.Any(), userUUID, "Команда", "Планы").Return(roomUUID, nil)
+			},
+		},
+		{
+			name:           "success direct",
+			body:           `{"kind":"direct","member_uuid":"` + peerUUID.String() + `"}`,
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.Nil, nil)
+				mockSvc.EXPECT().CreateDirectRoom(gomock.Any(), userUUID, peerUUID).Return(roomUUID, nil)
+			},
+		},
+		{
+			name:           "invalid body",
+			body:           `invalid`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unknown kind",
+			body:           `{"kind":"channel"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "direct without member",
+			body:           `{"kind":"direct"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "direct with self",
+			body:           `{"kind":"direct","member_uuid":"` + userUUID.String() + `"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.Nil, nil)
+				mockSvc.EXPECT().CreateDirectRoom(gomock.Any(), userUUID, userUUID).Return(uuid.Nil, services.ErrInvalidDirectRoom)
+			},
+		},
+		{
+			name:           "direct peer not found",
+			body:           `{"kind":"direct","member_uuid":"` + peerUUID.String() + `"}`,
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.Nil, nil)
+				mockSvc.EXPECT().CreateDirectRoom(gomock.Any(), userUUID, peerUUID).Return(uuid.Nil, services.ErrUserNotFound)
+			},
+		},
+		{
+			name:           "name too long",
+			body:           `{"name":"` + strings.Repeat("a", services.MaxRoomNameLength+1) + `"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.Nil, nil)
+				mockSvc.EXPECT().CreateRoom(gomock.Any(), userUUID, gomock.Any(), "").Return(uuid.Nil, services.ErrInvalidRoomMetadata)
 			},
 		},
 		{
@@ -63,7 +136,7 @@ func TestCreateChatHandler(t *testing.T) {
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
 				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.Nil, nil)
-				mockSvc.EXPECT().CreateRoom(gomock.Any(), userUUID).Return(uuid.Nil, errors.New("fail"))
+				mockSvc.EXPECT().CreateRoom(gomock.Any(), userUUID, "", "").Return(uuid.Nil, errors.New("fail"))
 			},
 		},
 	}
@@ -71,83 +144,114 @@ func TestCreateChatHandler(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setup()
-			req := httptest.NewRequest("POST", "/chat", nil)
+			req := httptest.NewRequest("POST", "/chat", strings.NewReader(tt.body))
 			w := httptest.NewRecorder()
 
 			handler := CreateChatHandler(mockSvc, mockParser)
 			handler.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, roomUUID.String(), w.Body.String())
+			}
 		})
 	}
 }
 
-func TestRemoveChatHandler(t *testing.T) {
+func TestUpdateChatHandler(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockSvc := NewMockRoomRemover(ctrl)
+	mockSvc := NewMockRoomUpdater(ctrl)
 	mockParser := NewMockTokenParser(ctrl)
 
 	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	name := "Команда"
 
 	tests := []struct {
 		name           string
 		roomID         string
+		body           string
 		expectedStatus int
 		setup          func()
 	}{
 		{
 			name:           "success",
 			roomID:         roomUUID.String(),
+			body:           `{"name":"Команда"}`,
 			expectedStatus: http.StatusOK,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
-				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
-				mockSvc.EXPECT().RemoveRoom(gomock.Any(), roomUUID).Return(nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().UpdateRoom(gomock.Any(), userUUID, roomUUID, &name, nil).Return(nil)
 			},
 		},
 		{
-			name:           "invalid UUID",
-			roomID:         "invalid-uuid",
+			name:           "invalid room uuid",
+			roomID:         "invalid",
+			body:           `{"name":"Команда"}`,
 			expectedStatus: http.StatusBadRequest,
 			setup:          func() {},
 		},
 		{
-			name:           "token get error",
+			name:           "invalid body",
+			roomID:         roomUUID.String(),
+			body:           `invalid`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid metadata",
+			roomID:         roomUUID.String(),
+			body:           `{"name":"Команда"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().UpdateRoom(gomock.Any(), userUUID, roomUUID, &name, nil).Return(services.ErrInvalidRoomMetadata)
+			},
+		},
+		{
+			name:           "unauthorized",
 			roomID:         roomUUID.String(),
+			body:           `{"name":"Команда"}`,
 			expectedStatus: http.StatusUnauthorized,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("fail"))
 			},
 		},
 		{
-			name:           "token parse error",
+			name:           "forbidden",
 			roomID:         roomUUID.String(),
-			expectedStatus: http.StatusUnauthorized,
+			body:           `{"name":"Команда"}`,
+			expectedStatus: http.StatusForbidden,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
-				mockParser.EXPECT().Parse("token").Return(uuid.Nil, uuid.Nil, errors.New("fail"))
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().UpdateRoom(gomock.Any(), userUUID, roomUUID, &name, nil).Return(services.ErrForbidden)
 			},
 		},
 		{
 			name:           "room not found",
 			roomID:         roomUUID.String(),
+			body:           `{"name":"Команда"}`,
 			expectedStatus: http.StatusNotFound,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
-				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
-				mockSvc.EXPECT().RemoveRoom(gomock.Any(), roomUUID).Return(services.ErrRoomNotFound)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().UpdateRoom(gomock.Any(), userUUID, roomUUID, &name, nil).Return(services.ErrRoomNotFound)
 			},
 		},
 		{
 			name:           "internal error",
 			roomID:         roomUUID.String(),
+			body:           `{"name":"Команда"}`,
 			expectedStatus: http.StatusInternalServerError,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
-				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
-				mockSvc.EXPECT().RemoveRoom(gomock.Any(), roomUUID).Return(errors.New("fail"))
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().UpdateRoom(gomock.Any(), userUUID, roomUUID, &name, nil).Return(errors.New("fail"))
 			},
 		},
 	}
@@ -155,13 +259,11 @@ func TestRemoveChatHandler(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setup()
-
 			r := chi.NewRouter()
-			r.Delete("/chat/{room-uuid}", RemoveChatHandler(mockSvc, mockParser))
+			r.Put("/chat/{room-uuid}", UpdateChatHandler(mockSvc, mockParser))
 
-			req := httptest.NewRequest("DELETE", "/chat/"+tt.roomID, nil)
+			req := httptest.NewRequest("PUT", "/chat/"+tt.roomID, strings.NewReader(tt.body))
 			w := httptest.NewRecorder()
-
 			r.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
@@ -169,52 +271,40 @@ func TestRemoveChatHandler(t *testing.T) {
 	}
 }
 
-func TestAddChatMemberHandler(t *testing.T) {
+func TestRemoveChatHandler(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
-	mockSvc := NewMockRoomMemberAdder(ctrl)
+	mockSvc := NewMockRoomRemover(ctrl)
 	mockParser := NewMockTokenParser(ctrl)
 
-	userUUID := uuid.New()
 	roomUUID := uuid.New()
 
 	tests := []struct {
 		name           string
 		roomID         string
-		memberID       string
 		expectedStatus int
 		setup          func()
 	}{
 		{
 			name:           "success",
 			roomID:         roomUUID.String(),
-			memberID:       userUUID.String(),
 			expectedStatus: http.StatusOK,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
 				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
-				mockSvc.EXPECT().AddRoomMember(gomock.Any(), roomUUID, userUUID).Return(nil)
+				mockSvc.EXPECT().RemoveRoom(gomock.Any(), gomock.Any(), roomUUID).Return(nil)
 			},
 		},
 		{
-			name:           "invalid room UUID",
-			roomID:         "invalid",
-			memberID:       userUUID.String(),
-			expectedStatus: http.StatusBadRequest,
-			setup:          func() {},
-		},
-		{
-			name:           "invalid member UUID",
-			roomID:         roomUUID.String(),
-			memberID:       "invalid",
+			name:           "invalid UUID",
+			roomID:         "invalid-uuid",
 			expectedStatus: http.StatusBadRequest,
 			setup:          func() {},
 		},
 		{
 			name:           "token get error",
 			roomID:         roomUUID.String(),
-			memberID:       userUUID.String(),
 			expectedStatus: http.StatusUnauthorized,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("fail"))
@@ -223,7 +313,6 @@ func TestAddChatMemberHandler(t *testing.T) {
 		{
 			name:           "token parse error",
 			roomID:         roomUUID.String(),
-			memberID:       userUUID.String(),
 			expectedStatus: http.StatusUnauthorized,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
@@ -233,23 +322,31 @@ func TestAddChatMemberHandler(t *testing.T) {
 		{
 			name:           "room not found",
 			roomID:         roomUUID.String(),
-			memberID:       userUUID.String(),
 			expectedStatus: http.StatusNotFound,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
 				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
-				mockSvc.EXPECT().AddRoomMember(gomock.Any(), roomUUID, userUUID).Return(services.ErrRoomNotFound)
+				mockSvc.EXPECT().RemoveRoom(gomock.Any(), gomock.Any(), roomUUID).Return(services.ErrRoomNotFound)
+			},
+		},
+		{
+			name:           "not owner",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
+				mockSvc.EXPECT().RemoveRoom(gomock.Any(), gomock.Any(), roomUUID).Return(services.ErrForbidden)
 			},
 		},
 		{
 			name:           "internal error",
 			roomID:         roomUUID.String(),
-			memberID:       userUUID.String(),
 			expectedStatus: http.StatusInternalServerError,
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
 				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
-				mockSvc.EXPECT().AddRoomMember(gomock.Any(), roomUUID, userUUID).Return(errors.New("fail"))
+				mockSvc.EXPECT().RemoveRoom(gomock.Any(), gomock.Any(), roomUUID).Return(errors.New("fail"))
 			},
 		},
 	}
@@ -257,11 +354,13 @@ func TestAddChatMemberHandler(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setup()
+
 			r := chi.NewRouter()
-			r.Post("/chat/{room-uuid}/{member-uuid}", AddChatMemberHandler(mockSvc, mockParser))
+			r.Delete("/chat/{room-uuid}", RemoveChatHandler(mockSvc, mockParser))
 
-			req := httptest.NewRequest("POST", "/chat/"+tt.roomID+"/"+tt.memberID, nil)
+			req := httptest.NewRequest("DELETE", "/chat/"+tt.roomID, nil)
 			w := httptest.NewRecorder()
+
 			r.ServeHTTP(w, req)
 
 			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
@@ -294,7 +393,7 @@ func TestRemoveChatMemberHandler(t *testing.T) {
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
 				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
-				mockSvc.EXPECT().RemoveRoomMember(gomock.Any(), roomUUID, userUUID).Return(nil)
+				mockSvc.EXPECT().RemoveRoomMember(gomock.Any(), gomock.Any(), roomUUID, userUUID).Return(nil)
 			},
 		},
 		{
@@ -338,7 +437,40 @@ func TestRemoveChatMemberHandler(t *testing.T) {
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
 				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
-				mockSvc.EXPECT().RemoveRoomMember(gomock.Any(), roomUUID, userUUID).Return(services.ErrRoomNotFound)
+				mockSvc.EXPECT().RemoveRoomMember(gomock.Any(), gomock.Any(), roomUUID, userUUID).Return(services.ErrRoomNotFound)
+			},
+		},
+		{
+			name:           "member not in room",
+			roomID:         roomUUID.String(),
+			memberID:       userUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
+				mockSvc.EXPECT().RemoveRoomMember(gomock.Any(), gomock.Any(), roomUUID, userUUID).Return(services.ErrUserNotInRoom)
+			},
+		},
+		{
+			name:           "forbidden",
+			roomID:         roomUUID.String(),
+			memberID:       userUUID.String(),
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
+				mockSvc.EXPECT().RemoveRoomMember(gomock.Any(), gomock.Any(), roomUUID, userUUID).Return(services.ErrForbidden)
+			},
+		},
+		{
+			name:           "owner cannot leave",
+			roomID:         roomUUID.String(),
+			memberID:       userUUID.String(),
+			expectedStatus: http.StatusConflict,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().RemoveRoomMember(gomock.Any(), userUUID, roomUUID, userUUID).Return(services.ErrOwnerCannotLeave)
 			},
 		},
 		{
@@ -349,7 +481,7 @@ func TestRemoveChatMemberHandler(t *testing.T) {
 			setup: func() {
 				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
 				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
-				mockSvc.EXPECT().RemoveRoomMember(gomock.Any(), roomUUID, userUUID).Return(errors.New("fail"))
+				mockSvc.EXPECT().RemoveRoomMember(gomock.Any(), gomock.Any(), roomUUID, userUUID).Return(errors.New("fail"))
 			},
 		},
 	}
@@ -369,48 +501,630 @@ func TestRemoveChatMemberHandler(t *testing.T) {
 	}
 }
 
-func TestChatWebSocketHandlerWithJWT(t *testing.T) {
-	// JWT
-	j, err := jwt.New()
-	require.NoError(t, err)
+func TestLeaveChatHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
-	userUUID := uuid.New()
-	deviceUUID := uuid.New()
-	token, err := j.Generate(userUUID, deviceUUID)
-	require.NoError(t, err)
+	mockSvc := NewMockRoomLeaver(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
 
 	roomUUID := uuid.New()
+	userUUID := uuid.New()
 
-	// chi router
-	r := chi.NewRouter()
-	r.Get("/chat/ws/{room-uuid}", ChatWebSocketHandler(
-		func(conn *websocket.Conn, userUUID, roomUUID uuid.UUID) *chat.ChatClient {
-			return chat.NewChatClient(conn, userUUID, roomUUID)
+	tests := []struct {
+		name           string
+		roomID         string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().LeaveRoom(gomock.Any(), roomUUID, userUUID).Return(nil)
+			},
 		},
-		func(roomUUID uuid.UUID) *chat.ChatRoom {
-			return chat.NewChatRoom(roomUUID)
+		{
+			name:           "invalid room UUID",
+			roomID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
 		},
-		j,
-	))
+		{
+			name:           "token parse error",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.Nil, uuid.Nil, errors.New("fail"))
+			},
+		},
+		{
+			name:           "not in room",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().LeaveRoom(gomock.Any(), roomUUID, userUUID).Return(services.ErrUserNotInRoom)
+			},
+		},
+		{
+			name:           "owner cannot leave",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusConflict,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().LeaveRoom(gomock.Any(), roomUUID, userUUID).Return(services.ErrOwnerCannotLeave)
+			},
+		},
+		{
+			name:           "internal error",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().LeaveRoom(gomock.Any(), roomUUID, userUUID).Return(errors.New("fail"))
+			},
+		},
+	}
 
-	server := httptest.NewServer(r)
-	defer server.Close()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Post("/chat/{room-uuid}/leave", LeaveChatHandler(mockSvc, mockParser))
 
-	// Convert http:// to ws://
-	wsURL := "ws" + server.URL[len("http"):] + "/chat/ws/" + roomUUID.String()
+			req := httptest.NewRequest("POST", "/chat/"+tt.roomID+"/leave", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
 
-	header := make(map[string][]string)
-	header["Authorization"] = []string{"Bearer " + token}
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
 
-	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
-	require.NoError(t, err)
-	defer conn.Close()
+func TestTransferChatOwnershipHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
 
-	// Send and receive message
-	testMsg := []byte("hello")
-	require.NoError(t, conn.WriteMessage(websocket.TextMessage, testMsg))
+	mockSvc := NewMockRoomOwnershipTransferrer(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
 
-	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
-	_, _, err = conn.ReadMessage()
-	require.Error(t, err) // no other clients yet, timeout expected
+	roomUUID := uuid.New()
+	ownerUUID := uuid.New()
+	memberUUID := uuid.New()
+
+	tests := []struct {
+		name           string
+		roomID         string
+		memberID       string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			roomID:         roomUUID.String(),
+			memberID:       memberUUID.String(),
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(ownerUUID, uuid.New(), nil)
+				mockSvc.EXPECT().TransferOwnership(gomock.Any(), ownerUUID, roomUUID, memberUUID).Return(nil)
+			},
+		},
+		{
+			name:           "invalid member UUID",
+			roomID:         roomUUID.String(),
+			memberID:       "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "token get error",
+			roomID:         roomUUID.String(),
+			memberID:       memberUUID.String(),
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("fail"))
+			},
+		},
+		{
+			name:           "forbidden",
+			roomID:         roomUUID.String(),
+			memberID:       memberUUID.String(),
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(ownerUUID, uuid.New(), nil)
+				mockSvc.EXPECT().TransferOwnership(gomock.Any(), ownerUUID, roomUUID, memberUUID).Return(services.ErrForbidden)
+			},
+		},
+		{
+			name:           "new owner not in room",
+			roomID:         roomUUID.String(),
+			memberID:       memberUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(ownerUUID, uuid.New(), nil)
+				mockSvc.EXPECT().TransferOwnership(gomock.Any(), ownerUUID, roomUUID, memberUUID).Return(services.ErrUserNotInRoom)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Post("/chat/{room-uuid}/{member-uuid}/owner", TransferChatOwnershipHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("POST", "/chat/"+tt.roomID+"/"+tt.memberID+"/owner", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestSetChatMemberRoleHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockRoomMemberRoleSetter(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	roomUUID := uuid.New()
+	ownerUUID := uuid.New()
+	memberUUID := uuid.New()
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			body:           `{"role":"admin"}`,
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(ownerUUID, uuid.New(), nil)
+				mockSvc.EXPECT().SetRoomMemberRole(gomock.Any(), ownerUUID, roomUUID, memberUUID, "admin").Return(nil)
+			},
+		},
+		{
+			name:           "invalid body",
+			body:           `invalid`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid role",
+			body:           `{"role":"owner"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(ownerUUID, uuid.New(), nil)
+				mockSvc.EXPECT().SetRoomMemberRole(gomock.Any(), ownerUUID, roomUUID, memberUUID, "owner").Return(services.ErrInvalidRole)
+			},
+		},
+		{
+			name:           "forbidden",
+			body:           `{"role":"admin"}`,
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(ownerUUID, uuid.New(), nil)
+				mockSvc.EXPECT().SetRoomMemberRole(gomock.Any(), ownerUUID, roomUUID, memberUUID, "admin").Return(services.ErrForbidden)
+			},
+		},
+		{
+			name:           "internal error",
+			body:           `{"role":"admin"}`,
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(ownerUUID, uuid.New(), nil)
+				mockSvc.EXPECT().SetRoomMemberRole(gomock.Any(), ownerUUID, roomUUID, memberUUID, "admin").Return(errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Put("/chat/{room-uuid}/{member-uuid}/role", SetChatMemberRoleHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("PUT", "/chat/"+roomUUID.String()+"/"+memberUUID.String()+"/role", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+// newTestChatClient создаёт клиента чата с параметрами соединения по умолчанию
+func newTestChatClient(conn *websocket.Conn, userUUID, deviceUUID, roomUUID uuid.UUID) *chat.ChatClient {
+	return chat.NewChatClient(conn, userUUID, deviceUUID, roomUUID)
+}
+
+func TestListChatsHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockRoomLister(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastMessageAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	active := models.RoomSummaryDB{RoomUUID: uuid.New(), Name: "Команда", Topic: "Планы", Kind: models.RoomKindGroup, Role: models.RoomRoleMember, MemberCount: 3, CreatedAt: createdAt, LastMessageAt: &lastMessageAt}
+	quiet := models.RoomSummaryDB{RoomUUID: uuid.New(), Kind: models.RoomKindDirect, Role: models.RoomRoleOwner, MemberCount: 1, CreatedAt: createdAt}
+
+	tests := []struct {
+		name           string
+		expectedStatus int
+		expectedBody   *RoomsResponse
+		setup          func()
+	}{
+		{
+			name:           "success",
+			expectedStatus: http.StatusOK,
+			expectedBody: &RoomsResponse{Rooms: []RoomResponse{
+				{RoomUUID: active.RoomUUID.String(), Name: "Команда", Topic: "Планы", Kind: models.RoomKindGroup, Role: models.RoomRoleMember, MemberCount: 3, CreatedAt: createdAt, LastActivityAt: lastMessageAt},
+				{RoomUUID: quiet.RoomUUID.String(), Kind: models.RoomKindDirect, Role: models.RoomRoleOwner, MemberCount: 1, CreatedAt: createdAt, LastActivityAt: createdAt},
+			}},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListRooms(gomock.Any(), userUUID).Return([]models.RoomSummaryDB{active, quiet}, nil)
+			},
+		},
+		{
+			name:           "no rooms",
+			expectedStatus: http.StatusOK,
+			expectedBody:   &RoomsResponse{Rooms: []RoomResponse{}},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListRooms(gomock.Any(), userUUID).Return(nil, nil)
+			},
+		},
+		{
+			name:           "unauthorized",
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "service error",
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListRooms(gomock.Any(), userUUID).Return(nil, errors.New("db error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Get("/chat", ListChatsHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest(http.MethodGet, "/chat", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != nil {
+				var body RoomsResponse
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+				assert.Equal(t, *tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestListChatMembersHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockRoomMemberLister(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	joinedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	member := models.RoomMemberUserDB{
+		RoomMemberDB: models.RoomMemberDB{RoomUUID: roomUUID, UserUUID: userUUID, Role: models.RoomRoleOwner, JoinedAt: joinedAt},
+		Username:     "alice",
+	}
+
+	tests := []struct {
+		name           string
+		roomID         string
+		expectedStatus int
+		expectedBody   *RoomMembersResponse
+		setup          func()
+	}{
+		{
+			name:           "success",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusOK,
+			expectedBody: &RoomMembersResponse{Members: []RoomMemberResponse{
+				{UserUUID: userUUID.String(), Username: "alice", Role: models.RoomRoleOwner, JoinedAt: joinedAt},
+			}},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListRoomMembers(gomock.Any(), userUUID, roomUUID).Return([]models.RoomMemberUserDB{member}, nil)
+			},
+		},
+		{
+			name:           "invalid room UUID",
+			roomID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "token parse error",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.Nil, uuid.Nil, errors.New("invalid token"))
+			},
+		},
+		{
+			name:           "not a member",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListRoomMembers(gomock.Any(), userUUID, roomUUID).Return(nil, services.ErrUserNotInRoom)
+			},
+		},
+		{
+			name:           "room not found",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListRoomMembers(gomock.Any(), userUUID, roomUUID).Return(nil, services.ErrRoomNotFound)
+			},
+		},
+		{
+			name:           "service error",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListRoomMembers(gomock.Any(), userUUID, roomUUID).Return(nil, errors.New("db error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Get("/chat/{room-uuid}/members", ListChatMembersHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest(http.MethodGet, "/chat/"+tt.roomID+"/members", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != nil {
+				var body RoomMembersResponse
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+				assert.Equal(t, *tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestChatWebSocketHandlerWithJWT(t *testing.T) {
+	// JWT
+	j, err := jwt.New()
+	require.NoError(t, err)
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	token, err := j.Generate(userUUID, deviceUUID)
+	require.NoError(t, err)
+
+	roomUUID := uuid.New()
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockChecker := NewMockRoomMemberChecker(ctrl)
+	// членство проверяется до апгрейда и повторно после регистрации в хабе
+	mockChecker.EXPECT().CheckRoomMember(gomock.Any(), roomUUID, userUUID).Return(nil).Times(2)
+
+	// chi router
+	r := chi.NewRouter()
+	r.Get("/chat/ws/{room-uuid}", ChatWebSocketHandler(
+		newTestChatClient,
+		chat.NewHub(func(roomUUID uuid.UUID) *chat.ChatRoom {
+			return chat.NewChatRoom(roomUUID)
+		}),
+		mockChecker,
+		j,
+	))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	// Convert http:// to ws://
+	wsURL := "ws" + server.URL[len("http"):] + "/chat/ws/" + roomUUID.String()
+
+	header := make(map[string][]string)
+	header["Authorization"] = []string{"Bearer " + token}
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	// Send and receive message
+	testMsg := []byte(`{"v":1,"type":"message","ciphertext":"hello","key_epoch":1}`)
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, testMsg))
+
+	// the sender gets an ack for the saved message
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	var ack chat.Envelope
+	require.NoError(t, conn.ReadJSON(&ack))
+	assert.Equal(t, chat.TypeAck, ack.Type)
+	assert.Equal(t, roomUUID, ack.RoomUUID)
+	assert.NotEqual(t, uuid.Nil, ack.MessageUUID)
+
+	conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, _, err = conn.ReadMessage()
+	require.Error(t, err) // no other clients yet, timeout expected
+}
+
+func TestChatWebSocketHandlerClosesRemovedMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	j, err := jwt.New()
+	require.NoError(t, err)
+
+	userUUID := uuid.New()
+	token, err := j.Generate(userUUID, uuid.New())
+	require.NoError(t, err)
+
+	roomUUID := uuid.New()
+	hub := chat.NewHub(func(roomUUID uuid.UUID) *chat.ChatRoom {
+		return chat.NewChatRoom(roomUUID)
+	})
+
+	// Участника удалили между проверкой перед апгрейдом и регистрацией в хабе
+	mockChecker := NewMockRoomMemberChecker(ctrl)
+	gomock.InOrder(
+		mockChecker.EXPECT().CheckRoomMember(gomock.Any(), roomUUID, userUUID).Return(nil),
+		mockChecker.EXPECT().CheckRoomMember(gomock.Any(), roomUUID, userUUID).Return(services.ErrUserNotInRoom),
+	)
+
+	r := chi.NewRouter()
+	r.Get("/chat/{room-uuid}/ws", ChatWebSocketHandler(newTestChatClient, hub, mockChecker, j))
+
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	header := http.Header{}
+	header.Set("Authorization", "Bearer "+token)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+server.URL[len("http"):]+"/chat/"+roomUUID.String()+"/ws", header)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "unexpected error: %v", err)
+	require.Eventually(t, func() bool { return hub.ConnectionCount() == 0 }, time.Second, 5*time.Millisecond)
+}
+
+func TestChatWebSocketHandlerRejectsNonMembers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	j, err := jwt.New()
+	require.NoError(t, err)
+
+	userUUID := uuid.New()
+	token, err := j.Generate(userUUID, uuid.New())
+	require.NoError(t, err)
+
+	roomUUID := uuid.New()
+	mockChecker := NewMockRoomMemberChecker(ctrl)
+
+	r := chi.NewRouter()
+	r.Get("/chat/{room-uuid}/ws", ChatWebSocketHandler(
+		newTestChatClient,
+		chat.NewHub(func(roomUUID uuid.UUID) *chat.ChatRoom {
+			return chat.NewChatRoom(roomUUID)
+		}),
+		mockChecker,
+		j,
+	))
+
+	tests := []struct {
+		name           string
+		roomID         string
+		token          string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "invalid room UUID",
+			roomID:         "invalid-uuid",
+			token:          token,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid token",
+			roomID:         roomUUID.String(),
+			token:          "invalid",
+			expectedStatus: http.StatusUnauthorized,
+			setup:          func() {},
+		},
+		{
+			name:           "room not found",
+			roomID:         roomUUID.String(),
+			token:          token,
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockChecker.EXPECT().CheckRoomMember(gomock.Any(), roomUUID, userUUID).Return(services.ErrRoomNotFound)
+			},
+		},
+		{
+			name:           "user not in room",
+			roomID:         roomUUID.String(),
+			token:          token,
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockChecker.EXPECT().CheckRoomMember(gomock.Any(), roomUUID, userUUID).Return(services.ErrUserNotInRoom)
+			},
+		},
+		{
+			name:           "checker error",
+			roomID:         roomUUID.String(),
+			token:          token,
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockChecker.EXPECT().CheckRoomMember(gomock.Any(), roomUUID, userUUID).Return(errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			req := httptest.NewRequest("GET", "/chat/"+tt.roomID+"/ws", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			w := httptest.NewRecorder()
+
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
 }
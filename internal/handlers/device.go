@@ -0,0 +1,398 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+)
+
+// Интерфейс для получения списка устройств пользователя
+type DeviceLister interface {
+	// ListDevices возвращает активные устройства пользователя
+	ListDevices(ctx context.Context, userUUID uuid.UUID) ([]models.UserDeviceDB, error)
+}
+
+// Интерфейс для переименования устройства
+type DeviceRenamer interface {
+	// RenameDevice задаёт название устройства пользователя
+	RenameDevice(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, name string) error
+}
+
+// Интерфейс для отзыва устройства
+type DeviceRevoker interface {
+	// RevokeDevice отзывает устройство пользователя вместе с его сессиями и ключами комнат
+	RevokeDevice(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID) error
+}
+
+// Интерфейс для подтверждения нового устройства
+type DeviceApprover interface {
+	// ApproveDevice подтверждает ожидающее устройство пользователя с доверенного устройства
+	ApproveDevice(ctx context.Context, userUUID uuid.UUID, approverUUID uuid.UUID, deviceUUID uuid.UUID, signature string) error
+}
+
+// Интерфейс для регистрации ключа подписи устройства
+type DeviceSigningKeySetter interface {
+	// SetSigningKey регистрирует ключ подписи активного устройства, добавленного без него
+	SetSigningKey(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, signingKey string, signature string) error
+}
+
+// DeviceResponse — устройство пользователя.
+// swagger:model DeviceResponse
+type DeviceResponse struct {
+	// UUID устройства
+	DeviceUUID string `json:"device_uuid"`
+	// Название устройства
+	Name string `json:"name"`
+	// Публичный ключ устройства
+	PublicKey string `json:"public_key"`
+	// Отпечаток публичного ключа устройства
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Публичный ключ подписи устройства
+	SigningKey string `json:"signing_key,omitempty"`
+	// Статус устройства: active или pending (ожидает подтверждения)
+	Status string `json:"status"`
+	// UUID устройства, подтвердившего это устройство
+	ApprovedBy string `json:"approved_by,omitempty"`
+	// Подпись подтверждения ключом подписи устройства approved_by
+	ApprovalSignature string `json:"approval_signature,omitempty"`
+	// Признак устройства, с которого выполнен запрос
+	Current bool `json:"current"`
+	// Время добавления устройства
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// DevicesResponse — список устройств пользователя.
+// swagger:model DevicesResponse
+type DevicesResponse struct {
+	// UUID текущего пользователя
+	UserUUID string `json:"user_uuid"`
+	// Неотозванные устройства
+	Devices []DeviceResponse `json:"devices"`
+}
+
+// ApproveDeviceRequest — тело запроса на подтверждение устройства.
+// swagger:model ApproveDeviceRequest
+type ApproveDeviceRequest struct {
+	// Подпись Ed25519 (base64) ключом подписи текущего устройства над UUID и ключами подтверждаемого устройства
+	// required: true
+	Signature string `json:"signature"`
+}
+
+// SetSigningKeyRequest — тело запроса на регистрацию ключа подписи устройства.
+// swagger:model SetSigningKeyRequest
+type SetSigningKeyRequest struct {
+	// Публичный ключ подписи Ed25519 текущего устройства (base64)
+	// required: true
+	SigningKey string `json:"signing_key"`
+	// Подпись Ed25519 (base64) этим ключом над UUID и ключами текущего устройства
+	// required: true
+	Signature string `json:"signature"`
+}
+
+// RenameDeviceRequest — тело запроса на переименование устройства.
+// swagger:model RenameDeviceRequest
+type RenameDeviceRequest struct {
+	// Новое название устройства; пустая строка сбрасывает название
+	// example: Рабочий ноутбук
+	Name string `json:"name"`
+}
+
+// ListDevicesHandler возвращает устройства текущего пользователя
+// @Summary Список устройств
+// @Description Возвращает неотозванные устройства текущего пользователя в порядке добавления,
+// @Description включая устройства, ожидающие подтверждения (status = pending).
+// @Description Устройство, с которого выполнен запрос, отмечено полем current.
+// @Tags Auth
+// @Accept plain
+// @Produce json
+// @Success 200 {object} DevicesResponse "Устройства пользователя"
+// @Failure 401 "Неавторизован"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /auth/devices [get]
+func ListDevicesHandler(svc DeviceLister, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, deviceUUID, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		devices, err := svc.ListDevices(r.Context(), userUUID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp := DevicesResponse{
+			UserUUID: userUUID.String(),
+			Devices:  make([]DeviceResponse, 0, len(devices)),
+		}
+		for _, device := range devices {
+			item := DeviceResponse{
+				DeviceUUID:        device.DeviceUUID.String(),
+				Name:              device.Name,
+				PublicKey:         device.PublicKey,
+				Fingerprint:       deviceFingerprint(device.PublicKey),
+				SigningKey:        device.SigningKey,
+				Status:            device.Status,
+				ApprovalSignature: device.ApprovalSignature,
+				Current:           device.DeviceUUID == deviceUUID,
+				CreatedAt:         device.CreatedAt,
+			}
+			if device.ApprovedBy != nil {
+				item.ApprovedBy = device.ApprovedBy.String()
+			}
+			resp.Devices = append(resp.Devices, item)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// RenameDeviceHandler задаёт название устройства
+// @Summary Переименование устройства
+// @Description Задаёт человекочитаемое название устройства текущего пользователя (не длиннее 64 символов).
+// @Tags Auth
+// @Accept json
+// @Produce plain
+// @Param device-uuid path string true "UUID устройства"
+// @Param request body RenameDeviceRequest true "Новое название"
+// @Success 200 "Устройство переименовано"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 404 "Устройство не найдено"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /auth/devices/{device-uuid} [patch]
+func RenameDeviceHandler(svc DeviceRenamer, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deviceUUID, err := uuid.Parse(chi.URLParam(r, "device-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req RenameDeviceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.RenameDevice(r.Context(), userUUID, deviceUUID, req.Name); err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidDeviceName):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, services.ErrDeviceNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// RevokeDeviceHandler отзывает устройство
+// @Summary Отзыв устройства
+// @Description Отзывает устройство текущего пользователя: вход с него становится невозможен,
+// @Description все его access- и refresh-токены отзываются, WebSocket-соединения закрываются,
+// @Description а зашифрованные для него ключи комнат удаляются. Во всех комнатах пользователя начинается новая эпоха ключа.
+// @Description Отозвать можно и текущее устройство — это равносильно выходу с него без возможности повторного входа.
+// @Tags Auth
+// @Accept plain
+// @Produce plain
+// @Param device-uuid path string true "UUID устройства"
+// @Success 200 "Устройство отозвано"
+// @Failure 400 "Некорректный UUID устройства"
+// @Failure 401 "Неавторизован"
+// @Failure 404 "Устройство не найдено"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /auth/devices/{device-uuid} [delete]
+func RevokeDeviceHandler(svc DeviceRevoker, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deviceUUID, err := uuid.Parse(chi.URLParam(r, "device-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.RevokeDevice(r.Context(), userUUID, deviceUUID); err != nil {
+			switch {
+			case errors.Is(err, services.ErrDeviceNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// ApproveDeviceHandler подтверждает новое устройство с текущего (доверенного) устройства
+// @Summary Подтверждение устройства
+// @Description Делает ожидающее устройство пользователя активным. Запрос выполняется с уже активного устройства
+// @Description того же пользователя, у которого есть ключ подписи; тело содержит подпись этим ключом над UUID
+// @Description и ключами подтверждаемого устройства. Подпись проверяется и сохраняется вместе с устройством.
+// @Tags Auth
+// @Accept json
+// @Produce plain
+// @Param device-uuid path string true "UUID подтверждаемого устройства"
+// @Param request body ApproveDeviceRequest true "Подпись подтверждения"
+// @Success 200 "Устройство подтверждено"
+// @Failure 400 "Некорректные данные запроса или неверная подпись"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Текущее устройство не может подтверждать устройства"
+// @Failure 404 "Устройство не найдено"
+// @Failure 409 "Устройство не ожидает подтверждения"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /auth/devices/{device-uuid}/approve [post]
+func ApproveDeviceHandler(svc DeviceApprover, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deviceUUID, err := uuid.Parse(chi.URLParam(r, "device-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req ApproveDeviceRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Signature == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, approverUUID, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.ApproveDevice(r.Context(), userUUID, approverUUID, deviceUUID, req.Signature); err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidApprovalSignature):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			case errors.Is(err, services.ErrDeviceNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, services.ErrDeviceNotPending):
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// SetSigningKeyHandler регистрирует ключ подписи текущего устройства
+// @Summary Регистрация ключа подписи устройства
+// @Description Сохраняет ключ подписи активного устройства, добавленного до появления ключей подписи.
+// @Description Пока у пользователя есть активное устройство, новые устройства ожидают подтверждения,
+// @Description а подтверждать их может только устройство с ключом подписи. Тело содержит ключ и подпись
+// @Description этим ключом над UUID и ключами текущего устройства, подтверждающую владение ключом.
+// @Tags Auth
+// @Accept json
+// @Produce plain
+// @Param request body SetSigningKeyRequest true "Ключ подписи и подпись"
+// @Success 200 "Ключ подписи сохранён"
+// @Failure 400 "Некорректные данные запроса, ключ или подпись"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Текущее устройство не активно"
+// @Failure 404 "Устройство не найдено"
+// @Failure 409 "Ключ подписи уже задан"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /auth/device/signing-key [put]
+func SetSigningKeyHandler(svc DeviceSigningKeySetter, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SetSigningKeyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.SigningKey == "" || req.Signature == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, deviceUUID, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.SetSigningKey(r.Context(), userUUID, deviceUUID, req.SigningKey, req.Signature); err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidDeviceKey), errors.Is(err, services.ErrInvalidApprovalSignature):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			case errors.Is(err, services.ErrDeviceNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, services.ErrSigningKeyAlreadySet):
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// deviceFingerprint возвращает отпечаток публичного ключа устройства или пустую строку,
+// если ключ сохранён до введения проверки формата и не разбирается
+func deviceFingerprint(publicKey string) string {
+	fingerprint, err := e2ee.Fingerprint(publicKey)
+	if err != nil {
+		return ""
+	}
+	return fingerprint
+}
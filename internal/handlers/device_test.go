@@ -0,0 +1,497 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListDevicesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockDeviceLister(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	currentUUID := uuid.New()
+	otherUUID := uuid.New()
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	devices := []models.UserDeviceDB{
+		{DeviceUUID: currentUUID, UserUUID: userUUID, PublicKey: "key-1", SigningKey: "sign-1", Status: models.DeviceStatusActive, Name: "Ноутбук", CreatedAt: createdAt},
+		{DeviceUUID: otherUUID, UserUUID: userUUID, PublicKey: "key-2", SigningKey: "sign-2", Status: models.DeviceStatusActive, ApprovedBy: &currentUUID, ApprovalSignature: "sig", CreatedAt: createdAt},
+	}
+
+	tests := []struct {
+		name           string
+		expectedStatus int
+		expectedBody   *DevicesResponse
+		setup          func()
+	}{
+		{
+			name:           "success",
+			expectedStatus: http.StatusOK,
+			expectedBody: &DevicesResponse{UserUUID: userUUID.String(), Devices: []DeviceResponse{
+				{DeviceUUID: currentUUID.String(), Name: "Ноутбук", PublicKey: "key-1", SigningKey: "sign-1", Status: models.DeviceStatusActive, Current: true, CreatedAt: createdAt},
+				{DeviceUUID: otherUUID.String(), PublicKey: "key-2", SigningKey: "sign-2", Status: models.DeviceStatusActive, ApprovedBy: currentUUID.String(), ApprovalSignature: "sig", CreatedAt: createdAt},
+			}},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, currentUUID, nil)
+				mockSvc.EXPECT().ListDevices(gomock.Any(), userUUID).Return(devices, nil)
+			},
+		},
+		{
+			name:           "unauthorized",
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "internal error",
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, currentUUID, nil)
+				mockSvc.EXPECT().ListDevices(gomock.Any(), userUUID).Return(nil, errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			req := httptest.NewRequest("GET", "/auth/devices", nil)
+			w := httptest.NewRecorder()
+			ListDevicesHandler(mockSvc, mockParser).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != nil {
+				var resp DevicesResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, *tt.expectedBody, resp)
+			}
+		})
+	}
+}
+
+func TestRenameDeviceHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockDeviceRenamer(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	authorized := func() {
+		mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+		mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+	}
+
+	tests := []struct {
+		name           string
+		deviceID       string
+		body           string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			deviceID:       deviceUUID.String(),
+			body:           `{"name":"Ноутбук"}`,
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().RenameDevice(gomock.Any(), userUUID, deviceUUID, "Ноутбук").Return(nil)
+			},
+		},
+		{
+			name:           "invalid device uuid",
+			deviceID:       "invalid",
+			body:           `{"name":"Ноутбук"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid body",
+			deviceID:       deviceUUID.String(),
+			body:           `invalid`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unauthorized",
+			deviceID:       deviceUUID.String(),
+			body:           `{"name":"Ноутбук"}`,
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "invalid name",
+			deviceID:       deviceUUID.String(),
+			body:           `{"name":"x"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().RenameDevice(gomock.Any(), userUUID, deviceUUID, "x").Return(services.ErrInvalidDeviceName)
+			},
+		},
+		{
+			name:           "not found",
+			deviceID:       deviceUUID.String(),
+			body:           `{"name":"Ноутбук"}`,
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().RenameDevice(gomock.Any(), userUUID, deviceUUID, "Ноутбук").Return(services.ErrDeviceNotFound)
+			},
+		},
+		{
+			name:           "internal error",
+			deviceID:       deviceUUID.String(),
+			body:           `{"name":"Ноутбук"}`,
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().RenameDevice(gomock.Any(), userUUID, deviceUUID, "Ноутбук").Return(errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Patch("/auth/devices/{device-uuid}", RenameDeviceHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("PATCH", "/auth/devices/"+tt.deviceID, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestRevokeDeviceHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockDeviceRevoker(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	authorized := func() {
+		mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+		mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+	}
+
+	tests := []struct {
+		name           string
+		deviceID       string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			deviceID:       deviceUUID.String(),
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().RevokeDevice(gomock.Any(), userUUID, deviceUUID).Return(nil)
+			},
+		},
+		{
+			name:           "invalid device uuid",
+			deviceID:       "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unauthorized",
+			deviceID:       deviceUUID.String(),
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "not found",
+			deviceID:       deviceUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().RevokeDevice(gomock.Any(), userUUID, deviceUUID).Return(services.ErrDeviceNotFound)
+			},
+		},
+		{
+			name:           "internal error",
+			deviceID:       deviceUUID.String(),
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().RevokeDevice(gomock.Any(), userUUID, deviceUUID).Return(errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Delete("/auth/devices/{device-uuid}", RevokeDeviceHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("DELETE", "/auth/devices/"+tt.deviceID, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestApproveDeviceHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockDeviceApprover(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	approverUUID := uuid.New()
+	deviceUUID := uuid.New()
+	body := `{"signature":"sig"}`
+
+	authorized := func() {
+		mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+		mockParser.EXPECT().Parse("token").Return(userUUID, approverUUID, nil)
+	}
+
+	tests := []struct {
+		name           string
+		deviceID       string
+		body           string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			deviceID:       deviceUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ApproveDevice(gomock.Any(), userUUID, approverUUID, deviceUUID, "sig").Return(nil)
+			},
+		},
+		{
+			name:           "invalid device uuid",
+			deviceID:       "invalid",
+			body:           body,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "missing signature",
+			deviceID:       deviceUUID.String(),
+			body:           `{}`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unauthorized",
+			deviceID:       deviceUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "invalid signature",
+			deviceID:       deviceUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ApproveDevice(gomock.Any(), userUUID, approverUUID, deviceUUID, "sig").Return(services.ErrInvalidApprovalSignature)
+			},
+		},
+		{
+			name:           "forbidden",
+			deviceID:       deviceUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ApproveDevice(gomock.Any(), userUUID, approverUUID, deviceUUID, "sig").Return(services.ErrForbidden)
+			},
+		},
+		{
+			name:           "not found",
+			deviceID:       deviceUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ApproveDevice(gomock.Any(), userUUID, approverUUID, deviceUUID, "sig").Return(services.ErrDeviceNotFound)
+			},
+		},
+		{
+			name:           "not pending",
+			deviceID:       deviceUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusConflict,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ApproveDevice(gomock.Any(), userUUID, approverUUID, deviceUUID, "sig").Return(services.ErrDeviceNotPending)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Post("/auth/devices/{device-uuid}/approve", ApproveDeviceHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("POST", "/auth/devices/"+tt.deviceID+"/approve", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestSetSigningKeyHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockDeviceSigningKeySetter(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	body := `{"signing_key":"key","signature":"sig"}`
+
+	authorized := func() {
+		mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+		mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			body:           body,
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().SetSigningKey(gomock.Any(), userUUID, deviceUUID, "key", "sig").Return(nil)
+			},
+		},
+		{
+			name:           "missing signature",
+			body:           `{"signing_key":"key"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unauthorized",
+			body:           body,
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "invalid signing key",
+			body:           body,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().SetSigningKey(gomock.Any(), userUUID, deviceUUID, "key", "sig").Return(services.ErrInvalidDeviceKey)
+			},
+		},
+		{
+			name:           "invalid signature",
+			body:           body,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().SetSigningKey(gomock.Any(), userUUID, deviceUUID, "key", "sig").Return(services.ErrInvalidApprovalSignature)
+			},
+		},
+		{
+			name:           "forbidden",
+			body:           body,
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().SetSigningKey(gomock.Any(), userUUID, deviceUUID, "key", "sig").Return(services.ErrForbidden)
+			},
+		},
+		{
+			name:           "not found",
+			body:           body,
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().SetSigningKey(gomock.Any(), userUUID, deviceUUID, "key", "sig").Return(services.ErrDeviceNotFound)
+			},
+		},
+		{
+			name:           "already set",
+			body:           body,
+			expectedStatus: http.StatusConflict,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().SetSigningKey(gomock.Any(), userUUID, deviceUUID, "key", "sig").Return(services.ErrSigningKeyAlreadySet)
+			},
+		},
+		{
+			name:           "internal error",
+			body:           body,
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().SetSigningKey(gomock.Any(), userUUID, deviceUUID, "key", "sig").Return(errors.New("db error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			req := httptest.NewRequest("PUT", "/auth/device/signing-key", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			SetSigningKeyHandler(mockSvc, mockParser)(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,489 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+)
+
+// Интерфейсы для работы с приглашениями в комнаты
+type RoomInviter interface {
+	// Invite приглашает пользователя в комнату от имени пользователя actorUUID
+	Invite(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, inviteeUUID uuid.UUID) (*models.RoomInviteDB, error)
+}
+
+type InviteLister interface {
+	// ListInvites возвращает ожидающие ответа приглашения пользователя
+	ListInvites(ctx context.Context, userUUID uuid.UUID) ([]models.RoomInviteDetailsDB, error)
+}
+
+type InviteAcceptor interface {
+	// AcceptInvite принимает приглашение и возвращает UUID комнаты
+	AcceptInvite(ctx context.Context, userUUID uuid.UUID, inviteUUID uuid.UUID) (uuid.UUID, error)
+}
+
+type InviteDecliner interface {
+	// DeclineInvite отклоняет приглашение
+	DeclineInvite(ctx context.Context, userUUID uuid.UUID, inviteUUID uuid.UUID) error
+}
+
+type InviteLinkCreator interface {
+	// CreateInviteLink создаёт ссылку-приглашение в комнату от имени пользователя actorUUID
+	CreateInviteLink(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, ttl time.Duration, maxUses int) (*models.RoomInviteLinkDB, error)
+}
+
+type InviteLinkJoiner interface {
+	// JoinByLink добавляет пользователя в комнату по ссылке-приглашению и возвращает UUID комнаты
+	JoinByLink(ctx context.Context, userUUID uuid.UUID, linkUUID uuid.UUID) (uuid.UUID, error)
+}
+
+type InviteLinkRevoker interface {
+	// RevokeInviteLink удаляет ссылку-приглашение от имени пользователя actorUUID
+	RevokeInviteLink(ctx context.Context, actorUUID uuid.UUID, linkUUID uuid.UUID) error
+}
+
+// InviteRequest представляет JSON тело запроса на приглашение пользователя в комнату.
+// swagger:model InviteRequest
+type InviteRequest struct {
+	// UUID приглашаемого пользователя
+	// required: true
+	MemberUUID string `json:"member_uuid"`
+}
+
+// InviteResponse — приглашение в комнату.
+// swagger:model InviteResponse
+type InviteResponse struct {
+	// UUID приглашения
+	InviteUUID string `json:"invite_uuid"`
+	// UUID комнаты
+	RoomUUID string `json:"room_uuid"`
+	// Название комнаты
+	RoomName string `json:"room_name,omitempty"`
+	// UUID пригласившего пользователя
+	InviterUUID string `json:"inviter_uuid"`
+	// Имя пригласившего пользователя
+	InviterUsername string `json:"inviter_username,omitempty"`
+	// UUID приглашённого пользователя
+	InviteeUUID string `json:"invitee_uuid"`
+	// Время создания приглашения
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InvitesResponse — ожидающие ответа приглашения пользователя.
+// swagger:model InvitesResponse
+type InvitesResponse struct {
+	// Приглашения, начиная с самых новых
+	Invites []InviteResponse `json:"invites"`
+}
+
+// InviteLinkRequest представляет JSON тело запроса на создание ссылки-приглашения.
+// swagger:model InviteLinkRequest
+type InviteLinkRequest struct {
+	// Срок действия ссылки в секундах (по умолчанию сутки, не более 30 дней)
+	// example: 3600
+	ExpiresIn int64 `json:"expires_in,omitempty"`
+	// Максимальное число входов по ссылке (0 — без ограничения)
+	// example: 10
+	MaxUses int `json:"max_uses,omitempty"`
+}
+
+// InviteLinkResponse — ссылка-приглашение в комнату.
+// swagger:model InviteLinkResponse
+type InviteLinkResponse struct {
+	// UUID ссылки, по которому входят в комнату
+	LinkUUID string `json:"link_uuid"`
+	// UUID комнаты
+	RoomUUID string `json:"room_uuid"`
+	// Время, после которого ссылка недействительна
+	ExpiresAt time.Time `json:"expires_at"`
+	// Максимальное число входов по ссылке (0 — без ограничения)
+	MaxUses int `json:"max_uses"`
+}
+
+// InviteChatMemberHandler приглашает пользователя в комнату
+// @Summary Приглашение пользователя в комнату
+// @Description Создаёт приглашение пользователя member_uuid в групповую комнату. Доступно владельцу и администраторам.
+// @Description Пользователь становится участником, только приняв приглашение. Повторное приглашение,
+// @Description пока на прежнее не ответили, возвращает прежнее приглашение.
+// @Tags Invites
+// @Accept json
+// @Produce json
+// @Param room-uuid path string true "UUID комнаты"
+// @Param request body InviteRequest true "Приглашаемый пользователь"
+// @Success 200 {object} InviteResponse "Приглашение"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Недостаточно прав или комната личная"
+// @Failure 404 "Комната или пользователь не найдены"
+// @Failure 409 "Пользователь уже состоит в комнате"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid}/invites [post]
+func InviteChatMemberHandler(svc RoomInviter, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomUUID, err := uuid.Parse(chi.URLParam(r, "room-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req InviteRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		memberUUID, err := uuid.Parse(req.MemberUUID)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		invite, err := svc.Invite(r.Context(), userUUID, roomUUID, memberUUID)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrUserNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			case errors.Is(err, services.ErrAlreadyMember):
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(newInviteResponse(*invite))
+	}
+}
+
+// ListInvitesHandler возвращает приглашения текущего пользователя
+// @Summary Список приглашений пользователя
+// @Description Возвращает приглашения в комнаты, на которые пользователь ещё не ответил.
+// @Tags Invites
+// @Accept plain
+// @Produce json
+// @Success 200 {object} InvitesResponse "Приглашения пользователя"
+// @Failure 401 "Неавторизован"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /invites [get]
+func ListInvitesHandler(svc InviteLister, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		invites, err := svc.ListInvites(r.Context(), userUUID)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		resp := InvitesResponse{Invites: make([]InviteResponse, 0, len(invites))}
+		for _, invite := range invites {
+			item := newInviteResponse(invite.RoomInviteDB)
+			item.RoomName = invite.RoomName
+			item.InviterUsername = invite.InviterUsername
+			resp.Invites = append(resp.Invites, item)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// AcceptInviteHandler принимает приглашение в комнату
+// @Summary Принятие приглашения
+// @Description Принимает приглашение текущего пользователя и добавляет его в комнату; начинается новая эпоха ключа комнаты.
+// @Tags Invites
+// @Accept plain
+// @Produce plain
+// @Param invite-uuid path string true "UUID приглашения"
+// @Success 200 {string} string "UUID комнаты"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 404 "Приглашение не найдено или на него уже ответили"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /invites/{invite-uuid}/accept [post]
+func AcceptInviteHandler(svc InviteAcceptor, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inviteUUID, err := uuid.Parse(chi.URLParam(r, "invite-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		roomUUID, err := svc.AcceptInvite(r.Context(), userUUID, inviteUUID)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInviteNotFound), errors.Is(err, services.ErrRoomNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Write([]byte(roomUUID.String()))
+	}
+}
+
+// DeclineInviteHandler отклоняет приглашение в комнату
+// @Summary Отклонение приглашения
+// @Description Отклоняет приглашение текущего пользователя.
+// @Tags Invites
+// @Accept plain
+// @Produce plain
+// @Param invite-uuid path string true "UUID приглашения"
+// @Success 200 "Приглашение отклонено"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 404 "Приглашение не найдено или на него уже ответили"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /invites/{invite-uuid}/decline [post]
+func DeclineInviteHandler(svc InviteDecliner, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		inviteUUID, err := uuid.Parse(chi.URLParam(r, "invite-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.DeclineInvite(r.Context(), userUUID, inviteUUID); err != nil {
+			switch {
+			case errors.Is(err, services.ErrInviteNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// CreateInviteLinkHandler создаёт ссылку-приглашение в комнату
+// @Summary Создание ссылки-приглашения
+// @Description Создаёт ссылку-приглашение в групповую комнату со сроком действия и ограничением числа входов.
+// @Description Доступно владельцу и администраторам. Пустое тело создаёт ссылку на сутки без ограничения числа входов.
+// @Tags Invites
+// @Accept json
+// @Produce json
+// @Param room-uuid path string true "UUID комнаты"
+// @Param request body InviteLinkRequest false "Параметры ссылки"
+// @Success 200 {object} InviteLinkResponse "Ссылка-приглашение"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Недостаточно прав или комната личная"
+// @Failure 404 "Комната не найдена"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid}/invite-links [post]
+func CreateInviteLinkHandler(svc InviteLinkCreator, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomUUID, err := uuid.Parse(chi.URLParam(r, "room-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req InviteLinkRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		link, err := svc.CreateInviteLink(r.Context(), userUUID, roomUUID, time.Duration(req.ExpiresIn)*time.Second, req.MaxUses)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidInviteLink):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, services.ErrRoomNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(InviteLinkResponse{
+			LinkUUID:  link.LinkUUID.String(),
+			RoomUUID:  link.RoomUUID.String(),
+			ExpiresAt: link.ExpiresAt,
+			MaxUses:   link.MaxUses,
+		})
+	}
+}
+
+// JoinByInviteLinkHandler добавляет текущего пользователя в комнату по ссылке-приглашению
+// @Summary Вход в комнату по ссылке-приглашению
+// @Description Добавляет текущего пользователя в комнату по ссылке-приглашению и возвращает UUID комнаты.
+// @Description Участнику комнаты возвращается UUID комнаты без учёта входа по ссылке.
+// @Tags Invites
+// @Accept plain
+// @Produce plain
+// @Param link-uuid path string true "UUID ссылки-приглашения"
+// @Success 200 {string} string "UUID комнаты"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 404 "Ссылка не найдена"
+// @Failure 410 "Срок действия ссылки истёк или входы по ней закончились"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /invites/links/{link-uuid} [post]
+func JoinByInviteLinkHandler(svc InviteLinkJoiner, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		linkUUID, err := uuid.Parse(chi.URLParam(r, "link-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		roomUUID, err := svc.JoinByLink(r.Context(), userUUID, linkUUID)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInviteLinkNotFound), errors.Is(err, services.ErrRoomNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, services.ErrInviteLinkExpired):
+				w.WriteHeader(http.StatusGone)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Write([]byte(roomUUID.String()))
+	}
+}
+
+// RevokeInviteLinkHandler отзывает ссылку-приглашение
+// @Summary Отзыв ссылки-приглашения
+// @Description Удаляет ссылку-приглашение: входить по ней больше нельзя. Доступно владельцу и администраторам комнаты.
+// @Tags Invites
+// @Accept plain
+// @Produce plain
+// @Param link-uuid path string true "UUID ссылки-приглашения"
+// @Success 200 "Ссылка отозвана"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Недостаточно прав"
+// @Failure 404 "Ссылка не найдена"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /invites/links/{link-uuid} [delete]
+func RevokeInviteLinkHandler(svc InviteLinkRevoker, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		linkUUID, err := uuid.Parse(chi.URLParam(r, "link-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.RevokeInviteLink(r.Context(), userUUID, linkUUID); err != nil {
+			switch {
+			case errors.Is(err, services.ErrInviteLinkNotFound), errors.Is(err, services.ErrRoomNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, services.ErrForbidden):
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// newInviteResponse преобразует запись приглашения в ответ API
+func newInviteResponse(invite models.RoomInviteDB) InviteResponse {
+	return InviteResponse{
+		InviteUUID:  invite.InviteUUID.String(),
+		RoomUUID:    invite.RoomUUID.String(),
+		InviterUUID: invite.InviterUUID.String(),
+		InviteeUUID: invite.InviteeUUID.String(),
+		CreatedAt:   invite.CreatedAt,
+	}
+}
@@ -0,0 +1,617 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInviteChatMemberHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockRoomInviter(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	roomUUID := uuid.New()
+	actorUUID := uuid.New()
+	memberUUID := uuid.New()
+	invite := &models.RoomInviteDB{InviteUUID: uuid.New(), RoomUUID: roomUUID, InviterUUID: actorUUID, InviteeUUID: memberUUID, Status: models.RoomInviteStatusPending}
+	body := `{"member_uuid":"` + memberUUID.String() + `"}`
+
+	authorized := func() {
+		mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+		mockParser.EXPECT().Parse("token").Return(actorUUID, uuid.New(), nil)
+	}
+
+	tests := []struct {
+		name           string
+		roomID         string
+		body           string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			roomID:         roomUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().Invite(gomock.Any(), actorUUID, roomUUID, memberUUID).Return(invite, nil)
+			},
+		},
+		{
+			name:           "invalid room uuid",
+			roomID:         "invalid",
+			body:           body,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid member uuid",
+			roomID:         roomUUID.String(),
+			body:           `{"member_uuid":"invalid"}`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unauthorized",
+			roomID:         roomUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "user not found",
+			roomID:         roomUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().Invite(gomock.Any(), actorUUID, roomUUID, memberUUID).Return(nil, services.ErrUserNotFound)
+			},
+		},
+		{
+			name:           "forbidden",
+			roomID:         roomUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().Invite(gomock.Any(), actorUUID, roomUUID, memberUUID).Return(nil, services.ErrForbidden)
+			},
+		},
+		{
+			name:           "already member",
+			roomID:         roomUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusConflict,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().Invite(gomock.Any(), actorUUID, roomUUID, memberUUID).Return(nil, services.ErrAlreadyMember)
+			},
+		},
+		{
+			name:           "internal error",
+			roomID:         roomUUID.String(),
+			body:           body,
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().Invite(gomock.Any(), actorUUID, roomUUID, memberUUID).Return(nil, errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Post("/chat/{room-uuid}/invites", InviteChatMemberHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("POST", "/chat/"+tt.roomID+"/invites", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				var resp InviteResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, invite.InviteUUID.String(), resp.InviteUUID)
+				assert.Equal(t, memberUUID.String(), resp.InviteeUUID)
+			}
+		})
+	}
+}
+
+func TestListInvitesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockInviteLister(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	createdAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	invite := models.RoomInviteDetailsDB{
+		RoomInviteDB:    models.RoomInviteDB{InviteUUID: uuid.New(), RoomUUID: uuid.New(), InviterUUID: uuid.New(), InviteeUUID: userUUID, CreatedAt: createdAt},
+		RoomName:        "Команда",
+		InviterUsername: "alice",
+	}
+
+	tests := []struct {
+		name           string
+		expectedStatus int
+		expectedBody   *InvitesResponse
+		setup          func()
+	}{
+		{
+			name:           "success",
+			expectedStatus: http.StatusOK,
+			expectedBody: &InvitesResponse{Invites: []InviteResponse{{
+				InviteUUID:      invite.InviteUUID.String(),
+				RoomUUID:        invite.RoomUUID.String(),
+				RoomName:        "Команда",
+				InviterUUID:     invite.InviterUUID.String(),
+				InviterUsername: "alice",
+				InviteeUUID:     userUUID.String(),
+				CreatedAt:       createdAt,
+			}}},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListInvites(gomock.Any(), userUUID).Return([]models.RoomInviteDetailsDB{invite}, nil)
+			},
+		},
+		{
+			name:           "no invites",
+			expectedStatus: http.StatusOK,
+			expectedBody:   &InvitesResponse{Invites: []InviteResponse{}},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListInvites(gomock.Any(), userUUID).Return(nil, nil)
+			},
+		},
+		{
+			name:           "unauthorized",
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "internal error",
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListInvites(gomock.Any(), userUUID).Return(nil, errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			req := httptest.NewRequest("GET", "/invites", nil)
+			w := httptest.NewRecorder()
+			ListInvitesHandler(mockSvc, mockParser).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != nil {
+				var resp InvitesResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, *tt.expectedBody, resp)
+			}
+		})
+	}
+}
+
+func TestAcceptInviteHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockInviteAcceptor(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	inviteUUID := uuid.New()
+	roomUUID := uuid.New()
+
+	tests := []struct {
+		name           string
+		inviteID       string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			inviteID:       inviteUUID.String(),
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().AcceptInvite(gomock.Any(), userUUID, inviteUUID).Return(roomUUID, nil)
+			},
+		},
+		{
+			name:           "invalid invite uuid",
+			inviteID:       "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unauthorized",
+			inviteID:       inviteUUID.String(),
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "not found",
+			inviteID:       inviteUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().AcceptInvite(gomock.Any(), userUUID, inviteUUID).Return(uuid.Nil, services.ErrInviteNotFound)
+			},
+		},
+		{
+			name:           "internal error",
+			inviteID:       inviteUUID.String(),
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().AcceptInvite(gomock.Any(), userUUID, inviteUUID).Return(uuid.Nil, errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Post("/invites/{invite-uuid}/accept", AcceptInviteHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("POST", "/invites/"+tt.inviteID+"/accept", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, roomUUID.String(), w.Body.String())
+			}
+		})
+	}
+}
+
+func TestDeclineInviteHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockInviteDecliner(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	inviteUUID := uuid.New()
+
+	tests := []struct {
+		name           string
+		inviteID       string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			inviteID:       inviteUUID.String(),
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().DeclineInvite(gomock.Any(), userUUID, inviteUUID).Return(nil)
+			},
+		},
+		{
+			name:           "invalid invite uuid",
+			inviteID:       "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "not found",
+			inviteID:       inviteUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().DeclineInvite(gomock.Any(), userUUID, inviteUUID).Return(services.ErrInviteNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Post("/invites/{invite-uuid}/decline", DeclineInviteHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("POST", "/invites/"+tt.inviteID+"/decline", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestCreateInviteLinkHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockInviteLinkCreator(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	roomUUID := uuid.New()
+	actorUUID := uuid.New()
+	expiresAt := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	link := &models.RoomInviteLinkDB{LinkUUID: uuid.New(), RoomUUID: roomUUID, ExpiresAt: expiresAt, MaxUses: 10}
+
+	authorized := func() {
+		mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+		mockParser.EXPECT().Parse("token").Return(actorUUID, uuid.New(), nil)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			body:           `{"expires_in":3600,"max_uses":10}`,
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().CreateInviteLink(gomock.Any(), actorUUID, roomUUID, time.Hour, 10).Return(link, nil)
+			},
+		},
+		{
+			name:           "defaults without body",
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().CreateInviteLink(gomock.Any(), actorUUID, roomUUID, time.Duration(0), 0).Return(link, nil)
+			},
+		},
+		{
+			name:           "invalid body",
+			body:           `invalid`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid parameters",
+			body:           `{"max_uses":-1}`,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().CreateInviteLink(gomock.Any(), actorUUID, roomUUID, time.Duration(0), -1).Return(nil, services.ErrInvalidInviteLink)
+			},
+		},
+		{
+			name:           "forbidden",
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().CreateInviteLink(gomock.Any(), actorUUID, roomUUID, time.Duration(0), 0).Return(nil, services.ErrForbidden)
+			},
+		},
+		{
+			name:           "room not found",
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().CreateInviteLink(gomock.Any(), actorUUID, roomUUID, time.Duration(0), 0).Return(nil, services.ErrRoomNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Post("/chat/{room-uuid}/invite-links", CreateInviteLinkHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("POST", "/chat/"+roomUUID.String()+"/invite-links", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				var resp InviteLinkResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, InviteLinkResponse{LinkUUID: link.LinkUUID.String(), RoomUUID: roomUUID.String(), ExpiresAt: expiresAt, MaxUses: 10}, resp)
+			}
+		})
+	}
+}
+
+func TestJoinByInviteLinkHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockInviteLinkJoiner(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	linkUUID := uuid.New()
+	roomUUID := uuid.New()
+
+	tests := []struct {
+		name           string
+		linkID         string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			linkID:         linkUUID.String(),
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().JoinByLink(gomock.Any(), userUUID, linkUUID).Return(roomUUID, nil)
+			},
+		},
+		{
+			name:           "invalid link uuid",
+			linkID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "not found",
+			linkID:         linkUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().JoinByLink(gomock.Any(), userUUID, linkUUID).Return(uuid.Nil, services.ErrInviteLinkNotFound)
+			},
+		},
+		{
+			name:           "expired",
+			linkID:         linkUUID.String(),
+			expectedStatus: http.StatusGone,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().JoinByLink(gomock.Any(), userUUID, linkUUID).Return(uuid.Nil, services.ErrInviteLinkExpired)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Post("/invites/links/{link-uuid}", JoinByInviteLinkHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("POST", "/invites/links/"+tt.linkID, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				assert.Equal(t, roomUUID.String(), w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRevokeInviteLinkHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockInviteLinkRevoker(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	linkUUID := uuid.New()
+
+	tests := []struct {
+		name           string
+		linkID         string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			linkID:         linkUUID.String(),
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().RevokeInviteLink(gomock.Any(), userUUID, linkUUID).Return(nil)
+			},
+		},
+		{
+			name:           "invalid link uuid",
+			linkID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unauthorized",
+			linkID:         linkUUID.String(),
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "forbidden",
+			linkID:         linkUUID.String(),
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().RevokeInviteLink(gomock.Any(), userUUID, linkUUID).Return(services.ErrForbidden)
+			},
+		},
+		{
+			name:           "not found",
+			linkID:         linkUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().RevokeInviteLink(gomock.Any(), userUUID, linkUUID).Return(services.ErrInviteLinkNotFound)
+			},
+		},
+		{
+			name:           "service error",
+			linkID:         linkUUID.String(),
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().RevokeInviteLink(gomock.Any(), userUUID, linkUUID).Return(errors.New("db error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Delete("/invites/links/{link-uuid}", RevokeInviteLinkHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest(http.MethodDelete, "/invites/links/"+tt.linkID, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+)
+
+// Интерфейс для чтения истории сообщений комнаты
+type MessageLister interface {
+	// ListMessages возвращает страницу истории комнаты между сообщениями before и after (uuid.Nil — без ограничения)
+	ListMessages(ctx context.Context, userUUID uuid.UUID, roomUUID uuid.UUID, before uuid.UUID, after uuid.UUID, limit int) ([]models.RoomMessageDB, bool, error)
+}
+
+// MessageResponse — зашифрованное сообщение из истории комнаты.
+// swagger:model MessageResponse
+type MessageResponse struct {
+	// UUID сообщения
+	MessageUUID string `json:"message_uuid"`
+	// UUID комнаты
+	RoomUUID string `json:"room_uuid"`
+	// UUID отправителя
+	SenderUUID string `json:"sender_uuid"`
+	// UUID устройства отправителя
+	SenderDevice string `json:"sender_device,omitempty"`
+	// Время приёма сообщения сервером
+	SentAt time.Time `json:"sent_at"`
+	// Эпоха ключа комнаты, которым зашифровано сообщение
+	KeyEpoch int64 `json:"key_epoch"`
+	// Шифртекст сообщения
+	Ciphertext string `json:"ciphertext"`
+}
+
+// MessagesResponse — страница истории сообщений комнаты.
+// swagger:model MessagesResponse
+type MessagesResponse struct {
+	// Сообщения в порядке отправки (от старых к новым)
+	Messages []MessageResponse `json:"messages"`
+	// Есть ли ещё сообщения в направлении чтения: более новые при заданном after, иначе более старые
+	HasMore bool `json:"has_more"`
+}
+
+// ListMessagesHandler возвращает страницу истории сообщений комнаты
+// @Summary История сообщений комнаты
+// @Description Возвращает зашифрованные сообщения комнаты в порядке отправки. Доступно только участникам комнаты.
+// @Description Без параметров возвращаются последние сообщения. С before — сообщения, отправленные до указанного,
+// @Description с after — после указанного; before и after можно задать одновременно.
+// @Description Чтобы листать историю назад, передайте в before UUID первого сообщения предыдущей страницы.
+// @Tags Chat
+// @Accept plain
+// @Produce json
+// @Param room-uuid path string true "UUID комнаты"
+// @Param before query string false "UUID сообщения: вернуть сообщения, отправленные до него"
+// @Param after query string false "UUID сообщения: вернуть сообщения, отправленные после него"
+// @Param limit query int false "Размер страницы (по умолчанию 50, не более 200)"
+// @Success 200 {object} MessagesResponse "Страница истории"
+// @Failure 400 "Некорректные параметры запроса или курсор не является сообщением комнаты"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Пользователь не состоит в комнате"
+// @Failure 404 "Комната не найдена"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid}/messages [get]
+func ListMessagesHandler(svc MessageLister, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomUUID, err := uuid.Parse(chi.URLParam(r, "room-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		var before, after uuid.UUID
+		if v := query.Get("before"); v != "" {
+			if before, err = uuid.Parse(v); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		if v := query.Get("after"); v != "" {
+			if after, err = uuid.Parse(v); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		var limit int
+		if v := query.Get("limit"); v != "" {
+			limit, err = strconv.Atoi(v)
+			if err != nil || limit <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		messages, hasMore, err := svc.ListMessages(r.Context(), userUUID, roomUUID, before, after, limit)
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrInvalidCursor):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, services.ErrUserNotInRoom):
+				w.WriteHeader(http.StatusForbidden)
+			case errors.Is(err, services.ErrRoomNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		resp := MessagesResponse{
+			Messages: make([]MessageResponse, 0, len(messages)),
+			HasMore:  hasMore,
+		}
+		for _, m := range messages {
+			item := MessageResponse{
+				MessageUUID: m.MessageUUID.String(),
+				RoomUUID:    m.RoomUUID.String(),
+				SenderUUID:  m.SenderUUID.String(),
+				SentAt:      m.SentAt,
+				KeyEpoch:    m.KeyEpoch,
+				Ciphertext:  m.Ciphertext,
+			}
+			if m.SenderDeviceUUID != uuid.Nil {
+				item.SenderDevice = m.SenderDeviceUUID.String()
+			}
+			resp.Messages = append(resp.Messages, item)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListMessagesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockMessageLister(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	cursorUUID := uuid.New()
+	sentAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	message := models.RoomMessageDB{
+		MessageUUID:      uuid.New(),
+		RoomUUID:         roomUUID,
+		SenderUUID:       userUUID,
+		SenderDeviceUUID: deviceUUID,
+		KeyEpoch:         2,
+		Ciphertext:       "ciphertext",
+		SentAt:           sentAt,
+	}
+
+	tests := []struct {
+		name           string
+		roomID         string
+		query          string
+		expectedStatus int
+		expectedBody   *MessagesResponse
+		setup          func()
+	}{
+		{
+			name:           "latest messages",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusOK,
+			expectedBody: &MessagesResponse{
+				Messages: []MessageResponse{{
+					MessageUUID:  message.MessageUUID.String(),
+					RoomUUID:     roomUUID.String(),
+					SenderUUID:   userUUID.String(),
+					SenderDevice: deviceUUID.String(),
+					SentAt:       sentAt,
+					KeyEpoch:     2,
+					Ciphertext:   "ciphertext",
+				}},
+				HasMore: true,
+			},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().ListMessages(gomock.Any(), userUUID, roomUUID, uuid.Nil, uuid.Nil, 0).
+					Return([]models.RoomMessageDB{message}, true, nil)
+			},
+		},
+		{
+			name:           "cursors and limit",
+			roomID:         roomUUID.String(),
+			query:          "?before=" + cursorUUID.String() + "&after=" + message.MessageUUID.String() + "&limit=10",
+			expectedStatus: http.StatusOK,
+			expectedBody:   &MessagesResponse{Messages: []MessageResponse{}},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().ListMessages(gomock.Any(), userUUID, roomUUID, cursorUUID, message.MessageUUID, 10).
+					Return(nil, false, nil)
+			},
+		},
+		{
+			name:           "invalid room UUID",
+			roomID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid before",
+			roomID:         roomUUID.String(),
+			query:          "?before=bad",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid after",
+			roomID:         roomUUID.String(),
+			query:          "?after=bad",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid limit",
+			roomID:         roomUUID.String(),
+			query:          "?limit=-1",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unauthorized",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "invalid cursor",
+			roomID:         roomUUID.String(),
+			query:          "?before=" + cursorUUID.String(),
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().ListMessages(gomock.Any(), userUUID, roomUUID, cursorUUID, uuid.Nil, 0).
+					Return(nil, false, services.ErrInvalidCursor)
+			},
+		},
+		{
+			name:           "not a member",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().ListMessages(gomock.Any(), userUUID, roomUUID, uuid.Nil, uuid.Nil, 0).
+					Return(nil, false, services.ErrUserNotInRoom)
+			},
+		},
+		{
+			name:           "room not found",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().ListMessages(gomock.Any(), userUUID, roomUUID, uuid.Nil, uuid.Nil, 0).
+					Return(nil, false, services.ErrRoomNotFound)
+			},
+		},
+		{
+			name:           "service error",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().ListMessages(gomock.Any(), userUUID, roomUUID, uuid.Nil, uuid.Nil, 0).
+					Return(nil, false, errors.New("db error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Get("/chat/{room-uuid}/messages", ListMessagesHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest(http.MethodGet, "/chat/"+tt.roomID+"/messages"+tt.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != nil {
+				var body MessagesResponse
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+				assert.Equal(t, *tt.expectedBody, body)
+			}
+		})
+	}
+}
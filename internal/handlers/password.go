@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/services"
+)
+
+// Интерфейс для смены пароля пользователя
+type PasswordChanger interface {
+	// ChangePassword меняет пароль, отзывает все сессии пользователя и начинает новую сессию устройства
+	ChangePassword(
+		ctx context.Context,
+		userUUID uuid.UUID,
+		deviceUUID uuid.UUID,
+		currentPassword string,
+		newPassword string,
+		ip string,
+	) (token string, refreshToken string, err error)
+}
+
+// ChangePasswordRequest — тело запроса на смену пароля.
+// swagger:model ChangePasswordRequest
+type ChangePasswordRequest struct {
+	// Текущий пароль пользователя
+	// required: true
+	CurrentPassword string `json:"current_password"`
+
+	// Новый пароль пользователя
+	// required: true
+	NewPassword string `json:"new_password"`
+}
+
+// ChangePasswordHandler меняет пароль текущего пользователя
+// @Summary Смена пароля
+// @Description Проверяет текущий пароль и политику паролей, сохраняет bcrypt-хэш нового пароля и отзывает
+// @Description все сессии пользователя на всех устройствах. Для текущего устройства начинается новая сессия:
+// @Description JWT возвращается в заголовке Authorization, refresh-токен — в заголовке X-Refresh-Token.
+// @Tags Auth
+// @Accept json
+// @Produce plain
+// @Param request body ChangePasswordRequest true "Текущий и новый пароль"
+// @Success 200 "Пароль изменён, новые токены возвращены в заголовках Authorization и X-Refresh-Token"
+// @Failure 400 "Некорректные данные запроса, неверный текущий пароль или новый пароль не соответствует политике"
+// @Failure 401 "Неавторизован"
+// @Failure 429 "Слишком много неудачных попыток; время ожидания в секундах — в заголовке Retry-After"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /auth/password [post]
+func ChangePasswordHandler(svc PasswordChanger, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, deviceUUID, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req ChangePasswordRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if req.CurrentPassword == "" || req.NewPassword == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		newToken, refreshToken, err := svc.ChangePassword(r.Context(), userUUID, deviceUUID, req.CurrentPassword, req.NewPassword, clientIP(r))
+		if err != nil {
+			if errors.Is(err, services.ErrInvalidCredentials) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if writePolicyError(w, err) {
+				return
+			}
+			if writeTooManyAttempts(w, err) {
+				return
+			}
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Authorization", "Bearer "+newToken)
+		w.Header().Set(RefreshTokenHeader, refreshToken)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// writePolicyError отвечает 400 с описанием нарушенного правила, если err — ошибка политики
+// имён пользователей или паролей, и сообщает, был ли записан ответ
+func writePolicyError(w http.ResponseWriter, err error) bool {
+	if errors.Is(err, services.ErrInvalidUsername) ||
+		errors.Is(err, services.ErrWeakPassword) ||
+		errors.Is(err, services.ErrBreachedPassword) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return true
+	}
+	return false
+}
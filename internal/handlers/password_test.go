@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangePasswordHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockPasswordChanger(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	body := `{"current_password":"old-secret-1","new_password":"new-secret-2"}`
+
+	authorized := func() {
+		mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+		mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+	}
+
+	tests := []struct {
+		name           string
+		body           string
+		setup          func()
+		expectedStatus int
+		expectedBody   string
+		expectTokens   bool
+	}{
+		{
+			name: "success",
+			body: body,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ChangePassword(gomock.Any(), userUUID, deviceUUID, "old-secret-1", "new-secret-2", gomock.Any()).
+					Return("new-token", "new-refresh", nil)
+			},
+			expectedStatus: http.StatusOK,
+			expectTokens:   true,
+		},
+		{
+			name: "unauthorized",
+			body: body,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+			expectedStatus: http.StatusUnauthorized,
+		},
+		{
+			name:           "invalid body",
+			body:           "not json",
+			setup:          authorized,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "missing new password",
+			body:           `{"current_password":"old-secret-1"}`,
+			setup:          authorized,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "wrong current password",
+			body: body,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ChangePassword(gomock.Any(), userUUID, deviceUUID, "old-secret-1", "new-secret-2", gomock.Any()).
+					Return("", "", services.ErrInvalidCredentials)
+			},
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name: "weak new password",
+			body: body,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ChangePassword(gomock.Any(), userUUID, deviceUUID, "old-secret-1", "new-secret-2", gomock.Any()).
+					Return("", "", fmt.Errorf("%w: password must not contain the username", services.ErrWeakPassword))
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "password does not meet the password policy: password must not contain the username\n",
+		},
+		{
+			name: "breached new password",
+			body: body,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ChangePassword(gomock.Any(), userUUID, deviceUUID, "old-secret-1", "new-secret-2", gomock.Any()).
+					Return("", "", services.ErrBreachedPassword)
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   "password is in the list of breached passwords\n",
+		},
+		{
+			name: "too many attempts",
+			body: body,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ChangePassword(gomock.Any(), userUUID, deviceUUID, "old-secret-1", "new-secret-2", gomock.Any()).
+					Return("", "", &services.TooManyAttemptsError{RetryAfter: 90 * time.Second})
+			},
+			expectedStatus: http.StatusTooManyRequests,
+		},
+		{
+			name: "internal error",
+			body: body,
+			setup: func() {
+				authorized()
+				mockSvc.EXPECT().ChangePassword(gomock.Any(), userUUID, deviceUUID, "old-secret-1", "new-secret-2", gomock.Any()).
+					Return("", "", errors.New("db error"))
+			},
+			expectedStatus: http.StatusInternalServerError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/password", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			ChangePasswordHandler(mockSvc, mockParser).ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			if tt.expectedBody != "" {
+				assert.Equal(t, tt.expectedBody, w.Body.String())
+			}
+			if tt.expectTokens {
+				assert.Equal(t, "Bearer new-token", w.Header().Get("Authorization"))
+				assert.Equal(t, "new-refresh", w.Header().Get(RefreshTokenHeader))
+			}
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+)
+
+// Интерфейсы для распределения ключей комнаты
+type RoomKeyUploader interface {
+	// UploadRoomKeys сохраняет ключ комнаты эпохи keyEpoch, загруженный и подписанный устройством deviceUUID
+	// и зашифрованный для каждого из устройств keys (deviceUUID -> ключ)
+	UploadRoomKeys(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, roomUUID uuid.UUID, keyEpoch int64, keys map[uuid.UUID]models.SignedRoomKey) error
+}
+
+type RoomKeyGetter interface {
+	// GetRoomKey возвращает ключ комнаты эпохи keyEpoch (0 — текущей), зашифрованный для устройства пользователя,
+	// вместе с загрузившим его устройством
+	GetRoomKey(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, roomUUID uuid.UUID, keyEpoch int64) (*models.IssuedRoomKeyDB, error)
+}
+
+type RoomDeviceLister interface {
+	// ListRoomDevices возвращает текущую эпоху ключа и устройства всех участников комнаты с их публичными ключами
+	ListRoomDevices(ctx context.Context, userUUID uuid.UUID, roomUUID uuid.UUID) (keyEpoch int64, devices []models.UserDeviceDB, err error)
+}
+
+// RoomKeyItem — ключ комнаты, зашифрованный для одного устройства.
+// swagger:model RoomKeyItem
+type RoomKeyItem struct {
+	// UUID устройства получателя
+	// required: true
+	// example: 4c2b87cd-8c44-4546-89df-7a751cbac96e
+	DeviceUUID string `json:"device_uuid"`
+
+	// Ключ комнаты, зашифрованный публичным ключом устройства (base64)
+	// required: true
+	// example: kq3Vn0mYp2Jt...
+	EncryptedKey string `json:"encrypted_key"`
+
+	// Подпись зашифрованного ключа ключом подписи загружающего устройства (Ed25519, base64)
+	// required: true
+	// example: 3q2+7wHf0QhX...
+	Signature string `json:"signature"`
+}
+
+// UploadRoomKeysRequest представляет JSON тело запроса на загрузку ключей комнаты.
+// swagger:model UploadRoomKeysRequest
+type UploadRoomKeysRequest struct {
+	// Эпоха ключа комнаты; должна совпадать с текущей эпохой
+	// required: true
+	// example: 2
+	KeyEpoch int64 `json:"key_epoch"`
+
+	// Ключи комнаты для устройств участников
+	// required: true
+	Keys []RoomKeyItem `json:"keys"`
+}
+
+// RoomKeyResponse — ключ комнаты, зашифрованный для устройства, и подпись загрузившего его устройства.
+// swagger:model RoomKeyResponse
+type RoomKeyResponse struct {
+	// Эпоха ключа комнаты
+	KeyEpoch int64 `json:"key_epoch"`
+	// Ключ комнаты, зашифрованный публичным ключом устройства (base64)
+	EncryptedKey string `json:"encrypted_key"`
+	// UUID владельца устройства, загрузившего ключ; пусто для ключей, загруженных до появления подписей
+	IssuerUserUUID string `json:"issuer_user_uuid,omitempty"`
+	// UUID устройства, загрузившего ключ; пусто для ключей, загруженных до появления подписей
+	IssuerDeviceUUID string `json:"issuer_device_uuid,omitempty"`
+	// Ключ подписи устройства, загрузившего ключ
+	IssuerSigningKey string `json:"issuer_signing_key,omitempty"`
+	// Подпись ключа ключом подписи загрузившего устройства (base64)
+	Signature string `json:"signature,omitempty"`
+}
+
+// RoomDevicesResponse — текущая эпоха ключа и устройства участников комнаты.
+// swagger:model RoomDevicesResponse
+type RoomDevicesResponse struct {
+	// Текущая эпоха ключа комнаты, для которой нужно зашифровать ключ
+	KeyEpoch int64 `json:"key_epoch"`
+	// Устройства участников комнаты
+	Devices []RoomDeviceResponse `json:"devices"`
+}
+
+// RoomDeviceResponse — устройство участника комнаты.
+// swagger:model RoomDeviceResponse
+type RoomDeviceResponse struct {
+	// UUID пользователя-владельца устройства
+	UserUUID string `json:"user_uuid"`
+	// UUID устройства
+	DeviceUUID string `json:"device_uuid"`
+	// Публичный ключ устройства
+	PublicKey string `json:"public_key"`
+	// Ключ подписи устройства, которым проверяются выпущенные им ключи комнаты
+	SigningKey string `json:"signing_key,omitempty"`
+	// Отпечаток публичного ключа устройства
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+// UploadRoomKeysHandler сохраняет ключ комнаты, зашифрованный для устройств участников
+// @Summary Загрузка ключей комнаты
+// @Description Сохраняет ключ комнаты текущей эпохи, зашифрованный публичным ключом каждого из указанных устройств.
+// @Description Доступно любому участнику комнаты; устройства должны принадлежать участникам комнаты.
+// @Description Каждый ключ подписывается ключом подписи загружающего устройства (device_uuid из JWT).
+// @Description Ключи всех устройств сохраняются атомарно. У эпохи один ключ: его выпускает первая загрузка,
+// @Description последующие только пересылают его устройствам без ключа и принимаются, если ключ эпохи уже есть
+// @Description у текущего устройства. Ключ, выпущенный другим устройством той же эпохи, отклоняется с кодом 409:
+// @Description клиент должен получить сохранённый ключ эпохи и использовать его.
+// @Tags Keys
+// @Accept json
+// @Produce plain
+// @Param room-uuid path string true "UUID комнаты"
+// @Param request body UploadRoomKeysRequest true "Ключи для устройств"
+// @Success 200 "Ключи сохранены"
+// @Failure 400 "Некорректные данные запроса, устройство не принадлежит участнику комнаты или подпись ключа неверна"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Пользователь не состоит в комнате"
+// @Failure 404 "Комната не найдена"
+// @Failure 409 "Эпоха ключа устарела или её ключ уже выпущен другим устройством"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid}/keys [put]
+func UploadRoomKeysHandler(svc RoomKeyUploader, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomUUID, err := uuid.Parse(chi.URLParam(r, "room-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var req UploadRoomKeysRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || len(req.Keys) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		keys := make(map[uuid.UUID]models.SignedRoomKey, len(req.Keys))
+		for _, k := range req.Keys {
+			deviceUUID, err := uuid.Parse(k.DeviceUUID)
+			if err != nil || k.EncryptedKey == "" || k.Signature == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			keys[deviceUUID] = models.SignedRoomKey{EncryptedKey: k.EncryptedKey, Signature: k.Signature}
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, deviceUUID, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		if err := svc.UploadRoomKeys(r.Context(), userUUID, deviceUUID, roomUUID, req.KeyEpoch, keys); err != nil {
+			writeRoomKeyError(w, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// GetRoomKeyHandler возвращает ключ комнаты, зашифрованный для устройства из JWT
+// @Summary Получение ключа комнаты
+// @Description Возвращает ключ комнаты, зашифрованный публичным ключом текущего устройства (device_uuid из JWT).
+// @Description Без параметра epoch возвращается ключ текущей эпохи. Вместе с ключом возвращаются устройство,
+// @Description загрузившее его, и подпись: клиент проверяет её ключом подписи устройства участника комнаты.
+// @Tags Keys
+// @Accept plain
+// @Produce json
+// @Param room-uuid path string true "UUID комнаты"
+// @Param epoch query int false "Эпоха ключа"
+// @Success 200 {object} RoomKeyResponse "Зашифрованный ключ комнаты"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Пользователь не состоит в комнате"
+// @Failure 404 "Комната или ключ для устройства не найдены"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid}/keys [get]
+func GetRoomKeyHandler(svc RoomKeyGetter, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomUUID, err := uuid.Parse(chi.URLParam(r, "room-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var keyEpoch int64
+		if v := r.URL.Query().Get("epoch"); v != "" {
+			keyEpoch, err = strconv.ParseInt(v, 10, 64)
+			if err != nil || keyEpoch <= 0 {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, deviceUUID, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		key, err := svc.GetRoomKey(r.Context(), userUUID, deviceUUID, roomUUID, keyEpoch)
+		if err != nil {
+			writeRoomKeyError(w, err)
+			return
+		}
+
+		resp := RoomKeyResponse{
+			KeyEpoch:         key.KeyEpoch,
+			EncryptedKey:     key.EncryptedKey,
+			IssuerSigningKey: key.IssuerSigningKey,
+			Signature:        key.Signature,
+		}
+		if key.IssuerDeviceUUID != nil {
+			resp.IssuerDeviceUUID = key.IssuerDeviceUUID.String()
+		}
+		if key.IssuerUserUUID != nil {
+			resp.IssuerUserUUID = key.IssuerUserUUID.String()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// ListRoomDevicesHandler возвращает устройства участников комнаты с публичными ключами
+// @Summary Устройства участников комнаты
+// @Description Возвращает текущую эпоху ключа и все устройства всех участников комнаты с их публичными ключами
+// @Description и ключами подписи, чтобы клиент мог зашифровать ключ этой эпохи для каждого из них
+// @Description и проверить подписи полученных ключей.
+// @Tags Keys
+// @Accept plain
+// @Produce json
+// @Param room-uuid path string true "UUID комнаты"
+// @Success 200 {object} RoomDevicesResponse "Эпоха ключа и устройства участников"
+// @Failure 400 "Некорректные данные запроса"
+// @Failure 401 "Неавторизован"
+// @Failure 403 "Пользователь не состоит в комнате"
+// @Failure 404 "Комната не найдена"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /chat/{room-uuid}/devices [get]
+func ListRoomDevicesHandler(svc RoomDeviceLister, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		roomUUID, err := uuid.Parse(chi.URLParam(r, "room-uuid"))
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		userUUID, _, err := parser.Parse(token)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		keyEpoch, devices, err := svc.ListRoomDevices(r.Context(), userUUID, roomUUID)
+		if err != nil {
+			writeRoomKeyError(w, err)
+			return
+		}
+
+		resp := RoomDevicesResponse{
+			KeyEpoch: keyEpoch,
+			Devices:  make([]RoomDeviceResponse, 0, len(devices)),
+		}
+		for _, d := range devices {
+			resp.Devices = append(resp.Devices, RoomDeviceResponse{
+				UserUUID:    d.UserUUID.String(),
+				DeviceUUID:  d.DeviceUUID.String(),
+				PublicKey:   d.PublicKey,
+				SigningKey:  d.SigningKey,
+				Fingerprint: deviceFingerprint(d.PublicKey),
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// writeRoomKeyError отображает ошибки сервиса ключей комнаты в HTTP-статусы
+func writeRoomKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrDeviceNotInRoom), errors.Is(err, services.ErrInvalidRoomKeySignature):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, services.ErrUserNotInRoom):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, services.ErrRoomNotFound), errors.Is(err, services.ErrRoomKeyNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, services.ErrStaleKeyEpoch), errors.Is(err, services.ErrRoomKeyConflict):
+		w.WriteHeader(http.StatusConflict)
+	default:
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
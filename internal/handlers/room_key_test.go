@@ -0,0 +1,383 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadRoomKeysHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockRoomKeyUploader(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	uploaderUUID := uuid.New()
+	validBody := `{"key_epoch":2,"keys":[{"device_uuid":"` + deviceUUID.String() + `","encrypted_key":"wrapped","signature":"sig"}]}`
+
+	tests := []struct {
+		name           string
+		roomID         string
+		body           string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			roomID:         roomUUID.String(),
+			body:           validBody,
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uploaderUUID, nil)
+				mockSvc.EXPECT().UploadRoomKeys(gomock.Any(), userUUID, uploaderUUID, roomUUID, int64(2), map[uuid.UUID]models.SignedRoomKey{deviceUUID: {EncryptedKey: "wrapped", Signature: "sig"}}).Return(nil)
+			},
+		},
+		{
+			name:           "invalid room UUID",
+			roomID:         "invalid",
+			body:           validBody,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "empty keys",
+			roomID:         roomUUID.String(),
+			body:           `{"keys":[]}`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "missing signature",
+			roomID:         roomUUID.String(),
+			body:           `{"key_epoch":2,"keys":[{"device_uuid":"` + deviceUUID.String() + `","encrypted_key":"wrapped"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid device UUID",
+			roomID:         roomUUID.String(),
+			body:           `{"keys":[{"device_uuid":"bad","encrypted_key":"wrapped","signature":"sig"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "unauthorized",
+			roomID:         roomUUID.String(),
+			body:           validBody,
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "device not in room",
+			roomID:         roomUUID.String(),
+			body:           validBody,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uploaderUUID, nil)
+				mockSvc.EXPECT().UploadRoomKeys(gomock.Any(), userUUID, uploaderUUID, roomUUID, int64(2), gomock.Any()).Return(services.ErrDeviceNotInRoom)
+			},
+		},
+		{
+			name:           "invalid signature",
+			roomID:         roomUUID.String(),
+			body:           validBody,
+			expectedStatus: http.StatusBadRequest,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uploaderUUID, nil)
+				mockSvc.EXPECT().UploadRoomKeys(gomock.Any(), userUUID, uploaderUUID, roomUUID, int64(2), gomock.Any()).Return(services.ErrInvalidRoomKeySignature)
+			},
+		},
+		{
+			name:           "not a member",
+			roomID:         roomUUID.String(),
+			body:           validBody,
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uploaderUUID, nil)
+				mockSvc.EXPECT().UploadRoomKeys(gomock.Any(), userUUID, uploaderUUID, roomUUID, int64(2), gomock.Any()).Return(services.ErrUserNotInRoom)
+			},
+		},
+		{
+			name:           "stale epoch",
+			roomID:         roomUUID.String(),
+			body:           validBody,
+			expectedStatus: http.StatusConflict,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uploaderUUID, nil)
+				mockSvc.EXPECT().UploadRoomKeys(gomock.Any(), userUUID, uploaderUUID, roomUUID, int64(2), gomock.Any()).Return(services.ErrStaleKeyEpoch)
+			},
+		},
+		{
+			name:           "epoch key issued by another device",
+			roomID:         roomUUID.String(),
+			body:           validBody,
+			expectedStatus: http.StatusConflict,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uploaderUUID, nil)
+				mockSvc.EXPECT().UploadRoomKeys(gomock.Any(), userUUID, uploaderUUID, roomUUID, int64(2), gomock.Any()).Return(services.ErrRoomKeyConflict)
+			},
+		},
+		{
+			name:           "service error",
+			roomID:         roomUUID.String(),
+			body:           validBody,
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uploaderUUID, nil)
+				mockSvc.EXPECT().UploadRoomKeys(gomock.Any(), userUUID, uploaderUUID, roomUUID, int64(2), gomock.Any()).Return(errors.New("db error"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Put("/chat/{room-uuid}/keys", UploadRoomKeysHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest(http.MethodPut, "/chat/"+tt.roomID+"/keys", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+		})
+	}
+}
+
+func TestGetRoomKeyHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockRoomKeyGetter(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	issuerUserUUID := uuid.New()
+	issuerDeviceUUID := uuid.New()
+	key := &models.IssuedRoomKeyDB{
+		RoomKeyDB: models.RoomKeyDB{
+			RoomUUID:         roomUUID,
+			DeviceUUID:       deviceUUID,
+			KeyEpoch:         2,
+			EncryptedKey:     "wrapped",
+			IssuerDeviceUUID: &issuerDeviceUUID,
+			Signature:        "sig",
+		},
+		IssuerUserUUID:   &issuerUserUUID,
+		IssuerSigningKey: "signing",
+	}
+	legacy := &models.IssuedRoomKeyDB{RoomKeyDB: models.RoomKeyDB{RoomUUID: roomUUID, DeviceUUID: deviceUUID, KeyEpoch: 1, EncryptedKey: "old"}}
+	issued := &RoomKeyResponse{
+		KeyEpoch:         2,
+		EncryptedKey:     "wrapped",
+		IssuerUserUUID:   issuerUserUUID.String(),
+		IssuerDeviceUUID: issuerDeviceUUID.String(),
+		IssuerSigningKey: "signing",
+		Signature:        "sig",
+	}
+
+	tests := []struct {
+		name           string
+		roomID         string
+		query          string
+		expectedStatus int
+		expectedBody   *RoomKeyResponse
+		setup          func()
+	}{
+		{
+			name:           "current epoch",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusOK,
+			expectedBody:   issued,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().GetRoomKey(gomock.Any(), userUUID, deviceUUID, roomUUID, int64(0)).Return(key, nil)
+			},
+		},
+		{
+			name:           "explicit epoch",
+			roomID:         roomUUID.String(),
+			query:          "?epoch=2",
+			expectedStatus: http.StatusOK,
+			expectedBody:   issued,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().GetRoomKey(gomock.Any(), userUUID, deviceUUID, roomUUID, int64(2)).Return(key, nil)
+			},
+		},
+		{
+			name:           "unsigned key of an old epoch",
+			roomID:         roomUUID.String(),
+			query:          "?epoch=1",
+			expectedStatus: http.StatusOK,
+			expectedBody:   &RoomKeyResponse{KeyEpoch: 1, EncryptedKey: "old"},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().GetRoomKey(gomock.Any(), userUUID, deviceUUID, roomUUID, int64(1)).Return(legacy, nil)
+			},
+		},
+		{
+			name:           "invalid epoch",
+			roomID:         roomUUID.String(),
+			query:          "?epoch=zero",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "invalid room UUID",
+			roomID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "token parse error",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.Nil, uuid.Nil, errors.New("fail"))
+			},
+		},
+		{
+			name:           "key not found",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().GetRoomKey(gomock.Any(), userUUID, deviceUUID, roomUUID, int64(0)).Return(nil, services.ErrRoomKeyNotFound)
+			},
+		},
+		{
+			name:           "not a member",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusForbidden,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, deviceUUID, nil)
+				mockSvc.EXPECT().GetRoomKey(gomock.Any(), userUUID, deviceUUID, roomUUID, int64(0)).Return(nil, services.ErrUserNotInRoom)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Get("/chat/{room-uuid}/keys", GetRoomKeyHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest(http.MethodGet, "/chat/"+tt.roomID+"/keys"+tt.query, nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != nil {
+				var resp RoomKeyResponse
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, *tt.expectedBody, resp)
+			}
+		})
+	}
+}
+
+func TestListRoomDevicesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockRoomDeviceLister(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	device := models.UserDeviceDB{UserUUID: userUUID, DeviceUUID: uuid.New(), PublicKey: "pk", SigningKey: "signing"}
+
+	tests := []struct {
+		name           string
+		roomID         string
+		expectedStatus int
+		setup          func()
+	}{
+		{
+			name:           "success",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusOK,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListRoomDevices(gomock.Any(), userUUID, roomUUID).Return(int64(3), []models.UserDeviceDB{device}, nil)
+			},
+		},
+		{
+			name:           "invalid room UUID",
+			roomID:         "invalid",
+			expectedStatus: http.StatusBadRequest,
+			setup:          func() {},
+		},
+		{
+			name:           "room not found",
+			roomID:         roomUUID.String(),
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(userUUID, uuid.New(), nil)
+				mockSvc.EXPECT().ListRoomDevices(gomock.Any(), userUUID, roomUUID).Return(int64(0), nil, services.ErrRoomNotFound)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Get("/chat/{room-uuid}/devices", ListRoomDevicesHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest(http.MethodGet, "/chat/"+tt.roomID+"/devices", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedStatus == http.StatusOK {
+				var resp RoomDevicesResponse
+				assert.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+				assert.Equal(t, RoomDevicesResponse{
+					KeyEpoch: 3,
+					Devices: []RoomDeviceResponse{{
+						UserUUID:   device.UserUUID.String(),
+						DeviceUUID: device.DeviceUUID.String(),
+						PublicKey:  "pk",
+						SigningKey: "signing",
+					}},
+				}, resp)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+)
+
+// Интерфейс для поиска пользователей в справочнике
+type UserFinder interface {
+	// FindUser возвращает пользователя по имени
+	FindUser(ctx context.Context, username string) (*models.UserDB, error)
+}
+
+// Интерфейс для получения устройств пользователя из справочника
+type UserDeviceLister interface {
+	// ListUserDevices возвращает пользователя по имени и его активные устройства
+	ListUserDevices(ctx context.Context, username string) (*models.UserDB, []models.UserDeviceDB, error)
+}
+
+// UserResponse — пользователь из справочника.
+// swagger:model UserResponse
+type UserResponse struct {
+	// UUID пользователя
+	UserUUID string `json:"user_uuid"`
+	// Имя пользователя
+	Username string `json:"username"`
+}
+
+// UserDeviceResponse — активное устройство пользователя с его ключами.
+// swagger:model UserDeviceResponse
+type UserDeviceResponse struct {
+	// UUID устройства
+	DeviceUUID string `json:"device_uuid"`
+	// Публичный ключ устройства
+	PublicKey string `json:"public_key"`
+	// Отпечаток публичного ключа устройства
+	Fingerprint string `json:"fingerprint,omitempty"`
+	// Публичный ключ подписи устройства
+	SigningKey string `json:"signing_key,omitempty"`
+}
+
+// UserDevicesResponse — пользователь и его активные устройства.
+// swagger:model UserDevicesResponse
+type UserDevicesResponse struct {
+	// UUID пользователя
+	UserUUID string `json:"user_uuid"`
+	// Имя пользователя
+	Username string `json:"username"`
+	// Активные устройства пользователя
+	Devices []UserDeviceResponse `json:"devices"`
+}
+
+// GetUserHandler возвращает пользователя по имени
+// @Summary Поиск пользователя по имени
+// @Description Возвращает UUID пользователя с указанным именем, например чтобы добавить его в комнату.
+// @Tags Users
+// @Accept plain
+// @Produce json
+// @Param username path string true "Имя пользователя"
+// @Success 200 {object} UserResponse "Пользователь"
+// @Failure 401 "Неавторизован"
+// @Failure 404 "Пользователь не найден"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /users/{username} [get]
+func GetUserHandler(svc UserFinder, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, _, err := parser.Parse(token); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		user, err := svc.FindUser(r.Context(), chi.URLParam(r, "username"))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrUserNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(UserResponse{
+			UserUUID: user.UserUUID.String(),
+			Username: user.Username,
+		})
+	}
+}
+
+// ListUserDevicesHandler возвращает активные устройства пользователя с публичными ключами
+// @Summary Устройства пользователя
+// @Description Возвращает UUID пользователя и публичные ключи его активных устройств с отпечатками,
+// @Description чтобы клиент мог вычислить номер безопасности и сверить его с собеседником.
+// @Tags Users
+// @Accept plain
+// @Produce json
+// @Param username path string true "Имя пользователя"
+// @Success 200 {object} UserDevicesResponse "Пользователь и его устройства"
+// @Failure 401 "Неавторизован"
+// @Failure 404 "Пользователь не найден"
+// @Failure 500 "Внутренняя ошибка сервера"
+// @Router /users/{username}/devices [get]
+func ListUserDevicesHandler(svc UserDeviceLister, parser TokenParser) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, err := parser.GetFromRequest(r)
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if _, _, err := parser.Parse(token); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		user, devices, err := svc.ListUserDevices(r.Context(), chi.URLParam(r, "username"))
+		if err != nil {
+			switch {
+			case errors.Is(err, services.ErrUserNotFound):
+				w.WriteHeader(http.StatusNotFound)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			return
+		}
+
+		resp := UserDevicesResponse{
+			UserUUID: user.UserUUID.String(),
+			Username: user.Username,
+			Devices:  make([]UserDeviceResponse, 0, len(devices)),
+		}
+		for _, device := range devices {
+			resp.Devices = append(resp.Devices, UserDeviceResponse{
+				DeviceUUID:  device.DeviceUUID.String(),
+				PublicKey:   device.PublicKey,
+				Fingerprint: deviceFingerprint(device.PublicKey),
+				SigningKey:  device.SigningKey,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	}
+}
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetUserHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockUserFinder(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	user := &models.UserDB{UserUUID: uuid.New(), Username: "alice"}
+
+	tests := []struct {
+		name           string
+		expectedStatus int
+		expectedBody   *UserResponse
+		setup          func()
+	}{
+		{
+			name:           "success",
+			expectedStatus: http.StatusOK,
+			expectedBody:   &UserResponse{UserUUID: user.UserUUID.String(), Username: "alice"},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
+				mockSvc.EXPECT().FindUser(gomock.Any(), "alice").Return(user, nil)
+			},
+		},
+		{
+			name:           "unauthorized",
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "invalid token",
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.Nil, uuid.Nil, errors.New("invalid"))
+			},
+		},
+		{
+			name:           "not found",
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
+				mockSvc.EXPECT().FindUser(gomock.Any(), "alice").Return(nil, services.ErrUserNotFound)
+			},
+		},
+		{
+			name:           "internal error",
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
+				mockSvc.EXPECT().FindUser(gomock.Any(), "alice").Return(nil, errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Get("/users/{username}", GetUserHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("GET", "/users/alice", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != nil {
+				var body UserResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+				assert.Equal(t, *tt.expectedBody, body)
+			}
+		})
+	}
+}
+
+func TestListUserDevicesHandler(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockSvc := NewMockUserDeviceLister(ctrl)
+	mockParser := NewMockTokenParser(ctrl)
+
+	priv, err := e2ee.GenerateDeviceKey()
+	require.NoError(t, err)
+	publicKey := e2ee.EncodePublicKey(priv.PublicKey())
+	fingerprint, err := e2ee.Fingerprint(publicKey)
+	require.NoError(t, err)
+
+	user := &models.UserDB{UserUUID: uuid.New(), Username: "alice"}
+	device := models.UserDeviceDB{DeviceUUID: uuid.New(), UserUUID: user.UserUUID, PublicKey: publicKey, SigningKey: "sign"}
+
+	tests := []struct {
+		name           string
+		expectedStatus int
+		expectedBody   *UserDevicesResponse
+		setup          func()
+	}{
+		{
+			name:           "success",
+			expectedStatus: http.StatusOK,
+			expectedBody: &UserDevicesResponse{
+				UserUUID: user.UserUUID.String(),
+				Username: "alice",
+				Devices: []UserDeviceResponse{{
+					DeviceUUID:  device.DeviceUUID.String(),
+					PublicKey:   publicKey,
+					Fingerprint: fingerprint,
+					SigningKey:  "sign",
+				}},
+			},
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
+				mockSvc.EXPECT().ListUserDevices(gomock.Any(), "alice").Return(user, []models.UserDeviceDB{device}, nil)
+			},
+		},
+		{
+			name:           "unauthorized",
+			expectedStatus: http.StatusUnauthorized,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("", errors.New("no token"))
+			},
+		},
+		{
+			name:           "not found",
+			expectedStatus: http.StatusNotFound,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
+				mockSvc.EXPECT().ListUserDevices(gomock.Any(), "alice").Return(nil, nil, services.ErrUserNotFound)
+			},
+		},
+		{
+			name:           "internal error",
+			expectedStatus: http.StatusInternalServerError,
+			setup: func() {
+				mockParser.EXPECT().GetFromRequest(gomock.Any()).Return("token", nil)
+				mockParser.EXPECT().Parse("token").Return(uuid.New(), uuid.New(), nil)
+				mockSvc.EXPECT().ListUserDevices(gomock.Any(), "alice").Return(nil, nil, errors.New("fail"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			r := chi.NewRouter()
+			r.Get("/users/{username}/devices", ListUserDevicesHandler(mockSvc, mockParser))
+
+			req := httptest.NewRequest("GET", "/users/alice/devices", nil)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.expectedStatus, w.Result().StatusCode)
+			if tt.expectedBody != nil {
+				var body UserDevicesResponse
+				require.NoError(t, json.NewDecoder(w.Body).Decode(&body))
+				assert.Equal(t, *tt.expectedBody, body)
+			}
+		})
+	}
+}
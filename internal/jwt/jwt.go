@@ -10,10 +10,21 @@ import (
 	"github.com/google/uuid"
 )
 
+// ErrTokenRevoked возвращается при разборе отозванного токена.
+var ErrTokenRevoked = errors.New("token revoked")
+
+// RevocationChecker проверяет, отозван ли токен.
+type RevocationChecker interface {
+	// IsRevoked сообщает, отозван ли токен с идентификатором tokenID,
+	// выданный пользователю userUUID для устройства deviceUUID в момент issuedAt.
+	IsRevoked(tokenID string, userUUID uuid.UUID, deviceUUID uuid.UUID, issuedAt time.Time) bool
+}
+
 // JWT представляет работу с JWT-токенами.
 type JWT struct {
 	secretKey []byte
 	exp       time.Duration
+	revoked   RevocationChecker
 }
 
 // Opt — функциональная опция для настройки JWT.
@@ -61,21 +72,56 @@ func WithExpiration(exp ...time.Duration) Opt {
 	}
 }
 
+// WithRevocationChecker задаёт хранилище отозванных токенов, проверяемое при каждом разборе токена.
+// Используется первое непустое значение.
+func WithRevocationChecker(checkers ...RevocationChecker) Opt {
+	return func(j *JWT) error {
+		for _, c := range checkers {
+			if c != nil {
+				j.revoked = c
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+// Claims — данные, извлечённые из валидного токена.
+type Claims struct {
+	TokenID     string    // уникальный идентификатор токена (jti)
+	UserUUID    uuid.UUID // идентификатор пользователя
+	DeviceUUID  uuid.UUID // идентификатор устройства
+	SessionUUID uuid.UUID // идентификатор сессии (семейства refresh-токенов), uuid.Nil — вне сессии
+	IssuedAt    time.Time // время выдачи токена с точностью до микросекунды
+	ExpiresAt   time.Time // время истечения токена
+}
+
 // claims — структура для хранения полезной нагрузки токена (включая служебные поля JWT).
 type claims struct {
-	UserUUID   uuid.UUID `json:"user_uuid"`   // идентификатор пользователя в системе.
-	DeviceUUID uuid.UUID `json:"device_uuid"` // идентификатор устройства.
+	UserUUID    uuid.UUID `json:"user_uuid"`        // идентификатор пользователя в системе.
+	DeviceUUID  uuid.UUID `json:"device_uuid"`      // идентификатор устройства.
+	SessionUUID uuid.UUID `json:"sid,omitempty"`    // идентификатор сессии.
+	IssuedAtUS  int64     `json:"iat_us,omitempty"` // время выдачи в микросекундах: iat хранит только секунды.
 	jwt.RegisteredClaims
 }
 
-// Generate создаёт JWT-токен.
+// Generate создаёт JWT-токен с уникальным идентификатором (jti) вне сессии.
 func (j *JWT) Generate(userUUID uuid.UUID, deviceUUID uuid.UUID) (string, error) {
+	return j.GenerateWithSession(userUUID, deviceUUID, uuid.Nil)
+}
+
+// GenerateWithSession создаёт JWT-токен, привязанный к сессии sessionUUID (claim sid).
+func (j *JWT) GenerateWithSession(userUUID uuid.UUID, deviceUUID uuid.UUID, sessionUUID uuid.UUID) (string, error) {
+	now := time.Now()
 	c := claims{
-		UserUUID:   userUUID,
-		DeviceUUID: deviceUUID,
+		UserUUID:    userUUID,
+		DeviceUUID:  deviceUUID,
+		SessionUUID: sessionUUID,
+		IssuedAtUS:  now.UnixMicro(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.exp)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        uuid.New().String(),
+			ExpiresAt: jwt.NewNumericDate(now.Add(j.exp)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 	}
 
@@ -85,6 +131,16 @@ func (j *JWT) Generate(userUUID uuid.UUID, deviceUUID uuid.UUID) (string, error)
 
 // Parse парсит токен.
 func (j *JWT) Parse(tokenString string) (userUUID uuid.UUID, deviceUUID uuid.UUID, err error) {
+	c, err := j.ParseClaims(tokenString)
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	return c.UserUUID, c.DeviceUUID, nil
+}
+
+// ParseClaims парсит токен и возвращает его данные.
+// Если задан RevocationChecker и токен отозван, возвращает ErrTokenRevoked.
+func (j *JWT) ParseClaims(tokenString string) (*Claims, error) {
 	c := &claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, c, func(t *jwt.Token) (any, error) {
@@ -94,17 +150,34 @@ func (j *JWT) Parse(tokenString string) (userUUID uuid.UUID, deviceUUID uuid.UUI
 		return j.secretKey, nil
 	})
 	if err != nil || !token.Valid {
-		return uuid.Nil, uuid.Nil, errors.New("invalid token")
+		return nil, errors.New("invalid token")
+	}
+
+	parsed, ok := token.Claims.(*claims)
+	if !ok {
+		return nil, errors.New("invalid token claims")
+	}
+
+	result := &Claims{
+		TokenID:     parsed.ID,
+		UserUUID:    parsed.UserUUID,
+		DeviceUUID:  parsed.DeviceUUID,
+		SessionUUID: parsed.SessionUUID,
+	}
+	if parsed.IssuedAtUS != 0 {
+		result.IssuedAt = time.UnixMicro(parsed.IssuedAtUS)
+	} else if parsed.IssuedAt != nil {
+		result.IssuedAt = parsed.IssuedAt.Time
+	}
+	if parsed.ExpiresAt != nil {
+		result.ExpiresAt = parsed.ExpiresAt.Time
 	}
 
-	if claims, ok := token.Claims.(*claims); ok {
-		userUUID = claims.UserUUID
-		deviceUUID = claims.DeviceUUID
-	} else {
-		return uuid.Nil, uuid.Nil, errors.New("invalid token claims")
+	if j.revoked != nil && j.revoked.IsRevoked(result.TokenID, result.UserUUID, result.DeviceUUID, result.IssuedAt) {
+		return nil, ErrTokenRevoked
 	}
 
-	return userUUID, deviceUUID, nil
+	return result, nil
 }
 
 // GetFromRequest получает токен из запроса.
@@ -48,3 +48,75 @@ func TestJWT_WithSecretKeyAndExpiration(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEmpty(t, token)
 }
+
+// revokedSet — простая реализация RevocationChecker для тестов
+type revokedSet map[string]bool
+
+func (r revokedSet) IsRevoked(tokenID string, userUUID uuid.UUID, deviceUUID uuid.UUID, issuedAt time.Time) bool {
+	return r[tokenID]
+}
+
+func TestJWT_ParseClaims(t *testing.T) {
+	j, err := New(WithExpiration(time.Hour))
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	deviceID := uuid.New()
+
+	issuedAfter := time.Now().Truncate(time.Microsecond)
+	token, err := j.Generate(userID, deviceID)
+	assert.NoError(t, err)
+
+	c, err := j.ParseClaims(token)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, c.TokenID)
+	// Время выдачи сохраняет доли секунды, чтобы токен, выданный до отзыва в ту же секунду, был отозван
+	assert.False(t, c.IssuedAt.Before(issuedAfter))
+	assert.Equal(t, userID, c.UserUUID)
+	assert.Equal(t, deviceID, c.DeviceUUID)
+	assert.WithinDuration(t, time.Now(), c.IssuedAt, 2*time.Second)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), c.ExpiresAt, 2*time.Second)
+
+	// Каждый токен получает собственный jti
+	other, err := j.Generate(userID, deviceID)
+	assert.NoError(t, err)
+	otherClaims, err := j.ParseClaims(other)
+	assert.NoError(t, err)
+	assert.NotEqual(t, c.TokenID, otherClaims.TokenID)
+	assert.Equal(t, uuid.Nil, otherClaims.SessionUUID)
+}
+
+func TestJWT_GenerateWithSession(t *testing.T) {
+	j, err := New()
+	assert.NoError(t, err)
+
+	userID := uuid.New()
+	deviceID := uuid.New()
+	sessionID := uuid.New()
+
+	token, err := j.GenerateWithSession(userID, deviceID, sessionID)
+	assert.NoError(t, err)
+
+	c, err := j.ParseClaims(token)
+	assert.NoError(t, err)
+	assert.Equal(t, userID, c.UserUUID)
+	assert.Equal(t, deviceID, c.DeviceUUID)
+	assert.Equal(t, sessionID, c.SessionUUID)
+}
+
+func TestJWT_Parse_RevokedToken(t *testing.T) {
+	revoked := revokedSet{}
+	j, err := New(WithRevocationChecker(revoked))
+	assert.NoError(t, err)
+
+	token, err := j.Generate(uuid.New(), uuid.New())
+	assert.NoError(t, err)
+
+	c, err := j.ParseClaims(token)
+	assert.NoError(t, err)
+
+	revoked[c.TokenID] = true
+
+	_, _, err = j.Parse(token)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+}
@@ -26,11 +26,83 @@ type UserDB struct {
 	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
+// Статусы устройства пользователя
+const (
+	DeviceStatusActive  = "active"  // устройство может входить в аккаунт и получать ключи комнат
+	DeviceStatusPending = "pending" // устройство ожидает подтверждения с доверенного устройства
+)
+
 // UserDeviceDB представляет запись устройства пользователя в базе
 type UserDeviceDB struct {
-	DeviceUUID uuid.UUID `json:"device_uuid" db:"device_uuid"` // UUID устройства (PK)
+	DeviceUUID        uuid.UUID  `json:"device_uuid" db:"device_uuid"`               // UUID устройства (PK)
+	UserUUID          uuid.UUID  `json:"user_uuid" db:"user_uuid"`                   // UUID пользователя (FK)
+	PublicKey         string     `json:"public_key" db:"public_key"`                 // Публичный ключ устройства
+	SigningKey        string     `json:"signing_key" db:"signing_key"`               // Публичный ключ подписи устройства (Ed25519) или пустая строка
+	Status            string     `json:"status" db:"status"`                         // Статус устройства (active, pending)
+	ApprovedBy        *uuid.UUID `json:"approved_by" db:"approved_by"`               // UUID устройства, подтвердившего это устройство
+	ApprovalSignature string     `json:"approval_signature" db:"approval_signature"` // Подпись подтверждения ключом подписи ApprovedBy
+	ApprovedAt        *time.Time `json:"approved_at" db:"approved_at"`               // Время подтверждения устройства
+	Name              string     `json:"name" db:"name"`                             // Название устройства, заданное пользователем
+	RevokedAt         *time.Time `json:"revoked_at" db:"revoked_at"`                 // Время отзыва устройства или nil, если устройство не отозвано
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`                 // Время создания записи
+	UpdatedAt         time.Time  `json:"updated_at" db:"updated_at"`                 // Время последнего обновления записи
+}
+
+// Области действия выхода из аккаунта
+const (
+	LogoutScopeToken  = "token"  // отзыв только текущего токена
+	LogoutScopeDevice = "device" // отзыв всех сессий текущего устройства
+	LogoutScopeUser   = "user"   // отзыв всех сессий пользователя на всех устройствах
+)
+
+// RevokedTokenDB представляет запись отозванного токена в таблице revoked_tokens
+type RevokedTokenDB struct {
+	TokenID    string    `json:"token_id" db:"token_id"`       // Идентификатор токена (jti, PK)
+	UserUUID   uuid.UUID `json:"user_uuid" db:"user_uuid"`     // UUID пользователя (FK)
+	DeviceUUID uuid.UUID `json:"device_uuid" db:"device_uuid"` // UUID устройства
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`   // Время истечения токена
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`   // Время создания записи
+}
+
+// SessionRevocationDB представляет запись в таблице session_revocations.
+// Все токены пользователя (устройства), выданные раньше RevokedAt, считаются отозванными.
+// Нулевой DeviceUUID означает все устройства пользователя.
+type SessionRevocationDB struct {
 	UserUUID   uuid.UUID `json:"user_uuid" db:"user_uuid"`     // UUID пользователя (FK)
-	PublicKey  string    `json:"public_key" db:"public_key"`   // Публичный ключ устройства
+	DeviceUUID uuid.UUID `json:"device_uuid" db:"device_uuid"` // UUID устройства или uuid.Nil
+	RevokedAt  time.Time `json:"revoked_at" db:"revoked_at"`   // Момент отзыва сессий
 	CreatedAt  time.Time `json:"created_at" db:"created_at"`   // Время создания записи
 	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`   // Время последнего обновления записи
 }
+
+// RefreshTokenDB представляет запись refresh-токена в таблице refresh_tokens.
+// Сам токен не хранится — только его SHA-256 хэш.
+// Все токены, полученные ротацией из одного входа, образуют сессию (семейство) SessionUUID.
+type RefreshTokenDB struct {
+	TokenHash   string     `json:"token_hash" db:"token_hash"`     // SHA-256 хэш токена (PK)
+	SessionUUID uuid.UUID  `json:"session_uuid" db:"session_uuid"` // UUID сессии (семейства токенов)
+	UserUUID    uuid.UUID  `json:"user_uuid" db:"user_uuid"`       // UUID пользователя (FK)
+	DeviceUUID  uuid.UUID  `json:"device_uuid" db:"device_uuid"`   // UUID устройства (FK)
+	ExpiresAt   time.Time  `json:"expires_at" db:"expires_at"`     // Время истечения токена
+	UsedAt      *time.Time `json:"used_at" db:"used_at"`           // Время обмена токена на новую пару (ротации)
+	RevokedAt   *time.Time `json:"revoked_at" db:"revoked_at"`     // Время отзыва токена
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`     // Время создания записи
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`     // Время последнего обновления записи
+}
+
+// Области блокировки входа
+const (
+	LoginLockoutScopeUsername = "username" // блокировка по имени пользователя
+	LoginLockoutScopeIP       = "ip"       // блокировка по IP-адресу клиента
+)
+
+// LoginLockoutDB представляет запись о блокировке входа в таблице login_lockouts.
+// Записи не влияют на проверку попыток входа и хранятся для администратора.
+type LoginLockoutDB struct {
+	LockoutUUID uuid.UUID `json:"lockout_uuid" db:"lockout_uuid"` // UUID записи (PK)
+	Scope       string    `json:"scope" db:"scope"`               // Область блокировки: username или ip
+	Key         string    `json:"key" db:"key"`                   // Имя пользователя или IP-адрес
+	Failures    int       `json:"failures" db:"failures"`         // Число неудачных попыток к моменту блокировки
+	LockedAt    time.Time `json:"locked_at" db:"locked_at"`       // Момент блокировки
+	LockedUntil time.Time `json:"locked_until" db:"locked_until"` // Момент окончания блокировки
+}
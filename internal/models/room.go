@@ -10,15 +10,145 @@ import (
 type RoomDB struct {
 	RoomUUID    uuid.UUID `json:"room_uuid" db:"room_uuid"`       // UUID комнаты (PK)
 	CreatorUUID uuid.UUID `json:"creator_uuid" db:"creator_uuid"` // UUID создателя комнаты (FK)
+	Name        string    `json:"name" db:"name"`                 // Название комнаты
+	Topic       string    `json:"topic" db:"topic"`               // Тема комнаты
+	Kind        string    `json:"kind" db:"kind"`                 // Вид комнаты (group/direct)
+	DirectKey   uuid.UUID `json:"direct_key" db:"direct_key"`     // Ключ пары собеседников личной комнаты (uuid.Nil для групповых)
+	KeyEpoch    int64     `json:"key_epoch" db:"key_epoch"`       // Текущая эпоха ключа комнаты, растёт при смене состава
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`     // Время создания комнаты
 	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`     // Время последнего обновления комнаты
 }
 
+// Виды комнат
+const (
+	RoomKindGroup  = "group"  // групповая комната с произвольным составом участников
+	RoomKindDirect = "direct" // личная переписка двух пользователей, единственная для пары
+)
+
+// Роли участников комнаты
+const (
+	RoomRoleOwner  = "owner"  // владелец комнаты, единственный на комнату
+	RoomRoleAdmin  = "admin"  // администратор, может управлять обычными участниками
+	RoomRoleMember = "member" // обычный участник
+)
+
 // RoomMemberDB представляет запись участника комнаты в таблице room_members
 type RoomMemberDB struct {
 	RoomUUID  uuid.UUID `json:"room_uuid" db:"room_uuid"`   // UUID комнаты (FK)
 	UserUUID  uuid.UUID `json:"user_uuid" db:"user_uuid"`   // UUID пользователя (FK)
+	Role      string    `json:"role" db:"role"`             // Роль участника (owner/admin/member)
 	JoinedAt  time.Time `json:"joined_at" db:"joined_at"`   // Время присоединения к комнате
 	CreatedAt time.Time `json:"created_at" db:"created_at"` // Время создания записи
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"` // Время последнего обновления записи
 }
+
+// RoomSummaryDB — комната пользователя со сведениями для списка комнат
+type RoomSummaryDB struct {
+	RoomUUID      uuid.UUID  `json:"room_uuid" db:"room_uuid"`             // UUID комнаты
+	CreatorUUID   uuid.UUID  `json:"creator_uuid" db:"creator_uuid"`       // UUID создателя комнаты
+	Name          string     `json:"name" db:"name"`                       // Название комнаты
+	Topic         string     `json:"topic" db:"topic"`                     // Тема комнаты
+	Kind          string     `json:"kind" db:"kind"`                       // Вид комнаты (group/direct)
+	Role          string     `json:"role" db:"role"`                       // Роль пользователя в комнате
+	MemberCount   int64      `json:"member_count" db:"member_count"`       // Количество участников комнаты
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`           // Время создания комнаты
+	LastMessageAt *time.Time `json:"last_message_at" db:"last_message_at"` // Время последнего сообщения или nil, если сообщений нет
+}
+
+// LastActivityAt возвращает время последней активности в комнате: последнего сообщения или создания комнаты
+func (r RoomSummaryDB) LastActivityAt() time.Time {
+	if r.LastMessageAt != nil {
+		return *r.LastMessageAt
+	}
+	return r.CreatedAt
+}
+
+// RoomMemberUserDB — участник комнаты с именем пользователя из таблицы users
+type RoomMemberUserDB struct {
+	RoomMemberDB
+	Username string `json:"username" db:"username"` // Имя пользователя
+}
+
+// RoomMessageDB представляет запись сообщения комнаты в таблице room_messages
+type RoomMessageDB struct {
+	MessageUUID       uuid.UUID `json:"message_uuid" db:"message_uuid"`               // UUID сообщения (PK)
+	RoomUUID          uuid.UUID `json:"room_uuid" db:"room_uuid"`                     // UUID комнаты (FK)
+	SenderUUID        uuid.UUID `json:"sender_uuid" db:"sender_uuid"`                 // UUID отправителя (FK)
+	SenderDeviceUUID  uuid.UUID `json:"sender_device_uuid" db:"sender_device_uuid"`   // UUID устройства отправителя (uuid.Nil для старых сообщений)
+	ClientMessageUUID uuid.UUID `json:"client_message_uuid" db:"client_message_uuid"` // UUID сообщения, присвоенный клиентом, для дедупликации повторных отправок
+	Ciphertext        string    `json:"ciphertext" db:"ciphertext"`                   // Зашифрованное содержимое сообщения
+	KeyEpoch          int64     `json:"key_epoch" db:"key_epoch"`                     // Эпоха ключа комнаты, которым зашифровано сообщение
+	SentAt            time.Time `json:"sent_at" db:"sent_at"`                         // Время отправки сообщения
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`                   // Время создания записи
+	UpdatedAt         time.Time `json:"updated_at" db:"updated_at"`                   // Время последнего обновления записи
+}
+
+// MessageCursor — позиция сообщения в истории комнаты для постраничного чтения.
+// Сообщения упорядочены по времени отправки, а при совпадении времени — по UUID.
+type MessageCursor struct {
+	SentAt      time.Time // Время отправки сообщения
+	MessageUUID uuid.UUID // UUID сообщения
+}
+
+// RoomKeyDB представляет запись в таблице room_keys — ключ комнаты,
+// зашифрованный публичным ключом конкретного устройства
+type RoomKeyDB struct {
+	RoomUUID         uuid.UUID  `json:"room_uuid" db:"room_uuid"`                   // UUID комнаты (FK)
+	DeviceUUID       uuid.UUID  `json:"device_uuid" db:"device_uuid"`               // UUID устройства (FK)
+	KeyEpoch         int64      `json:"key_epoch" db:"key_epoch"`                   // Эпоха ключа комнаты
+	EncryptedKey     string     `json:"encrypted_key" db:"encrypted_key"`           // Ключ комнаты, зашифрованный для устройства
+	IssuerDeviceUUID *uuid.UUID `json:"issuer_device_uuid" db:"issuer_device_uuid"` // UUID устройства, загрузившего и подписавшего ключ
+	Signature        string     `json:"signature" db:"signature"`                   // Подпись ключа ключом подписи загрузившего устройства
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`                 // Время создания записи
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`                 // Время последнего обновления записи
+}
+
+// SignedRoomKey — ключ комнаты, зашифрованный для устройства и подписанный загружающим устройством
+type SignedRoomKey struct {
+	EncryptedKey string // Ключ комнаты, зашифрованный публичным ключом устройства
+	Signature    string // Подпись ключа ключом подписи загружающего устройства
+}
+
+// IssuedRoomKeyDB — ключ комнаты вместе с пользователем и ключом подписи загрузившего его устройства
+type IssuedRoomKeyDB struct {
+	RoomKeyDB
+	IssuerUserUUID   *uuid.UUID `json:"issuer_user_uuid" db:"issuer_user_uuid"`     // UUID владельца загрузившего устройства
+	IssuerSigningKey string     `json:"issuer_signing_key" db:"issuer_signing_key"` // Ключ подписи загрузившего устройства
+}
+
+// Статусы приглашений в комнату
+const (
+	RoomInviteStatusPending  = "pending"  // приглашение ожидает ответа приглашённого
+	RoomInviteStatusAccepted = "accepted" // приглашение принято, пользователь добавлен в комнату
+	RoomInviteStatusDeclined = "declined" // приглашение отклонено
+)
+
+// RoomInviteDB представляет запись приглашения в комнату в таблице room_invites
+type RoomInviteDB struct {
+	InviteUUID  uuid.UUID `json:"invite_uuid" db:"invite_uuid"`   // UUID приглашения (PK)
+	RoomUUID    uuid.UUID `json:"room_uuid" db:"room_uuid"`       // UUID комнаты (FK)
+	InviterUUID uuid.UUID `json:"inviter_uuid" db:"inviter_uuid"` // UUID пригласившего пользователя (FK)
+	InviteeUUID uuid.UUID `json:"invitee_uuid" db:"invitee_uuid"` // UUID приглашённого пользователя (FK)
+	Status      string    `json:"status" db:"status"`             // Статус приглашения (pending/accepted/declined)
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`     // Время создания приглашения
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`     // Время последнего обновления записи
+}
+
+// RoomInviteDetailsDB — приглашение с названием комнаты и именем пригласившего пользователя
+type RoomInviteDetailsDB struct {
+	RoomInviteDB
+	RoomName        string `json:"room_name" db:"room_name"`               // Название комнаты
+	InviterUsername string `json:"inviter_username" db:"inviter_username"` // Имя пригласившего пользователя
+}
+
+// RoomInviteLinkDB представляет запись ссылки-приглашения в таблице room_invite_links
+type RoomInviteLinkDB struct {
+	LinkUUID    uuid.UUID `json:"link_uuid" db:"link_uuid"`       // UUID ссылки (PK), передаётся приглашаемым
+	RoomUUID    uuid.UUID `json:"room_uuid" db:"room_uuid"`       // UUID комнаты (FK)
+	CreatorUUID uuid.UUID `json:"creator_uuid" db:"creator_uuid"` // UUID создателя ссылки (FK)
+	ExpiresAt   time.Time `json:"expires_at" db:"expires_at"`     // Время, после которого ссылка недействительна
+	MaxUses     int       `json:"max_uses" db:"max_uses"`         // Максимальное число входов по ссылке (0 — без ограничения)
+	Uses        int       `json:"uses" db:"uses"`                 // Число входов по ссылке
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`     // Время создания ссылки
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`     // Время последнего обновления записи
+}
@@ -20,27 +20,126 @@ func NewDeviceWriteRepository(db *sqlx.DB) *DeviceWriteRepository {
 	return &DeviceWriteRepository{db: db}
 }
 
-// Save сохраняет новое устройство с привязкой к пользователю.
-// Если устройство с таким device_uuid уже существует, обновляются только public_key и updated_at.
+// Save сохраняет новое устройство с привязкой к пользователю в статусе status.
+// Если устройство с таким device_uuid уже существует, обновляются только ключи и updated_at.
 func (r *DeviceWriteRepository) Save(
 	ctx context.Context,
 	deviceUUID uuid.UUID,
 	userUUID uuid.UUID,
 	publicKey string,
+	signingKey string,
+	status string,
 ) error {
 	now := time.Now().UTC()
 	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO user_devices (device_uuid, user_uuid, public_key, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5)
+		`INSERT INTO user_devices (device_uuid, user_uuid, public_key, signing_key, status, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
 		 ON CONFLICT (device_uuid)
 		 DO UPDATE
 		 SET public_key = EXCLUDED.public_key,
+		     signing_key = EXCLUDED.signing_key,
 		     updated_at = EXCLUDED.updated_at`,
-		deviceUUID, userUUID, publicKey, now, now,
+		deviceUUID, userUUID, publicKey, signingKey, status, now, now,
 	)
 	return err
 }
 
+// Rename задаёт название неотозванного устройства пользователя.
+// Возвращает false, если у пользователя нет такого неотозванного устройства.
+func (r *DeviceWriteRepository) Rename(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	name string,
+) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE user_devices SET name = $1, updated_at = $2
+		 WHERE device_uuid = $3 AND user_uuid = $4 AND revoked_at IS NULL`,
+		name, time.Now().UTC(), deviceUUID, userUUID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Revoke помечает неотозванное (активное или ожидающее подтверждения) устройство пользователя отозванным.
+// Возвращает false, если у пользователя нет такого неотозванного устройства.
+func (r *DeviceWriteRepository) Revoke(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	revokedAt time.Time,
+) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE user_devices SET revoked_at = $1, updated_at = $1
+		 WHERE device_uuid = $2 AND user_uuid = $3 AND revoked_at IS NULL`,
+		revokedAt, deviceUUID, userUUID,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Approve переводит ожидающее подтверждения устройство пользователя в активное,
+// сохраняя подтвердившее устройство и подпись подтверждения.
+// Возвращает false, если у пользователя нет такого ожидающего устройства.
+func (r *DeviceWriteRepository) Approve(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	approverUUID uuid.UUID,
+	signature string,
+	approvedAt time.Time,
+) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE user_devices
+		 SET status = $1, approved_by = $2, approval_signature = $3, approved_at = $4, updated_at = $4
+		 WHERE device_uuid = $5 AND user_uuid = $6 AND status = $7 AND revoked_at IS NULL`,
+		models.DeviceStatusActive, approverUUID, signature, approvedAt, deviceUUID, userUUID, models.DeviceStatusPending,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// SetSigningKey сохраняет ключ подписи активного устройства пользователя, добавленного без него.
+// Возвращает false, если у пользователя нет такого активного устройства или ключ подписи уже задан.
+func (r *DeviceWriteRepository) SetSigningKey(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	signingKey string,
+) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE user_devices SET signing_key = $1, updated_at = $2
+		 WHERE device_uuid = $3 AND user_uuid = $4 AND status = $5 AND revoked_at IS NULL AND signing_key = ''`,
+		signingKey, time.Now().UTC(), deviceUUID, userUUID, models.DeviceStatusActive,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
 // DeviceReadRepository реализует чтение устройств пользователя через SQL базу
 type DeviceReadRepository struct {
 	db *sqlx.DB
@@ -51,7 +150,7 @@ func NewDeviceReadRepository(db *sqlx.DB) *DeviceReadRepository {
 	return &DeviceReadRepository{db: db}
 }
 
-// GetByDeviceUUID возвращает устройство по его UUID или nil, если не найдено
+// Get возвращает устройство по его UUID (в том числе отозванное) или nil, если не найдено
 func (r *DeviceReadRepository) Get(
 	ctx context.Context,
 	deviceUUID uuid.UUID,
@@ -66,3 +165,35 @@ func (r *DeviceReadRepository) Get(
 	}
 	return &device, nil
 }
+
+// ListByUser возвращает неотозванные устройства пользователя (активные и ожидающие подтверждения) в порядке добавления
+func (r *DeviceReadRepository) ListByUser(
+	ctx context.Context,
+	userUUID uuid.UUID,
+) ([]models.UserDeviceDB, error) {
+	var devices []models.UserDeviceDB
+	err := r.db.SelectContext(ctx, &devices,
+		`SELECT * FROM user_devices
+		 WHERE user_uuid = $1 AND revoked_at IS NULL
+		 ORDER BY created_at, device_uuid`,
+		userUUID,
+	)
+	return devices, err
+}
+
+// ListByRoom возвращает активные устройства всех участников комнаты.
+// Отозванные и ожидающие подтверждения устройства не возвращаются, поэтому ключи комнат для них не создаются.
+func (r *DeviceReadRepository) ListByRoom(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+) ([]models.UserDeviceDB, error) {
+	var devices []models.UserDeviceDB
+	err := r.db.SelectContext(ctx, &devices,
+		`SELECT d.* FROM user_devices AS d
+		 JOIN room_members AS rm ON rm.user_uuid = d.user_uuid
+		 WHERE rm.room_uuid = $1 AND d.revoked_at IS NULL AND d.status = $2
+		 ORDER BY d.user_uuid, d.device_uuid`,
+		roomUUID, models.DeviceStatusActive,
+	)
+	return devices, err
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
 	"github.com/sbilibin2017/bil-message/internal/repositories"
 	"github.com/stretchr/testify/assert"
 
@@ -19,11 +20,18 @@ func setupDeviceDB(t *testing.T) *sqlx.DB {
 
 	schema := `
 	CREATE TABLE user_devices (
-		device_uuid TEXT PRIMARY KEY,
-		user_uuid   TEXT NOT NULL,
-		public_key  TEXT NOT NULL,
-		created_at  DATETIME NOT NULL,
-		updated_at  DATETIME NOT NULL
+		device_uuid        TEXT PRIMARY KEY,
+		user_uuid          TEXT NOT NULL,
+		public_key         TEXT NOT NULL,
+		signing_key        TEXT NOT NULL DEFAULT '',
+		status             TEXT NOT NULL DEFAULT 'active',
+		approved_by        TEXT,
+		approval_signature TEXT NOT NULL DEFAULT '',
+		approved_at        DATETIME,
+		name               TEXT NOT NULL DEFAULT '',
+		revoked_at         DATETIME,
+		created_at         DATETIME NOT NULL,
+		updated_at         DATETIME NOT NULL
 	);`
 	_, err = db.Exec(schema)
 	assert.NoError(t, err)
@@ -42,7 +50,7 @@ func TestDeviceWriteAndRead(t *testing.T) {
 	publicKey := "pubkey123"
 
 	// Save new device
-	err := writeRepo.Save(ctx, deviceUUID, userUUID, publicKey)
+	err := writeRepo.Save(ctx, deviceUUID, userUUID, publicKey, "", models.DeviceStatusActive)
 	assert.NoError(t, err)
 
 	// Get by deviceUUID
@@ -56,7 +64,7 @@ func TestDeviceWriteAndRead(t *testing.T) {
 	// Save with same deviceUUID but updated publicKey
 	newPublicKey := "newpubkey456"
 	time.Sleep(time.Millisecond * 10) // ensure updated_at will be different
-	err = writeRepo.Save(ctx, deviceUUID, userUUID, newPublicKey)
+	err = writeRepo.Save(ctx, deviceUUID, userUUID, newPublicKey, "", models.DeviceStatusActive)
 	assert.NoError(t, err)
 
 	// Read again
@@ -89,7 +97,7 @@ func TestSaveMultipleDevices(t *testing.T) {
 		userUUID := uuid.New()
 		publicKey := "pub" + string(rune(i+'0'))
 
-		err := writeRepo.Save(ctx, deviceUUID, userUUID, publicKey)
+		err := writeRepo.Save(ctx, deviceUUID, userUUID, publicKey, "", models.DeviceStatusActive)
 		assert.NoError(t, err)
 
 		device, err := readRepo.Get(ctx, deviceUUID)
@@ -100,3 +108,208 @@ func TestSaveMultipleDevices(t *testing.T) {
 		assert.Equal(t, userUUID, device.UserUUID)
 	}
 }
+
+func TestDeviceListByRoom(t *testing.T) {
+	db := setupDeviceDB(t)
+	_, err := db.Exec(`
+	CREATE TABLE room_members (
+		room_uuid  TEXT NOT NULL,
+		user_uuid  TEXT NOT NULL,
+		role       TEXT NOT NULL DEFAULT 'member',
+		joined_at  DATETIME NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (room_uuid, user_uuid)
+	);`)
+	assert.NoError(t, err)
+
+	deviceWriteRepo := repositories.NewDeviceWriteRepository(db)
+	memberWriteRepo := repositories.NewRoomMemberWriteRepository(db)
+	readRepo := repositories.NewDeviceReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	member := uuid.New()
+	outsider := uuid.New()
+
+	assert.NoError(t, deviceWriteRepo.Save(ctx, uuid.New(), member, "member-key-1", "", models.DeviceStatusActive))
+	assert.NoError(t, deviceWriteRepo.Save(ctx, uuid.New(), member, "member-key-2", "", models.DeviceStatusActive))
+	assert.NoError(t, deviceWriteRepo.Save(ctx, uuid.New(), outsider, "outsider-key", "", models.DeviceStatusActive))
+	assert.NoError(t, memberWriteRepo.Save(ctx, roomUUID, member, "member", time.Now()))
+
+	revokedUUID := uuid.New()
+	assert.NoError(t, deviceWriteRepo.Save(ctx, revokedUUID, member, "member-key-revoked", "", models.DeviceStatusActive))
+	_, err = deviceWriteRepo.Revoke(ctx, member, revokedUUID, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.NoError(t, deviceWriteRepo.Save(ctx, uuid.New(), member, "member-key-pending", "", models.DeviceStatusPending))
+
+	devices, err := readRepo.ListByRoom(ctx, roomUUID)
+	assert.NoError(t, err)
+	assert.Len(t, devices, 2)
+	for _, d := range devices {
+		assert.Equal(t, member, d.UserUUID)
+	}
+
+	devices, err = readRepo.ListByRoom(ctx, uuid.New())
+	assert.NoError(t, err)
+	assert.Empty(t, devices)
+}
+
+func TestDeviceRenameAndRevoke(t *testing.T) {
+	db := setupDeviceDB(t)
+	writeRepo := repositories.NewDeviceWriteRepository(db)
+	readRepo := repositories.NewDeviceReadRepository(db)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	otherDeviceUUID := uuid.New()
+	assert.NoError(t, writeRepo.Save(ctx, deviceUUID, userUUID, "key-1", "", models.DeviceStatusActive))
+	time.Sleep(time.Millisecond * 10) // гарантируем порядок по created_at
+	assert.NoError(t, writeRepo.Save(ctx, otherDeviceUUID, userUUID, "key-2", "", models.DeviceStatusActive))
+	assert.NoError(t, writeRepo.Save(ctx, uuid.New(), uuid.New(), "foreign-key", "", models.DeviceStatusActive))
+
+	// Переименование своего устройства
+	ok, err := writeRepo.Rename(ctx, userUUID, deviceUUID, "Ноутбук")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	device, err := readRepo.Get(ctx, deviceUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ноутбук", device.Name)
+	assert.Nil(t, device.RevokedAt)
+
+	// Чужое устройство переименовать нельзя
+	ok, err = writeRepo.Rename(ctx, uuid.New(), deviceUUID, "Чужое")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	devices, err := readRepo.ListByUser(ctx, userUUID)
+	assert.NoError(t, err)
+	assert.Len(t, devices, 2)
+	assert.Equal(t, deviceUUID, devices[0].DeviceUUID)
+	assert.Equal(t, otherDeviceUUID, devices[1].DeviceUUID)
+
+	// Отзыв устройства
+	ok, err = writeRepo.Revoke(ctx, userUUID, deviceUUID, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	device, err = readRepo.Get(ctx, deviceUUID)
+	assert.NoError(t, err)
+	assert.NotNil(t, device.RevokedAt)
+
+	// Повторный отзыв и переименование отозванного устройства не выполняются
+	ok, err = writeRepo.Revoke(ctx, userUUID, deviceUUID, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = writeRepo.Rename(ctx, userUUID, deviceUUID, "Потерян")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	devices, err = readRepo.ListByUser(ctx, userUUID)
+	assert.NoError(t, err)
+	assert.Len(t, devices, 1)
+	assert.Equal(t, otherDeviceUUID, devices[0].DeviceUUID)
+}
+
+func TestDeviceApprove(t *testing.T) {
+	db := setupDeviceDB(t)
+	writeRepo := repositories.NewDeviceWriteRepository(db)
+	readRepo := repositories.NewDeviceReadRepository(db)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	approverUUID := uuid.New()
+	deviceUUID := uuid.New()
+	assert.NoError(t, writeRepo.Save(ctx, approverUUID, userUUID, "key-1", "signing-1", models.DeviceStatusActive))
+	assert.NoError(t, writeRepo.Save(ctx, deviceUUID, userUUID, "key-2", "signing-2", models.DeviceStatusPending))
+
+	device, err := readRepo.Get(ctx, deviceUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeviceStatusPending, device.Status)
+	assert.Equal(t, "signing-2", device.SigningKey)
+	assert.Nil(t, device.ApprovedBy)
+
+	// Ожидающие устройства видны в списке устройств пользователя
+	devices, err := readRepo.ListByUser(ctx, userUUID)
+	assert.NoError(t, err)
+	assert.Len(t, devices, 2)
+
+	// Чужой пользователь не может подтвердить устройство
+	ok, err := writeRepo.Approve(ctx, uuid.New(), deviceUUID, approverUUID, "sig", time.Now().UTC())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = writeRepo.Approve(ctx, userUUID, deviceUUID, approverUUID, "sig", time.Now().UTC())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	device, err = readRepo.Get(ctx, deviceUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.DeviceStatusActive, device.Status)
+	assert.Equal(t, approverUUID, *device.ApprovedBy)
+	assert.Equal(t, "sig", device.ApprovalSignature)
+	assert.NotNil(t, device.ApprovedAt)
+
+	// Повторное подтверждение не выполняется
+	ok, err = writeRepo.Approve(ctx, userUUID, deviceUUID, approverUUID, "sig-2", time.Now().UTC())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Отозванное ожидающее устройство подтвердить нельзя
+	revokedUUID := uuid.New()
+	assert.NoError(t, writeRepo.Save(ctx, revokedUUID, userUUID, "key-3", "signing-3", models.DeviceStatusPending))
+	_, err = writeRepo.Revoke(ctx, userUUID, revokedUUID, time.Now().UTC())
+	assert.NoError(t, err)
+
+	ok, err = writeRepo.Approve(ctx, userUUID, revokedUUID, approverUUID, "sig", time.Now().UTC())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestDeviceSetSigningKey(t *testing.T) {
+	db := setupDeviceDB(t)
+	writeRepo := repositories.NewDeviceWriteRepository(db)
+	readRepo := repositories.NewDeviceReadRepository(db)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	assert.NoError(t, writeRepo.Save(ctx, deviceUUID, userUUID, "key-1", "", models.DeviceStatusActive))
+
+	// Чужой пользователь не может задать ключ подписи
+	ok, err := writeRepo.SetSigningKey(ctx, uuid.New(), deviceUUID, "signing-1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = writeRepo.SetSigningKey(ctx, userUUID, deviceUUID, "signing-1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	device, err := readRepo.Get(ctx, deviceUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, "signing-1", device.SigningKey)
+
+	// Заданный ключ подписи не перезаписывается
+	ok, err = writeRepo.SetSigningKey(ctx, userUUID, deviceUUID, "signing-2")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Ожидающее устройство не может задать ключ подписи
+	pendingUUID := uuid.New()
+	assert.NoError(t, writeRepo.Save(ctx, pendingUUID, userUUID, "key-2", "", models.DeviceStatusPending))
+	ok, err = writeRepo.SetSigningKey(ctx, userUUID, pendingUUID, "signing-3")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Отозванное устройство не может задать ключ подписи
+	revokedUUID := uuid.New()
+	assert.NoError(t, writeRepo.Save(ctx, revokedUUID, userUUID, "key-3", "", models.DeviceStatusActive))
+	_, err = writeRepo.Revoke(ctx, userUUID, revokedUUID, time.Now().UTC())
+	assert.NoError(t, err)
+	ok, err = writeRepo.SetSigningKey(ctx, userUUID, revokedUUID, "signing-4")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
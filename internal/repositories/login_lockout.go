@@ -0,0 +1,58 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// LoginLockoutWriteRepository реализует запись блокировок входа через SQL
+type LoginLockoutWriteRepository struct {
+	db *sqlx.DB
+}
+
+// NewLoginLockoutWriteRepository создаёт новый репозиторий для записи блокировок входа
+func NewLoginLockoutWriteRepository(db *sqlx.DB) *LoginLockoutWriteRepository {
+	return &LoginLockoutWriteRepository{db: db}
+}
+
+// Save сохраняет запись о блокировке входа по имени пользователя или IP-адресу
+func (r *LoginLockoutWriteRepository) Save(
+	ctx context.Context,
+	lockoutUUID uuid.UUID,
+	scope string,
+	key string,
+	failures int,
+	lockedAt time.Time,
+	lockedUntil time.Time,
+) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO login_lockouts (lockout_uuid, scope, key, failures, locked_at, locked_until)
+		 VALUES ($1, $2, $3, $4, $5, $6)`,
+		lockoutUUID, scope, key, failures, lockedAt, lockedUntil,
+	)
+	return err
+}
+
+// LoginLockoutReadRepository реализует чтение блокировок входа через SQL
+type LoginLockoutReadRepository struct {
+	db *sqlx.DB
+}
+
+// NewLoginLockoutReadRepository создаёт новый репозиторий для чтения блокировок входа
+func NewLoginLockoutReadRepository(db *sqlx.DB) *LoginLockoutReadRepository {
+	return &LoginLockoutReadRepository{db: db}
+}
+
+// List возвращает не более limit последних блокировок входа, от новых к старым
+func (r *LoginLockoutReadRepository) List(ctx context.Context, limit int) ([]models.LoginLockoutDB, error) {
+	var lockouts []models.LoginLockoutDB
+	err := r.db.SelectContext(ctx, &lockouts,
+		`SELECT * FROM login_lockouts ORDER BY locked_at DESC LIMIT $1`,
+		limit,
+	)
+	return lockouts, err
+}
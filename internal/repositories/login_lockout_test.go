@@ -0,0 +1,67 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupLoginLockoutsDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE login_lockouts (
+		lockout_uuid TEXT PRIMARY KEY,
+		scope        TEXT NOT NULL,
+		key          TEXT NOT NULL,
+		failures     INTEGER NOT NULL,
+		locked_at    DATETIME NOT NULL,
+		locked_until DATETIME NOT NULL
+	);`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestLoginLockoutSaveAndList(t *testing.T) {
+	db := setupLoginLockoutsDB(t)
+	writeRepo := repositories.NewLoginLockoutWriteRepository(db)
+	readRepo := repositories.NewLoginLockoutReadRepository(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	older := uuid.New()
+	newer := uuid.New()
+
+	err := writeRepo.Save(ctx, older, models.LoginLockoutScopeUsername, "johndoe", 10, now.Add(-time.Hour), now.Add(-45*time.Minute))
+	require.NoError(t, err)
+	err = writeRepo.Save(ctx, newer, models.LoginLockoutScopeIP, "203.0.113.7", 50, now, now.Add(15*time.Minute))
+	require.NoError(t, err)
+
+	lockouts, err := readRepo.List(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 2)
+
+	assert.Equal(t, newer, lockouts[0].LockoutUUID)
+	assert.Equal(t, models.LoginLockoutScopeIP, lockouts[0].Scope)
+	assert.Equal(t, "203.0.113.7", lockouts[0].Key)
+	assert.Equal(t, 50, lockouts[0].Failures)
+	assert.True(t, lockouts[0].LockedUntil.Equal(now.Add(15*time.Minute)))
+	assert.Equal(t, older, lockouts[1].LockoutUUID)
+
+	lockouts, err = readRepo.List(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, lockouts, 1)
+	assert.Equal(t, newer, lockouts[0].LockoutUUID)
+}
@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// RefreshTokenWriteRepository реализует запись refresh-токенов через SQL
+type RefreshTokenWriteRepository struct {
+	db *sqlx.DB
+}
+
+// NewRefreshTokenWriteRepository создаёт новый репозиторий для записи refresh-токенов
+func NewRefreshTokenWriteRepository(db *sqlx.DB) *RefreshTokenWriteRepository {
+	return &RefreshTokenWriteRepository{db: db}
+}
+
+// Save сохраняет хэш нового refresh-токена сессии
+func (r *RefreshTokenWriteRepository) Save(
+	ctx context.Context,
+	tokenHash string,
+	sessionUUID uuid.UUID,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	expiresAt time.Time,
+) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (token_hash, session_uuid, user_uuid, device_uuid, expires_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		tokenHash, sessionUUID, userUUID, deviceUUID, expiresAt, now, now,
+	)
+	return err
+}
+
+// MarkUsed помечает токен использованным (обменянным на новую пару).
+// Возвращает false, если токен уже был использован или отозван:
+// так два параллельных обмена одного токена не могут оба завершиться успешно.
+func (r *RefreshTokenWriteRepository) MarkUsed(ctx context.Context, tokenHash string, usedAt time.Time) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET used_at = $1, updated_at = $2
+		 WHERE token_hash = $3 AND used_at IS NULL AND revoked_at IS NULL`,
+		usedAt, time.Now().UTC(), tokenHash,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RevokeSession отзывает все ещё не отозванные токены сессии
+func (r *RefreshTokenWriteRepository) RevokeSession(ctx context.Context, sessionUUID uuid.UUID) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1, updated_at = $1
+		 WHERE session_uuid = $2 AND revoked_at IS NULL`,
+		now, sessionUUID,
+	)
+	return err
+}
+
+// RevokeDevice отзывает все ещё не отозванные токены пользователя на устройстве
+func (r *RefreshTokenWriteRepository) RevokeDevice(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1, updated_at = $1
+		 WHERE user_uuid = $2 AND device_uuid = $3 AND revoked_at IS NULL`,
+		now, userUUID, deviceUUID,
+	)
+	return err
+}
+
+// RevokeUser отзывает все ещё не отозванные токены пользователя на всех устройствах
+func (r *RefreshTokenWriteRepository) RevokeUser(ctx context.Context, userUUID uuid.UUID) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $1, updated_at = $1
+		 WHERE user_uuid = $2 AND revoked_at IS NULL`,
+		now, userUUID,
+	)
+	return err
+}
+
+// DeleteExpired удаляет refresh-токены, срок действия которых истёк до момента before
+func (r *RefreshTokenWriteRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM refresh_tokens WHERE expires_at < $1`,
+		before,
+	)
+	return err
+}
+
+// RefreshTokenReadRepository реализует чтение refresh-токенов через SQL
+type RefreshTokenReadRepository struct {
+	db *sqlx.DB
+}
+
+// NewRefreshTokenReadRepository создаёт новый репозиторий для чтения refresh-токенов
+func NewRefreshTokenReadRepository(db *sqlx.DB) *RefreshTokenReadRepository {
+	return &RefreshTokenReadRepository{db: db}
+}
+
+// Get возвращает refresh-токен по хэшу или nil, если не найден
+func (r *RefreshTokenReadRepository) Get(ctx context.Context, tokenHash string) (*models.RefreshTokenDB, error) {
+	var token models.RefreshTokenDB
+	err := r.db.GetContext(ctx, &token,
+		`SELECT * FROM refresh_tokens WHERE token_hash = $1`,
+		tokenHash,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
@@ -0,0 +1,141 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/repositories"
+	"github.com/stretchr/testify/assert"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupRefreshTokensDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite", ":memory:")
+	assert.NoError(t, err)
+
+	schema := `
+	CREATE TABLE refresh_tokens (
+		token_hash   TEXT PRIMARY KEY,
+		session_uuid TEXT NOT NULL,
+		user_uuid    TEXT NOT NULL,
+		device_uuid  TEXT NOT NULL,
+		expires_at   DATETIME NOT NULL,
+		used_at      DATETIME,
+		revoked_at   DATETIME,
+		created_at   DATETIME NOT NULL,
+		updated_at   DATETIME NOT NULL
+	);`
+	_, err = db.Exec(schema)
+	assert.NoError(t, err)
+
+	return db
+}
+
+func TestRefreshTokenSaveGetAndMarkUsed(t *testing.T) {
+	db := setupRefreshTokensDB(t)
+	writeRepo := repositories.NewRefreshTokenWriteRepository(db)
+	readRepo := repositories.NewRefreshTokenReadRepository(db)
+	ctx := context.Background()
+
+	sessionUUID := uuid.New()
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	expiresAt := time.Now().UTC().Add(time.Hour)
+
+	err := writeRepo.Save(ctx, "hash1", sessionUUID, userUUID, deviceUUID, expiresAt)
+	assert.NoError(t, err)
+
+	token, err := readRepo.Get(ctx, "hash1")
+	assert.NoError(t, err)
+	assert.NotNil(t, token)
+	assert.Equal(t, sessionUUID, token.SessionUUID)
+	assert.Equal(t, userUUID, token.UserUUID)
+	assert.Equal(t, deviceUUID, token.DeviceUUID)
+	assert.Nil(t, token.UsedAt)
+	assert.Nil(t, token.RevokedAt)
+
+	// первый обмен успешен, повторный — нет
+	ok, err := writeRepo.MarkUsed(ctx, "hash1", time.Now().UTC())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = writeRepo.MarkUsed(ctx, "hash1", time.Now().UTC())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	token, err = readRepo.Get(ctx, "hash1")
+	assert.NoError(t, err)
+	assert.NotNil(t, token.UsedAt)
+
+	// несуществующий токен
+	token, err = readRepo.Get(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+}
+
+func TestRefreshTokenRevoke(t *testing.T) {
+	db := setupRefreshTokensDB(t)
+	writeRepo := repositories.NewRefreshTokenWriteRepository(db)
+	readRepo := repositories.NewRefreshTokenReadRepository(db)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceA := uuid.New()
+	deviceB := uuid.New()
+	sessionA := uuid.New()
+	sessionB := uuid.New()
+	expiresAt := time.Now().UTC().Add(time.Hour)
+
+	assert.NoError(t, writeRepo.Save(ctx, "a1", sessionA, userUUID, deviceA, expiresAt))
+	assert.NoError(t, writeRepo.Save(ctx, "a2", sessionA, userUUID, deviceA, expiresAt))
+	assert.NoError(t, writeRepo.Save(ctx, "b1", sessionB, userUUID, deviceB, expiresAt))
+	assert.NoError(t, writeRepo.Save(ctx, "other", uuid.New(), uuid.New(), uuid.New(), expiresAt))
+
+	revoked := func(hash string) bool {
+		token, err := readRepo.Get(ctx, hash)
+		assert.NoError(t, err)
+		return token.RevokedAt != nil
+	}
+
+	assert.NoError(t, writeRepo.RevokeSession(ctx, sessionA))
+	assert.True(t, revoked("a1"))
+	assert.True(t, revoked("a2"))
+	assert.False(t, revoked("b1"))
+
+	// отозванный токен нельзя обменять
+	ok, err := writeRepo.MarkUsed(ctx, "a1", time.Now().UTC())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, writeRepo.RevokeDevice(ctx, userUUID, deviceB))
+	assert.True(t, revoked("b1"))
+	assert.False(t, revoked("other"))
+
+	assert.NoError(t, writeRepo.RevokeUser(ctx, userUUID))
+	assert.False(t, revoked("other"))
+}
+
+func TestRefreshTokenDeleteExpired(t *testing.T) {
+	db := setupRefreshTokensDB(t)
+	writeRepo := repositories.NewRefreshTokenWriteRepository(db)
+	readRepo := repositories.NewRefreshTokenReadRepository(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	assert.NoError(t, writeRepo.Save(ctx, "expired", uuid.New(), uuid.New(), uuid.New(), now.Add(-time.Hour)))
+	assert.NoError(t, writeRepo.Save(ctx, "active", uuid.New(), uuid.New(), uuid.New(), now.Add(time.Hour)))
+
+	assert.NoError(t, writeRepo.DeleteExpired(ctx, now))
+
+	token, err := readRepo.Get(ctx, "expired")
+	assert.NoError(t, err)
+	assert.Nil(t, token)
+
+	token, err = readRepo.Get(ctx, "active")
+	assert.NoError(t, err)
+	assert.NotNil(t, token)
+}
@@ -20,21 +20,23 @@ func NewRoomWriteRepository(db *sqlx.DB) *RoomWriteRepository {
 	return &RoomWriteRepository{db: db}
 }
 
-// Save сохраняет новую комнату в базу.
+// Save сохраняет новую групповую комнату с названием и темой в базу.
 // Если комната с таким room_uuid уже существует, обновляется только updated_at.
 func (r *RoomWriteRepository) Save(
 	ctx context.Context,
 	roomUUID uuid.UUID,
 	creatorUUID uuid.UUID,
+	name string,
+	topic string,
 ) error {
 	now := time.Now().UTC()
 	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO rooms (room_uuid, creator_uuid, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4)
+		`INSERT INTO rooms (room_uuid, creator_uuid, name, topic, kind, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
 		 ON CONFLICT (room_uuid)
 		 DO UPDATE
 		 SET updated_at = EXCLUDED.updated_at`,
-		roomUUID, creatorUUID, now, now,
+		roomUUID, creatorUUID, name, topic, models.RoomKindGroup, now, now,
 	)
 	if err != nil {
 		return err
@@ -42,6 +44,38 @@ func (r *RoomWriteRepository) Save(
 	return err
 }
 
+// SaveDirect сохраняет личную комнату пары собеседников с ключом directKey.
+// Если личная комната с таким ключом уже существует, ничего не меняется.
+func (r *RoomWriteRepository) SaveDirect(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	creatorUUID uuid.UUID,
+	directKey uuid.UUID,
+) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO rooms (room_uuid, creator_uuid, kind, direct_key, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (direct_key) DO NOTHING`,
+		roomUUID, creatorUUID, models.RoomKindDirect, directKey, now, now,
+	)
+	return err
+}
+
+// UpdateMetadata изменяет название и тему комнаты
+func (r *RoomWriteRepository) UpdateMetadata(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	name string,
+	topic string,
+) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE rooms SET name = $1, topic = $2, updated_at = $3 WHERE room_uuid = $4`,
+		name, topic, time.Now().UTC(), roomUUID,
+	)
+	return err
+}
+
 // Delete удаляет комнату из базы по roomUUID
 func (r *RoomWriteRepository) Delete(ctx context.Context, roomUUID uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx,
@@ -51,6 +85,26 @@ func (r *RoomWriteRepository) Delete(ctx context.Context, roomUUID uuid.UUID) er
 	return err
 }
 
+// BumpKeyEpoch увеличивает эпоху ключа комнаты и возвращает новое значение
+func (r *RoomWriteRepository) BumpKeyEpoch(ctx context.Context, roomUUID uuid.UUID) (int64, error) {
+	var epoch int64
+	err := r.db.GetContext(ctx, &epoch,
+		`UPDATE rooms
+		 SET key_epoch = key_epoch + 1,
+		     updated_at = $2
+		 WHERE room_uuid = $1
+		 RETURNING key_epoch`,
+		roomUUID, time.Now().UTC(),
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return epoch, nil
+}
+
 // RoomReadRepository реализует интерфейс получения комнат через SQL базу
 type RoomReadRepository struct {
 	db *sqlx.DB
@@ -76,3 +130,43 @@ func (r *RoomReadRepository) Get(
 	}
 	return &room, nil
 }
+
+// GetDirect возвращает личную комнату по ключу пары собеседников или nil, если не найдена
+func (r *RoomReadRepository) GetDirect(
+	ctx context.Context,
+	directKey uuid.UUID,
+) (*models.RoomDB, error) {
+	var room models.RoomDB
+	err := r.db.GetContext(ctx, &room, "SELECT * FROM rooms WHERE direct_key = $1", directKey)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &room, nil
+}
+
+// ListByUser возвращает комнаты, в которых состоит пользователь, с его ролью, количеством участников
+// и временем последнего сообщения; комнаты с недавней активностью идут первыми
+func (r *RoomReadRepository) ListByUser(
+	ctx context.Context,
+	userUUID uuid.UUID,
+) ([]models.RoomSummaryDB, error) {
+	var rooms []models.RoomSummaryDB
+	err := r.db.SelectContext(ctx, &rooms,
+		`SELECT * FROM (
+		     SELECT r.room_uuid, r.creator_uuid, r.name, r.topic, r.kind, m.role, r.created_at,
+		            (SELECT COUNT(*) FROM room_members c WHERE c.room_uuid = r.room_uuid) AS member_count,
+		            (SELECT msg.sent_at FROM room_messages msg
+		              WHERE msg.room_uuid = r.room_uuid
+		              ORDER BY msg.sent_at DESC LIMIT 1) AS last_message_at
+		     FROM rooms r
+		     JOIN room_members m ON m.room_uuid = r.room_uuid
+		     WHERE m.user_uuid = $1
+		 ) user_rooms
+		 ORDER BY COALESCE(last_message_at, created_at) DESC, room_uuid`,
+		userUUID,
+	)
+	return rooms, err
+}
@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// RoomInviteWriteRepository реализует запись приглашений в комнаты через SQL
+type RoomInviteWriteRepository struct {
+	db *sqlx.DB
+}
+
+// NewRoomInviteWriteRepository создаёт новый репозиторий для записи приглашений
+func NewRoomInviteWriteRepository(db *sqlx.DB) *RoomInviteWriteRepository {
+	return &RoomInviteWriteRepository{db: db}
+}
+
+// Save сохраняет ожидающее ответа приглашение пользователя inviteeUUID в комнату.
+// Если у пользователя уже есть ожидающее приглашение в эту комнату, ничего не меняет.
+func (r *RoomInviteWriteRepository) Save(
+	ctx context.Context,
+	inviteUUID uuid.UUID,
+	roomUUID uuid.UUID,
+	inviterUUID uuid.UUID,
+	inviteeUUID uuid.UUID,
+) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO room_invites (invite_uuid, room_uuid, inviter_uuid, invitee_uuid, status, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 ON CONFLICT (room_uuid, invitee_uuid) WHERE status = 'pending' DO NOTHING`,
+		inviteUUID, roomUUID, inviterUUID, inviteeUUID, models.RoomInviteStatusPending, now, now,
+	)
+	return err
+}
+
+// Resolve переводит ожидающее приглашение в статус status.
+// Возвращает false, если приглашения нет или на него уже ответили.
+func (r *RoomInviteWriteRepository) Resolve(ctx context.Context, inviteUUID uuid.UUID, status string) (bool, error) {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE room_invites SET status = $1, updated_at = $2 WHERE invite_uuid = $3 AND status = $4`,
+		status, time.Now().UTC(), inviteUUID, models.RoomInviteStatusPending,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Reopen возвращает принятое приглашение в статус ожидания, если добавить пользователя в комнату не удалось
+func (r *RoomInviteWriteRepository) Reopen(ctx context.Context, inviteUUID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE room_invites SET status = $1, updated_at = $2 WHERE invite_uuid = $3 AND status = $4`,
+		models.RoomInviteStatusPending, time.Now().UTC(), inviteUUID, models.RoomInviteStatusAccepted,
+	)
+	return err
+}
+
+// RoomInviteReadRepository реализует чтение приглашений в комнаты через SQL
+type RoomInviteReadRepository struct {
+	db *sqlx.DB
+}
+
+// NewRoomInviteReadRepository создаёт новый репозиторий для чтения приглашений
+func NewRoomInviteReadRepository(db *sqlx.DB) *RoomInviteReadRepository {
+	return &RoomInviteReadRepository{db: db}
+}
+
+// Get возвращает приглашение по UUID или nil, если не найдено
+func (r *RoomInviteReadRepository) Get(ctx context.Context, inviteUUID uuid.UUID) (*models.RoomInviteDB, error) {
+	var invite models.RoomInviteDB
+	err := r.db.GetContext(ctx, &invite,
+		`SELECT * FROM room_invites WHERE invite_uuid = $1`, inviteUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// GetPending возвращает ожидающее ответа приглашение пользователя в комнату или nil, если его нет
+func (r *RoomInviteReadRepository) GetPending(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	inviteeUUID uuid.UUID,
+) (*models.RoomInviteDB, error) {
+	var invite models.RoomInviteDB
+	err := r.db.GetContext(ctx, &invite,
+		`SELECT * FROM room_invites WHERE room_uuid = $1 AND invitee_uuid = $2 AND status = $3`,
+		roomUUID, inviteeUUID, models.RoomInviteStatusPending,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &invite, nil
+}
+
+// ListPending возвращает ожидающие ответа приглашения пользователя, начиная с самых новых
+func (r *RoomInviteReadRepository) ListPending(
+	ctx context.Context,
+	inviteeUUID uuid.UUID,
+) ([]models.RoomInviteDetailsDB, error) {
+	var invites []models.RoomInviteDetailsDB
+	err := r.db.SelectContext(ctx, &invites,
+		`SELECT i.*, r.name AS room_name, u.username AS inviter_username
+		 FROM room_invites i
+		 JOIN rooms r ON r.room_uuid = i.room_uuid
+		 JOIN users u ON u.user_uuid = i.inviter_uuid
+		 WHERE i.invitee_uuid = $1 AND i.status = $2
+		 ORDER BY i.created_at DESC, i.invite_uuid`,
+		inviteeUUID, models.RoomInviteStatusPending,
+	)
+	return invites, err
+}
@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// RoomInviteLinkWriteRepository реализует запись ссылок-приглашений через SQL
+type RoomInviteLinkWriteRepository struct {
+	db *sqlx.DB
+}
+
+// NewRoomInviteLinkWriteRepository создаёт новый репозиторий для записи ссылок-приглашений
+func NewRoomInviteLinkWriteRepository(db *sqlx.DB) *RoomInviteLinkWriteRepository {
+	return &RoomInviteLinkWriteRepository{db: db}
+}
+
+// Save сохраняет ссылку-приглашение в комнату со сроком действия и ограничением числа входов (0 — без ограничения)
+func (r *RoomInviteLinkWriteRepository) Save(
+	ctx context.Context,
+	linkUUID uuid.UUID,
+	roomUUID uuid.UUID,
+	creatorUUID uuid.UUID,
+	expiresAt time.Time,
+	maxUses int,
+) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO room_invite_links (link_uuid, room_uuid, creator_uuid, expires_at, max_uses, uses, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, 0, $6, $7)`,
+		linkUUID, roomUUID, creatorUUID, expiresAt.UTC(), maxUses, now, now,
+	)
+	return err
+}
+
+// Use учитывает вход по ссылке, если в момент now она действует и лимит входов не исчерпан.
+// Возвращает false, если ссылки нет, её срок истёк или входы закончились.
+func (r *RoomInviteLinkWriteRepository) Use(ctx context.Context, linkUUID uuid.UUID, now time.Time) (bool, error) {
+	now = now.UTC()
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE room_invite_links
+		 SET uses = uses + 1, updated_at = $1
+		 WHERE link_uuid = $2 AND expires_at > $3 AND (max_uses = 0 OR uses < max_uses)`,
+		now, linkUUID, now,
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// Release возвращает вход по ссылке, учтённый Use, если добавить пользователя в комнату не удалось
+func (r *RoomInviteLinkWriteRepository) Release(ctx context.Context, linkUUID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE room_invite_links SET uses = uses - 1, updated_at = $1 WHERE link_uuid = $2 AND uses > 0`,
+		time.Now().UTC(), linkUUID,
+	)
+	return err
+}
+
+// Delete удаляет ссылку-приглашение
+func (r *RoomInviteLinkWriteRepository) Delete(ctx context.Context, linkUUID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM room_invite_links WHERE link_uuid = $1`, linkUUID)
+	return err
+}
+
+// RoomInviteLinkReadRepository реализует чтение ссылок-приглашений через SQL
+type RoomInviteLinkReadRepository struct {
+	db *sqlx.DB
+}
+
+// NewRoomInviteLinkReadRepository создаёт новый репозиторий для чтения ссылок-приглашений
+func NewRoomInviteLinkReadRepository(db *sqlx.DB) *RoomInviteLinkReadRepository {
+	return &RoomInviteLinkReadRepository{db: db}
+}
+
+// Get возвращает ссылку-приглашение по UUID или nil, если не найдена
+func (r *RoomInviteLinkReadRepository) Get(ctx context.Context, linkUUID uuid.UUID) (*models.RoomInviteLinkDB, error) {
+	var link models.RoomInviteLinkDB
+	err := r.db.GetContext(ctx, &link,
+		`SELECT * FROM room_invite_links WHERE link_uuid = $1`, linkUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &link, nil
+}
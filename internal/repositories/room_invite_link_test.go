@@ -0,0 +1,116 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupRoomInviteLinksDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE room_invite_links (
+		link_uuid TEXT PRIMARY KEY,
+		room_uuid TEXT NOT NULL,
+		creator_uuid TEXT NOT NULL,
+		expires_at DATETIME NOT NULL,
+		max_uses INTEGER NOT NULL DEFAULT 0,
+		uses INTEGER NOT NULL DEFAULT 0,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestRoomInviteLinkWriteAndRead(t *testing.T) {
+	db := setupRoomInviteLinksDB(t)
+	writeRepo := repositories.NewRoomInviteLinkWriteRepository(db)
+	readRepo := repositories.NewRoomInviteLinkReadRepository(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	linkUUID := uuid.New()
+	roomUUID := uuid.New()
+	creatorUUID := uuid.New()
+	require.NoError(t, writeRepo.Save(ctx, linkUUID, roomUUID, creatorUUID, now.Add(time.Hour), 2))
+
+	link, err := readRepo.Get(ctx, linkUUID)
+	require.NoError(t, err)
+	require.NotNil(t, link)
+	assert.Equal(t, roomUUID, link.RoomUUID)
+	assert.Equal(t, creatorUUID, link.CreatorUUID)
+	assert.Equal(t, 2, link.MaxUses)
+	assert.Equal(t, 0, link.Uses)
+	assert.WithinDuration(t, now.Add(time.Hour), link.ExpiresAt, time.Second)
+
+	// Два входа разрешены, третий — нет
+	for i := 0; i < 2; i++ {
+		ok, err := writeRepo.Use(ctx, linkUUID, now)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, err := writeRepo.Use(ctx, linkUUID, now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	link, err = readRepo.Get(ctx, linkUUID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, link.Uses)
+
+	// Возвращённый вход можно использовать снова
+	require.NoError(t, writeRepo.Release(ctx, linkUUID))
+	ok, err = writeRepo.Use(ctx, linkUUID, now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// По удалённой ссылке войти нельзя
+	require.NoError(t, writeRepo.Delete(ctx, linkUUID))
+	link, err = readRepo.Get(ctx, linkUUID)
+	require.NoError(t, err)
+	assert.Nil(t, link)
+	ok, err = writeRepo.Use(ctx, linkUUID, now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRoomInviteLinkExpiry(t *testing.T) {
+	db := setupRoomInviteLinksDB(t)
+	writeRepo := repositories.NewRoomInviteLinkWriteRepository(db)
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	linkUUID := uuid.New()
+	require.NoError(t, writeRepo.Save(ctx, linkUUID, uuid.New(), uuid.New(), now.Add(time.Minute), 0))
+
+	// Без ограничения числа входов ссылка действует до истечения срока
+	for i := 0; i < 3; i++ {
+		ok, err := writeRepo.Use(ctx, linkUUID, now)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+
+	ok, err := writeRepo.Use(ctx, linkUUID, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = writeRepo.Use(ctx, uuid.New(), now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	link, err := repositories.NewRoomInviteLinkReadRepository(db).Get(ctx, uuid.New())
+	require.NoError(t, err)
+	assert.Nil(t, link)
+}
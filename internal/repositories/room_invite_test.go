@@ -0,0 +1,128 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/repositories"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupRoomInvitesDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite", ":memory:")
+	require.NoError(t, err)
+
+	schema := `
+	CREATE TABLE users (
+		user_uuid TEXT PRIMARY KEY,
+		username TEXT UNIQUE NOT NULL,
+		password_hash TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	CREATE TABLE rooms (
+		room_uuid TEXT PRIMARY KEY,
+		creator_uuid TEXT NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		topic TEXT NOT NULL DEFAULT '',
+		kind TEXT NOT NULL DEFAULT 'group',
+		direct_key TEXT UNIQUE,
+		key_epoch INTEGER NOT NULL DEFAULT 1,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	CREATE TABLE room_invites (
+		invite_uuid TEXT PRIMARY KEY,
+		room_uuid TEXT NOT NULL,
+		inviter_uuid TEXT NOT NULL,
+		invitee_uuid TEXT NOT NULL,
+		status TEXT NOT NULL DEFAULT 'pending',
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);
+	CREATE UNIQUE INDEX room_invites_pending_idx ON room_invites (room_uuid, invitee_uuid) WHERE status = 'pending';`
+	_, err = db.Exec(schema)
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestRoomInviteWriteAndRead(t *testing.T) {
+	db := setupRoomInvitesDB(t)
+	writeRepo := repositories.NewRoomInviteWriteRepository(db)
+	readRepo := repositories.NewRoomInviteReadRepository(db)
+	ctx := context.Background()
+
+	inviterUUID := uuid.New()
+	inviteeUUID := uuid.New()
+	require.NoError(t, repositories.NewUserWriteRepository(db).Save(ctx, inviterUUID, "alice", "hash"))
+	roomUUID := uuid.New()
+	require.NoError(t, repositories.NewRoomWriteRepository(db).Save(ctx, roomUUID, inviterUUID, "Команда", ""))
+
+	inviteUUID := uuid.New()
+	require.NoError(t, writeRepo.Save(ctx, inviteUUID, roomUUID, inviterUUID, inviteeUUID))
+
+	// Повторное приглашение не создаёт второе ожидающее приглашение
+	require.NoError(t, writeRepo.Save(ctx, uuid.New(), roomUUID, inviterUUID, inviteeUUID))
+
+	invite, err := readRepo.GetPending(ctx, roomUUID, inviteeUUID)
+	require.NoError(t, err)
+	require.NotNil(t, invite)
+	assert.Equal(t, inviteUUID, invite.InviteUUID)
+	assert.Equal(t, models.RoomInviteStatusPending, invite.Status)
+
+	invites, err := readRepo.ListPending(ctx, inviteeUUID)
+	require.NoError(t, err)
+	require.Len(t, invites, 1)
+	assert.Equal(t, inviteUUID, invites[0].InviteUUID)
+	assert.Equal(t, "Команда", invites[0].RoomName)
+	assert.Equal(t, "alice", invites[0].InviterUsername)
+
+	// Принятое приглашение можно вернуть в ожидание, если вход в комнату не удался
+	ok, err := writeRepo.Resolve(ctx, inviteUUID, models.RoomInviteStatusAccepted)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	require.NoError(t, writeRepo.Reopen(ctx, inviteUUID))
+
+	// Ответить на приглашение можно только один раз
+	ok, err = writeRepo.Resolve(ctx, inviteUUID, models.RoomInviteStatusDeclined)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = writeRepo.Resolve(ctx, inviteUUID, models.RoomInviteStatusAccepted)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	invite, err = readRepo.Get(ctx, inviteUUID)
+	require.NoError(t, err)
+	require.NotNil(t, invite)
+	assert.Equal(t, models.RoomInviteStatusDeclined, invite.Status)
+
+	invite, err = readRepo.GetPending(ctx, roomUUID, inviteeUUID)
+	require.NoError(t, err)
+	assert.Nil(t, invite)
+
+	invites, err = readRepo.ListPending(ctx, inviteeUUID)
+	require.NoError(t, err)
+	assert.Empty(t, invites)
+
+	// После ответа пользователя можно пригласить снова
+	require.NoError(t, writeRepo.Save(ctx, uuid.New(), roomUUID, inviterUUID, inviteeUUID))
+	invite, err = readRepo.GetPending(ctx, roomUUID, inviteeUUID)
+	require.NoError(t, err)
+	assert.NotNil(t, invite)
+}
+
+func TestGetNonExistingRoomInvite(t *testing.T) {
+	db := setupRoomInvitesDB(t)
+	readRepo := repositories.NewRoomInviteReadRepository(db)
+
+	invite, err := readRepo.Get(context.Background(), uuid.New())
+	assert.NoError(t, err)
+	assert.Nil(t, invite)
+}
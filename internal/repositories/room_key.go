@@ -0,0 +1,159 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// RoomKeyWriteRepository реализует запись ключей комнаты через SQL
+type RoomKeyWriteRepository struct {
+	db *sqlx.DB
+}
+
+// NewRoomKeyWriteRepository создаёт новый репозиторий для записи ключей комнаты
+func NewRoomKeyWriteRepository(db *sqlx.DB) *RoomKeyWriteRepository {
+	return &RoomKeyWriteRepository{db: db}
+}
+
+// SaveEpoch в одной транзакции сохраняет ключ комнаты эпохи keyEpoch, зашифрованный для каждого из устройств keys
+// (deviceUUID -> ключ) и подписанный загружающим устройством uploaderUUID, и возвращает текущую эпоху комнаты.
+// Ключ сохраняется, только если keyEpoch — текущая эпоха комнаты: строка комнаты блокируется до конца транзакции,
+// поэтому изменение состава, начинающее новую эпоху, не может произойти между проверкой и сохранением.
+// Первая загрузка эпохи выпускает её ключ. Если ключ эпохи уже выпущен, сохраняются
+// только ключи устройств, у которых его ещё нет, и только когда ключ эпохи уже есть у загружающего
+// устройства uploaderUUID: оно пересылает выпущенный ключ, а не выпускает свой. Иначе возвращает false
+// и ничего не меняет. Ключ эпохи неизменяем: уже сохранённый для устройства ключ не перезаписывается.
+func (r *RoomKeyWriteRepository) SaveEpoch(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	uploaderUUID uuid.UUID,
+	keyEpoch int64,
+	keys map[uuid.UUID]models.SignedRoomKey,
+) (int64, bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	defer tx.Rollback()
+
+	var currentEpoch int64
+	err = tx.GetContext(ctx, &currentEpoch,
+		`SELECT key_epoch FROM rooms WHERE room_uuid = $1`+forUpdate(r.db),
+		roomUUID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	if currentEpoch != keyEpoch {
+		return currentEpoch, false, nil
+	}
+
+	now := time.Now().UTC()
+
+	// параллельная загрузка той же эпохи ждёт завершения этой транзакции на первичном ключе
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO room_key_epochs (room_uuid, key_epoch, created_at)
+		 VALUES ($1, $2, $3)
+		 ON CONFLICT (room_uuid, key_epoch)
+		 DO NOTHING`,
+		roomUUID, keyEpoch, now,
+	)
+	if err != nil {
+		return 0, false, err
+	}
+	issued, err := res.RowsAffected()
+	if err != nil {
+		return 0, false, err
+	}
+
+	if issued == 0 {
+		var count int
+		err := tx.GetContext(ctx, &count,
+			`SELECT COUNT(*) FROM room_keys WHERE room_uuid = $1 AND device_uuid = $2 AND key_epoch = $3`,
+			roomUUID, uploaderUUID, keyEpoch,
+		)
+		if err != nil {
+			return 0, false, err
+		}
+		if count == 0 {
+			return currentEpoch, false, nil
+		}
+	}
+
+	for deviceUUID, key := range keys {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO room_keys (room_uuid, device_uuid, key_epoch, encrypted_key, issuer_device_uuid, signature, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			 ON CONFLICT (room_uuid, device_uuid, key_epoch)
+			 DO NOTHING`,
+			roomUUID, deviceUUID, keyEpoch, key.EncryptedKey, uploaderUUID, key.Signature, now, now,
+		)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, false, err
+	}
+	return currentEpoch, true, nil
+}
+
+// forUpdate возвращает блокировку прочитанных строк до конца транзакции.
+// SQLite не поддерживает FOR UPDATE: транзакция записи в нём и так блокирует базу целиком.
+func forUpdate(db *sqlx.DB) string {
+	if db.DriverName() == "sqlite" {
+		return ""
+	}
+	return " FOR UPDATE"
+}
+
+// DeleteByDevice удаляет ключи всех комнат, зашифрованные для устройства
+func (r *RoomKeyWriteRepository) DeleteByDevice(ctx context.Context, deviceUUID uuid.UUID) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM room_keys WHERE device_uuid = $1`, deviceUUID)
+	return err
+}
+
+// RoomKeyReadRepository реализует чтение ключей комнаты через SQL
+type RoomKeyReadRepository struct {
+	db *sqlx.DB
+}
+
+// NewRoomKeyReadRepository создаёт новый репозиторий для чтения ключей комнаты
+func NewRoomKeyReadRepository(db *sqlx.DB) *RoomKeyReadRepository {
+	return &RoomKeyReadRepository{db: db}
+}
+
+// Get возвращает ключ комнаты эпохи keyEpoch для устройства вместе с пользователем и ключом подписи
+// загрузившего его устройства или nil, если ключ не найден. Загрузившее устройство могло быть отозвано
+// или покинуть комнату, но его ключ подписи всё равно возвращается, чтобы клиент проверил подпись ключа.
+func (r *RoomKeyReadRepository) Get(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	keyEpoch int64,
+) (*models.IssuedRoomKeyDB, error) {
+	var key models.IssuedRoomKeyDB
+	err := r.db.GetContext(ctx, &key,
+		`SELECT k.*, d.user_uuid AS issuer_user_uuid, COALESCE(d.signing_key, '') AS issuer_signing_key
+		 FROM room_keys AS k
+		 LEFT JOIN user_devices AS d ON d.device_uuid = k.issuer_device_uuid
+		 WHERE k.room_uuid = $1 AND k.device_uuid = $2 AND k.key_epoch = $3`,
+		roomUUID, deviceUUID, keyEpoch,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
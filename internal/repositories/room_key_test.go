@@ -0,0 +1,242 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/repositories"
+	"github.com/stretchr/testify/assert"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupRoomKeysDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite", ":memory:")
+	assert.NoError(t, err)
+
+	schema := `
+	CREATE TABLE room_keys (
+		room_uuid     TEXT NOT NULL,
+		device_uuid   TEXT NOT NULL,
+		key_epoch     INTEGER NOT NULL DEFAULT 1,
+		encrypted_key TEXT NOT NULL,
+		issuer_device_uuid TEXT,
+		signature     TEXT NOT NULL DEFAULT '',
+		created_at    DATETIME NOT NULL,
+		updated_at    DATETIME NOT NULL,
+		PRIMARY KEY (room_uuid, device_uuid, key_epoch)
+	);
+	CREATE TABLE room_key_epochs (
+		room_uuid  TEXT NOT NULL,
+		key_epoch  INTEGER NOT NULL,
+		created_at DATETIME NOT NULL,
+		PRIMARY KEY (room_uuid, key_epoch)
+	);
+	CREATE TABLE user_devices (
+		device_uuid TEXT PRIMARY KEY,
+		user_uuid   TEXT NOT NULL,
+		signing_key TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE rooms (
+		room_uuid TEXT PRIMARY KEY,
+		key_epoch INTEGER NOT NULL DEFAULT 1
+	);`
+	_, err = db.Exec(schema)
+	assert.NoError(t, err)
+
+	return db
+}
+
+// setRoomEpoch создаёт комнату или задаёт её текущую эпоху ключа
+func setRoomEpoch(t *testing.T, db *sqlx.DB, roomUUID uuid.UUID, keyEpoch int64) {
+	_, err := db.Exec(
+		`INSERT INTO rooms (room_uuid, key_epoch) VALUES ($1, $2)
+		 ON CONFLICT (room_uuid) DO UPDATE SET key_epoch = excluded.key_epoch`,
+		roomUUID, keyEpoch,
+	)
+	assert.NoError(t, err)
+}
+
+func TestRoomKeyWriteAndRead(t *testing.T) {
+	db := setupRoomKeysDB(t)
+	writeRepo := repositories.NewRoomKeyWriteRepository(db)
+	readRepo := repositories.NewRoomKeyReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	deviceUUID := uuid.New()
+	otherDeviceUUID := uuid.New()
+
+	userUUID := uuid.New()
+	_, err := db.Exec(`INSERT INTO user_devices (device_uuid, user_uuid, signing_key) VALUES ($1, $2, $3)`,
+		deviceUUID, userUUID, "signing-key")
+	assert.NoError(t, err)
+	setRoomEpoch(t, db, roomUUID, 1)
+
+	epoch, ok, err := writeRepo.SaveEpoch(ctx, roomUUID, deviceUUID, 1, map[uuid.UUID]models.SignedRoomKey{
+		deviceUUID: {EncryptedKey: "wrapped-1", Signature: "signature-1"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), epoch)
+
+	key, err := readRepo.Get(ctx, roomUUID, deviceUUID, 1)
+	assert.NoError(t, err)
+	assert.NotNil(t, key)
+	assert.Equal(t, roomUUID, key.RoomUUID)
+	assert.Equal(t, deviceUUID, key.DeviceUUID)
+	assert.Equal(t, int64(1), key.KeyEpoch)
+	assert.Equal(t, "wrapped-1", key.EncryptedKey)
+
+	// ключ подписан загрузившим устройством
+	assert.Equal(t, "signature-1", key.Signature)
+	assert.Equal(t, &deviceUUID, key.IssuerDeviceUUID)
+	assert.Equal(t, &userUUID, key.IssuerUserUUID)
+	assert.Equal(t, "signing-key", key.IssuerSigningKey)
+
+	// ключ эпохи неизменяем: повторная загрузка не заменяет его, а пересылает ключ новым устройствам
+	_, ok, err = writeRepo.SaveEpoch(ctx, roomUUID, deviceUUID, 1, map[uuid.UUID]models.SignedRoomKey{
+		deviceUUID:      {EncryptedKey: "wrapped-2"},
+		otherDeviceUUID: {EncryptedKey: "wrapped-other"},
+	})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	key, err = readRepo.Get(ctx, roomUUID, deviceUUID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "wrapped-1", key.EncryptedKey)
+
+	key, err = readRepo.Get(ctx, roomUUID, otherDeviceUUID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "wrapped-other", key.EncryptedKey)
+
+	// ключ новой эпохи хранится отдельно, старый остаётся доступен
+	setRoomEpoch(t, db, roomUUID, 2)
+	epoch, ok, err = writeRepo.SaveEpoch(ctx, roomUUID, deviceUUID, 2, map[uuid.UUID]models.SignedRoomKey{deviceUUID: {EncryptedKey: "wrapped-2"}})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), epoch)
+
+	key, err = readRepo.Get(ctx, roomUUID, deviceUUID, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "wrapped-2", key.EncryptedKey)
+
+	key, err = readRepo.Get(ctx, roomUUID, deviceUUID, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "wrapped-1", key.EncryptedKey)
+
+	// ключ для другого устройства отсутствует
+	key, err = readRepo.Get(ctx, roomUUID, uuid.New(), 1)
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	// ключ ещё не наступившей эпохи отсутствует
+	key, err = readRepo.Get(ctx, roomUUID, deviceUUID, 3)
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+}
+
+func TestRoomKeySaveEpochConflict(t *testing.T) {
+	db := setupRoomKeysDB(t)
+	writeRepo := repositories.NewRoomKeyWriteRepository(db)
+	readRepo := repositories.NewRoomKeyReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	first, second, third := uuid.New(), uuid.New(), uuid.New()
+	setRoomEpoch(t, db, roomUUID, 2)
+
+	_, ok, err := writeRepo.SaveEpoch(ctx, roomUUID, first, 2, map[uuid.UUID]models.SignedRoomKey{first: {EncryptedKey: "first-1"}, second: {EncryptedKey: "first-2"}})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// Устройство без ключа эпохи выпустило свой ключ после первого: загрузка отклоняется целиком
+	_, ok, err = writeRepo.SaveEpoch(ctx, roomUUID, third, 2, map[uuid.UUID]models.SignedRoomKey{
+		first:  {EncryptedKey: "third-1"},
+		second: {EncryptedKey: "third-2"},
+		third:  {EncryptedKey: "third-3"},
+	})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	key, err := readRepo.Get(ctx, roomUUID, third, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	key, err = readRepo.Get(ctx, roomUUID, second, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, "first-2", key.EncryptedKey)
+}
+
+func TestRoomKeySaveEpochStale(t *testing.T) {
+	db := setupRoomKeysDB(t)
+	writeRepo := repositories.NewRoomKeyWriteRepository(db)
+	readRepo := repositories.NewRoomKeyReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	deviceUUID := uuid.New()
+	setRoomEpoch(t, db, roomUUID, 3)
+
+	// ключ прошлой эпохи не сохраняется, возвращается текущая эпоха комнаты
+	epoch, ok, err := writeRepo.SaveEpoch(ctx, roomUUID, deviceUUID, 2, map[uuid.UUID]models.SignedRoomKey{deviceUUID: {EncryptedKey: "stale"}})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(3), epoch)
+
+	key, err := readRepo.Get(ctx, roomUUID, deviceUUID, 2)
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	// ключ ещё не наступившей эпохи тоже
+	epoch, ok, err = writeRepo.SaveEpoch(ctx, roomUUID, deviceUUID, 4, map[uuid.UUID]models.SignedRoomKey{deviceUUID: {EncryptedKey: "future"}})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(3), epoch)
+
+	// для несуществующей комнаты ничего не сохраняется
+	epoch, ok, err = writeRepo.SaveEpoch(ctx, uuid.New(), deviceUUID, 1, map[uuid.UUID]models.SignedRoomKey{deviceUUID: {EncryptedKey: "none"}})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), epoch)
+}
+
+func TestRoomKeyDeleteByDevice(t *testing.T) {
+	db := setupRoomKeysDB(t)
+	writeRepo := repositories.NewRoomKeyWriteRepository(db)
+	readRepo := repositories.NewRoomKeyReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	otherRoomUUID := uuid.New()
+	deviceUUID := uuid.New()
+	otherDeviceUUID := uuid.New()
+	setRoomEpoch(t, db, roomUUID, 1)
+	setRoomEpoch(t, db, otherRoomUUID, 1)
+
+	_, _, err := writeRepo.SaveEpoch(ctx, roomUUID, deviceUUID, 1, map[uuid.UUID]models.SignedRoomKey{
+		deviceUUID:      {EncryptedKey: "wrapped-1"},
+		otherDeviceUUID: {EncryptedKey: "wrapped-3"},
+	})
+	assert.NoError(t, err)
+	_, _, err = writeRepo.SaveEpoch(ctx, otherRoomUUID, deviceUUID, 1, map[uuid.UUID]models.SignedRoomKey{deviceUUID: {EncryptedKey: "wrapped-2"}})
+	assert.NoError(t, err)
+
+	assert.NoError(t, writeRepo.DeleteByDevice(ctx, deviceUUID))
+
+	key, err := readRepo.Get(ctx, roomUUID, deviceUUID, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	key, err = readRepo.Get(ctx, otherRoomUUID, deviceUUID, 1)
+	assert.NoError(t, err)
+	assert.Nil(t, key)
+
+	// ключи других устройств не затрагиваются
+	key, err = readRepo.Get(ctx, roomUUID, otherDeviceUUID, 1)
+	assert.NoError(t, err)
+	assert.NotNil(t, key)
+}
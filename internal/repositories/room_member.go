@@ -2,6 +2,7 @@ package repositories
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,27 +20,89 @@ func NewRoomMemberWriteRepository(db *sqlx.DB) *RoomMemberWriteRepository {
 	return &RoomMemberWriteRepository{db: db}
 }
 
-// Save добавляет пользователя в комнату.
-// Если запись уже существует, обновляется только updated_at.
+// Save добавляет пользователя в комнату с указанной ролью.
+// Если запись уже существует, обновляется только updated_at (роль не меняется).
 func (r *RoomMemberWriteRepository) Save(
 	ctx context.Context,
 	roomUUID uuid.UUID,
 	userUUID uuid.UUID,
+	role string,
 	joinedAt time.Time,
 ) error {
 	now := time.Now().UTC()
 
 	_, err := r.db.ExecContext(ctx,
-		`INSERT INTO room_members (room_uuid, user_uuid, joined_at, created_at, updated_at)
-		 VALUES ($1, $2, $3, $4, $5)
+		`INSERT INTO room_members (room_uuid, user_uuid, role, joined_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
 		 ON CONFLICT (room_uuid, user_uuid)
 		 DO UPDATE
 		 SET updated_at = EXCLUDED.updated_at`,
-		roomUUID, userUUID, joinedAt, now, now,
+		roomUUID, userUUID, role, joinedAt, now, now,
 	)
 	return err
 }
 
+// UpdateRole изменяет роль участника комнаты
+func (r *RoomMemberWriteRepository) UpdateRole(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	userUUID uuid.UUID,
+	role string,
+) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE room_members SET role = $1, updated_at = $2 WHERE room_uuid = $3 AND user_uuid = $4`,
+		role, time.Now().UTC(), roomUUID, userUUID,
+	)
+	return err
+}
+
+// TransferOwnership в одной транзакции передаёт роль владельца комнаты от fromUUID к toUUID,
+// а прежний владелец становится администратором. Возвращает false и ничего не меняет,
+// если fromUUID уже не владелец комнаты или toUUID не её участник.
+func (r *RoomMemberWriteRepository) TransferOwnership(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	fromUUID uuid.UUID,
+	toUUID uuid.UUID,
+) (bool, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+
+	res, err := tx.ExecContext(ctx,
+		`UPDATE room_members SET role = $1, updated_at = $2
+		 WHERE room_uuid = $3 AND user_uuid = $4 AND role = $5`,
+		models.RoomRoleAdmin, now, roomUUID, fromUUID, models.RoomRoleOwner,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	res, err = tx.ExecContext(ctx,
+		`UPDATE room_members SET role = $1, updated_at = $2
+		 WHERE room_uuid = $3 AND user_uuid = $4`,
+		models.RoomRoleOwner, now, roomUUID, toUUID,
+	)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
 // Delete удаляет конкретного пользователя из комнаты
 func (r *RoomMemberWriteRepository) Delete(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error {
 	_, err := r.db.ExecContext(ctx,
@@ -49,6 +112,86 @@ func (r *RoomMemberWriteRepository) Delete(ctx context.Context, roomUUID uuid.UU
 	return err
 }
 
+// SaveWithKeyRotation в одной транзакции добавляет пользователя в комнату с указанной ролью
+// и увеличивает эпоху ключа комнаты, чтобы новый участник не получил ключ, которым шифровались сообщения до него.
+// Возвращает новую эпоху или 0, если пользователь уже состоит в комнате: тогда ничего не меняется.
+func (r *RoomMemberWriteRepository) SaveWithKeyRotation(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	userUUID uuid.UUID,
+	role string,
+	joinedAt time.Time,
+) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO room_members (room_uuid, user_uuid, role, joined_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (room_uuid, user_uuid)
+		 DO NOTHING`,
+		roomUUID, userUUID, role, joinedAt, now, now,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return bumpKeyEpochTx(ctx, tx, res, roomUUID, now)
+}
+
+// DeleteWithKeyRotation в одной транзакции удаляет пользователя из комнаты и увеличивает эпоху ключа комнаты,
+// чтобы удалённый участник не смог расшифровать сообщения, отправленные после удаления, даже если сервер
+// остановится до закрытия его соединений. Возвращает новую эпоху или 0, если пользователь не состоял в комнате.
+func (r *RoomMemberWriteRepository) DeleteWithKeyRotation(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) (int64, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`DELETE FROM room_members WHERE room_uuid = $1 AND user_uuid = $2`,
+		roomUUID, userUUID,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return bumpKeyEpochTx(ctx, tx, res, roomUUID, time.Now().UTC())
+}
+
+// bumpKeyEpochTx увеличивает эпоху ключа комнаты и фиксирует транзакцию, если изменение состава res
+// затронуло участника; иначе возвращает 0, и транзакция откатывается вызывающим.
+func bumpKeyEpochTx(ctx context.Context, tx *sqlx.Tx, res sql.Result, roomUUID uuid.UUID, now time.Time) (int64, error) {
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return 0, err
+	}
+
+	var epoch int64
+	err = tx.GetContext(ctx, &epoch,
+		`UPDATE rooms
+		 SET key_epoch = key_epoch + 1,
+		     updated_at = $2
+		 WHERE room_uuid = $1
+		 RETURNING key_epoch`,
+		roomUUID, now,
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return epoch, nil
+}
+
 // RoomMemberReadRepository реализует чтение участников комнаты через SQL
 type RoomMemberReadRepository struct {
 	db *sqlx.DB
@@ -88,3 +231,20 @@ func (r *RoomMemberReadRepository) Get(
 	}
 	return &member, nil
 }
+
+// ListWithUsers возвращает участников комнаты с именами пользователей в порядке присоединения
+func (r *RoomMemberReadRepository) ListWithUsers(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+) ([]models.RoomMemberUserDB, error) {
+	var members []models.RoomMemberUserDB
+	err := r.db.SelectContext(ctx, &members,
+		`SELECT m.*, u.username
+		 FROM room_members m
+		 JOIN users u ON u.user_uuid = m.user_uuid
+		 WHERE m.room_uuid = $1
+		 ORDER BY m.joined_at, u.username`,
+		roomUUID,
+	)
+	return members, err
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
 	"github.com/sbilibin2017/bil-message/internal/repositories"
 	"github.com/stretchr/testify/assert"
 
@@ -21,6 +22,7 @@ func setupRoomMembersDB(t *testing.T) *sqlx.DB {
 	CREATE TABLE room_members (
 		room_uuid TEXT NOT NULL,
 		user_uuid TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT 'member',
 		joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME NOT NULL,
@@ -42,7 +44,7 @@ func TestRoomMemberWriteAndRead(t *testing.T) {
 	userUUID := uuid.New()
 
 	// Save new member
-	err := writeRepo.Save(ctx, roomUUID, userUUID, time.Time{})
+	err := writeRepo.Save(ctx, roomUUID, userUUID, models.RoomRoleMember, time.Time{})
 	assert.NoError(t, err)
 
 	// Get by roomUUID + userUUID
@@ -55,7 +57,7 @@ func TestRoomMemberWriteAndRead(t *testing.T) {
 	// Save same member again (should update updated_at)
 	oldUpdatedAt := member.UpdatedAt
 	time.Sleep(1 * time.Millisecond) // чтобы timestamp точно изменился
-	err = writeRepo.Save(ctx, roomUUID, userUUID, time.Time{})
+	err = writeRepo.Save(ctx, roomUUID, userUUID, models.RoomRoleMember, time.Time{})
 	assert.NoError(t, err)
 
 	member, err = readRepo.Get(ctx, roomUUID, userUUID)
@@ -84,7 +86,7 @@ func TestListMembers(t *testing.T) {
 	userIDs := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
 
 	for _, userUUID := range userIDs {
-		err := writeRepo.Save(ctx, roomUUID, userUUID, time.Time{})
+		err := writeRepo.Save(ctx, roomUUID, userUUID, models.RoomRoleMember, time.Time{})
 		assert.NoError(t, err)
 	}
 
@@ -102,3 +104,169 @@ func TestListMembers(t *testing.T) {
 		assert.True(t, memberMap[id], "userUUID %s not found in members", id)
 	}
 }
+
+func TestRoomMemberUpdateRole(t *testing.T) {
+	db := setupRoomMembersDB(t)
+	writeRepo := repositories.NewRoomMemberWriteRepository(db)
+	readRepo := repositories.NewRoomMemberReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+
+	err := writeRepo.Save(ctx, roomUUID, userUUID, models.RoomRoleOwner, time.Time{})
+	assert.NoError(t, err)
+
+	member, err := readRepo.Get(ctx, roomUUID, userUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoomRoleOwner, member.Role)
+
+	// Повторное сохранение не меняет роль
+	err = writeRepo.Save(ctx, roomUUID, userUUID, models.RoomRoleMember, time.Time{})
+	assert.NoError(t, err)
+
+	member, err = readRepo.Get(ctx, roomUUID, userUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoomRoleOwner, member.Role)
+
+	err = writeRepo.UpdateRole(ctx, roomUUID, userUUID, models.RoomRoleAdmin)
+	assert.NoError(t, err)
+
+	member, err = readRepo.Get(ctx, roomUUID, userUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoomRoleAdmin, member.Role)
+}
+
+func TestRoomMemberTransferOwnership(t *testing.T) {
+	db := setupRoomMembersDB(t)
+	writeRepo := repositories.NewRoomMemberWriteRepository(db)
+	readRepo := repositories.NewRoomMemberReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	ownerUUID := uuid.New()
+	memberUUID := uuid.New()
+
+	assert.NoError(t, writeRepo.Save(ctx, roomUUID, ownerUUID, models.RoomRoleOwner, time.Time{}))
+	assert.NoError(t, writeRepo.Save(ctx, roomUUID, memberUUID, models.RoomRoleMember, time.Time{}))
+
+	// Новый владелец не участник комнаты — роли не меняются
+	ok, err := writeRepo.TransferOwnership(ctx, roomUUID, ownerUUID, uuid.New())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	owner, err := readRepo.Get(ctx, roomUUID, ownerUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoomRoleOwner, owner.Role)
+
+	ok, err = writeRepo.TransferOwnership(ctx, roomUUID, ownerUUID, memberUUID)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	owner, err = readRepo.Get(ctx, roomUUID, ownerUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoomRoleAdmin, owner.Role)
+
+	member, err := readRepo.Get(ctx, roomUUID, memberUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoomRoleOwner, member.Role)
+
+	// Прежний владелец уже не владелец — повторная передача не выполняется
+	ok, err = writeRepo.TransferOwnership(ctx, roomUUID, ownerUUID, memberUUID)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestListMembersWithUsers(t *testing.T) {
+	db := setupRoomMembersDB(t)
+	_, err := db.Exec(`
+	CREATE TABLE users (
+		user_uuid TEXT PRIMARY KEY,
+		username TEXT NOT NULL UNIQUE,
+		password_hash TEXT NOT NULL,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL
+	);`)
+	assert.NoError(t, err)
+
+	writeRepo := repositories.NewRoomMemberWriteRepository(db)
+	readRepo := repositories.NewRoomMemberReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	ownerUUID := uuid.New()
+	memberUUID := uuid.New()
+	now := time.Now().UTC()
+	for userUUID, username := range map[uuid.UUID]string{ownerUUID: "alice", memberUUID: "bob"} {
+		_, err := db.Exec(`INSERT INTO users (user_uuid, username, password_hash, created_at, updated_at) VALUES ($1, $2, 'hash', $3, $3)`,
+			userUUID, username, now)
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, writeRepo.Save(ctx, roomUUID, memberUUID, models.RoomRoleMember, now.Add(time.Minute)))
+	assert.NoError(t, writeRepo.Save(ctx, roomUUID, ownerUUID, models.RoomRoleOwner, now))
+	assert.NoError(t, writeRepo.Save(ctx, uuid.New(), ownerUUID, models.RoomRoleOwner, now))
+
+	members, err := readRepo.ListWithUsers(ctx, roomUUID)
+	assert.NoError(t, err)
+	if assert.Len(t, members, 2) {
+		assert.Equal(t, ownerUUID, members[0].UserUUID)
+		assert.Equal(t, "alice", members[0].Username)
+		assert.Equal(t, models.RoomRoleOwner, members[0].Role)
+		assert.Equal(t, memberUUID, members[1].UserUUID)
+		assert.Equal(t, "bob", members[1].Username)
+		assert.Equal(t, roomUUID, members[1].RoomUUID)
+	}
+}
+
+func TestRoomMemberWithKeyRotation(t *testing.T) {
+	db := setupRoomMembersDB(t)
+	_, err := db.Exec(`
+	CREATE TABLE rooms (
+		room_uuid TEXT PRIMARY KEY,
+		key_epoch INTEGER NOT NULL DEFAULT 1,
+		updated_at DATETIME NOT NULL
+	);`)
+	assert.NoError(t, err)
+
+	writeRepo := repositories.NewRoomMemberWriteRepository(db)
+	readRepo := repositories.NewRoomMemberReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	_, err = db.Exec(`INSERT INTO rooms (room_uuid, updated_at) VALUES ($1, $2)`, roomUUID, time.Now().UTC())
+	assert.NoError(t, err)
+
+	// Вход участника начинает новую эпоху
+	epoch, err := writeRepo.SaveWithKeyRotation(ctx, roomUUID, userUUID, models.RoomRoleMember, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), epoch)
+
+	member, err := readRepo.Get(ctx, roomUUID, userUUID)
+	assert.NoError(t, err)
+	assert.NotNil(t, member)
+
+	// Повторный вход не меняет ни состав, ни эпоху
+	epoch, err = writeRepo.SaveWithKeyRotation(ctx, roomUUID, userUUID, models.RoomRoleMember, time.Now().UTC())
+	assert.NoError(t, err)
+	assert.Zero(t, epoch)
+
+	// Удаление участника начинает новую эпоху
+	epoch, err = writeRepo.DeleteWithKeyRotation(ctx, roomUUID, userUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), epoch)
+
+	member, err = readRepo.Get(ctx, roomUUID, userUUID)
+	assert.NoError(t, err)
+	assert.Nil(t, member)
+
+	// Удаление не участника ничего не меняет
+	epoch, err = writeRepo.DeleteWithKeyRotation(ctx, roomUUID, userUUID)
+	assert.NoError(t, err)
+	assert.Zero(t, epoch)
+
+	var keyEpoch int64
+	assert.NoError(t, db.Get(&keyEpoch, `SELECT key_epoch FROM rooms WHERE room_uuid = $1`, roomUUID))
+	assert.Equal(t, int64(3), keyEpoch)
+}
@@ -0,0 +1,156 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// RoomMessageWriteRepository реализует запись сообщений комнаты через SQL
+type RoomMessageWriteRepository struct {
+	db *sqlx.DB
+}
+
+// NewRoomMessageWriteRepository создаёт новый репозиторий для записи сообщений
+func NewRoomMessageWriteRepository(db *sqlx.DB) *RoomMessageWriteRepository {
+	return &RoomMessageWriteRepository{db: db}
+}
+
+// Save сохраняет сообщение комнаты, отправленное с устройства senderDeviceUUID и зашифрованное ключом эпохи keyEpoch.
+// Если сообщение с таким message_uuid или с тем же отправителем и clientMessageUUID в этой комнате
+// уже существует, запись не меняется.
+func (r *RoomMessageWriteRepository) Save(
+	ctx context.Context,
+	messageUUID uuid.UUID,
+	roomUUID uuid.UUID,
+	senderUUID uuid.UUID,
+	senderDeviceUUID uuid.UUID,
+	clientMessageUUID uuid.UUID,
+	keyEpoch int64,
+	ciphertext string,
+	sentAt time.Time,
+) error {
+	now := time.Now().UTC()
+	// сообщения без UUID клиента хранятся с NULL, чтобы не конфликтовать друг с другом по уникальному индексу
+	clientUUID := uuid.NullUUID{UUID: clientMessageUUID, Valid: clientMessageUUID != uuid.Nil}
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO room_messages (message_uuid, room_uuid, sender_uuid, sender_device_uuid, client_message_uuid, key_epoch, ciphertext, sent_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 ON CONFLICT DO NOTHING`,
+		messageUUID, roomUUID, senderUUID, senderDeviceUUID, clientUUID, keyEpoch, ciphertext, sentAt, now, now,
+	)
+	return err
+}
+
+// RoomMessageReadRepository реализует чтение сообщений комнаты через SQL
+type RoomMessageReadRepository struct {
+	db *sqlx.DB
+}
+
+// NewRoomMessageReadRepository создаёт новый репозиторий для чтения сообщений
+func NewRoomMessageReadRepository(db *sqlx.DB) *RoomMessageReadRepository {
+	return &RoomMessageReadRepository{db: db}
+}
+
+// Get возвращает сообщение по UUID или nil, если сообщение не найдено
+func (r *RoomMessageReadRepository) Get(ctx context.Context, messageUUID uuid.UUID) (*models.RoomMessageDB, error) {
+	var message models.RoomMessageDB
+	err := r.db.GetContext(ctx, &message,
+		`SELECT * FROM room_messages WHERE message_uuid = $1`,
+		messageUUID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// GetByClientUUID возвращает сообщение отправителя в комнате с UUID, присвоенным клиентом,
+// или nil, если сообщение не найдено
+func (r *RoomMessageReadRepository) GetByClientUUID(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	senderUUID uuid.UUID,
+	clientMessageUUID uuid.UUID,
+) (*models.RoomMessageDB, error) {
+	var message models.RoomMessageDB
+	err := r.db.GetContext(ctx, &message,
+		`SELECT * FROM room_messages WHERE room_uuid = $1 AND sender_uuid = $2 AND client_message_uuid = $3`,
+		roomUUID, senderUUID, clientMessageUUID,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &message, nil
+}
+
+// ListLast возвращает последние limit сообщений комнаты в порядке отправки (от старых к новым)
+func (r *RoomMessageReadRepository) ListLast(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	limit int,
+) ([]models.RoomMessageDB, error) {
+	var messages []models.RoomMessageDB
+	err := r.db.SelectContext(ctx, &messages,
+		`SELECT * FROM (
+		     SELECT * FROM room_messages
+		     WHERE room_uuid = $1
+		     ORDER BY sent_at DESC, message_uuid DESC
+		     LIMIT $2
+		 ) AS m
+		 ORDER BY sent_at ASC, message_uuid ASC`,
+		roomUUID, limit,
+	)
+	return messages, err
+}
+
+// ListPage возвращает до limit сообщений комнаты в порядке отправки (от старых к новым),
+// отправленных строго до курсора before и строго после курсора after (nil — без ограничения).
+// Если задан after, выбираются ближайшие к нему сообщения, иначе — ближайшие к before (или последние).
+func (r *RoomMessageReadRepository) ListPage(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	before *models.MessageCursor,
+	after *models.MessageCursor,
+	limit int,
+) ([]models.RoomMessageDB, error) {
+	query := `SELECT * FROM room_messages WHERE room_uuid = $1`
+	args := []any{roomUUID}
+
+	if before != nil {
+		args = append(args, before.SentAt, before.MessageUUID)
+		query += fmt.Sprintf(` AND (sent_at < $%d OR (sent_at = $%d AND message_uuid < $%d))`, len(args)-1, len(args)-1, len(args))
+	}
+	if after != nil {
+		args = append(args, after.SentAt, after.MessageUUID)
+		query += fmt.Sprintf(` AND (sent_at > $%d OR (sent_at = $%d AND message_uuid > $%d))`, len(args)-1, len(args)-1, len(args))
+	}
+
+	order := `DESC`
+	if after != nil {
+		order = `ASC`
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY sent_at %s, message_uuid %s LIMIT $%d`, order, order, len(args))
+
+	var messages []models.RoomMessageDB
+	if err := r.db.SelectContext(ctx, &messages, query, args...); err != nil {
+		return nil, err
+	}
+	if after == nil {
+		slices.Reverse(messages)
+	}
+	return messages, nil
+}
@@ -0,0 +1,262 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/sbilibin2017/bil-message/internal/repositories"
+	"github.com/stretchr/testify/assert"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupRoomMessagesDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite", ":memory:")
+	assert.NoError(t, err)
+
+	schema := `
+	CREATE TABLE room_messages (
+		message_uuid TEXT PRIMARY KEY,
+		room_uuid    TEXT NOT NULL,
+		sender_uuid  TEXT NOT NULL,
+		sender_device_uuid TEXT,
+		client_message_uuid TEXT,
+		key_epoch    INTEGER NOT NULL DEFAULT 1,
+		ciphertext   TEXT NOT NULL,
+		sent_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+		created_at   DATETIME NOT NULL,
+		updated_at   DATETIME NOT NULL
+	);
+	CREATE UNIQUE INDEX room_messages_room_sender_client_message_uuid_idx
+		ON room_messages (room_uuid, sender_uuid, client_message_uuid);`
+	_, err = db.Exec(schema)
+	assert.NoError(t, err)
+
+	return db
+}
+
+func TestRoomMessageWriteAndListLast(t *testing.T) {
+	db := setupRoomMessagesDB(t)
+	writeRepo := repositories.NewRoomMessageWriteRepository(db)
+	readRepo := repositories.NewRoomMessageReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	senderUUID := uuid.New()
+	deviceUUID := uuid.New()
+	base := time.Now().UTC()
+
+	texts := []string{"first", "second", "third"}
+	for i, text := range texts {
+		err := writeRepo.Save(ctx, uuid.New(), roomUUID, senderUUID, deviceUUID, uuid.Nil, int64(i+1), text, base.Add(time.Duration(i)*time.Second))
+		assert.NoError(t, err)
+	}
+
+	// сообщение другой комнаты не должно попасть в выборку
+	err := writeRepo.Save(ctx, uuid.New(), uuid.New(), senderUUID, deviceUUID, uuid.Nil, 1, "other", base)
+	assert.NoError(t, err)
+
+	messages, err := readRepo.ListLast(ctx, roomUUID, 2)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "second", messages[0].Ciphertext)
+	assert.Equal(t, "third", messages[1].Ciphertext)
+	assert.Equal(t, roomUUID, messages[0].RoomUUID)
+	assert.Equal(t, senderUUID, messages[0].SenderUUID)
+	assert.Equal(t, deviceUUID, messages[0].SenderDeviceUUID)
+	assert.Equal(t, int64(2), messages[0].KeyEpoch)
+	assert.Equal(t, int64(3), messages[1].KeyEpoch)
+
+	messages, err = readRepo.ListLast(ctx, roomUUID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 3)
+	assert.Equal(t, "first", messages[0].Ciphertext)
+}
+
+func TestRoomMessageSaveSameUUID(t *testing.T) {
+	db := setupRoomMessagesDB(t)
+	writeRepo := repositories.NewRoomMessageWriteRepository(db)
+	readRepo := repositories.NewRoomMessageReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	messageUUID := uuid.New()
+
+	err := writeRepo.Save(ctx, messageUUID, roomUUID, uuid.New(), uuid.New(), uuid.Nil, 1, "hello", time.Now().UTC())
+	assert.NoError(t, err)
+	err = writeRepo.Save(ctx, messageUUID, roomUUID, uuid.New(), uuid.New(), uuid.Nil, 1, "hello", time.Now().UTC())
+	assert.NoError(t, err)
+
+	messages, err := readRepo.ListLast(ctx, roomUUID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, messageUUID, messages[0].MessageUUID)
+}
+
+func TestRoomMessageSaveSameClientUUID(t *testing.T) {
+	db := setupRoomMessagesDB(t)
+	writeRepo := repositories.NewRoomMessageWriteRepository(db)
+	readRepo := repositories.NewRoomMessageReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	senderUUID := uuid.New()
+	clientUUID := uuid.New()
+	firstUUID := uuid.New()
+
+	// повторная отправка того же сообщения клиентом не создаёт вторую запись
+	err := writeRepo.Save(ctx, firstUUID, roomUUID, senderUUID, uuid.New(), clientUUID, 1, "hello", time.Now().UTC())
+	assert.NoError(t, err)
+	err = writeRepo.Save(ctx, uuid.New(), roomUUID, senderUUID, uuid.New(), clientUUID, 1, "hello", time.Now().UTC())
+	assert.NoError(t, err)
+
+	// тот же UUID клиента у другого отправителя — другое сообщение
+	err = writeRepo.Save(ctx, uuid.New(), roomUUID, uuid.New(), uuid.New(), clientUUID, 1, "other", time.Now().UTC())
+	assert.NoError(t, err)
+
+	// тот же UUID клиента того же отправителя в другой комнате — тоже другое сообщение
+	otherRoomUUID := uuid.New()
+	otherUUID := uuid.New()
+	err = writeRepo.Save(ctx, otherUUID, otherRoomUUID, senderUUID, uuid.New(), clientUUID, 1, "elsewhere", time.Now().UTC())
+	assert.NoError(t, err)
+
+	messages, err := readRepo.ListLast(ctx, roomUUID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+
+	message, err := readRepo.GetByClientUUID(ctx, roomUUID, senderUUID, clientUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, firstUUID, message.MessageUUID)
+	assert.Equal(t, clientUUID, message.ClientMessageUUID)
+
+	message, err = readRepo.GetByClientUUID(ctx, otherRoomUUID, senderUUID, clientUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, otherUUID, message.MessageUUID)
+
+	message, err = readRepo.GetByClientUUID(ctx, roomUUID, senderUUID, uuid.New())
+	assert.NoError(t, err)
+	assert.Nil(t, message)
+}
+
+func TestListLastWithoutSenderDevice(t *testing.T) {
+	db := setupRoomMessagesDB(t)
+	readRepo := repositories.NewRoomMessageReadRepository(db)
+	roomUUID := uuid.New()
+
+	// сообщения, сохранённые до появления sender_device_uuid, читаются с uuid.Nil
+	_, err := db.Exec(
+		`INSERT INTO room_messages (message_uuid, room_uuid, sender_uuid, ciphertext, sent_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $5, $5)`,
+		uuid.New(), roomUUID, uuid.New(), "legacy", time.Now().UTC(),
+	)
+	assert.NoError(t, err)
+
+	messages, err := readRepo.ListLast(context.Background(), roomUUID, 10)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, uuid.Nil, messages[0].SenderDeviceUUID)
+}
+
+func TestListLastEmptyRoom(t *testing.T) {
+	db := setupRoomMessagesDB(t)
+	readRepo := repositories.NewRoomMessageReadRepository(db)
+
+	messages, err := readRepo.ListLast(context.Background(), uuid.New(), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, messages)
+}
+
+func TestRoomMessageGet(t *testing.T) {
+	db := setupRoomMessagesDB(t)
+	writeRepo := repositories.NewRoomMessageWriteRepository(db)
+	readRepo := repositories.NewRoomMessageReadRepository(db)
+	ctx := context.Background()
+
+	messageUUID := uuid.New()
+	roomUUID := uuid.New()
+	err := writeRepo.Save(ctx, messageUUID, roomUUID, uuid.New(), uuid.New(), uuid.Nil, 1, "hello", time.Now().UTC())
+	assert.NoError(t, err)
+
+	message, err := readRepo.Get(ctx, messageUUID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, message) {
+		assert.Equal(t, roomUUID, message.RoomUUID)
+		assert.Equal(t, "hello", message.Ciphertext)
+	}
+
+	message, err = readRepo.Get(ctx, uuid.New())
+	assert.NoError(t, err)
+	assert.Nil(t, message)
+}
+
+func TestRoomMessageListPage(t *testing.T) {
+	db := setupRoomMessagesDB(t)
+	writeRepo := repositories.NewRoomMessageWriteRepository(db)
+	readRepo := repositories.NewRoomMessageReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	senderUUID := uuid.New()
+	base := time.Now().UTC().Truncate(time.Second)
+
+	// два последних сообщения отправлены одновременно и упорядочены по UUID
+	sameTimeA := uuid.MustParse("00000000-0000-0000-0000-00000000000a")
+	sameTimeB := uuid.MustParse("00000000-0000-0000-0000-00000000000b")
+	saved := []struct {
+		uuid   uuid.UUID
+		text   string
+		sentAt time.Time
+	}{
+		{uuid.New(), "m1", base},
+		{uuid.New(), "m2", base.Add(time.Second)},
+		{uuid.New(), "m3", base.Add(2 * time.Second)},
+		{sameTimeB, "m5", base.Add(3 * time.Second)},
+		{sameTimeA, "m4", base.Add(3 * time.Second)},
+	}
+	for _, m := range saved {
+		err := writeRepo.Save(ctx, m.uuid, roomUUID, senderUUID, uuid.New(), uuid.Nil, 1, m.text, m.sentAt)
+		assert.NoError(t, err)
+	}
+	err := writeRepo.Save(ctx, uuid.New(), uuid.New(), senderUUID, uuid.New(), uuid.Nil, 1, "other", base)
+	assert.NoError(t, err)
+
+	texts := func(messages []models.RoomMessageDB) []string {
+		result := make([]string, 0, len(messages))
+		for _, m := range messages {
+			result = append(result, m.Ciphertext)
+		}
+		return result
+	}
+	cursor := func(i int) *models.MessageCursor {
+		return &models.MessageCursor{SentAt: saved[i].sentAt, MessageUUID: saved[i].uuid}
+	}
+
+	tests := []struct {
+		name   string
+		before *models.MessageCursor
+		after  *models.MessageCursor
+		limit  int
+		want   []string
+	}{
+		{name: "latest", limit: 2, want: []string{"m4", "m5"}},
+		{name: "all", limit: 10, want: []string{"m1", "m2", "m3", "m4", "m5"}},
+		{name: "before same time", before: cursor(3), limit: 2, want: []string{"m3", "m4"}},
+		{name: "before", before: cursor(2), limit: 10, want: []string{"m1", "m2"}},
+		{name: "after", after: cursor(0), limit: 2, want: []string{"m2", "m3"}},
+		{name: "after same time", after: cursor(4), limit: 10, want: []string{"m5"}},
+		{name: "between", after: cursor(0), before: cursor(4), limit: 10, want: []string{"m2", "m3"}},
+		{name: "after last", after: cursor(3), limit: 10, want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := readRepo.ListPage(ctx, roomUUID, tt.before, tt.after, tt.limit)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, texts(messages))
+		})
+	}
+}
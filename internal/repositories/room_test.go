@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
 	"github.com/sbilibin2017/bil-message/internal/repositories"
 	"github.com/stretchr/testify/assert"
 
@@ -21,6 +22,11 @@ func setupRoomDB(t *testing.T) *sqlx.DB {
 	CREATE TABLE rooms (
 		room_uuid TEXT PRIMARY KEY,
 		creator_uuid TEXT NOT NULL,
+		name TEXT NOT NULL DEFAULT '',
+		topic TEXT NOT NULL DEFAULT '',
+		kind TEXT NOT NULL DEFAULT 'group',
+		direct_key TEXT UNIQUE,
+		key_epoch INTEGER NOT NULL DEFAULT 1,
 		created_at DATETIME NOT NULL,
 		updated_at DATETIME NOT NULL
 	);`
@@ -40,7 +46,7 @@ func TestRoomWriteAndRead(t *testing.T) {
 	creatorUUID := uuid.New()
 
 	// Save new room
-	err := writeRepo.Save(ctx, roomUUID, creatorUUID)
+	err := writeRepo.Save(ctx, roomUUID, creatorUUID, "", "")
 	assert.NoError(t, err)
 
 	// Get by roomUUID
@@ -53,7 +59,7 @@ func TestRoomWriteAndRead(t *testing.T) {
 	// Save with same room_uuid again (should update updated_at)
 	oldUpdatedAt := room.UpdatedAt
 	time.Sleep(1 * time.Millisecond) // ensure timestamp difference
-	err = writeRepo.Save(ctx, roomUUID, creatorUUID)
+	err = writeRepo.Save(ctx, roomUUID, creatorUUID, "", "")
 	assert.NoError(t, err)
 
 	room, err = readRepo.Get(ctx, roomUUID)
@@ -64,6 +70,67 @@ func TestRoomWriteAndRead(t *testing.T) {
 	assert.True(t, room.UpdatedAt.After(oldUpdatedAt))
 }
 
+func TestRoomMetadata(t *testing.T) {
+	db := setupRoomDB(t)
+	writeRepo := repositories.NewRoomWriteRepository(db)
+	readRepo := repositories.NewRoomReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	assert.NoError(t, writeRepo.Save(ctx, roomUUID, uuid.New(), "Команда", "Планы на неделю"))
+
+	room, err := readRepo.Get(ctx, roomUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Команда", room.Name)
+	assert.Equal(t, "Планы на неделю", room.Topic)
+	assert.Equal(t, models.RoomKindGroup, room.Kind)
+	assert.Equal(t, uuid.Nil, room.DirectKey)
+
+	assert.NoError(t, writeRepo.UpdateMetadata(ctx, roomUUID, "Команда А", ""))
+
+	room, err = readRepo.Get(ctx, roomUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Команда А", room.Name)
+	assert.Equal(t, "", room.Topic)
+}
+
+func TestSaveDirectRoom(t *testing.T) {
+	db := setupRoomDB(t)
+	writeRepo := repositories.NewRoomWriteRepository(db)
+	readRepo := repositories.NewRoomReadRepository(db)
+	ctx := context.Background()
+
+	directKey := uuid.New()
+	firstUUID := uuid.New()
+	creatorUUID := uuid.New()
+	assert.NoError(t, writeRepo.SaveDirect(ctx, firstUUID, creatorUUID, directKey))
+
+	// Повторное создание личной комнаты той же пары не создаёт новую комнату
+	secondUUID := uuid.New()
+	assert.NoError(t, writeRepo.SaveDirect(ctx, secondUUID, uuid.New(), directKey))
+
+	room, err := readRepo.GetDirect(ctx, directKey)
+	assert.NoError(t, err)
+	if assert.NotNil(t, room) {
+		assert.Equal(t, firstUUID, room.RoomUUID)
+		assert.Equal(t, creatorUUID, room.CreatorUUID)
+		assert.Equal(t, models.RoomKindDirect, room.Kind)
+		assert.Equal(t, directKey, room.DirectKey)
+	}
+
+	room, err = readRepo.Get(ctx, secondUUID)
+	assert.NoError(t, err)
+	assert.Nil(t, room)
+
+	// Групповые комнаты не занимают ключ личной комнаты
+	assert.NoError(t, writeRepo.Save(ctx, uuid.New(), creatorUUID, "", ""))
+	assert.NoError(t, writeRepo.Save(ctx, uuid.New(), creatorUUID, "", ""))
+
+	room, err = readRepo.GetDirect(ctx, uuid.New())
+	assert.NoError(t, err)
+	assert.Nil(t, room)
+}
+
 func TestGetNonExistingRoom(t *testing.T) {
 	db := setupRoomDB(t)
 	readRepo := repositories.NewRoomReadRepository(db)
@@ -84,7 +151,7 @@ func TestSaveMultipleRooms(t *testing.T) {
 		roomUUID := uuid.New()
 		creatorUUID := uuid.New()
 
-		err := writeRepo.Save(ctx, roomUUID, creatorUUID)
+		err := writeRepo.Save(ctx, roomUUID, creatorUUID, "", "")
 		assert.NoError(t, err)
 
 		room, err := readRepo.Get(ctx, roomUUID)
@@ -94,3 +161,107 @@ func TestSaveMultipleRooms(t *testing.T) {
 		assert.Equal(t, creatorUUID, room.CreatorUUID)
 	}
 }
+
+func TestBumpRoomKeyEpoch(t *testing.T) {
+	db := setupRoomDB(t)
+	writeRepo := repositories.NewRoomWriteRepository(db)
+	readRepo := repositories.NewRoomReadRepository(db)
+	ctx := context.Background()
+
+	roomUUID := uuid.New()
+	assert.NoError(t, writeRepo.Save(ctx, roomUUID, uuid.New(), "", ""))
+
+	room, err := readRepo.Get(ctx, roomUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), room.KeyEpoch)
+
+	epoch, err := writeRepo.BumpKeyEpoch(ctx, roomUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), epoch)
+
+	epoch, err = writeRepo.BumpKeyEpoch(ctx, roomUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), epoch)
+
+	room, err = readRepo.Get(ctx, roomUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), room.KeyEpoch)
+
+	// Несуществующая комната
+	epoch, err = writeRepo.BumpKeyEpoch(ctx, uuid.New())
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), epoch)
+}
+
+func TestListRoomsByUser(t *testing.T) {
+	db := setupRoomDB(t)
+	_, err := db.Exec(`
+	CREATE TABLE room_members (
+		room_uuid TEXT NOT NULL,
+		user_uuid TEXT NOT NULL,
+		role TEXT NOT NULL DEFAULT 'member',
+		joined_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		created_at DATETIME NOT NULL,
+		updated_at DATETIME NOT NULL,
+		PRIMARY KEY (room_uuid, user_uuid)
+	);
+	CREATE TABLE room_messages (
+		message_uuid TEXT PRIMARY KEY,
+		room_uuid    TEXT NOT NULL,
+		sender_uuid  TEXT NOT NULL,
+		sender_device_uuid TEXT,
+		client_message_uuid TEXT,
+		key_epoch    INTEGER NOT NULL DEFAULT 1,
+		ciphertext   TEXT NOT NULL,
+		sent_at      DATETIME DEFAULT CURRENT_TIMESTAMP,
+		created_at   DATETIME NOT NULL,
+		updated_at   DATETIME NOT NULL
+	);`)
+	assert.NoError(t, err)
+
+	roomRepo := repositories.NewRoomWriteRepository(db)
+	memberRepo := repositories.NewRoomMemberWriteRepository(db)
+	messageRepo := repositories.NewRoomMessageWriteRepository(db)
+	readRepo := repositories.NewRoomReadRepository(db)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	otherUUID := uuid.New()
+	quietRoom := uuid.New()
+	activeRoom := uuid.New()
+	foreignRoom := uuid.New()
+
+	for _, roomUUID := range []uuid.UUID{quietRoom, activeRoom, foreignRoom} {
+		assert.NoError(t, roomRepo.Save(ctx, roomUUID, otherUUID, "", ""))
+		assert.NoError(t, memberRepo.Save(ctx, roomUUID, otherUUID, models.RoomRoleOwner, time.Now().UTC()))
+	}
+	assert.NoError(t, memberRepo.Save(ctx, quietRoom, userUUID, models.RoomRoleAdmin, time.Now().UTC()))
+	assert.NoError(t, memberRepo.Save(ctx, activeRoom, userUUID, models.RoomRoleMember, time.Now().UTC()))
+
+	sentAt := time.Now().UTC().Add(time.Hour)
+	assert.NoError(t, messageRepo.Save(ctx, uuid.New(), activeRoom, otherUUID, uuid.New(), uuid.Nil, 1, "old", sentAt.Add(-time.Minute)))
+	assert.NoError(t, messageRepo.Save(ctx, uuid.New(), activeRoom, otherUUID, uuid.New(), uuid.Nil, 1, "new", sentAt))
+
+	rooms, err := readRepo.ListByUser(ctx, userUUID)
+	assert.NoError(t, err)
+	if assert.Len(t, rooms, 2) {
+		assert.Equal(t, activeRoom, rooms[0].RoomUUID)
+		assert.Equal(t, models.RoomRoleMember, rooms[0].Role)
+		assert.Equal(t, int64(2), rooms[0].MemberCount)
+		if assert.NotNil(t, rooms[0].LastMessageAt) {
+			assert.True(t, rooms[0].LastMessageAt.Equal(sentAt))
+			assert.True(t, rooms[0].LastActivityAt().Equal(sentAt))
+		}
+
+		assert.Equal(t, quietRoom, rooms[1].RoomUUID)
+		assert.Equal(t, models.RoomRoleAdmin, rooms[1].Role)
+		assert.Equal(t, otherUUID, rooms[1].CreatorUUID)
+		assert.Equal(t, models.RoomKindGroup, rooms[1].Kind)
+		assert.Nil(t, rooms[1].LastMessageAt)
+		assert.Equal(t, rooms[1].CreatedAt, rooms[1].LastActivityAt())
+	}
+
+	rooms, err = readRepo.ListByUser(ctx, uuid.New())
+	assert.NoError(t, err)
+	assert.Empty(t, rooms)
+}
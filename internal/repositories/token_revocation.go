@@ -0,0 +1,98 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// TokenRevocationWriteRepository реализует запись отозванных токенов и сессий через SQL
+type TokenRevocationWriteRepository struct {
+	db *sqlx.DB
+}
+
+// NewTokenRevocationWriteRepository создаёт новый репозиторий для записи отозванных токенов
+func NewTokenRevocationWriteRepository(db *sqlx.DB) *TokenRevocationWriteRepository {
+	return &TokenRevocationWriteRepository{db: db}
+}
+
+// SaveToken сохраняет отозванный токен.
+// Повторный отзыв того же токена ничего не меняет.
+func (r *TokenRevocationWriteRepository) SaveToken(
+	ctx context.Context,
+	tokenID string,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	expiresAt time.Time,
+) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO revoked_tokens (token_id, user_uuid, device_uuid, expires_at, created_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (token_id) DO NOTHING`,
+		tokenID, userUUID, deviceUUID, expiresAt, time.Now().UTC(),
+	)
+	return err
+}
+
+// SaveSession сохраняет момент отзыва всех сессий пользователя на устройстве.
+// Нулевой deviceUUID означает все устройства пользователя.
+// Если запись уже существует, обновляются revoked_at и updated_at.
+func (r *TokenRevocationWriteRepository) SaveSession(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	revokedAt time.Time,
+) error {
+	now := time.Now().UTC()
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO session_revocations (user_uuid, device_uuid, revoked_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (user_uuid, device_uuid)
+		 DO UPDATE
+		 SET revoked_at = EXCLUDED.revoked_at,
+		     updated_at = EXCLUDED.updated_at`,
+		userUUID, deviceUUID, revokedAt, now, now,
+	)
+	return err
+}
+
+// DeleteExpiredTokens удаляет отозванные токены, срок действия которых истёк до момента before
+func (r *TokenRevocationWriteRepository) DeleteExpiredTokens(ctx context.Context, before time.Time) error {
+	_, err := r.db.ExecContext(ctx,
+		`DELETE FROM revoked_tokens WHERE expires_at < $1`,
+		before,
+	)
+	return err
+}
+
+// TokenRevocationReadRepository реализует чтение отозванных токенов и сессий через SQL
+type TokenRevocationReadRepository struct {
+	db *sqlx.DB
+}
+
+// NewTokenRevocationReadRepository создаёт новый репозиторий для чтения отозванных токенов
+func NewTokenRevocationReadRepository(db *sqlx.DB) *TokenRevocationReadRepository {
+	return &TokenRevocationReadRepository{db: db}
+}
+
+// ListTokens возвращает отозванные токены, срок действия которых ещё не истёк к моменту after
+func (r *TokenRevocationReadRepository) ListTokens(ctx context.Context, after time.Time) ([]models.RevokedTokenDB, error) {
+	var tokens []models.RevokedTokenDB
+	err := r.db.SelectContext(ctx, &tokens,
+		`SELECT * FROM revoked_tokens WHERE expires_at >= $1`,
+		after,
+	)
+	return tokens, err
+}
+
+// ListSessions возвращает все записи об отзыве сессий
+func (r *TokenRevocationReadRepository) ListSessions(ctx context.Context) ([]models.SessionRevocationDB, error) {
+	var sessions []models.SessionRevocationDB
+	err := r.db.SelectContext(ctx, &sessions,
+		`SELECT * FROM session_revocations`,
+	)
+	return sessions, err
+}
@@ -0,0 +1,109 @@
+package repositories_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/sbilibin2017/bil-message/internal/repositories"
+	"github.com/stretchr/testify/assert"
+
+	_ "modernc.org/sqlite"
+)
+
+func setupTokenRevocationsDB(t *testing.T) *sqlx.DB {
+	db, err := sqlx.Open("sqlite", ":memory:")
+	assert.NoError(t, err)
+
+	schema := `
+	CREATE TABLE revoked_tokens (
+		token_id    TEXT PRIMARY KEY,
+		user_uuid   TEXT NOT NULL,
+		device_uuid TEXT NOT NULL,
+		expires_at  DATETIME NOT NULL,
+		created_at  DATETIME NOT NULL
+	);
+	CREATE TABLE session_revocations (
+		user_uuid   TEXT NOT NULL,
+		device_uuid TEXT NOT NULL,
+		revoked_at  DATETIME NOT NULL,
+		created_at  DATETIME NOT NULL,
+		updated_at  DATETIME NOT NULL,
+		PRIMARY KEY (user_uuid, device_uuid)
+	);`
+	_, err = db.Exec(schema)
+	assert.NoError(t, err)
+
+	return db
+}
+
+func TestTokenRevocationSaveAndListTokens(t *testing.T) {
+	db := setupTokenRevocationsDB(t)
+	writeRepo := repositories.NewTokenRevocationWriteRepository(db)
+	readRepo := repositories.NewTokenRevocationReadRepository(db)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	now := time.Now().UTC()
+
+	err := writeRepo.SaveToken(ctx, "active", userUUID, deviceUUID, now.Add(time.Hour))
+	assert.NoError(t, err)
+	err = writeRepo.SaveToken(ctx, "expired", userUUID, deviceUUID, now.Add(-time.Hour))
+	assert.NoError(t, err)
+
+	// повторный отзыв не приводит к ошибке
+	err = writeRepo.SaveToken(ctx, "active", userUUID, deviceUUID, now.Add(time.Hour))
+	assert.NoError(t, err)
+
+	tokens, err := readRepo.ListTokens(ctx, now)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+	assert.Equal(t, "active", tokens[0].TokenID)
+	assert.Equal(t, userUUID, tokens[0].UserUUID)
+	assert.Equal(t, deviceUUID, tokens[0].DeviceUUID)
+
+	// удаление истёкших токенов
+	err = writeRepo.DeleteExpiredTokens(ctx, now)
+	assert.NoError(t, err)
+
+	tokens, err = readRepo.ListTokens(ctx, now.Add(-2*time.Hour))
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 1)
+	assert.Equal(t, "active", tokens[0].TokenID)
+}
+
+func TestTokenRevocationSaveAndListSessions(t *testing.T) {
+	db := setupTokenRevocationsDB(t)
+	writeRepo := repositories.NewTokenRevocationWriteRepository(db)
+	readRepo := repositories.NewTokenRevocationReadRepository(db)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	first := time.Now().UTC().Add(-time.Minute).Truncate(time.Second)
+	second := first.Add(30 * time.Second)
+
+	err := writeRepo.SaveSession(ctx, userUUID, deviceUUID, first)
+	assert.NoError(t, err)
+	err = writeRepo.SaveSession(ctx, userUUID, uuid.Nil, first)
+	assert.NoError(t, err)
+
+	// повторный отзыв сдвигает момент отзыва
+	err = writeRepo.SaveSession(ctx, userUUID, deviceUUID, second)
+	assert.NoError(t, err)
+
+	sessions, err := readRepo.ListSessions(ctx)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	byDevice := make(map[uuid.UUID]time.Time)
+	for _, s := range sessions {
+		assert.Equal(t, userUUID, s.UserUUID)
+		byDevice[s.DeviceUUID] = s.RevokedAt
+	}
+	assert.True(t, byDevice[deviceUUID].Equal(second))
+	assert.True(t, byDevice[uuid.Nil].Equal(first))
+}
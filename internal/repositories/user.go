@@ -68,3 +68,19 @@ func (r *UserReadRepository) Get(
 	}
 	return &user, nil
 }
+
+// GetByUUID возвращает пользователя по user_uuid или nil, если не найден
+func (r *UserReadRepository) GetByUUID(
+	ctx context.Context,
+	userUUID uuid.UUID,
+) (*models.UserDB, error) {
+	var user models.UserDB
+	err := r.db.GetContext(ctx, &user, "SELECT * FROM users WHERE user_uuid = $1", userUUID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &user, nil
+}
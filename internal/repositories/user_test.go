@@ -101,3 +101,24 @@ func TestSaveMultipleUsers(t *testing.T) {
 		assert.Equal(t, username, user.Username)
 	}
 }
+
+func TestGetUserByUUID(t *testing.T) {
+	db := setupDB(t)
+	writeRepo := repositories.NewUserWriteRepository(db)
+	readRepo := repositories.NewUserReadRepository(db)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	assert.NoError(t, writeRepo.Save(ctx, userUUID, "johndoe", "hashedpass"))
+
+	user, err := readRepo.GetByUUID(ctx, userUUID)
+	assert.NoError(t, err)
+	if assert.NotNil(t, user) {
+		assert.Equal(t, "johndoe", user.Username)
+		assert.Equal(t, userUUID, user.UserUUID)
+	}
+
+	user, err = readRepo.GetByUUID(ctx, uuid.New())
+	assert.NoError(t, err)
+	assert.Nil(t, user)
+}
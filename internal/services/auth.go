@@ -5,6 +5,7 @@ import (
 	"errors"
 
 	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
 	"github.com/sbilibin2017/bil-message/internal/models"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -16,6 +17,12 @@ var (
 
 	// ErrInvalidCredentials возвращается, если переданы неверные имя пользователя или пароль
 	ErrInvalidCredentials = errors.New("invalid username or password")
+
+	// ErrInvalidDeviceKey возвращается, если публичный ключ или ключ подписи устройства имеет неподдерживаемый формат
+	ErrInvalidDeviceKey = errors.New("invalid device key")
+
+	// ErrDevicePendingApproval возвращается при входе с устройства, ещё не подтверждённого доверенным устройством
+	ErrDevicePendingApproval = errors.New("device is pending approval")
 )
 
 //
@@ -34,21 +41,34 @@ type UserSaver interface {
 	Save(ctx context.Context, userUUID uuid.UUID, username string, passwordHash string) error
 }
 
-// DeviceGetter
+// DeviceGetter описывает интерфейс получения устройства по UUID
 type DeviceGetter interface {
+	// Get возвращает устройство (в том числе отозванное) или nil, если устройства нет
 	Get(ctx context.Context, deviceUUID uuid.UUID) (*models.UserDeviceDB, error)
 }
 
 // DeviceSaver описывает интерфейс сохранения нового устройства пользователя
 type DeviceSaver interface {
-	// Save сохраняет устройство с UUID, привязанное к пользователю и его публичному ключу
-	Save(ctx context.Context, deviceUUID uuid.UUID, userUUID uuid.UUID, publicKey string) error
+	// Save сохраняет устройство с UUID, привязанное к пользователю, с публичным ключом, ключом подписи и статусом
+	Save(ctx context.Context, deviceUUID uuid.UUID, userUUID uuid.UUID, publicKey string, signingKey string, status string) error
 }
 
-// TokenGenerator описывает интерфейс генерации JWT токена
-type TokenGenerator interface {
-	// Generate создает JWT токен, содержащий userUUID и deviceUUID
-	Generate(userUUID uuid.UUID, deviceUUID uuid.UUID) (string, error)
+// SessionIssuer описывает интерфейс выдачи токенов для новой сессии устройства
+type SessionIssuer interface {
+	// IssueSession начинает новую сессию и возвращает access- и refresh-токены
+	IssueSession(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID) (accessToken string, refreshToken string, err error)
+}
+
+// LoginThrottler описывает интерфейс ограничения попыток входа по имени пользователя и IP-адресу
+type LoginThrottler interface {
+	// Begin возвращает ошибку ErrTooManyAttempts, если попытки временно запрещены, иначе учитывает попытку
+	Begin(username string, ip string) error
+	// Fail подтверждает неудачную попытку
+	Fail(ctx context.Context, username string, ip string) error
+	// Succeed отменяет попытку и сбрасывает счётчик имени пользователя после верного пароля
+	Succeed(username string, ip string)
+	// Cancel отменяет только эту попытку, если пароль не удалось проверить
+	Cancel(username string, ip string)
 }
 
 //
@@ -58,13 +78,60 @@ type TokenGenerator interface {
 // AuthService предоставляет методы для:
 //   - регистрации пользователей
 //   - добавления устройств
-//   - входа (логина) с проверкой пароля и генерацией токена
+//   - входа (логина) с проверкой пароля и выдачей токенов
 type AuthService struct {
 	ug UserGetter
 	us UserSaver
 	dg DeviceGetter
 	ds DeviceSaver
-	tg TokenGenerator
+	si SessionIssuer
+	dl DeviceLister // список устройств пользователя; задан, если новые устройства требуют подтверждения
+	pp *PasswordPolicy
+	lt LoginThrottler // ограничение попыток входа; nil — попытки не ограничиваются
+}
+
+// AuthOpt — функциональная опция для настройки AuthService.
+type AuthOpt func(*AuthService)
+
+// WithDeviceApproval включает подтверждение новых устройств: устройство, добавленное пользователем,
+// у которого уже есть активное устройство, создаётся в статусе pending
+// и не может войти, пока его не подтвердит доверенное устройство.
+// Используется первое непустое значение; по умолчанию новые устройства сразу активны.
+func WithDeviceApproval(listers ...DeviceLister) AuthOpt {
+	return func(svc *AuthService) {
+		for _, dl := range listers {
+			if dl != nil {
+				svc.dl = dl
+				return
+			}
+		}
+	}
+}
+
+// WithPasswordPolicy задаёт политику паролей, применяемую при регистрации.
+// Используется первое значение, отличное от nil; по умолчанию — NewPasswordPolicy().
+func WithPasswordPolicy(policies ...*PasswordPolicy) AuthOpt {
+	return func(svc *AuthService) {
+		for _, pp := range policies {
+			if pp != nil {
+				svc.pp = pp
+				return
+			}
+		}
+	}
+}
+
+// WithLoginThrottle включает ограничение попыток проверки пароля в Login и AddDevice.
+// Используется первое непустое значение; по умолчанию попытки не ограничиваются.
+func WithLoginThrottle(throttles ...LoginThrottler) AuthOpt {
+	return func(svc *AuthService) {
+		for _, lt := range throttles {
+			if lt != nil {
+				svc.lt = lt
+				return
+			}
+		}
+	}
 }
 
 // NewAuthService создаёт новый экземпляр AuthService
@@ -73,18 +140,25 @@ func NewAuthService(
 	us UserSaver,
 	dg DeviceGetter,
 	ds DeviceSaver,
-	tg TokenGenerator,
+	si SessionIssuer,
+	opts ...AuthOpt,
 ) *AuthService {
-	return &AuthService{
+	svc := &AuthService{
 		ug: ug,
 		us: us,
 		dg: dg,
 		ds: ds,
-		tg: tg,
+		si: si,
+		pp: NewPasswordPolicy(),
 	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
 }
 
 // Register создаёт нового пользователя с указанными username и password.
+// Имя пользователя проверяется ValidateUsername, пароль — политикой паролей.
 // Если пользователь уже существует, возвращает ErrUsernameAlreadyExists.
 // Пароль хэшируется с использованием bcrypt.
 func (svc *AuthService) Register(
@@ -92,6 +166,13 @@ func (svc *AuthService) Register(
 	username string,
 	password string,
 ) (userUUID uuid.UUID, err error) {
+	if err := ValidateUsername(username); err != nil {
+		return uuid.Nil, err
+	}
+	if err := svc.pp.Validate(username, password); err != nil {
+		return uuid.Nil, err
+	}
+
 	// Проверяем, существует ли пользователь
 	existing, err := svc.ug.Get(ctx, username)
 	if err != nil {
@@ -118,79 +199,158 @@ func (svc *AuthService) Register(
 }
 
 // AddDevice добавляет новое устройство пользователю.
-// Проверяет логин/пароль, создает UUID для устройства, сохраняет его в БД вместе с publicKey.
-// Возвращает UUID устройства. JWT не создается (это делает Login).
+// Проверяет формат ключей и логин/пароль, создает UUID для устройства, сохраняет его в БД вместе с publicKey
+// (X25519 в base64) и ключом подписи signingKey (Ed25519 в base64, может быть пустым).
+// Возвращает UUID устройства и его статус: pending, если устройство должно быть подтверждено
+// доверенным устройством пользователя (см. WithDeviceApproval), иначе active.
+// JWT не создается (это делает Login). Попытки проверки пароля ограничиваются так же, как в Login.
 func (svc *AuthService) AddDevice(
 	ctx context.Context,
 	username string,
 	password string,
 	publicKey string,
-) (deviceUUID uuid.UUID, err error) {
-	// Проверяем пользователя
-	user, err := svc.ug.Get(ctx, username)
-	if err != nil {
-		return uuid.Nil, err
+	signingKey string,
+	ip string,
+) (deviceUUID uuid.UUID, status string, err error) {
+	if _, err := e2ee.ParsePublicKey(publicKey); err != nil {
+		return uuid.Nil, "", ErrInvalidDeviceKey
 	}
-	if user == nil {
-		return uuid.Nil, ErrInvalidCredentials
+	if signingKey != "" {
+		if _, err := e2ee.ParseSigningKey(signingKey); err != nil {
+			return uuid.Nil, "", ErrInvalidDeviceKey
+		}
 	}
 
-	// Проверяем пароль
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return uuid.Nil, ErrInvalidCredentials
+	// Проверяем пользователя и пароль
+	user, err := svc.checkPassword(ctx, username, password, ip)
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+
+	status, err = svc.newDeviceStatus(ctx, user.UserUUID)
+	if err != nil {
+		return uuid.Nil, "", err
 	}
 
 	// Генерируем UUID устройства
 	deviceUUID = uuid.New()
 
 	// Сохраняем устройство в базе
-	if err := svc.ds.Save(ctx, deviceUUID, user.UserUUID, publicKey); err != nil {
-		return uuid.Nil, err
+	if err := svc.ds.Save(ctx, deviceUUID, user.UserUUID, publicKey, signingKey, status); err != nil {
+		return uuid.Nil, "", err
 	}
 
-	return deviceUUID, nil
+	return deviceUUID, status, nil
+}
+
+// newDeviceStatus определяет статус нового устройства пользователя.
+// Если подтверждение включено, устройство ожидает подтверждения, когда у пользователя есть хотя бы одно
+// активное устройство. Устройство, добавленное до появления ключей подписи, сначала регистрирует
+// свой ключ подписи (DeviceService.SetSigningKey) и только после этого может подтвердить новое.
+func (svc *AuthService) newDeviceStatus(ctx context.Context, userUUID uuid.UUID) (string, error) {
+	if svc.dl == nil {
+		return models.DeviceStatusActive, nil
+	}
+
+	devices, err := svc.dl.ListByUser(ctx, userUUID)
+	if err != nil {
+		return "", err
+	}
+	for _, d := range devices {
+		if d.Status == models.DeviceStatusActive {
+			return models.DeviceStatusPending, nil
+		}
+	}
+	return models.DeviceStatusActive, nil
 }
 
-// Login проверяет учетные данные пользователя и выдает JWT для конкретного устройства.
-// Для входа клиент передает username, password и deviceUUID.
-// Если пароль неверный или пользователя нет, возвращает ErrInvalidCredentials.
-// Login проверяет учетные данные пользователя и выдает JWT для конкретного устройства.
-// Проверяет, что устройство существует и принадлежит пользователю.
-// Если пароль неверный, устройство не найдено или пользователь не существует, возвращает ErrInvalidCredentials.
+// Login проверяет учетные данные пользователя и начинает новую сессию устройства.
+// Проверяет, что устройство существует, принадлежит пользователю и не отозвано.
+// Возвращает короткоживущий access-токен (JWT) и одноразовый refresh-токен.
+// Если пароль неверный, устройство не найдено или пользователь не существует, возвращает ErrInvalidCredentials;
+// если устройство ещё не подтверждено — ErrDevicePendingApproval.
+// Если попытки входа по имени пользователя или с IP-адреса ip временно запрещены, возвращает *TooManyAttemptsError.
 func (svc *AuthService) Login(
 	ctx context.Context,
 	username string,
 	password string,
 	deviceUUID uuid.UUID,
-) (token string, err error) {
-	// Находим пользователя
-	user, err := svc.ug.Get(ctx, username)
+	ip string,
+) (token string, refreshToken string, err error) {
+	// Находим пользователя и проверяем пароль
+	user, err := svc.checkPassword(ctx, username, password, ip)
 	if err != nil {
-		return "", err
-	}
-	if user == nil {
-		return "", ErrInvalidCredentials
-	}
-
-	// Проверяем пароль
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return "", ErrInvalidCredentials
+		return "", "", err
 	}
 
 	// Проверяем устройство
 	device, err := svc.dg.Get(ctx, deviceUUID)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+	if device == nil || device.UserUUID != user.UserUUID || device.RevokedAt != nil {
+		return "", "", ErrInvalidCredentials
 	}
-	if device == nil || device.UserUUID != user.UserUUID {
-		return "", ErrInvalidCredentials
+	if device.Status == models.DeviceStatusPending {
+		return "", "", ErrDevicePendingApproval
 	}
 
-	// Генерируем JWT для указанного устройства
-	token, err = svc.tg.Generate(user.UserUUID, deviceUUID)
+	// Начинаем новую сессию для указанного устройства
+	token, refreshToken, err = svc.si.IssueSession(ctx, user.UserUUID, deviceUUID)
 	if err != nil {
-		return "", err
+		return "", "", err
+	}
+
+	return token, refreshToken, nil
+}
+
+// checkPassword находит пользователя и проверяет его пароль с учётом ограничения попыток входа.
+// Неизвестное имя пользователя и неверный пароль одинаково возвращают ErrInvalidCredentials
+// и считаются неудачной попыткой.
+func (svc *AuthService) checkPassword(
+	ctx context.Context,
+	username string,
+	password string,
+	ip string,
+) (*models.UserDB, error) {
+	if svc.lt == nil {
+		return svc.comparePassword(ctx, username, password)
+	}
+
+	if err := svc.lt.Begin(username, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := svc.comparePassword(ctx, username, password)
+	if errors.Is(err, ErrInvalidCredentials) {
+		if err := svc.lt.Fail(ctx, username, ip); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		// Ошибка хранилища не говорит о пароле: отменяем только эту попытку, не сбрасывая счётчик
+		svc.lt.Cancel(username, ip)
+		return nil, err
+	}
+	svc.lt.Succeed(username, ip)
+
+	return user, nil
+}
+
+// comparePassword находит пользователя и сверяет пароль с его bcrypt-хэшем
+func (svc *AuthService) comparePassword(ctx context.Context, username string, password string) (*models.UserDB, error) {
+	user, err := svc.ug.Get(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
 	}
 
-	return token, nil
+	return user, nil
 }
@@ -150,53 +150,129 @@ func (m *MockDeviceSaver) EXPECT() *MockDeviceSaverMockRecorder {
 }
 
 // Save mocks base method.
-func (m *MockDeviceSaver) Save(ctx context.Context, deviceUUID, userUUID uuid.UUID, publicKey string) error {
+func (m *MockDeviceSaver) Save(ctx context.Context, deviceUUID, userUUID uuid.UUID, publicKey, signingKey, status string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", ctx, deviceUUID, userUUID, publicKey)
+	ret := m.ctrl.Call(m, "Save", ctx, deviceUUID, userUUID, publicKey, signingKey, status)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockDeviceSaverMockRecorder) Save(ctx, deviceUUID, userUUID, publicKey interface{}) *gomock.Call {
+func (mr *MockDeviceSaverMockRecorder) Save(ctx, deviceUUID, userUUID, publicKey, signingKey, status interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockDeviceSaver)(nil).Save), ctx, deviceUUID, userUUID, publicKey)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockDeviceSaver)(nil).Save), ctx, deviceUUID, userUUID, publicKey, signingKey, status)
 }
 
-// MockTokenGenerator is a mock of TokenGenerator interface.
-type MockTokenGenerator struct {
+// MockSessionIssuer is a mock of SessionIssuer interface.
+type MockSessionIssuer struct {
 	ctrl     *gomock.Controller
-	recorder *MockTokenGeneratorMockRecorder
+	recorder *MockSessionIssuerMockRecorder
 }
 
-// MockTokenGeneratorMockRecorder is the mock recorder for MockTokenGenerator.
-type MockTokenGeneratorMockRecorder struct {
-	mock *MockTokenGenerator
+// MockSessionIssuerMockRecorder is the mock recorder for MockSessionIssuer.
+type MockSessionIssuerMockRecorder struct {
+	mock *MockSessionIssuer
 }
 
-// NewMockTokenGenerator creates a new mock instance.
-func NewMockTokenGenerator(ctrl *gomock.Controller) *MockTokenGenerator {
-	mock := &MockTokenGenerator{ctrl: ctrl}
-	mock.recorder = &MockTokenGeneratorMockRecorder{mock}
+// NewMockSessionIssuer creates a new mock instance.
+func NewMockSessionIssuer(ctrl *gomock.Controller) *MockSessionIssuer {
+	mock := &MockSessionIssuer{ctrl: ctrl}
+	mock.recorder = &MockSessionIssuerMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockTokenGenerator) EXPECT() *MockTokenGeneratorMockRecorder {
+func (m *MockSessionIssuer) EXPECT() *MockSessionIssuerMockRecorder {
 	return m.recorder
 }
 
-// Generate mocks base method.
-func (m *MockTokenGenerator) Generate(userUUID, deviceUUID uuid.UUID) (string, error) {
+// IssueSession mocks base method.
+func (m *MockSessionIssuer) IssueSession(ctx context.Context, userUUID, deviceUUID uuid.UUID) (string, string, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Generate", userUUID, deviceUUID)
+	ret := m.ctrl.Call(m, "IssueSession", ctx, userUUID, deviceUUID)
 	ret0, _ := ret[0].(string)
-	ret1, _ := ret[1].(error)
-	return ret0, ret1
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// IssueSession indicates an expected call of IssueSession.
+func (mr *MockSessionIssuerMockRecorder) IssueSession(ctx, userUUID, deviceUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IssueSession", reflect.TypeOf((*MockSessionIssuer)(nil).IssueSession), ctx, userUUID, deviceUUID)
+}
+
+// MockLoginThrottler is a mock of LoginThrottler interface.
+type MockLoginThrottler struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginThrottlerMockRecorder
+}
+
+// MockLoginThrottlerMockRecorder is the mock recorder for MockLoginThrottler.
+type MockLoginThrottlerMockRecorder struct {
+	mock *MockLoginThrottler
+}
+
+// NewMockLoginThrottler creates a new mock instance.
+func NewMockLoginThrottler(ctrl *gomock.Controller) *MockLoginThrottler {
+	mock := &MockLoginThrottler{ctrl: ctrl}
+	mock.recorder = &MockLoginThrottlerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginThrottler) EXPECT() *MockLoginThrottlerMockRecorder {
+	return m.recorder
+}
+
+// Begin mocks base method.
+func (m *MockLoginThrottler) Begin(username, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", username, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockLoginThrottlerMockRecorder) Begin(username, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockLoginThrottler)(nil).Begin), username, ip)
+}
+
+// Cancel mocks base method.
+func (m *MockLoginThrottler) Cancel(username, ip string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Cancel", username, ip)
+}
+
+// Cancel indicates an expected call of Cancel.
+func (mr *MockLoginThrottlerMockRecorder) Cancel(username, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Cancel", reflect.TypeOf((*MockLoginThrottler)(nil).Cancel), username, ip)
+}
+
+// Fail mocks base method.
+func (m *MockLoginThrottler) Fail(ctx context.Context, username, ip string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Fail", ctx, username, ip)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Fail indicates an expected call of Fail.
+func (mr *MockLoginThrottlerMockRecorder) Fail(ctx, username, ip interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Fail", reflect.TypeOf((*MockLoginThrottler)(nil).Fail), ctx, username, ip)
+}
+
+// Succeed mocks base method.
+func (m *MockLoginThrottler) Succeed(username, ip string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Succeed", username, ip)
 }
 
-// Generate indicates an expected call of Generate.
-func (mr *MockTokenGeneratorMockRecorder) Generate(userUUID, deviceUUID interface{}) *gomock.Call {
+// Succeed indicates an expected call of Succeed.
+func (mr *MockLoginThrottlerMockRecorder) Succeed(username, ip interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Generate", reflect.TypeOf((*MockTokenGenerator)(nil).Generate), userUUID, deviceUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Succeed", reflect.TypeOf((*MockLoginThrottler)(nil).Succeed), username, ip)
 }
@@ -2,11 +2,14 @@ package services
 
 import (
 	"context"
+	"crypto/ed25519"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
 	"github.com/sbilibin2017/bil-message/internal/models"
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/crypto/bcrypt"
@@ -19,9 +22,9 @@ func TestAuthService_Register(t *testing.T) {
 	mockGetter := NewMockUserGetter(ctrl)
 	mockSaver := NewMockUserSaver(ctrl)
 	mockDeviceSaver := NewMockDeviceSaver(ctrl)
-	mockTokenGen := NewMockTokenGenerator(ctrl)
+	mockSessionIssuer := NewMockSessionIssuer(ctrl)
 
-	svc := NewAuthService(mockGetter, mockSaver, nil, mockDeviceSaver, mockTokenGen)
+	svc := NewAuthService(mockGetter, mockSaver, nil, mockDeviceSaver, mockSessionIssuer)
 
 	tests := []struct {
 		name        string
@@ -34,7 +37,7 @@ func TestAuthService_Register(t *testing.T) {
 		{
 			name:     "successful registration",
 			username: "johndoe",
-			password: "secret",
+			password: "secret-42",
 			setupMocks: func() {
 				// Никто не существует с таким username
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(nil, nil)
@@ -45,7 +48,7 @@ func TestAuthService_Register(t *testing.T) {
 		{
 			name:     "username already exists",
 			username: "johndoe",
-			password: "secret",
+			password: "secret-42",
 			setupMocks: func() {
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(&models.UserDB{}, nil)
 			},
@@ -55,7 +58,7 @@ func TestAuthService_Register(t *testing.T) {
 		{
 			name:     "getter returns error",
 			username: "johndoe",
-			password: "secret",
+			password: "secret-42",
 			setupMocks: func() {
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(nil, errors.New("db error"))
 			},
@@ -65,7 +68,7 @@ func TestAuthService_Register(t *testing.T) {
 		{
 			name:     "saver returns error",
 			username: "johndoe",
-			password: "secret",
+			password: "secret-42",
 			setupMocks: func() {
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(nil, nil)
 				mockSaver.EXPECT().Save(gomock.Any(), gomock.Any(), "johndoe", gomock.Any()).
@@ -74,6 +77,22 @@ func TestAuthService_Register(t *testing.T) {
 			expectError: true,
 			expectedErr: errors.New("db save error"),
 		},
+		{
+			name:        "invalid username",
+			username:    "j",
+			password:    "secret-42",
+			setupMocks:  func() {},
+			expectError: true,
+			expectedErr: ErrInvalidUsername,
+		},
+		{
+			name:        "weak password",
+			username:    "johndoe",
+			password:    "secret",
+			setupMocks:  func() {},
+			expectError: true,
+			expectedErr: ErrWeakPassword,
+		},
 	}
 
 	for _, tt := range tests {
@@ -85,7 +104,7 @@ func TestAuthService_Register(t *testing.T) {
 			if tt.expectError {
 				assert.Error(t, err)
 				if tt.expectedErr != nil {
-					assert.EqualError(t, err, tt.expectedErr.Error())
+					assert.ErrorContains(t, err, tt.expectedErr.Error())
 				}
 				assert.Equal(t, uuid.Nil, userUUID)
 			} else {
@@ -110,6 +129,10 @@ func TestAuthService_AddDevice(t *testing.T) {
 	mockDeviceSaver := NewMockDeviceSaver(ctrl)
 	svc := NewAuthService(mockGetter, nil, nil, mockDeviceSaver, nil)
 
+	devicePriv, err := e2ee.GenerateDeviceKey()
+	assert.NoError(t, err)
+	pubkey := e2ee.EncodePublicKey(devicePriv.PublicKey())
+
 	tests := []struct {
 		name        string
 		username    string
@@ -123,7 +146,7 @@ func TestAuthService_AddDevice(t *testing.T) {
 			name:      "successful add device",
 			username:  "johndoe",
 			password:  "secret",
-			publicKey: "pubkey",
+			publicKey: pubkey,
 			setupMocks: func() {
 				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
 				user := &models.UserDB{
@@ -132,14 +155,14 @@ func TestAuthService_AddDevice(t *testing.T) {
 					PasswordHash: string(hashedPassword),
 				}
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
-				mockDeviceSaver.EXPECT().Save(gomock.Any(), gomock.Any(), user.UserUUID, "pubkey").Return(nil)
+				mockDeviceSaver.EXPECT().Save(gomock.Any(), gomock.Any(), user.UserUUID, pubkey, "", models.DeviceStatusActive).Return(nil)
 			},
 		},
 		{
 			name:      "user not found",
 			username:  "johndoe",
 			password:  "secret",
-			publicKey: "pubkey",
+			publicKey: pubkey,
 			setupMocks: func() {
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(nil, nil)
 			},
@@ -150,7 +173,7 @@ func TestAuthService_AddDevice(t *testing.T) {
 			name:      "invalid password",
 			username:  "johndoe",
 			password:  "wrongpass",
-			publicKey: "pubkey",
+			publicKey: pubkey,
 			setupMocks: func() {
 				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
 				user := &models.UserDB{
@@ -167,7 +190,7 @@ func TestAuthService_AddDevice(t *testing.T) {
 			name:      "device save error",
 			username:  "johndoe",
 			password:  "secret",
-			publicKey: "pubkey",
+			publicKey: pubkey,
 			setupMocks: func() {
 				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
 				user := &models.UserDB{
@@ -176,7 +199,7 @@ func TestAuthService_AddDevice(t *testing.T) {
 					PasswordHash: string(hashedPassword),
 				}
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
-				mockDeviceSaver.EXPECT().Save(gomock.Any(), gomock.Any(), user.UserUUID, "pubkey").
+				mockDeviceSaver.EXPECT().Save(gomock.Any(), gomock.Any(), user.UserUUID, pubkey, "", models.DeviceStatusActive).
 					Return(errors.New("db error"))
 			},
 			expectError: true,
@@ -186,19 +209,28 @@ func TestAuthService_AddDevice(t *testing.T) {
 			name:      "getter returns error",
 			username:  "johndoe",
 			password:  "secret",
-			publicKey: "pubkey",
+			publicKey: pubkey,
 			setupMocks: func() {
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(nil, errors.New("db get error"))
 			},
 			expectError: true,
 			expectedErr: errors.New("db get error"),
 		},
+		{
+			name:        "invalid public key",
+			username:    "johndoe",
+			password:    "secret",
+			publicKey:   "pubkey",
+			setupMocks:  func() {},
+			expectError: true,
+			expectedErr: ErrInvalidDeviceKey,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.setupMocks()
-			_, err := svc.AddDevice(context.Background(), tt.username, tt.password, tt.publicKey)
+			_, _, err := svc.AddDevice(context.Background(), tt.username, tt.password, tt.publicKey, "", "203.0.113.7")
 			if tt.expectError {
 				assert.Error(t, err)
 				if tt.expectedErr != nil {
@@ -211,14 +243,120 @@ func TestAuthService_AddDevice(t *testing.T) {
 	}
 }
 
+func TestAuthService_AddDeviceWithApproval(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGetter := NewMockUserGetter(ctrl)
+	mockDeviceSaver := NewMockDeviceSaver(ctrl)
+	mockDeviceLister := NewMockDeviceLister(ctrl)
+	svc := NewAuthService(mockGetter, nil, nil, mockDeviceSaver, nil, WithDeviceApproval(mockDeviceLister))
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
+	user := &models.UserDB{UserUUID: uuid.New(), Username: "johndoe", PasswordHash: string(hashedPassword)}
+
+	signing, err := e2ee.GenerateDeviceKey()
+	assert.NoError(t, err)
+	pubkey := e2ee.EncodePublicKey(signing.PublicKey())
+	signingPriv, err := e2ee.DeriveSigningKey(signing)
+	assert.NoError(t, err)
+	signingKey := e2ee.EncodeSigningKey(signingPriv.Public().(ed25519.PublicKey))
+
+	tests := []struct {
+		name           string
+		signingKey     string
+		setupMocks     func()
+		expectedStatus string
+		expectedErr    error
+	}{
+		{
+			name:       "first device is active",
+			signingKey: signingKey,
+			setupMocks: func() {
+				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
+				mockDeviceLister.EXPECT().ListByUser(gomock.Any(), user.UserUUID).Return(nil, nil)
+				mockDeviceSaver.EXPECT().Save(gomock.Any(), gomock.Any(), user.UserUUID, pubkey, signingKey, models.DeviceStatusActive).Return(nil)
+			},
+			expectedStatus: models.DeviceStatusActive,
+		},
+		{
+			name:       "device with trusted device is pending",
+			signingKey: signingKey,
+			setupMocks: func() {
+				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
+				mockDeviceLister.EXPECT().ListByUser(gomock.Any(), user.UserUUID).Return([]models.UserDeviceDB{
+					{Status: models.DeviceStatusPending, SigningKey: "pending-key"},
+					{Status: models.DeviceStatusActive, SigningKey: "trusted-key"},
+				}, nil)
+				mockDeviceSaver.EXPECT().Save(gomock.Any(), gomock.Any(), user.UserUUID, pubkey, signingKey, models.DeviceStatusPending).Return(nil)
+			},
+			expectedStatus: models.DeviceStatusPending,
+		},
+		{
+			// устройство без ключа подписи сначала регистрирует его, затем подтверждает новое
+			name:       "device of legacy account is pending",
+			signingKey: signingKey,
+			setupMocks: func() {
+				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
+				mockDeviceLister.EXPECT().ListByUser(gomock.Any(), user.UserUUID).Return([]models.UserDeviceDB{
+					{Status: models.DeviceStatusActive},
+				}, nil)
+				mockDeviceSaver.EXPECT().Save(gomock.Any(), gomock.Any(), user.UserUUID, pubkey, signingKey, models.DeviceStatusPending).Return(nil)
+			},
+			expectedStatus: models.DeviceStatusPending,
+		},
+		{
+			name:       "only pending devices",
+			signingKey: signingKey,
+			setupMocks: func() {
+				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
+				mockDeviceLister.EXPECT().ListByUser(gomock.Any(), user.UserUUID).Return([]models.UserDeviceDB{
+					{Status: models.DeviceStatusPending, SigningKey: "pending-key"},
+				}, nil)
+				mockDeviceSaver.EXPECT().Save(gomock.Any(), gomock.Any(), user.UserUUID, pubkey, signingKey, models.DeviceStatusActive).Return(nil)
+			},
+			expectedStatus: models.DeviceStatusActive,
+		},
+		{
+			name:        "invalid signing key",
+			signingKey:  "not-a-key",
+			setupMocks:  func() {},
+			expectedErr: ErrInvalidDeviceKey,
+		},
+		{
+			name:       "lister error",
+			signingKey: signingKey,
+			setupMocks: func() {
+				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
+				mockDeviceLister.EXPECT().ListByUser(gomock.Any(), user.UserUUID).Return(nil, errors.New("db error"))
+			},
+			expectedErr: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+			deviceUUID, status, err := svc.AddDevice(context.Background(), "johndoe", "secret", pubkey, tt.signingKey, "203.0.113.7")
+			if tt.expectedErr != nil {
+				assert.EqualError(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEqual(t, uuid.Nil, deviceUUID)
+			assert.Equal(t, tt.expectedStatus, status)
+		})
+	}
+}
+
 func TestAuthService_Login(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockGetter := NewMockUserGetter(ctrl)
 	mockDeviceGetter := NewMockDeviceGetter(ctrl)
-	mockTokenGen := NewMockTokenGenerator(ctrl)
-	svc := NewAuthService(mockGetter, nil, mockDeviceGetter, nil, mockTokenGen)
+	mockSessionIssuer := NewMockSessionIssuer(ctrl)
+	svc := NewAuthService(mockGetter, nil, mockDeviceGetter, nil, mockSessionIssuer)
 
 	tests := []struct {
 		name        string
@@ -245,7 +383,7 @@ func TestAuthService_Login(t *testing.T) {
 				}
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
 				mockDeviceGetter.EXPECT().Get(gomock.Any(), deviceUUID).Return(device, nil)
-				mockTokenGen.EXPECT().Generate(userUUID, deviceUUID).Return("token123", nil)
+				mockSessionIssuer.EXPECT().IssueSession(gomock.Any(), userUUID, deviceUUID).Return("token123", "refresh123", nil)
 			},
 		},
 		{
@@ -322,6 +460,51 @@ func TestAuthService_Login(t *testing.T) {
 			expectError: true,
 			expectedErr: ErrInvalidCredentials,
 		},
+		{
+			name:     "device revoked",
+			username: "johndoe",
+			password: "secret",
+			setupMocks: func(userUUID, deviceUUID uuid.UUID) {
+				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
+				user := &models.UserDB{
+					UserUUID:     userUUID,
+					Username:     "johndoe",
+					PasswordHash: string(hashedPassword),
+				}
+				revokedAt := time.Now()
+				device := &models.UserDeviceDB{
+					UserUUID:   userUUID,
+					DeviceUUID: deviceUUID,
+					RevokedAt:  &revokedAt,
+				}
+				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
+				mockDeviceGetter.EXPECT().Get(gomock.Any(), deviceUUID).Return(device, nil)
+			},
+			expectError: true,
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "device pending approval",
+			username: "johndoe",
+			password: "secret",
+			setupMocks: func(userUUID, deviceUUID uuid.UUID) {
+				hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
+				user := &models.UserDB{
+					UserUUID:     userUUID,
+					Username:     "johndoe",
+					PasswordHash: string(hashedPassword),
+				}
+				device := &models.UserDeviceDB{
+					UserUUID:   userUUID,
+					DeviceUUID: deviceUUID,
+					Status:     models.DeviceStatusPending,
+				}
+				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
+				mockDeviceGetter.EXPECT().Get(gomock.Any(), deviceUUID).Return(device, nil)
+			},
+			expectError: true,
+			expectedErr: ErrDevicePendingApproval,
+		},
 		{
 			name:     "device getter returns error",
 			username: "johndoe",
@@ -356,7 +539,7 @@ func TestAuthService_Login(t *testing.T) {
 				}
 				mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil)
 				mockDeviceGetter.EXPECT().Get(gomock.Any(), deviceUUID).Return(device, nil)
-				mockTokenGen.EXPECT().Generate(userUUID, deviceUUID).Return("", errors.New("jwt error"))
+				mockSessionIssuer.EXPECT().IssueSession(gomock.Any(), userUUID, deviceUUID).Return("", "", errors.New("jwt error"))
 			},
 			expectError: true,
 			expectedErr: errors.New("jwt error"),
@@ -369,7 +552,7 @@ func TestAuthService_Login(t *testing.T) {
 			deviceUUID := uuid.New()
 			tt.setupMocks(userUUID, deviceUUID)
 
-			_, err := svc.Login(context.Background(), tt.username, tt.password, deviceUUID)
+			_, _, err := svc.Login(context.Background(), tt.username, tt.password, deviceUUID, "203.0.113.7")
 			if tt.expectError {
 				assert.Error(t, err)
 				if tt.expectedErr != nil {
@@ -381,3 +564,108 @@ func TestAuthService_Login(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthService_LoginThrottle(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockGetter := NewMockUserGetter(ctrl)
+	mockDeviceGetter := NewMockDeviceGetter(ctrl)
+	mockSessionIssuer := NewMockSessionIssuer(ctrl)
+	mockThrottler := NewMockLoginThrottler(ctrl)
+	svc := NewAuthService(mockGetter, nil, mockDeviceGetter, nil, mockSessionIssuer, WithLoginThrottle(nil, mockThrottler))
+
+	ctx := context.Background()
+	ip := "203.0.113.7"
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &models.UserDB{UserUUID: userUUID, Username: "johndoe", PasswordHash: string(hashedPassword)}
+	device := &models.UserDeviceDB{UserUUID: userUUID, DeviceUUID: deviceUUID}
+
+	tests := []struct {
+		name        string
+		password    string
+		setupMocks  func()
+		expectedErr error
+	}{
+		{
+			name:     "success resets attempts",
+			password: "secret",
+			setupMocks: func() {
+				gomock.InOrder(
+					mockThrottler.EXPECT().Begin("johndoe", ip).Return(nil),
+					mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil),
+					mockThrottler.EXPECT().Succeed("johndoe", ip),
+				)
+				mockDeviceGetter.EXPECT().Get(gomock.Any(), deviceUUID).Return(device, nil)
+				mockSessionIssuer.EXPECT().IssueSession(gomock.Any(), userUUID, deviceUUID).Return("token", "refresh", nil)
+			},
+		},
+		{
+			name:     "wrong password counts as failure",
+			password: "wrong",
+			setupMocks: func() {
+				gomock.InOrder(
+					mockThrottler.EXPECT().Begin("johndoe", ip).Return(nil),
+					mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(user, nil),
+					mockThrottler.EXPECT().Fail(gomock.Any(), "johndoe", ip).Return(nil),
+				)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "unknown user counts as failure",
+			password: "secret",
+			setupMocks: func() {
+				gomock.InOrder(
+					mockThrottler.EXPECT().Begin("johndoe", ip).Return(nil),
+					mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(nil, nil),
+					mockThrottler.EXPECT().Fail(gomock.Any(), "johndoe", ip).Return(nil),
+				)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:     "throttled before password check",
+			password: "secret",
+			setupMocks: func() {
+				mockThrottler.EXPECT().Begin("johndoe", ip).Return(&TooManyAttemptsError{RetryAfter: time.Minute})
+			},
+			expectedErr: ErrTooManyAttempts,
+		},
+		{
+			name:     "storage error releases attempt",
+			password: "secret",
+			setupMocks: func() {
+				gomock.InOrder(
+					mockThrottler.EXPECT().Begin("johndoe", ip).Return(nil),
+					mockGetter.EXPECT().Get(gomock.Any(), "johndoe").Return(nil, errors.New("db error")),
+					mockThrottler.EXPECT().Cancel("johndoe", ip),
+				)
+			},
+			expectedErr: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+			_, _, err := svc.Login(ctx, "johndoe", tt.password, deviceUUID, ip)
+			if tt.expectedErr != nil {
+				assert.ErrorContains(t, err, tt.expectedErr.Error())
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// AddDevice проверяет пароль с тем же ограничением
+	mockThrottler.EXPECT().Begin("johndoe", ip).Return(&TooManyAttemptsError{RetryAfter: time.Second})
+	devicePriv, err := e2ee.GenerateDeviceKey()
+	assert.NoError(t, err)
+	_, _, err = svc.AddDevice(ctx, "johndoe", "secret", e2ee.EncodePublicKey(devicePriv.PublicKey()), "", ip)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+}
@@ -1,9 +1,12 @@
 package services
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/sbilibin2017/bil-message/internal/models"
@@ -15,35 +18,106 @@ var ErrRoomNotFound = errors.New("room not found")
 // ErrUserNotInRoom возвращается, если пользователь не состоит в комнате.
 var ErrUserNotInRoom = errors.New("user not in room")
 
+// ErrForbidden возвращается, если у пользователя недостаточно прав для операции с комнатой.
+var ErrForbidden = errors.New("forbidden")
+
+// ErrOwnerCannotLeave возвращается, если владелец пытается покинуть комнату, не передав владение.
+var ErrOwnerCannotLeave = errors.New("owner must transfer ownership before leaving")
+
+// ErrInvalidRole возвращается при попытке назначить неизвестную или недопустимую роль.
+var ErrInvalidRole = errors.New("invalid room role")
+
+// ErrInvalidRoomMetadata возвращается, если название или тема комнаты длиннее допустимого.
+var ErrInvalidRoomMetadata = errors.New("invalid room name or topic")
+
+// ErrInvalidDirectRoom возвращается при попытке создать личную комнату с самим собой.
+var ErrInvalidDirectRoom = errors.New("direct room requires another user")
+
+const (
+	// MaxRoomNameLength — максимальная длина названия комнаты в символах
+	MaxRoomNameLength = 255
+	// MaxRoomTopicLength — максимальная длина темы комнаты в символах
+	MaxRoomTopicLength = 1024
+)
+
+// directRoomNamespace — пространство имён UUID ключей личных комнат
+var directRoomNamespace = uuid.MustParse("6f1c1f0e-3b7a-4d2e-9a51-0d9b8c2e7f43")
+
 // RoomWriter описывает интерфейс для создания или обновления комнаты в хранилище.
 type RoomWriter interface {
-	// Save сохраняет комнату с указанным roomUUID и creatorUUID.
+	// Save сохраняет групповую комнату с указанным roomUUID, creatorUUID, названием и темой.
 	// Если комната уже существует, обновляет только updated_at.
-	Save(ctx context.Context, roomUUID uuid.UUID, creatorUUID uuid.UUID) error
+	Save(ctx context.Context, roomUUID uuid.UUID, creatorUUID uuid.UUID, name string, topic string) error
+
+	// SaveDirect сохраняет личную комнату пары собеседников с ключом directKey.
+	// Если личная комната с таким ключом уже существует, ничего не меняет.
+	SaveDirect(ctx context.Context, roomUUID uuid.UUID, creatorUUID uuid.UUID, directKey uuid.UUID) error
+
+	// UpdateMetadata изменяет название и тему комнаты.
+	UpdateMetadata(ctx context.Context, roomUUID uuid.UUID, name string, topic string) error
 
 	Delete(ctx context.Context, roomUUID uuid.UUID) error
+
+	// BumpKeyEpoch увеличивает эпоху ключа комнаты и возвращает новое значение.
+	BumpKeyEpoch(ctx context.Context, roomUUID uuid.UUID) (int64, error)
 }
 
 // RoomReader описывает интерфейс для чтения информации о комнате.
 type RoomReader interface {
 	// Get возвращает комнату по roomUUID или nil, если комната не найдена.
 	Get(ctx context.Context, roomUUID uuid.UUID) (*models.RoomDB, error)
+
+	// GetDirect возвращает личную комнату по ключу пары собеседников или nil, если комната не найдена.
+	GetDirect(ctx context.Context, directKey uuid.UUID) (*models.RoomDB, error)
+
+	// ListByUser возвращает комнаты пользователя с его ролью, количеством участников и временем последнего сообщения.
+	ListByUser(ctx context.Context, userUUID uuid.UUID) ([]models.RoomSummaryDB, error)
 }
 
 // RoomMemberWriter описывает интерфейс для добавления и удаления участников комнаты.
 type RoomMemberWriter interface {
-	// Save добавляет пользователя в комнату.
+	// Save добавляет пользователя в комнату с указанной ролью.
 	// Если запись уже существует, обновляет только updated_at.
-	Save(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID, joinedAt time.Time) error
+	Save(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID, role string, joinedAt time.Time) error
+	// SaveWithKeyRotation в одной транзакции добавляет пользователя в комнату и увеличивает эпоху ключа комнаты.
+	// Возвращает новую эпоху или 0, если пользователь уже состоит в комнате.
+	SaveWithKeyRotation(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID, role string, joinedAt time.Time) (int64, error)
 
-	// Delete удаляет пользователя из комнаты.
-	Delete(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error
+	// UpdateRole изменяет роль участника комнаты.
+	UpdateRole(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID, role string) error
+	// TransferOwnership атомарно передаёт роль владельца от fromUUID к toUUID, делая fromUUID администратором.
+	// Возвращает false, если fromUUID уже не владелец или toUUID не участник комнаты.
+	TransferOwnership(ctx context.Context, roomUUID uuid.UUID, fromUUID uuid.UUID, toUUID uuid.UUID) (bool, error)
+
+	// DeleteWithKeyRotation в одной транзакции удаляет пользователя из комнаты и увеличивает эпоху ключа комнаты.
+	// Возвращает новую эпоху или 0, если пользователь не состоял в комнате.
+	DeleteWithKeyRotation(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) (int64, error)
 }
 
 // RoomMemberReader описывает интерфейс для чтения участников комнаты.
 type RoomMemberReader interface {
 	// Get возвращает участника комнаты по roomUUID и userUUID, или nil если не найден.
 	Get(ctx context.Context, roomUUID, userUUID uuid.UUID) (*models.RoomMemberDB, error)
+
+	// ListWithUsers возвращает участников комнаты с именами пользователей.
+	ListWithUsers(ctx context.Context, roomUUID uuid.UUID) ([]models.RoomMemberUserDB, error)
+}
+
+// UserReader описывает интерфейс для проверки существования пользователя.
+type UserReader interface {
+	// GetByUUID возвращает пользователя по userUUID или nil, если пользователь не найден.
+	GetByUUID(ctx context.Context, userUUID uuid.UUID) (*models.UserDB, error)
+}
+
+// RoomNotifier описывает интерфейс уведомления подключённых клиентов о событиях комнаты.
+type RoomNotifier interface {
+	// NotifyKeyRotation сообщает клиентам комнаты о начале эпохи keyEpoch.
+	NotifyKeyRotation(roomUUID uuid.UUID, keyEpoch int64)
+	// NotifyRoomUpdated сообщает клиентам комнаты её новые название и тему.
+	NotifyRoomUpdated(roomUUID uuid.UUID, name string, topic string)
+	// Disconnect закрывает соединения пользователя userUUID с комнатой roomUUID (устройства deviceUUID,
+	// если оно задано; всех клиентов комнаты, если userUUID = uuid.Nil) с причиной reason.
+	Disconnect(roomUUID uuid.UUID, userUUID uuid.UUID, deviceUUID uuid.UUID, reason string)
 }
 
 // ChatService реализует бизнес-логику работы с комнатами и их участниками.
@@ -52,6 +126,8 @@ type ChatService struct {
 	rr  RoomReader       // репозиторий для чтения комнат
 	rmw RoomMemberWriter // репозиторий для записи участников
 	rmr RoomMemberReader // репозиторий для чтения участников
+	ur  UserReader       // репозиторий для проверки существования пользователей
+	rn  RoomNotifier     // уведомление клиентов о событиях комнаты, может быть nil
 }
 
 // NewChatService создаёт новый экземпляр RoomService с указанными репозиториями.
@@ -60,45 +136,228 @@ func NewChatService(
 	rr RoomReader,
 	rmw RoomMemberWriter,
 	rmr RoomMemberReader,
+	ur UserReader,
+	rn RoomNotifier,
 ) *ChatService {
 	return &ChatService{
 		rw:  rw,
 		rr:  rr,
 		rmw: rmw,
 		rmr: rmr,
+		ur:  ur,
+		rn:  rn,
 	}
 }
 
-// Create создаёт новую комнату и добавляет создателя как участника.
-func (svc *ChatService) CreateRoom(ctx context.Context, userUUID uuid.UUID) (roomUUID uuid.UUID, err error) {
+// CreateRoom создаёт новую групповую комнату с названием и темой и добавляет создателя как владельца.
+func (svc *ChatService) CreateRoom(ctx context.Context, userUUID uuid.UUID, name string, topic string) (roomUUID uuid.UUID, err error) {
+	name, topic, err = normalizeRoomMetadata(name, topic)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
 	roomUUID = uuid.New()
 
-	if err := svc.rw.Save(ctx, roomUUID, userUUID); err != nil {
+	if err := svc.rw.Save(ctx, roomUUID, userUUID, name, topic); err != nil {
 		return uuid.Nil, err
 	}
 
-	if err := svc.rmw.Save(ctx, roomUUID, userUUID, time.Now().UTC()); err != nil {
+	if err := svc.rmw.Save(ctx, roomUUID, userUUID, models.RoomRoleOwner, time.Now().UTC()); err != nil {
 		return uuid.Nil, err
 	}
 
 	return roomUUID, nil
 }
 
-// Remove удаляет комнату и всех её участников.
-func (svc *ChatService) RemoveRoom(ctx context.Context, roomUUID uuid.UUID) error {
-	room, err := svc.rr.Get(ctx, roomUUID)
+// CreateDirectRoom возвращает личную комнату пользователя userUUID и собеседника peerUUID, создавая её при первом обращении.
+// Для каждой пары пользователей существует одна личная комната: повторный вызов любым из собеседников
+// возвращает ту же комнату. Создатель становится владельцем, собеседник — обычным участником.
+// Собеседник, покинувший существующую комнату, не возвращается в неё без своего согласия: вернуться
+// он может только сам, вызвав CreateDirectRoom. Если комнату покинул пользователь userUUID, он добавляется снова,
+// и начинается новая эпоха ключа.
+func (svc *ChatService) CreateDirectRoom(ctx context.Context, userUUID uuid.UUID, peerUUID uuid.UUID) (uuid.UUID, error) {
+	if userUUID == peerUUID {
+		return uuid.Nil, ErrInvalidDirectRoom
+	}
+	if err := svc.checkUser(ctx, peerUUID); err != nil {
+		return uuid.Nil, err
+	}
+
+	directKey := directRoomKey(userUUID, peerUUID)
+	newRoomUUID := uuid.New()
+	if err := svc.rw.SaveDirect(ctx, newRoomUUID, userUUID, directKey); err != nil {
+		return uuid.Nil, err
+	}
+
+	room, err := svc.rr.GetDirect(ctx, directKey)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if room == nil {
+		return uuid.Nil, ErrRoomNotFound
+	}
+
+	// Новая комната начинается с первой эпохи, и в неё добавляются оба собеседника
+	if room.RoomUUID == newRoomUUID {
+		now := time.Now().UTC()
+		if err := svc.rmw.Save(ctx, room.RoomUUID, userUUID, models.RoomRoleOwner, now); err != nil {
+			return uuid.Nil, err
+		}
+		if err := svc.rmw.Save(ctx, room.RoomUUID, peerUUID, models.RoomRoleMember, now); err != nil {
+			return uuid.Nil, err
+		}
+		return room.RoomUUID, nil
+	}
+
+	member, err := svc.rmr.Get(ctx, room.RoomUUID, userUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if member != nil {
+		return room.RoomUUID, nil
+	}
+
+	role := models.RoomRoleMember
+	if userUUID == room.CreatorUUID {
+		role = models.RoomRoleOwner
+	}
+	epoch, err := svc.rmw.SaveWithKeyRotation(ctx, room.RoomUUID, userUUID, role, time.Now().UTC())
+	if err != nil {
+		return uuid.Nil, err
+	}
+	svc.notifyKeyRotation(room.RoomUUID, epoch)
+
+	return room.RoomUUID, nil
+}
+
+// UpdateRoom изменяет название и тему комнаты; nil оставляет значение без изменений.
+// В групповой комнате изменять их могут владелец и администраторы, в личной — оба собеседника.
+// Подключённые клиенты получают новые название и тему событием комнаты.
+func (svc *ChatService) UpdateRoom(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, name *string, topic *string) error {
+	room, actor, err := svc.getActor(ctx, roomUUID, actorUUID)
 	if err != nil {
 		return err
 	}
+	if room.Kind != models.RoomKindDirect && actor.Role != models.RoomRoleOwner && actor.Role != models.RoomRoleAdmin {
+		return ErrForbidden
+	}
+
+	newName, newTopic := room.Name, room.Topic
+	if name != nil {
+		newName = *name
+	}
+	if topic != nil {
+		newTopic = *topic
+	}
+	newName, newTopic, err = normalizeRoomMetadata(newName, newTopic)
+	if err != nil {
+		return err
+	}
+	if newName == room.Name && newTopic == room.Topic {
+		return nil
+	}
+
+	if err := svc.rw.UpdateMetadata(ctx, roomUUID, newName, newTopic); err != nil {
+		return err
+	}
+
+	if svc.rn != nil {
+		svc.rn.NotifyRoomUpdated(roomUUID, newName, newTopic)
+	}
+
+	return nil
+}
+
+// RemoveRoom удаляет комнату и всех её участников.
+// Удалить комнату может только её владелец; соединения всех участников с комнатой закрываются.
+func (svc *ChatService) RemoveRoom(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID) error {
+	_, actor, err := svc.getActor(ctx, roomUUID, actorUUID)
+	if err != nil {
+		return err
+	}
+	if actor.Role != models.RoomRoleOwner {
+		return ErrForbidden
+	}
+
+	if err := svc.rw.Delete(ctx, roomUUID); err != nil {
+		return err
+	}
+
+	svc.disconnect(roomUUID, uuid.Nil, "room removed")
+	return nil
+}
+
+// JoinRoom добавляет пользователя в групповую комнату как обычного участника, например по принятому приглашению,
+// и сообщает, был ли он добавлен. Если пользователь уже состоит в комнате, ничего не меняет и возвращает false;
+// иначе вместе с добавлением начинается новая эпоха ключа комнаты.
+func (svc *ChatService) JoinRoom(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) (bool, error) {
+	room, err := svc.rr.Get(ctx, roomUUID)
+	if err != nil {
+		return false, err
+	}
 	if room == nil {
-		return ErrRoomNotFound
+		return false, ErrRoomNotFound
+	}
+	if room.Kind == models.RoomKindDirect {
+		return false, ErrForbidden
+	}
+
+	epoch, err := svc.rmw.SaveWithKeyRotation(ctx, roomUUID, userUUID, models.RoomRoleMember, time.Now().UTC())
+	if err != nil {
+		return false, err
 	}
 
-	return svc.rw.Delete(ctx, roomUUID)
+	svc.notifyKeyRotation(roomUUID, epoch)
+	return epoch > 0, nil
 }
 
-// AddUser добавляет пользователя в существующую комнату..
-func (svc *ChatService) AddRoomMember(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error {
+// RemoveRoomMember удаляет пользователя из комнаты.
+// Владелец может удалить любого участника, администратор — только обычных участников.
+// Удаление самого себя равносильно выходу из комнаты (см. LeaveRoom).
+// Вместе с удалением начинается новая эпоха ключа комнаты, недоступная удалённому участнику,
+// затем соединения участника с комнатой закрываются.
+func (svc *ChatService) RemoveRoomMember(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, userUUID uuid.UUID) error {
+	if actorUUID == userUUID {
+		return svc.LeaveRoom(ctx, roomUUID, userUUID)
+	}
+
+	_, actor, err := svc.getActor(ctx, roomUUID, actorUUID)
+	if err != nil {
+		return err
+	}
+
+	member, err := svc.rmr.Get(ctx, roomUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrUserNotInRoom
+	}
+
+	switch actor.Role {
+	case models.RoomRoleOwner:
+	case models.RoomRoleAdmin:
+		if member.Role != models.RoomRoleMember {
+			return ErrForbidden
+		}
+	default:
+		return ErrForbidden
+	}
+
+	epoch, err := svc.rmw.DeleteWithKeyRotation(ctx, roomUUID, userUUID)
+	if err != nil {
+		return err
+	}
+
+	svc.disconnect(roomUUID, userUUID, "removed from room")
+	svc.notifyKeyRotation(roomUUID, epoch)
+	return nil
+}
+
+// LeaveRoom удаляет пользователя из комнаты по его собственному запросу.
+// Владелец не может покинуть комнату, пока не передаст владение другому участнику.
+// Вместе с выходом начинается новая эпоха ключа комнаты, затем соединения пользователя с комнатой закрываются.
+func (svc *ChatService) LeaveRoom(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error {
 	room, err := svc.rr.Get(ctx, roomUUID)
 	if err != nil {
 		return err
@@ -107,11 +366,198 @@ func (svc *ChatService) AddRoomMember(ctx context.Context, roomUUID uuid.UUID, u
 		return ErrRoomNotFound
 	}
 
-	return svc.rmw.Save(ctx, roomUUID, userUUID, time.Now().UTC())
+	member, err := svc.rmr.Get(ctx, roomUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrUserNotInRoom
+	}
+	if member.Role == models.RoomRoleOwner {
+		return ErrOwnerCannotLeave
+	}
+
+	epoch, err := svc.rmw.DeleteWithKeyRotation(ctx, roomUUID, userUUID)
+	if err != nil {
+		return err
+	}
+
+	svc.disconnect(roomUUID, userUUID, "left the room")
+	svc.notifyKeyRotation(roomUUID, epoch)
+	return nil
+}
+
+// TransferOwnership передаёт владение комнатой другому участнику.
+// Выполнить передачу может только текущий владелец; после передачи он становится администратором.
+func (svc *ChatService) TransferOwnership(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, newOwnerUUID uuid.UUID) error {
+	_, actor, err := svc.getActor(ctx, roomUUID, actorUUID)
+	if err != nil {
+		return err
+	}
+	if actor.Role != models.RoomRoleOwner {
+		return ErrForbidden
+	}
+	if actorUUID == newOwnerUUID {
+		return nil
+	}
+
+	member, err := svc.rmr.Get(ctx, roomUUID, newOwnerUUID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrUserNotInRoom
+	}
+
+	// Повышение и понижение выполняются в одной транзакции, поэтому у комнаты всегда ровно один владелец
+	ok, err := svc.rmw.TransferOwnership(ctx, roomUUID, actorUUID, newOwnerUUID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// Владелец или участник изменились параллельно с передачей
+		return ErrUserNotInRoom
+	}
+
+	return nil
+}
+
+// SetRoomMemberRole назначает участнику роль администратора или обычного участника.
+// Изменять роли может только владелец; роль владельца передаётся через TransferOwnership.
+func (svc *ChatService) SetRoomMemberRole(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, userUUID uuid.UUID, role string) error {
+	if role != models.RoomRoleAdmin && role != models.RoomRoleMember {
+		return ErrInvalidRole
+	}
+
+	_, actor, err := svc.getActor(ctx, roomUUID, actorUUID)
+	if err != nil {
+		return err
+	}
+	if actor.Role != models.RoomRoleOwner || actorUUID == userUUID {
+		return ErrForbidden
+	}
+
+	member, err := svc.rmr.Get(ctx, roomUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrUserNotInRoom
+	}
+
+	return svc.rmw.UpdateRole(ctx, roomUUID, userUUID, role)
+}
+
+// ListRooms возвращает комнаты, в которых состоит пользователь, начиная с комнат с недавней активностью.
+func (svc *ChatService) ListRooms(ctx context.Context, userUUID uuid.UUID) ([]models.RoomSummaryDB, error) {
+	return svc.rr.ListByUser(ctx, userUUID)
+}
+
+// ListRoomMembers возвращает участников комнаты с именами пользователей.
+// Список доступен только участникам комнаты.
+func (svc *ChatService) ListRoomMembers(ctx context.Context, userUUID uuid.UUID, roomUUID uuid.UUID) ([]models.RoomMemberUserDB, error) {
+	if err := svc.CheckRoomMember(ctx, roomUUID, userUUID); err != nil {
+		return nil, err
+	}
+	return svc.rmr.ListWithUsers(ctx, roomUUID)
+}
+
+// rotateKey начинает новую эпоху ключа комнаты и уведомляет подключённых клиентов.
+func (svc *ChatService) rotateKey(ctx context.Context, roomUUID uuid.UUID) error {
+	epoch, err := svc.rw.BumpKeyEpoch(ctx, roomUUID)
+	if err != nil {
+		return err
+	}
+
+	svc.notifyKeyRotation(roomUUID, epoch)
+	return nil
+}
+
+// notifyKeyRotation сообщает подключённым клиентам комнаты о новой эпохе ключа; 0 означает, что эпоха не менялась.
+// Ключ эпохи выпускает любой подключённый участник: сервер сохраняет только первый загруженный ключ,
+// остальные клиенты получают его, поэтому ротация не зависит от того, в сети ли конкретный участник.
+func (svc *ChatService) notifyKeyRotation(roomUUID uuid.UUID, epoch int64) {
+	if svc.rn != nil && epoch > 0 {
+		svc.rn.NotifyKeyRotation(roomUUID, epoch)
+	}
+}
+
+// RotateUserRoomKeys начинает новую эпоху ключа во всех комнатах пользователя userUUID,
+// например после отзыва одного из его устройств.
+func (svc *ChatService) RotateUserRoomKeys(ctx context.Context, userUUID uuid.UUID) error {
+	rooms, err := svc.rr.ListByUser(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+	for _, room := range rooms {
+		if err := svc.rotateKey(ctx, room.RoomUUID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// disconnect закрывает соединения пользователя userUUID (всех клиентов, если uuid.Nil) с комнатой roomUUID
+func (svc *ChatService) disconnect(roomUUID uuid.UUID, userUUID uuid.UUID, reason string) {
+	if svc.rn != nil {
+		svc.rn.Disconnect(roomUUID, userUUID, uuid.Nil, reason)
+	}
+}
+
+// getActor проверяет существование комнаты и возвращает комнату и запись участника, выполняющего операцию.
+// Если пользователь не состоит в комнате, возвращает ErrForbidden.
+func (svc *ChatService) getActor(ctx context.Context, roomUUID uuid.UUID, actorUUID uuid.UUID) (*models.RoomDB, *models.RoomMemberDB, error) {
+	room, err := svc.rr.Get(ctx, roomUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if room == nil {
+		return nil, nil, ErrRoomNotFound
+	}
+
+	actor, err := svc.rmr.Get(ctx, roomUUID, actorUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if actor == nil {
+		return nil, nil, ErrForbidden
+	}
+
+	return room, actor, nil
+}
+
+// checkUser проверяет, что пользователь существует; иначе возвращает ErrUserNotFound.
+func (svc *ChatService) checkUser(ctx context.Context, userUUID uuid.UUID) error {
+	user, err := svc.ur.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// normalizeRoomMetadata убирает пробелы по краям названия и темы комнаты и проверяет их длину.
+func normalizeRoomMetadata(name string, topic string) (string, string, error) {
+	name, topic = strings.TrimSpace(name), strings.TrimSpace(topic)
+	if utf8.RuneCountInString(name) > MaxRoomNameLength || utf8.RuneCountInString(topic) > MaxRoomTopicLength {
+		return "", "", ErrInvalidRoomMetadata
+	}
+	return name, topic, nil
+}
+
+// directRoomKey возвращает ключ личной комнаты пары пользователей, не зависящий от порядка собеседников.
+func directRoomKey(a uuid.UUID, b uuid.UUID) uuid.UUID {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return uuid.NewSHA1(directRoomNamespace, append(a[:], b[:]...))
 }
 
-// RemoveUser удаляет пользователя из комнаты.
-func (svc *ChatService) RemoveRoomMember(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error {
+// CheckRoomMember проверяет, что комната существует и пользователь является её участником.
+// Возвращает ErrRoomNotFound, если комнаты нет, и ErrUserNotInRoom, если пользователь не участник.
+func (svc *ChatService) CheckRoomMember(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error {
 	room, err := svc.rr.Get(ctx, roomUUID)
 	if err != nil {
 		return err
@@ -128,5 +574,5 @@ func (svc *ChatService) RemoveRoomMember(ctx context.Context, roomUUID uuid.UUID
 		return ErrUserNotInRoom
 	}
 
-	return svc.rmw.Delete(ctx, roomUUID, userUUID)
+	return nil
 }
@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: /home/sergey/Github/bil-message/internal/services/room.go
+// Source: /home/sergey/Github/bil-message/internal/services/chat.go
 
 // Package services is a generated GoMock package.
 package services
@@ -37,6 +37,21 @@ func (m *MockRoomWriter) EXPECT() *MockRoomWriterMockRecorder {
 	return m.recorder
 }
 
+// BumpKeyEpoch mocks base method.
+func (m *MockRoomWriter) BumpKeyEpoch(ctx context.Context, roomUUID uuid.UUID) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BumpKeyEpoch", ctx, roomUUID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BumpKeyEpoch indicates an expected call of BumpKeyEpoch.
+func (mr *MockRoomWriterMockRecorder) BumpKeyEpoch(ctx, roomUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BumpKeyEpoch", reflect.TypeOf((*MockRoomWriter)(nil).BumpKeyEpoch), ctx, roomUUID)
+}
+
 // Delete mocks base method.
 func (m *MockRoomWriter) Delete(ctx context.Context, roomUUID uuid.UUID) error {
 	m.ctrl.T.Helper()
@@ -52,17 +67,45 @@ func (mr *MockRoomWriterMockRecorder) Delete(ctx, roomUUID interface{}) *gomock.
 }
 
 // Save mocks base method.
-func (m *MockRoomWriter) Save(ctx context.Context, roomUUID, creatorUUID uuid.UUID) error {
+func (m *MockRoomWriter) Save(ctx context.Context, roomUUID, creatorUUID uuid.UUID, name, topic string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", ctx, roomUUID, creatorUUID)
+	ret := m.ctrl.Call(m, "Save", ctx, roomUUID, creatorUUID, name, topic)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockRoomWriterMockRecorder) Save(ctx, roomUUID, creatorUUID interface{}) *gomock.Call {
+func (mr *MockRoomWriterMockRecorder) Save(ctx, roomUUID, creatorUUID, name, topic interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockRoomWriter)(nil).Save), ctx, roomUUID, creatorUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockRoomWriter)(nil).Save), ctx, roomUUID, creatorUUID, name, topic)
+}
+
+// SaveDirect mocks base method.
+func (m *MockRoomWriter) SaveDirect(ctx context.Context, roomUUID, creatorUUID, directKey uuid.UUID) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveDirect", ctx, roomUUID, creatorUUID, directKey)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveDirect indicates an expected call of SaveDirect.
+func (mr *MockRoomWriterMockRecorder) SaveDirect(ctx, roomUUID, creatorUUID, directKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveDirect", reflect.TypeOf((*MockRoomWriter)(nil).SaveDirect), ctx, roomUUID, creatorUUID, directKey)
+}
+
+// UpdateMetadata mocks base method.
+func (m *MockRoomWriter) UpdateMetadata(ctx context.Context, roomUUID uuid.UUID, name, topic string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateMetadata", ctx, roomUUID, name, topic)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateMetadata indicates an expected call of UpdateMetadata.
+func (mr *MockRoomWriterMockRecorder) UpdateMetadata(ctx, roomUUID, name, topic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateMetadata", reflect.TypeOf((*MockRoomWriter)(nil).UpdateMetadata), ctx, roomUUID, name, topic)
 }
 
 // MockRoomReader is a mock of RoomReader interface.
@@ -103,6 +146,36 @@ func (mr *MockRoomReaderMockRecorder) Get(ctx, roomUUID interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRoomReader)(nil).Get), ctx, roomUUID)
 }
 
+// GetDirect mocks base method.
+func (m *MockRoomReader) GetDirect(ctx context.Context, directKey uuid.UUID) (*models.RoomDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetDirect", ctx, directKey)
+	ret0, _ := ret[0].(*models.RoomDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetDirect indicates an expected call of GetDirect.
+func (mr *MockRoomReaderMockRecorder) GetDirect(ctx, directKey interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetDirect", reflect.TypeOf((*MockRoomReader)(nil).GetDirect), ctx, directKey)
+}
+
+// ListByUser mocks base method.
+func (m *MockRoomReader) ListByUser(ctx context.Context, userUUID uuid.UUID) ([]models.RoomSummaryDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListByUser", ctx, userUUID)
+	ret0, _ := ret[0].([]models.RoomSummaryDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListByUser indicates an expected call of ListByUser.
+func (mr *MockRoomReaderMockRecorder) ListByUser(ctx, userUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListByUser", reflect.TypeOf((*MockRoomReader)(nil).ListByUser), ctx, userUUID)
+}
+
 // MockRoomMemberWriter is a mock of RoomMemberWriter interface.
 type MockRoomMemberWriter struct {
 	ctrl     *gomock.Controller
@@ -126,32 +199,77 @@ func (m *MockRoomMemberWriter) EXPECT() *MockRoomMemberWriterMockRecorder {
 	return m.recorder
 }
 
-// Delete mocks base method.
-func (m *MockRoomMemberWriter) Delete(ctx context.Context, roomUUID, userUUID uuid.UUID) error {
+// DeleteWithKeyRotation mocks base method.
+func (m *MockRoomMemberWriter) DeleteWithKeyRotation(ctx context.Context, roomUUID, userUUID uuid.UUID) (int64, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Delete", ctx, roomUUID, userUUID)
-	ret0, _ := ret[0].(error)
-	return ret0
+	ret := m.ctrl.Call(m, "DeleteWithKeyRotation", ctx, roomUUID, userUUID)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
-// Delete indicates an expected call of Delete.
-func (mr *MockRoomMemberWriterMockRecorder) Delete(ctx, roomUUID, userUUID interface{}) *gomock.Call {
+// DeleteWithKeyRotation indicates an expected call of DeleteWithKeyRotation.
+func (mr *MockRoomMemberWriterMockRecorder) DeleteWithKeyRotation(ctx, roomUUID, userUUID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockRoomMemberWriter)(nil).Delete), ctx, roomUUID, userUUID)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteWithKeyRotation", reflect.TypeOf((*MockRoomMemberWriter)(nil).DeleteWithKeyRotation), ctx, roomUUID, userUUID)
 }
 
 // Save mocks base method.
-func (m *MockRoomMemberWriter) Save(ctx context.Context, roomUUID, userUUID uuid.UUID, joinedAt time.Time) error {
+func (m *MockRoomMemberWriter) Save(ctx context.Context, roomUUID, userUUID uuid.UUID, role string, joinedAt time.Time) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", ctx, roomUUID, userUUID, joinedAt)
+	ret := m.ctrl.Call(m, "Save", ctx, roomUUID, userUUID, role, joinedAt)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockRoomMemberWriterMockRecorder) Save(ctx, roomUUID, userUUID, joinedAt interface{}) *gomock.Call {
+func (mr *MockRoomMemberWriterMockRecorder) Save(ctx, roomUUID, userUUID, role, joinedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockRoomMemberWriter)(nil).Save), ctx, roomUUID, userUUID, role, joinedAt)
+}
+
+// SaveWithKeyRotation mocks base method.
+func (m *MockRoomMemberWriter) SaveWithKeyRotation(ctx context.Context, roomUUID, userUUID uuid.UUID, role string, joinedAt time.Time) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveWithKeyRotation", ctx, roomUUID, userUUID, role, joinedAt)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SaveWithKeyRotation indicates an expected call of SaveWithKeyRotation.
+func (mr *MockRoomMemberWriterMockRecorder) SaveWithKeyRotation(ctx, roomUUID, userUUID, role, joinedAt interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveWithKeyRotation", reflect.TypeOf((*MockRoomMemberWriter)(nil).SaveWithKeyRotation), ctx, roomUUID, userUUID, role, joinedAt)
+}
+
+// TransferOwnership mocks base method.
+func (m *MockRoomMemberWriter) TransferOwnership(ctx context.Context, roomUUID, fromUUID, toUUID uuid.UUID) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TransferOwnership", ctx, roomUUID, fromUUID, toUUID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TransferOwnership indicates an expected call of TransferOwnership.
+func (mr *MockRoomMemberWriterMockRecorder) TransferOwnership(ctx, roomUUID, fromUUID, toUUID interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockRoomMemberWriter)(nil).Save), ctx, roomUUID, userUUID, joinedAt)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TransferOwnership", reflect.TypeOf((*MockRoomMemberWriter)(nil).TransferOwnership), ctx, roomUUID, fromUUID, toUUID)
+}
+
+// UpdateRole mocks base method.
+func (m *MockRoomMemberWriter) UpdateRole(ctx context.Context, roomUUID, userUUID uuid.UUID, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateRole", ctx, roomUUID, userUUID, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateRole indicates an expected call of UpdateRole.
+func (mr *MockRoomMemberWriterMockRecorder) UpdateRole(ctx, roomUUID, userUUID, role interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateRole", reflect.TypeOf((*MockRoomMemberWriter)(nil).UpdateRole), ctx, roomUUID, userUUID, role)
 }
 
 // MockRoomMemberReader is a mock of RoomMemberReader interface.
@@ -191,3 +309,115 @@ func (mr *MockRoomMemberReaderMockRecorder) Get(ctx, roomUUID, userUUID interfac
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRoomMemberReader)(nil).Get), ctx, roomUUID, userUUID)
 }
+
+// ListWithUsers mocks base method.
+func (m *MockRoomMemberReader) ListWithUsers(ctx context.Context, roomUUID uuid.UUID) ([]models.RoomMemberUserDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListWithUsers", ctx, roomUUID)
+	ret0, _ := ret[0].([]models.RoomMemberUserDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListWithUsers indicates an expected call of ListWithUsers.
+func (mr *MockRoomMemberReaderMockRecorder) ListWithUsers(ctx, roomUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListWithUsers", reflect.TypeOf((*MockRoomMemberReader)(nil).ListWithUsers), ctx, roomUUID)
+}
+
+// MockUserReader is a mock of UserReader interface.
+type MockUserReader struct {
+	ctrl     *gomock.Controller
+	recorder *MockUserReaderMockRecorder
+}
+
+// MockUserReaderMockRecorder is the mock recorder for MockUserReader.
+type MockUserReaderMockRecorder struct {
+	mock *MockUserReader
+}
+
+// NewMockUserReader creates a new mock instance.
+func NewMockUserReader(ctrl *gomock.Controller) *MockUserReader {
+	mock := &MockUserReader{ctrl: ctrl}
+	mock.recorder = &MockUserReaderMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUserReader) EXPECT() *MockUserReaderMockRecorder {
+	return m.recorder
+}
+
+// GetByUUID mocks base method.
+func (m *MockUserReader) GetByUUID(ctx context.Context, userUUID uuid.UUID) (*models.UserDB, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetByUUID", ctx, userUUID)
+	ret0, _ := ret[0].(*models.UserDB)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetByUUID indicates an expected call of GetByUUID.
+func (mr *MockUserReaderMockRecorder) GetByUUID(ctx, userUUID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetByUUID", reflect.TypeOf((*MockUserReader)(nil).GetByUUID), ctx, userUUID)
+}
+
+// MockRoomNotifier is a mock of RoomNotifier interface.
+type MockRoomNotifier struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoomNotifierMockRecorder
+}
+
+// MockRoomNotifierMockRecorder is the mock recorder for MockRoomNotifier.
+type MockRoomNotifierMockRecorder struct {
+	mock *MockRoomNotifier
+}
+
+// NewMockRoomNotifier creates a new mock instance.
+func NewMockRoomNotifier(ctrl *gomock.Controller) *MockRoomNotifier {
+	mock := &MockRoomNotifier{ctrl: ctrl}
+	mock.recorder = &MockRoomNotifierMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoomNotifier) EXPECT() *MockRoomNotifierMockRecorder {
+	return m.recorder
+}
+
+// Disconnect mocks base method.
+func (m *MockRoomNotifier) Disconnect(roomUUID, userUUID, deviceUUID uuid.UUID, reason string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Disconnect", roomUUID, userUUID, deviceUUID, reason)
+}
+
+// Disconnect indicates an expected call of Disconnect.
+func (mr *MockRoomNotifierMockRecorder) Disconnect(roomUUID, userUUID, deviceUUID, reason interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Disconnect", reflect.TypeOf((*MockRoomNotifier)(nil).Disconnect), roomUUID, userUUID, deviceUUID, reason)
+}
+
+// NotifyKeyRotation mocks base method.
+func (m *MockRoomNotifier) NotifyKeyRotation(roomUUID uuid.UUID, keyEpoch int64) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "NotifyKeyRotation", roomUUID, keyEpoch)
+}
+
+// NotifyKeyRotation indicates an expected call of NotifyKeyRotation.
+func (mr *MockRoomNotifierMockRecorder) NotifyKeyRotation(roomUUID, keyEpoch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyKeyRotation", reflect.TypeOf((*MockRoomNotifier)(nil).NotifyKeyRotation), roomUUID, keyEpoch)
+}
+
+// NotifyRoomUpdated mocks base method.
+func (m *MockRoomNotifier) NotifyRoomUpdated(roomUUID uuid.UUID, name, topic string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "NotifyRoomUpdated", roomUUID, name, topic)
+}
+
+// NotifyRoomUpdated indicates an expected call of NotifyRoomUpdated.
+func (mr *MockRoomNotifierMockRecorder) NotifyRoomUpdated(roomUUID, name, topic interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NotifyRoomUpdated", reflect.TypeOf((*MockRoomNotifier)(nil).NotifyRoomUpdated), roomUUID, name, topic)
+}
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -20,35 +21,43 @@ func TestRoomService_Create(t *testing.T) {
 	mockRR := NewMockRoomReader(ctrl)
 	mockRMW := NewMockRoomMemberWriter(ctrl)
 	mockRMR := NewMockRoomMemberReader(ctrl)
-	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR)
+	mockUR := NewMockUserReader(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, nil)
 	userUUID := uuid.New()
 	ctx := context.Background()
 
 	tests := []struct {
 		name          string
+		roomName      string
 		mockSetup     func()
 		expectedError error
 	}{
+		{
+			name:          "name too long",
+			roomName:      strings.Repeat("я", MaxRoomNameLength+1),
+			mockSetup:     func() {},
+			expectedError: ErrInvalidRoomMetadata,
+		},
 		{
 			name: "success",
 			mockSetup: func() {
-				mockRW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID).Return(nil)
-				mockRMW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID, gomock.Any()).Return(nil)
+				mockRW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID, "Команда", "Планы").Return(nil)
+				mockRMW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID, models.RoomRoleOwner, gomock.Any()).Return(nil)
 			},
 			expectedError: nil,
 		},
 		{
 			name: "room writer save error",
 			mockSetup: func() {
-				mockRW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID).Return(errors.New("room save fail"))
+				mockRW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID, "Команда", "Планы").Return(errors.New("room save fail"))
 			},
 			expectedError: errors.New("room save fail"),
 		},
 		{
 			name: "room member save error",
 			mockSetup: func() {
-				mockRW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID).Return(nil)
-				mockRMW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID, gomock.Any()).Return(errors.New("save member fail"))
+				mockRW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID, "Команда", "Планы").Return(nil)
+				mockRMW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID, models.RoomRoleOwner, gomock.Any()).Return(errors.New("save member fail"))
 			},
 			expectedError: errors.New("save member fail"),
 		},
@@ -57,7 +66,11 @@ func TestRoomService_Create(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			tt.mockSetup()
-			roomUUID, err := svc.CreateRoom(ctx, userUUID)
+			roomName := tt.roomName
+			if roomName == "" {
+				roomName = "  Команда "
+			}
+			roomUUID, err := svc.CreateRoom(ctx, userUUID, roomName, "Планы")
 
 			if tt.expectedError != nil {
 				assert.EqualError(t, err, tt.expectedError.Error())
@@ -70,7 +83,7 @@ func TestRoomService_Create(t *testing.T) {
 	}
 }
 
-func TestRoomService_AddUser(t *testing.T) {
+func TestRoomService_CreateDirectRoom(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -78,32 +91,193 @@ func TestRoomService_AddUser(t *testing.T) {
 	mockRR := NewMockRoomReader(ctrl)
 	mockRMW := NewMockRoomMemberWriter(ctrl)
 	mockRMR := NewMockRoomMemberReader(ctrl)
-	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR)
-	roomUUID := uuid.New()
+	mockUR := NewMockUserReader(ctrl)
+	mockKN := NewMockRoomNotifier(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, mockKN)
 	userUUID := uuid.New()
+	peerUUID := uuid.New()
+	roomUUID := uuid.New()
+	directKey := directRoomKey(userUUID, peerUUID)
 	ctx := context.Background()
 
+	assert.Equal(t, directKey, directRoomKey(peerUUID, userUUID))
+
+	// UUID, с которым сервис сохраняет новую личную комнату
+	var createdUUID uuid.UUID
+	saveNew := func(_ context.Context, roomUUID uuid.UUID, _ uuid.UUID, _ uuid.UUID) error {
+		createdUUID = roomUUID
+		return nil
+	}
+	getNew := func(context.Context, uuid.UUID) (*models.RoomDB, error) {
+		return &models.RoomDB{RoomUUID: createdUUID, CreatorUUID: userUUID, Kind: models.RoomKindDirect}, nil
+	}
+
 	tests := []struct {
 		name          string
-		mockRoom      *models.RoomDB
-		mockRoomErr   error
-		mockSaveErr   error
+		peerUUID      uuid.UUID
+		mockSetup     func()
+		created       bool
 		expectedError error
 	}{
-		{"success", &models.RoomDB{RoomUUID: roomUUID}, nil, nil, nil},
-		{"room not found", nil, nil, nil, ErrRoomNotFound},
-		{"room reader error", nil, errors.New("db error"), nil, errors.New("db error")},
-		{"save error", &models.RoomDB{RoomUUID: roomUUID}, nil, errors.New("save fail"), errors.New("save fail")},
+		{
+			name:     "new room",
+			peerUUID: peerUUID,
+			created:  true,
+			mockSetup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), peerUUID).Return(&models.UserDB{UserUUID: peerUUID}, nil)
+				mockRW.EXPECT().SaveDirect(gomock.Any(), gomock.Any(), userUUID, directKey).DoAndReturn(saveNew)
+				mockRR.EXPECT().GetDirect(gomock.Any(), directKey).DoAndReturn(getNew)
+				mockRMW.EXPECT().Save(gomock.Any(), gomock.Any(), userUUID, models.RoomRoleOwner, gomock.Any()).Return(nil)
+				mockRMW.EXPECT().Save(gomock.Any(), gomock.Any(), peerUUID, models.RoomRoleMember, gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:     "existing room",
+			peerUUID: peerUUID,
+			mockSetup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), peerUUID).Return(&models.UserDB{UserUUID: peerUUID}, nil)
+				mockRW.EXPECT().SaveDirect(gomock.Any(), gomock.Any(), userUUID, directKey).Return(nil)
+				mockRR.EXPECT().GetDirect(gomock.Any(), directKey).Return(&models.RoomDB{RoomUUID: roomUUID, CreatorUUID: peerUUID, Kind: models.RoomKindDirect}, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleMember}, nil)
+			},
+		},
+		{
+			name:     "peer who left is not re-added",
+			peerUUID: peerUUID,
+			mockSetup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), peerUUID).Return(&models.UserDB{UserUUID: peerUUID}, nil)
+				mockRW.EXPECT().SaveDirect(gomock.Any(), gomock.Any(), userUUID, directKey).Return(nil)
+				// оба собеседника покинули комнату: возвращается только вызывающий
+				mockRR.EXPECT().GetDirect(gomock.Any(), directKey).Return(&models.RoomDB{RoomUUID: roomUUID, CreatorUUID: userUUID, Kind: models.RoomKindDirect}, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+				mockRMW.EXPECT().SaveWithKeyRotation(gomock.Any(), roomUUID, userUUID, models.RoomRoleOwner, gomock.Any()).Return(int64(4), nil)
+				mockKN.EXPECT().NotifyKeyRotation(roomUUID, int64(4))
+			},
+		},
+		{
+			name:     "rejoin rotates key",
+			peerUUID: peerUUID,
+			mockSetup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), peerUUID).Return(&models.UserDB{UserUUID: peerUUID}, nil)
+				mockRW.EXPECT().SaveDirect(gomock.Any(), gomock.Any(), userUUID, directKey).Return(nil)
+				mockRR.EXPECT().GetDirect(gomock.Any(), directKey).Return(&models.RoomDB{RoomUUID: roomUUID, CreatorUUID: peerUUID, Kind: models.RoomKindDirect}, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+				// возвращение собеседника и новая эпоха фиксируются одной транзакцией
+				mockRMW.EXPECT().SaveWithKeyRotation(gomock.Any(), roomUUID, userUUID, models.RoomRoleMember, gomock.Any()).Return(int64(3), nil)
+				mockKN.EXPECT().NotifyKeyRotation(roomUUID, int64(3))
+			},
+		},
+		{
+			name:     "peer not found",
+			peerUUID: peerUUID,
+			mockSetup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), peerUUID).Return(nil, nil)
+			},
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name:          "with self",
+			peerUUID:      userUUID,
+			mockSetup:     func() {},
+			expectedError: ErrInvalidDirectRoom,
+		},
+		{
+			name:     "save error",
+			peerUUID: peerUUID,
+			mockSetup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), peerUUID).Return(&models.UserDB{UserUUID: peerUUID}, nil)
+				mockRW.EXPECT().SaveDirect(gomock.Any(), gomock.Any(), userUUID, directKey).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name:     "room not found",
+			peerUUID: peerUUID,
+			mockSetup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), peerUUID).Return(&models.UserDB{UserUUID: peerUUID}, nil)
+				mockRW.EXPECT().SaveDirect(gomock.Any(), gomock.Any(), userUUID, directKey).Return(nil)
+				mockRR.EXPECT().GetDirect(gomock.Any(), directKey).Return(nil, nil)
+			},
+			expectedError: ErrRoomNotFound,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(tt.mockRoom, tt.mockRoomErr)
-			if tt.mockRoom != nil {
-				mockRMW.EXPECT().Save(gomock.Any(), roomUUID, userUUID, gomock.Any()).Return(tt.mockSaveErr)
+			tt.mockSetup()
+
+			got, err := svc.CreateDirectRoom(ctx, userUUID, tt.peerUUID)
+			switch {
+			case tt.expectedError != nil:
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Equal(t, uuid.Nil, got)
+			case tt.created:
+				assert.NoError(t, err)
+				assert.Equal(t, createdUUID, got)
+			default:
+				assert.NoError(t, err)
+				assert.Equal(t, roomUUID, got)
 			}
+		})
+	}
+}
 
-			err := svc.AddRoomMember(ctx, roomUUID, userUUID)
+func TestRoomService_UpdateRoom(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRoomWriter(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMW := NewMockRoomMemberWriter(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockUR := NewMockUserReader(ctrl)
+	mockKN := NewMockRoomNotifier(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, mockKN)
+	roomUUID := uuid.New()
+	actorUUID := uuid.New()
+	ctx := context.Background()
+
+	group := &models.RoomDB{RoomUUID: roomUUID, Name: "Команда", Topic: "Планы", Kind: models.RoomKindGroup}
+	direct := &models.RoomDB{RoomUUID: roomUUID, Kind: models.RoomKindDirect}
+	name := func(s string) *string { return &s }
+
+	tests := []struct {
+		name          string
+		room          *models.RoomDB
+		actor         *models.RoomMemberDB
+		newName       *string
+		newTopic      *string
+		updated       bool
+		wantName      string
+		wantTopic     string
+		updateErr     error
+		expectedError error
+	}{
+		{"owner renames", group, &models.RoomMemberDB{Role: models.RoomRoleOwner}, name(" Релиз "), nil, true, "Релиз", "Планы", nil, nil},
+		{"admin changes topic", group, &models.RoomMemberDB{Role: models.RoomRoleAdmin}, nil, name("Сроки"), true, "Команда", "Сроки", nil, nil},
+		{"member in group", group, &models.RoomMemberDB{Role: models.RoomRoleMember}, name("Релиз"), nil, false, "", "", nil, ErrForbidden},
+		{"member in direct", direct, &models.RoomMemberDB{Role: models.RoomRoleMember}, nil, name("Отпуск"), true, "", "Отпуск", nil, nil},
+		{"unchanged", group, &models.RoomMemberDB{Role: models.RoomRoleOwner}, name("Команда"), nil, false, "", "", nil, nil},
+		{"topic too long", group, &models.RoomMemberDB{Role: models.RoomRoleOwner}, nil, name(strings.Repeat("я", MaxRoomTopicLength+1)), false, "", "", nil, ErrInvalidRoomMetadata},
+		{"not a member", group, nil, name("Релиз"), nil, false, "", "", nil, ErrForbidden},
+		{"room not found", nil, nil, name("Релиз"), nil, false, "", "", nil, ErrRoomNotFound},
+		{"update error", group, &models.RoomMemberDB{Role: models.RoomRoleOwner}, name("Релиз"), nil, true, "Релиз", "Планы", errors.New("db error"), errors.New("db error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(tt.room, nil)
+			if tt.room != nil {
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(tt.actor, nil)
+			}
+			if tt.updated {
+				mockRW.EXPECT().UpdateMetadata(gomock.Any(), roomUUID, tt.wantName, tt.wantTopic).Return(tt.updateErr)
+				if tt.updateErr == nil {
+					mockKN.EXPECT().NotifyRoomUpdated(roomUUID, tt.wantName, tt.wantTopic)
+				}
+			}
+
+			err := svc.UpdateRoom(ctx, actorUUID, roomUUID, tt.newName, tt.newTopic)
 			if tt.expectedError != nil {
 				assert.EqualError(t, err, tt.expectedError.Error())
 			} else {
@@ -113,6 +287,82 @@ func TestRoomService_AddUser(t *testing.T) {
 	}
 }
 
+func TestRoomService_JoinRoom(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRoomWriter(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMW := NewMockRoomMemberWriter(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockUR := NewMockUserReader(ctrl)
+	mockKN := NewMockRoomNotifier(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, mockKN)
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		joined        bool
+		expectedError error
+	}{
+		{
+			name:   "success",
+			joined: true,
+			mockSetup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(&models.RoomDB{RoomUUID: roomUUID, Kind: models.RoomKindGroup}, nil)
+				mockRMW.EXPECT().SaveWithKeyRotation(gomock.Any(), roomUUID, userUUID, models.RoomRoleMember, gomock.Any()).Return(int64(2), nil)
+				mockKN.EXPECT().NotifyKeyRotation(roomUUID, int64(2))
+			},
+		},
+		{
+			name: "already member",
+			mockSetup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(&models.RoomDB{RoomUUID: roomUUID, Kind: models.RoomKindGroup}, nil)
+				mockRMW.EXPECT().SaveWithKeyRotation(gomock.Any(), roomUUID, userUUID, models.RoomRoleMember, gomock.Any()).Return(int64(0), nil)
+			},
+		},
+		{
+			name: "room not found",
+			mockSetup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(nil, nil)
+			},
+			expectedError: ErrRoomNotFound,
+		},
+		{
+			name: "direct room",
+			mockSetup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(&models.RoomDB{RoomUUID: roomUUID, Kind: models.RoomKindDirect}, nil)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name: "save error",
+			mockSetup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(&models.RoomDB{RoomUUID: roomUUID, Kind: models.RoomKindGroup}, nil)
+				mockRMW.EXPECT().SaveWithKeyRotation(gomock.Any(), roomUUID, userUUID, models.RoomRoleMember, gomock.Any()).Return(int64(0), errors.New("save fail"))
+			},
+			expectedError: errors.New("save fail"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			joined, err := svc.JoinRoom(ctx, roomUUID, userUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.joined, joined)
+		})
+	}
+}
+
 func TestRoomService_RemoveUser(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -121,44 +371,248 @@ func TestRoomService_RemoveUser(t *testing.T) {
 	mockRR := NewMockRoomReader(ctrl)
 	mockRMW := NewMockRoomMemberWriter(ctrl)
 	mockRMR := NewMockRoomMemberReader(ctrl)
-	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR)
+	mockUR := NewMockUserReader(ctrl)
+	mockKN := NewMockRoomNotifier(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, mockKN)
 	roomUUID := uuid.New()
+	actorUUID := uuid.New()
 	userUUID := uuid.New()
 	member := &models.RoomMemberDB{
 		RoomUUID: roomUUID,
 		UserUUID: userUUID,
+		Role:     models.RoomRoleMember,
 		JoinedAt: time.Now(),
 	}
+	admin := &models.RoomMemberDB{RoomUUID: roomUUID, UserUUID: userUUID, Role: models.RoomRoleAdmin}
+	owner := &models.RoomMemberDB{Role: models.RoomRoleOwner}
 	ctx := context.Background()
 
 	tests := []struct {
 		name          string
 		mockRoom      *models.RoomDB
 		mockRoomErr   error
+		mockActor     *models.RoomMemberDB
 		mockMember    *models.RoomMemberDB
 		mockMemberErr error
+		expectDelete  bool
 		mockDelErr    error
 		expectedError error
 	}{
-		{"success", &models.RoomDB{RoomUUID: roomUUID}, nil, member, nil, nil, nil},
-		{"room not found", nil, nil, nil, nil, nil, ErrRoomNotFound},
-		{"room reader error", nil, errors.New("db error"), nil, nil, nil, errors.New("db error")},
-		{"member not in room", &models.RoomDB{RoomUUID: roomUUID}, nil, nil, nil, nil, ErrUserNotInRoom},
-		{"member get error", &models.RoomDB{RoomUUID: roomUUID}, nil, nil, errors.New("member read fail"), nil, errors.New("member read fail")},
-		{"delete error", &models.RoomDB{RoomUUID: roomUUID}, nil, member, nil, errors.New("delete fail"), errors.New("delete fail")},
+		{"owner removes member", &models.RoomDB{RoomUUID: roomUUID}, nil, owner, member, nil, true, nil, nil},
+		{"owner removes admin", &models.RoomDB{RoomUUID: roomUUID}, nil, owner, admin, nil, true, nil, nil},
+		{"admin removes member", &models.RoomDB{RoomUUID: roomUUID}, nil, &models.RoomMemberDB{Role: models.RoomRoleAdmin}, member, nil, true, nil, nil},
+		{"admin removes admin", &models.RoomDB{RoomUUID: roomUUID}, nil, &models.RoomMemberDB{Role: models.RoomRoleAdmin}, admin, nil, false, nil, ErrForbidden},
+		{"member removes member", &models.RoomDB{RoomUUID: roomUUID}, nil, &models.RoomMemberDB{Role: models.RoomRoleMember}, member, nil, false, nil, ErrForbidden},
+		{"room not found", nil, nil, nil, nil, nil, false, nil, ErrRoomNotFound},
+		{"room reader error", nil, errors.New("db error"), nil, nil, nil, false, nil, errors.New("db error")},
+		{"actor not in room", &models.RoomDB{RoomUUID: roomUUID}, nil, nil, nil, nil, false, nil, ErrForbidden},
+		{"member not in room", &models.RoomDB{RoomUUID: roomUUID}, nil, owner, nil, nil, false, nil, ErrUserNotInRoom},
+		{"member get error", &models.RoomDB{RoomUUID: roomUUID}, nil, owner, nil, errors.New("member read fail"), false, nil, errors.New("member read fail")},
+		{"delete error", &models.RoomDB{RoomUUID: roomUUID}, nil, owner, member, nil, true, errors.New("delete fail"), errors.New("delete fail")},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(tt.mockRoom, tt.mockRoomErr)
 			if tt.mockRoom != nil {
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(tt.mockActor, nil)
+			}
+			if tt.mockActor != nil {
 				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(tt.mockMember, tt.mockMemberErr)
 			}
+			if tt.expectDelete {
+				mockRMW.EXPECT().DeleteWithKeyRotation(gomock.Any(), roomUUID, userUUID).Return(int64(3), tt.mockDelErr)
+				if tt.mockDelErr == nil {
+					mockKN.EXPECT().Disconnect(roomUUID, userUUID, uuid.Nil, "removed from room")
+					mockKN.EXPECT().NotifyKeyRotation(roomUUID, int64(3))
+				}
+			}
+
+			err := svc.RemoveRoomMember(ctx, actorUUID, roomUUID, userUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRoomService_RemoveUserSelf(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRoomWriter(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMW := NewMockRoomMemberWriter(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockUR := NewMockUserReader(ctrl)
+	mockKN := NewMockRoomNotifier(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, mockKN)
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+
+	mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(&models.RoomDB{RoomUUID: roomUUID}, nil)
+	mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleMember}, nil)
+	mockRMW.EXPECT().DeleteWithKeyRotation(gomock.Any(), roomUUID, userUUID).Return(int64(2), nil)
+	mockKN.EXPECT().Disconnect(roomUUID, userUUID, uuid.Nil, "left the room")
+	mockKN.EXPECT().NotifyKeyRotation(roomUUID, int64(2))
+
+	err := svc.RemoveRoomMember(context.Background(), userUUID, roomUUID, userUUID)
+	assert.NoError(t, err)
+}
+
+func TestRoomService_LeaveRoom(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRoomWriter(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMW := NewMockRoomMemberWriter(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockUR := NewMockUserReader(ctrl)
+	mockKN := NewMockRoomNotifier(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, mockKN)
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockRoom      *models.RoomDB
+		mockMember    *models.RoomMemberDB
+		mockDelErr    error
+		expectedError error
+	}{
+		{"member leaves", &models.RoomDB{RoomUUID: roomUUID}, &models.RoomMemberDB{Role: models.RoomRoleMember}, nil, nil},
+		{"admin leaves", &models.RoomDB{RoomUUID: roomUUID}, &models.RoomMemberDB{Role: models.RoomRoleAdmin}, nil, nil},
+		{"owner cannot leave", &models.RoomDB{RoomUUID: roomUUID}, &models.RoomMemberDB{Role: models.RoomRoleOwner}, nil, ErrOwnerCannotLeave},
+		{"room not found", nil, nil, nil, ErrRoomNotFound},
+		{"not in room", &models.RoomDB{RoomUUID: roomUUID}, nil, nil, ErrUserNotInRoom},
+		{"delete error", &models.RoomDB{RoomUUID: roomUUID}, &models.RoomMemberDB{Role: models.RoomRoleMember}, errors.New("delete fail"), errors.New("delete fail")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(tt.mockRoom, nil)
+			if tt.mockRoom != nil {
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(tt.mockMember, nil)
+			}
+			if tt.mockMember != nil && tt.mockMember.Role != models.RoomRoleOwner {
+				mockRMW.EXPECT().DeleteWithKeyRotation(gomock.Any(), roomUUID, userUUID).Return(int64(2), tt.mockDelErr)
+				if tt.mockDelErr == nil {
+					mockKN.EXPECT().Disconnect(roomUUID, userUUID, uuid.Nil, "left the room")
+					mockKN.EXPECT().NotifyKeyRotation(roomUUID, int64(2))
+				}
+			}
+
+			err := svc.LeaveRoom(ctx, roomUUID, userUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRoomService_TransferOwnership(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRoomWriter(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMW := NewMockRoomMemberWriter(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockUR := NewMockUserReader(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, nil)
+	roomUUID := uuid.New()
+	ownerUUID := uuid.New()
+	memberUUID := uuid.New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockActor     *models.RoomMemberDB
+		mockMember    *models.RoomMemberDB
+		expectUpdate  bool
+		mockUpdated   bool
+		mockUpdateErr error
+		expectedError error
+	}{
+		{"success", &models.RoomMemberDB{Role: models.RoomRoleOwner}, &models.RoomMemberDB{Role: models.RoomRoleMember}, true, true, nil, nil},
+		{"actor not owner", &models.RoomMemberDB{Role: models.RoomRoleAdmin}, nil, false, false, nil, ErrForbidden},
+		{"actor not in room", nil, nil, false, false, nil, ErrForbidden},
+		{"new owner not in room", &models.RoomMemberDB{Role: models.RoomRoleOwner}, nil, false, false, nil, ErrUserNotInRoom},
+		{"members changed concurrently", &models.RoomMemberDB{Role: models.RoomRoleOwner}, &models.RoomMemberDB{Role: models.RoomRoleMember}, true, false, nil, ErrUserNotInRoom},
+		{"update error", &models.RoomMemberDB{Role: models.RoomRoleOwner}, &models.RoomMemberDB{Role: models.RoomRoleMember}, true, false, errors.New("update fail"), errors.New("update fail")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(&models.RoomDB{RoomUUID: roomUUID}, nil)
+			mockRMR.EXPECT().Get(gomock.Any(), roomUUID, ownerUUID).Return(tt.mockActor, nil)
+			if tt.mockActor != nil && tt.mockActor.Role == models.RoomRoleOwner {
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, memberUUID).Return(tt.mockMember, nil)
+			}
+			if tt.expectUpdate {
+				mockRMW.EXPECT().TransferOwnership(gomock.Any(), roomUUID, ownerUUID, memberUUID).Return(tt.mockUpdated, tt.mockUpdateErr)
+			}
+
+			err := svc.TransferOwnership(ctx, ownerUUID, roomUUID, memberUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRoomService_SetRoomMemberRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRoomWriter(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMW := NewMockRoomMemberWriter(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockUR := NewMockUserReader(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, nil)
+	roomUUID := uuid.New()
+	ownerUUID := uuid.New()
+	memberUUID := uuid.New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		role          string
+		mockActor     *models.RoomMemberDB
+		mockMember    *models.RoomMemberDB
+		expectedError error
+	}{
+		{"promote to admin", models.RoomRoleAdmin, &models.RoomMemberDB{Role: models.RoomRoleOwner}, &models.RoomMemberDB{Role: models.RoomRoleMember}, nil},
+		{"demote to member", models.RoomRoleMember, &models.RoomMemberDB{Role: models.RoomRoleOwner}, &models.RoomMemberDB{Role: models.RoomRoleAdmin}, nil},
+		{"owner role rejected", models.RoomRoleOwner, nil, nil, ErrInvalidRole},
+		{"unknown role rejected", "superuser", nil, nil, ErrInvalidRole},
+		{"actor not owner", models.RoomRoleAdmin, &models.RoomMemberDB{Role: models.RoomRoleAdmin}, nil, ErrForbidden},
+		{"member not in room", models.RoomRoleAdmin, &models.RoomMemberDB{Role: models.RoomRoleOwner}, nil, ErrUserNotInRoom},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.expectedError != ErrInvalidRole {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(&models.RoomDB{RoomUUID: roomUUID}, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, ownerUUID).Return(tt.mockActor, nil)
+			}
+			if tt.mockActor != nil && tt.mockActor.Role == models.RoomRoleOwner {
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, memberUUID).Return(tt.mockMember, nil)
+			}
 			if tt.mockMember != nil {
-				mockRMW.EXPECT().Delete(gomock.Any(), roomUUID, userUUID).Return(tt.mockDelErr)
+				mockRMW.EXPECT().UpdateRole(gomock.Any(), roomUUID, memberUUID, tt.role).Return(nil)
 			}
 
-			err := svc.RemoveRoomMember(ctx, roomUUID, userUUID)
+			err := svc.SetRoomMemberRole(ctx, ownerUUID, roomUUID, memberUUID, tt.role)
 			if tt.expectedError != nil {
 				assert.EqualError(t, err, tt.expectedError.Error())
 			} else {
@@ -176,42 +630,55 @@ func TestRoomService_Remove(t *testing.T) {
 	mockRR := NewMockRoomReader(ctrl)
 	mockRMW := NewMockRoomMemberWriter(ctrl)
 	mockRMR := NewMockRoomMemberReader(ctrl)
-	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR)
+	mockUR := NewMockUserReader(ctrl)
+	mockKN := NewMockRoomNotifier(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, mockKN)
 	roomUUID := uuid.New()
+	actorUUID := uuid.New()
+	owner := &models.RoomMemberDB{Role: models.RoomRoleOwner}
 	ctx := context.Background()
 
 	tests := []struct {
 		name          string
 		mockRoom      *models.RoomDB
 		mockRoomErr   error
+		mockActor     *models.RoomMemberDB
 		mockDelErr    error
 		expectedError error
 	}{
 		{
 			name:          "success",
 			mockRoom:      &models.RoomDB{RoomUUID: roomUUID},
-			mockRoomErr:   nil,
-			mockDelErr:    nil,
+			mockActor:     owner,
 			expectedError: nil,
 		},
 		{
 			name:          "room not found",
 			mockRoom:      nil,
-			mockRoomErr:   nil,
-			mockDelErr:    nil,
 			expectedError: ErrRoomNotFound,
 		},
 		{
 			name:          "room reader error",
 			mockRoom:      nil,
 			mockRoomErr:   errors.New("db error"),
-			mockDelErr:    nil,
 			expectedError: errors.New("db error"),
 		},
+		{
+			name:          "actor is admin",
+			mockRoom:      &models.RoomDB{RoomUUID: roomUUID},
+			mockActor:     &models.RoomMemberDB{Role: models.RoomRoleAdmin},
+			expectedError: ErrForbidden,
+		},
+		{
+			name:          "actor not in room",
+			mockRoom:      &models.RoomDB{RoomUUID: roomUUID},
+			mockActor:     nil,
+			expectedError: ErrForbidden,
+		},
 		{
 			name:          "delete error",
 			mockRoom:      &models.RoomDB{RoomUUID: roomUUID},
-			mockRoomErr:   nil,
+			mockActor:     owner,
 			mockDelErr:    errors.New("delete fail"),
 			expectedError: errors.New("delete fail"),
 		},
@@ -221,10 +688,121 @@ func TestRoomService_Remove(t *testing.T) {
 		t.Run(tt.name, func(t *testing.T) {
 			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(tt.mockRoom, tt.mockRoomErr)
 			if tt.mockRoom != nil {
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(tt.mockActor, nil)
+			}
+			if tt.mockActor == owner {
 				mockRW.EXPECT().Delete(gomock.Any(), roomUUID).Return(tt.mockDelErr)
+				if tt.mockDelErr == nil {
+					// соединения всех участников с удалённой комнатой закрываются
+					mockKN.EXPECT().Disconnect(roomUUID, uuid.Nil, uuid.Nil, "room removed")
+				}
+			}
+
+			err := svc.RemoveRoom(ctx, actorUUID, roomUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRoomService_CheckRoomMember(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRoomWriter(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMW := NewMockRoomMemberWriter(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockUR := NewMockUserReader(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, nil)
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	member := &models.RoomMemberDB{RoomUUID: roomUUID, UserUUID: userUUID}
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockRoom      *models.RoomDB
+		mockRoomErr   error
+		mockMember    *models.RoomMemberDB
+		mockMemberErr error
+		expectedError error
+	}{
+		{"success", &models.RoomDB{RoomUUID: roomUUID}, nil, member, nil, nil},
+		{"room not found", nil, nil, nil, nil, ErrRoomNotFound},
+		{"room reader error", nil, errors.New("db error"), nil, nil, errors.New("db error")},
+		{"member not in room", &models.RoomDB{RoomUUID: roomUUID}, nil, nil, nil, ErrUserNotInRoom},
+		{"member get error", &models.RoomDB{RoomUUID: roomUUID}, nil, nil, errors.New("member read fail"), errors.New("member read fail")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(tt.mockRoom, tt.mockRoomErr)
+			if tt.mockRoom != nil {
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(tt.mockMember, tt.mockMemberErr)
+			}
+
+			err := svc.CheckRoomMember(ctx, roomUUID, userUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
 			}
+		})
+	}
+}
 
-			err := svc.RemoveRoom(ctx, roomUUID)
+func TestRoomService_RotateUserRoomKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRoomWriter(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockKN := NewMockRoomNotifier(ctrl)
+	svc := NewChatService(mockRW, mockRR, NewMockRoomMemberWriter(ctrl), NewMockRoomMemberReader(ctrl), NewMockUserReader(ctrl), mockKN)
+	userUUID := uuid.New()
+	firstRoom, secondRoom := uuid.New(), uuid.New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name: "rotates every room of the user",
+			mockSetup: func() {
+				mockRR.EXPECT().ListByUser(ctx, userUUID).Return([]models.RoomSummaryDB{{RoomUUID: firstRoom}, {RoomUUID: secondRoom}}, nil)
+				mockRW.EXPECT().BumpKeyEpoch(ctx, firstRoom).Return(int64(2), nil)
+				mockKN.EXPECT().NotifyKeyRotation(firstRoom, int64(2))
+				mockRW.EXPECT().BumpKeyEpoch(ctx, secondRoom).Return(int64(5), nil)
+				mockKN.EXPECT().NotifyKeyRotation(secondRoom, int64(5))
+			},
+		},
+		{
+			name: "list error",
+			mockSetup: func() {
+				mockRR.EXPECT().ListByUser(ctx, userUUID).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name: "bump error",
+			mockSetup: func() {
+				mockRR.EXPECT().ListByUser(ctx, userUUID).Return([]models.RoomSummaryDB{{RoomUUID: firstRoom}, {RoomUUID: secondRoom}}, nil)
+				mockRW.EXPECT().BumpKeyEpoch(ctx, firstRoom).Return(int64(0), errors.New("bump error"))
+			},
+			expectedError: errors.New("bump error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			err := svc.RotateUserRoomKeys(ctx, userUUID)
 			if tt.expectedError != nil {
 				assert.EqualError(t, err, tt.expectedError.Error())
 			} else {
@@ -233,3 +811,117 @@ func TestRoomService_Remove(t *testing.T) {
 		})
 	}
 }
+
+func TestRoomService_RotateKeyWithoutNotifier(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRoomWriter(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMW := NewMockRoomMemberWriter(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockUR := NewMockUserReader(ctrl)
+	svc := NewChatService(mockRW, mockRR, mockRMW, mockRMR, mockUR, nil)
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+
+	mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(&models.RoomDB{RoomUUID: roomUUID}, nil).Times(2)
+	mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleMember}, nil).Times(2)
+
+	// Эпоха увеличивается и без подключённого уведомителя
+	mockRMW.EXPECT().DeleteWithKeyRotation(gomock.Any(), roomUUID, userUUID).Return(int64(2), nil)
+	assert.NoError(t, svc.LeaveRoom(context.Background(), roomUUID, userUUID))
+
+	// Ошибка смены эпохи возвращается вызывающему
+	mockRMW.EXPECT().DeleteWithKeyRotation(gomock.Any(), roomUUID, userUUID).Return(int64(0), errors.New("bump fail"))
+	assert.EqualError(t, svc.LeaveRoom(context.Background(), roomUUID, userUUID), "bump fail")
+}
+
+func TestRoomService_ListRooms(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRR := NewMockRoomReader(ctrl)
+	svc := NewChatService(NewMockRoomWriter(ctrl), mockRR, NewMockRoomMemberWriter(ctrl), NewMockRoomMemberReader(ctrl), nil, nil)
+	userUUID := uuid.New()
+	rooms := []models.RoomSummaryDB{{RoomUUID: uuid.New(), Role: models.RoomRoleOwner, MemberCount: 2}}
+
+	mockRR.EXPECT().ListByUser(gomock.Any(), userUUID).Return(rooms, nil)
+	result, err := svc.ListRooms(context.Background(), userUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, rooms, result)
+
+	mockRR.EXPECT().ListByUser(gomock.Any(), userUUID).Return(nil, errors.New("db error"))
+	_, err = svc.ListRooms(context.Background(), userUUID)
+	assert.EqualError(t, err, "db error")
+}
+
+func TestRoomService_ListRoomMembers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	svc := NewChatService(NewMockRoomWriter(ctrl), mockRR, NewMockRoomMemberWriter(ctrl), mockRMR, nil, nil)
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	room := &models.RoomDB{RoomUUID: roomUUID}
+	member := &models.RoomMemberDB{RoomUUID: roomUUID, UserUUID: userUUID}
+	members := []models.RoomMemberUserDB{{RoomMemberDB: *member, Username: "alice"}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		setup         func()
+		expected      []models.RoomMemberUserDB
+		expectedError error
+	}{
+		{
+			name: "success",
+			setup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockRMR.EXPECT().ListWithUsers(gomock.Any(), roomUUID).Return(members, nil)
+			},
+			expected: members,
+		},
+		{
+			name: "room not found",
+			setup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(nil, nil)
+			},
+			expectedError: ErrRoomNotFound,
+		},
+		{
+			name: "not a member",
+			setup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+			},
+			expectedError: ErrUserNotInRoom,
+		},
+		{
+			name: "list error",
+			setup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockRMR.EXPECT().ListWithUsers(gomock.Any(), roomUUID).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+
+			result, err := svc.ListRoomMembers(ctx, userUUID, roomUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
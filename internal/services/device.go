@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+var (
+	// ErrDeviceNotFound возвращается, если у пользователя нет такого активного устройства
+	ErrDeviceNotFound = errors.New("device not found")
+
+	// ErrInvalidDeviceName возвращается, если название устройства слишком длинное
+	ErrInvalidDeviceName = errors.New("invalid device name")
+
+	// ErrDeviceNotPending возвращается при подтверждении устройства, которое не ожидает подтверждения
+	ErrDeviceNotPending = errors.New("device is not pending approval")
+
+	// ErrInvalidApprovalSignature возвращается, если подпись подтверждения не соответствует устройству
+	ErrInvalidApprovalSignature = errors.New("invalid device approval signature")
+
+	// ErrSigningKeyAlreadySet возвращается, если у устройства уже есть ключ подписи
+	ErrSigningKeyAlreadySet = errors.New("device signing key already set")
+)
+
+// MaxDeviceNameLength — максимальная длина названия устройства в символах
+const MaxDeviceNameLength = 64
+
+// DeviceLister описывает интерфейс получения списка устройств пользователя.
+type DeviceLister interface {
+	// ListByUser возвращает неотозванные устройства пользователя.
+	ListByUser(ctx context.Context, userUUID uuid.UUID) ([]models.UserDeviceDB, error)
+}
+
+// DeviceUpdater описывает интерфейс изменения устройств пользователя.
+type DeviceUpdater interface {
+	// Rename задаёт название неотозванного устройства; возвращает false, если устройство не найдено.
+	Rename(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, name string) (bool, error)
+
+	// Revoke помечает неотозванное устройство отозванным; возвращает false, если устройство не найдено.
+	Revoke(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, revokedAt time.Time) (bool, error)
+
+	// Approve делает ожидающее устройство активным; возвращает false, если устройство не ожидает подтверждения.
+	Approve(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, approverUUID uuid.UUID, signature string, approvedAt time.Time) (bool, error)
+
+	// SetSigningKey сохраняет ключ подписи активного устройства без него; возвращает false, если ключ не сохранён.
+	SetSigningKey(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, signingKey string) (bool, error)
+}
+
+// RoomKeyDeleter описывает интерфейс удаления ключей комнат, зашифрованных для устройства.
+type RoomKeyDeleter interface {
+	// DeleteByDevice удаляет ключи всех комнат для устройства.
+	DeleteByDevice(ctx context.Context, deviceUUID uuid.UUID) error
+}
+
+// RoomKeyRotator описывает интерфейс начала новой эпохи ключа в комнатах пользователя.
+type RoomKeyRotator interface {
+	// RotateUserRoomKeys начинает новую эпоху ключа во всех комнатах пользователя userUUID.
+	RotateUserRoomKeys(ctx context.Context, userUUID uuid.UUID) error
+}
+
+// DeviceService реализует управление устройствами пользователя: просмотр, переименование,
+// подтверждение и отзыв. Отозванное устройство не может войти в аккаунт, его сессии и соединения
+// завершаются, ключи комнат, зашифрованные для него, удаляются, а в комнатах пользователя
+// начинается новая эпоха ключа.
+type DeviceService struct {
+	dg DeviceGetter         // репозиторий для чтения устройства
+	dl DeviceLister         // репозиторий для получения списка устройств
+	dw DeviceUpdater        // репозиторий для изменения устройств
+	kd RoomKeyDeleter       // репозиторий ключей комнат
+	sr DeviceSessionRevoker // отзыв сессий устройства
+	sd SessionDisconnector  // закрытие соединений WebSocket устройства
+	kr RoomKeyRotator       // ротация ключей комнат пользователя
+}
+
+// DeviceOpt — функциональная опция для настройки DeviceService.
+type DeviceOpt func(*DeviceService)
+
+// WithDeviceDisconnector задаёт закрытие соединений WebSocket отозванного устройства.
+// Используется первое непустое значение.
+func WithDeviceDisconnector(disconnectors ...SessionDisconnector) DeviceOpt {
+	return func(svc *DeviceService) {
+		for _, sd := range disconnectors {
+			if sd != nil {
+				svc.sd = sd
+				return
+			}
+		}
+	}
+}
+
+// WithRoomKeyRotator задаёт ротацию ключей комнат пользователя после отзыва устройства:
+// без неё отозванное устройство, сохранившее ключ текущей эпохи, может расшифровать новые сообщения.
+// Используется первое непустое значение.
+func WithRoomKeyRotator(rotators ...RoomKeyRotator) DeviceOpt {
+	return func(svc *DeviceService) {
+		for _, kr := range rotators {
+			if kr != nil {
+				svc.kr = kr
+				return
+			}
+		}
+	}
+}
+
+// NewDeviceService создаёт новый экземпляр DeviceService.
+func NewDeviceService(
+	dg DeviceGetter,
+	dl DeviceLister,
+	dw DeviceUpdater,
+	kd RoomKeyDeleter,
+	sr DeviceSessionRevoker,
+	opts ...DeviceOpt,
+) *DeviceService {
+	svc := &DeviceService{
+		dg: dg,
+		dl: dl,
+		dw: dw,
+		kd: kd,
+		sr: sr,
+	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
+}
+
+// ListDevices возвращает неотозванные устройства пользователя, включая ожидающие подтверждения.
+func (svc *DeviceService) ListDevices(ctx context.Context, userUUID uuid.UUID) ([]models.UserDeviceDB, error) {
+	return svc.dl.ListByUser(ctx, userUUID)
+}
+
+// RenameDevice задаёт название устройства пользователя; пустое название сбрасывает его.
+// Возвращает ErrInvalidDeviceName для слишком длинного названия
+// и ErrDeviceNotFound, если у пользователя нет такого неотозванного устройства.
+func (svc *DeviceService) RenameDevice(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, name string) error {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > MaxDeviceNameLength {
+		return ErrInvalidDeviceName
+	}
+
+	ok, err := svc.dw.Rename(ctx, userUUID, deviceUUID, name)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDeviceNotFound
+	}
+	return nil
+}
+
+// RevokeDevice отзывает устройство пользователя: запрещает вход с него, отзывает все его access-
+// и refresh-токены, закрывает его соединения WebSocket, удаляет зашифрованные для него ключи комнат
+// и начинает новую эпоху ключа во всех комнатах пользователя.
+// Повторный отзыв уже отозванного устройства завершает оставшиеся шаги и не считается ошибкой.
+// Возвращает ErrDeviceNotFound, если устройства нет или оно принадлежит другому пользователю.
+func (svc *DeviceService) RevokeDevice(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID) error {
+	device, err := svc.dg.Get(ctx, deviceUUID)
+	if err != nil {
+		return err
+	}
+	if device == nil || device.UserUUID != userUUID {
+		return ErrDeviceNotFound
+	}
+
+	if device.RevokedAt == nil {
+		if _, err := svc.dw.Revoke(ctx, userUUID, deviceUUID, time.Now().UTC()); err != nil {
+			return err
+		}
+	}
+
+	if err := svc.sr.RevokeSessions(ctx, userUUID, deviceUUID); err != nil {
+		return err
+	}
+
+	if err := svc.kd.DeleteByDevice(ctx, deviceUUID); err != nil {
+		return err
+	}
+
+	if svc.sd != nil {
+		svc.sd.Disconnect(uuid.Nil, userUUID, deviceUUID, "device revoked")
+	}
+
+	// Ключ текущей эпохи мог остаться на устройстве, поэтому новые сообщения шифруются ключом новой эпохи
+	if svc.kr != nil {
+		return svc.kr.RotateUserRoomKeys(ctx, userUUID)
+	}
+	return nil
+}
+
+// ApproveDevice подтверждает ожидающее устройство deviceUUID пользователя с доверенного устройства approverUUID.
+// signature — подпись ключом подписи подтверждающего устройства над UUID нового устройства и его ключами
+// (см. e2ee.SignDeviceApproval); сервер проверяет её и сохраняет вместе с устройством.
+// Возвращает ErrForbidden, если подтверждающее устройство не активно или у него нет ключа подписи,
+// ErrDeviceNotFound, ErrDeviceNotPending и ErrInvalidApprovalSignature для соответствующих ошибок.
+func (svc *DeviceService) ApproveDevice(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	approverUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	signature string,
+) error {
+	approver, err := svc.dg.Get(ctx, approverUUID)
+	if err != nil {
+		return err
+	}
+	if approver == nil || approver.UserUUID != userUUID || approver.RevokedAt != nil ||
+		approver.Status != models.DeviceStatusActive || approver.SigningKey == "" {
+		return ErrForbidden
+	}
+
+	device, err := svc.dg.Get(ctx, deviceUUID)
+	if err != nil {
+		return err
+	}
+	if device == nil || device.UserUUID != userUUID || device.RevokedAt != nil {
+		return ErrDeviceNotFound
+	}
+	if device.Status != models.DeviceStatusPending {
+		return ErrDeviceNotPending
+	}
+
+	if err := e2ee.VerifyDeviceApproval(approver.SigningKey, deviceUUID, device.PublicKey, device.SigningKey, signature); err != nil {
+		return ErrInvalidApprovalSignature
+	}
+
+	ok, err := svc.dw.Approve(ctx, userUUID, deviceUUID, approverUUID, signature, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrDeviceNotPending
+	}
+	return nil
+}
+
+// SetSigningKey регистрирует ключ подписи signingKey (Ed25519 в base64) активного устройства deviceUUID,
+// добавленного до появления ключей подписи. Без ключа подписи такое устройство не может подтверждать
+// новые устройства, а они всё равно ожидают подтверждения. signature — подпись ключом signingKey над
+// UUID устройства и его ключами (см. e2ee.SignDeviceApproval), подтверждающая владение ключом.
+// Возвращает ErrDeviceNotFound, ErrForbidden для неактивного устройства, ErrInvalidDeviceKey,
+// ErrInvalidApprovalSignature и ErrSigningKeyAlreadySet для соответствующих ошибок.
+func (svc *DeviceService) SetSigningKey(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	signingKey string,
+	signature string,
+) error {
+	if _, err := e2ee.ParseSigningKey(signingKey); err != nil {
+		return ErrInvalidDeviceKey
+	}
+
+	device, err := svc.dg.Get(ctx, deviceUUID)
+	if err != nil {
+		return err
+	}
+	if device == nil || device.UserUUID != userUUID || device.RevokedAt != nil {
+		return ErrDeviceNotFound
+	}
+	if device.Status != models.DeviceStatusActive {
+		return ErrForbidden
+	}
+	if device.SigningKey != "" {
+		return ErrSigningKeyAlreadySet
+	}
+
+	if err := e2ee.VerifyDeviceApproval(signingKey, deviceUUID, device.PublicKey, signingKey, signature); err != nil {
+		return ErrInvalidApprovalSignature
+	}
+
+	ok, err := svc.dw.SetSigningKey(ctx, userUUID, deviceUUID, signingKey)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSigningKeyAlreadySet
+	}
+	return nil
+}
@@ -0,0 +1,423 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeviceService_ListDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDL := NewMockDeviceLister(ctrl)
+	svc := NewDeviceService(NewMockDeviceGetter(ctrl), mockDL, NewMockDeviceUpdater(ctrl), NewMockRoomKeyDeleter(ctrl), NewMockDeviceSessionRevoker(ctrl))
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	devices := []models.UserDeviceDB{{DeviceUUID: uuid.New(), UserUUID: userUUID, Name: "Ноутбук"}}
+
+	mockDL.EXPECT().ListByUser(ctx, userUUID).Return(devices, nil)
+	got, err := svc.ListDevices(ctx, userUUID)
+	assert.NoError(t, err)
+	assert.Equal(t, devices, got)
+
+	mockDL.EXPECT().ListByUser(ctx, userUUID).Return(nil, errors.New("db error"))
+	_, err = svc.ListDevices(ctx, userUUID)
+	assert.Error(t, err)
+}
+
+func TestDeviceService_RenameDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDW := NewMockDeviceUpdater(ctrl)
+	svc := NewDeviceService(NewMockDeviceGetter(ctrl), NewMockDeviceLister(ctrl), mockDW, NewMockRoomKeyDeleter(ctrl), NewMockDeviceSessionRevoker(ctrl))
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	tests := []struct {
+		name          string
+		deviceName    string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name:       "success",
+			deviceName: "  Ноутбук  ",
+			mockSetup: func() {
+				mockDW.EXPECT().Rename(ctx, userUUID, deviceUUID, "Ноутбук").Return(true, nil)
+			},
+		},
+		{
+			name:          "name too long",
+			deviceName:    strings.Repeat("я", MaxDeviceNameLength+1),
+			mockSetup:     func() {},
+			expectedError: ErrInvalidDeviceName,
+		},
+		{
+			name:       "device not found",
+			deviceName: "Телефон",
+			mockSetup: func() {
+				mockDW.EXPECT().Rename(ctx, userUUID, deviceUUID, "Телефон").Return(false, nil)
+			},
+			expectedError: ErrDeviceNotFound,
+		},
+		{
+			name:       "writer error",
+			deviceName: "Телефон",
+			mockSetup: func() {
+				mockDW.EXPECT().Rename(ctx, userUUID, deviceUUID, "Телефон").Return(false, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			err := svc.RenameDevice(ctx, userUUID, deviceUUID, tt.deviceName)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeviceService_RevokeDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDG := NewMockDeviceGetter(ctrl)
+	mockDW := NewMockDeviceUpdater(ctrl)
+	mockKD := NewMockRoomKeyDeleter(ctrl)
+	mockSR := NewMockDeviceSessionRevoker(ctrl)
+	mockSD := NewMockSessionDisconnector(ctrl)
+	mockKR := NewMockRoomKeyRotator(ctrl)
+	svc := NewDeviceService(mockDG, NewMockDeviceLister(ctrl), mockDW, mockKD, mockSR,
+		WithDeviceDisconnector(mockSD),
+		WithRoomKeyRotator(mockKR),
+	)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	revokedAt := time.Now()
+	active := &models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: userUUID}
+	revoked := &models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: userUUID, RevokedAt: &revokedAt}
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name: "success",
+			mockSetup: func() {
+				gomock.InOrder(
+					mockDG.EXPECT().Get(ctx, deviceUUID).Return(active, nil),
+					mockDW.EXPECT().Revoke(ctx, userUUID, deviceUUID, gomock.Any()).Return(true, nil),
+					mockSR.EXPECT().RevokeSessions(ctx, userUUID, deviceUUID).Return(nil),
+					mockKD.EXPECT().DeleteByDevice(ctx, deviceUUID).Return(nil),
+					mockSD.EXPECT().Disconnect(uuid.Nil, userUUID, deviceUUID, "device revoked"),
+					mockKR.EXPECT().RotateUserRoomKeys(ctx, userUUID).Return(nil),
+				)
+			},
+		},
+		{
+			name: "already revoked finishes cleanup",
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(revoked, nil)
+				mockSR.EXPECT().RevokeSessions(ctx, userUUID, deviceUUID).Return(nil)
+				mockKD.EXPECT().DeleteByDevice(ctx, deviceUUID).Return(nil)
+				mockSD.EXPECT().Disconnect(uuid.Nil, userUUID, deviceUUID, "device revoked")
+				mockKR.EXPECT().RotateUserRoomKeys(ctx, userUUID).Return(nil)
+			},
+		},
+		{
+			name: "device not found",
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(nil, nil)
+			},
+			expectedError: ErrDeviceNotFound,
+		},
+		{
+			name: "device of another user",
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(&models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: uuid.New()}, nil)
+			},
+			expectedError: ErrDeviceNotFound,
+		},
+		{
+			name: "session revocation error",
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(active, nil)
+				mockDW.EXPECT().Revoke(ctx, userUUID, deviceUUID, gomock.Any()).Return(true, nil)
+				mockSR.EXPECT().RevokeSessions(ctx, userUUID, deviceUUID).Return(errors.New("revoke error"))
+			},
+			expectedError: errors.New("revoke error"),
+		},
+		{
+			name: "room key deletion error",
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(active, nil)
+				mockDW.EXPECT().Revoke(ctx, userUUID, deviceUUID, gomock.Any()).Return(true, nil)
+				mockSR.EXPECT().RevokeSessions(ctx, userUUID, deviceUUID).Return(nil)
+				mockKD.EXPECT().DeleteByDevice(ctx, deviceUUID).Return(errors.New("delete error"))
+			},
+			expectedError: errors.New("delete error"),
+		},
+		{
+			name: "room key rotation error",
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(active, nil)
+				mockDW.EXPECT().Revoke(ctx, userUUID, deviceUUID, gomock.Any()).Return(true, nil)
+				mockSR.EXPECT().RevokeSessions(ctx, userUUID, deviceUUID).Return(nil)
+				mockKD.EXPECT().DeleteByDevice(ctx, deviceUUID).Return(nil)
+				mockSD.EXPECT().Disconnect(uuid.Nil, userUUID, deviceUUID, "device revoked")
+				mockKR.EXPECT().RotateUserRoomKeys(ctx, userUUID).Return(errors.New("rotate error"))
+			},
+			expectedError: errors.New("rotate error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			err := svc.RevokeDevice(ctx, userUUID, deviceUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeviceService_ApproveDevice(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDG := NewMockDeviceGetter(ctrl)
+	mockDW := NewMockDeviceUpdater(ctrl)
+	svc := NewDeviceService(mockDG, NewMockDeviceLister(ctrl), mockDW, NewMockRoomKeyDeleter(ctrl), NewMockDeviceSessionRevoker(ctrl))
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	approverUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	newSigningKey := func() (ed25519.PrivateKey, string) {
+		priv, err := e2ee.GenerateDeviceKey()
+		assert.NoError(t, err)
+		signing, err := e2ee.DeriveSigningKey(priv)
+		assert.NoError(t, err)
+		return signing, e2ee.EncodeSigningKey(signing.Public().(ed25519.PublicKey))
+	}
+	approverPriv, approverKey := newSigningKey()
+	_, deviceKey := newSigningKey()
+
+	approver := &models.UserDeviceDB{DeviceUUID: approverUUID, UserUUID: userUUID, Status: models.DeviceStatusActive, SigningKey: approverKey}
+	pending := &models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: userUUID, Status: models.DeviceStatusPending, PublicKey: "pubkey", SigningKey: deviceKey}
+	signature := e2ee.SignDeviceApproval(approverPriv, deviceUUID, "pubkey", deviceKey)
+
+	tests := []struct {
+		name          string
+		signature     string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name:      "success",
+			signature: signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, approverUUID).Return(approver, nil)
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(pending, nil)
+				mockDW.EXPECT().Approve(ctx, userUUID, deviceUUID, approverUUID, signature, gomock.Any()).Return(true, nil)
+			},
+		},
+		{
+			name:      "approver without signing key",
+			signature: signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, approverUUID).Return(&models.UserDeviceDB{DeviceUUID: approverUUID, UserUUID: userUUID, Status: models.DeviceStatusActive}, nil)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name:      "approver is pending",
+			signature: signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, approverUUID).Return(&models.UserDeviceDB{DeviceUUID: approverUUID, UserUUID: userUUID, Status: models.DeviceStatusPending, SigningKey: approverKey}, nil)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name:      "device of another user",
+			signature: signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, approverUUID).Return(approver, nil)
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(&models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: uuid.New(), Status: models.DeviceStatusPending}, nil)
+			},
+			expectedError: ErrDeviceNotFound,
+		},
+		{
+			name:      "device already active",
+			signature: signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, approverUUID).Return(approver, nil)
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(&models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: userUUID, Status: models.DeviceStatusActive}, nil)
+			},
+			expectedError: ErrDeviceNotPending,
+		},
+		{
+			name:      "invalid signature",
+			signature: e2ee.SignDeviceApproval(approverPriv, deviceUUID, "other-pubkey", deviceKey),
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, approverUUID).Return(approver, nil)
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(pending, nil)
+			},
+			expectedError: ErrInvalidApprovalSignature,
+		},
+		{
+			name:      "approved concurrently",
+			signature: signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, approverUUID).Return(approver, nil)
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(pending, nil)
+				mockDW.EXPECT().Approve(ctx, userUUID, deviceUUID, approverUUID, signature, gomock.Any()).Return(false, nil)
+			},
+			expectedError: ErrDeviceNotPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			err := svc.ApproveDevice(ctx, userUUID, approverUUID, deviceUUID, tt.signature)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDeviceService_SetSigningKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockDG := NewMockDeviceGetter(ctrl)
+	mockDW := NewMockDeviceUpdater(ctrl)
+	svc := NewDeviceService(mockDG, NewMockDeviceLister(ctrl), mockDW, NewMockRoomKeyDeleter(ctrl), NewMockDeviceSessionRevoker(ctrl))
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	priv, err := e2ee.GenerateDeviceKey()
+	assert.NoError(t, err)
+	signing, err := e2ee.DeriveSigningKey(priv)
+	assert.NoError(t, err)
+	signingKey := e2ee.EncodeSigningKey(signing.Public().(ed25519.PublicKey))
+	signature := e2ee.SignDeviceApproval(signing, deviceUUID, "pubkey", signingKey)
+
+	legacy := &models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: userUUID, Status: models.DeviceStatusActive, PublicKey: "pubkey"}
+
+	tests := []struct {
+		name          string
+		signingKey    string
+		signature     string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name:       "success",
+			signingKey: signingKey,
+			signature:  signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(legacy, nil)
+				mockDW.EXPECT().SetSigningKey(ctx, userUUID, deviceUUID, signingKey).Return(true, nil)
+			},
+		},
+		{
+			name:          "invalid signing key",
+			signingKey:    "not-a-key",
+			signature:     signature,
+			mockSetup:     func() {},
+			expectedError: ErrInvalidDeviceKey,
+		},
+		{
+			name:       "device of another user",
+			signingKey: signingKey,
+			signature:  signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(&models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: uuid.New(), Status: models.DeviceStatusActive}, nil)
+			},
+			expectedError: ErrDeviceNotFound,
+		},
+		{
+			name:       "device is pending",
+			signingKey: signingKey,
+			signature:  signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(&models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: userUUID, Status: models.DeviceStatusPending, PublicKey: "pubkey"}, nil)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name:       "signing key already set",
+			signingKey: signingKey,
+			signature:  signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(&models.UserDeviceDB{DeviceUUID: deviceUUID, UserUUID: userUUID, Status: models.DeviceStatusActive, PublicKey: "pubkey", SigningKey: "old-key"}, nil)
+			},
+			expectedError: ErrSigningKeyAlreadySet,
+		},
+		{
+			name:       "invalid signature",
+			signingKey: signingKey,
+			signature:  e2ee.SignDeviceApproval(signing, deviceUUID, "other-pubkey", signingKey),
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(legacy, nil)
+			},
+			expectedError: ErrInvalidApprovalSignature,
+		},
+		{
+			name:       "set concurrently",
+			signingKey: signingKey,
+			signature:  signature,
+			mockSetup: func() {
+				mockDG.EXPECT().Get(ctx, deviceUUID).Return(legacy, nil)
+				mockDW.EXPECT().SetSigningKey(ctx, userUUID, deviceUUID, signingKey).Return(false, nil)
+			},
+			expectedError: ErrSigningKeyAlreadySet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			err := svc.SetSigningKey(ctx, userUUID, deviceUUID, tt.signingKey, tt.signature)
+			if tt.expectedError != nil {
+				assert.ErrorIs(t, err, tt.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+var (
+	// ErrInviteNotFound возвращается, если приглашения нет, оно адресовано другому пользователю или на него уже ответили
+	ErrInviteNotFound = errors.New("invite not found")
+
+	// ErrAlreadyMember возвращается при приглашении пользователя, который уже состоит в комнате
+	ErrAlreadyMember = errors.New("user is already a room member")
+
+	// ErrInvalidInviteLink возвращается при некорректном сроке действия или числе входов ссылки-приглашения
+	ErrInvalidInviteLink = errors.New("invalid invite link parameters")
+
+	// ErrInviteLinkNotFound возвращается, если ссылки-приглашения нет
+	ErrInviteLinkNotFound = errors.New("invite link not found")
+
+	// ErrInviteLinkExpired возвращается, если срок действия ссылки истёк или входы по ней закончились
+	ErrInviteLinkExpired = errors.New("invite link expired or used up")
+)
+
+const (
+	// DefaultInviteLinkTTL — срок действия ссылки-приглашения по умолчанию
+	DefaultInviteLinkTTL = 24 * time.Hour
+	// MaxInviteLinkTTL — максимальный срок действия ссылки-приглашения
+	MaxInviteLinkTTL = 30 * 24 * time.Hour
+)
+
+// RoomInviteWriter описывает интерфейс для записи приглашений в комнаты.
+type RoomInviteWriter interface {
+	// Save сохраняет ожидающее приглашение; если такое уже есть, ничего не меняет.
+	Save(ctx context.Context, inviteUUID uuid.UUID, roomUUID uuid.UUID, inviterUUID uuid.UUID, inviteeUUID uuid.UUID) error
+
+	// Resolve переводит ожидающее приглашение в статус status; возвращает false, если на приглашение уже ответили.
+	Resolve(ctx context.Context, inviteUUID uuid.UUID, status string) (bool, error)
+
+	// Reopen возвращает принятое приглашение в статус ожидания.
+	Reopen(ctx context.Context, inviteUUID uuid.UUID) error
+}
+
+// RoomInviteReader описывает интерфейс для чтения приглашений в комнаты.
+type RoomInviteReader interface {
+	// Get возвращает приглашение по UUID или nil, если приглашение не найдено.
+	Get(ctx context.Context, inviteUUID uuid.UUID) (*models.RoomInviteDB, error)
+
+	// GetPending возвращает ожидающее приглашение пользователя в комнату или nil, если его нет.
+	GetPending(ctx context.Context, roomUUID uuid.UUID, inviteeUUID uuid.UUID) (*models.RoomInviteDB, error)
+
+	// ListPending возвращает ожидающие приглашения пользователя с названиями комнат и именами пригласивших.
+	ListPending(ctx context.Context, inviteeUUID uuid.UUID) ([]models.RoomInviteDetailsDB, error)
+}
+
+// RoomInviteLinkWriter описывает интерфейс для записи ссылок-приглашений.
+type RoomInviteLinkWriter interface {
+	// Save сохраняет ссылку-приглашение со сроком действия и ограничением числа входов (0 — без ограничения).
+	Save(ctx context.Context, linkUUID uuid.UUID, roomUUID uuid.UUID, creatorUUID uuid.UUID, expiresAt time.Time, maxUses int) error
+
+	// Use учитывает вход по ссылке; возвращает false, если ссылка недействительна в момент now.
+	Use(ctx context.Context, linkUUID uuid.UUID, now time.Time) (bool, error)
+
+	// Release возвращает вход по ссылке, учтённый Use.
+	Release(ctx context.Context, linkUUID uuid.UUID) error
+
+	// Delete удаляет ссылку-приглашение.
+	Delete(ctx context.Context, linkUUID uuid.UUID) error
+}
+
+// RoomInviteLinkReader описывает интерфейс для чтения ссылок-приглашений.
+type RoomInviteLinkReader interface {
+	// Get возвращает ссылку-приглашение по UUID или nil, если ссылка не найдена.
+	Get(ctx context.Context, linkUUID uuid.UUID) (*models.RoomInviteLinkDB, error)
+}
+
+// RoomJoiner описывает интерфейс добавления пользователя в комнату.
+type RoomJoiner interface {
+	// JoinRoom добавляет пользователя в групповую комнату как обычного участника;
+	// возвращает false, если пользователь уже состоит в комнате.
+	JoinRoom(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) (bool, error)
+}
+
+// InviteService реализует приглашения в групповые комнаты: персональные приглашения,
+// которые приглашённый принимает или отклоняет, и ссылки-приглашения со сроком действия.
+// Пользователь становится участником комнаты только после принятия приглашения или входа по ссылке.
+type InviteService struct {
+	rr  RoomReader           // репозиторий для чтения комнат
+	rmr RoomMemberReader     // репозиторий для чтения участников
+	ur  UserReader           // репозиторий для проверки существования пользователей
+	iw  RoomInviteWriter     // репозиторий для записи приглашений
+	ir  RoomInviteReader     // репозиторий для чтения приглашений
+	lw  RoomInviteLinkWriter // репозиторий для записи ссылок-приглашений
+	lr  RoomInviteLinkReader // репозиторий для чтения ссылок-приглашений
+	rj  RoomJoiner           // добавление пользователя в комнату
+}
+
+// NewInviteService создаёт новый экземпляр InviteService.
+func NewInviteService(
+	rr RoomReader,
+	rmr RoomMemberReader,
+	ur UserReader,
+	iw RoomInviteWriter,
+	ir RoomInviteReader,
+	lw RoomInviteLinkWriter,
+	lr RoomInviteLinkReader,
+	rj RoomJoiner,
+) *InviteService {
+	return &InviteService{
+		rr:  rr,
+		rmr: rmr,
+		ur:  ur,
+		iw:  iw,
+		ir:  ir,
+		lw:  lw,
+		lr:  lr,
+		rj:  rj,
+	}
+}
+
+// Invite приглашает пользователя inviteeUUID в групповую комнату от имени actorUUID.
+// Приглашать могут владелец и администраторы комнаты. Повторное приглашение, пока на прежнее не ответили,
+// возвращает прежнее приглашение. Если пользователя нет, возвращает ErrUserNotFound,
+// если он уже состоит в комнате — ErrAlreadyMember.
+func (svc *InviteService) Invite(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, inviteeUUID uuid.UUID) (*models.RoomInviteDB, error) {
+	if err := svc.checkInviter(ctx, actorUUID, roomUUID); err != nil {
+		return nil, err
+	}
+
+	user, err := svc.ur.GetByUUID(ctx, inviteeUUID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	member, err := svc.rmr.Get(ctx, roomUUID, inviteeUUID)
+	if err != nil {
+		return nil, err
+	}
+	if member != nil {
+		return nil, ErrAlreadyMember
+	}
+
+	if err := svc.iw.Save(ctx, uuid.New(), roomUUID, actorUUID, inviteeUUID); err != nil {
+		return nil, err
+	}
+
+	invite, err := svc.ir.GetPending(ctx, roomUUID, inviteeUUID)
+	if err != nil {
+		return nil, err
+	}
+	if invite == nil {
+		return nil, ErrInviteNotFound
+	}
+
+	return invite, nil
+}
+
+// ListInvites возвращает ожидающие ответа приглашения пользователя.
+func (svc *InviteService) ListInvites(ctx context.Context, userUUID uuid.UUID) ([]models.RoomInviteDetailsDB, error) {
+	return svc.ir.ListPending(ctx, userUUID)
+}
+
+// AcceptInvite принимает приглашение пользователя и добавляет его в комнату.
+// Возвращает UUID комнаты; если приглашения нет или на него уже ответили, возвращает ErrInviteNotFound.
+func (svc *InviteService) AcceptInvite(ctx context.Context, userUUID uuid.UUID, inviteUUID uuid.UUID) (uuid.UUID, error) {
+	invite, err := svc.getPendingInvite(ctx, userUUID, inviteUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	// Пользователь входит в комнату, только если именно этот запрос ответил на приглашение;
+	// если вход не удался, приглашение возвращается в ожидание и его можно принять повторно
+	if err := svc.resolve(ctx, inviteUUID, models.RoomInviteStatusAccepted); err != nil {
+		return uuid.Nil, err
+	}
+
+	if _, err := svc.rj.JoinRoom(ctx, invite.RoomUUID, userUUID); err != nil {
+		return uuid.Nil, errors.Join(err, svc.iw.Reopen(ctx, inviteUUID))
+	}
+
+	return invite.RoomUUID, nil
+}
+
+// DeclineInvite отклоняет приглашение пользователя.
+// Если приглашения нет или на него уже ответили, возвращает ErrInviteNotFound.
+func (svc *InviteService) DeclineInvite(ctx context.Context, userUUID uuid.UUID, inviteUUID uuid.UUID) error {
+	if _, err := svc.getPendingInvite(ctx, userUUID, inviteUUID); err != nil {
+		return err
+	}
+
+	return svc.resolve(ctx, inviteUUID, models.RoomInviteStatusDeclined)
+}
+
+// CreateInviteLink создаёт ссылку-приглашение в групповую комнату от имени actorUUID.
+// Создавать ссылки могут владелец и администраторы комнаты. Нулевой ttl означает срок по умолчанию,
+// нулевой maxUses — вход без ограничения числа пользователей до истечения срока.
+func (svc *InviteService) CreateInviteLink(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID, ttl time.Duration, maxUses int) (*models.RoomInviteLinkDB, error) {
+	if ttl == 0 {
+		ttl = DefaultInviteLinkTTL
+	}
+	if ttl < 0 || ttl > MaxInviteLinkTTL || maxUses < 0 {
+		return nil, ErrInvalidInviteLink
+	}
+
+	if err := svc.checkInviter(ctx, actorUUID, roomUUID); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	link := &models.RoomInviteLinkDB{
+		LinkUUID:    uuid.New(),
+		RoomUUID:    roomUUID,
+		CreatorUUID: actorUUID,
+		ExpiresAt:   now.Add(ttl),
+		MaxUses:     maxUses,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+	if err := svc.lw.Save(ctx, link.LinkUUID, link.RoomUUID, link.CreatorUUID, link.ExpiresAt, link.MaxUses); err != nil {
+		return nil, err
+	}
+
+	return link, nil
+}
+
+// JoinByLink добавляет пользователя в комнату по ссылке-приглашению и возвращает UUID комнаты.
+// Участнику комнаты возвращается UUID комнаты без учёта входа по ссылке: если пользователь стал участником
+// одновременным запросом уже после проверки, израсходованный вход возвращается.
+// Если ссылки нет, возвращает ErrInviteLinkNotFound, если срок истёк или входы закончились — ErrInviteLinkExpired.
+func (svc *InviteService) JoinByLink(ctx context.Context, userUUID uuid.UUID, linkUUID uuid.UUID) (uuid.UUID, error) {
+	link, err := svc.lr.Get(ctx, linkUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if link == nil {
+		return uuid.Nil, ErrInviteLinkNotFound
+	}
+
+	member, err := svc.rmr.Get(ctx, link.RoomUUID, userUUID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if member != nil {
+		return link.RoomUUID, nil
+	}
+
+	// Вход по ссылке расходуется атомарно до добавления в комнату, поэтому лимит входов
+	// соблюдается при одновременных запросах; если вход не удался, он возвращается
+	ok, err := svc.lw.Use(ctx, linkUUID, time.Now().UTC())
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !ok {
+		return uuid.Nil, ErrInviteLinkExpired
+	}
+
+	joined, err := svc.rj.JoinRoom(ctx, link.RoomUUID, userUUID)
+	if err != nil {
+		return uuid.Nil, errors.Join(err, svc.lw.Release(ctx, linkUUID))
+	}
+	if !joined {
+		if err := svc.lw.Release(ctx, linkUUID); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	return link.RoomUUID, nil
+}
+
+// RevokeInviteLink удаляет ссылку-приглашение linkUUID от имени actorUUID; входить по ней больше нельзя.
+// Отзывать ссылки могут владелец и администраторы комнаты. Если ссылки нет, возвращает ErrInviteLinkNotFound.
+func (svc *InviteService) RevokeInviteLink(ctx context.Context, actorUUID uuid.UUID, linkUUID uuid.UUID) error {
+	link, err := svc.lr.Get(ctx, linkUUID)
+	if err != nil {
+		return err
+	}
+	if link == nil {
+		return ErrInviteLinkNotFound
+	}
+
+	if err := svc.checkInviter(ctx, actorUUID, link.RoomUUID); err != nil {
+		return err
+	}
+
+	return svc.lw.Delete(ctx, linkUUID)
+}
+
+// checkInviter проверяет, что комната групповая, а actorUUID — её владелец или администратор.
+func (svc *InviteService) checkInviter(ctx context.Context, actorUUID uuid.UUID, roomUUID uuid.UUID) error {
+	room, err := svc.rr.Get(ctx, roomUUID)
+	if err != nil {
+		return err
+	}
+	if room == nil {
+		return ErrRoomNotFound
+	}
+
+	actor, err := svc.rmr.Get(ctx, roomUUID, actorUUID)
+	if err != nil {
+		return err
+	}
+	if actor == nil || room.Kind == models.RoomKindDirect || (actor.Role != models.RoomRoleOwner && actor.Role != models.RoomRoleAdmin) {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// getPendingInvite возвращает ожидающее приглашение, адресованное пользователю userUUID.
+func (svc *InviteService) getPendingInvite(ctx context.Context, userUUID uuid.UUID, inviteUUID uuid.UUID) (*models.RoomInviteDB, error) {
+	invite, err := svc.ir.Get(ctx, inviteUUID)
+	if err != nil {
+		return nil, err
+	}
+	if invite == nil || invite.InviteeUUID != userUUID || invite.Status != models.RoomInviteStatusPending {
+		return nil, ErrInviteNotFound
+	}
+	return invite, nil
+}
+
+// resolve фиксирует ответ на приглашение; если на приглашение уже ответили, возвращает ErrInviteNotFound.
+func (svc *InviteService) resolve(ctx context.Context, inviteUUID uuid.UUID, status string) error {
+	ok, err := svc.iw.Resolve(ctx, inviteUUID, status)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInviteNotFound
+	}
+	return nil
+}
@@ -0,0 +1,556 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type inviteMocks struct {
+	rr  *MockRoomReader
+	rmr *MockRoomMemberReader
+	ur  *MockUserReader
+	iw  *MockRoomInviteWriter
+	ir  *MockRoomInviteReader
+	lw  *MockRoomInviteLinkWriter
+	lr  *MockRoomInviteLinkReader
+	rj  *MockRoomJoiner
+}
+
+func newTestInviteService(ctrl *gomock.Controller) (*InviteService, inviteMocks) {
+	m := inviteMocks{
+		rr:  NewMockRoomReader(ctrl),
+		rmr: NewMockRoomMemberReader(ctrl),
+		ur:  NewMockUserReader(ctrl),
+		iw:  NewMockRoomInviteWriter(ctrl),
+		ir:  NewMockRoomInviteReader(ctrl),
+		lw:  NewMockRoomInviteLinkWriter(ctrl),
+		lr:  NewMockRoomInviteLinkReader(ctrl),
+		rj:  NewMockRoomJoiner(ctrl),
+	}
+	return NewInviteService(m.rr, m.rmr, m.ur, m.iw, m.ir, m.lw, m.lr, m.rj), m
+}
+
+func TestInviteService_Invite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, m := newTestInviteService(ctrl)
+	roomUUID := uuid.New()
+	actorUUID := uuid.New()
+	inviteeUUID := uuid.New()
+	group := &models.RoomDB{RoomUUID: roomUUID, Kind: models.RoomKindGroup}
+	invite := &models.RoomInviteDB{InviteUUID: uuid.New(), RoomUUID: roomUUID, InviterUUID: actorUUID, InviteeUUID: inviteeUUID, Status: models.RoomInviteStatusPending}
+	ctx := context.Background()
+
+	allowed := func(role string) func() {
+		return func() {
+			m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+			m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(&models.RoomMemberDB{Role: role}, nil)
+		}
+	}
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name: "success",
+			mockSetup: func() {
+				allowed(models.RoomRoleAdmin)()
+				m.ur.EXPECT().GetByUUID(gomock.Any(), inviteeUUID).Return(&models.UserDB{UserUUID: inviteeUUID}, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, inviteeUUID).Return(nil, nil)
+				m.iw.EXPECT().Save(gomock.Any(), gomock.Any(), roomUUID, actorUUID, inviteeUUID).Return(nil)
+				m.ir.EXPECT().GetPending(gomock.Any(), roomUUID, inviteeUUID).Return(invite, nil)
+			},
+		},
+		{
+			name: "room not found",
+			mockSetup: func() {
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(nil, nil)
+			},
+			expectedError: ErrRoomNotFound,
+		},
+		{
+			name: "actor not in room",
+			mockSetup: func() {
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(nil, nil)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name:          "actor is member",
+			mockSetup:     allowed(models.RoomRoleMember),
+			expectedError: ErrForbidden,
+		},
+		{
+			name: "direct room",
+			mockSetup: func() {
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(&models.RoomDB{RoomUUID: roomUUID, Kind: models.RoomKindDirect}, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleOwner}, nil)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name: "user not found",
+			mockSetup: func() {
+				allowed(models.RoomRoleOwner)()
+				m.ur.EXPECT().GetByUUID(gomock.Any(), inviteeUUID).Return(nil, nil)
+			},
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name: "already member",
+			mockSetup: func() {
+				allowed(models.RoomRoleOwner)()
+				m.ur.EXPECT().GetByUUID(gomock.Any(), inviteeUUID).Return(&models.UserDB{UserUUID: inviteeUUID}, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, inviteeUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleMember}, nil)
+			},
+			expectedError: ErrAlreadyMember,
+		},
+		{
+			name: "save error",
+			mockSetup: func() {
+				allowed(models.RoomRoleOwner)()
+				m.ur.EXPECT().GetByUUID(gomock.Any(), inviteeUUID).Return(&models.UserDB{UserUUID: inviteeUUID}, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, inviteeUUID).Return(nil, nil)
+				m.iw.EXPECT().Save(gomock.Any(), gomock.Any(), roomUUID, actorUUID, inviteeUUID).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := svc.Invite(ctx, actorUUID, roomUUID, inviteeUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, invite, got)
+			}
+		})
+	}
+}
+
+func TestInviteService_ListInvites(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, m := newTestInviteService(ctrl)
+	userUUID := uuid.New()
+	invites := []models.RoomInviteDetailsDB{{RoomInviteDB: models.RoomInviteDB{InviteUUID: uuid.New()}, RoomName: "Команда", InviterUsername: "alice"}}
+
+	m.ir.EXPECT().ListPending(gomock.Any(), userUUID).Return(invites, nil)
+
+	got, err := svc.ListInvites(context.Background(), userUUID)
+	require.NoError(t, err)
+	assert.Equal(t, invites, got)
+}
+
+func TestInviteService_AcceptInvite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, m := newTestInviteService(ctrl)
+	userUUID := uuid.New()
+	roomUUID := uuid.New()
+	inviteUUID := uuid.New()
+	pending := &models.RoomInviteDB{InviteUUID: inviteUUID, RoomUUID: roomUUID, InviteeUUID: userUUID, Status: models.RoomInviteStatusPending}
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name: "success",
+			mockSetup: func() {
+				m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(pending, nil)
+				gomock.InOrder(
+					m.iw.EXPECT().Resolve(gomock.Any(), inviteUUID, models.RoomInviteStatusAccepted).Return(true, nil),
+					m.rj.EXPECT().JoinRoom(gomock.Any(), roomUUID, userUUID).Return(true, nil),
+				)
+			},
+		},
+		{
+			name: "not found",
+			mockSetup: func() {
+				m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(nil, nil)
+			},
+			expectedError: ErrInviteNotFound,
+		},
+		{
+			name: "addressed to another user",
+			mockSetup: func() {
+				m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(&models.RoomInviteDB{InviteUUID: inviteUUID, InviteeUUID: uuid.New(), Status: models.RoomInviteStatusPending}, nil)
+			},
+			expectedError: ErrInviteNotFound,
+		},
+		{
+			name: "already declined",
+			mockSetup: func() {
+				m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(&models.RoomInviteDB{InviteUUID: inviteUUID, InviteeUUID: userUUID, Status: models.RoomInviteStatusDeclined}, nil)
+			},
+			expectedError: ErrInviteNotFound,
+		},
+		{
+			// на приглашение одновременно ответили: пользователь в комнату не входит
+			name: "resolved concurrently",
+			mockSetup: func() {
+				m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(pending, nil)
+				m.iw.EXPECT().Resolve(gomock.Any(), inviteUUID, models.RoomInviteStatusAccepted).Return(false, nil)
+			},
+			expectedError: ErrInviteNotFound,
+		},
+		{
+			// приглашение возвращается в ожидание
+			name: "join error",
+			mockSetup: func() {
+				m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(pending, nil)
+				gomock.InOrder(
+					m.iw.EXPECT().Resolve(gomock.Any(), inviteUUID, models.RoomInviteStatusAccepted).Return(true, nil),
+					m.rj.EXPECT().JoinRoom(gomock.Any(), roomUUID, userUUID).Return(false, errors.New("db error")),
+					m.iw.EXPECT().Reopen(gomock.Any(), inviteUUID).Return(nil),
+				)
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name: "resolve error",
+			mockSetup: func() {
+				m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(pending, nil)
+				m.iw.EXPECT().Resolve(gomock.Any(), inviteUUID, models.RoomInviteStatusAccepted).Return(false, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := svc.AcceptInvite(ctx, userUUID, inviteUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Equal(t, uuid.Nil, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, roomUUID, got)
+			}
+		})
+	}
+}
+
+func TestInviteService_DeclineInvite(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, m := newTestInviteService(ctrl)
+	userUUID := uuid.New()
+	inviteUUID := uuid.New()
+	pending := &models.RoomInviteDB{InviteUUID: inviteUUID, RoomUUID: uuid.New(), InviteeUUID: userUUID, Status: models.RoomInviteStatusPending}
+	ctx := context.Background()
+
+	m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(pending, nil)
+	m.iw.EXPECT().Resolve(gomock.Any(), inviteUUID, models.RoomInviteStatusDeclined).Return(true, nil)
+	assert.NoError(t, svc.DeclineInvite(ctx, userUUID, inviteUUID))
+
+	m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(pending, nil)
+	m.iw.EXPECT().Resolve(gomock.Any(), inviteUUID, models.RoomInviteStatusDeclined).Return(false, nil)
+	assert.ErrorIs(t, svc.DeclineInvite(ctx, userUUID, inviteUUID), ErrInviteNotFound)
+
+	m.ir.EXPECT().Get(gomock.Any(), inviteUUID).Return(pending, nil)
+	assert.ErrorIs(t, svc.DeclineInvite(ctx, uuid.New(), inviteUUID), ErrInviteNotFound)
+}
+
+func TestInviteService_CreateInviteLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, m := newTestInviteService(ctrl)
+	roomUUID := uuid.New()
+	actorUUID := uuid.New()
+	group := &models.RoomDB{RoomUUID: roomUUID, Kind: models.RoomKindGroup}
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		ttl           time.Duration
+		maxUses       int
+		expectedTTL   time.Duration
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name:        "default ttl",
+			expectedTTL: DefaultInviteLinkTTL,
+			mockSetup: func() {
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleOwner}, nil)
+				m.lw.EXPECT().Save(gomock.Any(), gomock.Any(), roomUUID, actorUUID, gomock.Any(), 0).Return(nil)
+			},
+		},
+		{
+			name:        "custom ttl and max uses",
+			ttl:         time.Hour,
+			maxUses:     5,
+			expectedTTL: time.Hour,
+			mockSetup: func() {
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleAdmin}, nil)
+				m.lw.EXPECT().Save(gomock.Any(), gomock.Any(), roomUUID, actorUUID, gomock.Any(), 5).Return(nil)
+			},
+		},
+		{
+			name:          "ttl too long",
+			ttl:           MaxInviteLinkTTL + time.Hour,
+			mockSetup:     func() {},
+			expectedError: ErrInvalidInviteLink,
+		},
+		{
+			name:          "negative max uses",
+			maxUses:       -1,
+			mockSetup:     func() {},
+			expectedError: ErrInvalidInviteLink,
+		},
+		{
+			name: "member forbidden",
+			mockSetup: func() {
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleMember}, nil)
+			},
+			expectedError: ErrForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			link, err := svc.CreateInviteLink(ctx, actorUUID, roomUUID, tt.ttl, tt.maxUses)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, link)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, roomUUID, link.RoomUUID)
+			assert.Equal(t, tt.maxUses, link.MaxUses)
+			assert.WithinDuration(t, time.Now().Add(tt.expectedTTL), link.ExpiresAt, time.Minute)
+		})
+	}
+}
+
+func TestInviteService_JoinByLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, m := newTestInviteService(ctrl)
+	userUUID := uuid.New()
+	roomUUID := uuid.New()
+	linkUUID := uuid.New()
+	link := &models.RoomInviteLinkDB{LinkUUID: linkUUID, RoomUUID: roomUUID, ExpiresAt: time.Now().Add(time.Hour), MaxUses: 2, Uses: 1}
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name: "success",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+				gomock.InOrder(
+					m.lw.EXPECT().Use(gomock.Any(), linkUUID, gomock.Any()).Return(true, nil),
+					m.rj.EXPECT().JoinRoom(gomock.Any(), roomUUID, userUUID).Return(true, nil),
+				)
+			},
+		},
+		{
+			name: "already member",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleMember}, nil)
+			},
+		},
+		{
+			name: "link not found",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(nil, nil)
+			},
+			expectedError: ErrInviteLinkNotFound,
+		},
+		{
+			name: "link expired",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(&models.RoomInviteLinkDB{LinkUUID: linkUUID, RoomUUID: roomUUID, ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+				m.lw.EXPECT().Use(gomock.Any(), linkUUID, gomock.Any()).Return(false, nil)
+			},
+			expectedError: ErrInviteLinkExpired,
+		},
+		{
+			name: "link used up",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(&models.RoomInviteLinkDB{LinkUUID: linkUUID, RoomUUID: roomUUID, ExpiresAt: time.Now().Add(time.Hour), MaxUses: 1, Uses: 1}, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+				m.lw.EXPECT().Use(gomock.Any(), linkUUID, gomock.Any()).Return(false, nil)
+			},
+			expectedError: ErrInviteLinkExpired,
+		},
+		{
+			// израсходованный вход по ссылке возвращается
+			name: "join error",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+				gomock.InOrder(
+					m.lw.EXPECT().Use(gomock.Any(), linkUUID, gomock.Any()).Return(true, nil),
+					m.rj.EXPECT().JoinRoom(gomock.Any(), roomUUID, userUUID).Return(false, errors.New("db error")),
+					m.lw.EXPECT().Release(gomock.Any(), linkUUID).Return(nil),
+				)
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			// пользователь стал участником одновременным запросом: вход по ссылке возвращается
+			name: "joined concurrently",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+				gomock.InOrder(
+					m.lw.EXPECT().Use(gomock.Any(), linkUUID, gomock.Any()).Return(true, nil),
+					m.rj.EXPECT().JoinRoom(gomock.Any(), roomUUID, userUUID).Return(false, nil),
+					m.lw.EXPECT().Release(gomock.Any(), linkUUID).Return(nil),
+				)
+			},
+		},
+		{
+			// пользователь в комнату не входит: JoinRoom не вызывается
+			name: "last use taken concurrently",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+				m.lw.EXPECT().Use(gomock.Any(), linkUUID, gomock.Any()).Return(false, nil)
+			},
+			expectedError: ErrInviteLinkExpired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := svc.JoinByLink(ctx, userUUID, linkUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Equal(t, uuid.Nil, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, roomUUID, got)
+			}
+		})
+	}
+}
+
+func TestInviteService_RevokeInviteLink(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	svc, m := newTestInviteService(ctrl)
+	actorUUID := uuid.New()
+	roomUUID := uuid.New()
+	linkUUID := uuid.New()
+	link := &models.RoomInviteLinkDB{LinkUUID: linkUUID, RoomUUID: roomUUID, ExpiresAt: time.Now().Add(time.Hour)}
+	group := &models.RoomDB{RoomUUID: roomUUID, Kind: models.RoomKindGroup}
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockSetup     func()
+		expectedError error
+	}{
+		{
+			name: "owner",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleOwner}, nil)
+				m.lw.EXPECT().Delete(gomock.Any(), linkUUID).Return(nil)
+			},
+		},
+		{
+			name: "admin",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleAdmin}, nil)
+				m.lw.EXPECT().Delete(gomock.Any(), linkUUID).Return(nil)
+			},
+		},
+		{
+			name: "member forbidden",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleMember}, nil)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name: "not a member",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(nil, nil)
+			},
+			expectedError: ErrForbidden,
+		},
+		{
+			name: "link not found",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(nil, nil)
+			},
+			expectedError: ErrInviteLinkNotFound,
+		},
+		{
+			name: "delete error",
+			mockSetup: func() {
+				m.lr.EXPECT().Get(gomock.Any(), linkUUID).Return(link, nil)
+				m.rr.EXPECT().Get(gomock.Any(), roomUUID).Return(group, nil)
+				m.rmr.EXPECT().Get(gomock.Any(), roomUUID, actorUUID).Return(&models.RoomMemberDB{Role: models.RoomRoleOwner}, nil)
+				m.lw.EXPECT().Delete(gomock.Any(), linkUUID).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			err := svc.RevokeInviteLink(ctx, actorUUID, linkUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,511 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// ErrTooManyAttempts возвращается, если попытки входа временно запрещены из-за неудачных попыток
+// по имени пользователя или IP-адресу. Конкретная ошибка имеет тип *TooManyAttemptsError.
+var ErrTooManyAttempts = errors.New("too many login attempts")
+
+// TooManyAttemptsError сообщает, через сколько можно повторить попытку входа.
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration // время до следующей разрешённой попытки
+}
+
+// Error возвращает текст ошибки.
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("%s: retry after %s", ErrTooManyAttempts, e.RetryAfter)
+}
+
+// Unwrap позволяет сравнивать ошибку с ErrTooManyAttempts через errors.Is.
+func (e *TooManyAttemptsError) Unwrap() error {
+	return ErrTooManyAttempts
+}
+
+const (
+	// DefaultLoginFreeAttempts — число неудачных попыток входа по имени пользователя без задержки
+	DefaultLoginFreeAttempts = 3
+	// DefaultLoginLockoutThreshold — число неудачных попыток по имени пользователя, после которого вход блокируется
+	DefaultLoginLockoutThreshold = 10
+	// DefaultLoginIPFreeAttempts — число неудачных попыток входа с одного IP-адреса без задержки
+	DefaultLoginIPFreeAttempts = 10
+	// DefaultLoginIPLockoutThreshold — число неудачных попыток с одного IP-адреса, после которого вход блокируется
+	DefaultLoginIPLockoutThreshold = 50
+	// DefaultLoginBackoffBase — задержка после первой неудачной попытки сверх бесплатных
+	DefaultLoginBackoffBase = time.Second
+	// DefaultLoginBackoffMax — максимальная задержка между попытками до блокировки
+	DefaultLoginBackoffMax = 5 * time.Minute
+	// DefaultLoginLockoutDuration — длительность блокировки; столько же хранится счётчик неудачных попыток
+	DefaultLoginLockoutDuration = 15 * time.Minute
+	// DefaultLoginMaxTrackedKeys — максимальное число счётчиков неудачных попыток в памяти
+	DefaultLoginMaxTrackedKeys = 100000
+
+	// DefaultLockoutsPageSize — число записей о блокировках входа, возвращаемых по умолчанию
+	DefaultLockoutsPageSize = 100
+	// MaxLockoutsPageSize — максимальное число записей о блокировках входа в одном ответе
+	MaxLockoutsPageSize = 1000
+)
+
+// LoginLockoutWriter описывает интерфейс для сохранения записей о блокировках входа.
+type LoginLockoutWriter interface {
+	// Save сохраняет запись о блокировке входа по имени пользователя или IP-адресу.
+	Save(
+		ctx context.Context,
+		lockoutUUID uuid.UUID,
+		scope string,
+		key string,
+		failures int,
+		lockedAt time.Time,
+		lockedUntil time.Time,
+	) error
+}
+
+// LoginLockoutReader описывает интерфейс для чтения записей о блокировках входа.
+type LoginLockoutReader interface {
+	// List возвращает не более limit последних блокировок входа, от новых к старым.
+	List(ctx context.Context, limit int) ([]models.LoginLockoutDB, error)
+}
+
+// attemptLimits — пороги неудачных попыток для одной области (имя пользователя или IP-адрес).
+type attemptLimits struct {
+	free    int // неудачные попытки без задержки
+	lockout int // неудачные попытки до блокировки
+}
+
+// attemptKey — ключ счётчика неудачных попыток.
+type attemptKey struct {
+	scope string
+	key   string
+}
+
+// attemptState — неудачные попытки по одному ключу.
+type attemptState struct {
+	failures    int           // неудачные и ещё не завершившиеся попытки
+	lastFailure time.Time     // момент последней попытки, учтённой в failures
+	recorded    bool          // запись о текущей блокировке уже сохранена
+	elem        *list.Element // элемент ключа в порядке последних попыток
+}
+
+// LoginThrottleService ограничивает попытки входа по имени пользователя и IP-адресу.
+// После бесплатных неудачных попыток каждая следующая удваивает задержку до следующей попытки,
+// а после порога блокировки вход запрещён на время блокировки; каждая блокировка сохраняется в базе данных.
+// Счётчики хранятся в памяти, поэтому при нескольких экземплярах сервера ограничения действуют в каждом отдельно.
+// Их число ограничено: попытки с несуществующими именами пользователей не проверяют пароль и дёшевы для
+// атакующего, поэтому при переполнении вытесняются счётчики с самой давней последней попыткой, кроме
+// действующих блокировок; если заблокированы все, попытки с новыми ключами отклоняются.
+type LoginThrottleService struct {
+	lw LoginLockoutWriter // репозиторий для записи блокировок
+	lr LoginLockoutReader // репозиторий для чтения блокировок
+
+	usernameLimits  attemptLimits
+	ipLimits        attemptLimits
+	backoffBase     time.Duration
+	backoffMax      time.Duration
+	lockoutDuration time.Duration
+	maxKeys         int
+
+	mu       sync.Mutex
+	attempts map[attemptKey]*attemptState
+	order    *list.List // ключи attempts от самой давней последней попытки к самой свежей
+	now      func() time.Time
+}
+
+// LoginThrottleOpt — функциональная опция для настройки LoginThrottleService.
+type LoginThrottleOpt func(*LoginThrottleService)
+
+// WithLoginFreeAttempts задаёт число неудачных попыток по имени пользователя без задержки.
+// Используется первое положительное значение.
+func WithLoginFreeAttempts(attempts ...int) LoginThrottleOpt {
+	return func(svc *LoginThrottleService) {
+		for _, a := range attempts {
+			if a > 0 {
+				svc.usernameLimits.free = a
+				return
+			}
+		}
+	}
+}
+
+// WithLoginLockoutThreshold задаёт число неудачных попыток по имени пользователя до блокировки.
+// Используется первое положительное значение.
+func WithLoginLockoutThreshold(attempts ...int) LoginThrottleOpt {
+	return func(svc *LoginThrottleService) {
+		for _, a := range attempts {
+			if a > 0 {
+				svc.usernameLimits.lockout = a
+				return
+			}
+		}
+	}
+}
+
+// WithLoginIPFreeAttempts задаёт число неудачных попыток с одного IP-адреса без задержки.
+// Используется первое положительное значение.
+func WithLoginIPFreeAttempts(attempts ...int) LoginThrottleOpt {
+	return func(svc *LoginThrottleService) {
+		for _, a := range attempts {
+			if a > 0 {
+				svc.ipLimits.free = a
+				return
+			}
+		}
+	}
+}
+
+// WithLoginIPLockoutThreshold задаёт число неудачных попыток с одного IP-адреса до блокировки.
+// Используется первое положительное значение.
+func WithLoginIPLockoutThreshold(attempts ...int) LoginThrottleOpt {
+	return func(svc *LoginThrottleService) {
+		for _, a := range attempts {
+			if a > 0 {
+				svc.ipLimits.lockout = a
+				return
+			}
+		}
+	}
+}
+
+// WithLoginBackoff задаёт начальную и максимальную задержку между попытками.
+// Значения применяются, только если оба положительны.
+func WithLoginBackoff(base time.Duration, max time.Duration) LoginThrottleOpt {
+	return func(svc *LoginThrottleService) {
+		if base > 0 && max > 0 {
+			svc.backoffBase = base
+			svc.backoffMax = max
+		}
+	}
+}
+
+// WithLoginLockoutDuration задаёт длительность блокировки.
+// Используется первое положительное значение.
+func WithLoginLockoutDuration(durations ...time.Duration) LoginThrottleOpt {
+	return func(svc *LoginThrottleService) {
+		for _, d := range durations {
+			if d > 0 {
+				svc.lockoutDuration = d
+				return
+			}
+		}
+	}
+}
+
+// WithLoginMaxTrackedKeys задаёт максимальное число счётчиков неудачных попыток в памяти.
+// Используется первое положительное значение.
+func WithLoginMaxTrackedKeys(keys ...int) LoginThrottleOpt {
+	return func(svc *LoginThrottleService) {
+		for _, k := range keys {
+			if k > 0 {
+				svc.maxKeys = k
+				return
+			}
+		}
+	}
+}
+
+// NewLoginThrottleService создаёт новый экземпляр LoginThrottleService.
+// Без опций используются значения DefaultLogin*.
+func NewLoginThrottleService(
+	lw LoginLockoutWriter,
+	lr LoginLockoutReader,
+	opts ...LoginThrottleOpt,
+) *LoginThrottleService {
+	svc := &LoginThrottleService{
+		lw:              lw,
+		lr:              lr,
+		usernameLimits:  attemptLimits{free: DefaultLoginFreeAttempts, lockout: DefaultLoginLockoutThreshold},
+		ipLimits:        attemptLimits{free: DefaultLoginIPFreeAttempts, lockout: DefaultLoginIPLockoutThreshold},
+		backoffBase:     DefaultLoginBackoffBase,
+		backoffMax:      DefaultLoginBackoffMax,
+		lockoutDuration: DefaultLoginLockoutDuration,
+		maxKeys:         DefaultLoginMaxTrackedKeys,
+		attempts:        make(map[attemptKey]*attemptState),
+		order:           list.New(),
+		now:             time.Now,
+	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
+}
+
+// keys возвращает ключи счётчиков для попытки входа. Имя пользователя учитывается с учётом регистра,
+// как и при регистрации и поиске пользователя; пустой IP-адрес не учитывается.
+func (svc *LoginThrottleService) keys(username string, ip string) []attemptKey {
+	keys := []attemptKey{{models.LoginLockoutScopeUsername, username}}
+	if ip != "" {
+		keys = append(keys, attemptKey{models.LoginLockoutScopeIP, ip})
+	}
+	return keys
+}
+
+// limits возвращает пороги для области scope.
+func (svc *LoginThrottleService) limits(scope string) attemptLimits {
+	if scope == models.LoginLockoutScopeIP {
+		return svc.ipLimits
+	}
+	return svc.usernameLimits
+}
+
+// blockedUntil возвращает момент, до которого попытки по ключу запрещены, или нулевое время.
+func (svc *LoginThrottleService) blockedUntil(state *attemptState, limits attemptLimits) time.Time {
+	switch {
+	case state.failures >= limits.lockout:
+		return state.lastFailure.Add(svc.lockoutDuration)
+	case state.failures > limits.free:
+		delay := svc.backoffMax
+		if shift := state.failures - limits.free - 1; shift < 32 {
+			if d := svc.backoffBase << shift; d > 0 && d < delay {
+				delay = d
+			}
+		}
+		return state.lastFailure.Add(delay)
+	default:
+		return time.Time{}
+	}
+}
+
+// Begin вызывается перед проверкой пароля. Если попытки по имени пользователя или IP-адресу временно
+// запрещены, возвращает *TooManyAttemptsError. Иначе попытка заранее учитывается как неудачная,
+// чтобы параллельные запросы не обходили ограничение; после проверки пароля нужно вызвать Fail или Succeed.
+func (svc *LoginThrottleService) Begin(username string, ip string) error {
+	now := svc.now()
+	keys := svc.keys(username, ip)
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	var retryAfter time.Duration
+	for _, key := range keys {
+		state, ok := svc.attempts[key]
+		if !ok {
+			continue
+		}
+		if until := svc.blockedUntil(state, svc.limits(key.scope)); until.After(now) && until.Sub(now) > retryAfter {
+			retryAfter = until.Sub(now)
+		}
+	}
+	if retryAfter > 0 {
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+
+	missing := 0
+	for _, key := range keys {
+		state, ok := svc.attempts[key]
+		if ok && now.Sub(state.lastFailure) > svc.lockoutDuration {
+			svc.remove(key)
+			ok = false
+		}
+		if !ok {
+			missing++
+		}
+	}
+	if retryAfter := svc.evict(missing, keys, now); retryAfter > 0 {
+		return &TooManyAttemptsError{RetryAfter: retryAfter}
+	}
+
+	for _, key := range keys {
+		state, ok := svc.attempts[key]
+		if !ok {
+			state = svc.track(key)
+		}
+		state.failures++
+		state.lastFailure = now
+		svc.order.MoveToBack(state.elem)
+	}
+
+	return nil
+}
+
+// evict освобождает место для n новых счётчиков, вытесняя счётчики с самой давней последней попыткой.
+// Счётчики с действующей блокировкой и счётчики ключей keep не вытесняются: иначе атакующий мог бы снять
+// блокировку, заполнив память попытками с другими ключами. Если места не хватает, ничего не вытесняется
+// и возвращается время до снятия ближайшей блокировки — новые ключи отклоняются. Вызывается под svc.mu.
+func (svc *LoginThrottleService) evict(n int, keep []attemptKey, now time.Time) time.Duration {
+	excess := len(svc.attempts) + n - svc.maxKeys
+	if n == 0 || excess <= 0 {
+		return 0
+	}
+
+	var (
+		victims    []attemptKey
+		retryAfter time.Duration
+	)
+	for e := svc.order.Front(); e != nil && len(victims) < excess; e = e.Next() {
+		key := e.Value.(attemptKey)
+		if containsAttemptKey(keep, key) {
+			continue
+		}
+		state := svc.attempts[key]
+		if state.failures >= svc.limits(key.scope).lockout {
+			if until := state.lastFailure.Add(svc.lockoutDuration); until.After(now) {
+				if wait := until.Sub(now); retryAfter == 0 || wait < retryAfter {
+					retryAfter = wait
+				}
+				continue
+			}
+		}
+		victims = append(victims, key)
+	}
+
+	if len(victims) < excess {
+		if retryAfter == 0 {
+			retryAfter = svc.backoffBase
+		}
+		log.Printf("login throttle: all %d tracked keys are locked out, rejecting new keys", len(svc.attempts))
+		return retryAfter
+	}
+	for _, key := range victims {
+		svc.remove(key)
+	}
+	return 0
+}
+
+// containsAttemptKey сообщает, есть ли key среди keys.
+func containsAttemptKey(keys []attemptKey, key attemptKey) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// track заводит счётчик ключа; место под него освобождает evict. Вызывается под svc.mu.
+func (svc *LoginThrottleService) track(key attemptKey) *attemptState {
+	state := &attemptState{elem: svc.order.PushBack(key)}
+	svc.attempts[key] = state
+	return state
+}
+
+// remove удаляет счётчик ключа. Вызывается под svc.mu.
+func (svc *LoginThrottleService) remove(key attemptKey) {
+	if state, ok := svc.attempts[key]; ok {
+		svc.order.Remove(state.elem)
+		delete(svc.attempts, key)
+	}
+}
+
+// Fail подтверждает неудачную попытку, учтённую в Begin. Если попытка привела к блокировке,
+// сохраняет запись о блокировке в базе данных.
+func (svc *LoginThrottleService) Fail(ctx context.Context, username string, ip string) error {
+	var lockouts []models.LoginLockoutDB
+
+	svc.mu.Lock()
+	for _, key := range svc.keys(username, ip) {
+		state, ok := svc.attempts[key]
+		if !ok || state.recorded || state.failures < svc.limits(key.scope).lockout {
+			continue
+		}
+		state.recorded = true
+		lockouts = append(lockouts, models.LoginLockoutDB{
+			Scope:       key.scope,
+			Key:         key.key,
+			Failures:    state.failures,
+			LockedAt:    state.lastFailure.UTC(),
+			LockedUntil: state.lastFailure.Add(svc.lockoutDuration).UTC(),
+		})
+	}
+	svc.mu.Unlock()
+
+	for _, l := range lockouts {
+		log.Printf("login locked out: %s %q after %d failed attempts until %s", l.Scope, l.Key, l.Failures, l.LockedUntil)
+		if err := svc.lw.Save(ctx, uuid.New(), l.Scope, l.Key, l.Failures, l.LockedAt, l.LockedUntil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Succeed отменяет попытку, учтённую в Begin, после верного пароля.
+// Счётчик имени пользователя сбрасывается полностью, счётчик IP-адреса — только на эту попытку,
+// чтобы успешный вход в свой аккаунт не снимал ограничения с перебора чужих.
+func (svc *LoginThrottleService) Succeed(username string, ip string) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	for _, key := range svc.keys(username, ip) {
+		if key.scope == models.LoginLockoutScopeUsername {
+			svc.remove(key)
+			continue
+		}
+		svc.release(key)
+	}
+}
+
+// Cancel отменяет только попытку, учтённую в Begin, если пароль не удалось проверить из-за ошибки,
+// не связанной с паролем: прежние неудачные попытки остаются в счётчиках.
+func (svc *LoginThrottleService) Cancel(username string, ip string) {
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	for _, key := range svc.keys(username, ip) {
+		svc.release(key)
+	}
+}
+
+// release уменьшает счётчик ключа на одну попытку и удаляет опустевший счётчик.
+// Вызывается под svc.mu.
+func (svc *LoginThrottleService) release(key attemptKey) {
+	state, ok := svc.attempts[key]
+	if !ok {
+		return
+	}
+	state.failures--
+	if state.failures <= 0 {
+		svc.remove(key)
+	}
+}
+
+// ListLockouts возвращает не более limit последних блокировок входа, от новых к старым.
+// limit вне диапазона (0, MaxLockoutsPageSize] заменяется на DefaultLockoutsPageSize или MaxLockoutsPageSize.
+func (svc *LoginThrottleService) ListLockouts(ctx context.Context, limit int) ([]models.LoginLockoutDB, error) {
+	switch {
+	case limit <= 0:
+		limit = DefaultLockoutsPageSize
+	case limit > MaxLockoutsPageSize:
+		limit = MaxLockoutsPageSize
+	}
+	return svc.lr.List(ctx, limit)
+}
+
+// Cleanup удаляет счётчики, последняя попытка по которым была раньше длительности блокировки:
+// Begin всё равно начал бы их заново.
+func (svc *LoginThrottleService) Cleanup() {
+	now := svc.now()
+
+	svc.mu.Lock()
+	for e := svc.order.Front(); e != nil; e = svc.order.Front() {
+		key := e.Value.(attemptKey)
+		if now.Sub(svc.attempts[key].lastFailure) <= svc.lockoutDuration {
+			break
+		}
+		svc.remove(key)
+	}
+	svc.mu.Unlock()
+}
+
+// Run периодически очищает устаревшие счётчики до отмены контекста.
+func (svc *LoginThrottleService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			svc.Cleanup()
+		}
+	}
+}
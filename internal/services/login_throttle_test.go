@@ -0,0 +1,250 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testClock — управляемые часы для проверки задержек
+type testClock struct {
+	now time.Time
+}
+
+func (c *testClock) Now() time.Time { return c.now }
+
+func (c *testClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestThrottle(t *testing.T, lw LoginLockoutWriter, opts ...LoginThrottleOpt) (*LoginThrottleService, *testClock) {
+	t.Helper()
+	clock := &testClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	svc := NewLoginThrottleService(lw, nil, opts...)
+	svc.now = clock.Now
+	return svc, clock
+}
+
+// failAttempt выполняет неудачную попытку входа
+func failAttempt(t *testing.T, svc *LoginThrottleService, username, ip string) {
+	t.Helper()
+	require.NoError(t, svc.Begin(username, ip))
+	require.NoError(t, svc.Fail(context.Background(), username, ip))
+}
+
+// retryAfter возвращает задержку из ошибки Begin или 0, если попытка разрешена
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	if err == nil {
+		return 0
+	}
+	var tooMany *TooManyAttemptsError
+	require.True(t, errors.As(err, &tooMany), err)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+	return tooMany.RetryAfter
+}
+
+func TestLoginThrottleService_Backoff(t *testing.T) {
+	svc, clock := newTestThrottle(t, nil,
+		WithLoginFreeAttempts(2),
+		WithLoginLockoutThreshold(100),
+		WithLoginBackoff(time.Second, 4*time.Second),
+	)
+
+	failAttempt(t, svc, "johndoe", "")
+	failAttempt(t, svc, "johndoe", "")
+
+	// после бесплатных попыток задержка удваивается и ограничена максимумом
+	for _, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		failAttempt(t, svc, "johndoe", "")
+		assert.Equal(t, expected, retryAfter(t, svc.Begin("johndoe", "")))
+		clock.Advance(expected)
+	}
+
+	// другие пользователи не затронуты; имена пользователей различаются с учётом регистра
+	assert.NoError(t, svc.Begin("alice", ""))
+	assert.NoError(t, svc.Begin("JohnDoe", ""))
+}
+
+func TestLoginThrottleService_Lockout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLW := NewMockLoginLockoutWriter(ctrl)
+	svc, clock := newTestThrottle(t, mockLW,
+		WithLoginFreeAttempts(5),
+		WithLoginLockoutThreshold(3),
+		WithLoginLockoutDuration(10*time.Minute),
+	)
+
+	lockedAt := clock.Now().Add(2 * time.Second)
+	mockLW.EXPECT().
+		Save(gomock.Any(), gomock.Any(), models.LoginLockoutScopeUsername, "johndoe", 3, lockedAt, lockedAt.Add(10*time.Minute)).
+		Return(nil).
+		Times(1)
+
+	for i := 0; i < 3; i++ {
+		failAttempt(t, svc, "johndoe", "")
+		clock.Advance(time.Second)
+	}
+
+	assert.Equal(t, 10*time.Minute-time.Second, retryAfter(t, svc.Begin("johndoe", "")))
+
+	// после окончания блокировки счётчик начинается заново
+	clock.Advance(10 * time.Minute)
+	assert.NoError(t, svc.Begin("johndoe", ""))
+	svc.Succeed("johndoe", "")
+}
+
+func TestLoginThrottleService_IP(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLW := NewMockLoginLockoutWriter(ctrl)
+	svc, _ := newTestThrottle(t, mockLW,
+		WithLoginFreeAttempts(100),
+		WithLoginLockoutThreshold(100),
+		WithLoginIPFreeAttempts(2),
+		WithLoginIPLockoutThreshold(3),
+	)
+
+	mockLW.EXPECT().
+		Save(gomock.Any(), gomock.Any(), models.LoginLockoutScopeIP, "203.0.113.7", 3, gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	// перебор разных имён с одного адреса
+	failAttempt(t, svc, "alice", "203.0.113.7")
+	failAttempt(t, svc, "bob", "203.0.113.7")
+
+	// успешный вход в свой аккаунт не сбрасывает счётчик адреса
+	require.NoError(t, svc.Begin("carol", "203.0.113.7"))
+	svc.Succeed("carol", "203.0.113.7")
+
+	failAttempt(t, svc, "dave", "203.0.113.7")
+
+	assert.Equal(t, DefaultLoginLockoutDuration, retryAfter(t, svc.Begin("erin", "203.0.113.7")))
+	assert.NoError(t, svc.Begin("erin", "198.51.100.1"))
+}
+
+func TestLoginThrottleService_ConcurrentAttemptsReserved(t *testing.T) {
+	svc, _ := newTestThrottle(t, nil, WithLoginFreeAttempts(1), WithLoginLockoutThreshold(10))
+
+	// попытка учитывается до проверки пароля, поэтому параллельная попытка уже ограничена
+	require.NoError(t, svc.Begin("johndoe", ""))
+	require.NoError(t, svc.Begin("johndoe", ""))
+	assert.Positive(t, retryAfter(t, svc.Begin("johndoe", "")))
+
+	// успешный вход снимает ограничение по имени пользователя
+	svc.Succeed("johndoe", "")
+	assert.NoError(t, svc.Begin("johndoe", ""))
+}
+
+func TestLoginThrottleService_CancelKeepsFailures(t *testing.T) {
+	svc, _ := newTestThrottle(t, nil, WithLoginFreeAttempts(1), WithLoginLockoutThreshold(10))
+
+	failAttempt(t, svc, "johndoe", "")
+
+	// ошибка хранилища отменяет только свою попытку и не сбрасывает прежние неудачные
+	require.NoError(t, svc.Begin("johndoe", ""))
+	svc.Cancel("johndoe", "")
+	require.Contains(t, svc.attempts, attemptKey{models.LoginLockoutScopeUsername, "johndoe"})
+	assert.Equal(t, 1, svc.attempts[attemptKey{models.LoginLockoutScopeUsername, "johndoe"}].failures)
+
+	failAttempt(t, svc, "johndoe", "")
+	assert.Positive(t, retryAfter(t, svc.Begin("johndoe", "")))
+}
+
+func TestLoginThrottleService_Cleanup(t *testing.T) {
+	svc, clock := newTestThrottle(t, nil, WithLoginLockoutDuration(time.Minute))
+
+	failAttempt(t, svc, "johndoe", "203.0.113.7")
+	svc.Cleanup()
+	assert.Len(t, svc.attempts, 2)
+
+	clock.Advance(2 * time.Minute)
+	svc.Cleanup()
+	assert.Empty(t, svc.attempts)
+}
+
+func TestLoginThrottleService_MaxTrackedKeys(t *testing.T) {
+	svc, clock := newTestThrottle(t, nil, WithLoginFreeAttempts(1), WithLoginMaxTrackedKeys(3))
+
+	failAttempt(t, svc, "johndoe", "")
+	failAttempt(t, svc, "johndoe", "")
+
+	// перебор несуществующих имён не раздувает счётчики сверх лимита
+	for i := 0; i < 10; i++ {
+		clock.Advance(time.Millisecond)
+		failAttempt(t, svc, fmt.Sprintf("ghost%d", i), "")
+		assert.LessOrEqual(t, len(svc.attempts), 3)
+	}
+	assert.Len(t, svc.attempts, 3)
+	assert.Equal(t, 3, svc.order.Len())
+
+	// вытесняются счётчики с самой давней последней попыткой
+	assert.NotContains(t, svc.attempts, attemptKey{models.LoginLockoutScopeUsername, "johndoe"})
+	assert.Contains(t, svc.attempts, attemptKey{models.LoginLockoutScopeUsername, "ghost9"})
+}
+
+func TestLoginThrottleService_MaxTrackedKeysKeepsLockouts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLW := NewMockLoginLockoutWriter(ctrl)
+	mockLW.EXPECT().
+		Save(gomock.Any(), gomock.Any(), models.LoginLockoutScopeUsername, gomock.Any(), 1, gomock.Any(), gomock.Any()).
+		Return(nil).
+		Times(2)
+
+	svc, clock := newTestThrottle(t, mockLW,
+		WithLoginFreeAttempts(1),
+		WithLoginLockoutThreshold(1),
+		WithLoginLockoutDuration(10*time.Minute),
+		WithLoginMaxTrackedKeys(2),
+	)
+
+	failAttempt(t, svc, "johndoe", "")
+	clock.Advance(time.Second)
+	failAttempt(t, svc, "janedoe", "")
+	clock.Advance(time.Second)
+
+	// все счётчики заблокированы: новые ключи отклоняются, блокировки не вытесняются
+	assert.Equal(t, 10*time.Minute-2*time.Second, retryAfter(t, svc.Begin("ghost", "")))
+	assert.Len(t, svc.attempts, 2)
+	assert.Equal(t, 10*time.Minute-2*time.Second, retryAfter(t, svc.Begin("johndoe", "")))
+
+	// после окончания блокировки счётчик снова можно вытеснить
+	clock.Advance(10*time.Minute - time.Second)
+	require.NoError(t, svc.Begin("ghost", ""))
+	svc.Cancel("ghost", "")
+	assert.NotContains(t, svc.attempts, attemptKey{models.LoginLockoutScopeUsername, "johndoe"})
+	assert.Contains(t, svc.attempts, attemptKey{models.LoginLockoutScopeUsername, "janedoe"})
+}
+
+func TestLoginThrottleService_ListLockouts(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLR := NewMockLoginLockoutReader(ctrl)
+	svc := NewLoginThrottleService(nil, mockLR)
+
+	lockouts := []models.LoginLockoutDB{{Scope: models.LoginLockoutScopeUsername, Key: "johndoe", Failures: 10}}
+	mockLR.EXPECT().List(gomock.Any(), 20).Return(lockouts, nil)
+
+	got, err := svc.ListLockouts(context.Background(), 20)
+	require.NoError(t, err)
+	assert.Equal(t, lockouts, got)
+
+	mockLR.EXPECT().List(gomock.Any(), DefaultLockoutsPageSize).Return(nil, nil)
+	_, err = svc.ListLockouts(context.Background(), 0)
+	require.NoError(t, err)
+
+	mockLR.EXPECT().List(gomock.Any(), MaxLockoutsPageSize).Return(nil, nil)
+	_, err = svc.ListLockouts(context.Background(), MaxLockoutsPageSize+1)
+	require.NoError(t, err)
+}
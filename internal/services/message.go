@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// ErrInvalidCursor возвращается, если курсор постраничной выборки не является сообщением комнаты.
+var ErrInvalidCursor = errors.New("invalid message cursor")
+
+const (
+	// DefaultMessagesPageSize — размер страницы истории сообщений по умолчанию
+	DefaultMessagesPageSize = 50
+	// MaxMessagesPageSize — максимальный размер страницы истории сообщений
+	MaxMessagesPageSize = 200
+)
+
+// RoomMessageWriter описывает интерфейс для сохранения сообщений комнаты.
+type RoomMessageWriter interface {
+	// Save сохраняет сообщение комнаты, отправленное с устройства senderDeviceUUID и зашифрованное ключом эпохи keyEpoch.
+	// Повторное сохранение сообщения с тем же отправителем и clientMessageUUID в комнате не меняет запись.
+	Save(
+		ctx context.Context,
+		messageUUID uuid.UUID,
+		roomUUID uuid.UUID,
+		senderUUID uuid.UUID,
+		senderDeviceUUID uuid.UUID,
+		clientMessageUUID uuid.UUID,
+		keyEpoch int64,
+		ciphertext string,
+		sentAt time.Time,
+	) error
+}
+
+// RoomMessageReader описывает интерфейс для чтения сообщений комнаты.
+type RoomMessageReader interface {
+	// GetByClientUUID возвращает сообщение отправителя в комнате с UUID, присвоенным клиентом, или nil.
+	GetByClientUUID(ctx context.Context, roomUUID uuid.UUID, senderUUID uuid.UUID, clientMessageUUID uuid.UUID) (*models.RoomMessageDB, error)
+	// ListLast возвращает последние limit сообщений комнаты в порядке отправки.
+	ListLast(ctx context.Context, roomUUID uuid.UUID, limit int) ([]models.RoomMessageDB, error)
+	// Get возвращает сообщение по UUID или nil.
+	Get(ctx context.Context, messageUUID uuid.UUID) (*models.RoomMessageDB, error)
+	// ListPage возвращает до limit сообщений комнаты между курсорами before и after в порядке отправки.
+	ListPage(ctx context.Context, roomUUID uuid.UUID, before *models.MessageCursor, after *models.MessageCursor, limit int) ([]models.RoomMessageDB, error)
+}
+
+// MessageService реализует бизнес-логику хранения сообщений комнат.
+type MessageService struct {
+	mw  RoomMessageWriter // репозиторий для записи сообщений
+	mr  RoomMessageReader // репозиторий для чтения сообщений
+	rr  RoomReader        // репозиторий для чтения комнат
+	rmr RoomMemberReader  // репозиторий для чтения участников
+}
+
+// NewMessageService создаёт новый экземпляр MessageService с указанными репозиториями.
+func NewMessageService(
+	mw RoomMessageWriter,
+	mr RoomMessageReader,
+	rr RoomReader,
+	rmr RoomMemberReader,
+) *MessageService {
+	return &MessageService{
+		mw:  mw,
+		mr:  mr,
+		rr:  rr,
+		rmr: rmr,
+	}
+}
+
+// SaveMessage сохраняет зашифрованное ключом эпохи keyEpoch сообщение, отправленное с устройства
+// senderDeviceUUID, в истории комнаты и возвращает сохранённое сообщение с присвоенными UUID и временем отправки.
+//
+// Если клиент присвоил сообщению clientMessageUUID и уже отправлял его в эту комнату раньше (повтор после переподключения),
+// новое сообщение не создаётся: возвращается ранее сохранённое и duplicate = true.
+func (svc *MessageService) SaveMessage(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	senderUUID uuid.UUID,
+	senderDeviceUUID uuid.UUID,
+	clientMessageUUID uuid.UUID,
+	keyEpoch int64,
+	ciphertext string,
+) (message *models.RoomMessageDB, duplicate bool, err error) {
+	message = &models.RoomMessageDB{
+		MessageUUID:       uuid.New(),
+		RoomUUID:          roomUUID,
+		SenderUUID:        senderUUID,
+		SenderDeviceUUID:  senderDeviceUUID,
+		ClientMessageUUID: clientMessageUUID,
+		KeyEpoch:          keyEpoch,
+		Ciphertext:        ciphertext,
+		SentAt:            time.Now().UTC(),
+	}
+
+	if err := svc.mw.Save(
+		ctx,
+		message.MessageUUID,
+		message.RoomUUID,
+		message.SenderUUID,
+		message.SenderDeviceUUID,
+		message.ClientMessageUUID,
+		message.KeyEpoch,
+		message.Ciphertext,
+		message.SentAt,
+	); err != nil {
+		return nil, false, err
+	}
+
+	if clientMessageUUID == uuid.Nil {
+		return message, false, nil
+	}
+
+	// Запись с этим UUID клиента могла быть сохранена раньше — возвращаем её
+	stored, err := svc.mr.GetByClientUUID(ctx, roomUUID, senderUUID, clientMessageUUID)
+	if err != nil {
+		return nil, false, err
+	}
+	if stored == nil || stored.MessageUUID == message.MessageUUID {
+		return message, false, nil
+	}
+	return stored, true, nil
+}
+
+// RoomKeyEpoch возвращает текущую эпоху ключа комнаты; если комнаты нет, возвращает ErrRoomNotFound.
+func (svc *MessageService) RoomKeyEpoch(ctx context.Context, roomUUID uuid.UUID) (int64, error) {
+	room, err := svc.rr.Get(ctx, roomUUID)
+	if err != nil {
+		return 0, err
+	}
+	if room == nil {
+		return 0, ErrRoomNotFound
+	}
+	return room.KeyEpoch, nil
+}
+
+// ListLastMessages возвращает последние limit сообщений комнаты от старых к новым.
+func (svc *MessageService) ListLastMessages(
+	ctx context.Context,
+	roomUUID uuid.UUID,
+	limit int,
+) ([]models.RoomMessageDB, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+	return svc.mr.ListLast(ctx, roomUUID, limit)
+}
+
+// ListMessages возвращает страницу истории комнаты для её участника userUUID в порядке отправки.
+//
+// before и after — UUID сообщений комнаты, ограничивающих страницу (uuid.Nil — без ограничения).
+// Если задан after, возвращаются ближайшие после него сообщения, иначе — ближайшие до before
+// (или последние сообщения комнаты). hasMore сообщает, есть ли ещё сообщения в направлении чтения:
+// более новые при заданном after и более старые в остальных случаях.
+// limit вне диапазона (0, MaxMessagesPageSize] заменяется на DefaultMessagesPageSize или MaxMessagesPageSize.
+func (svc *MessageService) ListMessages(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	roomUUID uuid.UUID,
+	before uuid.UUID,
+	after uuid.UUID,
+	limit int,
+) (messages []models.RoomMessageDB, hasMore bool, err error) {
+	if err := svc.checkMember(ctx, roomUUID, userUUID); err != nil {
+		return nil, false, err
+	}
+
+	switch {
+	case limit <= 0:
+		limit = DefaultMessagesPageSize
+	case limit > MaxMessagesPageSize:
+		limit = MaxMessagesPageSize
+	}
+
+	beforeCursor, err := svc.cursor(ctx, roomUUID, before)
+	if err != nil {
+		return nil, false, err
+	}
+	afterCursor, err := svc.cursor(ctx, roomUUID, after)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Запрашиваем на одно сообщение больше, чтобы узнать, есть ли следующая страница
+	messages, err = svc.mr.ListPage(ctx, roomUUID, beforeCursor, afterCursor, limit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(messages) <= limit {
+		return messages, false, nil
+	}
+	if afterCursor != nil {
+		return messages[:limit], true, nil
+	}
+	return messages[1:], true, nil
+}
+
+// cursor возвращает позицию сообщения messageUUID комнаты roomUUID или nil для uuid.Nil.
+func (svc *MessageService) cursor(ctx context.Context, roomUUID uuid.UUID, messageUUID uuid.UUID) (*models.MessageCursor, error) {
+	if messageUUID == uuid.Nil {
+		return nil, nil
+	}
+	message, err := svc.mr.Get(ctx, messageUUID)
+	if err != nil {
+		return nil, err
+	}
+	if message == nil || message.RoomUUID != roomUUID {
+		return nil, ErrInvalidCursor
+	}
+	return &models.MessageCursor{SentAt: message.SentAt, MessageUUID: message.MessageUUID}, nil
+}
+
+// checkMember проверяет, что комната существует и пользователь является её участником.
+func (svc *MessageService) checkMember(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) error {
+	room, err := svc.rr.Get(ctx, roomUUID)
+	if err != nil {
+		return err
+	}
+	if room == nil {
+		return ErrRoomNotFound
+	}
+
+	member, err := svc.rmr.Get(ctx, roomUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	if member == nil {
+		return ErrUserNotInRoom
+	}
+
+	return nil
+}
@@ -0,0 +1,336 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageService_SaveMessage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMW := NewMockRoomMessageWriter(ctrl)
+	mockMR := NewMockRoomMessageReader(ctrl)
+	svc := NewMessageService(mockMW, mockMR, NewMockRoomReader(ctrl), NewMockRoomMemberReader(ctrl))
+	roomUUID := uuid.New()
+	senderUUID := uuid.New()
+	deviceUUID := uuid.New()
+	clientUUID := uuid.New()
+	ctx := context.Background()
+
+	earlier := &models.RoomMessageDB{MessageUUID: uuid.New(), RoomUUID: roomUUID, SenderUUID: senderUUID, ClientMessageUUID: clientUUID}
+
+	tests := []struct {
+		name              string
+		clientUUID        uuid.UUID
+		setup             func()
+		expectedDuplicate bool
+		expectedMessage   *models.RoomMessageDB
+		expectedError     error
+	}{
+		{
+			name:       "success without client UUID",
+			clientUUID: uuid.Nil,
+			setup: func() {
+				mockMW.EXPECT().Save(gomock.Any(), gomock.Any(), roomUUID, senderUUID, deviceUUID, uuid.Nil, int64(2), "ciphertext", gomock.Any()).Return(nil)
+			},
+		},
+		{
+			name:       "first delivery",
+			clientUUID: clientUUID,
+			setup: func() {
+				var saved uuid.UUID
+				mockMW.EXPECT().Save(gomock.Any(), gomock.Any(), roomUUID, senderUUID, deviceUUID, clientUUID, int64(2), "ciphertext", gomock.Any()).
+					DoAndReturn(func(_ context.Context, messageUUID, _, _, _, _ uuid.UUID, _ int64, _ string, _ any) error {
+						saved = messageUUID
+						return nil
+					})
+				mockMR.EXPECT().GetByClientUUID(gomock.Any(), roomUUID, senderUUID, clientUUID).
+					DoAndReturn(func(context.Context, uuid.UUID, uuid.UUID, uuid.UUID) (*models.RoomMessageDB, error) {
+						return &models.RoomMessageDB{MessageUUID: saved}, nil
+					})
+			},
+		},
+		{
+			name:       "resend after reconnect",
+			clientUUID: clientUUID,
+			setup: func() {
+				mockMW.EXPECT().Save(gomock.Any(), gomock.Any(), roomUUID, senderUUID, deviceUUID, clientUUID, int64(2), "ciphertext", gomock.Any()).Return(nil)
+				mockMR.EXPECT().GetByClientUUID(gomock.Any(), roomUUID, senderUUID, clientUUID).Return(earlier, nil)
+			},
+			expectedDuplicate: true,
+			expectedMessage:   earlier,
+		},
+		{
+			name:       "save error",
+			clientUUID: clientUUID,
+			setup: func() {
+				mockMW.EXPECT().Save(gomock.Any(), gomock.Any(), roomUUID, senderUUID, deviceUUID, clientUUID, int64(2), "ciphertext", gomock.Any()).Return(errors.New("save fail"))
+			},
+			expectedError: errors.New("save fail"),
+		},
+		{
+			name:       "lookup error",
+			clientUUID: clientUUID,
+			setup: func() {
+				mockMW.EXPECT().Save(gomock.Any(), gomock.Any(), roomUUID, senderUUID, deviceUUID, clientUUID, int64(2), "ciphertext", gomock.Any()).Return(nil)
+				mockMR.EXPECT().GetByClientUUID(gomock.Any(), roomUUID, senderUUID, clientUUID).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+
+			message, duplicate, err := svc.SaveMessage(ctx, roomUUID, senderUUID, deviceUUID, tt.clientUUID, 2, "ciphertext")
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, message)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedDuplicate, duplicate)
+			if tt.expectedMessage != nil {
+				assert.Equal(t, tt.expectedMessage, message)
+			} else {
+				assert.NotEqual(t, uuid.Nil, message.MessageUUID)
+				assert.Equal(t, deviceUUID, message.SenderDeviceUUID)
+				assert.Equal(t, tt.clientUUID, message.ClientMessageUUID)
+				assert.False(t, message.SentAt.IsZero())
+			}
+		})
+	}
+}
+
+func TestMessageService_ListLastMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMW := NewMockRoomMessageWriter(ctrl)
+	mockMR := NewMockRoomMessageReader(ctrl)
+	svc := NewMessageService(mockMW, mockMR, NewMockRoomReader(ctrl), NewMockRoomMemberReader(ctrl))
+	roomUUID := uuid.New()
+	ctx := context.Background()
+
+	messages := []models.RoomMessageDB{
+		{MessageUUID: uuid.New(), RoomUUID: roomUUID, Ciphertext: "first"},
+		{MessageUUID: uuid.New(), RoomUUID: roomUUID, Ciphertext: "second"},
+	}
+
+	tests := []struct {
+		name          string
+		limit         int
+		mockMessages  []models.RoomMessageDB
+		mockErr       error
+		expectedError error
+		expectedLen   int
+	}{
+		{"success", 10, messages, nil, nil, 2},
+		{"zero limit", 0, nil, nil, nil, 0},
+		{"reader error", 10, nil, errors.New("db error"), errors.New("db error"), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.limit > 0 {
+				mockMR.EXPECT().ListLast(gomock.Any(), roomUUID, tt.limit).Return(tt.mockMessages, tt.mockErr)
+			}
+
+			result, err := svc.ListLastMessages(ctx, roomUUID, tt.limit)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, result, tt.expectedLen)
+			}
+		})
+	}
+}
+
+func TestMessageService_RoomKeyEpoch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRR := NewMockRoomReader(ctrl)
+	svc := NewMessageService(NewMockRoomMessageWriter(ctrl), NewMockRoomMessageReader(ctrl), mockRR, NewMockRoomMemberReader(ctrl))
+	roomUUID := uuid.New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockRoom      *models.RoomDB
+		mockErr       error
+		expectedEpoch int64
+		expectedError error
+	}{
+		{"success", &models.RoomDB{RoomUUID: roomUUID, KeyEpoch: 3}, nil, 3, nil},
+		{"room not found", nil, nil, 0, ErrRoomNotFound},
+		{"reader error", nil, errors.New("db error"), 0, errors.New("db error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(tt.mockRoom, tt.mockErr)
+
+			epoch, err := svc.RoomKeyEpoch(ctx, roomUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedEpoch, epoch)
+			}
+		})
+	}
+}
+
+func TestMessageService_ListMessages(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockMR := NewMockRoomMessageReader(ctrl)
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	svc := NewMessageService(NewMockRoomMessageWriter(ctrl), mockMR, mockRR, mockRMR)
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	ctx := context.Background()
+
+	room := &models.RoomDB{RoomUUID: roomUUID}
+	member := &models.RoomMemberDB{RoomUUID: roomUUID, UserUUID: userUUID}
+	cursorMessage := &models.RoomMessageDB{MessageUUID: uuid.New(), RoomUUID: roomUUID, SentAt: time.Now().UTC()}
+	cursor := &models.MessageCursor{SentAt: cursorMessage.SentAt, MessageUUID: cursorMessage.MessageUUID}
+	page := []models.RoomMessageDB{
+		{MessageUUID: uuid.New(), Ciphertext: "m1"},
+		{MessageUUID: uuid.New(), Ciphertext: "m2"},
+		{MessageUUID: uuid.New(), Ciphertext: "m3"},
+	}
+
+	expectMember := func() {
+		mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+		mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+	}
+
+	tests := []struct {
+		name            string
+		before          uuid.UUID
+		after           uuid.UUID
+		limit           int
+		setup           func()
+		expectedTexts   []string
+		expectedHasMore bool
+		expectedError   error
+	}{
+		{
+			name: "latest page with default limit",
+			setup: func() {
+				expectMember()
+				mockMR.EXPECT().ListPage(gomock.Any(), roomUUID, nil, nil, DefaultMessagesPageSize+1).Return(page, nil)
+			},
+			expectedTexts: []string{"m1", "m2", "m3"},
+		},
+		{
+			name:   "older messages remain",
+			before: cursorMessage.MessageUUID,
+			limit:  2,
+			setup: func() {
+				expectMember()
+				mockMR.EXPECT().Get(gomock.Any(), cursorMessage.MessageUUID).Return(cursorMessage, nil)
+				mockMR.EXPECT().ListPage(gomock.Any(), roomUUID, cursor, nil, 3).Return(page, nil)
+			},
+			expectedTexts:   []string{"m2", "m3"},
+			expectedHasMore: true,
+		},
+		{
+			name:  "newer messages remain",
+			after: cursorMessage.MessageUUID,
+			limit: 2,
+			setup: func() {
+				expectMember()
+				mockMR.EXPECT().Get(gomock.Any(), cursorMessage.MessageUUID).Return(cursorMessage, nil)
+				mockMR.EXPECT().ListPage(gomock.Any(), roomUUID, nil, cursor, 3).Return(page, nil)
+			},
+			expectedTexts:   []string{"m1", "m2"},
+			expectedHasMore: true,
+		},
+		{
+			name:  "limit capped",
+			limit: MaxMessagesPageSize + 1,
+			setup: func() {
+				expectMember()
+				mockMR.EXPECT().ListPage(gomock.Any(), roomUUID, nil, nil, MaxMessagesPageSize+1).Return(nil, nil)
+			},
+			expectedTexts: []string{},
+		},
+		{
+			name:   "cursor not found",
+			before: cursorMessage.MessageUUID,
+			setup: func() {
+				expectMember()
+				mockMR.EXPECT().Get(gomock.Any(), cursorMessage.MessageUUID).Return(nil, nil)
+			},
+			expectedError: ErrInvalidCursor,
+		},
+		{
+			name:  "cursor from another room",
+			after: cursorMessage.MessageUUID,
+			setup: func() {
+				expectMember()
+				mockMR.EXPECT().Get(gomock.Any(), cursorMessage.MessageUUID).
+					Return(&models.RoomMessageDB{MessageUUID: cursorMessage.MessageUUID, RoomUUID: uuid.New()}, nil)
+			},
+			expectedError: ErrInvalidCursor,
+		},
+		{
+			name: "room not found",
+			setup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(nil, nil)
+			},
+			expectedError: ErrRoomNotFound,
+		},
+		{
+			name: "user not in room",
+			setup: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+			},
+			expectedError: ErrUserNotInRoom,
+		},
+		{
+			name: "reader error",
+			setup: func() {
+				expectMember()
+				mockMR.EXPECT().ListPage(gomock.Any(), roomUUID, nil, nil, DefaultMessagesPageSize+1).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+
+			messages, hasMore, err := svc.ListMessages(ctx, userUUID, roomUUID, tt.before, tt.after, tt.limit)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, messages)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedHasMore, hasMore)
+			texts := []string{}
+			for _, m := range messages {
+				texts = append(texts, m.Ciphertext)
+			}
+			assert.Equal(t, tt.expectedTexts, texts)
+		})
+	}
+}
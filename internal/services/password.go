@@ -0,0 +1,313 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Ошибки политики имён пользователей и паролей
+var (
+	// ErrInvalidUsername возвращается, если имя пользователя не соответствует правилам
+	ErrInvalidUsername = errors.New("invalid username")
+
+	// ErrWeakPassword возвращается, если пароль не соответствует политике паролей
+	ErrWeakPassword = errors.New("password does not meet the password policy")
+
+	// ErrBreachedPassword возвращается, если пароль найден в списке скомпрометированных паролей
+	ErrBreachedPassword = errors.New("password is in the list of breached passwords")
+)
+
+const (
+	// MinUsernameLength — минимальная длина имени пользователя
+	MinUsernameLength = 3
+	// MaxUsernameLength — максимальная длина имени пользователя
+	MaxUsernameLength = 32
+
+	// DefaultMinPasswordLength — минимальная длина пароля по умолчанию
+	DefaultMinPasswordLength = 8
+	// DefaultMinPasswordClasses — минимальное число классов символов в пароле по умолчанию
+	DefaultMinPasswordClasses = 2
+	// MaxPasswordBytes — максимальная длина пароля в байтах: bcrypt не учитывает байты после 72-го
+	MaxPasswordBytes = 72
+
+	// passwordClasses — число классов символов: строчные и заглавные буквы, цифры, прочие символы
+	passwordClasses = 4
+)
+
+// usernamePattern — латинские буквы, цифры, '_', '.' и '-'; первый символ — буква или цифра
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidateUsername проверяет имя пользователя: от MinUsernameLength до MaxUsernameLength символов,
+// только латинские буквы, цифры, '_', '.' и '-', начинается с буквы или цифры.
+func ValidateUsername(username string) error {
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength {
+		return fmt.Errorf("%w: username must be %d to %d characters long", ErrInvalidUsername, MinUsernameLength, MaxUsernameLength)
+	}
+	if !usernamePattern.MatchString(username) {
+		return fmt.Errorf("%w: username may contain only latin letters, digits, '_', '.' and '-' and must start with a letter or digit", ErrInvalidUsername)
+	}
+	return nil
+}
+
+// BreachedPasswords — множество SHA-1 (в верхнем регистре hex) скомпрометированных паролей.
+type BreachedPasswords map[string]struct{}
+
+// ReadBreachedPasswords читает список скомпрометированных паролей, по одному в строке.
+// Строка из 40 шестнадцатеричных символов (возможно, с суффиксом ":count", как в выгрузках
+// Have I Been Pwned) считается SHA-1 пароля, любая другая — самим паролем. Пустые строки
+// и строки, начинающиеся с '#', пропускаются.
+func ReadBreachedPasswords(r io.Reader) (BreachedPasswords, error) {
+	list := make(BreachedPasswords)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		if len(hash) == sha1.Size*2 {
+			if _, err := hex.DecodeString(hash); err == nil {
+				list[strings.ToUpper(hash)] = struct{}{}
+				continue
+			}
+		}
+		list[passwordSHA1(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// Contains сообщает, есть ли пароль в списке.
+func (b BreachedPasswords) Contains(password string) bool {
+	_, ok := b[passwordSHA1(password)]
+	return ok
+}
+
+// passwordSHA1 возвращает SHA-1 пароля в верхнем регистре hex
+func passwordSHA1(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// PasswordPolicy проверяет пароли: длину, число классов символов, отсутствие имени пользователя
+// в пароле и отсутствие пароля в списке скомпрометированных.
+type PasswordPolicy struct {
+	minLength  int               // минимальная длина пароля в символах
+	minClasses int               // минимальное число классов символов
+	breached   BreachedPasswords // скомпрометированные пароли; nil — проверка отключена
+}
+
+// PasswordPolicyOpt — функциональная опция для настройки PasswordPolicy.
+type PasswordPolicyOpt func(*PasswordPolicy)
+
+// WithMinPasswordLength задаёт минимальную длину пароля в символах.
+// Используется первое положительное значение.
+func WithMinPasswordLength(lengths ...int) PasswordPolicyOpt {
+	return func(p *PasswordPolicy) {
+		for _, l := range lengths {
+			if l > 0 {
+				p.minLength = l
+				return
+			}
+		}
+	}
+}
+
+// WithMinPasswordClasses задаёт, сколько из четырёх классов символов (строчные буквы, заглавные буквы,
+// цифры, прочие символы) должен содержать пароль. Используется первое значение от 1 до 4.
+func WithMinPasswordClasses(classes ...int) PasswordPolicyOpt {
+	return func(p *PasswordPolicy) {
+		for _, c := range classes {
+			if c > 0 && c <= passwordClasses {
+				p.minClasses = c
+				return
+			}
+		}
+	}
+}
+
+// WithBreachedPasswords задаёт список скомпрометированных паролей.
+// Используется первое значение, отличное от nil.
+func WithBreachedPasswords(lists ...BreachedPasswords) PasswordPolicyOpt {
+	return func(p *PasswordPolicy) {
+		for _, l := range lists {
+			if l != nil {
+				p.breached = l
+				return
+			}
+		}
+	}
+}
+
+// NewPasswordPolicy создаёт политику паролей. По умолчанию пароль должен содержать не менее
+// DefaultMinPasswordLength символов DefaultMinPasswordClasses классов, список скомпрометированных паролей не задан.
+func NewPasswordPolicy(opts ...PasswordPolicyOpt) *PasswordPolicy {
+	p := &PasswordPolicy{
+		minLength:  DefaultMinPasswordLength,
+		minClasses: DefaultMinPasswordClasses,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Validate проверяет пароль пользователя username. Возвращает ошибку, оборачивающую ErrWeakPassword
+// с описанием нарушенного правила, или ErrBreachedPassword.
+func (p *PasswordPolicy) Validate(username string, password string) error {
+	if utf8.RuneCountInString(password) < p.minLength {
+		return fmt.Errorf("%w: password must be at least %d characters long", ErrWeakPassword, p.minLength)
+	}
+	if len(password) > MaxPasswordBytes {
+		return fmt.Errorf("%w: password must be at most %d bytes long", ErrWeakPassword, MaxPasswordBytes)
+	}
+	if passwordClassCount(password) < p.minClasses {
+		return fmt.Errorf("%w: password must contain at least %d of: lowercase letters, uppercase letters, digits, other characters",
+			ErrWeakPassword, p.minClasses)
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		return fmt.Errorf("%w: password must not contain the username", ErrWeakPassword)
+	}
+	if p.breached.Contains(password) {
+		return ErrBreachedPassword
+	}
+	return nil
+}
+
+// passwordClassCount возвращает число классов символов, встречающихся в пароле
+func passwordClassCount(password string) int {
+	var lower, upper, digit, other bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			other = true
+		}
+	}
+
+	count := 0
+	for _, present := range []bool{lower, upper, digit, other} {
+		if present {
+			count++
+		}
+	}
+	return count
+}
+
+// PasswordService реализует смену пароля пользователя.
+type PasswordService struct {
+	ur UserReader           // репозиторий для получения пользователя по UUID
+	us UserSaver            // репозиторий для сохранения нового хэша пароля
+	sr DeviceSessionRevoker // сервис отзыва сессий
+	si SessionIssuer        // сервис выдачи токенов новой сессии
+	pp *PasswordPolicy      // политика паролей
+	lt LoginThrottler       // ограничение попыток проверки текущего пароля; nil — попытки не ограничиваются
+}
+
+// NewPasswordService создаёт новый экземпляр PasswordService.
+// Проверка текущего пароля ограничивается тем же lt, что и вход, поэтому перебор через смену пароля
+// блокируется вместе со входом.
+func NewPasswordService(
+	ur UserReader,
+	us UserSaver,
+	sr DeviceSessionRevoker,
+	si SessionIssuer,
+	pp *PasswordPolicy,
+	lt LoginThrottler,
+) *PasswordService {
+	return &PasswordService{ur: ur, us: us, sr: sr, si: si, pp: pp, lt: lt}
+}
+
+// ChangePassword меняет пароль пользователя после проверки текущего пароля и политики паролей.
+// Все сессии пользователя, включая текущую, отзываются, а для устройства deviceUUID начинается новая сессия,
+// токены которой возвращаются: так смена пароля завершает сеансы на всех остальных устройствах.
+// Если текущий пароль неверен, возвращает ErrInvalidCredentials; если попытки по имени пользователя
+// или с IP-адреса ip временно запрещены, возвращает *TooManyAttemptsError.
+func (svc *PasswordService) ChangePassword(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	currentPassword string,
+	newPassword string,
+	ip string,
+) (token string, refreshToken string, err error) {
+	user, err := svc.ur.GetByUUID(ctx, userUUID)
+	if err != nil {
+		return "", "", err
+	}
+	if user == nil {
+		return "", "", ErrInvalidCredentials
+	}
+
+	if err := svc.checkCurrentPassword(ctx, user.Username, user.PasswordHash, currentPassword, ip); err != nil {
+		return "", "", err
+	}
+
+	if newPassword == currentPassword {
+		return "", "", fmt.Errorf("%w: new password must differ from the current one", ErrWeakPassword)
+	}
+	if err := svc.pp.Validate(user.Username, newPassword); err != nil {
+		return "", "", err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return "", "", err
+	}
+
+	if err := svc.us.Save(ctx, user.UserUUID, user.Username, string(hashedPassword)); err != nil {
+		return "", "", err
+	}
+
+	if err := svc.sr.RevokeSessions(ctx, user.UserUUID, uuid.Nil); err != nil {
+		return "", "", err
+	}
+
+	return svc.si.IssueSession(ctx, user.UserUUID, deviceUUID)
+}
+
+// checkCurrentPassword сверяет текущий пароль с bcrypt-хэшем с учётом ограничения попыток входа.
+func (svc *PasswordService) checkCurrentPassword(ctx context.Context, username string, passwordHash string, password string, ip string) error {
+	if svc.lt == nil {
+		if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+			return ErrInvalidCredentials
+		}
+		return nil
+	}
+
+	if err := svc.lt.Begin(username, ip); err != nil {
+		return err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte(password)); err != nil {
+		if err := svc.lt.Fail(ctx, username, ip); err != nil {
+			return err
+		}
+		return ErrInvalidCredentials
+	}
+	svc.lt.Succeed(username, ip)
+
+	return nil
+}
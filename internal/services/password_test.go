@@ -0,0 +1,230 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestValidateUsername(t *testing.T) {
+	valid := []string{"bob", "john.doe", "user_42", "a-b", strings.Repeat("a", MaxUsernameLength)}
+	for _, username := range valid {
+		assert.NoError(t, ValidateUsername(username), username)
+	}
+
+	invalid := []string{"", "ab", strings.Repeat("a", MaxUsernameLength+1), "_bob", ".bob", "john doe", "иван", "bob!"}
+	for _, username := range invalid {
+		assert.ErrorIs(t, ValidateUsername(username), ErrInvalidUsername, username)
+	}
+}
+
+func TestReadBreachedPasswords(t *testing.T) {
+	input := strings.Join([]string{
+		"# список утёкших паролей",
+		"",
+		"qwerty123",
+		// SHA-1 от "password1" в формате выгрузки с числом утечек
+		"e38ad214943daad1d64c102faec29de4afe9da3d:2413945",
+		"P@ssw0rd!",
+	}, "\n")
+
+	list, err := ReadBreachedPasswords(strings.NewReader(input))
+	require.NoError(t, err)
+	assert.Len(t, list, 3)
+
+	assert.True(t, list.Contains("qwerty123"))
+	assert.True(t, list.Contains("password1"))
+	assert.True(t, list.Contains("P@ssw0rd!"))
+	assert.False(t, list.Contains("correct horse battery staple"))
+
+	var empty BreachedPasswords
+	assert.False(t, empty.Contains("qwerty123"))
+}
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	breached, err := ReadBreachedPasswords(strings.NewReader("Summer2024"))
+	require.NoError(t, err)
+
+	defaults := NewPasswordPolicy()
+	strict := NewPasswordPolicy(
+		WithMinPasswordLength(0, 12),
+		WithMinPasswordClasses(5, 3),
+		WithBreachedPasswords(nil, breached),
+	)
+
+	tests := []struct {
+		name        string
+		policy      *PasswordPolicy
+		password    string
+		expectedErr error
+	}{
+		{name: "default ok", policy: defaults, password: "secret-42"},
+		{name: "default too short", policy: defaults, password: "abc-12", expectedErr: ErrWeakPassword},
+		{name: "default single class", policy: defaults, password: "abcdefghij", expectedErr: ErrWeakPassword},
+		{name: "too long for bcrypt", policy: defaults, password: strings.Repeat("a1", 37), expectedErr: ErrWeakPassword},
+		{name: "contains username", policy: defaults, password: "JohnDoe-2024", expectedErr: ErrWeakPassword},
+		{name: "strict ok", policy: strict, password: "Correct-horse-42"},
+		{name: "strict too short", policy: strict, password: "Short-42", expectedErr: ErrWeakPassword},
+		{name: "strict two classes", policy: strict, password: "correcthorse42", expectedErr: ErrWeakPassword},
+		{name: "breached", policy: NewPasswordPolicy(WithBreachedPasswords(breached)), password: "Summer2024", expectedErr: ErrBreachedPassword},
+		{name: "multibyte length counted in characters", policy: defaults, password: "пароль-1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate("johndoe", tt.password)
+			if tt.expectedErr == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.expectedErr)
+		})
+	}
+}
+
+func TestPasswordService_ChangePassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := NewMockUserReader(ctrl)
+	mockUS := NewMockUserSaver(ctrl)
+	mockSR := NewMockDeviceSessionRevoker(ctrl)
+	mockSI := NewMockSessionIssuer(ctrl)
+	svc := NewPasswordService(mockUR, mockUS, mockSR, mockSI, NewPasswordPolicy(), nil)
+
+	ctx := context.Background()
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-secret-1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.UserDB{UserUUID: userUUID, Username: "johndoe", PasswordHash: string(hash)}
+
+	tests := []struct {
+		name            string
+		currentPassword string
+		newPassword     string
+		setup           func()
+		expectedErr     error
+	}{
+		{
+			name:            "success",
+			currentPassword: "old-secret-1",
+			newPassword:     "new-secret-2",
+			setup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), userUUID).Return(user, nil)
+				mockUS.EXPECT().Save(gomock.Any(), userUUID, "johndoe", gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ uuid.UUID, _ string, passwordHash string) error {
+						assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte("new-secret-2")))
+						return nil
+					})
+				gomock.InOrder(
+					mockSR.EXPECT().RevokeSessions(gomock.Any(), userUUID, uuid.Nil).Return(nil),
+					mockSI.EXPECT().IssueSession(gomock.Any(), userUUID, deviceUUID).Return("token", "refresh", nil),
+				)
+			},
+		},
+		{
+			name:            "user not found",
+			currentPassword: "old-secret-1",
+			newPassword:     "new-secret-2",
+			setup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), userUUID).Return(nil, nil)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:            "wrong current password",
+			currentPassword: "wrong-secret-1",
+			newPassword:     "new-secret-2",
+			setup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), userUUID).Return(user, nil)
+			},
+			expectedErr: ErrInvalidCredentials,
+		},
+		{
+			name:            "same password",
+			currentPassword: "old-secret-1",
+			newPassword:     "old-secret-1",
+			setup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), userUUID).Return(user, nil)
+			},
+			expectedErr: ErrWeakPassword,
+		},
+		{
+			name:            "weak new password",
+			currentPassword: "old-secret-1",
+			newPassword:     "short",
+			setup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), userUUID).Return(user, nil)
+			},
+			expectedErr: ErrWeakPassword,
+		},
+		{
+			name:            "revoke error",
+			currentPassword: "old-secret-1",
+			newPassword:     "new-secret-2",
+			setup: func() {
+				mockUR.EXPECT().GetByUUID(gomock.Any(), userUUID).Return(user, nil)
+				mockUS.EXPECT().Save(gomock.Any(), userUUID, "johndoe", gomock.Any()).Return(nil)
+				mockSR.EXPECT().RevokeSessions(gomock.Any(), userUUID, uuid.Nil).Return(errors.New("db error"))
+			},
+			expectedErr: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			token, refreshToken, err := svc.ChangePassword(ctx, userUUID, deviceUUID, tt.currentPassword, tt.newPassword, "203.0.113.7")
+			if tt.expectedErr != nil {
+				assert.ErrorContains(t, err, tt.expectedErr.Error())
+				assert.Empty(t, token)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "token", token)
+			assert.Equal(t, "refresh", refreshToken)
+		})
+	}
+}
+
+func TestPasswordService_ChangePasswordThrottled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUR := NewMockUserReader(ctrl)
+	mockLW := NewMockLoginLockoutWriter(ctrl)
+	throttle, _ := newTestThrottle(t, mockLW, WithLoginFreeAttempts(1), WithLoginLockoutThreshold(2))
+	svc := NewPasswordService(mockUR, nil, nil, nil, NewPasswordPolicy(), throttle)
+
+	ctx := context.Background()
+	userUUID := uuid.New()
+	ip := "203.0.113.7"
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-secret-1"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.UserDB{UserUUID: userUUID, Username: "johndoe", PasswordHash: string(hash)}
+
+	mockUR.EXPECT().GetByUUID(gomock.Any(), userUUID).Return(user, nil).Times(3)
+	mockLW.EXPECT().
+		Save(gomock.Any(), gomock.Any(), models.LoginLockoutScopeUsername, "johndoe", 2, gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	for i := 0; i < 2; i++ {
+		_, _, err := svc.ChangePassword(ctx, userUUID, uuid.New(), "wrong-secret-1", "new-secret-2", ip)
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	}
+
+	// после блокировки даже верный текущий пароль не проверяется
+	_, _, err = svc.ChangePassword(ctx, userUUID, uuid.New(), "old-secret-1", "new-secret-2", ip)
+	assert.ErrorIs(t, err, ErrTooManyAttempts)
+}
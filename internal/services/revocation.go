@@ -0,0 +1,308 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// ErrInvalidLogoutScope возвращается при неизвестной области действия выхода из аккаунта.
+var ErrInvalidLogoutScope = errors.New("invalid logout scope")
+
+// TokenRevocationWriter описывает интерфейс для сохранения отозванных токенов и сессий.
+type TokenRevocationWriter interface {
+	// SaveToken сохраняет отозванный токен до момента его истечения.
+	SaveToken(ctx context.Context, tokenID string, userUUID uuid.UUID, deviceUUID uuid.UUID, expiresAt time.Time) error
+	// SaveSession сохраняет момент отзыва всех сессий пользователя на устройстве (uuid.Nil — на всех устройствах).
+	SaveSession(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID, revokedAt time.Time) error
+	// DeleteExpiredTokens удаляет отозванные токены, истёкшие до момента before.
+	DeleteExpiredTokens(ctx context.Context, before time.Time) error
+}
+
+// TokenRevocationReader описывает интерфейс для чтения отозванных токенов и сессий.
+type TokenRevocationReader interface {
+	// ListTokens возвращает отозванные токены, не истёкшие к моменту after.
+	ListTokens(ctx context.Context, after time.Time) ([]models.RevokedTokenDB, error)
+	// ListSessions возвращает все записи об отзыве сессий.
+	ListSessions(ctx context.Context) ([]models.SessionRevocationDB, error)
+}
+
+// RefreshTokenRevoker описывает интерфейс для отзыва refresh-токенов.
+type RefreshTokenRevoker interface {
+	// RevokeSession отзывает все refresh-токены сессии.
+	RevokeSession(ctx context.Context, sessionUUID uuid.UUID) error
+	// RevokeDevice отзывает все refresh-токены пользователя на устройстве.
+	RevokeDevice(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID) error
+	// RevokeUser отзывает все refresh-токены пользователя.
+	RevokeUser(ctx context.Context, userUUID uuid.UUID) error
+	// DeleteExpired удаляет refresh-токены, истёкшие до момента before.
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+// SessionDisconnector описывает интерфейс закрытия живых соединений WebSocket пользователя.
+type SessionDisconnector interface {
+	// Disconnect закрывает соединения пользователя userUUID с причиной reason: на устройстве deviceUUID
+	// (uuid.Nil — на всех устройствах) в комнате roomUUID (uuid.Nil — во всех комнатах).
+	Disconnect(roomUUID uuid.UUID, userUUID uuid.UUID, deviceUUID uuid.UUID, reason string)
+}
+
+// sessionKey — ключ кэша отозванных сессий.
+type sessionKey struct {
+	userUUID   uuid.UUID
+	deviceUUID uuid.UUID
+}
+
+// RevocationService реализует отзыв токенов и сессий.
+// Отозванные токены хранятся в базе данных и кэшируются в памяти,
+// поэтому проверка токена при каждом запросе не обращается к базе.
+type RevocationService struct {
+	rw TokenRevocationWriter // репозиторий для записи отозванных токенов
+	rr TokenRevocationReader // репозиторий для чтения отозванных токенов
+	rt RefreshTokenRevoker   // репозиторий refresh-токенов
+	sd SessionDisconnector   // закрытие соединений отозванных сессий; nil — соединения не закрываются
+
+	mu       sync.RWMutex
+	tokens   map[string]time.Time     // jti -> время истечения токена
+	sessions map[sessionKey]time.Time // пользователь/устройство -> момент отзыва сессий
+}
+
+// RevocationOpt — функциональная опция для настройки RevocationService.
+type RevocationOpt func(*RevocationService)
+
+// WithSessionDisconnector задаёт закрытие живых соединений WebSocket при выходе и отзыве сессий:
+// проверка токена выполняется только при подключении, поэтому без него открытое соединение
+// продолжает работать после отзыва. Используется первое непустое значение.
+func WithSessionDisconnector(disconnectors ...SessionDisconnector) RevocationOpt {
+	return func(svc *RevocationService) {
+		for _, sd := range disconnectors {
+			if sd != nil {
+				svc.sd = sd
+				return
+			}
+		}
+	}
+}
+
+// NewRevocationService создаёт новый экземпляр RevocationService с указанными репозиториями.
+func NewRevocationService(
+	rw TokenRevocationWriter,
+	rr TokenRevocationReader,
+	rt RefreshTokenRevoker,
+	opts ...RevocationOpt,
+) *RevocationService {
+	svc := &RevocationService{
+		rw:       rw,
+		rr:       rr,
+		rt:       rt,
+		tokens:   make(map[string]time.Time),
+		sessions: make(map[sessionKey]time.Time),
+	}
+	for _, opt := range opts {
+		opt(svc)
+	}
+	return svc
+}
+
+// Logout отзывает текущий токен и, в зависимости от scope, все сессии устройства или пользователя.
+// Текущий токен и refresh-токены его сессии sessionUUID отзываются всегда, а соединения WebSocket
+// устройства закрываются, поэтому сессия перестаёт действовать сразу после ответа.
+func (svc *RevocationService) Logout(
+	ctx context.Context,
+	tokenID string,
+	sessionUUID uuid.UUID,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	expiresAt time.Time,
+	scope string,
+) error {
+	switch scope {
+	case models.LogoutScopeToken:
+		if sessionUUID != uuid.Nil {
+			if err := svc.rt.RevokeSession(ctx, sessionUUID); err != nil {
+				return err
+			}
+		}
+	case models.LogoutScopeDevice:
+		if err := svc.RevokeSessions(ctx, userUUID, deviceUUID); err != nil {
+			return err
+		}
+	case models.LogoutScopeUser:
+		if err := svc.RevokeSessions(ctx, userUUID, uuid.Nil); err != nil {
+			return err
+		}
+	default:
+		return ErrInvalidLogoutScope
+	}
+
+	if err := svc.RevokeToken(ctx, tokenID, userUUID, deviceUUID, expiresAt); err != nil {
+		return err
+	}
+	if scope == models.LogoutScopeToken {
+		svc.disconnect(userUUID, deviceUUID)
+	}
+	return nil
+}
+
+// RevokeToken отзывает один токен по его идентификатору.
+func (svc *RevocationService) RevokeToken(
+	ctx context.Context,
+	tokenID string,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	expiresAt time.Time,
+) error {
+	if err := svc.rw.SaveToken(ctx, tokenID, userUUID, deviceUUID, expiresAt); err != nil {
+		return err
+	}
+
+	svc.mu.Lock()
+	svc.tokens[tokenID] = expiresAt
+	svc.mu.Unlock()
+
+	return nil
+}
+
+// RevokeSessions отзывает все сессии пользователя на устройстве (uuid.Nil — на всех устройствах):
+// refresh-токены, access-токены, выданные не позже текущего момента, и открытые соединения WebSocket.
+// Момент отзыва хранится с точностью до микросекунды, как и время выдачи токена.
+func (svc *RevocationService) RevokeSessions(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID) error {
+	revokedAt := time.Now().UTC().Truncate(time.Microsecond)
+
+	if deviceUUID == uuid.Nil {
+		if err := svc.rt.RevokeUser(ctx, userUUID); err != nil {
+			return err
+		}
+	} else {
+		if err := svc.rt.RevokeDevice(ctx, userUUID, deviceUUID); err != nil {
+			return err
+		}
+	}
+
+	if err := svc.rw.SaveSession(ctx, userUUID, deviceUUID, revokedAt); err != nil {
+		return err
+	}
+
+	svc.mu.Lock()
+	svc.sessions[sessionKey{userUUID, deviceUUID}] = revokedAt
+	svc.mu.Unlock()
+
+	svc.disconnect(userUUID, deviceUUID)
+	return nil
+}
+
+// disconnect закрывает соединения WebSocket пользователя на устройстве (uuid.Nil — на всех устройствах)
+// во всех комнатах
+func (svc *RevocationService) disconnect(userUUID uuid.UUID, deviceUUID uuid.UUID) {
+	if svc.sd != nil {
+		svc.sd.Disconnect(uuid.Nil, userUUID, deviceUUID, "session revoked")
+	}
+}
+
+// IsRevoked сообщает, отозван ли токен: отдельно по jti или вместе со всеми сессиями
+// устройства либо пользователя. Токен, выданный в момент отзыва сессий, тоже считается отозванным.
+func (svc *RevocationService) IsRevoked(
+	tokenID string,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	issuedAt time.Time,
+) bool {
+	svc.mu.RLock()
+	defer svc.mu.RUnlock()
+
+	if _, ok := svc.tokens[tokenID]; ok {
+		return true
+	}
+
+	for _, key := range []sessionKey{{userUUID, deviceUUID}, {userUUID, uuid.Nil}} {
+		if revokedAt, ok := svc.sessions[key]; ok && !issuedAt.After(revokedAt) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Load загружает в кэш отозванные токены и сессии из базы данных.
+// Позволяет нескольким экземплярам сервера видеть отзывы, выполненные друг другом.
+// Снимок базы объединяется с кэшем, а не заменяет его: отзыв, выполненный после чтения снимка,
+// не теряется. Для сессий сохраняется более поздний момент отзыва.
+func (svc *RevocationService) Load(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	tokens, err := svc.rr.ListTokens(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	sessions, err := svc.rr.ListSessions(ctx)
+	if err != nil {
+		return err
+	}
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+
+	for _, t := range tokens {
+		if expiresAt, ok := svc.tokens[t.TokenID]; !ok || t.ExpiresAt.After(expiresAt) {
+			svc.tokens[t.TokenID] = t.ExpiresAt
+		}
+	}
+
+	for _, s := range sessions {
+		key := sessionKey{s.UserUUID, s.DeviceUUID}
+		if revokedAt, ok := svc.sessions[key]; !ok || s.RevokedAt.After(revokedAt) {
+			svc.sessions[key] = s.RevokedAt
+		}
+	}
+
+	return nil
+}
+
+// Cleanup удаляет истёкшие отозванные токены и refresh-токены из базы данных и кэша:
+// такие токены и так не пройдут проверку срока действия.
+func (svc *RevocationService) Cleanup(ctx context.Context) error {
+	now := time.Now().UTC()
+
+	if err := svc.rw.DeleteExpiredTokens(ctx, now); err != nil {
+		return err
+	}
+
+	if err := svc.rt.DeleteExpired(ctx, now); err != nil {
+		return err
+	}
+
+	svc.mu.Lock()
+	for tokenID, expiresAt := range svc.tokens {
+		if expiresAt.Before(now) {
+			delete(svc.tokens, tokenID)
+		}
+	}
+	svc.mu.Unlock()
+
+	return nil
+}
+
+// Run периодически очищает истёкшие токены и перезагружает кэш до отмены контекста.
+func (svc *RevocationService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := svc.Cleanup(ctx); err != nil {
+				log.Printf("failed to cleanup revoked tokens: %v", err)
+			}
+			if err := svc.Load(ctx); err != nil {
+				log.Printf("failed to reload revoked tokens: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationService_Logout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	sessionUUID := uuid.New()
+	otherDevice := uuid.New()
+	expiresAt := time.Now().Add(time.Hour)
+	issuedBefore := time.Now().Add(-time.Minute)
+	ctx := context.Background()
+
+	tests := []struct {
+		name               string
+		scope              string
+		setupMocks         func(rw *MockTokenRevocationWriter, rt *MockRefreshTokenRevoker, sd *MockSessionDisconnector)
+		expectedError      error
+		sameDeviceRevoked  bool
+		otherDeviceRevoked bool
+	}{
+		{
+			name:  "token scope",
+			scope: models.LogoutScopeToken,
+			setupMocks: func(rw *MockTokenRevocationWriter, rt *MockRefreshTokenRevoker, sd *MockSessionDisconnector) {
+				rt.EXPECT().RevokeSession(gomock.Any(), sessionUUID).Return(nil)
+				rw.EXPECT().SaveToken(gomock.Any(), "jti", userUUID, deviceUUID, expiresAt).Return(nil)
+				sd.EXPECT().Disconnect(uuid.Nil, userUUID, deviceUUID, gomock.Any())
+			},
+		},
+		{
+			name:  "device scope",
+			scope: models.LogoutScopeDevice,
+			setupMocks: func(rw *MockTokenRevocationWriter, rt *MockRefreshTokenRevoker, sd *MockSessionDisconnector) {
+				rt.EXPECT().RevokeDevice(gomock.Any(), userUUID, deviceUUID).Return(nil)
+				rw.EXPECT().SaveSession(gomock.Any(), userUUID, deviceUUID, gomock.Any()).Return(nil)
+				sd.EXPECT().Disconnect(uuid.Nil, userUUID, deviceUUID, gomock.Any())
+				rw.EXPECT().SaveToken(gomock.Any(), "jti", userUUID, deviceUUID, expiresAt).Return(nil)
+			},
+			sameDeviceRevoked: true,
+		},
+		{
+			name:  "user scope",
+			scope: models.LogoutScopeUser,
+			setupMocks: func(rw *MockTokenRevocationWriter, rt *MockRefreshTokenRevoker, sd *MockSessionDisconnector) {
+				rt.EXPECT().RevokeUser(gomock.Any(), userUUID).Return(nil)
+				rw.EXPECT().SaveSession(gomock.Any(), userUUID, uuid.Nil, gomock.Any()).Return(nil)
+				sd.EXPECT().Disconnect(uuid.Nil, userUUID, uuid.Nil, gomock.Any())
+				rw.EXPECT().SaveToken(gomock.Any(), "jti", userUUID, deviceUUID, expiresAt).Return(nil)
+			},
+			sameDeviceRevoked:  true,
+			otherDeviceRevoked: true,
+		},
+		{
+			name:  "revoke refresh session error",
+			scope: models.LogoutScopeToken,
+			setupMocks: func(rw *MockTokenRevocationWriter, rt *MockRefreshTokenRevoker, sd *MockSessionDisconnector) {
+				rt.EXPECT().RevokeSession(gomock.Any(), sessionUUID).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name:          "invalid scope",
+			scope:         "everything",
+			setupMocks:    func(rw *MockTokenRevocationWriter, rt *MockRefreshTokenRevoker, sd *MockSessionDisconnector) {},
+			expectedError: ErrInvalidLogoutScope,
+		},
+		{
+			name:  "save session error",
+			scope: models.LogoutScopeDevice,
+			setupMocks: func(rw *MockTokenRevocationWriter, rt *MockRefreshTokenRevoker, sd *MockSessionDisconnector) {
+				rt.EXPECT().RevokeDevice(gomock.Any(), userUUID, deviceUUID).Return(nil)
+				rw.EXPECT().SaveSession(gomock.Any(), userUUID, deviceUUID, gomock.Any()).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name:  "save token error",
+			scope: models.LogoutScopeToken,
+			setupMocks: func(rw *MockTokenRevocationWriter, rt *MockRefreshTokenRevoker, sd *MockSessionDisconnector) {
+				rt.EXPECT().RevokeSession(gomock.Any(), sessionUUID).Return(nil)
+				rw.EXPECT().SaveToken(gomock.Any(), "jti", userUUID, deviceUUID, expiresAt).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRW := NewMockTokenRevocationWriter(ctrl)
+			mockRR := NewMockTokenRevocationReader(ctrl)
+			mockRT := NewMockRefreshTokenRevoker(ctrl)
+			mockSD := NewMockSessionDisconnector(ctrl)
+			tt.setupMocks(mockRW, mockRT, mockSD)
+
+			svc := NewRevocationService(mockRW, mockRR, mockRT, WithSessionDisconnector(mockSD))
+			err := svc.Logout(ctx, "jti", sessionUUID, userUUID, deviceUUID, expiresAt, tt.scope)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.False(t, svc.IsRevoked("jti", userUUID, deviceUUID, time.Now()))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, svc.IsRevoked("jti", userUUID, deviceUUID, time.Now()))
+			assert.Equal(t, tt.sameDeviceRevoked, svc.IsRevoked("other", userUUID, deviceUUID, issuedBefore))
+			assert.Equal(t, tt.otherDeviceRevoked, svc.IsRevoked("other", userUUID, otherDevice, issuedBefore))
+
+			// токены, выданные после отзыва сессий, остаются действительными
+			assert.False(t, svc.IsRevoked("fresh", userUUID, deviceUUID, time.Now().Add(time.Microsecond)))
+		})
+	}
+}
+
+func TestRevocationService_Load(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	revokedAt := time.Now().Add(-time.Minute)
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		tokensErr     error
+		sessionsErr   error
+		expectedError error
+	}{
+		{"success", nil, nil, nil},
+		{"list tokens error", errors.New("db error"), nil, errors.New("db error")},
+		{"list sessions error", nil, errors.New("db error"), errors.New("db error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRW := NewMockTokenRevocationWriter(ctrl)
+			mockRR := NewMockTokenRevocationReader(ctrl)
+
+			mockRR.EXPECT().ListTokens(gomock.Any(), gomock.Any()).Return(
+				[]models.RevokedTokenDB{{TokenID: "jti", UserUUID: userUUID, DeviceUUID: deviceUUID, ExpiresAt: time.Now().Add(time.Hour)}},
+				tt.tokensErr,
+			)
+			if tt.tokensErr == nil {
+				mockRR.EXPECT().ListSessions(gomock.Any()).Return(
+					[]models.SessionRevocationDB{{UserUUID: userUUID, DeviceUUID: deviceUUID, RevokedAt: revokedAt}},
+					tt.sessionsErr,
+				)
+			}
+
+			svc := NewRevocationService(mockRW, mockRR, nil)
+			err := svc.Load(ctx)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.False(t, svc.IsRevoked("jti", userUUID, deviceUUID, time.Now()))
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.True(t, svc.IsRevoked("jti", userUUID, deviceUUID, time.Now()))
+			assert.True(t, svc.IsRevoked("other", userUUID, deviceUUID, revokedAt.Add(-time.Second)))
+			assert.False(t, svc.IsRevoked("other", userUUID, deviceUUID, revokedAt.Add(time.Second)))
+		})
+	}
+}
+
+func TestRevocationService_LoadKeepsConcurrentRevocations(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockTokenRevocationWriter(ctrl)
+	mockRR := NewMockTokenRevocationReader(ctrl)
+	mockRT := NewMockRefreshTokenRevoker(ctrl)
+	svc := NewRevocationService(mockRW, mockRR, mockRT)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	loadedUUID := uuid.New()
+	issuedAt := time.Now().UTC()
+
+	mockRR.EXPECT().ListTokens(gomock.Any(), gomock.Any()).Return(
+		[]models.RevokedTokenDB{{TokenID: "loaded", UserUUID: loadedUUID, DeviceUUID: deviceUUID, ExpiresAt: time.Now().Add(time.Hour)}},
+		nil,
+	)
+	// Выход из аккаунта завершается после чтения снимка, но до обновления кэша
+	mockRR.EXPECT().ListSessions(gomock.Any()).DoAndReturn(func(ctx context.Context) ([]models.SessionRevocationDB, error) {
+		mockRW.EXPECT().SaveToken(gomock.Any(), "jti", userUUID, deviceUUID, gomock.Any()).Return(nil)
+		mockRT.EXPECT().RevokeDevice(gomock.Any(), userUUID, deviceUUID).Return(nil)
+		mockRW.EXPECT().SaveSession(gomock.Any(), userUUID, deviceUUID, gomock.Any()).Return(nil)
+		assert.NoError(t, svc.RevokeToken(ctx, "jti", userUUID, deviceUUID, time.Now().Add(time.Hour)))
+		assert.NoError(t, svc.RevokeSessions(ctx, userUUID, deviceUUID))
+		// Снимок содержит более ранний отзыв сессий того же устройства
+		return []models.SessionRevocationDB{{UserUUID: userUUID, DeviceUUID: deviceUUID, RevokedAt: issuedAt.Add(-time.Hour)}}, nil
+	})
+
+	assert.NoError(t, svc.Load(ctx))
+
+	assert.True(t, svc.IsRevoked("jti", userUUID, deviceUUID, time.Now()))
+	assert.True(t, svc.IsRevoked("other", userUUID, deviceUUID, issuedAt))
+	assert.True(t, svc.IsRevoked("loaded", loadedUUID, deviceUUID, time.Now()))
+}
+
+func TestRevocationService_IsRevokedWithinSecond(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	revokedAt := time.Date(2026, 1, 2, 3, 4, 5, 500_000_000, time.UTC)
+
+	mockRR := NewMockTokenRevocationReader(ctrl)
+	mockRR.EXPECT().ListTokens(gomock.Any(), gomock.Any()).Return(nil, nil)
+	mockRR.EXPECT().ListSessions(gomock.Any()).Return(
+		[]models.SessionRevocationDB{{UserUUID: userUUID, DeviceUUID: deviceUUID, RevokedAt: revokedAt}},
+		nil,
+	)
+
+	svc := NewRevocationService(nil, mockRR, nil)
+	assert.NoError(t, svc.Load(context.Background()))
+
+	// Токен, выданный в ту же секунду до отзыва или в момент отзыва, отозван
+	assert.True(t, svc.IsRevoked("early", userUUID, deviceUUID, revokedAt.Add(-400*time.Millisecond)))
+	assert.True(t, svc.IsRevoked("same", userUUID, deviceUUID, revokedAt))
+	// Токен, выданный в ту же секунду после отзыва, действителен
+	assert.False(t, svc.IsRevoked("late", userUUID, deviceUUID, revokedAt.Add(time.Microsecond)))
+}
+
+func TestRevocationService_Cleanup(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockTokenRevocationWriter(ctrl)
+	mockRR := NewMockTokenRevocationReader(ctrl)
+	mockRT := NewMockRefreshTokenRevoker(ctrl)
+	svc := NewRevocationService(mockRW, mockRR, mockRT)
+	ctx := context.Background()
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+
+	mockRW.EXPECT().SaveToken(gomock.Any(), gomock.Any(), userUUID, deviceUUID, gomock.Any()).Return(nil).Times(2)
+	assert.NoError(t, svc.RevokeToken(ctx, "expired", userUUID, deviceUUID, time.Now().Add(-time.Minute)))
+	assert.NoError(t, svc.RevokeToken(ctx, "active", userUUID, deviceUUID, time.Now().Add(time.Hour)))
+
+	mockRW.EXPECT().DeleteExpiredTokens(gomock.Any(), gomock.Any()).Return(nil)
+	mockRT.EXPECT().DeleteExpired(gomock.Any(), gomock.Any()).Return(nil)
+	assert.NoError(t, svc.Cleanup(ctx))
+	assert.False(t, svc.IsRevoked("expired", userUUID, deviceUUID, time.Now()))
+	assert.True(t, svc.IsRevoked("active", userUUID, deviceUUID, time.Now()))
+
+	mockRW.EXPECT().DeleteExpiredTokens(gomock.Any(), gomock.Any()).Return(errors.New("db error"))
+	assert.EqualError(t, svc.Cleanup(ctx), "db error")
+}
@@ -0,0 +1,212 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// ErrRoomKeyNotFound возвращается, если для устройства ещё не загружен ключ комнаты.
+var ErrRoomKeyNotFound = errors.New("room key not found")
+
+// ErrDeviceNotInRoom возвращается при загрузке ключа для устройства, не принадлежащего участнику комнаты.
+var ErrDeviceNotInRoom = errors.New("device does not belong to a room member")
+
+// ErrStaleKeyEpoch возвращается при загрузке ключа не для текущей эпохи комнаты.
+var ErrStaleKeyEpoch = errors.New("stale room key epoch")
+
+// ErrInvalidRoomKeySignature возвращается, если ключ комнаты не подписан ключом подписи загружающего устройства.
+var ErrInvalidRoomKeySignature = errors.New("invalid room key signature")
+
+// ErrRoomKeyConflict возвращается, если ключ эпохи уже выпущен другим устройством:
+// загружающему нужно получить сохранённый ключ эпохи и использовать его.
+var ErrRoomKeyConflict = errors.New("room key epoch already issued")
+
+// RoomKeyWriter описывает интерфейс для сохранения ключей комнаты.
+type RoomKeyWriter interface {
+	// SaveEpoch в одной транзакции сохраняет ключ комнаты эпохи keyEpoch для устройств keys, подписанный устройством uploaderUUID,
+	// и возвращает текущую эпоху комнаты, прочитанную с блокировкой строки комнаты в той же транзакции.
+	// Если keyEpoch не текущая эпоха, ничего не сохраняется. Первая загрузка эпохи выпускает её ключ;
+	// последующие только пересылают его устройствам без ключа и принимаются, только если ключ эпохи
+	// уже есть у загружающего устройства uploaderUUID, иначе возвращается false.
+	// Уже сохранённый ключ эпохи не перезаписывается.
+	SaveEpoch(ctx context.Context, roomUUID uuid.UUID, uploaderUUID uuid.UUID, keyEpoch int64, keys map[uuid.UUID]models.SignedRoomKey) (int64, bool, error)
+}
+
+// RoomKeyReader описывает интерфейс для чтения ключей комнаты.
+type RoomKeyReader interface {
+	// Get возвращает ключ комнаты эпохи keyEpoch для устройства вместе с загрузившим его устройством
+	// или nil, если ключ не найден.
+	Get(ctx context.Context, roomUUID uuid.UUID, deviceUUID uuid.UUID, keyEpoch int64) (*models.IssuedRoomKeyDB, error)
+}
+
+// RoomDeviceLister описывает интерфейс для получения устройств участников комнаты.
+type RoomDeviceLister interface {
+	// ListByRoom возвращает все устройства всех участников комнаты.
+	ListByRoom(ctx context.Context, roomUUID uuid.UUID) ([]models.UserDeviceDB, error)
+}
+
+// RoomKeyService реализует распределение ключей комнаты между устройствами участников.
+// Сервер хранит только ключи, зашифрованные публичными ключами устройств, и не может их расшифровать.
+// Ключи хранятся по эпохам: при смене состава участников эпоха комнаты растёт, и клиенты
+// выпускают новый ключ, а ключи прошлых эпох остаются доступны для расшифровки истории.
+type RoomKeyService struct {
+	rr  RoomReader       // репозиторий для чтения комнат
+	rmr RoomMemberReader // репозиторий для чтения участников
+	kw  RoomKeyWriter    // репозиторий для записи ключей
+	kr  RoomKeyReader    // репозиторий для чтения ключей
+	dl  RoomDeviceLister // репозиторий устройств участников
+}
+
+// NewRoomKeyService создаёт новый экземпляр RoomKeyService с указанными репозиториями.
+func NewRoomKeyService(
+	rr RoomReader,
+	rmr RoomMemberReader,
+	kw RoomKeyWriter,
+	kr RoomKeyReader,
+	dl RoomDeviceLister,
+) *RoomKeyService {
+	return &RoomKeyService{
+		rr:  rr,
+		rmr: rmr,
+		kw:  kw,
+		kr:  kr,
+		dl:  dl,
+	}
+}
+
+// UploadRoomKeys сохраняет ключ комнаты эпохи keyEpoch, зашифрованный для каждого из устройств keys (deviceUUID -> ключ).
+// Загружать ключи может любой участник комнаты, только для устройств её участников и только для текущей эпохи.
+// Каждый ключ должен быть подписан ключом подписи загружающего устройства deviceUUID, иначе возвращается
+// ErrInvalidRoomKeySignature: клиенты отклоняют ключи без верной подписи устройства участника.
+// Ключи всех устройств сохраняются атомарно, и у эпохи один ключ: выигрывает первая загрузка, а ключ,
+// выпущенный устройством deviceUUID после неё, отклоняется с ErrRoomKeyConflict.
+func (svc *RoomKeyService) UploadRoomKeys(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	roomUUID uuid.UUID,
+	keyEpoch int64,
+	keys map[uuid.UUID]models.SignedRoomKey,
+) error {
+	if _, err := svc.checkMember(ctx, roomUUID, userUUID); err != nil {
+		return err
+	}
+
+	devices, err := svc.dl.ListByRoom(ctx, roomUUID)
+	if err != nil {
+		return err
+	}
+
+	allowed := make(map[uuid.UUID]models.UserDeviceDB, len(devices))
+	for _, d := range devices {
+		allowed[d.DeviceUUID] = d
+	}
+
+	uploader, ok := allowed[deviceUUID]
+	if !ok || uploader.SigningKey == "" {
+		return ErrInvalidRoomKeySignature
+	}
+
+	for target, key := range keys {
+		d, ok := allowed[target]
+		if !ok {
+			return ErrDeviceNotInRoom
+		}
+		pub, err := e2ee.ParsePublicKey(d.PublicKey)
+		if err != nil {
+			return ErrInvalidRoomKeySignature
+		}
+		if err := e2ee.VerifyRoomKey(uploader.SigningKey, roomUUID, keyEpoch, e2ee.EncodePublicKey(pub), key.EncryptedKey, key.Signature); err != nil {
+			return ErrInvalidRoomKeySignature
+		}
+	}
+
+	// Эпоха сравнивается в транзакции сохранения: изменение состава между проверкой и записью
+	// иначе позволило бы сохранить ключ эпохи, уже недоступной для новых сообщений
+	currentEpoch, ok, err := svc.kw.SaveEpoch(ctx, roomUUID, deviceUUID, keyEpoch, keys)
+	if err != nil {
+		return err
+	}
+	if currentEpoch != keyEpoch {
+		return ErrStaleKeyEpoch
+	}
+	if !ok {
+		return ErrRoomKeyConflict
+	}
+
+	return nil
+}
+
+// GetRoomKey возвращает ключ комнаты эпохи keyEpoch, зашифрованный для устройства deviceUUID пользователя userUUID,
+// вместе с подписью и ключом подписи загрузившего его устройства.
+// Если keyEpoch не положительный, возвращается ключ текущей эпохи комнаты.
+func (svc *RoomKeyService) GetRoomKey(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+	roomUUID uuid.UUID,
+	keyEpoch int64,
+) (*models.IssuedRoomKeyDB, error) {
+	room, err := svc.checkMember(ctx, roomUUID, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if keyEpoch <= 0 {
+		keyEpoch = room.KeyEpoch
+	}
+
+	key, err := svc.kr.Get(ctx, roomUUID, deviceUUID, keyEpoch)
+	if err != nil {
+		return nil, err
+	}
+	if key == nil {
+		return nil, ErrRoomKeyNotFound
+	}
+
+	return key, nil
+}
+
+// ListRoomDevices возвращает текущую эпоху ключа комнаты и устройства всех её участников
+// с их публичными ключами — для них нужно зашифровать ключ этой эпохи.
+func (svc *RoomKeyService) ListRoomDevices(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	roomUUID uuid.UUID,
+) (keyEpoch int64, devices []models.UserDeviceDB, err error) {
+	room, err := svc.checkMember(ctx, roomUUID, userUUID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	devices, err = svc.dl.ListByRoom(ctx, roomUUID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return room.KeyEpoch, devices, nil
+}
+
+// checkMember проверяет, что комната существует и пользователь является её участником, и возвращает комнату.
+func (svc *RoomKeyService) checkMember(ctx context.Context, roomUUID uuid.UUID, userUUID uuid.UUID) (*models.RoomDB, error) {
+	room, err := svc.rr.Get(ctx, roomUUID)
+	if err != nil {
+		return nil, err
+	}
+	if room == nil {
+		return nil, ErrRoomNotFound
+	}
+
+	member, err := svc.rmr.Get(ctx, roomUUID, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if member == nil {
+		return nil, ErrUserNotInRoom
+	}
+
+	return room, nil
+}
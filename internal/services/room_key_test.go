@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/e2ee"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRoomKeyService_UploadRoomKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockKW := NewMockRoomKeyWriter(ctrl)
+	mockKR := NewMockRoomKeyReader(ctrl)
+	mockDL := NewMockRoomDeviceLister(ctrl)
+	svc := NewRoomKeyService(mockRR, mockRMR, mockKW, mockKR, mockDL)
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	deviceA := uuid.New()
+	deviceB := uuid.New()
+	room := &models.RoomDB{RoomUUID: roomUUID, KeyEpoch: 2}
+	member := &models.RoomMemberDB{RoomUUID: roomUUID, UserUUID: userUUID}
+	ctx := context.Background()
+
+	privA, err := e2ee.GenerateDeviceKey()
+	assert.NoError(t, err)
+	signingA, err := e2ee.DeriveSigningKey(privA)
+	assert.NoError(t, err)
+	privB, err := e2ee.GenerateDeviceKey()
+	assert.NoError(t, err)
+
+	devices := []models.UserDeviceDB{
+		{
+			DeviceUUID: deviceA,
+			PublicKey:  e2ee.EncodePublicKey(privA.PublicKey()),
+			SigningKey: e2ee.EncodeSigningKey(signingA.Public().(ed25519.PublicKey)),
+		},
+		{DeviceUUID: deviceB, PublicKey: e2ee.EncodePublicKey(privB.PublicKey())},
+	}
+	unsignedUploader := []models.UserDeviceDB{{DeviceUUID: deviceA, PublicKey: devices[0].PublicKey}, devices[1]}
+
+	// signed возвращает ключ для устройства d, подписанный ключом подписи устройства A
+	signed := func(d models.UserDeviceDB, keyEpoch int64, encryptedKey string) models.SignedRoomKey {
+		return models.SignedRoomKey{
+			EncryptedKey: encryptedKey,
+			Signature:    e2ee.SignRoomKey(signingA, roomUUID, keyEpoch, d.PublicKey, encryptedKey),
+		}
+	}
+	validKeys := map[uuid.UUID]models.SignedRoomKey{deviceA: signed(devices[0], 2, "key-a"), deviceB: signed(devices[1], 2, "key-b")}
+
+	tests := []struct {
+		name          string
+		keyEpoch      int64
+		keys          map[uuid.UUID]models.SignedRoomKey
+		setupMocks    func()
+		expectedError error
+	}{
+		{
+			name:     "success",
+			keyEpoch: 2,
+			keys:     validKeys,
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(devices, nil)
+				mockKW.EXPECT().SaveEpoch(gomock.Any(), roomUUID, deviceA, int64(2), validKeys).Return(int64(2), true, nil)
+			},
+		},
+		{
+			name:     "epoch key issued by another device",
+			keyEpoch: 2,
+			keys:     validKeys,
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(devices, nil)
+				mockKW.EXPECT().SaveEpoch(gomock.Any(), roomUUID, deviceA, int64(2), gomock.Any()).Return(int64(2), false, nil)
+			},
+			expectedError: ErrRoomKeyConflict,
+		},
+		{
+			// эпоха сравнивается в транзакции сохранения
+			name:     "stale epoch",
+			keyEpoch: 1,
+			keys:     map[uuid.UUID]models.SignedRoomKey{deviceA: signed(devices[0], 1, "key-a")},
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(devices, nil)
+				mockKW.EXPECT().SaveEpoch(gomock.Any(), roomUUID, deviceA, int64(1), gomock.Any()).Return(int64(2), false, nil)
+			},
+			expectedError: ErrStaleKeyEpoch,
+		},
+		{
+			// состав изменился после чтения комнаты: эпоха выросла к моменту сохранения
+			name:     "epoch bumped before save",
+			keyEpoch: 2,
+			keys:     validKeys,
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(devices, nil)
+				mockKW.EXPECT().SaveEpoch(gomock.Any(), roomUUID, deviceA, int64(2), validKeys).Return(int64(3), false, nil)
+			},
+			expectedError: ErrStaleKeyEpoch,
+		},
+		{
+			name:     "room not found",
+			keyEpoch: 2,
+			keys:     map[uuid.UUID]models.SignedRoomKey{deviceA: signed(devices[0], 2, "key-a")},
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(nil, nil)
+			},
+			expectedError: ErrRoomNotFound,
+		},
+		{
+			name:     "user not in room",
+			keyEpoch: 2,
+			keys:     map[uuid.UUID]models.SignedRoomKey{deviceA: signed(devices[0], 2, "key-a")},
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+			},
+			expectedError: ErrUserNotInRoom,
+		},
+		{
+			name:     "device not in room",
+			keyEpoch: 2,
+			keys:     map[uuid.UUID]models.SignedRoomKey{deviceA: signed(devices[0], 2, "key-a"), uuid.New(): signed(devices[1], 2, "key-x")},
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(devices, nil)
+			},
+			expectedError: ErrDeviceNotInRoom,
+		},
+		{
+			name:     "unsigned key",
+			keyEpoch: 2,
+			keys:     map[uuid.UUID]models.SignedRoomKey{deviceA: signed(devices[0], 2, "key-a"), deviceB: {EncryptedKey: "key-b"}},
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(devices, nil)
+			},
+			expectedError: ErrInvalidRoomKeySignature,
+		},
+		{
+			name:     "key signed for another device",
+			keyEpoch: 2,
+			keys:     map[uuid.UUID]models.SignedRoomKey{deviceB: signed(devices[0], 2, "key-b")},
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(devices, nil)
+			},
+			expectedError: ErrInvalidRoomKeySignature,
+		},
+		{
+			name:     "uploader without signing key",
+			keyEpoch: 2,
+			keys:     validKeys,
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(unsignedUploader, nil)
+			},
+			expectedError: ErrInvalidRoomKeySignature,
+		},
+		{
+			name:     "list devices error",
+			keyEpoch: 2,
+			keys:     map[uuid.UUID]models.SignedRoomKey{deviceA: signed(devices[0], 2, "key-a")},
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name:     "save error",
+			keyEpoch: 2,
+			keys:     map[uuid.UUID]models.SignedRoomKey{deviceA: signed(devices[0], 2, "key-a")},
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(devices, nil)
+				mockKW.EXPECT().SaveEpoch(gomock.Any(), roomUUID, deviceA, int64(2), gomock.Any()).Return(int64(0), false, errors.New("save fail"))
+			},
+			expectedError: errors.New("save fail"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			err := svc.UploadRoomKeys(ctx, userUUID, deviceA, roomUUID, tt.keyEpoch, tt.keys)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestRoomKeyService_GetRoomKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockKW := NewMockRoomKeyWriter(ctrl)
+	mockKR := NewMockRoomKeyReader(ctrl)
+	mockDL := NewMockRoomDeviceLister(ctrl)
+	svc := NewRoomKeyService(mockRR, mockRMR, mockKW, mockKR, mockDL)
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	room := &models.RoomDB{RoomUUID: roomUUID, KeyEpoch: 2}
+	member := &models.RoomMemberDB{RoomUUID: roomUUID, UserUUID: userUUID}
+	ctx := context.Background()
+
+	stored := &models.IssuedRoomKeyDB{
+		RoomKeyDB: models.RoomKeyDB{RoomUUID: roomUUID, DeviceUUID: deviceUUID, KeyEpoch: 1, EncryptedKey: "wrapped"},
+	}
+
+	tests := []struct {
+		name          string
+		keyEpoch      int64
+		readEpoch     int64
+		mockKey       *models.IssuedRoomKeyDB
+		mockKeyErr    error
+		expectedError error
+	}{
+		{"current epoch", 0, 2, stored, nil, nil},
+		{"explicit epoch", 1, 1, stored, nil, nil},
+		{"key not found", 0, 2, nil, nil, ErrRoomKeyNotFound},
+		{"reader error", 1, 1, nil, errors.New("db error"), errors.New("db error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+			mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(member, nil)
+			mockKR.EXPECT().Get(gomock.Any(), roomUUID, deviceUUID, tt.readEpoch).Return(tt.mockKey, tt.mockKeyErr)
+
+			key, err := svc.GetRoomKey(ctx, userUUID, deviceUUID, roomUUID, tt.keyEpoch)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, key)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.mockKey, key)
+			}
+		})
+	}
+
+	t.Run("user not in room", func(t *testing.T) {
+		mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(room, nil)
+		mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(nil, nil)
+
+		_, err := svc.GetRoomKey(ctx, userUUID, deviceUUID, roomUUID, 0)
+		assert.ErrorIs(t, err, ErrUserNotInRoom)
+	})
+}
+
+func TestRoomKeyService_ListRoomDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRR := NewMockRoomReader(ctrl)
+	mockRMR := NewMockRoomMemberReader(ctrl)
+	mockKW := NewMockRoomKeyWriter(ctrl)
+	mockKR := NewMockRoomKeyReader(ctrl)
+	mockDL := NewMockRoomDeviceLister(ctrl)
+	svc := NewRoomKeyService(mockRR, mockRMR, mockKW, mockKR, mockDL)
+
+	roomUUID := uuid.New()
+	userUUID := uuid.New()
+	room := &models.RoomDB{RoomUUID: roomUUID, KeyEpoch: 2}
+	member := &models.RoomMemberDB{RoomUUID: roomUUID, UserUUID: userUUID}
+	devices := []models.UserDeviceDB{{DeviceUUID: uuid.New(), UserUUID: userUUID, PublicKey: "pk"}}
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		mockRoom      *models.RoomDB
+		mockMember    *models.RoomMemberDB
+		mockDevices   []models.UserDeviceDB
+		mockErr       error
+		expectedError error
+	}{
+		{"success", room, member, devices, nil, nil},
+		{"room not found", nil, nil, nil, nil, ErrRoomNotFound},
+		{"user not in room", room, nil, nil, nil, ErrUserNotInRoom},
+		{"list error", room, member, nil, errors.New("db error"), errors.New("db error")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRR.EXPECT().Get(gomock.Any(), roomUUID).Return(tt.mockRoom, nil)
+			if tt.mockRoom != nil {
+				mockRMR.EXPECT().Get(gomock.Any(), roomUUID, userUUID).Return(tt.mockMember, nil)
+			}
+			if tt.mockMember != nil {
+				mockDL.EXPECT().ListByRoom(gomock.Any(), roomUUID).Return(tt.mockDevices, tt.mockErr)
+			}
+
+			keyEpoch, result, err := svc.ListRoomDevices(ctx, userUUID, roomUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, int64(2), keyEpoch)
+				assert.Equal(t, devices, result)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// ErrInvalidRefreshToken возвращается, если refresh-токен не найден, истёк или отозван.
+var ErrInvalidRefreshToken = errors.New("invalid refresh token")
+
+// ErrRefreshTokenReused возвращается при повторном использовании уже обменянного refresh-токена.
+// В этом случае вся сессия устройства отзывается.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// RefreshTokenWriter описывает интерфейс для записи refresh-токенов.
+type RefreshTokenWriter interface {
+	// Save сохраняет хэш нового refresh-токена сессии.
+	Save(
+		ctx context.Context,
+		tokenHash string,
+		sessionUUID uuid.UUID,
+		userUUID uuid.UUID,
+		deviceUUID uuid.UUID,
+		expiresAt time.Time,
+	) error
+	// MarkUsed помечает токен использованным; возвращает false, если он уже использован или отозван.
+	MarkUsed(ctx context.Context, tokenHash string, usedAt time.Time) (bool, error)
+	// RevokeSession отзывает все refresh-токены сессии.
+	RevokeSession(ctx context.Context, sessionUUID uuid.UUID) error
+}
+
+// RefreshTokenReader описывает интерфейс для чтения refresh-токенов.
+type RefreshTokenReader interface {
+	// Get возвращает refresh-токен по хэшу или nil, если не найден.
+	Get(ctx context.Context, tokenHash string) (*models.RefreshTokenDB, error)
+}
+
+// SessionTokenGenerator описывает интерфейс генерации access-токена, привязанного к сессии.
+type SessionTokenGenerator interface {
+	// GenerateWithSession создаёт JWT, содержащий userUUID, deviceUUID и sessionUUID.
+	GenerateWithSession(userUUID uuid.UUID, deviceUUID uuid.UUID, sessionUUID uuid.UUID) (string, error)
+}
+
+// DeviceSessionRevoker описывает интерфейс отзыва всех сессий устройства.
+type DeviceSessionRevoker interface {
+	// RevokeSessions отзывает все access- и refresh-токены пользователя на устройстве.
+	RevokeSessions(ctx context.Context, userUUID uuid.UUID, deviceUUID uuid.UUID) error
+}
+
+// SessionService выдаёт пары access/refresh токенов и обменивает refresh-токены на новые.
+// Каждый refresh-токен одноразовый: при обмене выдаётся новый токен той же сессии,
+// а повторное предъявление уже обменянного токена отзывает все сессии устройства.
+type SessionService struct {
+	rw  RefreshTokenWriter    // репозиторий для записи refresh-токенов
+	rr  RefreshTokenReader    // репозиторий для чтения refresh-токенов
+	tg  SessionTokenGenerator // генератор access-токенов
+	sr  DeviceSessionRevoker  // отзыв сессий устройства при повторном использовании токена
+	ttl time.Duration         // время жизни refresh-токена
+}
+
+// NewSessionService создаёт новый экземпляр SessionService.
+// ttl задаёт время жизни refresh-токена.
+func NewSessionService(
+	rw RefreshTokenWriter,
+	rr RefreshTokenReader,
+	tg SessionTokenGenerator,
+	sr DeviceSessionRevoker,
+	ttl time.Duration,
+) *SessionService {
+	return &SessionService{
+		rw:  rw,
+		rr:  rr,
+		tg:  tg,
+		sr:  sr,
+		ttl: ttl,
+	}
+}
+
+// IssueSession начинает новую сессию устройства и возвращает access- и refresh-токены.
+func (svc *SessionService) IssueSession(
+	ctx context.Context,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+) (accessToken string, refreshToken string, err error) {
+	return svc.issue(ctx, uuid.New(), userUUID, deviceUUID)
+}
+
+// Refresh обменивает refresh-токен на новую пару токенов той же сессии.
+// Возвращает ErrInvalidRefreshToken для неизвестного, истёкшего или отозванного токена
+// и ErrRefreshTokenReused, если токен уже был обменян ранее.
+func (svc *SessionService) Refresh(
+	ctx context.Context,
+	refreshToken string,
+) (accessToken string, newRefreshToken string, err error) {
+	tokenHash := hashRefreshToken(refreshToken)
+
+	token, err := svc.rr.Get(ctx, tokenHash)
+	if err != nil {
+		return "", "", err
+	}
+	if token == nil || token.RevokedAt != nil {
+		return "", "", ErrInvalidRefreshToken
+	}
+	if token.UsedAt != nil {
+		return "", "", svc.revokeOnReuse(ctx, token)
+	}
+
+	now := time.Now().UTC()
+	if !token.ExpiresAt.After(now) {
+		return "", "", ErrInvalidRefreshToken
+	}
+
+	ok, err := svc.rw.MarkUsed(ctx, tokenHash, now)
+	if err != nil {
+		return "", "", err
+	}
+	if !ok {
+		// Токен успели обменять параллельным запросом
+		return "", "", svc.revokeOnReuse(ctx, token)
+	}
+
+	return svc.issue(ctx, token.SessionUUID, token.UserUUID, token.DeviceUUID)
+}
+
+// revokeOnReuse отзывает сессию токена и все сессии устройства и возвращает ErrRefreshTokenReused.
+func (svc *SessionService) revokeOnReuse(ctx context.Context, token *models.RefreshTokenDB) error {
+	if err := svc.rw.RevokeSession(ctx, token.SessionUUID); err != nil {
+		return err
+	}
+	if err := svc.sr.RevokeSessions(ctx, token.UserUUID, token.DeviceUUID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issue генерирует и сохраняет новую пару токенов сессии.
+func (svc *SessionService) issue(
+	ctx context.Context,
+	sessionUUID uuid.UUID,
+	userUUID uuid.UUID,
+	deviceUUID uuid.UUID,
+) (accessToken string, refreshToken string, err error) {
+	refreshToken, err = newRefreshToken()
+	if err != nil {
+		return "", "", err
+	}
+
+	expiresAt := time.Now().UTC().Add(svc.ttl)
+	if err := svc.rw.Save(ctx, hashRefreshToken(refreshToken), sessionUUID, userUUID, deviceUUID, expiresAt); err != nil {
+		return "", "", err
+	}
+
+	accessToken, err = svc.tg.GenerateWithSession(userUUID, deviceUUID, sessionUUID)
+	if err != nil {
+		return "", "", err
+	}
+
+	return accessToken, refreshToken, nil
+}
+
+// newRefreshToken генерирует случайный непрозрачный refresh-токен.
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashRefreshToken возвращает SHA-256 хэш refresh-токена для хранения в базе.
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionService_IssueSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRefreshTokenWriter(ctrl)
+	mockRR := NewMockRefreshTokenReader(ctrl)
+	mockTG := NewMockSessionTokenGenerator(ctrl)
+	mockSR := NewMockDeviceSessionRevoker(ctrl)
+	svc := NewSessionService(mockRW, mockRR, mockTG, mockSR, time.Hour)
+
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		setupMocks    func()
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMocks: func() {
+				var sessionUUID uuid.UUID
+				mockRW.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any(), userUUID, deviceUUID, gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ string, s uuid.UUID, _, _ uuid.UUID, _ time.Time) error {
+						sessionUUID = s
+						return nil
+					})
+				mockTG.EXPECT().GenerateWithSession(userUUID, deviceUUID, gomock.Any()).
+					DoAndReturn(func(_, _ uuid.UUID, s uuid.UUID) (string, error) {
+						assert.Equal(t, sessionUUID, s)
+						return "access", nil
+					})
+			},
+		},
+		{
+			name: "save error",
+			setupMocks: func() {
+				mockRW.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any(), userUUID, deviceUUID, gomock.Any()).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name: "generate error",
+			setupMocks: func() {
+				mockRW.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any(), userUUID, deviceUUID, gomock.Any()).Return(nil)
+				mockTG.EXPECT().GenerateWithSession(userUUID, deviceUUID, gomock.Any()).Return("", errors.New("jwt error"))
+			},
+			expectedError: errors.New("jwt error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			access, refresh, err := svc.IssueSession(ctx, userUUID, deviceUUID)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Empty(t, access)
+				assert.Empty(t, refresh)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "access", access)
+			assert.NotEmpty(t, refresh)
+		})
+	}
+}
+
+func TestSessionService_Refresh(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRW := NewMockRefreshTokenWriter(ctrl)
+	mockRR := NewMockRefreshTokenReader(ctrl)
+	mockTG := NewMockSessionTokenGenerator(ctrl)
+	mockSR := NewMockDeviceSessionRevoker(ctrl)
+	svc := NewSessionService(mockRW, mockRR, mockTG, mockSR, time.Hour)
+
+	sessionUUID := uuid.New()
+	userUUID := uuid.New()
+	deviceUUID := uuid.New()
+	tokenHash := hashRefreshToken("refresh")
+	usedAt := time.Now().Add(-time.Minute)
+	ctx := context.Background()
+
+	stored := func(mutate func(*models.RefreshTokenDB)) *models.RefreshTokenDB {
+		token := &models.RefreshTokenDB{
+			TokenHash:   tokenHash,
+			SessionUUID: sessionUUID,
+			UserUUID:    userUUID,
+			DeviceUUID:  deviceUUID,
+			ExpiresAt:   time.Now().Add(time.Hour),
+		}
+		if mutate != nil {
+			mutate(token)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name          string
+		setupMocks    func()
+		expectedError error
+	}{
+		{
+			name: "success",
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), tokenHash).Return(stored(nil), nil)
+				mockRW.EXPECT().MarkUsed(gomock.Any(), tokenHash, gomock.Any()).Return(true, nil)
+				mockRW.EXPECT().Save(gomock.Any(), gomock.Not(tokenHash), sessionUUID, userUUID, deviceUUID, gomock.Any()).Return(nil)
+				mockTG.EXPECT().GenerateWithSession(userUUID, deviceUUID, sessionUUID).Return("access", nil)
+			},
+		},
+		{
+			name: "unknown token",
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), tokenHash).Return(nil, nil)
+			},
+			expectedError: ErrInvalidRefreshToken,
+		},
+		{
+			name: "revoked token",
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), tokenHash).Return(stored(func(t *models.RefreshTokenDB) { t.RevokedAt = &usedAt }), nil)
+			},
+			expectedError: ErrInvalidRefreshToken,
+		},
+		{
+			name: "expired token",
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), tokenHash).Return(stored(func(t *models.RefreshTokenDB) { t.ExpiresAt = time.Now().Add(-time.Minute) }), nil)
+			},
+			expectedError: ErrInvalidRefreshToken,
+		},
+		{
+			name: "reused token revokes device sessions",
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), tokenHash).Return(stored(func(t *models.RefreshTokenDB) { t.UsedAt = &usedAt }), nil)
+				mockRW.EXPECT().RevokeSession(gomock.Any(), sessionUUID).Return(nil)
+				mockSR.EXPECT().RevokeSessions(gomock.Any(), userUUID, deviceUUID).Return(nil)
+			},
+			expectedError: ErrRefreshTokenReused,
+		},
+		{
+			name: "concurrent reuse revokes device sessions",
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), tokenHash).Return(stored(nil), nil)
+				mockRW.EXPECT().MarkUsed(gomock.Any(), tokenHash, gomock.Any()).Return(false, nil)
+				mockRW.EXPECT().RevokeSession(gomock.Any(), sessionUUID).Return(nil)
+				mockSR.EXPECT().RevokeSessions(gomock.Any(), userUUID, deviceUUID).Return(nil)
+			},
+			expectedError: ErrRefreshTokenReused,
+		},
+		{
+			name: "revoke on reuse error",
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), tokenHash).Return(stored(func(t *models.RefreshTokenDB) { t.UsedAt = &usedAt }), nil)
+				mockRW.EXPECT().RevokeSession(gomock.Any(), sessionUUID).Return(nil)
+				mockSR.EXPECT().RevokeSessions(gomock.Any(), userUUID, deviceUUID).Return(errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name: "get error",
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), tokenHash).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+		{
+			name: "mark used error",
+			setupMocks: func() {
+				mockRR.EXPECT().Get(gomock.Any(), tokenHash).Return(stored(nil), nil)
+				mockRW.EXPECT().MarkUsed(gomock.Any(), tokenHash, gomock.Any()).Return(false, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setupMocks()
+
+			access, refresh, err := svc.Refresh(ctx, "refresh")
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Empty(t, access)
+				assert.Empty(t, refresh)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, "access", access)
+			assert.NotEmpty(t, refresh)
+			assert.NotEqual(t, "refresh", refresh)
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/sbilibin2017/bil-message/internal/models"
+)
+
+// ErrUserNotFound возвращается, если пользователь не найден
+var ErrUserNotFound = errors.New("user not found")
+
+// UserService реализует справочник пользователей.
+type UserService struct {
+	ug UserGetter   // репозиторий для поиска пользователей по имени
+	dl DeviceLister // репозиторий для получения устройств пользователя
+}
+
+// NewUserService создаёт новый экземпляр UserService.
+func NewUserService(ug UserGetter, dl DeviceLister) *UserService {
+	return &UserService{ug: ug, dl: dl}
+}
+
+// FindUser возвращает пользователя по имени.
+// Если пользователя нет, возвращает ErrUserNotFound.
+func (svc *UserService) FindUser(ctx context.Context, username string) (*models.UserDB, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return nil, ErrUserNotFound
+	}
+
+	user, err := svc.ug.Get(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	return user, nil
+}
+
+// ListUserDevices возвращает пользователя по имени и его активные устройства с публичными ключами.
+// Устройства, ожидающие подтверждения, не возвращаются: им ещё не выдаются ключи комнат.
+// Если пользователя нет, возвращает ErrUserNotFound.
+func (svc *UserService) ListUserDevices(ctx context.Context, username string) (*models.UserDB, []models.UserDeviceDB, error) {
+	user, err := svc.FindUser(ctx, username)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	devices, err := svc.dl.ListByUser(ctx, user.UserUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	active := make([]models.UserDeviceDB, 0, len(devices))
+	for _, device := range devices {
+		if device.Status == models.DeviceStatusActive {
+			active = append(active, device)
+		}
+	}
+
+	return user, active, nil
+}
@@ -0,0 +1,140 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/sbilibin2017/bil-message/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserService_FindUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUG := NewMockUserGetter(ctrl)
+	svc := NewUserService(mockUG, nil)
+	ctx := context.Background()
+	user := &models.UserDB{UserUUID: uuid.New(), Username: "alice"}
+
+	tests := []struct {
+		name          string
+		username      string
+		mockSetup     func()
+		expectedUser  *models.UserDB
+		expectedError error
+	}{
+		{
+			name:     "found",
+			username: " alice ",
+			mockSetup: func() {
+				mockUG.EXPECT().Get(gomock.Any(), "alice").Return(user, nil)
+			},
+			expectedUser: user,
+		},
+		{
+			name:     "not found",
+			username: "bob",
+			mockSetup: func() {
+				mockUG.EXPECT().Get(gomock.Any(), "bob").Return(nil, nil)
+			},
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name:          "empty username",
+			username:      "  ",
+			mockSetup:     func() {},
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name:     "reader error",
+			username: "alice",
+			mockSetup: func() {
+				mockUG.EXPECT().Get(gomock.Any(), "alice").Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+
+			got, err := svc.FindUser(ctx, tt.username)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, got)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expectedUser, got)
+			}
+		})
+	}
+}
+
+func TestUserService_ListUserDevices(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockUG := NewMockUserGetter(ctrl)
+	mockDL := NewMockDeviceLister(ctrl)
+	svc := NewUserService(mockUG, mockDL)
+	ctx := context.Background()
+	user := &models.UserDB{UserUUID: uuid.New(), Username: "alice"}
+
+	active := models.UserDeviceDB{DeviceUUID: uuid.New(), UserUUID: user.UserUUID, PublicKey: "key-1", Status: models.DeviceStatusActive}
+	pending := models.UserDeviceDB{DeviceUUID: uuid.New(), UserUUID: user.UserUUID, PublicKey: "key-2", Status: models.DeviceStatusPending}
+
+	tests := []struct {
+		name            string
+		username        string
+		mockSetup       func()
+		expectedDevices []models.UserDeviceDB
+		expectedError   error
+	}{
+		{
+			name:     "only active devices",
+			username: "alice",
+			mockSetup: func() {
+				mockUG.EXPECT().Get(gomock.Any(), "alice").Return(user, nil)
+				mockDL.EXPECT().ListByUser(gomock.Any(), user.UserUUID).Return([]models.UserDeviceDB{active, pending}, nil)
+			},
+			expectedDevices: []models.UserDeviceDB{active},
+		},
+		{
+			name:     "user not found",
+			username: "bob",
+			mockSetup: func() {
+				mockUG.EXPECT().Get(gomock.Any(), "bob").Return(nil, nil)
+			},
+			expectedError: ErrUserNotFound,
+		},
+		{
+			name:     "lister error",
+			username: "alice",
+			mockSetup: func() {
+				mockUG.EXPECT().Get(gomock.Any(), "alice").Return(user, nil)
+				mockDL.EXPECT().ListByUser(gomock.Any(), user.UserUUID).Return(nil, errors.New("db error"))
+			},
+			expectedError: errors.New("db error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.mockSetup()
+			gotUser, devices, err := svc.ListUserDevices(ctx, tt.username)
+			if tt.expectedError != nil {
+				assert.EqualError(t, err, tt.expectedError.Error())
+				assert.Nil(t, gotUser)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, user, gotUser)
+			assert.Equal(t, tt.expectedDevices, devices)
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package http
 
 import (
+	"context"
+	"net/http"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -52,3 +54,80 @@ func WithRetryPolicy(policies ...RetryPolicy) Opt {
 		return nil
 	}
 }
+
+// TokenStore описывает хранилище пары токенов клиента.
+type TokenStore interface {
+	// Load возвращает сохранённые JWT и refresh-токен.
+	Load() (accessToken string, refreshToken string, err error)
+	// Save сохраняет новую пару токенов.
+	Save(accessToken string, refreshToken string) error
+}
+
+// RefreshFunc обменивает refresh-токен на новую пару токенов.
+type RefreshFunc func(ctx context.Context, client *resty.Client, refreshToken string) (accessToken string, newRefreshToken string, err error)
+
+// refreshedKey помечает контекст запросов, выполняемых после обновления токенов,
+// чтобы ответ 401 на них не приводил к повторному обновлению.
+type refreshedKey struct{}
+
+// WithTokenRefresh возвращает опцию прозрачного обновления токенов.
+// Запросы без явно указанного токена авторизуются JWT из store.
+// При ответе 401 клиент обменивает refresh-токен из store через refresh,
+// сохраняет новую пару и один раз повторяет исходный запрос с новым JWT.
+// Если обновить токены не удалось, вызывающему возвращается исходный ответ 401.
+func WithTokenRefresh(store TokenStore, refresh RefreshFunc) Opt {
+	return func(c *resty.Client) error {
+		if store == nil || refresh == nil {
+			return nil
+		}
+
+		c.OnBeforeRequest(func(_ *resty.Client, r *resty.Request) error {
+			if r.Token != "" || r.Header.Get("Authorization") != "" {
+				return nil
+			}
+			if accessToken, _, err := store.Load(); err == nil {
+				r.SetAuthToken(accessToken)
+			}
+			return nil
+		})
+
+		c.OnAfterResponse(func(c *resty.Client, resp *resty.Response) error {
+			req := resp.Request
+			if resp.StatusCode() != http.StatusUnauthorized || req.Context().Value(refreshedKey{}) != nil {
+				return nil
+			}
+
+			_, refreshToken, err := store.Load()
+			if err != nil || refreshToken == "" {
+				return nil
+			}
+
+			ctx := context.WithValue(req.Context(), refreshedKey{}, true)
+			accessToken, newRefreshToken, err := refresh(ctx, c, refreshToken)
+			if err != nil {
+				return nil
+			}
+			if err := store.Save(accessToken, newRefreshToken); err != nil {
+				return err
+			}
+
+			header := req.Header.Clone()
+			header.Del("Authorization")
+
+			retry, err := c.R().
+				SetContext(ctx).
+				SetHeaderMultiValues(header).
+				SetBody(req.Body).
+				SetAuthToken(accessToken).
+				Execute(req.Method, req.RawRequest.URL.String())
+			if err != nil {
+				return err
+			}
+
+			*resp = *retry
+			return nil
+		})
+
+		return nil
+	}
+}
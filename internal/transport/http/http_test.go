@@ -1,6 +1,9 @@
 package http
 
 import (
+	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -55,3 +58,82 @@ func TestOptWithError(t *testing.T) {
 	assert.Nil(t, client)
 	assert.ErrorIs(t, err, assert.AnError)
 }
+
+// memoryTokenStore — хранилище токенов в памяти для тестов
+type memoryTokenStore struct {
+	access  string
+	refresh string
+}
+
+func (s *memoryTokenStore) Load() (string, string, error) {
+	if s.access == "" && s.refresh == "" {
+		return "", "", errors.New("no tokens")
+	}
+	return s.access, s.refresh, nil
+}
+
+func (s *memoryTokenStore) Save(access string, refresh string) error {
+	s.access, s.refresh = access, refresh
+	return nil
+}
+
+func TestWithTokenRefresh(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer new-access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		w.Write([]byte(r.URL.RawQuery + " " + string(body)))
+	}))
+	defer ts.Close()
+
+	store := &memoryTokenStore{access: "old-access", refresh: "old-refresh"}
+	refreshCalls := 0
+	refresh := func(ctx context.Context, c *resty.Client, refreshToken string) (string, string, error) {
+		refreshCalls++
+		assert.Equal(t, "old-refresh", refreshToken)
+		return "new-access", "new-refresh", nil
+	}
+
+	client, err := New(ts.URL, WithTokenRefresh(store, refresh))
+	require.NoError(t, err)
+
+	resp, err := client.R().
+		SetQueryParam("scope", "device").
+		SetBody(map[string]string{"key": "value"}).
+		Post("/resource")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, `scope=device {"key":"value"}`, resp.String())
+	assert.Equal(t, 1, refreshCalls)
+	assert.Equal(t, "new-access", store.access)
+	assert.Equal(t, "new-refresh", store.refresh)
+
+	// Следующий запрос сразу использует новый токен
+	resp, err = client.R().Get("/resource")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode())
+	assert.Equal(t, 1, refreshCalls)
+}
+
+func TestWithTokenRefreshFailure(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+
+	store := &memoryTokenStore{access: "old-access", refresh: "old-refresh"}
+	refresh := func(ctx context.Context, c *resty.Client, refreshToken string) (string, string, error) {
+		return "", "", errors.New("refresh rejected")
+	}
+
+	client, err := New(ts.URL, WithTokenRefresh(store, refresh))
+	require.NoError(t, err)
+
+	resp, err := client.R().Get("/resource")
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode())
+	assert.Equal(t, "old-access", store.access)
+	assert.Equal(t, "old-refresh", store.refresh)
+}
@@ -0,0 +1,13 @@
+-- +goose Up
+ALTER TABLE room_members
+    ADD COLUMN role VARCHAR(16) NOT NULL DEFAULT 'member';
+
+UPDATE room_members AS rm
+SET role = 'owner'
+FROM rooms AS r
+WHERE rm.room_uuid = r.room_uuid
+  AND rm.user_uuid = r.creator_uuid;
+
+-- +goose Down
+ALTER TABLE room_members
+    DROP COLUMN IF EXISTS role;
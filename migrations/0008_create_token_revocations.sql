@@ -0,0 +1,24 @@
+-- +goose Up
+CREATE TABLE revoked_tokens (
+    token_id    VARCHAR(64) PRIMARY KEY,
+    user_uuid   UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    device_uuid UUID NOT NULL,
+    expires_at  TIMESTAMP NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+
+-- device_uuid = '00000000-0000-0000-0000-000000000000' означает все устройства пользователя
+CREATE TABLE session_revocations (
+    user_uuid   UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    device_uuid UUID NOT NULL,
+    revoked_at  TIMESTAMP NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_uuid, device_uuid)
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS session_revocations;
+DROP TABLE IF EXISTS revoked_tokens;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE refresh_tokens (
+    token_hash   VARCHAR(64) PRIMARY KEY,
+    session_uuid UUID NOT NULL,
+    user_uuid    UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    device_uuid  UUID NOT NULL REFERENCES user_devices(device_uuid) ON DELETE CASCADE,
+    expires_at   TIMESTAMP NOT NULL,
+    used_at      TIMESTAMP,
+    revoked_at   TIMESTAMP,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_refresh_tokens_session_uuid ON refresh_tokens(session_uuid);
+CREATE INDEX idx_refresh_tokens_user_device ON refresh_tokens(user_uuid, device_uuid);
+
+-- +goose Down
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,50 @@
+-- +goose Up
+ALTER TABLE rooms
+    ADD COLUMN key_epoch BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE room_messages
+    ADD COLUMN key_epoch BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE room_keys
+    ADD COLUMN key_epoch BIGINT NOT NULL DEFAULT 1;
+
+ALTER TABLE room_keys
+    DROP CONSTRAINT room_keys_pkey;
+
+ALTER TABLE room_keys
+    ADD PRIMARY KEY (room_uuid, device_uuid, key_epoch);
+
+-- Эпоха, для которой выпущен ключ комнаты: первая загрузка ключа эпохи создаёт запись,
+-- и ключ, выпущенный другим устройством для той же эпохи, отклоняется
+CREATE TABLE room_key_epochs (
+    room_uuid  UUID NOT NULL REFERENCES rooms(room_uuid) ON DELETE CASCADE,
+    key_epoch  BIGINT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (room_uuid, key_epoch)
+);
+
+INSERT INTO room_key_epochs (room_uuid, key_epoch, created_at)
+SELECT room_uuid, key_epoch, MIN(created_at)
+FROM room_keys
+GROUP BY room_uuid, key_epoch;
+
+-- +goose Down
+DROP TABLE IF EXISTS room_key_epochs;
+
+DELETE FROM room_keys
+WHERE key_epoch <> (SELECT r.key_epoch FROM rooms AS r WHERE r.room_uuid = room_keys.room_uuid);
+
+ALTER TABLE room_keys
+    DROP CONSTRAINT room_keys_pkey;
+
+ALTER TABLE room_keys
+    ADD PRIMARY KEY (room_uuid, device_uuid);
+
+ALTER TABLE room_keys
+    DROP COLUMN IF EXISTS key_epoch;
+
+ALTER TABLE room_messages
+    DROP COLUMN IF EXISTS key_epoch;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS key_epoch;
@@ -0,0 +1,7 @@
+-- +goose Up
+ALTER TABLE room_messages
+    ADD COLUMN sender_device_uuid UUID;
+
+-- +goose Down
+ALTER TABLE room_messages
+    DROP COLUMN IF EXISTS sender_device_uuid;
@@ -0,0 +1,12 @@
+-- +goose Up
+ALTER TABLE room_messages
+    ADD COLUMN client_message_uuid UUID;
+
+CREATE UNIQUE INDEX room_messages_room_sender_client_message_uuid_idx
+    ON room_messages (room_uuid, sender_uuid, client_message_uuid);
+
+-- +goose Down
+DROP INDEX IF EXISTS room_messages_room_sender_client_message_uuid_idx;
+
+ALTER TABLE room_messages
+    DROP COLUMN IF EXISTS client_message_uuid;
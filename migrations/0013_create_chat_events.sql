@@ -0,0 +1,12 @@
+-- +goose Up
+-- События комнат, не помещающиеся в NOTIFY; хранятся несколько минут для доставки на другие экземпляры сервера
+CREATE TABLE chat_events (
+    event_id   BIGSERIAL PRIMARY KEY,
+    payload    TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_chat_events_created_at ON chat_events(created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS chat_events;
@@ -0,0 +1,6 @@
+-- +goose Up
+CREATE INDEX room_messages_room_sent_at_idx
+    ON room_messages (room_uuid, sent_at, message_uuid);
+
+-- +goose Down
+DROP INDEX IF EXISTS room_messages_room_sent_at_idx;
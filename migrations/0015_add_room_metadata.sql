@@ -0,0 +1,25 @@
+-- +goose Up
+ALTER TABLE rooms
+    ADD COLUMN name VARCHAR(255) NOT NULL DEFAULT '';
+
+ALTER TABLE rooms
+    ADD COLUMN topic TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE rooms
+    ADD COLUMN kind VARCHAR(16) NOT NULL DEFAULT 'group';
+
+ALTER TABLE rooms
+    ADD COLUMN direct_key UUID UNIQUE;
+
+-- +goose Down
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS direct_key;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS kind;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS topic;
+
+ALTER TABLE rooms
+    DROP COLUMN IF EXISTS name;
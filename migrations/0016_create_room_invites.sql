@@ -0,0 +1,20 @@
+-- +goose Up
+CREATE TABLE room_invites (
+    invite_uuid  UUID PRIMARY KEY,
+    room_uuid    UUID NOT NULL REFERENCES rooms(room_uuid) ON DELETE CASCADE,
+    inviter_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    invitee_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    status       VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX room_invites_pending_idx
+    ON room_invites (room_uuid, invitee_uuid)
+    WHERE status = 'pending';
+
+CREATE INDEX room_invites_invitee_idx
+    ON room_invites (invitee_uuid, status);
+
+-- +goose Down
+DROP TABLE IF EXISTS room_invites;
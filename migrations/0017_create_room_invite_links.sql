@@ -0,0 +1,14 @@
+-- +goose Up
+CREATE TABLE room_invite_links (
+    link_uuid    UUID PRIMARY KEY,
+    room_uuid    UUID NOT NULL REFERENCES rooms(room_uuid) ON DELETE CASCADE,
+    creator_uuid UUID NOT NULL REFERENCES users(user_uuid) ON DELETE CASCADE,
+    expires_at   TIMESTAMP NOT NULL,
+    max_uses     INTEGER NOT NULL DEFAULT 0,
+    uses         INTEGER NOT NULL DEFAULT 0,
+    created_at   TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS room_invite_links;
@@ -0,0 +1,18 @@
+-- +goose Up
+ALTER TABLE user_devices
+    ADD COLUMN name VARCHAR(64) NOT NULL DEFAULT '';
+
+-- revoked_at IS NOT NULL означает, что устройство отозвано и больше не может входить в аккаунт
+ALTER TABLE user_devices
+    ADD COLUMN revoked_at TIMESTAMP;
+
+CREATE INDEX idx_user_devices_user_uuid ON user_devices(user_uuid);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_user_devices_user_uuid;
+
+ALTER TABLE user_devices
+    DROP COLUMN IF EXISTS revoked_at;
+
+ALTER TABLE user_devices
+    DROP COLUMN IF EXISTS name;
@@ -0,0 +1,32 @@
+-- +goose Up
+ALTER TABLE user_devices
+    ADD COLUMN signing_key TEXT NOT NULL DEFAULT '';
+
+-- pending — устройство ожидает подтверждения с уже доверенного устройства пользователя
+ALTER TABLE user_devices
+    ADD COLUMN status VARCHAR(16) NOT NULL DEFAULT 'active';
+
+ALTER TABLE user_devices
+    ADD COLUMN approved_by UUID;
+
+ALTER TABLE user_devices
+    ADD COLUMN approval_signature TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE user_devices
+    ADD COLUMN approved_at TIMESTAMP;
+
+-- +goose Down
+ALTER TABLE user_devices
+    DROP COLUMN IF EXISTS approved_at;
+
+ALTER TABLE user_devices
+    DROP COLUMN IF EXISTS approval_signature;
+
+ALTER TABLE user_devices
+    DROP COLUMN IF EXISTS approved_by;
+
+ALTER TABLE user_devices
+    DROP COLUMN IF EXISTS status;
+
+ALTER TABLE user_devices
+    DROP COLUMN IF EXISTS signing_key;
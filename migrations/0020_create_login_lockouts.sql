@@ -0,0 +1,15 @@
+-- +goose Up
+-- scope: username — блокировка имени пользователя, ip — блокировка адреса клиента
+CREATE TABLE login_lockouts (
+    lockout_uuid UUID PRIMARY KEY,
+    scope        VARCHAR(16) NOT NULL,
+    key          VARCHAR(255) NOT NULL,
+    failures     INTEGER NOT NULL,
+    locked_at    TIMESTAMP NOT NULL,
+    locked_until TIMESTAMP NOT NULL
+);
+
+CREATE INDEX idx_login_lockouts_locked_at ON login_lockouts(locked_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS login_lockouts;
@@ -0,0 +1,18 @@
+-- +goose Up
+-- Устройство, загрузившее ключ комнаты, и его подпись ключом подписи этого устройства
+ALTER TABLE room_keys
+    ADD COLUMN issuer_device_uuid UUID;
+
+ALTER TABLE room_keys
+    ADD COLUMN signature TEXT NOT NULL DEFAULT '';
+
+-- Ключи текущих эпох не подписаны: новая эпоха заставляет клиентов выпустить подписанный ключ
+UPDATE rooms
+SET key_epoch = key_epoch + 1;
+
+-- +goose Down
+ALTER TABLE room_keys
+    DROP COLUMN IF EXISTS signature;
+
+ALTER TABLE room_keys
+    DROP COLUMN IF EXISTS issuer_device_uuid;
@@ -168,7 +168,6 @@ func (s *AuthSuite) TestRegisterUser() {
 func (s *AuthSuite) TestAddDevice() {
 	username := fmt.Sprintf("user_%d", time.Now().UnixNano())
 	password := "password123"
-	publicKey := "publickey123"
 
 	// Регистрация нового пользователя
 	cmdRegister := exec.Command(s.clientPath,
@@ -186,7 +185,6 @@ func (s *AuthSuite) TestAddDevice() {
 		"--address", "http://localhost"+s.address+"/api/v1",
 		"--username", username,
 		"--password", password,
-		"--public-key", publicKey,
 	)
 	out, err = cmd.CombinedOutput()
 	s.Require().NoError(err, "не удалось добавить устройство: %s", string(out))
@@ -198,12 +196,16 @@ func (s *AuthSuite) TestAddDevice() {
 	s.Require().NoError(err, "не удалось прочитать файл с UUID устройства")
 	s.Require().NotEmpty(data, "UUID устройства пустой")
 	log.Println("[TestAddDevice] UUID устройства успешно сохранён:", string(data))
+
+	keyFile := fmt.Sprintf("%s/bil_message_client_device_key", configDir)
+	info, err := os.Stat(keyFile)
+	s.Require().NoError(err, "приватный ключ устройства не сохранён")
+	s.Require().Equal(os.FileMode(0o600), info.Mode().Perm())
 }
 
 func (s *AuthSuite) TestLogin() {
 	username := fmt.Sprintf("user_%d", time.Now().UnixNano())
 	password := "password123"
-	publicKey := "publickey123"
 
 	// Регистрация и добавление устройства
 	cmdRegister := exec.Command(s.clientPath,
@@ -220,7 +222,6 @@ func (s *AuthSuite) TestLogin() {
 		"--address", "http://localhost"+s.address+"/api/v1",
 		"--username", username,
 		"--password", password,
-		"--public-key", publicKey,
 	)
 	out, err = cmdDevice.CombinedOutput()
 	s.Require().NoError(err, "не удалось добавить устройство: %s", string(out))
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net"
@@ -22,6 +23,7 @@ import (
 	"github.com/testcontainers/testcontainers-go/wait"
 
 	"github.com/gorilla/websocket"
+	"github.com/sbilibin2017/bil-message/internal/chat"
 )
 
 type ChatSuite struct {
@@ -154,7 +156,6 @@ func (s *ChatSuite) SetupSuite() {
 		"--address", clientAddr,
 		"--username", s.username1,
 		"--password", s.password,
-		"--public-key", "publickey123",
 	).CombinedOutput()
 	s.Require().NoError(err)
 	log.Println("[SetupSuite] Устройство добавлено")
@@ -189,7 +190,6 @@ func (s *ChatSuite) SetupSuite() {
 		"--address", clientAddr,
 		"--username", s.username2,
 		"--password", s.password,
-		"--public-key", "publickey123",
 	).CombinedOutput()
 	s.Require().NoError(err)
 	log.Println("[SetupSuite] Устройство второго пользователя добавлено")
@@ -235,18 +235,32 @@ func (s *ChatSuite) TestChatLifecycle() {
 	s.Require().NotEmpty(roomID)
 	log.Printf("[TestChatLifecycle] Комната создана, UUID: %s", roomID)
 
-	// Добавление второго пользователя в комнату
-	log.Println("[TestChatLifecycle] Добавление второго пользователя в комнату...")
-	cmd := exec.Command(s.clientPath, "add-member",
+	// Приглашение второго пользователя в комнату
+	log.Println("[TestChatLifecycle] Приглашение второго пользователя в комнату...")
+	cmd := exec.Command(s.clientPath, "invite",
 		"--address", clientAddr,
 		"--token", s.token1,
 		"--room-uuid", roomID,
 		"--member-uuid", s.userUUID2,
 	)
 	out, err = cmd.CombinedOutput()
-	log.Printf("[TestChatLifecycle] add-member output: %q", string(out))
+	log.Printf("[TestChatLifecycle] invite output: %q", string(out))
 	s.Require().NoError(err)
-	log.Println("[TestChatLifecycle] Второй пользователь добавлен в комнату")
+	inviteID := strings.TrimSpace(string(out))
+	s.Require().NotEmpty(inviteID)
+
+	// Принятие приглашения вторым пользователем
+	log.Println("[TestChatLifecycle] Принятие приглашения вторым пользователем...")
+	cmd = exec.Command(s.clientPath, "accept",
+		"--address", clientAddr,
+		"--token", s.token2,
+		"--invite-uuid", inviteID,
+	)
+	out, err = cmd.CombinedOutput()
+	log.Printf("[TestChatLifecycle] accept output: %q", string(out))
+	s.Require().NoError(err)
+	s.Require().Equal(roomID, strings.TrimSpace(string(out)))
+	log.Println("[TestChatLifecycle] Второй пользователь вступил в комнату")
 
 	// Удаление второго пользователя из комнаты
 	log.Println("[TestChatLifecycle] Удаление второго пользователя из комнаты...")
@@ -289,15 +303,23 @@ func (s *ChatSuite) TestMessaging() {
 	s.Require().NotEmpty(roomID)
 	log.Printf("[TestMessaging] Комната создана: %s", roomID)
 
-	// Добавление второго пользователя
-	log.Println("[TestMessaging] Добавление второго пользователя...")
-	_, err = exec.Command(s.clientPath, "add-member",
+	// Приглашение второго пользователя
+	log.Println("[TestMessaging] Приглашение второго пользователя...")
+	out, err = exec.Command(s.clientPath, "invite",
 		"--address", clientAddr,
 		"--token", s.token1,
 		"--room-uuid", roomID,
 		"--member-uuid", s.userUUID2,
 	).CombinedOutput()
-	s.Require().NoError(err)
+	s.Require().NoError(err, string(out))
+	inviteID := strings.TrimSpace(string(out))
+
+	out, err = exec.Command(s.clientPath, "accept",
+		"--address", clientAddr,
+		"--token", s.token2,
+		"--invite-uuid", inviteID,
+	).CombinedOutput()
+	s.Require().NoError(err, string(out))
 
 	// WebSocket URL
 	wsURL := fmt.Sprintf("ws://localhost%s/api/v1/chat/%s/ws", s.address, roomID)
@@ -321,26 +343,41 @@ func (s *ChatSuite) TestMessaging() {
 	defer conn2.Close()
 
 	// Канал для чтения сообщений от второго пользователя
-	msgCh := make(chan string, 1)
+	msgCh := make(chan chat.Envelope, 1)
 	go func() {
 		_, msg, err := conn2.ReadMessage()
 		if err != nil {
 			log.Printf("[TestMessaging] Ошибка чтения WS: %v", err)
 			return
 		}
-		msgCh <- string(msg)
+		var env chat.Envelope
+		if err := json.Unmarshal(msg, &env); err != nil {
+			log.Printf("[TestMessaging] Некорректный конверт: %v", err)
+			return
+		}
+		msgCh <- env
 	}()
 
-	// Первый отправляет сообщение
-	expected := "Hello from user1!"
-	err = conn1.WriteMessage(websocket.TextMessage, []byte(expected))
+	// Первый отправляет сообщение в конверте протокола
+	expected := "SGVsbG8gZnJvbSB1c2VyMSE="
+	frame, err := json.Marshal(chat.Envelope{
+		Version:    chat.ProtocolVersion,
+		Type:       chat.TypeMessage,
+		Ciphertext: expected,
+		KeyEpoch:   2,
+	})
+	s.Require().NoError(err)
+	err = conn1.WriteMessage(websocket.TextMessage, frame)
 	s.Require().NoError(err)
 
-	// Проверка получения сообщения
+	// Проверка получения сообщения с проставленным сервером отправителем
 	select {
 	case received := <-msgCh:
-		log.Printf("[TestMessaging] Второй получил: %q", received)
-		s.Require().Equal(expected, received)
+		log.Printf("[TestMessaging] Второй получил: %+v", received)
+		s.Require().Equal(chat.TypeMessage, received.Type)
+		s.Require().Equal(expected, received.Ciphertext)
+		s.Require().Equal(s.userUUID1, received.SenderUUID.String())
+		s.Require().NotZero(received.SentAt)
 	case <-time.After(5 * time.Second):
 		s.T().Fatal("таймаут: второй пользователь не получил сообщение")
 	}